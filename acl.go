@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"go.uber.org/zap"
+)
+
+// clientACL Slave 的用戶端允許清單
+type clientACL struct {
+	allow     []string // IP/CIDR
+	exception bool     // 不允許的用戶端回應例外而非拒絕連線
+}
+
+// WithClientACL 只允許 allow 中的用戶端 (IP/CIDR)；action 為 exception 時其他用戶端的請求回應 0x0A，否則拒絕連線
+func WithClientACL(allow []string, action string) SlaveOption {
+	return func(s *Slave) {
+		s.acl = &clientACL{allow: allow, exception: action == ACLActionException}
+	}
+}
+
+// permits 用戶端是否在允許清單中 (未設定存取控制時皆允許)
+func (a *clientACL) permits(addr net.Addr) bool {
+	if a == nil {
+		return true
+	}
+	if len(a.allow) == 0 {
+		return false
+	}
+	var ip net.IP
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		ip = addr.IP
+	case *net.UDPAddr:
+		ip = addr.IP
+	}
+	return ip != nil && MatchTargets(ip, a.allow)
+}
+
+// denyConn 拒絕不在允許清單的連線：以 RST 關閉 (SO_LINGER 0)，Master 看到的是連線被重設而非正常關閉
+func (s *Slave) denyConn(conn net.Conn) {
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.SetLinger(0)
+	}
+	conn.Close()
+	s.logger.Debug(T("用戶端不在允許清單，拒絕連線"), zap.String("remote", conn.RemoteAddr().String()))
+}
+
+// --- 配置 ---
+
+// Validate 驗證存取控制規則
+func (r ACLRule) Validate(tags map[string][]string) error {
+	if r.Name == "" {
+		return errors.New(T("存取控制規則必須指定名稱"))
+	}
+	switch r.Action {
+	case "", ACLActionReject, ACLActionException:
+	default:
+		return fmt.Errorf(T("存取控制規則 %s 的 action 不支援: %s (可用: reject, exception)"), r.Name, r.Action)
+	}
+	for _, target := range append(append([]string{}, r.Targets...), r.Allow...) {
+		if net.ParseIP(target) == nil {
+			if _, _, err := net.ParseCIDR(target); err != nil {
+				return fmt.Errorf(T("存取控制規則 %s 的位址無效: %s"), r.Name, target)
+			}
+		}
+	}
+	for _, tag := range r.Tags {
+		if _, ok := tags[tag]; !ok {
+			return fmt.Errorf(T("存取控制規則 %s 使用未定義的 Slave 標籤: %s"), r.Name, tag)
+		}
+	}
+	return nil
+}
+
+// aclRule 第一個符合 Slave 的存取控制規則
+func (c *Config) aclRule(ip net.IP) (ACLRule, bool) {
+	for _, rule := range c.ACL {
+		if len(rule.Targets) == 0 && len(rule.Tags) == 0 {
+			return rule, true
+		}
+		if len(rule.Targets) > 0 && MatchTargets(ip, rule.Targets) {
+			return rule, true
+		}
+		for _, tag := range rule.Tags {
+			if c.hasTag(ip, tag) {
+				return rule, true
+			}
+		}
+	}
+	return ACLRule{}, false
+}
@@ -0,0 +1,114 @@
+package main
+
+import "fmt"
+
+// AddressMode 暫存器定義與 RegisterMap 使用的位址慣例 (Modbus 請求一律以 PDU 位址 0 起算，與此無關)
+type AddressMode string
+
+const (
+	// AddressModeAuto 相容模式 (預設)：保持暫存器 40001 起算與 0 起算並存，其餘三種以 PDU 位址表示
+	AddressModeAuto AddressMode = ""
+	// AddressModeProtocol 四種暫存器皆以 PDU 位址表示 (0 起算)
+	AddressModeProtocol AddressMode = "protocol"
+	// AddressModePLC 四種暫存器皆為 1 起算 (位址 1 為 PDU 位址 0)
+	AddressModePLC AddressMode = "plc"
+	// AddressModeModicon 以前綴區分表格：線圈 00001、離散輸入 10001、輸入暫存器 30001、保持暫存器 40001 起算
+	AddressModeModicon AddressMode = "modicon"
+)
+
+// ParseAddressMode 解析位址慣例 (空字串與 auto 為相容模式)
+func ParseAddressMode(s string) (AddressMode, error) {
+	switch mode := AddressMode(s); mode {
+	case AddressModeAuto, AddressModeProtocol, AddressModePLC, AddressModeModicon:
+		return mode, nil
+	case "auto":
+		return AddressModeAuto, nil
+	default:
+		return AddressModeAuto, fmt.Errorf(T("不支援的位址慣例: %s (可用: auto, protocol, plc, modicon)"), s)
+	}
+}
+
+// String 位址慣例名稱
+func (m AddressMode) String() string {
+	if m == AddressModeAuto {
+		return "auto"
+	}
+	return string(m)
+}
+
+// modiconBase Modicon 慣例下各表格的位址前綴 (第一個位址為前綴 + 1)
+func modiconBase(table RegisterType) int {
+	switch table {
+	case RegisterTypeDiscreteInput:
+		return 10000
+	case RegisterTypeInputRegister:
+		return 30000
+	case RegisterTypeHoldingRegister:
+		return 40000
+	default:
+		return 0
+	}
+}
+
+// index 位址在表格中的索引 (即 PDU 位址)；不是此慣例下該表格的位址時回傳 -1
+func (m AddressMode) index(table RegisterType, address uint16) int {
+	switch m {
+	case AddressModeProtocol:
+		return int(address)
+	case AddressModePLC:
+		return int(address) - 1
+	case AddressModeModicon:
+		base := modiconBase(table)
+		// 保持暫存器之後沒有其他表格，可使用到 65535
+		if idx := int(address) - base - 1; idx >= 0 && (idx < 10000 || table == RegisterTypeHoldingRegister) {
+			return idx
+		}
+		return -1
+	default:
+		return holdingRegisterIndexFor(table, address)
+	}
+}
+
+// address 索引 (PDU 位址) 在此慣例下的位址 (相容模式的保持暫存器回傳 40001 起算的位址)
+func (m AddressMode) address(table RegisterType, idx int) (uint16, bool) {
+	var address int
+	switch m {
+	case AddressModeProtocol:
+		address = idx
+	case AddressModePLC:
+		address = idx + 1
+	case AddressModeModicon:
+		address = idx + modiconBase(table) + 1
+	default:
+		address = idx
+		if table == RegisterTypeHoldingRegister {
+			address += 40001
+		}
+	}
+	if idx < 0 || address > 0xFFFF {
+		return 0, false
+	}
+	return uint16(address), true
+}
+
+// rangeText 大小為 size 的表格在此慣例下的位址範圍 (錯誤訊息用)
+func (m AddressMode) rangeText(table RegisterType, size int) string {
+	first, _ := m.address(table, 0)
+	last, ok := m.address(table, size-1)
+	if !ok {
+		last = 0xFFFF
+	}
+	text := fmt.Sprintf("%d-%d", first, last)
+	if m == AddressModeAuto && table == RegisterTypeHoldingRegister {
+		text = fmt.Sprintf("0-%d, %s", size-1, text)
+	}
+	return text
+}
+
+// holdingRegisterIndexFor 相容模式的索引 (僅保持暫存器換算 40001 起算的位址)
+func holdingRegisterIndexFor(table RegisterType, address uint16) int {
+	if table == RegisterTypeHoldingRegister {
+		return holdingRegisterIndex(address)
+	}
+	return int(address)
+}
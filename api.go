@@ -0,0 +1,1241 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// AdminAPI 管理 API (掛載於指標伺服器，供 CLI 與測試程式操作運行中的實例)
+type AdminAPI struct {
+	engine *Engine
+	logger *zap.Logger
+}
+
+// NewAdminAPI 建立管理 API
+func NewAdminAPI(engine *Engine, logger *zap.Logger) *AdminAPI {
+	return &AdminAPI{
+		engine: engine,
+		logger: logger,
+	}
+}
+
+// Register 註冊路由
+func (a *AdminAPI) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/pairs", a.handleListPairs)
+	mux.HandleFunc("POST /api/pairs/{name}/failover", a.handleFailover)
+	mux.HandleFunc("GET /api/domains", a.handleListDomains)
+	mux.HandleFunc("POST /api/domains/{name}/outage", a.handleDomainOutage)
+	mux.HandleFunc("DELETE /api/domains/{name}/outage", a.handleRestoreDomain)
+	mux.HandleFunc("GET /api/bootstorm", a.handleBootStormStatus)
+	mux.HandleFunc("POST /api/bootstorm", a.handleBootStorm)
+	mux.HandleFunc("DELETE /api/bootstorm", a.handleRestoreBootStorm)
+	mux.HandleFunc("GET /api/decommissions", a.handleDecommissions)
+	mux.HandleFunc("POST /api/slaves/{id}/decommission", a.handleDecommission)
+	mux.HandleFunc("POST /api/slaves/{id}/blink", a.handleBlink)
+	mux.HandleFunc("DELETE /api/slaves/{id}/blink", a.handleStopBlink)
+	mux.HandleFunc("GET /api/slaves/{id}/checksum", a.handleChecksum)
+	mux.HandleFunc("GET /api/slaves/{id}/clock", a.handleDeviceClock)
+	mux.HandleFunc("PUT /api/slaves/{id}/clock", a.handleSetDeviceClock)
+	mux.HandleFunc("GET /api/checksums", a.handleChecksums)
+	mux.HandleFunc("GET /api/drift", a.handleDrift)
+	mux.HandleFunc("POST /api/drift/baseline", a.handleRebaseline)
+	mux.HandleFunc("POST /api/slaves/{id}/baseline", a.handleRebaselineSlave)
+	mux.HandleFunc("GET /api/snapshot", a.handleSnapshot)
+	mux.HandleFunc("POST /api/snapshot", a.handleRestoreSnapshot)
+	mux.HandleFunc("GET /api/polling", a.handlePolling)
+	mux.HandleFunc("DELETE /api/polling", a.handleResetPolling)
+	mux.HandleFunc("GET /api/slaves/{id}/coils", a.handleGetBitmap(false))
+	mux.HandleFunc("PUT /api/slaves/{id}/coils", a.handleSetBitmap(false))
+	mux.HandleFunc("GET /api/slaves/{id}/discrete_inputs", a.handleGetBitmap(true))
+	mux.HandleFunc("PUT /api/slaves/{id}/discrete_inputs", a.handleSetBitmap(true))
+	mux.HandleFunc("GET /api/slaves", a.handleListSlaves)
+	mux.HandleFunc("GET /api/scenario", a.handleScenario)
+	mux.HandleFunc("PUT /api/scenario", a.handleApplyScenario)
+	mux.HandleFunc("GET /api/scenario/params", a.handleScenarioParams)
+	mux.HandleFunc("PATCH /api/scenario/params", a.handleTuneScenario)
+	mux.HandleFunc("DELETE /api/scenario/params", a.handleResetScenarioParams)
+	mux.HandleFunc("GET /api/chaos", a.handleChaos)
+	mux.HandleFunc("GET /api/clock", a.handleClock)
+	mux.HandleFunc("PUT /api/clock", a.handleSetClock)
+	mux.HandleFunc("GET /api/slaves/{id}/registers", a.handleRegisters)
+	mux.HandleFunc("PUT /api/slaves/{id}/registers/{address}", a.handleWriteRegister)
+	mux.HandleFunc("PUT /api/registers/{name}", a.handleBulkWriteRegister)
+	mux.HandleFunc("GET /api/slaves/{id}/tables/{table}", a.handleReadTable)
+	mux.HandleFunc("PUT /api/slaves/{id}/tables/{table}", a.handleWriteTable)
+}
+
+// handleListPairs 處理 GET /api/pairs
+func (a *AdminAPI) handleListPairs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.engine.ListPairs())
+}
+
+// handleFailover 處理 POST /api/pairs/{name}/failover
+func (a *AdminAPI) handleFailover(w http.ResponseWriter, r *http.Request) {
+	status, err := a.engine.Failover(r.PathValue("name"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// DomainOutageRequest 故障域停擺請求
+type DomainOutageRequest struct {
+	Duration string `json:"duration,omitempty"` // 未指定時使用配置的 outage_duration
+}
+
+// handleListDomains 處理 GET /api/domains
+func (a *AdminAPI) handleListDomains(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.engine.ListDomains())
+}
+
+// handleDomainOutage 處理 POST /api/domains/{name}/outage
+func (a *AdminAPI) handleDomainOutage(w http.ResponseWriter, r *http.Request) {
+	var req DomainOutageRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf(T("解析請求失敗: %w"), err))
+			return
+		}
+	}
+
+	var duration time.Duration
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf(T("無效的 duration: %s"), req.Duration))
+			return
+		}
+		duration = d
+	}
+
+	status, err := a.engine.DomainOutage(r.PathValue("name"), duration)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// handleRestoreDomain 處理 DELETE /api/domains/{name}/outage
+func (a *AdminAPI) handleRestoreDomain(w http.ResponseWriter, r *http.Request) {
+	status, err := a.engine.RestoreDomain(r.PathValue("name"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// BootStormRequest 開機風暴請求 (未指定的欄位使用配置的 boot_storm)
+type BootStormRequest struct {
+	Outage  string   `json:"outage,omitempty"`
+	Stagger string   `json:"stagger,omitempty"`
+	Targets []string `json:"targets,omitempty"`
+}
+
+// handleBootStormStatus 處理 GET /api/bootstorm
+func (a *AdminAPI) handleBootStormStatus(w http.ResponseWriter, r *http.Request) {
+	status, ok := a.engine.BootStormStatus()
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New(T("沒有進行中的開機風暴")))
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// handleBootStorm 處理 POST /api/bootstorm
+func (a *AdminAPI) handleBootStorm(w http.ResponseWriter, r *http.Request) {
+	var req BootStormRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf(T("解析請求失敗: %w"), err))
+			return
+		}
+	}
+
+	cfg := a.engine.config.BootStorm
+	if req.Outage != "" {
+		d, err := time.ParseDuration(req.Outage)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf(T("無效的 outage: %s"), req.Outage))
+			return
+		}
+		cfg.Outage = d
+	}
+	if req.Stagger != "" {
+		d, err := time.ParseDuration(req.Stagger)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf(T("無效的 stagger: %s"), req.Stagger))
+			return
+		}
+		cfg.Stagger = d
+	}
+	if len(req.Targets) > 0 {
+		cfg.Targets = req.Targets
+	}
+	if err := cfg.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	status, err := a.engine.BootStorm(cfg)
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// handleRestoreBootStorm 處理 DELETE /api/bootstorm (立即復電)
+func (a *AdminAPI) handleRestoreBootStorm(w http.ResponseWriter, r *http.Request) {
+	status, err := a.engine.RestoreBootStorm()
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// BlinkRequest 識別閃爍請求
+type BlinkRequest struct {
+	Duration string   `json:"duration,omitempty"`
+	Period   string   `json:"period,omitempty"`
+	Register *uint16  `json:"register,omitempty"` // 未指定時為 DefaultBlinkRegister
+	Pattern  []uint16 `json:"pattern,omitempty"`
+	Coil     *int     `json:"coil,omitempty"` // 未指定或 -1 表示不切換線圈
+}
+
+// options 轉換為 BlinkOptions
+func (r BlinkRequest) options() (BlinkOptions, error) {
+	opts := BlinkOptions{
+		Register: r.Register,
+		Pattern:  r.Pattern,
+	}
+	if r.Coil != nil && *r.Coil != -1 {
+		if *r.Coil < 0 || *r.Coil > math.MaxUint16 {
+			return opts, fmt.Errorf(T("無效的 coil: %d"), *r.Coil)
+		}
+		coil := uint16(*r.Coil)
+		opts.Coil = &coil
+	}
+	if r.Duration != "" {
+		d, err := time.ParseDuration(r.Duration)
+		if err != nil {
+			return opts, fmt.Errorf(T("無效的 duration: %s"), r.Duration)
+		}
+		opts.Duration = d
+	}
+	if r.Period != "" {
+		d, err := time.ParseDuration(r.Period)
+		if err != nil {
+			return opts, fmt.Errorf(T("無效的 period: %s"), r.Period)
+		}
+		opts.Period = d
+	}
+	return opts, nil
+}
+
+// lookupSlave 依 ID (ip:port) 或 IP 尋找 Slave
+func (a *AdminAPI) lookupSlave(key string) (*Slave, error) {
+	if slave, ok := a.engine.GetSlaveByID(key); ok {
+		return slave, nil
+	}
+	if ip := net.ParseIP(key); ip != nil {
+		if slave, ok := a.engine.GetSlave(ip); ok {
+			return slave, nil
+		}
+	}
+	return nil, fmt.Errorf(T("找不到 Slave: %s"), key)
+}
+
+// handleBlink 處理 POST /api/slaves/{id}/blink
+func (a *AdminAPI) handleBlink(w http.ResponseWriter, r *http.Request) {
+	slave, err := a.lookupSlave(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	var req BlinkRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf(T("解析請求失敗: %w"), err))
+			return
+		}
+	}
+	opts, err := req.options()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	status, err := slave.Blink(opts)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// handleStopBlink 處理 DELETE /api/slaves/{id}/blink
+func (a *AdminAPI) handleStopBlink(w http.ResponseWriter, r *http.Request) {
+	slave, err := a.lookupSlave(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	slave.StopBlink()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SlaveChecksum Slave 暫存器內容雜湊
+type SlaveChecksum struct {
+	Slave    string `json:"slave"`
+	Checksum string `json:"checksum"`
+}
+
+// ChecksumReport 全部 Slave 的雜湊比對結果
+type ChecksumReport struct {
+	Total      int             `json:"total"`
+	Matched    int             `json:"matched,omitempty"`
+	Mismatched int             `json:"mismatched,omitempty"`
+	Slaves     []SlaveChecksum `json:"slaves"` // 指定 expect 時僅列出不符者
+}
+
+// formatChecksum 以 16 位十六進位表示雜湊
+func formatChecksum(sum uint64) string {
+	return fmt.Sprintf("%016x", sum)
+}
+
+// handleDecommissions 處理 GET /api/decommissions
+func (a *AdminAPI) handleDecommissions(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.engine.DecommissionStatus())
+}
+
+// handleDecommission 處理 POST /api/slaves/{id}/decommission (立即除役)
+func (a *AdminAPI) handleDecommission(w http.ResponseWriter, r *http.Request) {
+	slave, err := a.lookupSlave(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	event, err := a.engine.Decommission(slave)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, event)
+}
+
+// handleChecksum 處理 GET /api/slaves/{id}/checksum
+func (a *AdminAPI) handleChecksum(w http.ResponseWriter, r *http.Request) {
+	slave, err := a.lookupSlave(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, SlaveChecksum{
+		Slave:    slave.ID,
+		Checksum: formatChecksum(slave.Registers().Checksum()),
+	})
+}
+
+// DeviceClockRequest 設定設備時間請求 (time 與 offset 擇一)
+type DeviceClockRequest struct {
+	Time   string `json:"time,omitempty"`   // RFC 3339 時間
+	Offset string `json:"offset,omitempty"` // 相對參考時間的偏差 (例如 "-30s"，"0s" 表示校正為參考時間)
+}
+
+// handleDeviceClock 處理 GET /api/slaves/{id}/clock
+func (a *AdminAPI) handleDeviceClock(w http.ResponseWriter, r *http.Request) {
+	slave, err := a.lookupSlave(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	status, ok := slave.DeviceClockStatus()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf(T("slave %s 未啟用設備時鐘"), slave.ID))
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// handleSetDeviceClock 處理 PUT /api/slaves/{id}/clock (設定設備時間，之後依原本的走時誤差前進)
+func (a *AdminAPI) handleSetDeviceClock(w http.ResponseWriter, r *http.Request) {
+	slave, err := a.lookupSlave(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	var req DeviceClockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf(T("解析請求失敗: %w"), err))
+		return
+	}
+
+	var t time.Time
+	switch {
+	case req.Time != "" && req.Offset == "":
+		if t, err = time.Parse(time.RFC3339, req.Time); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf(T("無效的 time: %s"), req.Time))
+			return
+		}
+	case req.Offset != "" && req.Time == "":
+		offset, err := time.ParseDuration(req.Offset)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf(T("無效的 offset: %s"), req.Offset))
+			return
+		}
+		t = scenarioNow().Add(offset)
+	default:
+		writeError(w, http.StatusBadRequest, errors.New(T("必須指定 time 或 offset 其中之一")))
+		return
+	}
+
+	if err := slave.SetDeviceClock(t); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	status, _ := slave.DeviceClockStatus()
+	writeJSON(w, http.StatusOK, status)
+}
+
+// handleChecksums 處理 GET /api/checksums[?expect=<checksum>]
+func (a *AdminAPI) handleChecksums(w http.ResponseWriter, r *http.Request) {
+	expect := r.URL.Query().Get("expect")
+
+	slaves := a.engine.ListSlaves()
+	sort.Slice(slaves, func(i, j int) bool { return slaves[i].ID < slaves[j].ID })
+
+	report := ChecksumReport{Total: len(slaves), Slaves: []SlaveChecksum{}}
+	for _, slave := range slaves {
+		sum := formatChecksum(slave.Registers().Checksum())
+		if expect != "" {
+			if sum == expect {
+				report.Matched++
+				continue
+			}
+			report.Mismatched++
+		}
+		report.Slaves = append(report.Slaves, SlaveChecksum{Slave: slave.ID, Checksum: sum})
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// handleDrift 處理 GET /api/drift (立即檢查並回傳有漂移的 Slave)
+func (a *AdminAPI) handleDrift(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.engine.CheckDrift())
+}
+
+// handleSnapshot 處理 GET /api/snapshot
+func (a *AdminAPI) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.engine.Snapshot())
+}
+
+// handleRestoreSnapshot 處理 POST /api/snapshot (請求內容為快照)
+func (a *AdminAPI) handleRestoreSnapshot(w http.ResponseWriter, r *http.Request) {
+	var snap Snapshot
+	if err := json.NewDecoder(r.Body).Decode(&snap); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf(T("解析請求失敗: %w"), err))
+		return
+	}
+	result, err := a.engine.RestoreSnapshot(&snap)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleRebaseline 處理 POST /api/drift/baseline (以全部 Slave 目前的狀態為新基準)
+func (a *AdminAPI) handleRebaseline(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.engine.Rebaseline())
+}
+
+// handleRebaselineSlave 處理 POST /api/slaves/{id}/baseline
+func (a *AdminAPI) handleRebaselineSlave(w http.ResponseWriter, r *http.Request) {
+	slave, err := a.lookupSlave(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, a.engine.Rebaseline(slave))
+}
+
+// pollTracker 取得輪詢分析器 (未啟用時回應錯誤並回傳 nil)
+func (a *AdminAPI) pollTracker(w http.ResponseWriter) *PollTracker {
+	polls := a.engine.Polls()
+	if polls == nil {
+		writeError(w, http.StatusNotFound, errors.New(T("輪詢分析未啟用 (polling.enabled)")))
+	}
+	return polls
+}
+
+// handlePolling 處理 GET /api/polling[?master=<ip>]
+func (a *AdminAPI) handlePolling(w http.ResponseWriter, r *http.Request) {
+	if polls := a.pollTracker(w); polls != nil {
+		writeJSON(w, http.StatusOK, polls.Report(r.URL.Query().Get("master")))
+	}
+}
+
+// handleResetPolling 處理 DELETE /api/polling (清除觀察結果並重新起算)
+func (a *AdminAPI) handleResetPolling(w http.ResponseWriter, r *http.Request) {
+	if polls := a.pollTracker(w); polls != nil {
+		polls.Reset()
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// 位元表編碼
+const (
+	BitmapHex    = "hex"
+	BitmapBase64 = "base64"
+)
+
+// Bitmap 線圈/離散輸入的位元表 (LSB 先，與 Modbus 線圈回應相同)
+type Bitmap struct {
+	Address  uint16 `json:"address"`
+	Count    int    `json:"count"`              // 寫入時為 0 表示位元表的全部位元
+	Encoding string `json:"encoding,omitempty"` // hex (預設) 或 base64
+	Bitmap   string `json:"bitmap"`
+}
+
+// BitmapWriteResult 位元表寫入結果
+type BitmapWriteResult struct {
+	Address uint16 `json:"address"`
+	Written int    `json:"written"`
+}
+
+// EncodeBitmap 依編碼輸出位元表
+func EncodeBitmap(data []byte, encoding string) (string, error) {
+	switch encoding {
+	case "", BitmapHex:
+		return hex.EncodeToString(data), nil
+	case BitmapBase64:
+		return base64.StdEncoding.EncodeToString(data), nil
+	}
+	return "", fmt.Errorf(T("位元表編碼無效: %s (可用 hex、base64)"), encoding)
+}
+
+// DecodeBitmap 依編碼解析位元表 (hex 可含 0x 前綴與空白)
+func DecodeBitmap(s, encoding string) ([]byte, error) {
+	switch encoding {
+	case "", BitmapHex:
+		s = strings.Join(strings.Fields(s), "")
+		s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+		return hex.DecodeString(s)
+	case BitmapBase64:
+		return base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	}
+	return nil, fmt.Errorf(T("位元表編碼無效: %s (可用 hex、base64)"), encoding)
+}
+
+// handleGetBitmap 處理 GET /api/slaves/{id}/coils|discrete_inputs[?address=&count=&encoding=]
+func (a *AdminAPI) handleGetBitmap(discrete bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		slave, err := a.lookupSlave(r.PathValue("id"))
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+
+		query := r.URL.Query()
+		result := Bitmap{Encoding: query.Get("encoding")}
+		if v := query.Get("address"); v != "" {
+			address, err := strconv.ParseUint(v, 10, 16)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf(T("位址無效: %s"), v))
+				return
+			}
+			result.Address = uint16(address)
+		}
+		if v := query.Get("count"); v != "" {
+			if result.Count, err = strconv.Atoi(v); err != nil || result.Count < 0 {
+				writeError(w, http.StatusBadRequest, fmt.Errorf(T("數量無效: %s"), v))
+				return
+			}
+		}
+
+		read := slave.Registers().ReadCoilBitmap
+		if discrete {
+			read = slave.Registers().ReadDiscreteInputBitmap
+		}
+		data, err := read(result.Address, result.Count)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if result.Bitmap, err = EncodeBitmap(data, result.Encoding); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if result.Count == 0 {
+			result.Count = len(data) * 8
+		}
+		if result.Encoding == "" {
+			result.Encoding = BitmapHex
+		}
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+// handleSetBitmap 處理 PUT /api/slaves/{id}/coils|discrete_inputs (以位元表批次寫入)
+func (a *AdminAPI) handleSetBitmap(discrete bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		slave, err := a.lookupSlave(r.PathValue("id"))
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+
+		var req Bitmap
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf(T("解析請求失敗: %w"), err))
+			return
+		}
+		data, err := DecodeBitmap(req.Bitmap, req.Encoding)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf(T("解析位元表失敗: %w"), err))
+			return
+		}
+
+		write := slave.Registers().WriteCoilBitmap
+		if discrete {
+			write = slave.Registers().SetDiscreteInputBitmap
+		}
+		if err := write(req.Address, data, req.Count); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		slave.mu.Lock()
+		slave.syncRegistersToServer()
+		slave.mu.Unlock()
+
+		if req.Count == 0 {
+			req.Count = len(data) * 8
+		}
+		writeJSON(w, http.StatusOK, BitmapWriteResult{Address: req.Address, Written: req.Count})
+	}
+}
+
+// SlaveSummary Slave 概況 (儀表板的設備總覽)
+type SlaveSummary struct {
+	ID          string     `json:"id"`
+	IP          string     `json:"ip"`
+	UnitID      int        `json:"unit_id"`            // 主設備
+	UnitIDs     []int      `json:"unit_ids,omitempty"` // 閘道後方的邏輯設備
+	State       string     `json:"state"`
+	Scenario    string     `json:"scenario"`
+	Connections int        `json:"connections"`
+	Requests    uint64     `json:"requests"` // 累計值，每秒請求數由呼叫端依兩次查詢的差計算
+	Errors      uint64     `json:"errors"`
+	LastRequest *time.Time `json:"last_request,omitempty"`
+}
+
+// handleListSlaves 處理 GET /api/slaves (依 ID 排序)
+func (a *AdminAPI) handleListSlaves(w http.ResponseWriter, r *http.Request) {
+	slaves := a.engine.ListSlaves()
+	sort.Slice(slaves, func(i, j int) bool { return slaves[i].ID < slaves[j].ID })
+
+	result := make([]SlaveSummary, 0, len(slaves))
+	for _, slave := range slaves {
+		stats := slave.GetStats()
+		summary := SlaveSummary{
+			ID:          slave.ID,
+			IP:          slave.IP.String(),
+			UnitID:      int(slave.UnitID),
+			State:       slave.State().String(),
+			Scenario:    slave.GetScenario().String(),
+			Connections: slave.ConnCount(),
+			Requests:    stats.RequestCount.Load(),
+			Errors:      stats.ErrorCount.Load(),
+		}
+		for _, id := range slave.unitIDs() {
+			summary.UnitIDs = append(summary.UnitIDs, int(id))
+		}
+		if last := stats.LastRequestTime.Load(); last > 0 {
+			t := time.Unix(0, last)
+			summary.LastRequest = &t
+		}
+		result = append(result, summary)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// ScenarioStatus 目前的場景與可用場景
+type ScenarioStatus struct {
+	Current   string   `json:"current"`
+	Available []string `json:"available,omitempty"`
+}
+
+// ScenarioRequest 套用場景請求
+type ScenarioRequest struct {
+	Scenario string `json:"scenario"`
+}
+
+// handleScenario 處理 GET /api/scenario
+func (a *AdminAPI) handleScenario(w http.ResponseWriter, r *http.Request) {
+	status := ScenarioStatus{Current: a.engine.GetScenario().String()}
+	for _, scenario := range ScenarioTypes() {
+		status.Available = append(status.Available, scenario.String())
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// handleApplyScenario 處理 PUT /api/scenario (套用至全部 Slave，依配置的 targets 與保護時段)
+func (a *AdminAPI) handleApplyScenario(w http.ResponseWriter, r *http.Request) {
+	var req ScenarioRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf(T("解析請求失敗: %w"), err))
+		return
+	}
+	scenario := ParseScenarioType(req.Scenario)
+	if scenario.String() != req.Scenario {
+		writeError(w, http.StatusBadRequest, fmt.Errorf(T("未知的場景: %s"), req.Scenario))
+		return
+	}
+	if err := a.engine.ApplyScenario(scenario); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ScenarioStatus{Current: scenario.String()})
+}
+
+// ScenarioParamsStatus 場景目前生效的參數
+type ScenarioParamsStatus struct {
+	Scenario string         `json:"scenario"`
+	Tuned    bool           `json:"tuned"`            // 是否為執行期間調整過的參數
+	Slaves   int            `json:"slaves,omitempty"` // 本次更新的 Slave 數 (調整或還原時)
+	Params   ScenarioParams `json:"params"`
+}
+
+// paramsScenario 查詢參數 scenario 指定的場景 (未指定時為目前的整體場景)
+func (a *AdminAPI) paramsScenario(r *http.Request) (ScenarioType, error) {
+	name := r.URL.Query().Get("scenario")
+	if name == "" {
+		return a.engine.GetScenario(), nil
+	}
+	scenario := ParseScenarioType(name)
+	if scenario.String() != name {
+		return scenario, fmt.Errorf(T("未知的場景: %s"), name)
+	}
+	return scenario, nil
+}
+
+// scenarioParamsStatus 場景目前生效的參數
+func (a *AdminAPI) scenarioParamsStatus(scenario ScenarioType, slaves int) ScenarioParamsStatus {
+	_, tuned := a.engine.tuning.get(scenario)
+	return ScenarioParamsStatus{
+		Scenario: scenario.String(),
+		Tuned:    tuned,
+		Slaves:   slaves,
+		Params:   a.engine.ScenarioParams(scenario),
+	}
+}
+
+// handleScenarioParams 處理 GET /api/scenario/params
+func (a *AdminAPI) handleScenarioParams(w http.ResponseWriter, r *http.Request) {
+	scenario, err := a.paramsScenario(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, a.scenarioParamsStatus(scenario, 0))
+}
+
+// handleTuneScenario 處理 PATCH /api/scenario/params (只覆寫請求中的參數，立即套用到運行該場景的 Slave)
+func (a *AdminAPI) handleTuneScenario(w http.ResponseWriter, r *http.Request) {
+	scenario, err := a.paramsScenario(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf(T("解析請求失敗: %w"), err))
+		return
+	}
+	_, slaves, err := a.engine.TuneScenario(scenario, patch)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, a.scenarioParamsStatus(scenario, slaves))
+}
+
+// handleResetScenarioParams 處理 DELETE /api/scenario/params (回到配置檔的參數)
+func (a *AdminAPI) handleResetScenarioParams(w http.ResponseWriter, r *http.Request) {
+	scenario, err := a.paramsScenario(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	_, slaves := a.engine.ResetScenarioTuning(scenario)
+	writeJSON(w, http.StatusOK, a.scenarioParamsStatus(scenario, slaves))
+}
+
+// handleChaos 處理 GET /api/chaos (混沌模式狀態與進行中的擾動)
+func (a *AdminAPI) handleChaos(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.engine.ChaosStatus())
+}
+
+// ClockRequest 調整模擬時鐘倍率請求
+type ClockRequest struct {
+	Speed float64 `json:"speed"`
+}
+
+// handleClock 處理 GET /api/clock
+func (a *AdminAPI) handleClock(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.engine.ClockStatus())
+}
+
+// handleSetClock 處理 PUT /api/clock (調整模擬時鐘倍率)
+func (a *AdminAPI) handleSetClock(w http.ResponseWriter, r *http.Request) {
+	var req ClockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf(T("解析請求失敗: %w"), err))
+		return
+	}
+	status, err := a.engine.SetClockSpeed(req.Speed)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// RegisterValue 已定義暫存器的目前值
+type RegisterValue struct {
+	Address  uint16   `json:"address"`
+	Name     string   `json:"name"`
+	DataType string   `json:"data_type"`
+	Unit     string   `json:"unit,omitempty"`
+	Writable bool     `json:"writable"`          // Master 是否可寫入 (管理 API 不受限)
+	Derived  bool     `json:"derived,omitempty"` // 衍生暫存器 (由運算式計算，寫入會被覆蓋)
+	Value    any      `json:"value"`             // 縮放後的值；字串類型為字串
+	Raw      []uint16 `json:"raw"`               // 佔用的暫存器原始值
+}
+
+// RegisterWriteRequest 以工程值寫入已定義暫存器 (字串類型為字串，其餘為數值)
+type RegisterWriteRequest struct {
+	Value any `json:"value"`
+}
+
+// RegisterTable 原始表格內容 (線圈與離散輸入為 0/1)
+type RegisterTable struct {
+	Table   string   `json:"table"`
+	Address uint16   `json:"address"` // 映射表位址慣例下的位址
+	Values  []uint16 `json:"values"`
+}
+
+// maxTableCount 一次讀取原始表格的數量上限
+const maxTableCount = 2000
+
+// slaveDevice 依路徑的 {id} 與查詢參數 unit 取得設備的暫存器 (unit 未指定時為主設備)
+func (a *AdminAPI) slaveDevice(r *http.Request) (*Slave, *RegisterMap, int, error) {
+	slave, err := a.lookupSlave(r.PathValue("id"))
+	if err != nil {
+		return nil, nil, http.StatusNotFound, err
+	}
+	v := r.URL.Query().Get("unit")
+	if v == "" {
+		return slave, slave.Registers(), 0, nil
+	}
+	id, err := strconv.ParseUint(v, 10, 8)
+	if err != nil {
+		return nil, nil, http.StatusBadRequest, fmt.Errorf(T("Unit ID 無效: %s"), v)
+	}
+	if uint8(id) == slave.UnitID {
+		return slave, slave.Registers(), 0, nil
+	}
+	u, ok := slave.units[uint8(id)]
+	if !ok {
+		return nil, nil, http.StatusNotFound, fmt.Errorf(T("找不到 Unit ID: %d"), id)
+	}
+	return slave, u.registers, 0, nil
+}
+
+// handleRegisters 處理 GET /api/slaves/{id}/registers[?unit=]
+func (a *AdminAPI) handleRegisters(w http.ResponseWriter, r *http.Request) {
+	_, registers, code, err := a.slaveDevice(r)
+	if err != nil {
+		writeError(w, code, err)
+		return
+	}
+
+	defs := registers.Definitions()
+	result := make([]RegisterValue, 0, len(defs))
+	for _, meta := range defs {
+		value := RegisterValue{
+			Address:  meta.Address,
+			Name:     meta.Name,
+			DataType: meta.DataType.String(),
+			Unit:     meta.Unit,
+			Writable: meta.Writable,
+			Derived:  meta.Expression != "",
+		}
+		if meta.DataType.IsString() {
+			value.Value, _ = registers.GetString(meta.Address)
+		} else {
+			value.Value, _ = registers.GetScaledValue(meta.Address)
+		}
+		value.Raw, _ = registers.ReadHoldingRegisters(meta.Address, uint16(meta.DataType.RegisterCount()))
+		result = append(result, value)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleWriteRegister 處理 PUT /api/slaves/{id}/registers/{address}[?unit=]
+func (a *AdminAPI) handleWriteRegister(w http.ResponseWriter, r *http.Request) {
+	slave, registers, code, err := a.slaveDevice(r)
+	if err != nil {
+		writeError(w, code, err)
+		return
+	}
+	v := r.PathValue("address")
+	address, err := strconv.ParseUint(v, 10, 16)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf(T("位址無效: %s"), v))
+		return
+	}
+	var req RegisterWriteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf(T("解析請求失敗: %w"), err))
+		return
+	}
+
+	switch value := req.Value.(type) {
+	case string:
+		err = registers.SetString(uint16(address), value)
+	case float64:
+		if err = checkScaledRange(registers, uint16(address), value); err == nil {
+			if err = registers.SetScaledValue(uint16(address), value); err == nil {
+				setMeasuredNominal(registers, uint16(address), value)
+			}
+		}
+	default:
+		err = errors.New(T("value 必須為數值或字串"))
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	slave.mu.Lock()
+	slave.syncRegistersToServer()
+	slave.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegisterBulkWriteRequest 以工程值寫入多個 Slave 的同名暫存器
+type RegisterBulkWriteRequest struct {
+	Value   any      `json:"value"`
+	Targets []string `json:"targets,omitempty"` // Slave IP/CIDR (空表示全部)
+	Unit    int      `json:"unit,omitempty"`    // 閘道後方設備的 Unit ID (0 表示主設備)
+}
+
+// RegisterBulkWriteResult 批次寫入結果
+type RegisterBulkWriteResult struct {
+	Name    string   `json:"name"`
+	Written []string `json:"written"`           // 已寫入的 Slave ID
+	Skipped []string `json:"skipped,omitempty"` // 符合 targets 但沒有此暫存器 (或 Unit ID) 的 Slave ID
+}
+
+// bulkWrite 批次寫入的單一目標
+type bulkWrite struct {
+	slave     *Slave
+	registers *RegisterMap
+	address   uint16
+}
+
+// handleBulkWriteRegister 處理 PUT /api/registers/{name}：先確認所有目標皆可寫入，
+// 再同時持有各 Slave 的鎖一併寫入並同步，Master 不會讀到只寫入一部分的結果
+func (a *AdminAPI) handleBulkWriteRegister(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	var req RegisterBulkWriteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf(T("解析請求失敗: %w"), err))
+		return
+	}
+	for _, target := range req.Targets {
+		if net.ParseIP(target) == nil {
+			if _, _, err := net.ParseCIDR(target); err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf(T("無效的目標: %s"), target))
+				return
+			}
+		}
+	}
+
+	slaves := a.engine.ListSlaves()
+	sort.Slice(slaves, func(i, j int) bool { return slaves[i].ID < slaves[j].ID })
+
+	result := RegisterBulkWriteResult{Name: name, Written: []string{}}
+	var writes []bulkWrite
+	for _, slave := range slaves {
+		if !MatchTargets(slave.IP, req.Targets) {
+			continue
+		}
+		registers := slave.Registers()
+		if req.Unit > 0 && uint8(req.Unit) != slave.UnitID {
+			u, ok := slave.units[uint8(req.Unit)]
+			if !ok {
+				result.Skipped = append(result.Skipped, slave.ID)
+				continue
+			}
+			registers = u.registers
+		}
+		meta, ok := registerByName(registers, name)
+		if !ok {
+			result.Skipped = append(result.Skipped, slave.ID)
+			continue
+		}
+		if err := checkRegisterValue(registers, meta, req.Value); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("%s: %w", slave.ID, err))
+			return
+		}
+		writes = append(writes, bulkWrite{slave: slave, registers: registers, address: meta.Address})
+	}
+	if len(writes) == 0 {
+		writeError(w, http.StatusNotFound, fmt.Errorf(T("沒有符合的 Slave 定義暫存器: %s"), name))
+		return
+	}
+
+	// 依 ID 順序取得所有 Slave 的鎖 (同一 Slave 只取一次)
+	locked := make(map[*Slave]bool, len(writes))
+	for _, write := range writes {
+		if !locked[write.slave] {
+			write.slave.mu.Lock()
+			locked[write.slave] = true
+		}
+	}
+	for _, write := range writes {
+		// 已於上方驗證，寫入不會失敗
+		if value, ok := req.Value.(string); ok {
+			write.registers.SetString(write.address, value)
+		} else {
+			write.registers.SetScaledValue(write.address, req.Value.(float64))
+			setMeasuredNominal(write.registers, write.address, req.Value.(float64))
+		}
+		result.Written = append(result.Written, write.slave.ID)
+	}
+	for slave := range locked {
+		slave.syncRegistersToServer()
+		slave.mu.Unlock()
+	}
+
+	a.logger.Info(T("批次寫入暫存器"),
+		zap.String("name", name),
+		zap.Any("value", req.Value),
+		zap.Int("written", len(result.Written)),
+		zap.Int("skipped", len(result.Skipped)),
+	)
+	writeJSON(w, http.StatusOK, result)
+}
+
+// registerByName 依名稱 (不分大小寫) 尋找已定義暫存器
+func registerByName(registers *RegisterMap, name string) (RegisterMeta, bool) {
+	for _, meta := range registers.Definitions() {
+		if strings.EqualFold(meta.Name, name) {
+			return meta, true
+		}
+	}
+	return RegisterMeta{}, false
+}
+
+// checkRegisterValue 確認值的類型符合暫存器定義 (字串類型為字串，其餘為數值且縮放後不超出範圍)
+func checkRegisterValue(registers *RegisterMap, meta RegisterMeta, value any) error {
+	switch v := value.(type) {
+	case string:
+		if !meta.DataType.IsString() {
+			return fmt.Errorf(T("暫存器 %s 為 %s，value 必須為數值"), meta.Name, meta.DataType)
+		}
+		return checkStringValue(meta.Name, meta.Address, meta.DataType, v)
+	case float64:
+		if meta.DataType.IsString() {
+			return fmt.Errorf(T("暫存器 %s 為 %s，value 必須為字串"), meta.Name, meta.DataType)
+		}
+		return checkScaledRange(registers, meta.Address, v)
+	default:
+		return errors.New(T("value 必須為數值或字串"))
+	}
+}
+
+// checkScaledRange 確認縮放後的值可由暫存器的整數類型表示 (避免寫入時溢位)
+func checkScaledRange(registers *RegisterMap, address uint16, value float64) error {
+	meta, ok := registers.GetDefinition(address)
+	if !ok {
+		return nil
+	}
+	min, max, ok := meta.DataType.RawRange()
+	if !ok {
+		return nil
+	}
+	if raw := value * meta.Scale; raw < min || raw > max {
+		return fmt.Errorf(T("數值超出 %s 暫存器 %s 的範圍: %v (縮放後 %v)"), meta.DataType, meta.Name, value, raw)
+	}
+	return nil
+}
+
+// handleReadTable 處理 GET /api/slaves/{id}/tables/{table}[?unit=&address=&count=]
+func (a *AdminAPI) handleReadTable(w http.ResponseWriter, r *http.Request) {
+	_, registers, code, err := a.slaveDevice(r)
+	if err != nil {
+		writeError(w, code, err)
+		return
+	}
+	name := r.PathValue("table")
+	table, ok := parseTableName(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf(T("不支援的資料表: %s"), name))
+		return
+	}
+
+	query := r.URL.Query()
+	result := RegisterTable{Table: name}
+	if v := query.Get("address"); v != "" {
+		address, err := strconv.ParseUint(v, 10, 16)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf(T("位址無效: %s"), v))
+			return
+		}
+		result.Address = uint16(address)
+	}
+	count := 16
+	if v := query.Get("count"); v != "" {
+		if count, err = strconv.Atoi(v); err != nil || count < 1 || count > maxTableCount {
+			writeError(w, http.StatusBadRequest, fmt.Errorf(T("數量無效: %s"), v))
+			return
+		}
+	}
+
+	if result.Values, err = readTable(registers, table, result.Address, uint16(count)); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleWriteTable 處理 PUT /api/slaves/{id}/tables/{table}[?unit=] (內容 {address, values})
+func (a *AdminAPI) handleWriteTable(w http.ResponseWriter, r *http.Request) {
+	slave, registers, code, err := a.slaveDevice(r)
+	if err != nil {
+		writeError(w, code, err)
+		return
+	}
+	name := r.PathValue("table")
+	table, ok := parseTableName(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf(T("不支援的資料表: %s"), name))
+		return
+	}
+	var req RegisterTable
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf(T("解析請求失敗: %w"), err))
+		return
+	}
+	// 先確認整段位址有效，避免只寫入一部分
+	if _, err := readTable(registers, table, req.Address, uint16(len(req.Values))); err != nil || len(req.Values) > maxTableCount {
+		writeError(w, http.StatusBadRequest, fmt.Errorf(T("位址範圍無效: %d (%d 個)"), req.Address, len(req.Values)))
+		return
+	}
+
+	for i, value := range req.Values {
+		if err := writeTable(registers, table, req.Address+uint16(i), value); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	slave.mu.Lock()
+	slave.syncRegistersToServer()
+	slave.mu.Unlock()
+
+	req.Table = name
+	writeJSON(w, http.StatusOK, req)
+}
+
+// readTable 讀取任一資料表的連續位址 (線圈與離散輸入為 0/1)
+func readTable(registers *RegisterMap, table RegisterType, address, count uint16) ([]uint16, error) {
+	switch table {
+	case RegisterTypeCoil:
+		values, err := registers.ReadCoils(address, count)
+		return coilValues(values), err
+	case RegisterTypeDiscreteInput:
+		values, err := registers.ReadDiscreteInputs(address, count)
+		return coilValues(values), err
+	case RegisterTypeInputRegister:
+		return registers.ReadInputRegisters(address, count)
+	default:
+		return registers.ReadHoldingRegisters(address, count)
+	}
+}
+
+// writeJSON 輸出 JSON 回應
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError 輸出 JSON 錯誤回應
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// callAdminAPI 呼叫運行中實例的管理 API (供 CLI 使用)
+func callAdminAPI(baseURL, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf(T("序列化請求失敗: %w"), err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf(T("建立請求失敗: %w"), err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf(T("連線管理 API 失敗: %w"), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf(T("管理 API 錯誤: %s"), apiErr.Error)
+		}
+		return fmt.Errorf(T("管理 API 錯誤: HTTP %d"), resp.StatusCode)
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf(T("解析回應失敗: %w"), err)
+		}
+	}
+	return nil
+}
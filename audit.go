@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 稽核日誌預設值
+const (
+	DefaultAuditPath       = "modbussim-audit.jsonl"
+	DefaultAuditMaxSizeMB  = 100
+	DefaultAuditMaxBackups = 5
+)
+
+// auditFlushInterval 緩衝內容寫入檔案的間隔
+const auditFlushInterval = time.Second
+
+// AuditRecord 稽核日誌的一筆請求 (JSONL 的一行)
+type AuditRecord struct {
+	Time          time.Time `json:"ts"`
+	ClientIP      string    `json:"client_ip"`
+	ClientPort    int       `json:"client_port,omitempty"`
+	SlaveIP       string    `json:"slave_ip"`
+	SlaveID       string    `json:"slave_id"`
+	UnitID        uint8     `json:"unit_id"`
+	TransactionID uint16    `json:"transaction_id"`
+	FunctionCode  uint8     `json:"function_code"`
+	Address       *uint16   `json:"address,omitempty"`  // PDU 起始位址 (0 起算)
+	Quantity      *uint16   `json:"quantity,omitempty"` // 讀寫數量
+	Values        []uint16  `json:"values,omitempty"`   // 寫入值 (線圈為 0/1)
+	ResponseCode  uint8     `json:"response_code"`      // 回應功能碼 (最高位元為 1 表示例外)
+	ExceptionCode uint8     `json:"exception_code,omitempty"`
+	DurationUS    int64     `json:"duration_us"`
+}
+
+// AuditLog 請求稽核日誌 (JSONL，依大小輪替)
+type AuditLog struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64 // 0 表示不輪替
+	backups int
+	file    *os.File
+	w       *bufio.Writer
+	size    int64
+	failed  uint64 // 上次回報後寫入失敗的筆數
+
+	stop chan struct{}
+	done chan struct{}
+
+	logger *zap.Logger
+}
+
+// NewAuditLog 開啟 (附加寫入) 稽核日誌
+func NewAuditLog(config AuditConfig, logger *zap.Logger) (*AuditLog, error) {
+	a := &AuditLog{
+		path:    config.Path,
+		maxSize: int64(config.MaxSizeMB) << 20,
+		backups: config.MaxBackups,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		logger:  logger,
+	}
+	if err := a.open(); err != nil {
+		return nil, err
+	}
+	go a.run()
+
+	logger.Info(T("已啟用稽核日誌"),
+		zap.String("path", a.path),
+		zap.Int("max_size_mb", config.MaxSizeMB),
+		zap.Int("max_backups", config.MaxBackups),
+	)
+	return a, nil
+}
+
+// open 開啟日誌檔並取得目前大小
+func (a *AuditLog) open() error {
+	file, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf(T("開啟稽核日誌 %s 失敗: %w"), a.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf(T("開啟稽核日誌 %s 失敗: %w"), a.path, err)
+	}
+	a.file = file
+	a.w = bufio.NewWriterSize(file, 64<<10)
+	a.size = info.Size()
+	return nil
+}
+
+// Record 寫入一筆請求，超過大小上限時先輪替
+func (a *AuditLog) Record(rec AuditRecord) {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.w == nil {
+		return // 已關閉
+	}
+	if a.maxSize > 0 && a.size > 0 && a.size+int64(len(line)) > a.maxSize {
+		if err := a.rotateLocked(); err != nil {
+			a.logger.Warn(T("稽核日誌輪替失敗"), zap.String("path", a.path), zap.Error(err))
+			if a.w == nil {
+				return
+			}
+		}
+	}
+	if _, err := a.w.Write(line); err != nil {
+		a.failed++
+		return
+	}
+	a.size += int64(len(line))
+}
+
+// rotateLocked 將目前的日誌改名為 path.1 (既有的輪替檔依序後移，超出保留數者刪除) 並開啟新檔 (呼叫端需持有 a.mu)
+func (a *AuditLog) rotateLocked() error {
+	a.w.Flush()
+	a.file.Close()
+	a.w, a.file = nil, nil
+
+	if a.backups == 0 {
+		os.Remove(a.path)
+	} else {
+		os.Remove(a.backupPath(a.backups))
+		for i := a.backups - 1; i >= 1; i-- {
+			os.Rename(a.backupPath(i), a.backupPath(i+1))
+		}
+		if err := os.Rename(a.path, a.backupPath(1)); err != nil {
+			a.open()
+			return err
+		}
+	}
+	return a.open()
+}
+
+// backupPath 第 n 個輪替檔的路徑
+func (a *AuditLog) backupPath(n int) string {
+	return a.path + "." + strconv.Itoa(n)
+}
+
+// flush 將緩衝內容寫入檔案並回報寫入失敗
+func (a *AuditLog) flush() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.w == nil {
+		return
+	}
+	if err := a.w.Flush(); err != nil {
+		a.logger.Warn(T("寫入稽核日誌失敗"), zap.String("path", a.path), zap.Error(err))
+	}
+	if a.failed > 0 {
+		a.logger.Warn(T("稽核日誌有請求未能寫入"), zap.Uint64("dropped", a.failed))
+		a.failed = 0
+	}
+}
+
+// run 定期將緩衝內容寫入檔案
+func (a *AuditLog) run() {
+	defer close(a.done)
+
+	ticker := time.NewTicker(auditFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			a.flush()
+		case <-a.stop:
+			return
+		}
+	}
+}
+
+// Close 寫出剩餘內容並關閉檔案
+func (a *AuditLog) Close() error {
+	close(a.stop)
+	<-a.done
+	a.flush()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.w, a.file = nil, nil
+	return err
+}
+
+// writtenValues 寫入請求的值 (FC05/FC15 的線圈為 0/1，FC06/FC16 為暫存器值)
+func writtenValues(packet []byte) []uint16 {
+	data := packet[ModbusTCPHeaderLength+1:]
+	if len(data) < 4 {
+		return nil
+	}
+
+	switch packet[ModbusTCPHeaderLength] {
+	case FuncCodeWriteSingleCoil:
+		if binary.BigEndian.Uint16(data[2:4]) == 0xFF00 {
+			return []uint16{1}
+		}
+		return []uint16{0}
+	case FuncCodeWriteSingleRegister:
+		return []uint16{binary.BigEndian.Uint16(data[2:4])}
+	case FuncCodeWriteMultipleCoils:
+		quantity := int(binary.BigEndian.Uint16(data[2:4]))
+		if len(data) < 5 || len(data[5:]) < (quantity+7)/8 {
+			return nil
+		}
+		values := make([]uint16, quantity)
+		for i := range values {
+			values[i] = uint16(data[5+i/8]>>(i%8)) & 1
+		}
+		return values
+	case FuncCodeWriteMultipleRegisters:
+		quantity := int(binary.BigEndian.Uint16(data[2:4]))
+		if len(data) < 5 || len(data[5:]) < quantity*2 {
+			return nil
+		}
+		values := make([]uint16, quantity)
+		for i := range values {
+			values[i] = binary.BigEndian.Uint16(data[5+i*2:])
+		}
+		return values
+	}
+	return nil
+}
+
+// auditRequest 啟用稽核日誌時記錄一筆請求
+func (s *Slave) auditRequest(packet, response []byte, remote net.Addr, start time.Time) {
+	if s.audit == nil {
+		return
+	}
+
+	rec := AuditRecord{
+		Time:          start,
+		SlaveID:       s.ID,
+		UnitID:        packet[ModbusTCPHeaderLength-1],
+		TransactionID: binary.BigEndian.Uint16(packet[0:2]),
+		FunctionCode:  packet[ModbusTCPHeaderLength],
+		Values:        writtenValues(packet),
+		DurationUS:    time.Since(start).Microseconds(),
+	}
+	if s.IP != nil {
+		rec.SlaveIP = s.IP.String()
+	}
+	if host, port, err := net.SplitHostPort(remote.String()); err == nil {
+		rec.ClientIP = host
+		rec.ClientPort, _ = strconv.Atoi(port)
+	} else {
+		rec.ClientIP = remote.String()
+	}
+	if address, quantity, ok := requestRange(packet); ok {
+		rec.Address, rec.Quantity = &address, &quantity
+	}
+	if len(response) > ModbusTCPHeaderLength {
+		rec.ResponseCode = response[ModbusTCPHeaderLength]
+		if rec.ResponseCode&0x80 != 0 && len(response) > ModbusTCPHeaderLength+1 {
+			rec.ExceptionCode = response[ModbusTCPHeaderLength+1]
+		}
+	}
+
+	s.audit.Record(rec)
+}
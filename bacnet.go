@@ -0,0 +1,904 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// DefaultBACnetPort BACnet/IP 預設 UDP 埠 (0xBAC0)
+const DefaultBACnetPort = 47808
+
+// DefaultBACnetBroadcast 預設接收 Who-Is 廣播的位址
+const DefaultBACnetBroadcast = "255.255.255.255"
+
+// bacnetMaxInstance 物件 instance 的上限 (4194303 保留為「本設備」萬用值)
+const (
+	bacnetMaxInstance      = 4194302
+	bacnetWildcardInstance = 4194303
+)
+
+// 設備能力 (不支援分段，單一 APDU 需容納完整回應)
+const (
+	bacnetMaxAPDU      = 1476
+	bacnetMaxFrame     = 1497 // BVLC + NPDU + APDU
+	bacnetVendorID     = 0    // 未申請廠商代碼，沿用 ASHRAE 的 0
+	bacnetVendorName   = "ModbusSim"
+	bacnetModelName    = "Modbus Simulator"
+	bacnetAPDUTimeout  = 3000
+	bacnetAPDURetries  = 3
+	bacnetProtocolRev  = 14
+	bacnetSegmentation = 3 // no-segmentation
+)
+
+// BVLC (BACnet/IP 虛擬鏈路層) 功能
+const (
+	bvlcType              = 0x81
+	bvlcForwardedNPDU     = 0x04
+	bvlcOriginalUnicast   = 0x0A
+	bvlcOriginalBroadcast = 0x0B
+)
+
+// APDU 類型 (高 4 位元) 與服務
+const (
+	bacnetConfirmedRequest   = 0x0
+	bacnetUnconfirmedRequest = 0x1
+	bacnetComplexAck         = 0x3
+	bacnetErrorPDU           = 0x5
+	bacnetRejectPDU          = 0x6
+	bacnetAbortPDU           = 0x7
+
+	bacnetServiceIAm          = 0  // 非確認服務 I-Am
+	bacnetServiceWhoIs        = 8  // 非確認服務 Who-Is
+	bacnetServiceReadProperty = 12 // 確認服務 ReadProperty
+
+	bacnetRejectInvalidTag          = 4
+	bacnetRejectUnrecognizedService = 9
+	bacnetAbortSegmentation         = 4 // segmentation-not-supported
+)
+
+// 物件類型
+const (
+	bacnetAnalogInput = 0
+	bacnetAnalogValue = 2
+	bacnetDevice      = 8
+)
+
+// 屬性識別碼
+const (
+	bacnetPropAppSoftwareVersion = 12
+	bacnetPropAPDUTimeout        = 11
+	bacnetPropAddressBinding     = 30
+	bacnetPropEventState         = 36
+	bacnetPropFirmwareRevision   = 44
+	bacnetPropMaxAPDU            = 62
+	bacnetPropModelName          = 70
+	bacnetPropAPDURetries        = 73
+	bacnetPropObjectIdentifier   = 75
+	bacnetPropObjectList         = 76
+	bacnetPropObjectName         = 77
+	bacnetPropObjectType         = 79
+	bacnetPropOutOfService       = 81
+	bacnetPropPresentValue       = 85
+	bacnetPropObjectTypesSupport = 96
+	bacnetPropServicesSupported  = 97
+	bacnetPropProtocolVersion    = 98
+	bacnetPropSegmentation       = 107
+	bacnetPropStatusFlags        = 111
+	bacnetPropSystemStatus       = 112
+	bacnetPropUnits              = 117
+	bacnetPropVendorIdentifier   = 120
+	bacnetPropVendorName         = 121
+	bacnetPropProtocolRevision   = 139
+	bacnetPropDatabaseRevision   = 155
+	bacnetPropPropertyList       = 371
+)
+
+// 設備與類比物件提供的屬性 (依此順序列於 property-list)
+var (
+	bacnetDeviceProperties = []uint32{
+		bacnetPropObjectIdentifier, bacnetPropObjectName, bacnetPropObjectType,
+		bacnetPropSystemStatus, bacnetPropVendorName, bacnetPropVendorIdentifier,
+		bacnetPropModelName, bacnetPropFirmwareRevision, bacnetPropAppSoftwareVersion,
+		bacnetPropProtocolVersion, bacnetPropProtocolRevision, bacnetPropServicesSupported,
+		bacnetPropObjectTypesSupport, bacnetPropObjectList, bacnetPropMaxAPDU,
+		bacnetPropSegmentation, bacnetPropAPDUTimeout, bacnetPropAPDURetries,
+		bacnetPropAddressBinding, bacnetPropDatabaseRevision, bacnetPropPropertyList,
+	}
+	bacnetAnalogProperties = []uint32{
+		bacnetPropObjectIdentifier, bacnetPropObjectName, bacnetPropObjectType,
+		bacnetPropPresentValue, bacnetPropStatusFlags, bacnetPropEventState,
+		bacnetPropOutOfService, bacnetPropUnits, bacnetPropPropertyList,
+	}
+)
+
+// bacnetUnits 暫存器單位對應的 BACnet 工程單位 (未收錄者為 no-units)
+var bacnetUnits = map[string]uint32{
+	"mA":   2,
+	"A":    3,
+	"V":    5,
+	"kV":   6,
+	"VA":   8,
+	"kVA":  9,
+	"var":  11,
+	"kvar": 12,
+	"Wh":   18,
+	"kWh":  19,
+	"Hz":   27,
+	"W":    47,
+	"kW":   48,
+	"MW":   49,
+	"°C":   62,
+	"%":    98,
+	"MWh":  146,
+}
+
+const bacnetNoUnits = 95
+
+// 錯誤類別與代碼
+var (
+	bacnetErrUnknownObject   = &bacnetError{class: 1, code: 31}
+	bacnetErrUnknownProperty = &bacnetError{class: 2, code: 32}
+	bacnetErrInvalidIndex    = &bacnetError{class: 2, code: 42}
+	bacnetErrNotAnArray      = &bacnetError{class: 2, code: 50}
+	bacnetErrOther           = &bacnetError{class: 0, code: 0}
+)
+
+// bacnetError ReadProperty 的錯誤回應
+type bacnetError struct {
+	class uint32
+	code  uint32
+}
+
+// bacnetMaxAPDUs 確認請求中 max-APDU-length-accepted 的編碼
+var bacnetMaxAPDUs = [...]int{50, 128, 206, 480, 1024, 1476}
+
+// WithBACnet 在 Slave 的 IP 上另外於 UDP port 提供 BACnet/IP 設備
+// (registers 為提供的暫存器名稱，空值表示全部數值暫存器)
+func WithBACnet(port int, instance uint32, registers []string) SlaveOption {
+	wanted := make(map[string]bool, len(registers))
+	for _, name := range registers {
+		wanted[name] = true
+	}
+	return func(s *Slave) {
+		s.protocols = append(s.protocols, func(s *Slave) protocolListener {
+			return newBACnetListener(s, s.protocolAddr(port), instance, wanted)
+		})
+	}
+}
+
+// bacnet 開啟中的 BACnet/IP 接入層 (未提供或未監聽時為 nil)
+func (s *Slave) bacnet() *bacnetListener {
+	s.listenMu.Lock()
+	defer s.listenMu.Unlock()
+	for _, listener := range s.protocolListeners {
+		if l, ok := listener.(*bacnetListener); ok {
+			return l
+		}
+	}
+	return nil
+}
+
+// --- 物件 ---
+
+// bacnetObjectID 物件識別碼 (10 位元類型 + 22 位元 instance)
+func bacnetObjectID(objectType, instance uint32) uint32 {
+	return objectType<<22 | instance&0x3FFFFF
+}
+
+// bacnetObject 以暫存器提供的類比物件 (instance 為暫存器位址)
+type bacnetObject struct {
+	id   uint32
+	meta RegisterMeta
+}
+
+// bacnetObjects 設備的類比物件 (字串類型除外，依位址排序；可寫的暫存器為 Analog Value，其餘為 Analog Input)
+func bacnetObjects(registers *RegisterMap, wanted map[string]bool) []bacnetObject {
+	defs := registers.Definitions()
+	objects := make([]bacnetObject, 0, len(defs))
+	for _, meta := range defs {
+		if meta.DataType.IsString() || (len(wanted) > 0 && !wanted[meta.Name]) {
+			continue
+		}
+		objectType := uint32(bacnetAnalogInput)
+		if meta.Writable {
+			objectType = bacnetAnalogValue
+		}
+		objects = append(objects, bacnetObject{id: bacnetObjectID(objectType, uint32(meta.Address)), meta: meta})
+	}
+	return objects
+}
+
+// bacnetValue 屬性值 (已編碼的應用標籤；陣列屬性逐一保留元素以支援 array index)
+type bacnetValue struct {
+	encoded  []byte
+	elements [][]byte
+	array    bool
+}
+
+func bacnetArray(elements [][]byte) bacnetValue {
+	return bacnetValue{elements: elements, array: true}
+}
+
+// at 依 array index 取值 (index 0 為元素數量)
+func (v bacnetValue) at(hasIndex bool, index uint32) ([]byte, *bacnetError) {
+	if !hasIndex {
+		if !v.array {
+			return v.encoded, nil
+		}
+		var b []byte
+		for _, element := range v.elements {
+			b = append(b, element...)
+		}
+		return b, nil
+	}
+	if !v.array {
+		return nil, bacnetErrNotAnArray
+	}
+	if index == 0 {
+		return appendBACnetUnsigned(nil, 2, false, uint32(len(v.elements))), nil
+	}
+	if index > uint32(len(v.elements)) {
+		return nil, bacnetErrInvalidIndex
+	}
+	return v.elements[index-1], nil
+}
+
+// bacnetPropertyList property-list 的元素 (不含物件識別碼、名稱、類型與 property-list 本身)
+func bacnetPropertyList(properties []uint32) bacnetValue {
+	var elements [][]byte
+	for _, prop := range properties {
+		switch prop {
+		case bacnetPropObjectIdentifier, bacnetPropObjectName, bacnetPropObjectType, bacnetPropPropertyList:
+			continue
+		}
+		elements = append(elements, appendBACnetEnumerated(nil, prop))
+	}
+	return bacnetArray(elements)
+}
+
+// --- 接入層 ---
+
+// bacnetListener Slave 的 BACnet/IP 接入層 (與 Modbus listener 一同開啟與關閉，離線模擬時同樣停止回應)
+type bacnetListener struct {
+	slave    *Slave
+	addr     string
+	instance uint32
+	wanted   map[string]bool
+
+	conn net.PacketConn
+	wg   sync.WaitGroup
+}
+
+func newBACnetListener(slave *Slave, addr string, instance uint32, wanted map[string]bool) *bacnetListener {
+	return &bacnetListener{
+		slave:    slave,
+		addr:     addr,
+		instance: instance,
+		wanted:   wanted,
+	}
+}
+
+// Listen 開始監聽並在背景處理請求
+func (l *bacnetListener) Listen() error {
+	var lc net.ListenConfig
+	if l.slave.iface != "" {
+		lc.Control = bindToDevice(l.slave.iface)
+	}
+	conn, err := lc.ListenPacket(context.Background(), "udp", l.addr)
+	if err != nil {
+		return err
+	}
+	l.conn = conn
+
+	l.wg.Add(1)
+	go l.serve()
+	return nil
+}
+
+// Addr 監聽位址
+func (l *bacnetListener) Addr() string {
+	return l.addr
+}
+
+// Close 停止監聽並等待處理 goroutine 結束
+func (l *bacnetListener) Close() {
+	l.conn.Close()
+	l.wg.Wait()
+}
+
+func (l *bacnetListener) serve() {
+	defer l.wg.Done()
+
+	buf := make([]byte, bacnetMaxFrame)
+	for {
+		n, from, err := l.conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				l.slave.logger.Warn(T("BACnet 接收封包失敗"), zap.String("addr", l.addr), zap.Error(err))
+			}
+			return
+		}
+		frame, ok := parseBACnetFrame(buf[:n], from)
+		if !ok || l.slave.silentStandby.Load() {
+			continue
+		}
+		l.handle(frame)
+	}
+}
+
+// handle 處理 Who-Is 與 ReadProperty (其他非確認服務忽略，其他確認服務以 Reject 回應)
+func (l *bacnetListener) handle(frame bacnetFrame) {
+	apdu := frame.apdu
+	switch apdu[0] >> 4 {
+	case bacnetUnconfirmedRequest:
+		if len(apdu) >= 2 && apdu[1] == bacnetServiceWhoIs {
+			l.answerWhoIs(frame)
+		}
+	case bacnetConfirmedRequest:
+		if len(apdu) < 4 {
+			return
+		}
+		invokeID := apdu[2]
+		if apdu[0]&0x08 != 0 {
+			l.reply(frame, []byte{bacnetAbortPDU<<4 | 0x01, invokeID, bacnetAbortSegmentation})
+			return
+		}
+		if apdu[3] != bacnetServiceReadProperty {
+			l.reply(frame, []byte{bacnetRejectPDU << 4, invokeID, bacnetRejectUnrecognizedService})
+			return
+		}
+		response := l.readProperty(invokeID, apdu[4:])
+		maxAPDU := bacnetMaxAPDU
+		if i := int(apdu[1] & 0x0F); i < len(bacnetMaxAPDUs) {
+			maxAPDU = bacnetMaxAPDUs[i]
+		}
+		if len(response) > maxAPDU {
+			response = []byte{bacnetAbortPDU<<4 | 0x01, invokeID, bacnetAbortSegmentation}
+		}
+		l.reply(frame, response)
+	}
+}
+
+// answerWhoIs instance 落在 Who-Is 的範圍內時回覆 I-Am
+func (l *bacnetListener) answerWhoIs(frame bacnetFrame) {
+	low, high, ok := parseBACnetWhoIs(frame.apdu[2:])
+	if !ok || l.instance < low || l.instance > high {
+		return
+	}
+	apdu := []byte{bacnetUnconfirmedRequest << 4, bacnetServiceIAm}
+	apdu = appendBACnetObjectID(apdu, 12, false, bacnetObjectID(bacnetDevice, l.instance))
+	apdu = appendBACnetUnsigned(apdu, 2, false, bacnetMaxAPDU)
+	apdu = appendBACnetEnumerated(apdu, bacnetSegmentation)
+	apdu = appendBACnetUnsigned(apdu, 2, false, bacnetVendorID)
+	l.reply(frame, apdu)
+}
+
+// readProperty 執行 ReadProperty 並返回 ComplexACK、Error 或 Reject
+func (l *bacnetListener) readProperty(invokeID byte, body []byte) []byte {
+	req, ok := parseBACnetReadProperty(body)
+	if !ok {
+		return []byte{bacnetRejectPDU << 4, invokeID, bacnetRejectInvalidTag}
+	}
+
+	value, berr := l.property(req.object, req.property)
+	var encoded []byte
+	if berr == nil {
+		encoded, berr = value.at(req.hasIndex, req.index)
+	}
+	if berr != nil {
+		b := []byte{bacnetErrorPDU << 4, invokeID, bacnetServiceReadProperty}
+		b = appendBACnetEnumerated(b, berr.class)
+		return appendBACnetEnumerated(b, berr.code)
+	}
+
+	b := []byte{bacnetComplexAck << 4, invokeID, bacnetServiceReadProperty}
+	b = appendBACnetObjectID(b, 0, true, req.object)
+	b = appendBACnetUnsigned(b, 1, true, req.property)
+	if req.hasIndex {
+		b = appendBACnetUnsigned(b, 2, true, req.index)
+	}
+	b = append(b, 0x3E) // 開始標記 3
+	b = append(b, encoded...)
+	return append(b, 0x3F) // 結束標記 3
+}
+
+// property 物件的屬性值 (設備 instance 4194303 表示本設備)
+func (l *bacnetListener) property(object, prop uint32) (bacnetValue, *bacnetError) {
+	registers := l.slave.Registers()
+	objects := bacnetObjects(registers, l.wanted)
+	deviceID := bacnetObjectID(bacnetDevice, l.instance)
+	if object == deviceID || object == bacnetObjectID(bacnetDevice, bacnetWildcardInstance) {
+		return l.deviceProperty(deviceID, objects, prop)
+	}
+	for _, obj := range objects {
+		if obj.id == object {
+			return analogProperty(registers, obj, prop)
+		}
+	}
+	return bacnetValue{}, bacnetErrUnknownObject
+}
+
+// deviceProperty 設備物件的屬性值
+func (l *bacnetListener) deviceProperty(id uint32, objects []bacnetObject, prop uint32) (bacnetValue, *bacnetError) {
+	var b []byte
+	switch prop {
+	case bacnetPropObjectIdentifier:
+		b = appendBACnetObjectID(nil, 12, false, id)
+	case bacnetPropObjectName:
+		b = appendBACnetString(nil, l.slave.ID)
+	case bacnetPropObjectType:
+		b = appendBACnetEnumerated(nil, bacnetDevice)
+	case bacnetPropSystemStatus:
+		b = appendBACnetEnumerated(nil, 0) // operational
+	case bacnetPropVendorName:
+		b = appendBACnetString(nil, bacnetVendorName)
+	case bacnetPropVendorIdentifier:
+		b = appendBACnetUnsigned(nil, 2, false, bacnetVendorID)
+	case bacnetPropModelName:
+		b = appendBACnetString(nil, bacnetModelName)
+	case bacnetPropFirmwareRevision, bacnetPropAppSoftwareVersion:
+		b = appendBACnetString(nil, Version)
+	case bacnetPropProtocolVersion:
+		b = appendBACnetUnsigned(nil, 2, false, 1)
+	case bacnetPropProtocolRevision:
+		b = appendBACnetUnsigned(nil, 2, false, bacnetProtocolRev)
+	case bacnetPropServicesSupported:
+		b = appendBACnetBitString(nil, 40, bacnetServiceReadProperty, 34) // readProperty、who-Is
+	case bacnetPropObjectTypesSupport:
+		b = appendBACnetBitString(nil, 56, bacnetAnalogInput, bacnetAnalogValue, bacnetDevice)
+	case bacnetPropObjectList:
+		elements := make([][]byte, 0, 1+len(objects))
+		elements = append(elements, appendBACnetObjectID(nil, 12, false, id))
+		for _, obj := range objects {
+			elements = append(elements, appendBACnetObjectID(nil, 12, false, obj.id))
+		}
+		return bacnetArray(elements), nil
+	case bacnetPropMaxAPDU:
+		b = appendBACnetUnsigned(nil, 2, false, bacnetMaxAPDU)
+	case bacnetPropSegmentation:
+		b = appendBACnetEnumerated(nil, bacnetSegmentation)
+	case bacnetPropAPDUTimeout:
+		b = appendBACnetUnsigned(nil, 2, false, bacnetAPDUTimeout)
+	case bacnetPropAPDURetries:
+		b = appendBACnetUnsigned(nil, 2, false, bacnetAPDURetries)
+	case bacnetPropAddressBinding:
+		b = []byte{} // 空串列
+	case bacnetPropDatabaseRevision:
+		b = appendBACnetUnsigned(nil, 2, false, 0)
+	case bacnetPropPropertyList:
+		return bacnetPropertyList(bacnetDeviceProperties), nil
+	default:
+		return bacnetValue{}, bacnetErrUnknownProperty
+	}
+	return bacnetValue{encoded: b}, nil
+}
+
+// analogProperty 類比物件的屬性值
+func analogProperty(registers *RegisterMap, obj bacnetObject, prop uint32) (bacnetValue, *bacnetError) {
+	var b []byte
+	switch prop {
+	case bacnetPropObjectIdentifier:
+		b = appendBACnetObjectID(nil, 12, false, obj.id)
+	case bacnetPropObjectName:
+		b = appendBACnetString(nil, obj.meta.Name)
+	case bacnetPropObjectType:
+		b = appendBACnetEnumerated(nil, obj.id>>22)
+	case bacnetPropPresentValue:
+		value, err := registers.GetScaledValue(obj.meta.Address)
+		if err != nil {
+			return bacnetValue{}, bacnetErrOther
+		}
+		b = append([]byte{0x44}, binary.BigEndian.AppendUint32(nil, math.Float32bits(float32(value)))...)
+	case bacnetPropStatusFlags:
+		b = appendBACnetBitString(nil, 4) // in-alarm、fault、overridden、out-of-service 皆為 false
+	case bacnetPropEventState:
+		b = appendBACnetEnumerated(nil, 0) // normal
+	case bacnetPropOutOfService:
+		b = []byte{0x10} // false
+	case bacnetPropUnits:
+		units, ok := bacnetUnits[obj.meta.Unit]
+		if !ok {
+			units = bacnetNoUnits
+		}
+		b = appendBACnetEnumerated(nil, units)
+	case bacnetPropPropertyList:
+		return bacnetPropertyList(bacnetAnalogProperties), nil
+	default:
+		return bacnetValue{}, bacnetErrUnknownProperty
+	}
+	return bacnetValue{encoded: b}, nil
+}
+
+// reply 以 Original-Unicast-NPDU 回應請求端 (遠端網路經路由器轉送的請求以 DNET/DADR 回送)
+func (l *bacnetListener) reply(frame bacnetFrame, apdu []byte) {
+	if _, err := l.conn.WriteTo(encodeBACnetFrame(frame, apdu), frame.source); err != nil && !errors.Is(err, net.ErrClosed) {
+		l.slave.logger.Debug(T("BACnet 回應失敗"), zap.String("addr", l.addr), zap.Error(err))
+	}
+}
+
+// --- 探索 ---
+
+// bacnetDiscovery 接收 Who-Is 廣播並交由各 Slave 以自己的 IP 回覆 I-Am
+// (綁定單一 IP 的 socket 收不到廣播，因此由引擎統一在廣播位址上接收)
+type bacnetDiscovery struct {
+	engine *Engine
+	logger *zap.Logger
+	conns  []net.PacketConn
+	wg     sync.WaitGroup
+}
+
+// startBACnetDiscovery 在各廣播位址上接收 Who-Is
+func (e *Engine) startBACnetDiscovery() error {
+	d := &bacnetDiscovery{engine: e, logger: e.logger.With(zap.String("component", "bacnet"))}
+	for _, ip := range e.config.BACnet.Broadcast {
+		addr := net.JoinHostPort(ip, fmt.Sprint(e.config.BACnet.Port))
+		conn, err := net.ListenPacket("udp4", addr)
+		if err != nil {
+			d.close()
+			return fmt.Errorf(T("監聽 %s 失敗: %w"), addr, err)
+		}
+		d.conns = append(d.conns, conn)
+	}
+	for _, conn := range d.conns {
+		d.wg.Add(1)
+		go d.serve(conn)
+	}
+	e.mu.Lock()
+	e.bacnet = d
+	e.mu.Unlock()
+	return nil
+}
+
+// stopBACnetDiscovery 停止接收 Who-Is 廣播
+func (e *Engine) stopBACnetDiscovery() {
+	e.mu.Lock()
+	d := e.bacnet
+	e.bacnet = nil
+	e.mu.Unlock()
+	if d != nil {
+		d.close()
+	}
+}
+
+func (d *bacnetDiscovery) close() {
+	for _, conn := range d.conns {
+		conn.Close()
+	}
+	d.wg.Wait()
+}
+
+func (d *bacnetDiscovery) serve(conn net.PacketConn) {
+	defer d.wg.Done()
+
+	buf := make([]byte, bacnetMaxFrame)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				d.logger.Warn(T("BACnet 接收封包失敗"), zap.String("addr", conn.LocalAddr().String()), zap.Error(err))
+			}
+			return
+		}
+		frame, ok := parseBACnetFrame(buf[:n], from)
+		if !ok || len(frame.apdu) < 2 || frame.apdu[0]>>4 != bacnetUnconfirmedRequest || frame.apdu[1] != bacnetServiceWhoIs {
+			continue
+		}
+		for _, slave := range d.engine.ListSlaves() {
+			if l := slave.bacnet(); l != nil && !slave.silentStandby.Load() {
+				l.answerWhoIs(frame)
+			}
+		}
+	}
+}
+
+// --- 封包編碼 ---
+
+// bacnetFrame 解析後的 BACnet/IP 封包
+type bacnetFrame struct {
+	source net.Addr // 回應的對象 (Forwarded-NPDU 時為原始來源)
+	snet   uint16   // 經路由器轉送時的來源網路 (0 表示本地網路)
+	sadr   []byte
+	apdu   []byte
+}
+
+// parseBACnetFrame 解析 BVLC 與 NPDU (網路層訊息與送往其他網路的封包忽略)
+func parseBACnetFrame(b []byte, from net.Addr) (bacnetFrame, bool) {
+	if len(b) < 4 || b[0] != bvlcType || int(binary.BigEndian.Uint16(b[2:])) != len(b) {
+		return bacnetFrame{}, false
+	}
+	frame := bacnetFrame{source: from}
+	switch b[1] {
+	case bvlcOriginalUnicast, bvlcOriginalBroadcast:
+		b = b[4:]
+	case bvlcForwardedNPDU:
+		if len(b) < 10 {
+			return bacnetFrame{}, false
+		}
+		frame.source = &net.UDPAddr{IP: net.IP(append([]byte(nil), b[4:8]...)), Port: int(binary.BigEndian.Uint16(b[8:]))}
+		b = b[10:]
+	default:
+		return bacnetFrame{}, false
+	}
+
+	if len(b) < 2 || b[0] != 0x01 || b[1]&0x80 != 0 {
+		return bacnetFrame{}, false
+	}
+	control := b[1]
+	b = b[2:]
+	if control&0x20 != 0 {
+		if len(b) < 3 || len(b) < 3+int(b[2]) {
+			return bacnetFrame{}, false
+		}
+		if dnet := binary.BigEndian.Uint16(b); dnet != 0xFFFF {
+			return bacnetFrame{}, false
+		}
+		b = b[3+int(b[2]):]
+	}
+	if control&0x08 != 0 {
+		if len(b) < 3 || len(b) < 3+int(b[2]) {
+			return bacnetFrame{}, false
+		}
+		frame.snet = binary.BigEndian.Uint16(b)
+		frame.sadr = append([]byte(nil), b[3:3+int(b[2])]...)
+		b = b[3+int(b[2]):]
+	}
+	if control&0x20 != 0 {
+		if len(b) < 1 {
+			return bacnetFrame{}, false
+		}
+		b = b[1:] // hop count
+	}
+	if len(b) == 0 {
+		return bacnetFrame{}, false
+	}
+	frame.apdu = b
+	return frame, true
+}
+
+// encodeBACnetFrame 回應的 BVLC (Original-Unicast-NPDU) 與 NPDU
+func encodeBACnetFrame(request bacnetFrame, apdu []byte) []byte {
+	b := []byte{bvlcType, bvlcOriginalUnicast, 0, 0, 0x01}
+	if request.snet != 0 {
+		b = append(b, 0x20)
+		b = binary.BigEndian.AppendUint16(b, request.snet)
+		b = append(b, byte(len(request.sadr)))
+		b = append(b, request.sadr...)
+		b = append(b, 0xFF) // hop count
+	} else {
+		b = append(b, 0x00)
+	}
+	b = append(b, apdu...)
+	binary.BigEndian.PutUint16(b[2:], uint16(len(b)))
+	return b
+}
+
+// bacnetTag 標籤 (lvt 為長度/值/類型欄位，context 標籤的 6/7 為開始/結束標記)
+type bacnetTag struct {
+	number  byte
+	context bool
+	lvt     byte
+}
+
+// decodeBACnetTag 解析一個標籤與其內容
+func decodeBACnetTag(b []byte) (tag bacnetTag, data, rest []byte, ok bool) {
+	if len(b) == 0 {
+		return tag, nil, nil, false
+	}
+	tag = bacnetTag{number: b[0] >> 4, context: b[0]&0x08 != 0, lvt: b[0] & 0x07}
+	b = b[1:]
+	if tag.number == 15 {
+		if len(b) == 0 {
+			return tag, nil, nil, false
+		}
+		tag.number, b = b[0], b[1:]
+	}
+	if tag.context && tag.lvt >= 6 || !tag.context && tag.number == 1 {
+		return tag, nil, b, true // 開始/結束標記與布林值沒有內容
+	}
+	length := int(tag.lvt)
+	if tag.lvt == 5 {
+		if len(b) == 0 {
+			return tag, nil, nil, false
+		}
+		length, b = int(b[0]), b[1:]
+		switch {
+		case length == 254 && len(b) >= 2:
+			length, b = int(binary.BigEndian.Uint16(b)), b[2:]
+		case length == 255 && len(b) >= 4:
+			length, b = int(binary.BigEndian.Uint32(b)), b[4:]
+		case length >= 254:
+			return tag, nil, nil, false
+		}
+	}
+	if len(b) < length {
+		return tag, nil, nil, false
+	}
+	return tag, b[:length], b[length:], true
+}
+
+// decodeBACnetUnsigned 解析 1-4 bytes 的無號整數
+func decodeBACnetUnsigned(data []byte) (uint32, bool) {
+	if len(data) == 0 || len(data) > 4 {
+		return 0, false
+	}
+	var v uint32
+	for _, c := range data {
+		v = v<<8 | uint32(c)
+	}
+	return v, true
+}
+
+// contextUnsigned 解析指定編號的 context 標籤無號整數
+func contextUnsigned(b []byte, number byte) (uint32, []byte, bool) {
+	tag, data, rest, ok := decodeBACnetTag(b)
+	if !ok || !tag.context || tag.number != number {
+		return 0, nil, false
+	}
+	v, ok := decodeBACnetUnsigned(data)
+	return v, rest, ok
+}
+
+// parseBACnetWhoIs Who-Is 的 instance 範圍 (未指定時為全部)
+func parseBACnetWhoIs(body []byte) (low, high uint32, ok bool) {
+	if len(body) == 0 {
+		return 0, bacnetMaxInstance, true
+	}
+	low, rest, ok := contextUnsigned(body, 0)
+	if !ok {
+		return 0, 0, false
+	}
+	high, rest, ok = contextUnsigned(rest, 1)
+	return low, high, ok && len(rest) == 0
+}
+
+// bacnetReadProperty ReadProperty 請求
+type bacnetReadProperty struct {
+	object   uint32
+	property uint32
+	index    uint32
+	hasIndex bool
+}
+
+// parseBACnetReadProperty 解析 ReadProperty 請求 (物件識別碼、屬性識別碼與選用的 array index)
+func parseBACnetReadProperty(body []byte) (req bacnetReadProperty, ok bool) {
+	tag, data, rest, ok := decodeBACnetTag(body)
+	if !ok || !tag.context || tag.number != 0 || len(data) != 4 {
+		return req, false
+	}
+	req.object = binary.BigEndian.Uint32(data)
+	if req.property, rest, ok = contextUnsigned(rest, 1); !ok {
+		return req, false
+	}
+	if len(rest) > 0 {
+		if req.index, rest, ok = contextUnsigned(rest, 2); !ok {
+			return req, false
+		}
+		req.hasIndex = true
+	}
+	return req, len(rest) == 0
+}
+
+// appendBACnetTag 附加標籤 (class 為 context 時 context 為 true)
+func appendBACnetTag(b []byte, number byte, context bool, length int) []byte {
+	first := number << 4
+	if context {
+		first |= 0x08
+	}
+	switch {
+	case length < 5:
+		return append(b, first|byte(length))
+	case length < 254:
+		return append(b, first|5, byte(length))
+	default:
+		return binary.BigEndian.AppendUint16(append(b, first|5, 254), uint16(length))
+	}
+}
+
+// appendBACnetUnsigned 以最少的 bytes 附加無號整數
+func appendBACnetUnsigned(b []byte, number byte, context bool, v uint32) []byte {
+	n := 1
+	for n < 4 && v>>(8*n) != 0 {
+		n++
+	}
+	b = appendBACnetTag(b, number, context, n)
+	for i := n - 1; i >= 0; i-- {
+		b = append(b, byte(v>>(8*i)))
+	}
+	return b
+}
+
+// appendBACnetEnumerated 附加列舉值 (應用標籤 9)
+func appendBACnetEnumerated(b []byte, v uint32) []byte {
+	return appendBACnetUnsigned(b, 9, false, v)
+}
+
+// appendBACnetObjectID 附加物件識別碼
+func appendBACnetObjectID(b []byte, number byte, context bool, id uint32) []byte {
+	b = appendBACnetTag(b, number, context, 4)
+	return binary.BigEndian.AppendUint32(b, id)
+}
+
+// appendBACnetString 附加 UTF-8 字串 (應用標籤 7，字元集 0)
+func appendBACnetString(b []byte, s string) []byte {
+	b = appendBACnetTag(b, 7, false, 1+len(s))
+	return append(append(b, 0), s...)
+}
+
+// appendBACnetBitString 附加 n 位元的位元字串 (應用標籤 8)，set 為設為 1 的位元
+func appendBACnetBitString(b []byte, n int, set ...int) []byte {
+	bits := make([]byte, (n+7)/8)
+	for _, i := range set {
+		bits[i/8] |= 0x80 >> (i % 8)
+	}
+	b = appendBACnetTag(b, 8, false, 1+len(bits))
+	b = append(b, byte(len(bits)*8-n))
+	return append(b, bits...)
+}
+
+// --- 配置 ---
+
+// Validate 驗證 BACnet/IP 設備模式設定
+func (c *BACnetConfig) Validate(tags map[string][]string) error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		return fmt.Errorf(T("BACnet 監聽埠無效: %d"), c.Port)
+	}
+	if c.InstanceBase < 0 || c.InstanceBase > bacnetMaxInstance-0xFFFF {
+		return fmt.Errorf(T("BACnet 設備 instance 起始值無效: %d (需介於 0-%d)"), c.InstanceBase, bacnetMaxInstance-0xFFFF)
+	}
+	for _, addr := range c.Broadcast {
+		if ip := net.ParseIP(addr); ip == nil || ip.To4() == nil {
+			return fmt.Errorf(T("BACnet 廣播位址無效: %s"), addr)
+		}
+	}
+	for _, target := range c.Targets {
+		if net.ParseIP(target) == nil {
+			if _, _, err := net.ParseCIDR(target); err != nil {
+				return fmt.Errorf(T("BACnet 設備的目標無效: %s"), target)
+			}
+		}
+	}
+	for _, tag := range c.Tags {
+		if _, ok := tags[tag]; !ok {
+			return fmt.Errorf(T("BACnet 設備使用未定義的 Slave 標籤: %s"), tag)
+		}
+	}
+	return nil
+}
+
+// serves Slave 是否提供 BACnet (targets 與 tags 皆空表示全部 Slave)
+func (c *BACnetConfig) serves(config *Config, ip net.IP) bool {
+	if !c.Enabled {
+		return false
+	}
+	if len(c.Targets) == 0 && len(c.Tags) == 0 {
+		return true
+	}
+	if len(c.Targets) > 0 && MatchTargets(ip, c.Targets) {
+		return true
+	}
+	for _, tag := range c.Tags {
+		if config.hasTag(ip, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// deviceInstance Slave 的設備 instance (instance_base 加上 IP 位址的末 16 位元)
+func (c *BACnetConfig) deviceInstance(ip net.IP) uint32 {
+	ip16 := ip.To16()
+	if ip16 == nil {
+		return uint32(c.InstanceBase)
+	}
+	return uint32(c.InstanceBase) + uint32(binary.BigEndian.Uint16(ip16[14:]))
+}
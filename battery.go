@@ -0,0 +1,89 @@
+package main
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// 儲能系統 (BESS) 暫存器位址
+const (
+	AddrBatterySoC      uint16 = 40020 // 荷電狀態 (%)
+	AddrBatteryPower    uint16 = 40021 // 實際充放電功率 (W，正值充電、負值放電)
+	AddrBatterySetpoint uint16 = 40023 // 充放電功率設定值 (W，可寫入)
+	AddrBatteryCycles   uint16 = 40025 // 循環次數
+	AddrBatteryCapacity uint16 = 40027 // 額定容量 (kWh)
+	AddrBatteryMaxPower uint16 = 40028 // 最大充放電功率 (W)
+)
+
+// ProfileBattery 儲能系統設定檔名稱
+const ProfileBattery = "battery"
+
+// 儲能系統預設規格
+const (
+	defaultBatteryCapacityKWh = 100.0
+	defaultBatteryMaxPowerW   = 50000.0
+)
+
+func init() {
+	RegisterDeviceProfile(&DeviceProfile{
+		Name:        ProfileBattery,
+		Description: "儲能系統 (單相暫存器 + SoC、充放電功率、功率設定值、循環次數)",
+		Registers: append(singlePhaseRegisters(),
+			RegisterDefinition{Address: AddrBatterySoC, Name: "SoC", DataType: "uint16", Scale: 10, DefaultValue: 50.0, Unit: "%"},
+			RegisterDefinition{Address: AddrBatteryPower, Name: "BatteryPower", DataType: "int32", Scale: 1, DefaultValue: 0, Unit: "W"},
+			RegisterDefinition{Address: AddrBatterySetpoint, Name: "ChargeSetpoint", DataType: "int32", Scale: 1, DefaultValue: 0, Unit: "W", Writable: true},
+			RegisterDefinition{Address: AddrBatteryCycles, Name: "CycleCount", DataType: "uint32", Scale: 10, DefaultValue: 0, Unit: ""},
+			RegisterDefinition{Address: AddrBatteryCapacity, Name: "Capacity", DataType: "uint16", Scale: 1, DefaultValue: defaultBatteryCapacityKWh, Unit: "kWh"},
+			RegisterDefinition{Address: AddrBatteryMaxPower, Name: "MaxPower", DataType: "uint32", Scale: 1, DefaultValue: defaultBatteryMaxPowerW, Unit: "W"},
+		),
+		NewModel: func() DeviceModel { return &BatteryModel{} },
+	})
+}
+
+// BatteryModel 儲能系統模型：依功率設定值充放電，SoC 隨時間積分並限制在 0-100%
+type BatteryModel struct {
+	lastUpdate time.Time
+	cycles     float64
+	reload     atomic.Bool // 快照還原後，下次更新自暫存器重新載入循環次數
+}
+
+// Update 依經過時間推進 SoC 與循環次數
+func (m *BatteryModel) Update(registers *RegisterMap, now time.Time) {
+	if m.lastUpdate.IsZero() || m.reload.Swap(false) {
+		m.lastUpdate = now
+		m.cycles, _ = registers.GetScaledValue(AddrBatteryCycles)
+		return
+	}
+	hours := now.Sub(m.lastUpdate).Hours()
+	m.lastUpdate = now
+
+	soc, _ := registers.GetScaledValue(AddrBatterySoC)
+	setpoint, _ := registers.GetScaledValue(AddrBatterySetpoint)
+	capacity, _ := registers.GetScaledValue(AddrBatteryCapacity)
+	maxPower, _ := registers.GetScaledValue(AddrBatteryMaxPower)
+	if capacity <= 0 {
+		capacity = defaultBatteryCapacityKWh
+	}
+
+	// 設定值限制在額定功率內；滿充/放空時不再充/放
+	power := math.Max(-maxPower, math.Min(maxPower, setpoint))
+	if (power > 0 && soc >= 100) || (power < 0 && soc <= 0) {
+		power = 0
+	}
+
+	newSoC := soc + power*hours/1000/capacity*100
+	newSoC = math.Max(0, math.Min(100, newSoC))
+
+	// 一次完整循環 = 充電 100% + 放電 100%
+	m.cycles += math.Abs(newSoC-soc) / 200
+
+	registers.SetScaledValue(AddrBatterySoC, newSoC)
+	registers.SetScaledValue(AddrBatteryPower, power)
+	registers.SetScaledValue(AddrBatteryCycles, m.cycles)
+}
+
+// RestoreState 快照還原後自暫存器重新載入循環次數
+func (m *BatteryModel) RestoreState(registers *RegisterMap) {
+	m.reload.Store(true)
+}
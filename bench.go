@@ -0,0 +1,401 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goburrow/modbus"
+)
+
+// 負載測試預設值
+const (
+	DefaultBenchConcurrency = 10
+	DefaultBenchDuration    = 10 * time.Second
+	DefaultBenchTimeout     = time.Second
+	DefaultBenchQuantity    = 10
+	DefaultBenchMix         = "3=100"
+)
+
+// BenchConfig 負載測試配置
+type BenchConfig struct {
+	Targets     []string       // 目標 host:port (工作者依序分配)
+	Concurrency int            // 同時連線的工作者數 (每個工作者一條連線)
+	Rate        float64        // 全部工作者合計每秒請求數 (0 = 不限速)
+	Duration    time.Duration  // 測試時間
+	Requests    int            // 請求總數上限 (0 = 依測試時間)
+	Timeout     time.Duration  // 單次請求逾時
+	Unit        uint8          // Unit ID
+	Address     uint16         // 起始位址 (協定位址，0 起算)
+	Quantity    uint16         // 每次讀寫的數量
+	Mix         map[uint8]uint // 功能碼權重
+	Seed        int64          // 功能碼選擇的亂數種子 (0 = 依時間)
+}
+
+// ParseBenchMix 解析功能碼權重 (例如 "3=70,4=20,16=10")
+func ParseBenchMix(s string) (map[uint8]uint, error) {
+	mix := make(map[uint8]uint)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		fc, weight, ok := strings.Cut(part, "=")
+		if !ok {
+			weight = "1"
+		}
+		code, err := strconv.ParseUint(strings.TrimSpace(fc), 10, 8)
+		if err != nil || !isBenchFunction(uint8(code)) {
+			return nil, fmt.Errorf(T("不支援的功能碼: %s"), fc)
+		}
+		w, err := strconv.ParseUint(strings.TrimSpace(weight), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf(T("功能碼權重無效: %s"), part)
+		}
+		mix[uint8(code)] += uint(w)
+	}
+	total := uint(0)
+	for _, w := range mix {
+		total += w
+	}
+	if total == 0 {
+		return nil, errors.New(T("功能碼權重總和必須大於 0"))
+	}
+	return mix, nil
+}
+
+// isBenchFunction 負載測試支援的功能碼
+func isBenchFunction(fc uint8) bool {
+	switch fc {
+	case FuncCodeReadCoils, FuncCodeReadDiscreteInputs, FuncCodeReadHoldingRegisters, FuncCodeReadInputRegisters,
+		FuncCodeWriteSingleCoil, FuncCodeWriteSingleRegister, FuncCodeWriteMultipleCoils, FuncCodeWriteMultipleRegisters:
+		return true
+	}
+	return false
+}
+
+// Validate 驗證並補上預設值
+func (c *BenchConfig) Validate() error {
+	if len(c.Targets) == 0 {
+		return errors.New(T("未指定目標"))
+	}
+	for _, target := range c.Targets {
+		if _, _, err := net.SplitHostPort(target); err != nil {
+			return fmt.Errorf(T("目標位址無效: %s"), target)
+		}
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = DefaultBenchConcurrency
+	}
+	if c.Duration <= 0 && c.Requests <= 0 {
+		c.Duration = DefaultBenchDuration
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultBenchTimeout
+	}
+	if c.Rate < 0 {
+		return fmt.Errorf(T("請求速率不可為負: %v"), c.Rate)
+	}
+	if c.Quantity == 0 {
+		c.Quantity = DefaultBenchQuantity
+	}
+	if c.Quantity > 123 {
+		return fmt.Errorf(T("每次讀寫數量必須介於 1-123: %d"), c.Quantity)
+	}
+	if len(c.Mix) == 0 {
+		c.Mix, _ = ParseBenchMix(DefaultBenchMix)
+	}
+	return nil
+}
+
+// LatencySummary 延遲統計
+type LatencySummary struct {
+	Min  time.Duration `json:"min"`
+	Mean time.Duration `json:"mean"`
+	P50  time.Duration `json:"p50"`
+	P90  time.Duration `json:"p90"`
+	P99  time.Duration `json:"p99"`
+	P999 time.Duration `json:"p999"`
+	Max  time.Duration `json:"max"`
+}
+
+// summarizeLatency 計算延遲統計 (latencies 會被排序)
+func summarizeLatency(latencies []time.Duration) LatencySummary {
+	if len(latencies) == 0 {
+		return LatencySummary{}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+	}
+	percentile := func(p float64) time.Duration {
+		return latencies[min(int(p*float64(len(latencies))), len(latencies)-1)]
+	}
+	return LatencySummary{
+		Min:  latencies[0],
+		Mean: total / time.Duration(len(latencies)),
+		P50:  percentile(0.50),
+		P90:  percentile(0.90),
+		P99:  percentile(0.99),
+		P999: percentile(0.999),
+		Max:  latencies[len(latencies)-1],
+	}
+}
+
+// BenchFunctionReport 單一功能碼的結果
+type BenchFunctionReport struct {
+	Function uint8          `json:"function"`
+	Requests int            `json:"requests"`
+	Errors   int            `json:"errors"`
+	Latency  LatencySummary `json:"latency"` // 僅含成功的請求
+}
+
+// BenchReport 負載測試結果
+type BenchReport struct {
+	Targets     []string              `json:"targets"`
+	Concurrency int                   `json:"concurrency"`
+	Elapsed     time.Duration         `json:"elapsed"`
+	Requests    int                   `json:"requests"`
+	Errors      int                   `json:"errors"`
+	Throughput  float64               `json:"throughput"` // 每秒完成的請求數
+	Latency     LatencySummary        `json:"latency"`    // 僅含成功的請求
+	ErrorKinds  map[string]int        `json:"error_kinds,omitempty"`
+	Functions   []BenchFunctionReport `json:"functions"`
+}
+
+// benchResult 單一工作者的結果
+type benchResult struct {
+	latencies map[uint8][]time.Duration
+	requests  map[uint8]int
+	errors    map[uint8]int
+	kinds     map[string]int
+}
+
+// RunBench 以多條連線對目標發送請求直到測試時間結束、達到請求總數或 ctx 取消
+func RunBench(ctx context.Context, cfg BenchConfig) (*BenchReport, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if cfg.Duration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+
+	// 依權重展開的功能碼表
+	var functions []uint8
+	for fc, weight := range cfg.Mix {
+		for i := uint(0); i < weight; i++ {
+			functions = append(functions, fc)
+		}
+	}
+	sort.Slice(functions, func(i, j int) bool { return functions[i] < functions[j] })
+
+	// 請求總數以共用配額控制
+	var quota *atomic.Int64
+	if cfg.Requests > 0 {
+		quota = new(atomic.Int64)
+		quota.Store(int64(cfg.Requests))
+	}
+
+	results := make([]benchResult, cfg.Concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = start.UnixNano()
+	}
+	for i := 0; i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := benchWorker{
+				cfg:       cfg,
+				target:    cfg.Targets[i%len(cfg.Targets)],
+				functions: functions,
+				random:    rand.New(rand.NewSource(seed + int64(i))),
+				quota:     quota,
+			}
+			if cfg.Rate > 0 {
+				w.interval = time.Duration(float64(time.Second) * float64(cfg.Concurrency) / cfg.Rate)
+				// 錯開各工作者的起始時間，避免同時送出
+				w.next = start.Add(w.interval * time.Duration(i) / time.Duration(cfg.Concurrency))
+			}
+			results[i] = w.run(ctx)
+		}(i)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	report := &BenchReport{
+		Targets:     cfg.Targets,
+		Concurrency: cfg.Concurrency,
+		Elapsed:     elapsed,
+		ErrorKinds:  make(map[string]int),
+		Functions:   []BenchFunctionReport{},
+	}
+	var all []time.Duration
+	byFunction := make(map[uint8]*BenchFunctionReport)
+	latencies := make(map[uint8][]time.Duration)
+	for _, r := range results {
+		for fc, n := range r.requests {
+			f, ok := byFunction[fc]
+			if !ok {
+				f = &BenchFunctionReport{Function: fc}
+				byFunction[fc] = f
+			}
+			f.Requests += n
+			f.Errors += r.errors[fc]
+			report.Requests += n
+			report.Errors += r.errors[fc]
+			latencies[fc] = append(latencies[fc], r.latencies[fc]...)
+			all = append(all, r.latencies[fc]...)
+		}
+		for kind, n := range r.kinds {
+			report.ErrorKinds[kind] += n
+		}
+	}
+	for fc, f := range byFunction {
+		f.Latency = summarizeLatency(latencies[fc])
+		report.Functions = append(report.Functions, *f)
+	}
+	sort.Slice(report.Functions, func(i, j int) bool { return report.Functions[i].Function < report.Functions[j].Function })
+	report.Latency = summarizeLatency(all)
+	if elapsed > 0 {
+		report.Throughput = float64(report.Requests) / elapsed.Seconds()
+	}
+	return report, nil
+}
+
+// benchWorker 單一連線的負載產生器
+type benchWorker struct {
+	cfg       BenchConfig
+	target    string
+	functions []uint8
+	random    *rand.Rand
+	quota     *atomic.Int64 // 剩餘請求數 (nil = 不限)
+	interval  time.Duration // 每個工作者的請求間隔 (0 = 不限速)
+	next      time.Time
+}
+
+func (w *benchWorker) run(ctx context.Context) benchResult {
+	result := benchResult{
+		latencies: make(map[uint8][]time.Duration),
+		requests:  make(map[uint8]int),
+		errors:    make(map[uint8]int),
+		kinds:     make(map[string]int),
+	}
+
+	handler := modbus.NewTCPClientHandler(w.target)
+	handler.Timeout = w.cfg.Timeout
+	handler.SlaveId = w.cfg.Unit
+	defer handler.Close()
+	client := modbus.NewClient(handler)
+
+	for {
+		if w.quota != nil && w.quota.Add(-1) < 0 {
+			return result
+		}
+		if w.interval > 0 {
+			// 依排程時間送出 (落後時立即追上，不累積等待)
+			if wait := time.Until(w.next); wait > 0 {
+				select {
+				case <-ctx.Done():
+					return result
+				case <-time.After(wait):
+				}
+			}
+			w.next = w.next.Add(w.interval)
+		}
+		if ctx.Err() != nil {
+			return result
+		}
+
+		fc := w.functions[w.random.Intn(len(w.functions))]
+		begin := time.Now()
+		err := w.request(client, fc)
+		latency := time.Since(begin)
+
+		// 測試結束時中斷的請求不列入
+		if err != nil && ctx.Err() != nil {
+			return result
+		}
+		result.requests[fc]++
+		if err != nil {
+			result.errors[fc]++
+			result.kinds[benchErrorKind(err)]++
+			var modbusErr *modbus.ModbusError
+			if !errors.As(err, &modbusErr) {
+				// 逾時或連線錯誤後連線內容可能錯位，重新連線
+				handler.Close()
+			}
+			continue
+		}
+		result.latencies[fc] = append(result.latencies[fc], latency)
+	}
+}
+
+// request 送出一個指定功能碼的請求
+func (w *benchWorker) request(client modbus.Client, fc uint8) error {
+	address, quantity := w.cfg.Address, w.cfg.Quantity
+	var err error
+	switch fc {
+	case FuncCodeReadCoils:
+		_, err = client.ReadCoils(address, quantity)
+	case FuncCodeReadDiscreteInputs:
+		_, err = client.ReadDiscreteInputs(address, quantity)
+	case FuncCodeReadHoldingRegisters:
+		_, err = client.ReadHoldingRegisters(address, quantity)
+	case FuncCodeReadInputRegisters:
+		_, err = client.ReadInputRegisters(address, quantity)
+	case FuncCodeWriteSingleCoil:
+		value := uint16(0)
+		if w.random.Intn(2) == 1 {
+			value = 0xff00
+		}
+		_, err = client.WriteSingleCoil(address, value)
+	case FuncCodeWriteSingleRegister:
+		_, err = client.WriteSingleRegister(address, uint16(w.random.Intn(1<<16)))
+	case FuncCodeWriteMultipleCoils:
+		values := make([]byte, (quantity+7)/8)
+		w.random.Read(values)
+		_, err = client.WriteMultipleCoils(address, quantity, values)
+	case FuncCodeWriteMultipleRegisters:
+		values := make([]byte, 2*quantity)
+		w.random.Read(values)
+		_, err = client.WriteMultipleRegisters(address, quantity, values)
+	}
+	return err
+}
+
+// benchErrorKind 錯誤分類 (例外回應依例外碼，其餘為逾時或連線錯誤)
+func benchErrorKind(err error) string {
+	var modbusErr *modbus.ModbusError
+	if errors.As(err, &modbusErr) {
+		return fmt.Sprintf("exception:%d", modbusErr.ExceptionCode)
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return "timeout"
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return "timeout"
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return "connection"
+	}
+	return "protocol"
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// BindConflictError 監聽位址已被其他 socket 占用
+type BindConflictError struct {
+	Addr   string
+	Holder string // 占用的程序 (例如 "nginx (pid 1234)")，無法查得時為空
+	Err    error
+}
+
+func (e *BindConflictError) Error() string {
+	if e.Holder != "" {
+		return fmt.Sprintf(T("位址 %s 已被占用 (%s)"), e.Addr, e.Holder)
+	}
+	return fmt.Sprintf(T("位址 %s 已被占用"), e.Addr)
+}
+
+func (e *BindConflictError) Unwrap() error {
+	return e.Err
+}
+
+// isAddrInUse 錯誤是否為位址已被占用 (EADDRINUSE)
+func isAddrInUse(err error) bool {
+	return errors.Is(err, syscall.EADDRINUSE)
+}
+
+// newBindConflictError 建立綁定衝突錯誤並盡可能查出占用的程序
+func newBindConflictError(addr string, err error) *BindConflictError {
+	conflict := &BindConflictError{Addr: addr, Err: err}
+	if host, portStr, splitErr := net.SplitHostPort(addr); splitErr == nil {
+		if port, convErr := strconv.Atoi(portStr); convErr == nil {
+			conflict.Holder = findListenerHolder(net.ParseIP(host), port)
+		}
+	}
+	return conflict
+}
+
+// pendingBind 因綁定衝突等待重試的 Slave
+type pendingBind struct {
+	ip       net.IP
+	index    int
+	attempts int
+}
+
+// recordBindConflict 記錄綁定衝突 (計數並輸出可辨識的警告)
+func (e *Engine) recordBindConflict(ip net.IP, conflict *BindConflictError) {
+	e.bindConflicts.Add(1)
+	e.logger.Warn(T("監聽位址衝突"),
+		zap.String("ip", ip.String()),
+		zap.String("addr", conflict.Addr),
+		zap.String("holder", conflict.Holder),
+	)
+}
+
+// BindPending 因綁定衝突等待重試的 Slave 數
+func (e *Engine) BindPending() int {
+	return int(e.bindPending.Load())
+}
+
+// runBindRetry 定期重試因綁定衝突而未啟動的 Slave，直到成功或達到次數上限 (呼叫端需先設定 e.bindPending)
+func (e *Engine) runBindRetry(ctx context.Context, pending []*pendingBind) {
+	interval := e.config.Server.BindRetryInterval
+	maxAttempts := e.config.Server.BindRetryMax
+
+	sort.Slice(pending, func(i, j int) bool { return pending[i].index < pending[j].index })
+	defer e.bindPending.Store(0)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for len(pending) > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		remaining := pending[:0]
+		for _, p := range pending {
+			p.attempts++
+			slave, err := e.startSlave(ctx, p.ip, p.index)
+			if err == nil {
+				if !e.addRetriedSlave(ctx, slave) {
+					return
+				}
+				e.applyCurrentScenario(slave)
+				e.logger.Info(T("監聽位址衝突已解除"),
+					zap.String("ip", p.ip.String()),
+					zap.Int("attempts", p.attempts),
+				)
+				continue
+			}
+
+			var conflict *BindConflictError
+			if errors.As(err, &conflict) {
+				e.bindConflicts.Add(1)
+			}
+			if maxAttempts > 0 && p.attempts >= maxAttempts {
+				e.logger.Warn(T("放棄重試綁定"),
+					zap.String("ip", p.ip.String()),
+					zap.Int("attempts", p.attempts),
+					zap.Error(err),
+				)
+				continue
+			}
+			remaining = append(remaining, p)
+		}
+		pending = remaining
+		e.bindPending.Store(int64(len(pending)))
+	}
+}
+
+// addRetriedSlave 將重試成功或逐步上線的 Slave 加入引擎並設定其主備角色；引擎已在停止中時停止該 Slave 並回傳 false
+func (e *Engine) addRetriedSlave(ctx context.Context, slave *Slave) bool {
+	e.mu.Lock()
+	if ctx.Err() != nil {
+		e.mu.Unlock()
+		slave.Stop(context.Background())
+		return false
+	}
+	e.slaves[slave.ID] = slave
+	e.stats.SlaveCount++
+	e.stats.ActiveSlaves++
+	e.mu.Unlock()
+
+	e.applyPairRole(slave)
+	return true
+}
@@ -0,0 +1,104 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tcpStateListen /proc/net/tcp 中 LISTEN 狀態的代碼
+const tcpStateListen = "0A"
+
+// findListenerHolder 由 /proc/net/tcp{,6} 與 /proc/<pid>/fd 找出監聽指定位址的程序
+// (權限不足或查無結果時回傳空字串)
+func findListenerHolder(ip net.IP, port int) string {
+	var inodes []string
+	for _, path := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		inodes = append(inodes, listeningInodes(path, ip, port)...)
+	}
+	if len(inodes) == 0 {
+		return ""
+	}
+
+	wanted := make(map[string]bool, len(inodes))
+	for _, inode := range inodes {
+		wanted["socket:["+inode+"]"] = true
+	}
+
+	procs, _ := filepath.Glob("/proc/[0-9]*")
+	for _, proc := range procs {
+		fds, err := os.ReadDir(filepath.Join(proc, "fd"))
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(proc, "fd", fd.Name()))
+			if err != nil || !wanted[target] {
+				continue
+			}
+			pid := filepath.Base(proc)
+			comm, _ := os.ReadFile(filepath.Join(proc, "comm"))
+			if name := strings.TrimSpace(string(comm)); name != "" {
+				return fmt.Sprintf("%s (pid %s)", name, pid)
+			}
+			return "pid " + pid
+		}
+	}
+	return ""
+}
+
+// listeningInodes 取得監聽 ip:port (含萬用位址) 的 socket inode
+func listeningInodes(path string, ip net.IP, port int) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var inodes []string
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // 標題列
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != tcpStateListen {
+			continue
+		}
+		localIP, localPort, ok := parseProcNetAddr(fields[1])
+		if !ok || localPort != port {
+			continue
+		}
+		if ip == nil || localIP.IsUnspecified() || localIP.Equal(ip) {
+			inodes = append(inodes, fields[9])
+		}
+	}
+	return inodes
+}
+
+// parseProcNetAddr 解析 /proc/net/tcp 的位址欄位 (十六進位，IP 以 32 位元字為單位採主機位元組序)
+func parseProcNetAddr(s string) (net.IP, int, bool) {
+	hexIP, hexPort, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, 0, false
+	}
+	raw, err := hex.DecodeString(hexIP)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return nil, 0, false
+	}
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return nil, 0, false
+	}
+
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+	return ip, int(port), true
+}
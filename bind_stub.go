@@ -0,0 +1,24 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// findListenerHolder 非 Linux 平台無法查詢占用位址的程序
+func findListenerHolder(ip net.IP, port int) string {
+	return ""
+}
+
+// bindToDevice 非 Linux 平台不支援 SO_BINDTODEVICE，僅依 IP 綁定
+func bindToDevice(name string) func(network, address string, c syscall.RawConn) error {
+	return nil
+}
+
+// originalDst 非 Linux 平台不支援 SO_ORIGINAL_DST
+func originalDst(conn net.Conn) (net.IP, error) {
+	return nil, errors.New(T("SO_ORIGINAL_DST 僅在 Linux 上支援"))
+}
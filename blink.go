@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 識別閃爍預設值
+const (
+	DefaultBlinkRegister uint16 = 40110 // 未被設定檔與 long_command、時鐘、需量、費率等預設暫存器使用的保持暫存器
+	DefaultBlinkDuration        = 60 * time.Second
+	DefaultBlinkPeriod          = time.Second
+)
+
+// DefaultBlinkPattern 預設閃爍樣式 (交替 0xAAAA / 0x5555)
+var DefaultBlinkPattern = []uint16{0xAAAA, 0x5555}
+
+// BlinkOptions 識別閃爍參數
+type BlinkOptions struct {
+	Duration time.Duration
+	Period   time.Duration
+	Register *uint16 // 閃爍的保持暫存器位址，nil 表示 DefaultBlinkRegister
+	Pattern  []uint16
+	Coil     *uint16 // 週期切換的線圈位址，nil 表示不切換
+}
+
+// BlinkStatus 識別閃爍狀態
+type BlinkStatus struct {
+	Slave    string    `json:"slave"`
+	Register uint16    `json:"register"`
+	Pattern  []uint16  `json:"pattern"`
+	Coil     int       `json:"coil"` // -1 表示不切換
+	Until    time.Time `json:"until"`
+}
+
+// blinkState 識別閃爍的執行期狀態
+type blinkState struct {
+	opts      BlinkOptions
+	until     time.Time
+	step      int
+	savedWord uint16
+	savedCoil bool
+	cancel    context.CancelFunc
+}
+
+// withDefaults 補齊未設定的參數
+func (o BlinkOptions) withDefaults() BlinkOptions {
+	if o.Duration <= 0 {
+		o.Duration = DefaultBlinkDuration
+	}
+	if o.Period <= 0 {
+		o.Period = DefaultBlinkPeriod
+	}
+	if o.Register == nil {
+		register := DefaultBlinkRegister
+		o.Register = &register
+	}
+	if len(o.Pattern) == 0 {
+		o.Pattern = DefaultBlinkPattern
+	}
+	return o
+}
+
+// Blink 開始識別閃爍：指定暫存器依樣式輪替、線圈週期切換，結束後還原
+func (s *Slave) Blink(opts BlinkOptions) (BlinkStatus, error) {
+	opts = opts.withDefaults()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.scenarioCtx == nil {
+		return BlinkStatus{}, fmt.Errorf(T("Slave 未啟動: %s"), s.ID)
+	}
+
+	// 先結束進行中的閃爍，確保保存的是原始值
+	s.stopBlinkLocked()
+
+	savedWord, err := s.registers.ReadHoldingRegister(*opts.Register)
+	if err != nil {
+		return BlinkStatus{}, err
+	}
+	var savedCoil bool
+	if opts.Coil != nil {
+		if savedCoil, err = s.registers.ReadCoil(*opts.Coil); err != nil {
+			return BlinkStatus{}, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(s.scenarioCtx, opts.Duration)
+	state := &blinkState{
+		opts:      opts,
+		until:     time.Now().Add(opts.Duration),
+		savedWord: savedWord,
+		savedCoil: savedCoil,
+		cancel:    cancel,
+	}
+	s.blink = state
+	s.applyBlinkLocked()
+	s.syncRegistersToServer()
+
+	go s.runBlink(ctx, state)
+
+	status := state.status(s.ID)
+	s.logger.Info(T("開始識別閃爍"),
+		zap.String("id", s.ID),
+		zap.Uint16("register", status.Register),
+		zap.Int("coil", status.Coil),
+		zap.Duration("duration", opts.Duration),
+	)
+
+	return status, nil
+}
+
+// StopBlink 提前結束識別閃爍並還原
+func (s *Slave) StopBlink() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopBlinkLocked()
+}
+
+// stopBlinkLocked 結束閃爍並還原暫存器與線圈 (呼叫端需持有 s.mu)
+func (s *Slave) stopBlinkLocked() {
+	state := s.blink
+	if state == nil {
+		return
+	}
+	s.blink = nil
+	state.cancel()
+
+	s.registers.WriteHoldingRegister(*state.opts.Register, state.savedWord)
+	if state.opts.Coil != nil {
+		s.registers.WriteCoil(*state.opts.Coil, state.savedCoil)
+	}
+	s.syncRegistersToServer()
+
+	s.logger.Info(T("識別閃爍結束"), zap.String("id", s.ID))
+}
+
+// runBlink 依週期推進閃爍樣式，逾時後還原
+func (s *Slave) runBlink(ctx context.Context, state *blinkState) {
+	ticker := time.NewTicker(state.opts.Period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.mu.Lock()
+			if s.blink == state {
+				s.stopBlinkLocked()
+			}
+			s.mu.Unlock()
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			if s.blink == state {
+				state.step++
+				s.applyBlinkLocked()
+				s.syncRegistersToServer()
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// applyBlinkLocked 寫入目前的閃爍樣式 (呼叫端需持有 s.mu)
+func (s *Slave) applyBlinkLocked() {
+	state := s.blink
+	if state == nil {
+		return
+	}
+
+	pattern := state.opts.Pattern
+	s.registers.WriteHoldingRegister(*state.opts.Register, pattern[state.step%len(pattern)])
+	if state.opts.Coil != nil {
+		s.registers.WriteCoil(*state.opts.Coil, state.step%2 == 0)
+	}
+}
+
+func (b *blinkState) status(id string) BlinkStatus {
+	coil := -1
+	if b.opts.Coil != nil {
+		coil = int(*b.opts.Coil)
+	}
+	return BlinkStatus{
+		Slave:    id,
+		Register: *b.opts.Register,
+		Pattern:  b.opts.Pattern,
+		Coil:     coil,
+		Until:    b.until,
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 開機風暴預設值
+const (
+	DefaultBootStormOutage  = 10 * time.Second
+	DefaultBootStormStagger = 2 * time.Second
+)
+
+// 開機風暴階段
+const (
+	BootStormPowerOff = "power_off" // 斷電中，所有成員離線
+	BootStormBooting  = "booting"   // 已復電，成員依序上線
+	BootStormComplete = "complete"  // 所有成員已上線
+)
+
+// bootStormState 開機風暴的執行期狀態 (由 e.bootMu 保護)
+type bootStormState struct {
+	members     []bootStormMember
+	protected   int
+	phase       string
+	online      int
+	powerOffAt  time.Time
+	restoreAt   time.Time
+	stagger     time.Duration
+	completedAt time.Time
+	restoreNow  chan struct{} // 關閉時略過剩餘的等待，立即恢復
+}
+
+// bootStormMember 成員與復電後的上線延遲
+type bootStormMember struct {
+	slave  *Slave
+	offset time.Duration
+}
+
+// BootStormStatus 開機風暴狀態
+type BootStormStatus struct {
+	Phase       string    `json:"phase"`
+	Members     int       `json:"members"`
+	Online      int       `json:"online"`
+	Protected   int       `json:"protected"`
+	PowerOffAt  time.Time `json:"power_off_at"`
+	RestoreAt   time.Time `json:"restore_at"`
+	Stagger     string    `json:"stagger"`
+	CompletedAt time.Time `json:"completed_at,omitempty"`
+}
+
+// BootStorm 模擬全場斷電：所有 (或 targets 內的) Slave 同時離線，outage 後復電，
+// 各 Slave 在 stagger 時間窗內隨機上線，用於測試 EMS 的重新連線湧入與連線數上限
+func (e *Engine) BootStorm(cfg BootStormConfig) (BootStormStatus, error) {
+	if err := cfg.Validate(); err != nil {
+		return BootStormStatus{}, err
+	}
+	if e.State() != EngineStateRunning {
+		return BootStormStatus{}, errors.New(T("引擎未運行"))
+	}
+	if cfg.Outage == 0 {
+		cfg.Outage = DefaultBootStormOutage
+	}
+
+	e.bootMu.Lock()
+	defer e.bootMu.Unlock()
+
+	if e.bootStorm != nil && e.bootStorm.phase != BootStormComplete {
+		return BootStormStatus{}, errors.New(T("開機風暴進行中"))
+	}
+
+	now := time.Now()
+	state := &bootStormState{
+		phase:      BootStormPowerOff,
+		powerOffAt: now,
+		restoreAt:  now.Add(cfg.Outage),
+		stagger:    cfg.Stagger,
+		restoreNow: make(chan struct{}),
+	}
+	for _, slave := range e.ListSlaves() {
+		if !MatchTargets(slave.IP, cfg.Targets) {
+			continue
+		}
+		if e.protectionReason(slave.IP, now) != "" {
+			state.protected++
+			continue
+		}
+		var offset time.Duration
+		if cfg.Stagger > 0 {
+			offset = time.Duration(scenarioRandom().Int63n(int64(cfg.Stagger)))
+		}
+		state.members = append(state.members, bootStormMember{slave: slave, offset: offset})
+	}
+	sort.Slice(state.members, func(i, j int) bool { return state.members[i].offset < state.members[j].offset })
+
+	if state.protected > 0 {
+		e.suppressedCount.Add(uint64(state.protected))
+		e.logger.Warn(T("開機風暴略過受保護的 Slave"),
+			zap.String("audit", "fault_suppressed"),
+			zap.Int("protected", state.protected),
+		)
+	}
+
+	// 先標記再離線，避免斷線閃斷場景在斷電期間讓成員恢復上線
+	for _, member := range state.members {
+		member.slave.powerOff.Store(true)
+	}
+	for _, member := range state.members {
+		member.slave.GoOffline()
+	}
+
+	e.bootStorm = state
+	go e.runBootStorm(e.bgCtx, state)
+
+	e.logger.Warn(T("開機風暴：全場斷電"),
+		zap.Int("members", len(state.members)),
+		zap.Duration("outage", cfg.Outage),
+		zap.Duration("stagger", cfg.Stagger),
+	)
+
+	return state.status(), nil
+}
+
+// RestoreBootStorm 立即復電，尚未上線的成員同時上線
+func (e *Engine) RestoreBootStorm() (BootStormStatus, error) {
+	e.bootMu.Lock()
+	defer e.bootMu.Unlock()
+
+	if e.bootStorm == nil {
+		return BootStormStatus{}, errors.New(T("沒有進行中的開機風暴"))
+	}
+	select {
+	case <-e.bootStorm.restoreNow:
+	default:
+		close(e.bootStorm.restoreNow)
+	}
+	return e.bootStorm.status(), nil
+}
+
+// BootStormStatus 進行中或最近一次開機風暴的狀態
+func (e *Engine) BootStormStatus() (BootStormStatus, bool) {
+	e.bootMu.Lock()
+	defer e.bootMu.Unlock()
+
+	if e.bootStorm == nil {
+		return BootStormStatus{}, false
+	}
+	return e.bootStorm.status(), true
+}
+
+// runBootStorm 等待斷電結束後依各成員的延遲讓其上線
+func (e *Engine) runBootStorm(ctx context.Context, state *bootStormState) {
+	// wait 等到 at；引擎停止時回傳 false
+	wait := func(at time.Time) bool {
+		timer := time.NewTimer(time.Until(at))
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return false
+		case <-state.restoreNow:
+			return true
+		case <-timer.C:
+			return true
+		}
+	}
+
+	if !wait(state.restoreAt) {
+		return
+	}
+	e.bootMu.Lock()
+	state.phase = BootStormBooting
+	e.bootMu.Unlock()
+	e.logger.Info(T("開機風暴：電力恢復，Slave 開始上線"), zap.Int("members", len(state.members)))
+
+	for _, member := range state.members {
+		if !wait(state.restoreAt.Add(member.offset)) {
+			return
+		}
+
+		member.slave.powerOff.Store(false)
+		if !member.slave.heldOffline() {
+			if err := member.slave.GoOnline(); err != nil {
+				e.logger.Warn(T("恢復上線失敗"), zap.String("id", member.slave.ID), zap.Error(err))
+			}
+		}
+
+		e.bootMu.Lock()
+		state.online++
+		e.bootMu.Unlock()
+	}
+
+	e.bootMu.Lock()
+	state.phase = BootStormComplete
+	state.completedAt = time.Now()
+	e.bootMu.Unlock()
+
+	e.logger.Info(T("開機風暴完成"),
+		zap.Int("members", len(state.members)),
+		zap.Duration("elapsed", state.completedAt.Sub(state.powerOffAt)),
+	)
+}
+
+func (s *bootStormState) status() BootStormStatus {
+	return BootStormStatus{
+		Phase:       s.phase,
+		Members:     len(s.members),
+		Online:      s.online,
+		Protected:   s.protected,
+		PowerOffAt:  s.powerOffAt,
+		RestoreAt:   s.restoreAt,
+		Stagger:     s.stagger.String(),
+		CompletedAt: s.completedAt,
+	}
+}
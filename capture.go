@@ -0,0 +1,805 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultCapturePort 擷取檔中 Modbus TCP 伺服器的預設埠號
+const DefaultCapturePort = 502
+
+// 擷取時間軸的資料表名稱
+const (
+	CaptureCoils            = "coils"
+	CaptureDiscreteInputs   = "discrete_inputs"
+	CaptureInputRegisters   = "input_registers"
+	CaptureHoldingRegisters = "holding_registers"
+)
+
+// CaptureFrame 時間軸上的一筆狀態變化 (由回應或成功的寫入請求重建，值與已知狀態相同者不列入)
+type CaptureFrame struct {
+	Offset  time.Duration `json:"offset_ns"` // 相對擷取開始的時間
+	Unit    uint8         `json:"unit"`
+	Table   string        `json:"table"`
+	Address uint16        `json:"address"` // 協定位址 (0 起算)
+	Values  []uint16      `json:"values"`  // 線圈與離散輸入為 0/1
+}
+
+// CaptureStats 擷取檔解析統計
+type CaptureStats struct {
+	Packets    int `json:"packets"`    // 封包總數
+	Requests   int `json:"requests"`   // Modbus 請求數
+	Responses  int `json:"responses"`  // Modbus 回應數
+	Exceptions int `json:"exceptions"` // 例外回應數
+	Unmatched  int `json:"unmatched"`  // 找不到對應請求的回應數
+}
+
+// CaptureTimeline 由擷取檔重建的暫存器狀態時間軸 (可存成 JSON 作為回歸測試資料)
+type CaptureTimeline struct {
+	Start  time.Time      `json:"start"`
+	Port   uint16         `json:"port"`
+	Stats  CaptureStats   `json:"stats"`
+	Frames []CaptureFrame `json:"frames"`
+}
+
+// Duration 最後一筆變化相對擷取開始的時間
+func (tl *CaptureTimeline) Duration() time.Duration {
+	if len(tl.Frames) == 0 {
+		return 0
+	}
+	return tl.Frames[len(tl.Frames)-1].Offset
+}
+
+// Units 時間軸中出現的 Unit ID
+func (tl *CaptureTimeline) Units() []uint8 {
+	seen := make(map[uint8]bool)
+	var units []uint8
+	for _, f := range tl.Frames {
+		if !seen[f.Unit] {
+			seen[f.Unit] = true
+			units = append(units, f.Unit)
+		}
+	}
+	sort.Slice(units, func(i, j int) bool { return units[i] < units[j] })
+	return units
+}
+
+// Save 以 JSON 儲存時間軸
+func (tl *CaptureTimeline) Save(path string) error {
+	data, err := json.MarshalIndent(tl, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// LoadCaptureTimeline 載入時間軸：.json 為先前儲存的時間軸，其餘視為 pcap/pcapng 擷取檔
+func LoadCaptureTimeline(path string, port uint16) (*CaptureTimeline, error) {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var tl CaptureTimeline
+		if err := json.Unmarshal(data, &tl); err != nil {
+			return nil, fmt.Errorf(T("解析時間軸失敗: %w"), err)
+		}
+		return &tl, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadCapture(f, port)
+}
+
+// ReadCapture 解析 pcap/pcapng 擷取檔，依 port 判斷方向並重建暫存器狀態時間軸
+func ReadCapture(r io.Reader, port uint16) (*CaptureTimeline, error) {
+	if port == 0 {
+		port = DefaultCapturePort
+	}
+	builder := newTimelineBuilder(port)
+	if err := readPackets(bufio.NewReader(r), builder.packet); err != nil {
+		return nil, err
+	}
+	return builder.timeline(), nil
+}
+
+// --- pcap / pcapng ---
+
+// 常見的 link type
+const (
+	linkTypeNull     = 0
+	linkTypeEthernet = 1
+	linkTypeRaw      = 101
+	linkTypeLinuxSLL = 113
+	linkTypeIPv4     = 228
+	linkTypeIPv6     = 229
+	linkTypeSLL2     = 276
+)
+
+// maxCaptureBlock 單一封包/區塊的大小上限 (避免損壞的檔案造成大量配置)
+const maxCaptureBlock = 1 << 20
+
+// errCaptureFormat 無法辨識的檔頭
+func errCaptureFormat() error {
+	return errors.New(T("不支援的擷取檔格式 (僅支援 pcap 與 pcapng)"))
+}
+
+// readPackets 依檔頭判斷格式並逐一回呼封包
+func readPackets(r *bufio.Reader, fn func(at time.Time, linkType uint32, data []byte)) error {
+	magic, err := r.Peek(4)
+	if err != nil {
+		return errCaptureFormat()
+	}
+	switch {
+	case binary.LittleEndian.Uint32(magic) == 0x0a0d0d0a:
+		return readPcapng(r, fn)
+	case binary.LittleEndian.Uint32(magic) == 0xa1b2c3d4, binary.LittleEndian.Uint32(magic) == 0xa1b23c4d,
+		binary.BigEndian.Uint32(magic) == 0xa1b2c3d4, binary.BigEndian.Uint32(magic) == 0xa1b23c4d:
+		return readPcap(r, fn)
+	}
+	return errCaptureFormat()
+}
+
+// readPcap 解析傳統 libpcap 格式
+func readPcap(r io.Reader, fn func(time.Time, uint32, []byte)) error {
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return errCaptureFormat()
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if binary.BigEndian.Uint32(header) == 0xa1b2c3d4 || binary.BigEndian.Uint32(header) == 0xa1b23c4d {
+		order = binary.BigEndian
+	}
+	fraction := time.Microsecond
+	if order.Uint32(header) == 0xa1b23c4d {
+		fraction = time.Nanosecond
+	}
+	linkType := order.Uint32(header[20:]) & 0x0fffffff
+
+	record := make([]byte, 16)
+	for {
+		if _, err := io.ReadFull(r, record); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf(T("擷取檔已截斷: %w"), err)
+		}
+		length := order.Uint32(record[8:])
+		if length > maxCaptureBlock {
+			return fmt.Errorf(T("封包長度無效: %d"), length)
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return fmt.Errorf(T("擷取檔已截斷: %w"), err)
+		}
+		at := time.Unix(int64(order.Uint32(record)), int64(order.Uint32(record[4:]))*int64(fraction))
+		fn(at, linkType, data)
+	}
+}
+
+// pcapngInterface pcapng 介面描述
+type pcapngInterface struct {
+	linkType uint32
+	units    uint64 // 每秒的時間戳記單位數
+}
+
+// timestamp 將時間戳記轉換為時間
+func (iface pcapngInterface) timestamp(ts uint64) time.Time {
+	sec, frac := ts/iface.units, ts%iface.units
+	return time.Unix(int64(sec), int64(float64(frac)*1e9/float64(iface.units)))
+}
+
+// readPcapng 解析 pcapng 格式 (Section Header、Interface Description、Enhanced/Simple Packet 區塊)
+func readPcapng(r io.Reader, fn func(time.Time, uint32, []byte)) error {
+	var order binary.ByteOrder = binary.LittleEndian
+	var interfaces []pcapngInterface
+
+	head := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, head); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf(T("擷取檔已截斷: %w"), err)
+		}
+
+		blockType := order.Uint32(head)
+		if blockType == 0x0a0d0d0a {
+			// 新區段：由 byte-order magic 決定位元組順序，介面編號重新起算
+			magic := make([]byte, 4)
+			if _, err := io.ReadFull(r, magic); err != nil {
+				return fmt.Errorf(T("擷取檔已截斷: %w"), err)
+			}
+			order = binary.LittleEndian
+			if binary.BigEndian.Uint32(magic) == 0x1a2b3c4d {
+				order = binary.BigEndian
+			}
+			interfaces = nil
+			length := order.Uint32(head[4:])
+			if length < 16 || length > maxCaptureBlock {
+				return fmt.Errorf(T("區塊長度無效: %d"), length)
+			}
+			if _, err := io.CopyN(io.Discard, r, int64(length-12)); err != nil {
+				return fmt.Errorf(T("擷取檔已截斷: %w"), err)
+			}
+			continue
+		}
+
+		length := order.Uint32(head[4:])
+		if length < 12 || length > maxCaptureBlock || length%4 != 0 {
+			return fmt.Errorf(T("區塊長度無效: %d"), length)
+		}
+		body := make([]byte, length-8)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return fmt.Errorf(T("擷取檔已截斷: %w"), err)
+		}
+		body = body[:len(body)-4] // 結尾重複的區塊長度
+
+		switch blockType {
+		case 1: // Interface Description Block
+			if len(body) < 8 {
+				continue
+			}
+			iface := pcapngInterface{linkType: uint32(order.Uint16(body)), units: 1e6}
+			for opts := body[8:]; len(opts) >= 4; {
+				code, size := order.Uint16(opts), int(order.Uint16(opts[2:]))
+				if code == 0 || 4+size > len(opts) {
+					break
+				}
+				if code == 9 && size >= 1 { // if_tsresol：最高位元為 0 時為 10 的負冪次，否則為 2 的負冪次
+					if v := opts[4]; v&0x80 == 0 && v <= 19 {
+						iface.units = uint64(math.Pow10(int(v)))
+					} else if v&0x80 != 0 && v&0x7f <= 63 {
+						iface.units = 1 << (v & 0x7f)
+					}
+				}
+				opts = opts[min(4+(size+3)&^3, len(opts)):]
+			}
+			interfaces = append(interfaces, iface)
+
+		case 6: // Enhanced Packet Block
+			if len(body) < 20 {
+				continue
+			}
+			id := order.Uint32(body)
+			if int(id) >= len(interfaces) {
+				continue
+			}
+			iface := interfaces[id]
+			ts := uint64(order.Uint32(body[4:]))<<32 | uint64(order.Uint32(body[8:]))
+			captured := int(order.Uint32(body[12:]))
+			if 20+captured > len(body) {
+				continue
+			}
+			fn(iface.timestamp(ts), iface.linkType, body[20:20+captured])
+
+		case 3: // Simple Packet Block (無時間戳記，以上一個封包時間近似)
+			if len(body) < 4 || len(interfaces) == 0 {
+				continue
+			}
+			captured := min(int(order.Uint32(body)), len(body)-4)
+			fn(time.Time{}, interfaces[0].linkType, body[4:4+captured])
+		}
+	}
+}
+
+// --- IP / TCP 解碼 ---
+
+// tcpSegment 解碼後的 TCP 區段
+type tcpSegment struct {
+	src, dst string // ip:port
+	srcPort  uint16
+	dstPort  uint16
+	seq      uint32
+	syn      bool
+	payload  []byte
+}
+
+// decodeTCP 由鏈路層封包解出 TCP 區段 (不支援 IP 分段與 IPv6 擴充標頭)
+func decodeTCP(linkType uint32, data []byte) (tcpSegment, bool) {
+	var etherType uint16
+	switch linkType {
+	case linkTypeEthernet:
+		if len(data) < 14 {
+			return tcpSegment{}, false
+		}
+		etherType, data = binary.BigEndian.Uint16(data[12:]), data[14:]
+		for (etherType == 0x8100 || etherType == 0x88a8) && len(data) >= 4 { // VLAN
+			etherType, data = binary.BigEndian.Uint16(data[2:]), data[4:]
+		}
+	case linkTypeLinuxSLL:
+		if len(data) < 16 {
+			return tcpSegment{}, false
+		}
+		etherType, data = binary.BigEndian.Uint16(data[14:]), data[16:]
+	case linkTypeSLL2:
+		if len(data) < 20 {
+			return tcpSegment{}, false
+		}
+		etherType, data = binary.BigEndian.Uint16(data), data[20:]
+	case linkTypeNull:
+		if len(data) < 4 {
+			return tcpSegment{}, false
+		}
+		data = data[4:]
+	case linkTypeRaw, linkTypeIPv4, linkTypeIPv6:
+	default:
+		return tcpSegment{}, false
+	}
+	if len(data) == 0 {
+		return tcpSegment{}, false
+	}
+
+	var src, dst []byte
+	switch {
+	case etherType == 0x0800 || (etherType == 0 && data[0]>>4 == 4):
+		if len(data) < 20 {
+			return tcpSegment{}, false
+		}
+		ihl := int(data[0]&0x0f) * 4
+		total := int(binary.BigEndian.Uint16(data[2:]))
+		fragment := binary.BigEndian.Uint16(data[6:])
+		if data[9] != 6 || ihl < 20 || total < ihl || total > len(data) || fragment&0x3fff != 0 {
+			return tcpSegment{}, false
+		}
+		src, dst, data = data[12:16], data[16:20], data[ihl:total]
+	case etherType == 0x86dd || (etherType == 0 && data[0]>>4 == 6):
+		if len(data) < 40 || data[6] != 6 {
+			return tcpSegment{}, false
+		}
+		total := 40 + int(binary.BigEndian.Uint16(data[4:]))
+		if total > len(data) {
+			return tcpSegment{}, false
+		}
+		src, dst, data = data[8:24], data[24:40], data[40:total]
+	default:
+		return tcpSegment{}, false
+	}
+
+	if len(data) < 20 {
+		return tcpSegment{}, false
+	}
+	offset := int(data[12]>>4) * 4
+	if offset < 20 || offset > len(data) {
+		return tcpSegment{}, false
+	}
+	seg := tcpSegment{
+		srcPort: binary.BigEndian.Uint16(data),
+		dstPort: binary.BigEndian.Uint16(data[2:]),
+		seq:     binary.BigEndian.Uint32(data[4:]),
+		syn:     data[13]&0x02 != 0,
+		payload: data[offset:],
+	}
+	seg.src = fmt.Sprintf("%s:%d", ipString(src), seg.srcPort)
+	seg.dst = fmt.Sprintf("%s:%d", ipString(dst), seg.dstPort)
+	return seg, true
+}
+
+// ipString 以 net 套件格式輸出 IP
+func ipString(ip []byte) string {
+	if len(ip) == 4 {
+		return fmt.Sprintf("%d.%d.%d.%d", ip[0], ip[1], ip[2], ip[3])
+	}
+	return fmt.Sprintf("[%x]", ip)
+}
+
+// --- 時間軸重建 ---
+
+// tcpFlow 單一方向的 TCP 資料流 (依序號重組，遇到遺失則丟棄未完成的資料)
+type tcpFlow struct {
+	started bool
+	next    uint32
+	buf     []byte
+}
+
+// push 依序號加入區段
+func (f *tcpFlow) push(seg tcpSegment) {
+	if seg.syn {
+		f.started, f.next, f.buf = true, seg.seq+1, nil
+		return
+	}
+	payload := seg.payload
+	if len(payload) == 0 {
+		return
+	}
+	if !f.started {
+		f.started, f.next = true, seg.seq
+	}
+	switch diff := int32(seg.seq - f.next); {
+	case diff < 0: // 重傳或重疊
+		if -int(diff) >= len(payload) {
+			return
+		}
+		payload = payload[-diff:]
+	case diff > 0: // 中間有遺失，丟棄無法對齊的資料
+		f.buf = nil
+	}
+	f.buf = append(f.buf, payload...)
+	f.next = seg.seq + uint32(len(seg.payload))
+}
+
+// frames 取出完整的 Modbus TCP 訊框 (MBAP 不合法時丟棄緩衝區重新同步)
+func (f *tcpFlow) frames() [][]byte {
+	var out [][]byte
+	for len(f.buf) >= ModbusTCPHeaderLength+1 {
+		length := int(binary.BigEndian.Uint16(f.buf[4:]))
+		if binary.BigEndian.Uint16(f.buf[2:]) != 0 || length < 2 || length > 254 {
+			f.buf = nil
+			break
+		}
+		total := 6 + length
+		if len(f.buf) < total {
+			break
+		}
+		out = append(out, append([]byte(nil), f.buf[:total]...))
+		f.buf = f.buf[total:]
+	}
+	return out
+}
+
+// pendingRequest 等待回應的請求
+type pendingRequest struct {
+	unit     uint8
+	function uint8
+	address  uint16
+	quantity uint16
+	pdu      []byte
+}
+
+// stateKey 已知狀態的鍵
+type stateKey struct {
+	unit    uint8
+	table   string
+	address uint16
+}
+
+// timelineBuilder 由封包重建時間軸
+type timelineBuilder struct {
+	port    uint16
+	start   time.Time
+	last    time.Time
+	stats   CaptureStats
+	flows   map[string]*tcpFlow
+	pending map[string]pendingRequest // 連線 + transaction ID
+	state   map[stateKey]uint16
+	frames  []CaptureFrame
+}
+
+func newTimelineBuilder(port uint16) *timelineBuilder {
+	return &timelineBuilder{
+		port:    port,
+		flows:   make(map[string]*tcpFlow),
+		pending: make(map[string]pendingRequest),
+		state:   make(map[stateKey]uint16),
+	}
+}
+
+// packet 處理一個封包
+func (b *timelineBuilder) packet(at time.Time, linkType uint32, data []byte) {
+	b.stats.Packets++
+	if at.IsZero() {
+		at = b.last
+	}
+	b.last = at
+
+	seg, ok := decodeTCP(linkType, data)
+	if !ok || (seg.srcPort != b.port && seg.dstPort != b.port) {
+		return
+	}
+	if b.start.IsZero() {
+		b.start = at
+	}
+
+	flowKey := seg.src + ">" + seg.dst
+	flow, ok := b.flows[flowKey]
+	if !ok {
+		flow = &tcpFlow{}
+		b.flows[flowKey] = flow
+	}
+	flow.push(seg)
+
+	toServer := seg.dstPort == b.port
+	conn := seg.src + ">" + seg.dst
+	if !toServer {
+		conn = seg.dst + ">" + seg.src
+	}
+	for _, frame := range flow.frames() {
+		tid := binary.BigEndian.Uint16(frame)
+		key := fmt.Sprintf("%s#%d", conn, tid)
+		if toServer {
+			b.request(key, frame)
+		} else {
+			b.response(key, frame, at)
+		}
+	}
+}
+
+// request 記錄請求待配對
+func (b *timelineBuilder) request(key string, frame []byte) {
+	b.stats.Requests++
+	pdu := frame[ModbusTCPHeaderLength+1:]
+	req := pendingRequest{unit: frame[6], function: frame[ModbusTCPHeaderLength], pdu: pdu}
+	if len(pdu) >= 4 {
+		req.address = binary.BigEndian.Uint16(pdu)
+		req.quantity = binary.BigEndian.Uint16(pdu[2:])
+	}
+	b.pending[key] = req
+}
+
+// response 依配對的請求解出狀態變化
+func (b *timelineBuilder) response(key string, frame []byte, at time.Time) {
+	b.stats.Responses++
+	req, ok := b.pending[key]
+	if !ok {
+		b.stats.Unmatched++
+		return
+	}
+	delete(b.pending, key)
+
+	function := frame[ModbusTCPHeaderLength]
+	if function&0x80 != 0 {
+		b.stats.Exceptions++
+		return
+	}
+	if function != req.function {
+		b.stats.Unmatched++
+		return
+	}
+	pdu := frame[ModbusTCPHeaderLength+1:]
+
+	var table string
+	var values []uint16
+	switch function {
+	case FuncCodeReadCoils, FuncCodeReadDiscreteInputs:
+		table = CaptureCoils
+		if function == FuncCodeReadDiscreteInputs {
+			table = CaptureDiscreteInputs
+		}
+		if len(pdu) < 1 || int(pdu[0]) < (int(req.quantity)+7)/8 || len(pdu) < 1+int(pdu[0]) {
+			return
+		}
+		values = unpackBits(pdu[1:], int(req.quantity))
+	case FuncCodeReadHoldingRegisters, FuncCodeReadInputRegisters:
+		table = CaptureHoldingRegisters
+		if function == FuncCodeReadInputRegisters {
+			table = CaptureInputRegisters
+		}
+		if len(pdu) < 1 || int(pdu[0]) != 2*int(req.quantity) || len(pdu) < 1+int(pdu[0]) {
+			return
+		}
+		values = BytesToRegisters(pdu[1 : 1+pdu[0]])
+	case FuncCodeWriteSingleCoil:
+		table = CaptureCoils
+		if req.quantity == 0xff00 {
+			values = []uint16{1}
+		} else {
+			values = []uint16{0}
+		}
+	case FuncCodeWriteSingleRegister:
+		table, values = CaptureHoldingRegisters, []uint16{req.quantity}
+	case FuncCodeWriteMultipleCoils:
+		if len(req.pdu) < 5 || len(req.pdu) < 5+int(req.pdu[4]) {
+			return
+		}
+		table, values = CaptureCoils, unpackBits(req.pdu[5:5+req.pdu[4]], int(req.quantity))
+	case FuncCodeWriteMultipleRegisters:
+		if len(req.pdu) < 5 || int(req.pdu[4]) != 2*int(req.quantity) || len(req.pdu) < 5+int(req.pdu[4]) {
+			return
+		}
+		table, values = CaptureHoldingRegisters, BytesToRegisters(req.pdu[5:5+req.pdu[4]])
+	default:
+		return
+	}
+
+	changed := false
+	for i, v := range values {
+		k := stateKey{unit: req.unit, table: table, address: req.address + uint16(i)}
+		if old, ok := b.state[k]; !ok || old != v {
+			b.state[k] = v
+			changed = true
+		}
+	}
+	if changed {
+		b.frames = append(b.frames, CaptureFrame{
+			Offset:  at.Sub(b.start),
+			Unit:    req.unit,
+			Table:   table,
+			Address: req.address,
+			Values:  values,
+		})
+	}
+}
+
+// timeline 產生時間軸
+func (b *timelineBuilder) timeline() *CaptureTimeline {
+	frames := b.frames
+	if frames == nil {
+		frames = []CaptureFrame{}
+	}
+	return &CaptureTimeline{Start: b.start, Port: b.port, Stats: b.stats, Frames: frames}
+}
+
+// unpackBits 將位元組展開為 0/1 (LSB 先)
+func unpackBits(data []byte, count int) []uint16 {
+	values := make([]uint16, count)
+	for i := range values {
+		if i/8 < len(data) && data[i/8]>>(i%8)&1 != 0 {
+			values[i] = 1
+		}
+	}
+	return values
+}
+
+// applyCaptureFrame 將狀態變化寫入暫存器映射表 (擷取的是 PDU 位址，依映射表的位址慣例換算；超出範圍的部分略過)
+func applyCaptureFrame(registers *RegisterMap, frame CaptureFrame) {
+	for i, v := range frame.Values {
+		pdu := frame.Address + uint16(i)
+		switch frame.Table {
+		case CaptureHoldingRegisters:
+			writeAtPDU(registers, RegisterTypeHoldingRegister, pdu, func(address uint16) error {
+				return registers.WriteHoldingRegister(address, v)
+			})
+		case CaptureInputRegisters:
+			if address, ok := registers.PDUAddress(RegisterTypeInputRegister, pdu); ok {
+				registers.SetInputRegister(address, v)
+			}
+		case CaptureCoils:
+			if address, ok := registers.PDUAddress(RegisterTypeCoil, pdu); ok {
+				registers.WriteCoil(address, v != 0)
+			}
+		case CaptureDiscreteInputs:
+			if address, ok := registers.PDUAddress(RegisterTypeDiscreteInput, pdu); ok {
+				registers.SetDiscreteInput(address, v != 0)
+			}
+		}
+	}
+}
+
+// ValidateCapture 檢查擷取檔或時間軸是否可載入
+func ValidateCapture(path string, port uint16) error {
+	_, err := LoadCaptureTimeline(path, port)
+	return err
+}
+
+// --- Replay Scenario ---
+
+// ReplayScenario 擷取重播場景 - 依時間軸重現實驗室擷取的暫存器變化，讓擷取檔成為回歸測試資料
+//
+// capture 指定 pcap/pcapng 擷取檔或先前匯出的時間軸 JSON；capture_unit 非 0 時僅重播該 Unit，
+// time_scale 可加速重播，capture_loop 於播完後從頭開始。未指定 capture 時行為與正常場景相同。
+type ReplayScenario struct {
+	normalScenario NormalScenario
+
+	mu        sync.Mutex
+	timelines map[string]*CaptureTimeline // 依路徑與埠號快取
+	states    map[*RegisterMap]*replayState
+	activated map[*RegisterMap]time.Time // 進入場景的時間 (首次更新時作為重播起點)
+}
+
+// replayState 單一暫存器映射表的重播進度
+type replayState struct {
+	key      string
+	timeline *CaptureTimeline
+	start    time.Time
+	next     int
+}
+
+func (s *ReplayScenario) Type() ScenarioType {
+	return ScenarioReplay
+}
+
+// Activate 進入場景時從頭重播
+func (s *ReplayScenario) Activate(registers *RegisterMap) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, registers)
+	if s.activated == nil {
+		s.activated = make(map[*RegisterMap]time.Time)
+	}
+	s.activated[registers] = scenarioNow()
+}
+
+func (s *ReplayScenario) Update(registers *RegisterMap, params ScenarioParams) {
+	if params.Capture == "" {
+		s.normalScenario.Update(registers, params)
+		return
+	}
+
+	state := s.state(registers, params)
+	if state == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	frames := state.timeline.Frames
+	elapsed := scenarioSince(state.start)
+	if params.TimeScale > 0 {
+		elapsed = time.Duration(float64(elapsed) * params.TimeScale)
+	}
+	if params.CaptureLoop && state.next >= len(frames) && len(frames) > 0 && elapsed > state.timeline.Duration() {
+		state.start, state.next, elapsed = scenarioNow(), 0, 0
+	}
+
+	applied := false
+	for ; state.next < len(frames) && frames[state.next].Offset <= elapsed; state.next++ {
+		frame := frames[state.next]
+		if params.CaptureUnit != 0 && frame.Unit != params.CaptureUnit {
+			continue
+		}
+		applyCaptureFrame(registers, frame)
+		applied = true
+	}
+	if applied {
+		registers.EvaluateExpressions()
+	}
+}
+
+func (s *ReplayScenario) Reset(registers *RegisterMap) {
+	s.mu.Lock()
+	delete(s.states, registers)
+	delete(s.activated, registers)
+	s.mu.Unlock()
+
+	s.normalScenario.Reset(registers)
+}
+
+// state 取得 (必要時載入) 重播進度；擷取檔變更時重新開始，載入失敗時回傳 nil
+func (s *ReplayScenario) state(registers *RegisterMap, params ScenarioParams) *replayState {
+	port := params.CapturePort
+	if port == 0 {
+		port = DefaultCapturePort
+	}
+	key := fmt.Sprintf("%s#%d", params.Capture, port)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.states == nil {
+		s.states = make(map[*RegisterMap]*replayState)
+		s.timelines = make(map[string]*CaptureTimeline)
+	}
+	if state, ok := s.states[registers]; ok && state.key == key {
+		return state
+	}
+
+	timeline, ok := s.timelines[key]
+	if !ok {
+		var err error
+		if timeline, err = LoadCaptureTimeline(params.Capture, port); err != nil {
+			zap.L().Warn(T("載入擷取檔失敗"), zap.String("capture", params.Capture), zap.Error(err))
+		}
+		// 失敗也快取，避免每次更新重新讀檔
+		s.timelines[key] = timeline
+	}
+	if timeline == nil {
+		return nil
+	}
+
+	start, ok := s.activated[registers]
+	if !ok {
+		start = scenarioNow()
+	}
+	delete(s.activated, registers)
+
+	state := &replayState{key: key, timeline: timeline, start: start}
+	s.states[registers] = state
+	return state
+}
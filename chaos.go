@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 混沌模式預設值
+const (
+	DefaultChaosIntervalMin = 30 * time.Second
+	DefaultChaosIntervalMax = 2 * time.Minute
+	DefaultChaosDurationMin = 15 * time.Second
+	DefaultChaosDurationMax = time.Minute
+	DefaultChaosFractionMin = 0.05
+	DefaultChaosFractionMax = 0.2
+	DefaultChaosMaxActive   = 2
+)
+
+// DefaultChaosScenarios 混沌模式預設隨機挑選的擾動場景
+var DefaultChaosScenarios = []string{"voltage_sag", "connection_flap", "exception_storm", "jitter"}
+
+// --- Chaos Scenario ---
+
+// ChaosScenario 混沌模式 - Slave 照常波動，由引擎在隨機的時間點挑選隨機的部分 Slave 短暫套用擾動場景，
+// 供長時間無人值守的韌性測試
+type ChaosScenario struct {
+	normalScenario NormalScenario
+}
+
+func (s *ChaosScenario) Type() ScenarioType {
+	return ScenarioChaos
+}
+
+func (s *ChaosScenario) Update(registers *RegisterMap, params ScenarioParams) {
+	s.normalScenario.Update(registers, ScenarioParams{
+		VoltageVariance:   0.005,
+		FrequencyVariance: 0.0005,
+	})
+}
+
+func (s *ChaosScenario) Reset(registers *RegisterMap) {
+	s.normalScenario.Reset(registers)
+}
+
+// chaosBounds 混沌模式的範圍 (未設定的參數使用預設值)
+type chaosBounds struct {
+	scenarios                []ScenarioType
+	intervalMin, intervalMax time.Duration
+	durationMin, durationMax time.Duration
+	fractionMin, fractionMax float64
+	maxActive                int
+}
+
+// newChaosBounds 依 chaos 場景的參數建立範圍
+func newChaosBounds(params ScenarioParams) chaosBounds {
+	names := params.ChaosScenarios
+	if len(names) == 0 {
+		names = DefaultChaosScenarios
+	}
+	b := chaosBounds{
+		intervalMin: durationOr(params.ChaosIntervalMin, DefaultChaosIntervalMin),
+		intervalMax: durationOr(params.ChaosIntervalMax, DefaultChaosIntervalMax),
+		durationMin: durationOr(params.ChaosDurationMin, DefaultChaosDurationMin),
+		durationMax: durationOr(params.ChaosDurationMax, DefaultChaosDurationMax),
+		fractionMin: params.ChaosFractionMin,
+		fractionMax: params.ChaosFractionMax,
+		maxActive:   params.ChaosMaxActive,
+	}
+	for _, name := range names {
+		b.scenarios = append(b.scenarios, ParseScenarioType(name))
+	}
+	if b.fractionMin == 0 {
+		b.fractionMin = DefaultChaosFractionMin
+	}
+	if b.fractionMax == 0 {
+		b.fractionMax = DefaultChaosFractionMax
+	}
+	if b.maxActive == 0 {
+		b.maxActive = DefaultChaosMaxActive
+	}
+	// 只設定其中一端時，另一端跟著調整
+	b.intervalMax = max(b.intervalMax, b.intervalMin)
+	b.durationMax = max(b.durationMax, b.durationMin)
+	b.fractionMax = max(b.fractionMax, b.fractionMin)
+	return b
+}
+
+func durationOr(d, fallback time.Duration) time.Duration {
+	if d == 0 {
+		return fallback
+	}
+	return d
+}
+
+// randomDuration min-max 之間的隨機時間
+func randomDuration(min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
+	}
+	return min + time.Duration(scenarioRandom().Int63n(int64(max-min)+1))
+}
+
+// chaosDisturbance 進行中的擾動
+type chaosDisturbance struct {
+	scenario ScenarioType
+	slaves   []*Slave
+	started  time.Time
+	until    time.Time
+}
+
+// chaosState 混沌模式的執行期狀態 (由 e.chaosMu 保護)
+type chaosState struct {
+	running      bool // 整體場景為 chaos
+	nextAt       time.Time
+	disturbances []*chaosDisturbance
+	injected     uint64
+}
+
+// ChaosDisturbance 進行中的擾動
+type ChaosDisturbance struct {
+	Scenario string    `json:"scenario"`
+	Slaves   []string  `json:"slaves"`
+	Started  time.Time `json:"started"`
+	Until    time.Time `json:"until"`
+}
+
+// ChaosStatus 混沌模式狀態
+type ChaosStatus struct {
+	Running      bool               `json:"running"`
+	NextAt       time.Time          `json:"next_at,omitempty"`
+	Injected     uint64             `json:"injected"` // 累計注入的擾動數
+	Disturbances []ChaosDisturbance `json:"disturbances"`
+}
+
+// ChaosStatus 混沌模式的狀態與進行中的擾動
+func (e *Engine) ChaosStatus() ChaosStatus {
+	e.chaosMu.Lock()
+	defer e.chaosMu.Unlock()
+
+	status := ChaosStatus{
+		Running:      e.chaos.running,
+		NextAt:       e.chaos.nextAt,
+		Injected:     e.chaos.injected,
+		Disturbances: make([]ChaosDisturbance, 0, len(e.chaos.disturbances)),
+	}
+	for _, d := range e.chaos.disturbances {
+		ids := make([]string, len(d.slaves))
+		for i, slave := range d.slaves {
+			ids[i] = slave.ID
+		}
+		status.Disturbances = append(status.Disturbances, ChaosDisturbance{
+			Scenario: d.scenario.String(),
+			Slaves:   ids,
+			Started:  d.started,
+			Until:    d.until,
+		})
+	}
+	return status
+}
+
+// chaosStats 累計注入的擾動數與目前受擾動的 Slave 數
+func (e *Engine) chaosStats() (injected uint64, slaves int) {
+	e.chaosMu.Lock()
+	defer e.chaosMu.Unlock()
+
+	for _, d := range e.chaos.disturbances {
+		slaves += len(d.slaves)
+	}
+	return e.chaos.injected, slaves
+}
+
+// runChaos 整體場景為 chaos 時依參數注入與結束擾動 (間隔與持續時間依模擬時鐘)
+func (e *Engine) runChaos(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.chaosTick(scenarioNow())
+		}
+	}
+}
+
+// chaosTick 結束到期的擾動，並於排定的時間注入新的擾動
+func (e *Engine) chaosTick(now time.Time) {
+	e.chaosMu.Lock()
+	defer e.chaosMu.Unlock()
+
+	if e.GetScenario() != ScenarioChaos {
+		e.stopChaosLocked()
+		return
+	}
+
+	bounds := newChaosBounds(e.ScenarioParams(ScenarioChaos))
+	if !e.chaos.running {
+		e.chaos.running = true
+		e.chaos.nextAt = now.Add(randomDuration(bounds.intervalMin, bounds.intervalMax))
+		e.logger.Info(T("混沌模式開始"), zap.Time("next_at", e.chaos.nextAt))
+	}
+
+	active := e.chaos.disturbances[:0]
+	for _, d := range e.chaos.disturbances {
+		if now.Before(d.until) {
+			active = append(active, d)
+			continue
+		}
+		e.endDisturbance(d, now)
+	}
+	e.chaos.disturbances = active
+
+	if now.Before(e.chaos.nextAt) {
+		return
+	}
+	e.chaos.nextAt = now.Add(randomDuration(bounds.intervalMin, bounds.intervalMax))
+	if len(e.chaos.disturbances) < bounds.maxActive {
+		e.injectDisturbance(bounds, now)
+	}
+}
+
+// stopChaos 結束混沌模式，受擾動的 Slave 恢復為 chaos 場景 (整體場景切換前呼叫)
+func (e *Engine) stopChaos() {
+	e.chaosMu.Lock()
+	defer e.chaosMu.Unlock()
+	e.stopChaosLocked()
+}
+
+// stopChaosLocked 結束混沌模式 (呼叫端需持有 e.chaosMu)
+func (e *Engine) stopChaosLocked() {
+	if !e.chaos.running {
+		return
+	}
+	now := scenarioNow()
+	for _, d := range e.chaos.disturbances {
+		e.endDisturbance(d, now)
+	}
+	e.chaos = chaosState{injected: e.chaos.injected}
+	e.logger.Info(T("混沌模式結束"))
+}
+
+// injectDisturbance 隨機挑選擾動場景與部分運行 chaos 場景的 Slave (略過受保護的 Slave)
+func (e *Engine) injectDisturbance(bounds chaosBounds, now time.Time) {
+	var pool []*Slave
+	for _, slave := range e.ListSlaves() {
+		if slave.GetScenario() == ScenarioChaos && e.protectionReason(slave.IP, now) == "" {
+			pool = append(pool, slave)
+		}
+	}
+	if len(pool) == 0 {
+		return
+	}
+	// 依 ID 排序後洗牌，相同的亂數種子挑選相同的 Slave
+	sort.Slice(pool, func(i, j int) bool { return pool[i].ID < pool[j].ID })
+
+	random := scenarioRandom()
+	fraction := bounds.fractionMin + random.Float64()*(bounds.fractionMax-bounds.fractionMin)
+	count := min(max(int(math.Ceil(float64(len(pool))*fraction)), 1), len(pool))
+	d := &chaosDisturbance{
+		scenario: bounds.scenarios[random.Intn(len(bounds.scenarios))],
+		started:  now,
+		until:    now.Add(randomDuration(bounds.durationMin, bounds.durationMax)),
+	}
+	for _, i := range random.Perm(len(pool))[:count] {
+		d.slaves = append(d.slaves, pool[i])
+	}
+	for _, slave := range d.slaves {
+		slave.ApplyScenario(d.scenario)
+	}
+
+	e.chaos.disturbances = append(e.chaos.disturbances, d)
+	e.chaos.injected++
+	e.logger.Warn(T("混沌模式注入擾動"),
+		zap.String("scenario", d.scenario.String()),
+		zap.Int("slaves", len(d.slaves)),
+		zap.Int("pool", len(pool)),
+		zap.Duration("duration", d.until.Sub(now)),
+	)
+}
+
+// endDisturbance 結束擾動：仍在擾動場景的 Slave 恢復為 chaos 場景，已被其他操作切換的 Slave 不變
+func (e *Engine) endDisturbance(d *chaosDisturbance, now time.Time) {
+	restored := 0
+	for _, slave := range d.slaves {
+		if slave.GetScenario() == d.scenario {
+			slave.ApplyScenario(ScenarioChaos)
+			restored++
+			continue
+		}
+		// 受保護期間被抑制的擾動，保護結束後恢復為 chaos 場景而非擾動
+		e.protMu.Lock()
+		if scenario, ok := e.suppressed[slave.ID]; ok && scenario == d.scenario {
+			e.suppressed[slave.ID] = ScenarioChaos
+		}
+		e.protMu.Unlock()
+	}
+	e.logger.Info(T("混沌模式擾動結束"),
+		zap.String("scenario", d.scenario.String()),
+		zap.Int("slaves", restored),
+		zap.Duration("duration", now.Sub(d.started)),
+	)
+}
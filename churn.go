@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 連線擾動場景的中斷方式
+const (
+	ChurnModeRST = "rst" // 以 RST 中斷 (SO_LINGER 0)
+	ChurnModeFIN = "fin" // 正常關閉 (FIN)
+)
+
+// ChurnModes 所有連線中斷方式
+var ChurnModes = []string{ChurnModeRST, ChurnModeFIN}
+
+// DefaultChurnRate 每個 Slave 每分鐘強制中斷的連線數預設值
+const DefaultChurnRate = 6
+
+// isChurnMode 是否為有效的連線中斷方式
+func isChurnMode(mode string) bool {
+	for _, m := range ChurnModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// --- Connection Churn Scenario ---
+
+// ConnectionChurnScenario 連線擾動場景 - 由伺服器端隨機中斷既有連線，測試 EMS 的重連風暴
+// 暫存器值維持正常波動；listener 持續接受新連線，連線的中斷由 Slave 依 churn_rate/churn_modes 執行
+type ConnectionChurnScenario struct {
+	normalScenario NormalScenario
+}
+
+func (s *ConnectionChurnScenario) Type() ScenarioType {
+	return ScenarioConnectionChurn
+}
+
+func (s *ConnectionChurnScenario) Update(registers *RegisterMap, params ScenarioParams) {
+	s.normalScenario.Update(registers, ScenarioParams{
+		VoltageVariance:   0.005,
+		FrequencyVariance: 0.0005,
+	})
+}
+
+func (s *ConnectionChurnScenario) Reset(registers *RegisterMap) {
+	s.normalScenario.Reset(registers)
+}
+
+// updateChurn 依上次執行後經過的時間與 churn_rate 決定本次中斷的連線數，
+// 每條連線依 churn_modes 隨機以 RST 或 FIN 中斷
+func (s *Slave) updateChurn(params ScenarioParams) {
+	rate := params.ChurnRate
+	if rate == 0 {
+		rate = DefaultChurnRate
+	}
+	modes := params.ChurnModes
+	if len(modes) == 0 {
+		modes = ChurnModes
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	last := s.churnAt
+	s.churnAt = now
+	s.mu.Unlock()
+	if last.IsZero() || s.State() != SlaveStateRunning {
+		return
+	}
+
+	random := s.handler.rand()
+	expected := rate * now.Sub(last).Minutes()
+	count := int(expected)
+	if random.Float64() < expected-float64(count) {
+		count++
+	}
+
+	conns := s.tcpConns()
+	for i, j := range random.Perm(len(conns)) {
+		if i == count {
+			break
+		}
+		conn := conns[j]
+		mode := modes[random.Intn(len(modes))]
+		if tcpConn, ok := conn.(*net.TCPConn); ok && mode == ChurnModeRST {
+			tcpConn.SetLinger(0)
+		}
+		conn.Close()
+		s.stats.ForcedDisconnects.Add(1)
+		s.logger.Debug(T("強制中斷連線"),
+			zap.String("id", s.ID),
+			zap.String("remote", conn.RemoteAddr().String()),
+			zap.String("mode", mode),
+		)
+	}
+}
+
+// tcpConns Slave 所有埠號上開啟中的 Modbus TCP 連線
+func (s *Slave) tcpConns() []net.Conn {
+	s.listenMu.Lock()
+	listeners := make([]*slaveListener, 0, 1+len(s.protocolListeners))
+	if s.listener != nil {
+		listeners = append(listeners, s.listener)
+	}
+	for _, p := range s.protocolListeners {
+		if l, ok := p.(*slaveListener); ok {
+			listeners = append(listeners, l)
+		}
+	}
+	s.listenMu.Unlock()
+
+	var conns []net.Conn
+	for _, l := range listeners {
+		l.mu.Lock()
+		for conn := range l.conns {
+			conns = append(conns, conn)
+		}
+		l.mu.Unlock()
+	}
+	return conns
+}
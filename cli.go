@@ -2,9 +2,17 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -14,6 +22,9 @@ import (
 
 var (
 	cfgFile   string
+	apiURL    string
+	langFlag  string
+	seedFlag  int64
 	logger    *zap.Logger
 	appConfig *Config
 )
@@ -29,8 +40,9 @@ var rootCmd = &cobra.Command{
 		var err error
 		logger, err = initLogger()
 		if err != nil {
-			return fmt.Errorf("初始化日誌失敗: %w", err)
+			return fmt.Errorf(T("初始化日誌失敗: %w"), err)
 		}
+		zap.ReplaceGlobals(logger)
 
 		// 載入配置 (除了 version 和 help 命令)
 		if cmd.Name() != "version" && cmd.Name() != "help" && cmd.Name() != "generate" {
@@ -39,9 +51,17 @@ var rootCmd = &cobra.Command{
 				// 配置載入失敗時使用預設值
 				appConfig = DefaultConfig()
 				if cfgFile != "" {
-					logger.Warn("載入配置檔失敗，使用預設配置", zap.Error(err))
+					logger.Warn(T("載入配置檔失敗，使用預設配置"), zap.Error(err))
 				}
 			}
+
+			// 未指定 --lang 時依配置檔的語系
+			if !cmd.Flags().Changed("lang") {
+				SetLanguage(appConfig.Language)
+			}
+			if cmd.Flags().Changed("seed") {
+				appConfig.Seed = seedFlag
+			}
 		}
 		return nil
 	},
@@ -68,56 +88,173 @@ var startCmd = &cobra.Command{
 		if port, _ := cmd.Flags().GetInt("port"); port > 0 {
 			appConfig.Server.Port = port
 		}
+		if profile, _ := cmd.Flags().GetString("profile"); profile != "" {
+			if _, ok := GetDeviceProfile(profile); !ok {
+				return fmt.Errorf(T("未知的設備設定檔: %s"), profile)
+			}
+			appConfig.Slaves.Profile = profile
+		}
+		if identity, _ := cmd.Flags().GetString("identity"); identity != "" {
+			if identity != SlaveIdentityStatic && identity != SlaveIdentityPod {
+				return fmt.Errorf(T("不支援的 Slave 身分來源: %s (可用: static, pod)"), identity)
+			}
+			appConfig.Slaves.Identity = identity
+		}
+		if u, _ := cmd.Flags().GetString("user"); u != "" {
+			appConfig.Privilege.User = u
+		}
+		if g, _ := cmd.Flags().GetString("group"); g != "" {
+			appConfig.Privilege.Group = g
+		}
+		if speed, _ := cmd.Flags().GetFloat64("speed"); speed > 0 {
+			appConfig.Clock.Speed = speed
+		}
 
-		logger.Info("啟動 Modbus 模擬器",
-			zap.Int("port", appConfig.Server.Port),
-			zap.Int("slaves", appConfig.Slaves.Count),
-		)
-
-		// 建立引擎
-		engine := NewEngine(appConfig, logger)
-
-		// 設置優雅關閉
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
-
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+		daemon, _ := cmd.Flags().GetBool("daemon")
+		pidFile, _ := cmd.Flags().GetString("pid-file")
+		if daemon && pidFile == "" {
+			pidFile = DefaultPIDFile
+		}
 
-		// 啟動引擎
-		if err := engine.Start(ctx); err != nil {
-			return fmt.Errorf("啟動引擎失敗: %w", err)
+		// 以相同參數在背景重新執行，子程序完成啟動後才返回
+		if daemon && !isDaemonChild() {
+			if err := checkPIDFile(pidFile); err != nil {
+				return err
+			}
+			logFile, _ := cmd.Flags().GetString("log-file")
+			pid, err := startDaemon(os.Args[1:], logFile)
+			if err != nil {
+				return err
+			}
+			fmt.Printf(T("模擬器已在背景啟動 (PID %d，%s)\n"), pid, pidFile)
+			return nil
 		}
 
-		// 啟動指標收集器
-		if appConfig.Metrics.Enabled {
-			metrics := NewMetricsCollector(engine, logger)
-			if err := metrics.Start(appConfig.Metrics.Endpoint, appConfig.Metrics.Port); err != nil {
-				logger.Warn("啟動指標伺服器失敗", zap.Error(err))
-			} else {
-				logger.Info("指標伺服器已啟動",
-					zap.Int("port", appConfig.Metrics.Port),
-					zap.String("endpoint", appConfig.Metrics.Endpoint),
-				)
+		if pidFile != "" {
+			if err := writePIDFile(pidFile); err != nil {
+				return err
 			}
+			defer removePIDFile(pidFile)
 		}
+		return runSimulator(cmd, func(ctx context.Context, engine *Engine) {
+			notifyDaemonReady()
+			notifySystemdReady(ctx, engine)
+		})
+	},
+}
 
-		// 等待信號
-		sig := <-sigChan
-		logger.Info("收到關閉信號", zap.String("signal", sig.String()))
+// runSimulator 啟動引擎並運行到收到關閉信號；background 於降級權限後在背景執行，引擎停止前取消
+func runSimulator(cmd *cobra.Command, background func(ctx context.Context, engine *Engine)) error {
+	logger.Info(T("啟動 Modbus 模擬器"),
+		zap.Int("port", appConfig.Server.Port),
+		zap.Int("slaves", appConfig.Slaves.Count),
+	)
+
+	// 建立引擎
+	engine := NewEngine(appConfig, logger)
+
+	// 設置優雅關閉
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-		// 優雅關閉
-		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), appConfig.Server.GracefulTimeout)
-		defer shutdownCancel()
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-		if err := engine.Stop(shutdownCtx); err != nil {
-			logger.Error("關閉引擎失敗", zap.Error(err))
+	// 降級權限前先配置虛擬 IP
+	if setup, _ := cmd.Flags().GetBool("setup-network"); setup && len(appConfig.Network.IPRanges) > 0 {
+		// 通告速率未設定時跟隨 Slave 上線速率
+		network := appConfig.Network
+		if network.AnnounceRate == 0 {
+			network.AnnounceRate = appConfig.Slaves.RampUpRate
+		}
+		provisioner := NewNetworkProvisioner(network, logger)
+		if err := provisioner.Setup(ctx, appConfig.Network.IPRanges); err != nil {
+			return fmt.Errorf(T("設置虛擬 IP 失敗: %w"), err)
+		}
+	}
+
+	// 啟動引擎
+	if err := engine.Start(ctx); err != nil {
+		return fmt.Errorf(T("啟動引擎失敗: %w"), err)
+	}
+
+	// 自上次關閉時保存的快照接續 (逐步上線的 Slave 於啟動時還原)
+	snapshotPath, _ := cmd.Flags().GetString("snapshot")
+	if snapshotPath != "" {
+		if err := restoreSnapshotFile(engine, snapshotPath); err != nil {
+			engine.Stop(context.Background())
 			return err
 		}
+	}
+
+	// 啟動指標收集器 (同時提供管理 API)
+	if appConfig.Metrics.Enabled {
+		metrics := NewMetricsCollector(engine, logger)
+		NewAdminAPI(engine, logger).Register(metrics.Mux())
+		if appConfig.Metrics.UI {
+			RegisterDashboard(metrics.Mux())
+		}
+		if err := metrics.Start(appConfig.Metrics.Endpoint, appConfig.Metrics.Port); err != nil {
+			logger.Warn(T("啟動指標伺服器失敗"), zap.Error(err))
+		} else {
+			logger.Info(T("指標伺服器已啟動"),
+				zap.Int("port", appConfig.Metrics.Port),
+				zap.String("endpoint", appConfig.Metrics.Endpoint),
+			)
+		}
+	}
+
+	// 所有 listener 已綁定，切換為一般使用者；失敗時不以 root 繼續運行
+	if err := DropPrivileges(appConfig.Privilege, logger); err != nil {
+		engine.Stop(context.Background())
+		return err
+	}
+
+	bgCtx, bgCancel := context.WithCancel(ctx)
+	defer bgCancel()
+	if background != nil {
+		go background(bgCtx, engine)
+	}
+
+	// 等待信號
+	sig := <-sigChan
+	logger.Info(T("收到關閉信號"), zap.String("signal", sig.String()))
+	bgCancel()
+	sdNotify("STOPPING=1")
+
+	if snapshotPath != "" {
+		if err := SaveSnapshot(snapshotPath, engine.Snapshot()); err != nil {
+			logger.Error(T("保存快照失敗"), zap.Error(err))
+		} else {
+			logger.Info(T("已保存快照"), zap.String("path", snapshotPath))
+		}
+	}
+
+	// 優雅關閉
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), appConfig.Server.GracefulTimeout)
+	defer shutdownCancel()
+
+	if err := engine.Stop(shutdownCtx); err != nil {
+		logger.Error(T("關閉引擎失敗"), zap.Error(err))
+		return err
+	}
 
-		logger.Info("模擬器已停止")
+	logger.Info(T("模擬器已停止"))
+	return nil
+}
+
+// restoreSnapshotFile 還原快照檔 (檔案不存在時以初始狀態啟動)
+func restoreSnapshotFile(engine *Engine, path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		logger.Info(T("快照檔不存在，以初始狀態啟動"), zap.String("path", path))
 		return nil
-	},
+	}
+	snap, err := LoadSnapshot(path)
+	if err != nil {
+		return err
+	}
+	_, err = engine.RestoreSnapshot(snap)
+	return err
 }
 
 // stopCmd 停止命令
@@ -127,32 +264,53 @@ var stopCmd = &cobra.Command{
 	Long:  "停止正在運行的 Modbus TCP 模擬器。",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// 透過向 PID 發送信號來停止
-		pidFile := "/var/run/modbussim.pid"
+		pidFile := DefaultPIDFile
 		if pid, _ := cmd.Flags().GetString("pid-file"); pid != "" {
 			pidFile = pid
 		}
+		wait, _ := cmd.Flags().GetDuration("wait")
 
-		data, err := os.ReadFile(pidFile)
+		pid, err := stopProcess(pidFile, wait)
 		if err != nil {
-			return fmt.Errorf("讀取 PID 檔案失敗: %w", err)
+			return err
 		}
-
-		var pid int
-		if _, err := fmt.Sscanf(string(data), "%d", &pid); err != nil {
-			return fmt.Errorf("解析 PID 失敗: %w", err)
+		if wait > 0 {
+			fmt.Printf(T("模擬器已停止 (PID %d)\n"), pid)
+			return nil
 		}
+		fmt.Printf(T("已發送停止信號到 PID %d\n"), pid)
+		return nil
+	},
+}
 
-		process, err := os.FindProcess(pid)
-		if err != nil {
-			return fmt.Errorf("找不到程序: %w", err)
+// restartCmd 重新啟動命令
+var restartCmd = &cobra.Command{
+	Use:   "restart",
+	Short: "重新啟動模擬器",
+	Long:  "停止 PID 檔案中的實例並等待其結束，再以相同的 start 參數在背景啟動 (沒有執行中的實例時直接啟動)。",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pidFile, _ := cmd.Flags().GetString("pid-file")
+		if pidFile == "" {
+			pidFile = DefaultPIDFile
 		}
+		wait, _ := cmd.Flags().GetDuration("wait")
 
-		if err := process.Signal(syscall.SIGTERM); err != nil {
-			return fmt.Errorf("發送信號失敗: %w", err)
+		if pid, err := stopProcess(pidFile, wait); err == nil {
+			fmt.Printf(T("模擬器已停止 (PID %d)\n"), pid)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		} else {
+			fmt.Println(T("沒有執行中的實例，直接啟動"))
 		}
 
-		fmt.Printf("已發送停止信號到 PID %d\n", pid)
-		return nil
+		exe, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf(T("取得執行檔路徑失敗: %w"), err)
+		}
+		start := exec.Command(exe, restartArgs(os.Args[1:])...)
+		start.Stdout = os.Stdout
+		start.Stderr = os.Stderr
+		return start.Run()
 	},
 }
 
@@ -163,8 +321,8 @@ var statusCmd = &cobra.Command{
 	Long:  "顯示模擬器的當前運行狀態和統計資訊。",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// TODO: 從運行中的實例取得狀態
-		fmt.Println("狀態查詢功能尚未實作")
-		fmt.Println("請使用 metrics endpoint 查看詳細狀態")
+		fmt.Println(T("狀態查詢功能尚未實作"))
+		fmt.Println(T("請使用 metrics endpoint 查看詳細狀態"))
 		return nil
 	},
 }
@@ -186,6 +344,9 @@ var networkSetupCmd = &cobra.Command{
 		if iface != "" {
 			appConfig.Network.Interface = iface
 		}
+		if err := applyNetworkModeFlags(cmd); err != nil {
+			return err
+		}
 
 		startIP, _ := cmd.Flags().GetString("start")
 		endIP, _ := cmd.Flags().GetString("end")
@@ -197,19 +358,37 @@ var networkSetupCmd = &cobra.Command{
 			appConfig.Network.IPRanges = []IPRange{{Start: startIP, End: endIP}}
 		}
 
-		provisioner := NewNetworkProvisioner(appConfig.Network.Interface, logger)
+		provisioner := NewNetworkProvisioner(appConfig.Network, logger)
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
 		if err := provisioner.Setup(ctx, appConfig.Network.IPRanges); err != nil {
-			return fmt.Errorf("設置網路失敗: %w", err)
+			return fmt.Errorf(T("設置網路失敗: %w"), err)
 		}
 
-		fmt.Println("虛擬 IP 設置完成")
+		fmt.Println(T("虛擬 IP 設置完成"))
 		return nil
 	},
 }
 
+// applyNetworkModeFlags 以命令列參數覆寫虛擬 IP 的配置方式與專用介面
+func applyNetworkModeFlags(cmd *cobra.Command) error {
+	flags := cmd.Flags()
+	if flags.Changed("mode") {
+		appConfig.Network.Mode, _ = flags.GetString("mode")
+	}
+	if flags.Changed("link") {
+		appConfig.Network.Link, _ = flags.GetString("link")
+	}
+	if flags.Changed("parent") {
+		appConfig.Network.Parent, _ = flags.GetString("parent")
+	}
+	if flags.Changed("mtu") {
+		appConfig.Network.MTU, _ = flags.GetInt("mtu")
+	}
+	return appConfig.Network.validateMode()
+}
+
 // networkTeardownCmd 移除網路
 var networkTeardownCmd = &cobra.Command{
 	Use:   "teardown",
@@ -220,16 +399,19 @@ var networkTeardownCmd = &cobra.Command{
 		if iface != "" {
 			appConfig.Network.Interface = iface
 		}
+		if err := applyNetworkModeFlags(cmd); err != nil {
+			return err
+		}
 
-		provisioner := NewNetworkProvisioner(appConfig.Network.Interface, logger)
+		provisioner := NewNetworkProvisioner(appConfig.Network, logger)
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
 		if err := provisioner.Teardown(ctx); err != nil {
-			return fmt.Errorf("移除網路失敗: %w", err)
+			return fmt.Errorf(T("移除網路失敗: %w"), err)
 		}
 
-		fmt.Println("虛擬 IP 已移除")
+		fmt.Println(T("虛擬 IP 已移除"))
 		return nil
 	},
 }
@@ -244,22 +426,25 @@ var networkListCmd = &cobra.Command{
 		if iface != "" {
 			appConfig.Network.Interface = iface
 		}
+		if err := applyNetworkModeFlags(cmd); err != nil {
+			return err
+		}
 
-		provisioner := NewNetworkProvisioner(appConfig.Network.Interface, logger)
+		provisioner := NewNetworkProvisioner(appConfig.Network, logger)
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 
 		ips, err := provisioner.List(ctx)
 		if err != nil {
-			return fmt.Errorf("列出 IP 失敗: %w", err)
+			return fmt.Errorf(T("列出 IP 失敗: %w"), err)
 		}
 
 		if len(ips) == 0 {
-			fmt.Println("目前沒有配置虛擬 IP")
+			fmt.Println(T("目前沒有配置虛擬 IP"))
 			return nil
 		}
 
-		fmt.Printf("已配置的虛擬 IP (%d 個):\n", len(ips))
+		fmt.Printf(T("已配置的虛擬 IP (%d 個):\n"), len(ips))
 		for _, ip := range ips {
 			fmt.Printf("  - %s\n", ip.String())
 		}
@@ -284,13 +469,30 @@ var scenarioListCmd = &cobra.Command{
 			Name        string
 			Description string
 		}{
-			{"normal", "正常波動 (電壓 ±0.5%, 頻率 ±0.05%)"},
-			{"voltage_sag", "電壓驟降至 80%"},
-			{"jitter", "網路延遲 100-500ms"},
-			{"packet_loss", "封包丟失模擬 (5%)"},
+			{"normal", T("正常波動 (電壓 ±0.5%, 頻率 ±0.05%)")},
+			{"voltage_sag", T("電壓驟降至 80%")},
+			{"jitter", T("網路延遲 100-500ms")},
+			{"packet_loss", T("封包丟失模擬 (5%)")},
+			{"phase_imbalance", T("三相不平衡 (單相電壓 -10%、電流 +10%)")},
+			{"connection_flap", T("斷線閃斷 (離線 5s / 上線 15s 交替)")},
+			{"slow_drain", T("慢速回應 (逐位元組寫出，10 bytes/sec)")},
+			{"data_freeze", T("資料凍結 (指定暫存器停止更新，模擬感測器卡死)")},
+			{"fragmented_response", T("分段回應 (每 3 bytes 一個 TCP 區段，間隔 50ms)")},
+			{"exception_storm", T("例外風暴 (20% 請求回應 Illegal Data Address / Busy / Failure)")},
+			{"corrupted_response", T("損壞回應 (10% 回應 Byte Count/Transaction ID 錯誤、截斷或亂碼)")},
+			{"load_profile", T("日負載曲線 (電流/功率依 24 小時曲線變化，14:00 尖峰)")},
+			{"script", T("Lua 腳本 (依 script 指定的腳本更新暫存器)")},
+			{"plugin", T("外部外掛 (以 gRPC 呼叫 plugin 指定的外掛程序)")},
+			{"replay", T("擷取重播 (依 capture 指定的 pcap 擷取檔重現暫存器變化)")},
+			{"long_command", T("長時間命令 (寫入命令暫存器回應 Acknowledge，狀態暫存器 10s 後由執行中轉為完成)")},
+			{"connection_churn", T("連線擾動 (每分鐘隨機以 RST 或 FIN 中斷 6 條既有連線)")},
+			{"transaction_mismatch", T("交易錯亂 (10% 回應使用錯誤或上一個 Transaction ID，或與下一個回應對調順序)")},
+			{"harmonic_distortion", T("諧波失真 (每 1m 發生 20s 的諧波事件，電壓 THD 升至 8%、以第 5 次諧波為主，閃爍 Pst 1.2)")},
+			{"composite", T("組合場景 (同時運行 components 列出的場景，預設 voltage_sag + jitter + packet_loss)")},
+			{"chaos", T("混沌模式 (每 30s-2m 隨機挑選 5-20% 的 Slave 短暫套用 sag/flap/例外風暴/jitter)")},
 		}
 
-		fmt.Println("可用的模擬場景:")
+		fmt.Println(T("可用的模擬場景:"))
 		for _, s := range scenarios {
 			fmt.Printf("  %-15s %s\n", s.Name, s.Description)
 		}
@@ -308,9 +510,9 @@ var scenarioApplyCmd = &cobra.Command{
 		duration, _ := cmd.Flags().GetDuration("duration")
 
 		// TODO: 透過 API 或共享記憶體通知運行中的實例
-		fmt.Printf("套用場景: %s", scenarioName)
+		fmt.Printf(T("套用場景: %s"), scenarioName)
 		if duration > 0 {
-			fmt.Printf(" (持續 %v)", duration)
+			fmt.Printf(T(" (持續 %v)"), duration)
 		}
 		fmt.Println()
 
@@ -325,115 +527,1561 @@ var scenarioResetCmd = &cobra.Command{
 	Long:  "重設模擬器為正常運行模式。",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// TODO: 透過 API 或共享記憶體通知運行中的實例
-		fmt.Println("重設為正常模式")
+		fmt.Println(T("重設為正常模式"))
 		return nil
 	},
 }
 
-// configCmd 配置命令組
-var configCmd = &cobra.Command{
-	Use:   "config",
-	Short: "配置管理命令",
-	Long:  "管理配置檔。",
+// scenarioTuneCmd 即時調整場景參數
+var scenarioTuneCmd = &cobra.Command{
+	Use:   "tune [key=value ...]",
+	Short: "即時調整場景參數",
+	Long:  "即時調整運行中實例的場景參數 (例如 packet_loss_rate=0.05 jitter_max=500ms)，立即套用到運行該場景的 Slave，不需重新套用場景。清單以逗號分隔 (corrupt_modes=truncate,garbage)，對應表以點號指定 (exception_weights.slave_device_busy=3)；未指定參數時顯示目前生效的參數。",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := "/api/scenario/params"
+		if scenario, _ := cmd.Flags().GetString("scenario"); scenario != "" {
+			path += "?scenario=" + url.QueryEscape(scenario)
+		}
+
+		var status ScenarioParamsStatus
+		reset, _ := cmd.Flags().GetBool("reset")
+		switch {
+		case reset:
+			if err := callAdminAPI(apiURL, "DELETE", path, nil, &status); err != nil {
+				return err
+			}
+			fmt.Printf(T("場景 %s 的參數已還原為配置檔的設定 (%d 個 Slave)\n"), status.Scenario, status.Slaves)
+			return nil
+		case len(args) == 0:
+			if err := callAdminAPI(apiURL, "GET", path, nil, &status); err != nil {
+				return err
+			}
+		default:
+			patch, err := parseTuneArgs(args)
+			if err != nil {
+				return err
+			}
+			if err := callAdminAPI(apiURL, "PATCH", path, patch, &status); err != nil {
+				return err
+			}
+			fmt.Printf(T("場景 %s 的參數已調整 (%d 個 Slave)\n"), status.Scenario, status.Slaves)
+		}
+
+		data, err := json.MarshalIndent(status.Params, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	},
 }
 
-// configValidateCmd 驗證配置
-var configValidateCmd = &cobra.Command{
-	Use:   "validate",
-	Short: "驗證配置檔",
-	Long:  "驗證指定的配置檔是否有效。",
+// scenarioChaosCmd 混沌模式狀態
+var scenarioChaosCmd = &cobra.Command{
+	Use:   "chaos",
+	Short: "查看混沌模式狀態",
+	Long:  "查看運行中實例的混沌模式 (整體場景為 chaos 時隨機注入擾動) 的狀態、下次注入時間與進行中的擾動。",
+	Args:  cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := LoadConfig(cfgFile)
-		if err != nil {
-			return fmt.Errorf("配置驗證失敗: %w", err)
+		var status ChaosStatus
+		if err := callAdminAPI(apiURL, "GET", "/api/chaos", nil, &status); err != nil {
+			return err
 		}
 
-		fmt.Println("配置驗證通過")
-		fmt.Printf("  Slaves: %d\n", cfg.Slaves.Count)
-		fmt.Printf("  Port: %d\n", cfg.Server.Port)
-		fmt.Printf("  Interface: %s\n", cfg.Network.Interface)
-		fmt.Printf("  IP Ranges: %d\n", len(cfg.Network.IPRanges))
+		if !status.Running {
+			fmt.Printf(T("混沌模式未運行 (累計注入 %d 次擾動)\n"), status.Injected)
+			return nil
+		}
+		fmt.Printf(T("混沌模式運行中: 下次注入 %s，累計注入 %d 次擾動\n"), status.NextAt.Format(time.RFC3339), status.Injected)
+		for _, d := range status.Disturbances {
+			fmt.Printf(T("  %-20s 至 %s: %s\n"), d.Scenario, d.Until.Format(time.RFC3339), strings.Join(d.Slaves, ", "))
+		}
 		return nil
 	},
 }
 
-// configGenerateCmd 生成配置
-var configGenerateCmd = &cobra.Command{
-	Use:   "generate",
-	Short: "生成範例配置",
-	Long:  "生成範例配置檔。",
+// parseTuneArgs 將 key=value 參數轉為場景參數的 JSON 物件：數值轉為數字，*_modes、*_scenarios 以逗號分隔為清單，
+// 含點號的鍵 (exception_weights.slave_device_busy) 轉為巢狀物件
+func parseTuneArgs(args []string) (map[string]any, error) {
+	patch := make(map[string]any)
+	for _, arg := range args {
+		key, raw, ok := strings.Cut(arg, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf(T("無效的參數 (需為 key=value): %s"), arg)
+		}
+		var value any = raw
+		if f, err := strconv.ParseFloat(raw, 64); err == nil {
+			value = f
+		} else if strings.HasSuffix(key, "_modes") || strings.HasSuffix(key, "_scenarios") {
+			value = strings.Split(raw, ",")
+		}
+
+		if parent, child, nested := strings.Cut(key, "."); nested {
+			m, _ := patch[parent].(map[string]any)
+			if m == nil {
+				m = make(map[string]any)
+				patch[parent] = m
+			}
+			m[child] = value
+			continue
+		}
+		patch[key] = value
+	}
+	return patch, nil
+}
+
+// pairCmd 主備配對命令組
+var pairCmd = &cobra.Command{
+	Use:   "pair",
+	Short: "主備配對命令",
+	Long:  "查看與切換運行中實例的主備配對。",
+}
+
+// pairListCmd 列出配對
+var pairListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出主備配對",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		output, _ := cmd.Flags().GetString("output")
-		if output == "" {
-			output = "config.json"
+		var pairs []PairStatus
+		if err := callAdminAPI(apiURL, "GET", "/api/pairs", nil, &pairs); err != nil {
+			return err
 		}
 
-		cfg := DefaultConfig()
+		if len(pairs) == 0 {
+			fmt.Println(T("目前沒有主備配對"))
+			return nil
+		}
 
-		// 添加範例 IP 範圍
-		cfg.Network.IPRanges = []IPRange{
-			{Start: "192.168.1.101", End: "192.168.1.200"},
+		fmt.Printf("%-15s %-16s %-16s %s\n", "NAME", "ACTIVE", "STANDBY", "FAILOVERS")
+		for _, p := range pairs {
+			fmt.Printf("%-15s %-16s %-16s %d\n", p.Name, p.Active, p.Standby, p.Failovers)
 		}
+		return nil
+	},
+}
 
-		if err := cfg.SaveConfig(output); err != nil {
-			return fmt.Errorf("生成配置失敗: %w", err)
+// pairFailoverCmd 主備切換
+var pairFailoverCmd = &cobra.Command{
+	Use:   "failover [pair]",
+	Short: "主備切換",
+	Long:  "切換指定配對的作用端與備援端。",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var status PairStatus
+		if err := callAdminAPI(apiURL, "POST", "/api/pairs/"+args[0]+"/failover", nil, &status); err != nil {
+			return err
 		}
 
-		fmt.Printf("範例配置已生成: %s\n", output)
+		fmt.Printf(T("配對 %s 已切換: 作用端 %s，備援端 %s\n"), status.Name, status.Active, status.Standby)
 		return nil
 	},
 }
 
-// versionCmd 版本命令
-var versionCmd = &cobra.Command{
-	Use:   "version",
-	Short: "顯示版本資訊",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("modbussim version %s\n", Version)
-		fmt.Printf("  Build: %s\n", BuildTime)
-		fmt.Printf("  Commit: %s\n", GitCommit)
+// domainCmd 故障域命令組
+var domainCmd = &cobra.Command{
+	Use:   "domain",
+	Short: "故障域命令",
+	Long:  "查看運行中實例的故障域，並觸發或結束域內 Slave 同時斷線。",
+}
+
+// domainListCmd 列出故障域
+var domainListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出故障域",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var domains []DomainStatus
+		if err := callAdminAPI(apiURL, "GET", "/api/domains", nil, &domains); err != nil {
+			return err
+		}
+
+		if len(domains) == 0 {
+			fmt.Println(T("目前沒有故障域"))
+			return nil
+		}
+
+		fmt.Printf("%-15s %-8s %-6s %s\n", "NAME", "MEMBERS", "DOWN", "OUTAGES")
+		for _, d := range domains {
+			fmt.Printf("%-15s %-8d %-6t %d\n", d.Name, d.Members, d.Down, d.Outages)
+		}
+		return nil
 	},
 }
 
-func init() {
-	// 全域 flags
-	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "配置檔路徑")
+// domainOutageCmd 觸發故障域停擺
+var domainOutageCmd = &cobra.Command{
+	Use:   "outage [domain]",
+	Short: "故障域停擺",
+	Long:  "讓指定故障域內的所有 Slave 同時離線，經過指定時間後一起恢復；--restore 提前恢復。",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := "/api/domains/" + args[0] + "/outage"
 
-	// start 命令 flags
-	startCmd.Flags().StringP("ip", "i", "", "起始 IP 位址")
-	startCmd.Flags().IntP("count", "n", 0, "Slave 數量")
-	startCmd.Flags().IntP("port", "p", 0, "監聽埠號")
+		var status DomainStatus
+		if restore, _ := cmd.Flags().GetBool("restore"); restore {
+			if err := callAdminAPI(apiURL, "DELETE", path, nil, &status); err != nil {
+				return err
+			}
+			fmt.Printf(T("故障域 %s 已恢復\n"), status.Name)
+			return nil
+		}
 
-	// stop 命令 flags
-	stopCmd.Flags().String("pid-file", "/var/run/modbussim.pid", "PID 檔案路徑")
+		var req DomainOutageRequest
+		if duration, _ := cmd.Flags().GetDuration("duration"); duration > 0 {
+			req.Duration = duration.String()
+		}
+		if err := callAdminAPI(apiURL, "POST", path, req, &status); err != nil {
+			return err
+		}
 
-	// network 命令 flags
-	networkSetupCmd.Flags().StringP("interface", "i", "eth0", "網路介面")
-	networkSetupCmd.Flags().String("start", "", "起始 IP")
-	networkSetupCmd.Flags().String("end", "", "結束 IP")
-	networkSetupCmd.Flags().String("cidr", "", "CIDR 表示法")
+		fmt.Printf(T("故障域 %s 停擺中: %d 個 Slave 離線，至 %s\n"), status.Name, status.Members, status.Until.Format(time.RFC3339))
+		return nil
+	},
+}
 
-	networkTeardownCmd.Flags().StringP("interface", "i", "eth0", "網路介面")
-	networkListCmd.Flags().StringP("interface", "i", "eth0", "網路介面")
+// bootStormCmd 開機風暴
+var bootStormCmd = &cobra.Command{
+	Use:   "bootstorm",
+	Short: "開機風暴",
+	Long:  "模擬全場斷電後復電：Slave 同時離線，經過 --outage 後在 --stagger 時間窗內幾乎同時上線，用於測試 EMS 的重新連線湧入；--status 查看進度，--restore 立即復電。",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var status BootStormStatus
+		showStatus, _ := cmd.Flags().GetBool("status")
+		restore, _ := cmd.Flags().GetBool("restore")
+
+		switch {
+		case showStatus:
+			if err := callAdminAPI(apiURL, "GET", "/api/bootstorm", nil, &status); err != nil {
+				return err
+			}
+		case restore:
+			if err := callAdminAPI(apiURL, "DELETE", "/api/bootstorm", nil, &status); err != nil {
+				return err
+			}
+		default:
+			var req BootStormRequest
+			if outage, _ := cmd.Flags().GetDuration("outage"); outage > 0 {
+				req.Outage = outage.String()
+			}
+			if cmd.Flags().Changed("stagger") {
+				stagger, _ := cmd.Flags().GetDuration("stagger")
+				req.Stagger = stagger.String()
+			}
+			req.Targets, _ = cmd.Flags().GetStringSlice("target")
+			if err := callAdminAPI(apiURL, "POST", "/api/bootstorm", req, &status); err != nil {
+				return err
+			}
+		}
 
-	// scenario 命令 flags
-	scenarioApplyCmd.Flags().DurationP("duration", "d", 0, "場景持續時間")
+		fmt.Printf(T("開機風暴 %s: %d/%d 個 Slave 已上線 (斷電 %s，復電 %s，錯開 %s)\n"),
+			status.Phase, status.Online, status.Members,
+			status.PowerOffAt.Format(time.RFC3339), status.RestoreAt.Format(time.RFC3339), status.Stagger)
+		if status.Protected > 0 {
+			fmt.Printf(T("略過 %d 個受保護的 Slave\n"), status.Protected)
+		}
+		return nil
+	},
+}
 
-	// config 命令 flags
-	configGenerateCmd.Flags().StringP("output", "o", "config.json", "輸出檔案路徑")
+// clockCmd 模擬時鐘
+var clockCmd = &cobra.Command{
+	Use:   "clock",
+	Short: "查看或調整模擬時鐘",
+	Long:  "查看運行中實例的模擬時間與倍率；--speed 調整倍率 (例如 60 = 實際 1 分鐘模擬 1 小時)，電能累積、日負載曲線與場景持續時間隨之加速。",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var status ClockStatus
+		if cmd.Flags().Changed("speed") {
+			speed, _ := cmd.Flags().GetFloat64("speed")
+			if err := callAdminAPI(apiURL, "PUT", "/api/clock", ClockRequest{Speed: speed}, &status); err != nil {
+				return err
+			}
+		} else if err := callAdminAPI(apiURL, "GET", "/api/clock", nil, &status); err != nil {
+			return err
+		}
+
+		fmt.Printf(T("模擬時間: %s (倍率 %gx，啟動後經過 %s)\n"),
+			status.Now.Format(time.RFC3339), status.Speed, status.Elapsed.Round(time.Second))
+		return nil
+	},
+}
+
+// slaveCmd Slave 命令組
+var slaveCmd = &cobra.Command{
+	Use:   "slave",
+	Short: "Slave 操作命令",
+	Long:  "操作運行中實例的個別 Slave。",
+}
+
+// slaveListCmd 列出 Slave
+var slaveListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出 Slave",
+	Long:  "列出運行中實例的所有 Slave；每秒請求數 (RPS) 由間隔 --interval 的兩次查詢計算，0 表示不計算。",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var slaves []SlaveSummary
+		if err := callAdminAPI(apiURL, "GET", "/api/slaves", nil, &slaves); err != nil {
+			return err
+		}
+
+		interval, _ := cmd.Flags().GetDuration("interval")
+		var rps map[string]float64
+		if interval > 0 {
+			before := slaves
+			time.Sleep(interval)
+			slaves = nil
+			if err := callAdminAPI(apiURL, "GET", "/api/slaves", nil, &slaves); err != nil {
+				return err
+			}
+			rps = requestRates(before, slaves, interval)
+		}
+
+		if len(slaves) == 0 {
+			fmt.Println(T("目前沒有 Slave"))
+			return nil
+		}
+
+		fmt.Printf("%-16s %-10s %-10s %-20s %-8s %s\n", "IP", "UNITS", "STATE", "SCENARIO", "RPS", "LAST REQUEST")
+		for _, s := range slaves {
+			units := []string{strconv.Itoa(s.UnitID)}
+			for _, id := range s.UnitIDs {
+				units = append(units, strconv.Itoa(id))
+			}
+			rate := "-"
+			if r, ok := rps[s.ID]; ok {
+				rate = strconv.FormatFloat(r, 'f', 1, 64)
+			}
+			last := "-"
+			if s.LastRequest != nil {
+				last = s.LastRequest.Format(time.RFC3339)
+			}
+			fmt.Printf("%-16s %-10s %-10s %-20s %-8s %s\n", s.IP, strings.Join(units, ","), s.State, s.Scenario, rate, last)
+		}
+		return nil
+	},
+}
+
+// requestRates 依兩次查詢的累計請求數計算各 Slave 的每秒請求數；
+// 經過時間不為正、前一次查詢沒有該 Slave 或計數器重置 (重新啟動) 時不計算
+func requestRates(before, after []SlaveSummary, elapsed time.Duration) map[string]float64 {
+	rates := make(map[string]float64, len(after))
+	if elapsed <= 0 {
+		return rates
+	}
+	previous := make(map[string]uint64, len(before))
+	for _, s := range before {
+		previous[s.ID] = s.Requests
+	}
+	for _, s := range after {
+		if n, ok := previous[s.ID]; ok && s.Requests >= n {
+			rates[s.ID] = float64(s.Requests-n) / elapsed.Seconds()
+		}
+	}
+	return rates
+}
+
+// slaveInspectCmd 查看單一 Slave
+var slaveInspectCmd = &cobra.Command{
+	Use:   "inspect <ip|id>",
+	Short: "查看 Slave 詳細資訊",
+	Long:  "列出指定 Slave 的狀態、統計與所有已定義暫存器的工程值 (依暫存器名稱與單位解碼)；--unit 查看閘道後方的其他設備。",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var slaves []SlaveSummary
+		if err := callAdminAPI(apiURL, "GET", "/api/slaves", nil, &slaves); err != nil {
+			return err
+		}
+		var slave *SlaveSummary
+		for i := range slaves {
+			if slaves[i].ID == args[0] || slaves[i].IP == args[0] {
+				slave = &slaves[i]
+				break
+			}
+		}
+		if slave == nil {
+			return fmt.Errorf(T("找不到 Slave: %s"), args[0])
+		}
+
+		path := "/api/slaves/" + url.PathEscape(slave.ID) + "/registers"
+		if unit, _ := cmd.Flags().GetInt("unit"); unit > 0 {
+			path += "?unit=" + strconv.Itoa(unit)
+		}
+		var registers []RegisterValue
+		if err := callAdminAPI(apiURL, "GET", path, nil, &registers); err != nil {
+			return err
+		}
+
+		fmt.Printf(T("Slave:     %s (%s)\n"), slave.ID, slave.IP)
+		fmt.Printf(T("Unit ID:   %v\n"), append([]int{slave.UnitID}, slave.UnitIDs...))
+		fmt.Printf(T("狀態:      %s\n"), slave.State)
+		fmt.Printf(T("場景:      %s\n"), slave.Scenario)
+		fmt.Printf(T("連線數:    %d\n"), slave.Connections)
+		fmt.Printf(T("請求/錯誤: %d / %d\n"), slave.Requests, slave.Errors)
+		if slave.LastRequest != nil {
+			fmt.Printf(T("上次請求:  %s\n"), slave.LastRequest.Format(time.RFC3339))
+		}
+		fmt.Println()
+
+		fmt.Printf("%-8s %-24s %-14s %-6s %-8s %s\n", "ADDRESS", "NAME", "VALUE", "UNIT", "TYPE", "RAW")
+		for _, r := range registers {
+			value := fmt.Sprint(r.Value)
+			if v, ok := r.Value.(float64); ok {
+				value = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+			dataType := r.DataType
+			if r.Writable {
+				dataType += " rw"
+			}
+			fmt.Printf("%-8d %-24s %-14s %-6s %-8s %v\n", r.Address, r.Name, value, r.Unit, dataType, r.Raw)
+		}
+		return nil
+	},
+}
+
+// slaveBlinkCmd 識別閃爍
+var slaveBlinkCmd = &cobra.Command{
+	Use:   "blink [ip|id]",
+	Short: "識別閃爍",
+	Long:  "讓指定 Slave 的暫存器依樣式輪替並週期切換線圈，方便在 EMS 端辨識對應的設備。",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := "/api/slaves/" + args[0] + "/blink"
+
+		if stop, _ := cmd.Flags().GetBool("stop"); stop {
+			if err := callAdminAPI(apiURL, "DELETE", path, nil, nil); err != nil {
+				return err
+			}
+			fmt.Printf(T("Slave %s 已停止識別閃爍\n"), args[0])
+			return nil
+		}
+
+		duration, _ := cmd.Flags().GetDuration("duration")
+		register, _ := cmd.Flags().GetUint16("register")
+		coil, _ := cmd.Flags().GetInt("coil")
+		req := BlinkRequest{
+			Duration: duration.String(),
+			Register: &register,
+			Coil:     &coil,
+		}
+
+		var status BlinkStatus
+		if err := callAdminAPI(apiURL, "POST", path, req, &status); err != nil {
+			return err
+		}
+
+		fmt.Printf(T("Slave %s 識別閃爍中: 暫存器 %d 樣式 %v"), status.Slave, status.Register, status.Pattern)
+		if status.Coil >= 0 {
+			fmt.Printf(T("，線圈 %d 週期切換"), status.Coil)
+		}
+		fmt.Printf(T("，至 %s\n"), status.Until.Format(time.RFC3339))
+		return nil
+	},
+}
+
+// slaveClockCmd 設備時鐘
+var slaveClockCmd = &cobra.Command{
+	Use:   "clock [ip|id]",
+	Short: "查看或設定設備時鐘",
+	Long:  "查看 Slave 設備時鐘 (slaves.device_clock) 的時間、與參考時間的偏差及走時誤差；--offset 或 --time 設定設備時間 (--offset 0s 即校時)。",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := "/api/slaves/" + args[0] + "/clock"
+
+		var status DeviceClockStatus
+		offset, _ := cmd.Flags().GetString("offset")
+		at, _ := cmd.Flags().GetString("time")
+		if offset != "" || at != "" {
+			if err := callAdminAPI(apiURL, "PUT", path, DeviceClockRequest{Time: at, Offset: offset}, &status); err != nil {
+				return err
+			}
+		} else if err := callAdminAPI(apiURL, "GET", path, nil, &status); err != nil {
+			return err
+		}
+
+		fmt.Printf(T("設備時間: %s (偏差 %s，走時誤差 %+.1f ppm)\n"),
+			status.Time.Format(time.RFC3339), status.Offset.Round(time.Millisecond), status.DriftPPM)
+		if status.SyncedAt != nil {
+			fmt.Printf(T("上次校時: %s\n"), status.SyncedAt.Format(time.RFC3339))
+		}
+		return nil
+	},
+}
+
+// slaveChecksumCmd 暫存器雜湊
+var slaveChecksumCmd = &cobra.Command{
+	Use:   "checksum [ip|id]",
+	Short: "暫存器內容雜湊",
+	Long:  "取得 Slave 暫存器內容的雜湊；未指定 Slave 時列出全部，搭配 --expect 僅列出不符者。",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 1 {
+			var sum SlaveChecksum
+			if err := callAdminAPI(apiURL, "GET", "/api/slaves/"+args[0]+"/checksum", nil, &sum); err != nil {
+				return err
+			}
+			fmt.Printf("%s %s\n", sum.Slave, sum.Checksum)
+			return nil
+		}
+
+		path := "/api/checksums"
+		expect, _ := cmd.Flags().GetString("expect")
+		if expect != "" {
+			path += "?expect=" + url.QueryEscape(expect)
+		}
+
+		var report ChecksumReport
+		if err := callAdminAPI(apiURL, "GET", path, nil, &report); err != nil {
+			return err
+		}
+
+		for _, sum := range report.Slaves {
+			fmt.Printf("%-22s %s\n", sum.Slave, sum.Checksum)
+		}
+		if expect != "" {
+			fmt.Printf(T("共 %d 個 Slave：符合 %d，不符 %d\n"), report.Total, report.Matched, report.Mismatched)
+			if report.Mismatched > 0 {
+				return fmt.Errorf(T("%d 個 Slave 的暫存器內容與預期不符"), report.Mismatched)
+			}
+		}
+		return nil
+	},
+}
+
+// slaveDecommissionCmd Slave 除役
+var slaveDecommissionCmd = &cobra.Command{
+	Use:   "decommission [ip|id]",
+	Short: "Slave 除役",
+	Long:  "立即永久移除指定的 Slave (關閉 listener，視配置移除虛擬 IP)；未指定 Slave 時列出排定的除役與已除役的 Slave。",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 1 {
+			var event DecommissionEvent
+			if err := callAdminAPI(apiURL, "POST", "/api/slaves/"+args[0]+"/decommission", nil, &event); err != nil {
+				return err
+			}
+			fmt.Printf(T("Slave %s 已除役 (運行 %s，%d 個請求)\n"), event.SlaveID, event.Uptime, event.Requests)
+			return nil
+		}
+
+		var status DecommissionStatus
+		if err := callAdminAPI(apiURL, "GET", "/api/decommissions", nil, &status); err != nil {
+			return err
+		}
+		for _, s := range status.Scheduled {
+			fmt.Printf(T("%-22s 排定於 %s 除役 (規則 %s)\n"), s.SlaveID, s.At.Format(time.RFC3339), s.Rule)
+		}
+		for _, e := range status.Decommissioned {
+			fmt.Printf(T("%-22s 已於 %s 除役 (規則 %s)\n"), e.SlaveID, e.At.Format(time.RFC3339), e.Rule)
+		}
+		fmt.Printf(T("排定 %d 個，已除役 %d 個\n"), len(status.Scheduled), len(status.Decommissioned))
+		return nil
+	},
+}
+
+// slaveBitmapCmd 以位元表批次讀寫線圈/離散輸入
+var slaveBitmapCmd = &cobra.Command{
+	Use:   "bitmap <ip|id>",
+	Short: "位元表批次讀寫",
+	Long:  "以 hex 或 base64 位元表 (LSB 先) 批次讀取或設定線圈；--discrete 改為離散輸入，--set 指定要寫入的位元表。",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		flags := cmd.Flags()
+		table := "coils"
+		if discrete, _ := flags.GetBool("discrete"); discrete {
+			table = "discrete_inputs"
+		}
+		path := "/api/slaves/" + args[0] + "/" + table
+		address, _ := flags.GetUint16("address")
+		count, _ := flags.GetInt("count")
+		encoding, _ := flags.GetString("encoding")
+
+		if flags.Changed("set") {
+			bitmap, _ := flags.GetString("set")
+			var result BitmapWriteResult
+			req := Bitmap{Address: address, Count: count, Encoding: encoding, Bitmap: bitmap}
+			if err := callAdminAPI(apiURL, "PUT", path, req, &result); err != nil {
+				return err
+			}
+			fmt.Printf(T("已寫入 %d 個位元 (起始位址 %d)\n"), result.Written, result.Address)
+			return nil
+		}
+
+		query := url.Values{}
+		query.Set("address", strconv.Itoa(int(address)))
+		query.Set("encoding", encoding)
+		if count > 0 {
+			query.Set("count", strconv.Itoa(count))
+		}
+		var bitmap Bitmap
+		if err := callAdminAPI(apiURL, "GET", path+"?"+query.Encode(), nil, &bitmap); err != nil {
+			return err
+		}
+		fmt.Println(bitmap.Bitmap)
+		return nil
+	},
+}
+
+// registerCmd 暫存器命令組
+var registerCmd = &cobra.Command{
+	Use:   "register",
+	Short: "暫存器讀寫命令",
+	Long:  "以暫存器名稱讀寫運行中實例的已定義暫存器，數值為工程值 (依定義的縮放與資料類型換算)。",
+}
+
+// registerReadCmd 讀取暫存器
+var registerReadCmd = &cobra.Command{
+	Use:   "read",
+	Short: "讀取暫存器",
+	Long:  "讀取指定 Slave 的已定義暫存器 (--name 或 --address)，輸出工程值與原始值。",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, register, err := lookupRegister(cmd)
+		if err != nil {
+			return err
+		}
+		printRegister(register)
+		return nil
+	},
+}
+
+// registerWriteCmd 寫入暫存器
+var registerWriteCmd = &cobra.Command{
+	Use:   "write",
+	Short: "寫入暫存器",
+	Long:  "以工程值寫入指定 Slave 的已定義暫存器 (--name 或 --address)，寫入後輸出讀回的值；管理 API 寫入不受 writable 限制。",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, register, err := lookupRegister(cmd)
+		if err != nil {
+			return err
+		}
+
+		v, _ := cmd.Flags().GetString("value")
+		req := RegisterWriteRequest{Value: v}
+		if !strings.HasPrefix(register.DataType, "string") {
+			value, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return fmt.Errorf(T("暫存器 %s 為 %s，數值無效: %s"), register.Name, register.DataType, v)
+			}
+			req.Value = value
+		}
+
+		target := path + "/" + strconv.Itoa(int(register.Address))
+		if unit, _ := cmd.Flags().GetInt("unit"); unit > 0 {
+			target += "?unit=" + strconv.Itoa(unit)
+		}
+		if err := callAdminAPI(apiURL, "PUT", target, req, nil); err != nil {
+			return err
+		}
+
+		_, register, err = lookupRegister(cmd)
+		if err != nil {
+			return err
+		}
+		printRegister(register)
+		return nil
+	},
+}
+
+// registerSetAllCmd 批次寫入暫存器
+var registerSetAllCmd = &cobra.Command{
+	Use:   "set-all",
+	Short: "批次寫入多個 Slave 的暫存器",
+	Long:  "以工程值寫入所有符合 --targets 的 Slave 的同名暫存器 (例如模擬全系統的頻率事件)；任一 Slave 的值無效時全部不寫入。",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		flags := cmd.Flags()
+		name, _ := flags.GetString("name")
+		v, _ := flags.GetString("value")
+		targets, _ := flags.GetStringSlice("targets")
+		unit, _ := flags.GetInt("unit")
+
+		req := RegisterBulkWriteRequest{Value: v, Targets: targets, Unit: unit}
+		if asString, _ := flags.GetBool("string"); !asString {
+			value, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return fmt.Errorf(T("數值無效: %s (字串類型的暫存器請加上 --string)"), v)
+			}
+			req.Value = value
+		}
+
+		var result RegisterBulkWriteResult
+		if err := callAdminAPI(apiURL, "PUT", "/api/registers/"+url.PathEscape(name), req, &result); err != nil {
+			return err
+		}
+		fmt.Printf(T("已寫入 %d 個 Slave 的 %s = %s"), len(result.Written), result.Name, v)
+		if len(result.Skipped) > 0 {
+			fmt.Printf(T("，略過 %d 個沒有此暫存器的 Slave"), len(result.Skipped))
+		}
+		fmt.Println()
+		return nil
+	},
+}
+
+// lookupRegister 依 --slave、--unit 與 --name/--address 取得暫存器的目前值 (回傳暫存器 API 路徑)
+func lookupRegister(cmd *cobra.Command) (string, RegisterValue, error) {
+	flags := cmd.Flags()
+	slave, _ := flags.GetString("slave")
+	name, _ := flags.GetString("name")
+	if name == "" && !flags.Changed("address") {
+		return "", RegisterValue{}, errors.New(T("必須以 --name 或 --address 指定暫存器"))
+	}
+
+	path := "/api/slaves/" + url.PathEscape(slave) + "/registers"
+	query := path
+	if unit, _ := flags.GetInt("unit"); unit > 0 {
+		query += "?unit=" + strconv.Itoa(unit)
+	}
+	var registers []RegisterValue
+	if err := callAdminAPI(apiURL, "GET", query, nil, &registers); err != nil {
+		return "", RegisterValue{}, err
+	}
+
+	address, _ := flags.GetUint16("address")
+	for _, r := range registers {
+		if (name != "" && strings.EqualFold(r.Name, name)) || (name == "" && r.Address == address) {
+			return path, r, nil
+		}
+	}
+	if name != "" {
+		return "", RegisterValue{}, fmt.Errorf(T("找不到暫存器: %s"), name)
+	}
+	return "", RegisterValue{}, fmt.Errorf(T("位址 %d 沒有已定義的暫存器"), address)
+}
+
+// printRegister 輸出暫存器的工程值與原始值
+func printRegister(r RegisterValue) {
+	value := fmt.Sprint(r.Value)
+	if v, ok := r.Value.(float64); ok {
+		value = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	if r.Unit != "" {
+		value += " " + r.Unit
+	}
+	fmt.Printf("%s (%d, %s) = %s  raw %v\n", r.Name, r.Address, r.DataType, value, r.Raw)
+}
+
+// driftCmd 配置漂移命令組
+var driftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "配置漂移命令",
+	Long:  "檢查運行中實例各 Slave 的暫存器元資料與可寫入值是否偏離設定檔，並可重新設定基準。",
+}
+
+// driftCheckCmd 檢查配置漂移
+var driftCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "檢查配置漂移",
+	Long:  "立即比對所有 Slave 與基準，列出差異；有漂移時以非零狀態結束。",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var report DriftReport
+		if err := callAdminAPI(apiURL, "GET", "/api/drift", nil, &report); err != nil {
+			return err
+		}
+
+		for _, slave := range report.Slaves {
+			for _, d := range slave.Drifts {
+				fmt.Printf("%-22s %-6d %-16s %-11s %s -> %s\n", slave.Slave, d.Address, d.Name, d.Field, d.Expected, d.Actual)
+			}
+		}
+		fmt.Printf(T("共 %d 個 Slave (設定檔 %s)：%d 個有漂移\n"), report.Total, report.Profile, report.Drifted)
+		if report.Drifted > 0 {
+			return fmt.Errorf(T("%d 個 Slave 的配置偏離基準"), report.Drifted)
+		}
+		return nil
+	},
+}
+
+// driftBaselineCmd 重新設定基準
+var driftBaselineCmd = &cobra.Command{
+	Use:   "baseline [ip|id]",
+	Short: "重新設定基準",
+	Long:  "以 Slave 目前的暫存器元資料與可寫入值作為新的比對基準；未指定 Slave 時套用至全部。",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := "/api/drift/baseline"
+		if len(args) == 1 {
+			path = "/api/slaves/" + args[0] + "/baseline"
+		}
+
+		var status BaselineStatus
+		if err := callAdminAPI(apiURL, "POST", path, nil, &status); err != nil {
+			return err
+		}
+		fmt.Printf(T("已重新設定 %d 個 Slave 的基準\n"), status.Rebaselined)
+		return nil
+	},
+}
+
+// snapshotCmd 快照命令組
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "狀態快照命令",
+	Long:  "保存或還原運行中實例所有 Slave 的暫存器內容、累計電能與目前的場景。",
+}
+
+// snapshotSaveCmd 保存快照
+var snapshotSaveCmd = &cobra.Command{
+	Use:   "save <file>",
+	Short: "保存快照",
+	Long:  "將運行中實例的狀態保存為 JSON 快照檔。",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var snap Snapshot
+		if err := callAdminAPI(apiURL, "GET", "/api/snapshot", nil, &snap); err != nil {
+			return err
+		}
+		if err := SaveSnapshot(args[0], &snap); err != nil {
+			return err
+		}
+		fmt.Printf(T("已保存 %d 個 Slave 的快照至 %s\n"), len(snap.Slaves), args[0])
+		return nil
+	},
+}
+
+// snapshotRestoreCmd 還原快照
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore <file>",
+	Short: "還原快照",
+	Long:  "將快照檔還原至運行中的實例 (依 IP 對應 Slave，尚未啟動的 Slave 於啟動時還原)。",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		snap, err := LoadSnapshot(args[0])
+		if err != nil {
+			return err
+		}
+
+		var result RestoreResult
+		if err := callAdminAPI(apiURL, "POST", "/api/snapshot", snap, &result); err != nil {
+			return err
+		}
+		fmt.Printf(T("已還原 %d 個 Slave (待啟動 %d，失敗 %d)，場景: %s\n"), result.Restored, result.Pending, result.Failed, result.Scenario)
+		if result.Failed > 0 {
+			return fmt.Errorf(T("%d 個 Slave 還原失敗"), result.Failed)
+		}
+		return nil
+	},
+}
+
+// pollingCmd 輪詢效率報告
+var pollingCmd = &cobra.Command{
+	Use:   "polling",
+	Short: "輪詢效率報告",
+	Long:  "列出各 Master 的輪詢效率與讀取頻率遠高於數值變化頻率的熱點 (需啟用 polling.enabled)；--reset 清除觀察結果。",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if reset, _ := cmd.Flags().GetBool("reset"); reset {
+			if err := callAdminAPI(apiURL, "DELETE", "/api/polling", nil, nil); err != nil {
+				return err
+			}
+			fmt.Println(T("已清除輪詢觀察結果"))
+			return nil
+		}
+
+		path := "/api/polling"
+		if master, _ := cmd.Flags().GetString("master"); master != "" {
+			path += "?master=" + url.QueryEscape(master)
+		}
+
+		var report PollReport
+		if err := callAdminAPI(apiURL, "GET", path, nil, &report); err != nil {
+			return err
+		}
+
+		if len(report.Masters) == 0 {
+			fmt.Println(T("尚未觀察到讀取請求"))
+			return nil
+		}
+		for _, m := range report.Masters {
+			fmt.Printf(T("Master %s: %d 次輪詢，%d 次未變化 (效率 %.1f%%)\n"), m.Master, m.Polls, m.RedundantPolls, m.Efficiency*100)
+			for _, spot := range m.HotSpots {
+				suggested := spot.SuggestedInterval
+				if suggested == "" {
+					suggested = "-"
+				}
+				fmt.Printf("  %-22s FC%02d %-6d x%-4d polls=%-6d changes=%-5d every=%-8s suggest=%s\n",
+					spot.Slave, spot.Function, spot.Address, spot.Quantity, spot.Polls, spot.Changes, spot.PollInterval, suggested)
+			}
+		}
+		return nil
+	},
+}
+
+// benchCmd 負載測試
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Modbus 負載測試",
+	Long:  "以 Modbus TCP Master 身分對目標 (或模擬器本身) 發送請求，可設定並發連線數、功能碼比例與請求速率，並回報延遲百分位數。",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		flags := cmd.Flags()
+		cfg := BenchConfig{}
+		cfg.Targets, _ = flags.GetStringSlice("target")
+		cfg.Concurrency, _ = flags.GetInt("concurrency")
+		cfg.Rate, _ = flags.GetFloat64("rate")
+		cfg.Duration, _ = flags.GetDuration("duration")
+		cfg.Requests, _ = flags.GetInt("requests")
+		cfg.Timeout, _ = flags.GetDuration("timeout")
+		cfg.Unit, _ = flags.GetUint8("unit")
+		cfg.Address, _ = flags.GetUint16("address")
+		cfg.Quantity, _ = flags.GetUint16("quantity")
+		cfg.Seed = seedFlag
+		mix, _ := flags.GetString("mix")
+		var err error
+		if cfg.Mix, err = ParseBenchMix(mix); err != nil {
+			return err
+		}
+
+		// Ctrl+C 提前結束時仍輸出已完成的結果
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		report, err := RunBench(ctx, cfg)
+		if err != nil {
+			return err
+		}
+
+		if asJSON, _ := flags.GetBool("json"); asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(report)
+		}
+
+		fmt.Printf(T("目標 %s，%d 條連線，耗時 %s\n"), strings.Join(report.Targets, ", "), report.Concurrency, report.Elapsed.Round(time.Millisecond))
+		fmt.Printf(T("請求 %d，錯誤 %d，吞吐量 %.1f req/s\n"), report.Requests, report.Errors, report.Throughput)
+		printLatency := func(label string, l LatencySummary) {
+			fmt.Printf("  %-6s min=%-10s p50=%-10s p90=%-10s p99=%-10s p99.9=%-10s max=%s\n",
+				label, l.Min, l.P50, l.P90, l.P99, l.P999, l.Max)
+		}
+		printLatency("all", report.Latency)
+		for _, f := range report.Functions {
+			printLatency(fmt.Sprintf("FC%02d", f.Function), f.Latency)
+		}
+		kinds := make([]string, 0, len(report.ErrorKinds))
+		for kind := range report.ErrorKinds {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			fmt.Printf("  %-20s %d\n", kind, report.ErrorKinds[kind])
+		}
+		return nil
+	},
+}
+
+// captureCmd 解析擷取檔
+var captureCmd = &cobra.Command{
+	Use:   "capture <file>",
+	Short: "解析 Modbus 擷取檔",
+	Long:  "由 pcap/pcapng 擷取檔的回應與寫入請求重建暫存器狀態時間軸並列出摘要；--output 將時間軸存成 JSON，可作為 replay 場景的回歸測試資料。",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		port, _ := cmd.Flags().GetUint16("port")
+		timeline, err := LoadCaptureTimeline(args[0], port)
+		if err != nil {
+			return fmt.Errorf(T("解析擷取檔失敗: %w"), err)
+		}
+
+		stats := timeline.Stats
+		fmt.Printf(T("開始時間: %s，時長 %s\n"), timeline.Start.Format(time.RFC3339), timeline.Duration())
+		fmt.Printf(T("封包 %d，請求 %d，回應 %d (例外 %d，未配對 %d)\n"),
+			stats.Packets, stats.Requests, stats.Responses, stats.Exceptions, stats.Unmatched)
+
+		type tableKey struct {
+			unit  uint8
+			table string
+		}
+		type tableSummary struct {
+			frames    int
+			low, high uint16
+		}
+		summaries := make(map[tableKey]*tableSummary)
+		var keys []tableKey
+		for _, f := range timeline.Frames {
+			k := tableKey{f.Unit, f.Table}
+			last := f.Address + uint16(len(f.Values)) - 1
+			sum, ok := summaries[k]
+			if !ok {
+				sum = &tableSummary{low: f.Address, high: last}
+				summaries[k] = sum
+				keys = append(keys, k)
+			}
+			sum.frames++
+			sum.low, sum.high = min(sum.low, f.Address), max(sum.high, last)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].unit != keys[j].unit {
+				return keys[i].unit < keys[j].unit
+			}
+			return keys[i].table < keys[j].table
+		})
+		for _, k := range keys {
+			sum := summaries[k]
+			fmt.Printf("  unit=%-3d %-18s %5d-%-5d changes=%d\n", k.unit, k.table, sum.low, sum.high, sum.frames)
+		}
+
+		if output, _ := cmd.Flags().GetString("output"); output != "" {
+			if err := timeline.Save(output); err != nil {
+				return fmt.Errorf(T("儲存時間軸失敗: %w"), err)
+			}
+			fmt.Printf(T("時間軸已儲存: %s\n"), output)
+		}
+		return nil
+	},
+}
+
+// configCmd 配置命令組
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "配置管理命令",
+	Long:  "管理配置檔。",
+}
+
+// configValidateCmd 驗證配置
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "驗證配置檔",
+	Long:  "驗證指定的配置檔是否有效。",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := LoadConfig(cfgFile)
+		if err != nil {
+			return fmt.Errorf(T("配置驗證失敗: %w"), err)
+		}
+
+		fmt.Println(T("配置驗證通過"))
+		fmt.Printf("  Slaves: %d\n", cfg.Slaves.Count)
+		fmt.Printf("  Port: %d\n", cfg.Server.Port)
+		fmt.Printf("  Interface: %s\n", cfg.Network.Interface)
+		fmt.Printf("  IP Ranges: %d\n", len(cfg.Network.IPRanges))
+		return nil
+	},
+}
+
+// configGenerateCmd 生成配置
+var configGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "生成範例配置",
+	Long:  "生成範例配置檔。",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, _ := cmd.Flags().GetString("output")
+		if output == "" {
+			output = "config.json"
+		}
+
+		cfg := DefaultConfig()
+
+		// 添加範例 IP 範圍
+		cfg.Network.IPRanges = []IPRange{
+			{Start: "192.168.1.101", End: "192.168.1.200"},
+		}
+
+		if err := cfg.SaveConfig(output); err != nil {
+			return fmt.Errorf(T("生成配置失敗: %w"), err)
+		}
+
+		fmt.Printf(T("範例配置已生成: %s\n"), output)
+		return nil
+	},
+}
+
+// generateCmd 產生部署檔
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "產生部署檔",
+	Long:  "產生以容器或 systemd 部署模擬器的設定檔，以及監控用的 Grafana 儀表板。",
+}
+
+// generateComposeCmd 產生 docker-compose 部署檔
+var generateComposeCmd = &cobra.Command{
+	Use:   "docker-compose",
+	Short: "產生 docker-compose 部署檔",
+	Long:  "產生 docker-compose 部署檔：N 個容器各自以 macvlan 網路上的獨立 IP 運行一個 Slave，適用於不允許在主機上配置 IP 別名的環境。",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := DefaultComposeOptions()
+		flags := cmd.Flags()
+		opts.Count, _ = flags.GetInt("count")
+		opts.Subnet, _ = flags.GetString("subnet")
+		opts.Gateway, _ = flags.GetString("gateway")
+		opts.StartIP, _ = flags.GetString("start")
+		opts.Parent, _ = flags.GetString("parent")
+		opts.Image, _ = flags.GetString("image")
+		opts.Name, _ = flags.GetString("name")
+		opts.Port, _ = flags.GetInt("port")
+		opts.Profile, _ = flags.GetString("profile")
+		opts.Config, _ = flags.GetString("mount-config")
+
+		data, err := GenerateCompose(opts)
+		if err != nil {
+			return fmt.Errorf(T("產生部署檔失敗: %w"), err)
+		}
+
+		output, _ := flags.GetString("output")
+		if output == "" || output == "-" {
+			_, err := os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(output, data, 0o644); err != nil {
+			return fmt.Errorf(T("產生部署檔失敗: %w"), err)
+		}
+		fmt.Printf(T("部署檔已產生: %s (%d 個容器)\n"), output, opts.Count)
+		return nil
+	},
+}
+
+// generateK8sCmd 產生 Kubernetes 部署檔
+var generateK8sCmd = &cobra.Command{
+	Use:   "k8s",
+	Short: "產生 Kubernetes 部署檔",
+	Long:  "產生 StatefulSet 與 headless Service：每個 Pod 運行一個 Slave，依 Pod 環境變數取得身分 (StatefulSet 序號決定 Unit ID)，調整 replicas 即可跨節點水平擴展；指定 --subnet 時另產生 Multus NetworkAttachmentDefinition，讓每個 Pod 擁有現場網段上的 IP。",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := DefaultK8sOptions()
+		flags := cmd.Flags()
+		opts.Replicas, _ = flags.GetInt("replicas")
+		opts.Name, _ = flags.GetString("name")
+		opts.Namespace, _ = flags.GetString("namespace")
+		opts.Image, _ = flags.GetString("image")
+		opts.Port, _ = flags.GetInt("port")
+		opts.Profile, _ = flags.GetString("profile")
+		opts.ConfigMap, _ = flags.GetString("config-map")
+		opts.Subnet, _ = flags.GetString("subnet")
+		opts.RangeStart, _ = flags.GetString("start")
+		opts.RangeEnd, _ = flags.GetString("end")
+		opts.Gateway, _ = flags.GetString("gateway")
+		opts.Parent, _ = flags.GetString("parent")
+
+		data, err := GenerateK8s(opts)
+		if err != nil {
+			return fmt.Errorf(T("產生部署檔失敗: %w"), err)
+		}
+
+		output, _ := flags.GetString("output")
+		if output == "" || output == "-" {
+			_, err := os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(output, data, 0o644); err != nil {
+			return fmt.Errorf(T("產生部署檔失敗: %w"), err)
+		}
+		fmt.Printf(T("部署檔已產生: %s (%d 個 Pod)\n"), output, opts.Replicas)
+		return nil
+	},
+}
+
+// generateSystemdCmd 產生 systemd service unit
+var generateSystemdCmd = &cobra.Command{
+	Use:   "systemd",
+	Short: "產生 systemd service unit",
+	Long:  "產生 Type=notify 的 systemd service unit：模擬器於所有 Slave 完成綁定後才回報就緒，依賴此服務的 unit 啟動時整個 Slave 群已可輪詢。配置檔取自 -c (轉為絕對路徑，未指定時為 /etc/modbussim/config.json)。",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := DefaultSystemdOptions()
+		flags := cmd.Flags()
+		if cfgFile != "" {
+			config, err := filepath.Abs(cfgFile)
+			if err != nil {
+				return fmt.Errorf(T("產生部署檔失敗: %w"), err)
+			}
+			opts.Config = config
+		}
+		opts.Name, _ = flags.GetString("name")
+		opts.Binary, _ = flags.GetString("binary")
+		opts.User, _ = flags.GetString("user")
+		opts.Group, _ = flags.GetString("group")
+		opts.SetupNetwork, _ = flags.GetBool("setup-network")
+		opts.Snapshot, _ = flags.GetString("snapshot")
+		opts.StartTimeout, _ = flags.GetDuration("start-timeout")
+
+		data, err := GenerateSystemd(opts)
+		if err != nil {
+			return fmt.Errorf(T("產生部署檔失敗: %w"), err)
+		}
+
+		output, _ := flags.GetString("output")
+		if output == "" || output == "-" {
+			_, err := os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(output, data, 0o644); err != nil {
+			return fmt.Errorf(T("產生部署檔失敗: %w"), err)
+		}
+		fmt.Printf(T("systemd unit 已產生: %s\n"), output)
+		return nil
+	},
+}
+
+// generateGrafanaCmd 產生 Grafana 儀表板
+var generateGrafanaCmd = &cobra.Command{
+	Use:   "grafana",
+	Short: "產生 Grafana 儀表板",
+	Long:  "產生對應本模擬器 Prometheus 指標的 Grafana 儀表板 JSON：整體概況、請求延遲直方圖、場景面板與每個 Slave 的面板，並以場景切換作為 annotation 標出故障注入期間。",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := DefaultGrafanaOptions()
+		flags := cmd.Flags()
+		opts.Title, _ = flags.GetString("title")
+		opts.UID, _ = flags.GetString("uid")
+		opts.Datasource, _ = flags.GetString("datasource")
+		opts.Refresh, _ = flags.GetString("refresh")
+		opts.PerSlave, _ = flags.GetBool("per-slave")
+
+		data, err := GenerateGrafana(opts)
+		if err != nil {
+			return fmt.Errorf(T("產生部署檔失敗: %w"), err)
+		}
+
+		output, _ := flags.GetString("output")
+		if output == "" || output == "-" {
+			_, err := os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(output, data, 0o644); err != nil {
+			return fmt.Errorf(T("產生部署檔失敗: %w"), err)
+		}
+		fmt.Printf(T("儀表板已產生: %s\n"), output)
+		return nil
+	},
+}
+
+// clusterCmd 叢集模式命令組
+var clusterCmd = &cobra.Command{
+	Use:   "cluster",
+	Short: "叢集模式命令",
+	Long:  "以一個 coordinator 將 Slave 範圍與場景分配給多台主機上的 agent，並彙整各 agent 的指標。",
+}
+
+// clusterCoordinatorCmd 啟動 coordinator
+var clusterCoordinatorCmd = &cobra.Command{
+	Use:   "coordinator",
+	Short: "啟動叢集 coordinator",
+	Long:  "啟動叢集控制平面：依 agent 加入順序將 network.ip_ranges 分段分配，下發場景，並在指標埠提供彙整指標與 /api/cluster 管理 API。",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if listen, _ := cmd.Flags().GetString("listen"); listen != "" {
+			appConfig.Cluster.Listen = listen
+		}
+
+		coordinator, err := NewCoordinator(appConfig, logger)
+		if err != nil {
+			return err
+		}
+		if err := coordinator.Start(appConfig.Cluster.Listen); err != nil {
+			return err
+		}
+		defer coordinator.Stop()
+
+		if appConfig.Metrics.Enabled {
+			coordinator.StartMetrics(appConfig.Metrics.Endpoint, appConfig.Metrics.Port)
+		}
+
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+		sig := <-sigChan
+		logger.Info(T("收到關閉信號"), zap.String("signal", sig.String()))
+		return nil
+	},
+}
+
+// clusterAgentCmd 以 agent 身分啟動模擬器
+var clusterAgentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "以叢集 agent 啟動模擬器",
+	Long:  "向 coordinator 取得要模擬的 Slave 範圍後啟動引擎，定期回報指標並跟隨叢集的場景。",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		flags := cmd.Flags()
+		if coordinator, _ := flags.GetString("coordinator"); coordinator != "" {
+			appConfig.Cluster.Coordinator = coordinator
+		}
+		if id, _ := flags.GetString("id"); id != "" {
+			appConfig.Cluster.AgentID = id
+		}
+		if count, _ := flags.GetInt("count"); count > 0 {
+			appConfig.Slaves.Count = count
+		}
+
+		agent, err := NewClusterAgent(appConfig, logger)
+		if err != nil {
+			return err
+		}
+		defer agent.Close()
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		err = agent.Join(ctx)
+		stop()
+		if err != nil {
+			return err
+		}
+
+		return runSimulator(cmd, agent.Run)
+	},
+}
+
+// clusterStatusCmd 顯示叢集狀態
+var clusterStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "顯示叢集狀態",
+	Long:  "顯示 coordinator 的場景與各 agent 的分配及最近一次回報 (--api 指向 coordinator 的指標埠)。",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var status ClusterStatus
+		if err := callAdminAPI(apiURL, "GET", "/api/cluster", nil, &status); err != nil {
+			return err
+		}
+
+		fmt.Printf(T("場景: %s，已分配 %d/%d 個 Slave\n"), status.Scenario, status.Assigned, status.Capacity)
+		if len(status.Agents) == 0 {
+			fmt.Println(T("目前沒有 agent"))
+			return nil
+		}
+
+		fmt.Printf("%-20s %-5s %-16s %-7s %-8s %-12s %s\n", "AGENT", "UP", "FIRST IP", "SLAVES", "ACTIVE", "REQUESTS", "SCENARIO")
+		for _, a := range status.Agents {
+			first := ""
+			if len(a.IPRanges) > 0 {
+				first = a.IPRanges[0].Start
+			}
+			fmt.Printf("%-20s %-5t %-16s %-7d %-8d %-12d %s\n", a.ID, a.Up, first, a.Count, a.ActiveSlaves, a.Requests, a.Scenario)
+		}
+		return nil
+	},
+}
+
+// clusterScenarioCmd 變更叢集場景
+var clusterScenarioCmd = &cobra.Command{
+	Use:   "scenario [scenario]",
+	Short: "變更叢集場景",
+	Long:  "變更整個叢集的場景，各 agent 於下次回報時套用。",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var status ClusterStatus
+		req := map[string]string{"scenario": args[0]}
+		if err := callAdminAPI(apiURL, "PUT", "/api/cluster/scenario", req, &status); err != nil {
+			return err
+		}
+		fmt.Printf(T("叢集場景已變更為 %s (%d 個 agent)\n"), status.Scenario, len(status.Agents))
+		return nil
+	},
+}
+
+// versionCmd 版本命令
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "顯示版本資訊",
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Printf("modbussim version %s\n", Version)
+		fmt.Printf("  Build: %s\n", BuildTime)
+		fmt.Printf("  Commit: %s\n", GitCommit)
+	},
+}
+
+func init() {
+	// 全域 flags
+	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "配置檔路徑")
+	rootCmd.PersistentFlags().StringVar(&apiURL, "api", "http://localhost:9090", "運行中實例的管理 API 位址")
+	rootCmd.PersistentFlags().StringVar(&langFlag, "lang", LangAuto, "訊息語系 (zh-TW, en, auto)")
+	rootCmd.PersistentFlags().Int64Var(&seedFlag, "seed", 0, "亂數種子 (0 = 依時間產生；指定相同種子可重現模擬)")
+
+	// start/restart 命令 flags
+	for _, cmd := range []*cobra.Command{startCmd, restartCmd} {
+		cmd.Flags().StringP("ip", "i", "", "起始 IP 位址")
+		cmd.Flags().IntP("count", "n", 0, "Slave 數量")
+		cmd.Flags().IntP("port", "p", 0, "監聽埠號")
+		cmd.Flags().String("profile", "", "設備設定檔 (single_phase, three_phase, battery)")
+		cmd.Flags().String("identity", "", "Slave 身分來源 (static, pod)")
+		cmd.Flags().String("user", "", "綁定埠號後降級為此使用者 (需以 root 啟動，僅 Linux)")
+		cmd.Flags().String("group", "", "降級的群組 (預設為使用者的主要群組)")
+		cmd.Flags().Float64("speed", 0, "模擬時鐘倍率 (例如 60 = 實際 1 分鐘模擬 1 小時，預設使用配置的 clock.speed)")
+		cmd.Flags().Bool("setup-network", false, "啟動前依配置建立虛擬 IP")
+		cmd.Flags().String("snapshot", "", "啟動時自此快照檔還原 (檔案存在時)，關閉時保存")
+		cmd.Flags().String("pid-file", "", "PID 檔案路徑 (背景執行時預設為 /var/run/modbussim.pid)")
+		cmd.Flags().String("log-file", "", "背景執行時的日誌檔 (預設捨棄)")
+	}
+	startCmd.Flags().Bool("daemon", false, "在背景執行 (完成啟動後返回)")
+	restartCmd.Flags().Duration("wait", 30*time.Second, "等待原本的實例結束的時間上限")
+
+	// stop 命令 flags
+	stopCmd.Flags().String("pid-file", DefaultPIDFile, "PID 檔案路徑")
+	stopCmd.Flags().Duration("wait", 0, "等待程序結束的時間上限 (0 表示發送信號後立即返回)")
+
+	// network 命令 flags
+	networkSetupCmd.Flags().StringP("interface", "i", "eth0", "網路介面")
+	networkSetupCmd.Flags().String("start", "", "起始 IP")
+	networkSetupCmd.Flags().String("end", "", "結束 IP")
+	networkSetupCmd.Flags().String("cidr", "", "CIDR 表示法")
+	networkSetupCmd.Flags().String("parent", "", "macvlan 的上層介面 (預設為 --interface)")
+	networkSetupCmd.Flags().Int("mtu", 0, "專用介面的 MTU")
+
+	networkTeardownCmd.Flags().StringP("interface", "i", "eth0", "網路介面")
+	networkListCmd.Flags().StringP("interface", "i", "eth0", "網路介面")
+
+	for _, cmd := range []*cobra.Command{networkSetupCmd, networkTeardownCmd, networkListCmd} {
+		cmd.Flags().String("mode", "", "虛擬 IP 配置方式 (alias, dummy, macvlan)")
+		cmd.Flags().String("link", "", "dummy/macvlan 專用介面名稱 (預設 modbussim0)")
+	}
+
+	// scenario 命令 flags
+	scenarioApplyCmd.Flags().DurationP("duration", "d", 0, "場景持續時間")
+	scenarioTuneCmd.Flags().String("scenario", "", "調整的場景 (預設為目前的整體場景)")
+	scenarioTuneCmd.Flags().Bool("reset", false, "捨棄調整，還原為配置檔的參數")
+
+	// domain outage 參數
+	domainOutageCmd.Flags().DurationP("duration", "d", 0, "停擺時間 (預設使用配置的 outage_duration)")
+	domainOutageCmd.Flags().Bool("restore", false, "提前恢復")
+
+	// bootstorm 參數
+	bootStormCmd.Flags().Duration("outage", 0, "斷電時間 (預設使用配置的 boot_storm.outage)")
+	bootStormCmd.Flags().Duration("stagger", 0, "復電後 Slave 上線的錯開時間窗 (0 = 同時上線，預設使用配置值)")
+	bootStormCmd.Flags().StringSlice("target", nil, "僅影響指定 IP/CIDR (預設全部)")
+	bootStormCmd.Flags().Bool("status", false, "查看進度")
+
+	// clock 參數
+	clockCmd.Flags().Float64("speed", 0, "調整模擬時鐘倍率")
+	bootStormCmd.Flags().Bool("restore", false, "立即復電")
+
+	// slave list/inspect 參數
+	slaveListCmd.Flags().Duration("interval", time.Second, "計算每秒請求數的取樣間隔 (0 不計算)")
+	slaveInspectCmd.Flags().Int("unit", 0, "閘道後方設備的 Unit ID (預設為主設備)")
+
+	// register read/write 參數
+	for _, cmd := range []*cobra.Command{registerReadCmd, registerWriteCmd} {
+		cmd.Flags().String("slave", "", "Slave 的 IP 或 ID (ip:port)")
+		cmd.Flags().String("name", "", "暫存器名稱 (不分大小寫)")
+		cmd.Flags().Uint16("address", 0, "暫存器位址 (未指定 --name 時使用)")
+		cmd.Flags().Int("unit", 0, "閘道後方設備的 Unit ID (預設為主設備)")
+		cmd.MarkFlagRequired("slave")
+	}
+	registerWriteCmd.Flags().String("value", "", "要寫入的工程值 (字串類型為字串)")
+	registerWriteCmd.MarkFlagRequired("value")
+	registerSetAllCmd.Flags().String("name", "", "暫存器名稱 (不分大小寫)")
+	registerSetAllCmd.Flags().String("value", "", "要寫入的工程值")
+	registerSetAllCmd.Flags().StringSlice("targets", nil, "僅寫入指定 IP/CIDR 的 Slave (預設全部)")
+	registerSetAllCmd.Flags().Int("unit", 0, "閘道後方設備的 Unit ID (預設為主設備)")
+	registerSetAllCmd.Flags().Bool("string", false, "以字串寫入 (字串類型的暫存器)")
+	registerSetAllCmd.MarkFlagRequired("name")
+	registerSetAllCmd.MarkFlagRequired("value")
+	registerCmd.AddCommand(registerReadCmd, registerWriteCmd, registerSetAllCmd)
+
+	// slave blink 參數
+	slaveBlinkCmd.Flags().DurationP("duration", "d", DefaultBlinkDuration, "閃爍持續時間")
+	slaveBlinkCmd.Flags().Uint16("register", DefaultBlinkRegister, "閃爍的保持暫存器位址")
+	slaveBlinkCmd.Flags().Int("coil", -1, "週期切換的線圈位址 (-1 不切換)")
+	slaveBlinkCmd.Flags().Bool("stop", false, "停止閃爍並還原")
+
+	// slave clock 參數
+	slaveClockCmd.Flags().String("offset", "", "將設備時間設為參考時間加上此偏差 (例如 -30s，0s 即校時)")
+	slaveClockCmd.Flags().String("time", "", "將設備時間設為此時間 (RFC 3339)")
+	slaveChecksumCmd.Flags().String("expect", "", "預期的雜湊值 (僅列出不符者)")
+	slaveBitmapCmd.Flags().Bool("discrete", false, "讀寫離散輸入 (預設為線圈)")
+	slaveBitmapCmd.Flags().Uint16("address", 0, "起始位址 (0 起算)")
+	slaveBitmapCmd.Flags().Int("count", 0, "位元數 (讀取時 0 = 到結尾，寫入時 0 = 位元表的全部位元)")
+	slaveBitmapCmd.Flags().String("encoding", BitmapHex, "位元表編碼 (hex, base64)")
+	slaveBitmapCmd.Flags().String("set", "", "要寫入的位元表")
+
+	// polling 參數
+	pollingCmd.Flags().String("master", "", "僅列出指定 Master (IP)")
+	pollingCmd.Flags().Bool("reset", false, "清除觀察結果並重新起算")
+
+	// bench 參數
+	benchCmd.Flags().StringSlice("target", []string{"127.0.0.1:502"}, "目標 host:port (可重複指定，連線依序分配)")
+	benchCmd.Flags().Int("concurrency", DefaultBenchConcurrency, "並發連線數")
+	benchCmd.Flags().Float64("rate", 0, "合計每秒請求數 (0 = 不限速)")
+	benchCmd.Flags().DurationP("duration", "d", DefaultBenchDuration, "測試時間")
+	benchCmd.Flags().IntP("requests", "n", 0, "請求總數 (達到即結束，0 = 依測試時間)")
+	benchCmd.Flags().Duration("timeout", DefaultBenchTimeout, "單次請求逾時")
+	benchCmd.Flags().Uint8("unit", 1, "Unit ID")
+	benchCmd.Flags().Uint16("address", 0, "起始位址 (0 起算)")
+	benchCmd.Flags().Uint16("quantity", DefaultBenchQuantity, "每次讀寫的數量")
+	benchCmd.Flags().String("mix", DefaultBenchMix, "功能碼權重 (例如 3=70,4=20,16=10)")
+	benchCmd.Flags().Bool("json", false, "以 JSON 輸出結果")
+
+	// capture 參數
+	captureCmd.Flags().Uint16("port", DefaultCapturePort, "擷取檔中 Modbus 伺服器的埠號")
+	captureCmd.Flags().StringP("output", "o", "", "將時間軸存成 JSON 檔")
+
+	// config 命令 flags
+	configGenerateCmd.Flags().StringP("output", "o", "config.json", "輸出檔案路徑")
+
+	// generate 命令 flags
+	composeDefaults := DefaultComposeOptions()
+	generateComposeCmd.Flags().IntP("count", "n", composeDefaults.Count, "容器 (Slave) 數量")
+	generateComposeCmd.Flags().String("subnet", "", "macvlan 網段 (CIDR，必填)")
+	generateComposeCmd.Flags().String("gateway", "", "網段的閘道 (預設為網段的第一個位址)")
+	generateComposeCmd.Flags().String("start", "", "第一個容器的 IP (預設為閘道的下一個位址)")
+	generateComposeCmd.Flags().String("parent", composeDefaults.Parent, "macvlan 的上層介面")
+	generateComposeCmd.Flags().String("image", composeDefaults.Image, "模擬器映像")
+	generateComposeCmd.Flags().String("name", composeDefaults.Name, "服務名稱前綴")
+	generateComposeCmd.Flags().IntP("port", "p", composeDefaults.Port, "監聽埠號")
+	generateComposeCmd.Flags().String("profile", "", "設備設定檔 (single_phase, three_phase, battery)")
+	generateComposeCmd.Flags().String("mount-config", "", "掛載到每個容器的主機配置檔")
+	generateComposeCmd.Flags().StringP("output", "o", "", "輸出檔案路徑 (預設輸出到 stdout)")
+	generateComposeCmd.MarkFlagRequired("subnet")
+
+	k8sDefaults := DefaultK8sOptions()
+	generateK8sCmd.Flags().IntP("replicas", "n", k8sDefaults.Replicas, "Pod (Slave) 數量")
+	generateK8sCmd.Flags().String("name", k8sDefaults.Name, "StatefulSet 與 Service 名稱")
+	generateK8sCmd.Flags().String("namespace", "", "命名空間 (預設為 kubectl 的目前命名空間)")
+	generateK8sCmd.Flags().String("image", k8sDefaults.Image, "模擬器映像")
+	generateK8sCmd.Flags().IntP("port", "p", k8sDefaults.Port, "監聽埠號")
+	generateK8sCmd.Flags().String("profile", "", "設備設定檔 (single_phase, three_phase, battery)")
+	generateK8sCmd.Flags().String("config-map", "", "掛載為 /app/configs 的 ConfigMap")
+	generateK8sCmd.Flags().String("subnet", "", "Multus macvlan 附加網路的網段 (CIDR，未指定時以 Pod IP 監聽)")
+	generateK8sCmd.Flags().String("start", "", "附加網路配置的第一個 IP")
+	generateK8sCmd.Flags().String("end", "", "附加網路配置的最後一個 IP")
+	generateK8sCmd.Flags().String("gateway", "", "附加網路的閘道")
+	generateK8sCmd.Flags().String("parent", k8sDefaults.Parent, "節點上 macvlan 的上層介面")
+	generateK8sCmd.Flags().StringP("output", "o", "", "輸出檔案路徑 (預設輸出到 stdout)")
+
+	systemdDefaults := DefaultSystemdOptions()
+	generateSystemdCmd.Flags().String("name", systemdDefaults.Name, "unit 名稱 (不含 .service)")
+	generateSystemdCmd.Flags().String("binary", systemdDefaults.Binary, "模擬器執行檔的絕對路徑")
+	generateSystemdCmd.Flags().String("user", "", "綁定埠號後降級為此使用者")
+	generateSystemdCmd.Flags().String("group", "", "降級的群組 (預設為使用者的主要群組)")
+	generateSystemdCmd.Flags().Bool("setup-network", false, "啟動時配置虛擬 IP")
+	generateSystemdCmd.Flags().String("snapshot", "", "關閉時保存、啟動時接續的快照檔 (絕對路徑)")
+	generateSystemdCmd.Flags().Duration("start-timeout", systemdDefaults.StartTimeout, "等待所有 Slave 完成綁定的上限 (TimeoutStartSec)")
+	generateSystemdCmd.Flags().StringP("output", "o", "", "輸出檔案路徑 (預設輸出到 stdout)")
+
+	grafanaDefaults := DefaultGrafanaOptions()
+	generateGrafanaCmd.Flags().String("title", grafanaDefaults.Title, "儀表板標題")
+	generateGrafanaCmd.Flags().String("uid", grafanaDefaults.UID, "儀表板 UID (匯入時覆寫相同 UID 的儀表板)")
+	generateGrafanaCmd.Flags().String("datasource", "", "預設的 Prometheus 資料來源 (預設為 Grafana 的預設資料來源)")
+	generateGrafanaCmd.Flags().String("refresh", grafanaDefaults.Refresh, "自動重新整理間隔 (空字串 = 不自動重新整理)")
+	generateGrafanaCmd.Flags().Bool("per-slave", grafanaDefaults.PerSlave, "加入每個 Slave 的面板 (需啟用 metrics.per_slave)")
+	generateGrafanaCmd.Flags().StringP("output", "o", "", "輸出檔案路徑 (預設輸出到 stdout)")
 
 	// 組裝命令樹
 	networkCmd.AddCommand(networkSetupCmd, networkTeardownCmd, networkListCmd)
-	scenarioCmd.AddCommand(scenarioListCmd, scenarioApplyCmd, scenarioResetCmd)
+	scenarioCmd.AddCommand(scenarioListCmd, scenarioApplyCmd, scenarioResetCmd, scenarioTuneCmd, scenarioChaosCmd)
 	configCmd.AddCommand(configValidateCmd, configGenerateCmd)
+	pairCmd.AddCommand(pairListCmd, pairFailoverCmd)
+	domainCmd.AddCommand(domainListCmd, domainOutageCmd)
+	slaveCmd.AddCommand(slaveListCmd, slaveInspectCmd, slaveBlinkCmd, slaveClockCmd, slaveChecksumCmd, slaveDecommissionCmd, slaveBitmapCmd)
+	driftCmd.AddCommand(driftCheckCmd, driftBaselineCmd)
+	snapshotCmd.AddCommand(snapshotSaveCmd, snapshotRestoreCmd)
+	generateCmd.AddCommand(generateComposeCmd)
+	generateCmd.AddCommand(generateK8sCmd)
+	generateCmd.AddCommand(generateSystemdCmd)
+	generateCmd.AddCommand(generateGrafanaCmd)
+
+	clusterCoordinatorCmd.Flags().String("listen", "", "gRPC 監聽位址 (預設 :9700)")
+	clusterAgentCmd.Flags().String("coordinator", "", "coordinator 位址 (host:port)")
+	clusterAgentCmd.Flags().String("id", "", "agent 識別 (預設為主機名稱)")
+	clusterAgentCmd.Flags().IntP("count", "n", 0, "agent 可承載的 Slave 數")
+	clusterAgentCmd.Flags().Bool("setup-network", false, "啟動前依分配建立虛擬 IP")
+	clusterCmd.AddCommand(clusterCoordinatorCmd, clusterAgentCmd, clusterStatusCmd, clusterScenarioCmd)
 
 	rootCmd.AddCommand(
 		startCmd,
 		stopCmd,
+		restartCmd,
 		statusCmd,
 		networkCmd,
 		scenarioCmd,
+		pairCmd,
+		domainCmd,
+		bootStormCmd,
+		clockCmd,
+		slaveCmd,
+		registerCmd,
+		driftCmd,
+		snapshotCmd,
+		pollingCmd,
+		captureCmd,
+		benchCmd,
 		configCmd,
+		generateCmd,
+		clusterCmd,
 		versionCmd,
 	)
 }
@@ -447,5 +2095,8 @@ func initLogger() (*zap.Logger, error) {
 
 // Execute 執行 CLI
 func Execute() error {
+	// 說明文字在 cobra 解析前產生，先依 --lang 或環境變數決定語系
+	SetLanguage(scanLanguageFlag(os.Args[1:]))
+	localizeCommand(rootCmd)
 	return rootCmd.Execute()
 }
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Clock 時間來源 (場景以此取得目前時間，測試時以虛擬時鐘取代)
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock 系統時間
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// VirtualClock 手動推進的虛擬時鐘
+type VirtualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewVirtualClock 建立起始於 start 的虛擬時鐘
+func NewVirtualClock(start time.Time) *VirtualClock {
+	return &VirtualClock{now: start}
+}
+
+func (c *VirtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance 將時鐘往後推進 d
+func (c *VirtualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// SimulationClock 依倍率加速的模擬時鐘 (倍率 60 時實際 1 分鐘模擬 1 小時)；調整倍率時模擬時間連續不跳動
+type SimulationClock struct {
+	mu       sync.Mutex
+	base     Clock // 實際時間來源
+	speed    float64
+	realBase time.Time // 上次調整倍率時的實際時間
+	simBase  time.Time // 上次調整倍率時的模擬時間
+}
+
+// NewSimulationClock 建立以 base 為實際時間來源的模擬時鐘 (speed 不大於 0 時為 1)
+func NewSimulationClock(base Clock, speed float64) *SimulationClock {
+	now := base.Now()
+	if speed <= 0 {
+		speed = 1
+	}
+	return &SimulationClock{base: base, speed: speed, realBase: now, simBase: now}
+}
+
+func (c *SimulationClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.nowLocked(c.base.Now())
+}
+
+func (c *SimulationClock) nowLocked(real time.Time) time.Time {
+	return c.simBase.Add(time.Duration(float64(real.Sub(c.realBase)) * c.speed))
+}
+
+// Speed 目前的倍率
+func (c *SimulationClock) Speed() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.speed
+}
+
+// SetSpeed 調整倍率，之後的模擬時間由目前的模擬時間以新倍率前進
+func (c *SimulationClock) SetSpeed(speed float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	real := c.base.Now()
+	c.simBase, c.realBase, c.speed = c.nowLocked(real), real, speed
+}
+
+// ClockStatus 模擬時鐘狀態
+type ClockStatus struct {
+	Now     time.Time     `json:"now"`      // 模擬時間
+	RealNow time.Time     `json:"real_now"` // 實際時間
+	Speed   float64       `json:"speed"`
+	Elapsed time.Duration `json:"elapsed"` // 引擎啟動後經過的模擬時間
+}
+
+// ClockStatus 模擬時鐘的目前時間與倍率
+func (e *Engine) ClockStatus() ClockStatus {
+	now := e.clock.Now()
+	status := ClockStatus{
+		Now:     now,
+		RealNow: time.Now(),
+		Speed:   e.clock.Speed(),
+	}
+	if start := e.clockStart(); !start.IsZero() {
+		status.Elapsed = now.Sub(start)
+	}
+	return status
+}
+
+// clockStart 引擎啟動時的模擬時間
+func (e *Engine) clockStart() time.Time {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.clockStartedAt
+}
+
+// SetClockSpeed 調整模擬時鐘倍率 (立即生效，模擬時間由目前時間以新倍率前進)
+func (e *Engine) SetClockSpeed(speed float64) (ClockStatus, error) {
+	if speed <= 0 {
+		return ClockStatus{}, fmt.Errorf(T("模擬時鐘倍率必須大於 0: %v"), speed)
+	}
+	previous := e.clock.Speed()
+	e.clock.SetSpeed(speed)
+	e.logger.Info(T("已調整模擬時鐘倍率"),
+		zap.Float64("from", previous),
+		zap.Float64("to", speed),
+	)
+	return e.ClockStatus(), nil
+}
+
+// updateClockRegister 將模擬時間 (Unix 秒) 寫入 clock.register 指定的保持暫存器
+func (s *Slave) updateClockRegister() {
+	if s.config == nil || s.config.Clock.Register == 0 {
+		return
+	}
+	ts := uint32(scenarioNow().Unix())
+	s.registers.WriteHoldingRegisters(s.config.Clock.Register, []uint16{uint16(ts >> 16), uint16(ts)})
+}
+
+// 場景使用的時間與亂數來源 (由 ScenarioHarness 暫時替換)
+var (
+	scenarioSourceMu sync.RWMutex
+	scenarioClock    Clock = systemClock{}
+	scenarioRand           = newLockedRand(time.Now().UnixNano())
+)
+
+// scenarioNow 場景的目前時間
+func scenarioNow() time.Time {
+	scenarioSourceMu.RLock()
+	defer scenarioSourceMu.RUnlock()
+	return scenarioClock.Now()
+}
+
+// scenarioSince 自 t 起經過的場景時間
+func scenarioSince(t time.Time) time.Duration {
+	return scenarioNow().Sub(t)
+}
+
+// setScenarioClock 替換場景的時間來源 (引擎啟動時改用模擬時鐘)
+func setScenarioClock(clock Clock) {
+	scenarioSourceMu.Lock()
+	defer scenarioSourceMu.Unlock()
+	scenarioClock = clock
+}
+
+// scenarioRandom 場景的亂數來源
+func scenarioRandom() *lockedRand {
+	scenarioSourceMu.RLock()
+	defer scenarioSourceMu.RUnlock()
+	return scenarioRand
+}
+
+// seedScenarioRandom 以 seed 重設場景共用的亂數來源
+func seedScenarioRandom(seed int64) {
+	scenarioSourceMu.Lock()
+	defer scenarioSourceMu.Unlock()
+	scenarioRand = newLockedRand(seed)
+}
+
+// 各 Slave 獨立的亂數序列 (相同種子與 Slave ID 時可重現，不受其他 Slave 的排程影響)
+const (
+	randomStreamRequests = "requests" // 延遲抖動與封包丟失
+	randomStreamScenario = "scenario" // 場景更新的量測值波動
+	randomStreamNominal  = "nominal"  // 額定值隨機化
+	randomStreamClock    = "clock"    // 設備時鐘的走時誤差與偏差
+)
+
+// slaveSeed 由全域種子、Slave ID 與序列名稱導出 Slave 的亂數種子
+func slaveSeed(seed int64, id, stream string) int64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(seed))
+	h.Write(buf[:])
+	h.Write([]byte(id))
+	h.Write([]byte{0})
+	h.Write([]byte(stream))
+	return int64(h.Sum64())
+}
+
+// registerRandom 暫存器映射表的亂數來源 (未設定時為場景共用的來源)
+func registerRandom(registers *RegisterMap) *lockedRand {
+	if random := registers.Random(); random != nil {
+		return random
+	}
+	return scenarioRandom()
+}
+
+// setScenarioSources 替換場景的時間與亂數來源，回傳還原函式
+func setScenarioSources(clock Clock, random *lockedRand) (restore func()) {
+	scenarioSourceMu.Lock()
+	prevClock, prevRand := scenarioClock, scenarioRand
+	scenarioClock, scenarioRand = clock, random
+	scenarioSourceMu.Unlock()
+
+	return func() {
+		scenarioSourceMu.Lock()
+		scenarioClock, scenarioRand = prevClock, prevRand
+		scenarioSourceMu.Unlock()
+	}
+}
+
+// lockedRand 可並發使用的亂數產生器
+type lockedRand struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func newLockedRand(seed int64) *lockedRand {
+	return &lockedRand{r: rand.New(rand.NewSource(seed))}
+}
+
+func (r *lockedRand) Float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.r.Float64()
+}
+
+func (r *lockedRand) Intn(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.r.Intn(n)
+}
+
+func (r *lockedRand) Perm(n int) []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.r.Perm(n)
+}
+
+func (r *lockedRand) Int63n(n int64) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.r.Int63n(n)
+}
+
+func (r *lockedRand) NormFloat64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.r.NormFloat64()
+}
@@ -0,0 +1,789 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// 叢集協定 (見 cluster/cluster.proto，訊息與外掛協定相同以 pluginCodec 編碼)
+const (
+	clusterServiceName = "modbussim.cluster.v1.Coordinator"
+	clusterCallTimeout = 5 * time.Second
+
+	// 超過幾個回報間隔未回報即視為 agent 離線
+	clusterAgentTimeoutBeats = 3
+)
+
+// Coordinator 叢集控制平面：依加入順序將 network.ip_ranges 分段分配給各 agent，下發場景並彙整 agent 回報的指標
+type Coordinator struct {
+	config *Config
+	logger *zap.Logger
+	pool   []net.IP // 依序分配的 Slave IP
+
+	mu       sync.Mutex
+	agents   map[string]*clusterAgent
+	next     int // 下一個未分配的 IP
+	scenario ScenarioType
+
+	server *grpc.Server
+	addr   net.Addr
+}
+
+// clusterAgent coordinator 端記錄的 agent (範圍固定，agent 離線後重新加入時取回相同範圍)
+type clusterAgent struct {
+	id       string
+	address  string
+	offset   int
+	count    int
+	joinedAt time.Time
+	lastSeen time.Time
+	report   clusterReport
+}
+
+// ClusterStatus 叢集狀態 (GET /api/cluster)
+type ClusterStatus struct {
+	Scenario string               `json:"scenario"`
+	Capacity int                  `json:"capacity"` // 可分配的 Slave IP 數
+	Assigned int                  `json:"assigned"` // 已分配的 Slave IP 數
+	Agents   []ClusterAgentStatus `json:"agents"`
+}
+
+// ClusterAgentStatus 單一 agent 的分配與最近一次回報
+type ClusterAgentStatus struct {
+	ID            string    `json:"id"`
+	Address       string    `json:"address"`
+	IPRanges      []IPRange `json:"ip_ranges"`
+	UnitIDStart   uint8     `json:"unit_id_start"`
+	Count         int       `json:"count"`
+	Up            bool      `json:"up"`
+	JoinedAt      time.Time `json:"joined_at"`
+	LastSeen      time.Time `json:"last_seen"`
+	Scenario      string    `json:"scenario"`
+	Slaves        int       `json:"slaves"`
+	ActiveSlaves  int       `json:"active_slaves"`
+	Requests      uint64    `json:"requests"`
+	Errors        uint64    `json:"errors"`
+	BytesReceived uint64    `json:"bytes_received"`
+	BytesSent     uint64    `json:"bytes_sent"`
+	Connections   int       `json:"connections"`
+}
+
+// NewCoordinator 建立 coordinator (Slave IP 取自 network.ip_ranges)
+func NewCoordinator(config *Config, logger *zap.Logger) (*Coordinator, error) {
+	pool, err := config.ExpandIPRanges()
+	if err != nil {
+		return nil, err
+	}
+	if len(pool) == 0 {
+		return nil, errors.New(T("叢集 coordinator 需要配置 network.ip_ranges"))
+	}
+	return &Coordinator{
+		config:   config,
+		logger:   logger,
+		pool:     pool,
+		agents:   make(map[string]*clusterAgent),
+		scenario: ParseScenarioType(config.Scenario.DefaultScenario),
+	}, nil
+}
+
+// Start 啟動 gRPC 服務
+func (c *Coordinator) Start(listen string) error {
+	if listen == "" {
+		listen = DefaultClusterListen
+	}
+	lis, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fmt.Errorf(T("監聽失敗: %w"), err)
+	}
+
+	c.server = grpc.NewServer(grpc.ForceServerCodec(pluginCodec{}))
+	c.server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: clusterServiceName,
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{
+			{
+				MethodName: "Join",
+				Handler: func(_ any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+					req := &clusterJoinRequest{}
+					if err := dec(req); err != nil {
+						return nil, err
+					}
+					return c.join(ctx, req)
+				},
+			},
+			{
+				MethodName: "Report",
+				Handler: func(_ any, _ context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+					req := &clusterReport{}
+					if err := dec(req); err != nil {
+						return nil, err
+					}
+					return c.handleReport(req), nil
+				},
+			},
+		},
+	}, c)
+	c.addr = lis.Addr()
+	go c.server.Serve(lis)
+
+	c.logger.Info(T("叢集 coordinator 已啟動"),
+		zap.String("addr", c.addr.String()),
+		zap.Int("capacity", len(c.pool)),
+		zap.String("scenario", c.scenario.String()),
+	)
+	return nil
+}
+
+// Addr gRPC 服務的實際監聽位址
+func (c *Coordinator) Addr() net.Addr {
+	return c.addr
+}
+
+// Stop 停止 gRPC 服務
+func (c *Coordinator) Stop() {
+	if c.server != nil {
+		c.server.Stop()
+	}
+}
+
+// join 分配範圍給 agent (已知的 agent 取回原本的範圍)
+func (c *Coordinator) join(ctx context.Context, req *clusterJoinRequest) (*clusterAssignment, error) {
+	if req.AgentID == "" {
+		return nil, status.Error(codes.InvalidArgument, T("缺少 agent 識別"))
+	}
+	capacity := c.config.Cluster.SlavesPerAgent
+	if capacity == 0 {
+		capacity = int(req.Capacity)
+	}
+	if capacity < 1 {
+		return nil, status.Error(codes.InvalidArgument, T("agent 可承載的 Slave 數必須大於 0"))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	agent, ok := c.agents[req.AgentID]
+	resumed := false
+	if !ok {
+		var offset int
+		offset, resumed = c.resumeOffset(req.Offset, capacity)
+		if !resumed {
+			if c.next >= len(c.pool) {
+				return nil, status.Error(codes.ResourceExhausted, T("沒有可分配的 Slave IP"))
+			}
+			offset = c.next
+		}
+		agent = &clusterAgent{
+			id:       req.AgentID,
+			offset:   offset,
+			count:    min(capacity, len(c.pool)-offset),
+			joinedAt: time.Now(),
+		}
+		c.next = max(c.next, agent.offset+agent.count)
+		c.agents[agent.id] = agent
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		agent.address = p.Addr.String()
+	}
+	agent.lastSeen = time.Now()
+
+	assignment := c.assignment(agent)
+	c.logger.Info(T("agent 已加入叢集"),
+		zap.String("agent", agent.id),
+		zap.String("address", agent.address),
+		zap.Int("offset", agent.offset),
+		zap.Int("count", agent.count),
+		zap.Bool("resumed", resumed),
+	)
+	return assignment, nil
+}
+
+// resumeOffset coordinator 重新啟動後沿用 agent 先前的範圍 (範圍未被其他 agent 占用時)
+func (c *Coordinator) resumeOffset(offset, capacity int) (int, bool) {
+	if offset < 0 || offset >= len(c.pool) {
+		return 0, false
+	}
+	end := min(offset+capacity, len(c.pool))
+	for _, agent := range c.agents {
+		if offset < agent.offset+agent.count && agent.offset < end {
+			return 0, false
+		}
+	}
+	return offset, true
+}
+
+// assignment agent 的分配內容 (呼叫端需持有 c.mu)
+func (c *Coordinator) assignment(agent *clusterAgent) *clusterAssignment {
+	return &clusterAssignment{
+		IPRanges:    compactIPRanges(c.pool[agent.offset : agent.offset+agent.count]),
+		UnitIDStart: uint32(c.unitIDStart(agent.offset)),
+		Count:       uint32(agent.count),
+		Scenario:    c.scenario.String(),
+		Offset:      uint32(agent.offset),
+	}
+}
+
+// unitIDStart 範圍第一個 Slave 的 Unit ID (與單機運行時相同的編號方式，接續前一個 agent)
+func (c *Coordinator) unitIDStart(offset int) uint8 {
+	return uint8((int(c.config.Slaves.UnitIDStart)+offset-1)%255 + 1)
+}
+
+// handleReport 記錄 agent 的回報並回應目前的場景
+func (c *Coordinator) handleReport(req *clusterReport) *clusterDirective {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	agent, ok := c.agents[req.AgentID]
+	if !ok {
+		return &clusterDirective{Rejoin: true}
+	}
+	agent.report = *req
+	agent.lastSeen = time.Now()
+	return &clusterDirective{Scenario: c.scenario.String()}
+}
+
+// SetScenario 變更整個叢集的場景 (agent 於下次回報時套用)
+func (c *Coordinator) SetScenario(name string) error {
+	scenario := ParseScenarioType(name)
+	if scenario.String() != name {
+		return fmt.Errorf(T("未知的場景: %s"), name)
+	}
+
+	c.mu.Lock()
+	c.scenario = scenario
+	c.mu.Unlock()
+
+	c.logger.Info(T("叢集場景已變更"), zap.String("scenario", name))
+	return nil
+}
+
+// Status 叢集狀態 (agent 依 ID 排序)
+func (c *Coordinator) Status() ClusterStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := ClusterStatus{
+		Scenario: c.scenario.String(),
+		Capacity: len(c.pool),
+		Agents:   make([]ClusterAgentStatus, 0, len(c.agents)),
+	}
+	timeout := clusterAgentTimeoutBeats * c.config.Cluster.heartbeat()
+	for _, agent := range c.agents {
+		result.Assigned += agent.count
+		result.Agents = append(result.Agents, ClusterAgentStatus{
+			ID:            agent.id,
+			Address:       agent.address,
+			IPRanges:      compactIPRanges(c.pool[agent.offset : agent.offset+agent.count]),
+			UnitIDStart:   c.unitIDStart(agent.offset),
+			Count:         agent.count,
+			Up:            time.Since(agent.lastSeen) < timeout,
+			JoinedAt:      agent.joinedAt,
+			LastSeen:      agent.lastSeen,
+			Scenario:      agent.report.Scenario,
+			Slaves:        int(agent.report.Slaves),
+			ActiveSlaves:  int(agent.report.ActiveSlaves),
+			Requests:      agent.report.Requests,
+			Errors:        agent.report.Errors,
+			BytesReceived: agent.report.BytesReceived,
+			BytesSent:     agent.report.BytesSent,
+			Connections:   int(agent.report.Connections),
+		})
+	}
+	sort.Slice(result.Agents, func(i, j int) bool { return result.Agents[i].ID < result.Agents[j].ID })
+	return result
+}
+
+// Register 註冊叢集管理 API
+func (c *Coordinator) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/cluster", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, c.Status())
+	})
+	mux.HandleFunc("PUT /api/cluster/scenario", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Scenario string `json:"scenario"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := c.SetScenario(req.Scenario); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, c.Status())
+	})
+}
+
+// StartMetrics 啟動指標與管理 API 的 HTTP 伺服器 (彙整指標沿用單機的指標名稱，既有的儀表板可直接使用)
+func (c *Coordinator) StartMetrics(endpoint string, port int) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	mux := http.NewServeMux()
+	c.Register(mux)
+	mux.Handle(endpoint, promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorLog: zap.NewStdLog(c.logger)}))
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "healthy"})
+	})
+
+	addr := fmt.Sprintf(":%d", port)
+	c.logger.Info(T("啟動指標伺服器"), zap.String("addr", addr))
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			c.logger.Error(T("指標伺服器錯誤"), zap.Error(err))
+		}
+	}()
+}
+
+var (
+	clusterAgentsDesc = prometheus.NewDesc("modbussim_cluster_agents",
+		"Number of agents known to the coordinator by state", []string{"state"}, nil)
+	clusterAgentSlavesDesc = prometheus.NewDesc("modbussim_cluster_agent_slaves",
+		"Number of slaves running on each agent", []string{"agent"}, nil)
+	clusterAgentRequestsDesc = prometheus.NewDesc("modbussim_cluster_agent_requests_total",
+		"Total number of requests served by each agent", []string{"agent"}, nil)
+)
+
+// clusterFleetMetrics 各 agent 最近一次回報的總和
+var clusterFleetMetrics = []struct {
+	desc  *prometheus.Desc
+	kind  prometheus.ValueType
+	value func(*clusterReport) float64
+}{
+	{prometheus.NewDesc("modbussim_slaves_total", "Total number of slaves", nil, nil), prometheus.GaugeValue,
+		func(r *clusterReport) float64 { return float64(r.Slaves) }},
+	{prometheus.NewDesc("modbussim_slaves_active", "Active number of slaves", nil, nil), prometheus.GaugeValue,
+		func(r *clusterReport) float64 { return float64(r.ActiveSlaves) }},
+	{prometheus.NewDesc("modbussim_connections_active", "Number of open Modbus TCP connections across all slaves", nil, nil), prometheus.GaugeValue,
+		func(r *clusterReport) float64 { return float64(r.Connections) }},
+	{prometheus.NewDesc("modbussim_requests_total", "Total number of requests", nil, nil), prometheus.CounterValue,
+		func(r *clusterReport) float64 { return float64(r.Requests) }},
+	{prometheus.NewDesc("modbussim_errors_total", "Total number of errors", nil, nil), prometheus.CounterValue,
+		func(r *clusterReport) float64 { return float64(r.Errors) }},
+	{prometheus.NewDesc("modbussim_bytes_received_total", "Total bytes received", nil, nil), prometheus.CounterValue,
+		func(r *clusterReport) float64 { return float64(r.BytesReceived) }},
+	{prometheus.NewDesc("modbussim_bytes_sent_total", "Total bytes sent", nil, nil), prometheus.CounterValue,
+		func(r *clusterReport) float64 { return float64(r.BytesSent) }},
+}
+
+// Describe 實作 prometheus.Collector
+func (c *Coordinator) Describe(ch chan<- *prometheus.Desc) {
+	ch <- clusterAgentsDesc
+	ch <- clusterAgentSlavesDesc
+	ch <- clusterAgentRequestsDesc
+	for _, metric := range clusterFleetMetrics {
+		ch <- metric.desc
+	}
+}
+
+// Collect 實作 prometheus.Collector
+func (c *Coordinator) Collect(ch chan<- prometheus.Metric) {
+	status := c.Status()
+
+	up := 0
+	for _, agent := range status.Agents {
+		if agent.Up {
+			up++
+		}
+		ch <- prometheus.MustNewConstMetric(clusterAgentSlavesDesc, prometheus.GaugeValue, float64(agent.Slaves), agent.ID)
+		ch <- prometheus.MustNewConstMetric(clusterAgentRequestsDesc, prometheus.CounterValue, float64(agent.Requests), agent.ID)
+	}
+	ch <- prometheus.MustNewConstMetric(clusterAgentsDesc, prometheus.GaugeValue, float64(up), "up")
+	ch <- prometheus.MustNewConstMetric(clusterAgentsDesc, prometheus.GaugeValue, float64(len(status.Agents)-up), "down")
+
+	c.mu.Lock()
+	totals := make([]float64, len(clusterFleetMetrics))
+	for _, agent := range c.agents {
+		for i, metric := range clusterFleetMetrics {
+			totals[i] += metric.value(&agent.report)
+		}
+	}
+	c.mu.Unlock()
+	for i, metric := range clusterFleetMetrics {
+		ch <- prometheus.MustNewConstMetric(metric.desc, metric.kind, totals[i])
+	}
+}
+
+// ClusterAgent 叢集 agent：向 coordinator 取得 Slave 範圍後運行引擎，定期回報指標並跟隨叢集的場景
+type ClusterAgent struct {
+	config *Config
+	logger *zap.Logger
+	id     string
+	conn   *grpc.ClientConn
+
+	assignment  *clusterAssignment
+	unavailable bool // 無法連線 coordinator (僅在狀態轉換時記錄日誌)
+}
+
+// NewClusterAgent 建立 agent (cluster.agent_id 未設定時以主機名稱識別)
+func NewClusterAgent(config *Config, logger *zap.Logger) (*ClusterAgent, error) {
+	if config.Cluster.Coordinator == "" {
+		return nil, errors.New(T("未指定 coordinator 位址"))
+	}
+	id := config.Cluster.AgentID
+	if id == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf(T("取得主機名稱失敗: %w"), err)
+		}
+		id = hostname
+	}
+
+	conn, err := grpc.NewClient(config.Cluster.Coordinator,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(pluginCodec{})),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &ClusterAgent{config: config, logger: logger, id: id, conn: conn}, nil
+}
+
+// Close 關閉與 coordinator 的連線
+func (a *ClusterAgent) Close() error {
+	return a.conn.Close()
+}
+
+// Join 取得分配的範圍並套用到配置 (IP 範圍、Slave 數與 Unit ID 起點)；
+// coordinator 尚未啟動時每隔回報間隔重試，直到 ctx 結束
+func (a *ClusterAgent) Join(ctx context.Context) error {
+	for {
+		err := a.join(ctx)
+		if err == nil {
+			return nil
+		}
+		switch status.Code(err) {
+		case codes.InvalidArgument, codes.ResourceExhausted:
+			return fmt.Errorf(T("加入叢集失敗: %w"), err)
+		}
+		a.logger.Warn(T("連線 coordinator 失敗，稍後重試"),
+			zap.String("coordinator", a.config.Cluster.Coordinator),
+			zap.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(a.config.Cluster.heartbeat()):
+		}
+	}
+}
+
+// join 呼叫 Join (已加入過時帶上原本的起點，讓重新啟動的 coordinator 沿用)
+func (a *ClusterAgent) join(ctx context.Context) error {
+	req := &clusterJoinRequest{AgentID: a.id, Capacity: uint32(max(a.config.Slaves.Count, 0)), Offset: -1}
+	if a.assignment != nil {
+		req.Offset = int(a.assignment.Offset)
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, clusterCallTimeout)
+	defer cancel()
+	resp := &clusterAssignment{}
+	if err := a.conn.Invoke(callCtx, "/"+clusterServiceName+"/Join", req, resp); err != nil {
+		return err
+	}
+
+	if a.assignment != nil && !a.assignment.sameRange(resp) {
+		// 執行中的引擎無法更換範圍，保留原本的 Slave 並提示重新啟動
+		a.logger.Error(T("coordinator 分配了不同的範圍，重新啟動 agent 後生效"),
+			zap.Int("offset", int(resp.Offset)),
+			zap.Int("count", int(resp.Count)),
+		)
+		return nil
+	}
+	a.assignment = resp
+
+	a.config.Network.IPRanges = resp.IPRanges
+	a.config.Slaves.Count = int(resp.Count)
+	a.config.Slaves.UnitIDStart = uint8(resp.UnitIDStart)
+	a.logger.Info(T("已取得叢集分配"),
+		zap.String("agent", a.id),
+		zap.Int("count", int(resp.Count)),
+		zap.Int("unit_id_start", int(resp.UnitIDStart)),
+		zap.String("scenario", resp.Scenario),
+	)
+	return nil
+}
+
+// Run 定期回報引擎指標並套用叢集的場景，直到 ctx 結束
+func (a *ClusterAgent) Run(ctx context.Context, engine *Engine) {
+	ticker := time.NewTicker(a.config.Cluster.heartbeat())
+	defer ticker.Stop()
+
+	for {
+		a.report(ctx, engine)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// report 回報一次指標
+func (a *ClusterAgent) report(ctx context.Context, engine *Engine) {
+	stats := engine.Stats()
+	req := &clusterReport{
+		AgentID:       a.id,
+		Slaves:        uint32(stats.SlaveCount),
+		ActiveSlaves:  uint32(max(stats.ActiveSlaves, 0)),
+		Requests:      stats.TotalRequests,
+		Errors:        stats.TotalErrors,
+		BytesReceived: stats.BytesReceived,
+		BytesSent:     stats.BytesSent,
+		Connections:   uint32(stats.ActiveConnections),
+		Scenario:      engine.GetScenario().String(),
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, clusterCallTimeout)
+	defer cancel()
+	resp := &clusterDirective{}
+	if err := a.conn.Invoke(callCtx, "/"+clusterServiceName+"/Report", req, resp); err != nil {
+		if !a.unavailable && ctx.Err() == nil {
+			a.unavailable = true
+			a.logger.Warn(T("回報 coordinator 失敗"), zap.Error(err))
+		}
+		return
+	}
+	if a.unavailable {
+		a.unavailable = false
+		a.logger.Info(T("已恢復與 coordinator 的連線"))
+	}
+
+	if resp.Rejoin {
+		if err := a.join(ctx); err != nil {
+			a.logger.Warn(T("重新加入叢集失敗"), zap.Error(err))
+		}
+		return
+	}
+	if resp.Scenario != "" && resp.Scenario != req.Scenario {
+		if err := engine.ApplyScenario(ParseScenarioType(resp.Scenario)); err != nil {
+			a.logger.Warn(T("套用叢集場景失敗"), zap.String("scenario", resp.Scenario), zap.Error(err))
+		}
+	}
+}
+
+// compactIPRanges 將連續的 IP 合併為範圍
+func compactIPRanges(ips []net.IP) []IPRange {
+	var ranges []IPRange
+	var prev net.IP
+	for _, ip := range ips {
+		if prev != nil {
+			next := slices.Clone(prev)
+			incIP(next)
+			if next.Equal(ip) {
+				ranges[len(ranges)-1].End = ip.String()
+				prev = ip
+				continue
+			}
+		}
+		ranges = append(ranges, IPRange{Start: ip.String(), End: ip.String()})
+		prev = ip
+	}
+	return ranges
+}
+
+// --- 協定訊息 (protobuf wire format，欄位編號對應 cluster/cluster.proto) ---
+
+// clusterJoinRequest 對應 JoinRequest
+type clusterJoinRequest struct {
+	AgentID  string
+	Capacity uint32
+	Offset   int // 先前分配的起點 (-1 = 首次加入)
+}
+
+func (m *clusterJoinRequest) marshal() []byte {
+	var b []byte
+	b = appendPluginString(b, 1, m.AgentID)
+	b = appendClusterVarint(b, 2, uint64(m.Capacity))
+	if m.Offset >= 0 {
+		b = protowire.AppendTag(b, 3, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(m.Offset))
+	}
+	return b
+}
+
+func (m *clusterJoinRequest) unmarshal(data []byte) error {
+	m.Offset = -1
+	return consumePluginFields(data, func(num protowire.Number, typ protowire.Type, v []byte, n uint64) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			m.AgentID = string(v)
+		case num == 2 && typ == protowire.VarintType:
+			m.Capacity = uint32(n)
+		case num == 3 && typ == protowire.VarintType:
+			m.Offset = int(uint32(n))
+		}
+	})
+}
+
+// clusterAssignment 對應 Assignment
+type clusterAssignment struct {
+	IPRanges    []IPRange
+	UnitIDStart uint32
+	Count       uint32
+	Scenario    string
+	Offset      uint32
+}
+
+// sameRange 是否為相同的範圍
+func (m *clusterAssignment) sameRange(other *clusterAssignment) bool {
+	return m.Offset == other.Offset && m.Count == other.Count && m.UnitIDStart == other.UnitIDStart
+}
+
+func (m *clusterAssignment) marshal() []byte {
+	var b []byte
+	for _, r := range m.IPRanges {
+		var entry []byte
+		entry = appendPluginString(entry, 1, r.Start)
+		entry = appendPluginString(entry, 2, r.End)
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+	b = appendClusterVarint(b, 2, uint64(m.UnitIDStart))
+	b = appendClusterVarint(b, 3, uint64(m.Count))
+	b = appendPluginString(b, 4, m.Scenario)
+	b = appendClusterVarint(b, 5, uint64(m.Offset))
+	return b
+}
+
+func (m *clusterAssignment) unmarshal(data []byte) error {
+	var err error
+	consumeErr := consumePluginFields(data, func(num protowire.Number, typ protowire.Type, v []byte, n uint64) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			var r IPRange
+			if e := consumePluginFields(v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) {
+				if typ != protowire.BytesType {
+					return
+				}
+				if num == 1 {
+					r.Start = string(v)
+				} else if num == 2 {
+					r.End = string(v)
+				}
+			}); e != nil {
+				err = e
+			}
+			m.IPRanges = append(m.IPRanges, r)
+		case num == 2 && typ == protowire.VarintType:
+			m.UnitIDStart = uint32(n)
+		case num == 3 && typ == protowire.VarintType:
+			m.Count = uint32(n)
+		case num == 4 && typ == protowire.BytesType:
+			m.Scenario = string(v)
+		case num == 5 && typ == protowire.VarintType:
+			m.Offset = uint32(n)
+		}
+	})
+	if consumeErr != nil {
+		return consumeErr
+	}
+	return err
+}
+
+// clusterReport 對應 Report
+type clusterReport struct {
+	AgentID       string
+	Slaves        uint32
+	ActiveSlaves  uint32
+	Requests      uint64
+	Errors        uint64
+	BytesReceived uint64
+	BytesSent     uint64
+	Connections   uint32
+	Scenario      string
+}
+
+func (m *clusterReport) marshal() []byte {
+	var b []byte
+	b = appendPluginString(b, 1, m.AgentID)
+	b = appendClusterVarint(b, 2, uint64(m.Slaves))
+	b = appendClusterVarint(b, 3, uint64(m.ActiveSlaves))
+	b = appendClusterVarint(b, 4, m.Requests)
+	b = appendClusterVarint(b, 5, m.Errors)
+	b = appendClusterVarint(b, 6, m.BytesReceived)
+	b = appendClusterVarint(b, 7, m.BytesSent)
+	b = appendClusterVarint(b, 8, uint64(m.Connections))
+	b = appendPluginString(b, 9, m.Scenario)
+	return b
+}
+
+func (m *clusterReport) unmarshal(data []byte) error {
+	return consumePluginFields(data, func(num protowire.Number, typ protowire.Type, v []byte, n uint64) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			m.AgentID = string(v)
+		case num == 2 && typ == protowire.VarintType:
+			m.Slaves = uint32(n)
+		case num == 3 && typ == protowire.VarintType:
+			m.ActiveSlaves = uint32(n)
+		case num == 4 && typ == protowire.VarintType:
+			m.Requests = n
+		case num == 5 && typ == protowire.VarintType:
+			m.Errors = n
+		case num == 6 && typ == protowire.VarintType:
+			m.BytesReceived = n
+		case num == 7 && typ == protowire.VarintType:
+			m.BytesSent = n
+		case num == 8 && typ == protowire.VarintType:
+			m.Connections = uint32(n)
+		case num == 9 && typ == protowire.BytesType:
+			m.Scenario = string(v)
+		}
+	})
+}
+
+// clusterDirective 對應 Directive
+type clusterDirective struct {
+	Scenario string
+	Rejoin   bool
+}
+
+func (m *clusterDirective) marshal() []byte {
+	var b []byte
+	b = appendPluginString(b, 1, m.Scenario)
+	if m.Rejoin {
+		b = appendClusterVarint(b, 2, 1)
+	}
+	return b
+}
+
+func (m *clusterDirective) unmarshal(data []byte) error {
+	return consumePluginFields(data, func(num protowire.Number, typ protowire.Type, v []byte, n uint64) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			m.Scenario = string(v)
+		case num == 2 && typ == protowire.VarintType:
+			m.Rejoin = n != 0
+		}
+	})
+}
+
+// appendClusterVarint 附加 varint 欄位 (proto3 的零值不輸出)
+func appendClusterVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"io"
+	"time"
+)
+
+// transportScenarios 只影響通訊 (暫存器照常波動) 的場景；組合時不呼叫其 Update，以免覆寫其他場景寫入的暫存器
+var transportScenarios = map[ScenarioType]bool{
+	ScenarioJitter:              true,
+	ScenarioPacketLoss:          true,
+	ScenarioConnectionFlap:      true,
+	ScenarioSlowDrain:           true,
+	ScenarioFragmentedResponse:  true,
+	ScenarioExceptionStorm:      true,
+	ScenarioCorruptedResponse:   true,
+	ScenarioConnectionChurn:     true,
+	ScenarioTransactionMismatch: true,
+}
+
+// ScenarioComponent 組合場景中的單一場景與其參數
+type ScenarioComponent struct {
+	Handler ScenarioHandler
+	Params  ScenarioParams
+}
+
+// --- Composite Scenario ---
+
+// CompositeScenario 組合場景 - 同時運行多個場景 (例如 voltage_sag + jitter + packet_loss)，依列出的順序套用：
+//   - 暫存器：依序呼叫會改變暫存器的場景 (後列者覆寫前列者寫入的值)；只影響通訊的場景不更新暫存器，全部皆是時照常波動
+//   - 請求：RequestInterceptor、ExceptionInjector 依序詢問，第一個回應例外者生效
+//   - 延遲取第一個 RequestJitter；丟包率依各場景合併 (1 - Π(1 - rate))
+//   - 回應：ResponseShaper 依序串接 (前列者的輸出交給後列者寫出)；ResponseSequencer 取第一個
+//   - Activate 依序呼叫；Reset 以相反順序呼叫
+type CompositeScenario struct {
+	components []ScenarioComponent
+}
+
+// NewCompositeScenario 建立組合場景
+func NewCompositeScenario(components ...ScenarioComponent) *CompositeScenario {
+	return &CompositeScenario{components: components}
+}
+
+func (s *CompositeScenario) Type() ScenarioType {
+	return ScenarioComposite
+}
+
+// Components 組合的場景 (依套用順序)
+func (s *CompositeScenario) Components() []ScenarioComponent {
+	return s.components
+}
+
+// component 組合中 scenario 的參數；未包含時回傳 false
+func (s *CompositeScenario) component(scenario ScenarioType) (ScenarioParams, bool) {
+	for _, c := range s.components {
+		if c.Handler.Type() == scenario {
+			return c.Params, true
+		}
+	}
+	return ScenarioParams{}, false
+}
+
+// dataComponents 會改變暫存器的場景
+func (s *CompositeScenario) dataComponents() []ScenarioComponent {
+	var components []ScenarioComponent
+	for _, c := range s.components {
+		if !transportScenarios[c.Handler.Type()] {
+			components = append(components, c)
+		}
+	}
+	return components
+}
+
+func (s *CompositeScenario) Update(registers *RegisterMap, params ScenarioParams) {
+	components := s.dataComponents()
+	if len(components) == 0 {
+		GetScenarioHandler(ScenarioNormal).Update(registers, ScenarioParams{})
+		return
+	}
+	for _, c := range components {
+		c.Handler.Update(registers, c.Params)
+	}
+}
+
+func (s *CompositeScenario) Reset(registers *RegisterMap) {
+	for i := len(s.components) - 1; i >= 0; i-- {
+		s.components[i].Handler.Reset(registers)
+	}
+	if len(s.dataComponents()) == 0 {
+		GetScenarioHandler(ScenarioNormal).Reset(registers)
+	}
+}
+
+// Activate 依序呼叫各場景的 Activate
+func (s *CompositeScenario) Activate(registers *RegisterMap) {
+	for _, c := range s.components {
+		if activator, ok := c.Handler.(ScenarioActivator); ok {
+			activator.Activate(registers)
+		}
+	}
+}
+
+// Energy 最後一個更新電能的場景的累計電能
+func (s *CompositeScenario) Energy(registers *RegisterMap) (float64, bool) {
+	components := s.dataComponents()
+	if len(components) == 0 {
+		return GetScenarioHandler(ScenarioNormal).(EnergyAccumulator).Energy(registers)
+	}
+	for i := len(components) - 1; i >= 0; i-- {
+		if accumulator, ok := components[i].Handler.(EnergyAccumulator); ok {
+			if energy, ok := accumulator.Energy(registers); ok {
+				return energy, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// InterceptRequest 依序詢問各場景，第一個攔截者生效
+func (s *CompositeScenario) InterceptRequest(registers *RegisterMap, functionCode uint8, data []byte, params ScenarioParams) (uint8, bool) {
+	for _, c := range s.components {
+		if interceptor, ok := c.Handler.(RequestInterceptor); ok {
+			if code, ok := interceptor.InterceptRequest(registers, functionCode, data, c.Params); ok {
+				return code, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// InjectException 依序詢問各場景，第一個注入例外者生效
+func (s *CompositeScenario) InjectException(functionCode uint8, params ScenarioParams) (uint8, bool) {
+	for _, c := range s.components {
+		if injector, ok := c.Handler.(ExceptionInjector); ok {
+			if code, ok := injector.InjectException(functionCode, c.Params); ok {
+				return code, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// delayModel 第一個 RequestJitter 場景的延遲模型 (沒有時為 nil)
+func (s *CompositeScenario) delayModel() *DelayModel {
+	for _, c := range s.components {
+		if jitter, ok := c.Handler.(RequestJitter); ok {
+			model := jitter.JitterModel(c.Params)
+			return &model
+		}
+	}
+	return nil
+}
+
+// LossRate 合併各場景的丟包率 (任一場景丟棄即不回應)
+func (s *CompositeScenario) LossRate(params ScenarioParams) float64 {
+	delivered := 1.0
+	for _, c := range s.components {
+		if loss, ok := c.Handler.(RequestLoss); ok {
+			delivered *= 1 - loss.LossRate(c.Params)
+		}
+	}
+	return 1 - delivered
+}
+
+// WriteResponse 依序串接各場景的 ResponseShaper，最後一個寫出到連線
+func (s *CompositeScenario) WriteResponse(w io.Writer, response []byte, params ScenarioParams) error {
+	for i := len(s.components) - 1; i >= 0; i-- {
+		if shaper, ok := s.components[i].Handler.(ResponseShaper); ok {
+			w = &shapedWriter{shaper: shaper, params: s.components[i].Params, w: w}
+		}
+	}
+	_, err := w.Write(response)
+	return err
+}
+
+// SequenceResponse 交由第一個 ResponseSequencer 場景處理
+func (s *CompositeScenario) SequenceResponse(response []byte, previous uint16, hasPrevious bool, params ScenarioParams) ([]byte, time.Duration) {
+	for _, c := range s.components {
+		if sequencer, ok := c.Handler.(ResponseSequencer); ok {
+			return sequencer.SequenceResponse(response, previous, hasPrevious, c.Params)
+		}
+	}
+	return response, 0
+}
+
+// shapedWriter 將寫入的資料交給 ResponseShaper 寫出到下一層
+type shapedWriter struct {
+	shaper ResponseShaper
+	params ScenarioParams
+	w      io.Writer
+}
+
+func (w *shapedWriter) Write(p []byte) (int, error) {
+	if err := w.shaper.WriteResponse(w.w, p, w.params); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// scenarioComponent 場景為 target 或為包含 target 的組合場景時，回傳 target 的參數
+func scenarioComponent(target ScenarioType, handler ScenarioHandler, params ScenarioParams) (ScenarioParams, bool) {
+	if handler.Type() == target {
+		return params, true
+	}
+	if composite, ok := handler.(*CompositeScenario); ok {
+		return composite.component(target)
+	}
+	return ScenarioParams{}, false
+}
+
+// scenarioHandler 場景的處理器；組合場景依 components 與各場景目前生效的參數組成
+func (s *Slave) scenarioHandler(scenario ScenarioType) ScenarioHandler {
+	if scenario != ScenarioComposite {
+		return GetScenarioHandler(scenario)
+	}
+	names := s.scenarioParams(ScenarioComposite).Components
+	components := make([]ScenarioComponent, 0, len(names))
+	for _, name := range names {
+		component := ParseScenarioType(name)
+		components = append(components, ScenarioComponent{
+			Handler: GetScenarioHandler(component),
+			Params:  s.scenarioParams(component),
+		})
+	}
+	return NewCompositeScenario(components...)
+}
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
+	"net/url"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/spf13/viper"
@@ -18,37 +22,225 @@ type Config struct {
 	Scenario ScenarioConfig `json:"scenario" mapstructure:"scenario"`
 	Logging  LoggingConfig  `json:"logging" mapstructure:"logging"`
 	Metrics  MetricsConfig  `json:"metrics" mapstructure:"metrics"`
+	Tracing  TracingConfig  `json:"tracing" mapstructure:"tracing"`
+	Audit    AuditConfig    `json:"audit" mapstructure:"audit"`
+
+	Persistence PersistenceConfig `json:"persistence" mapstructure:"persistence"`
+	MQTT        MQTTConfig        `json:"mqtt" mapstructure:"mqtt"`
+	OPCUA       OPCUAConfig       `json:"opcua" mapstructure:"opcua"`
+	IEC104      IEC104Config      `json:"iec104" mapstructure:"iec104"`
+	BACnet      BACnetConfig      `json:"bacnet" mapstructure:"bacnet"`
+
+	Redundancy RedundancyConfig `json:"redundancy" mapstructure:"redundancy"`
+	Protection ProtectionConfig `json:"protection" mapstructure:"protection"`
+
+	FailureDomains    []FailureDomain        `json:"failure_domains" mapstructure:"failure_domains"`
+	BootStorm         BootStormConfig        `json:"boot_storm" mapstructure:"boot_storm"`
+	Decommission      DecommissionConfig     `json:"decommission" mapstructure:"decommission"`
+	WriteHooks        []WriteHookRule        `json:"write_hooks" mapstructure:"write_hooks"`
+	ACL               []ACLRule              `json:"acl" mapstructure:"acl"`
+	FunctionOverrides []FunctionOverrideRule `json:"function_overrides" mapstructure:"function_overrides"`
+
+	Drift   DriftConfig   `json:"drift" mapstructure:"drift"`
+	Polling PollingConfig `json:"polling" mapstructure:"polling"`
+	Clock   ClockConfig   `json:"clock" mapstructure:"clock"`
+
+	Privilege   PrivilegeConfig   `json:"privilege" mapstructure:"privilege"`
+	Diagnostics DiagnosticsConfig `json:"diagnostics" mapstructure:"diagnostics"`
+	Cluster     ClusterConfig     `json:"cluster" mapstructure:"cluster"`
+
+	Language string `json:"language" mapstructure:"language"` // 訊息語系: zh-TW | en | auto
+	Seed     int64  `json:"seed,omitempty" mapstructure:"seed"` // 亂數種子 (0 = 啟動時依時間產生；相同種子可重現模擬)
 }
 
 // ServerConfig 伺服器配置
 type ServerConfig struct {
 	Port            int           `json:"port" mapstructure:"port"`
-	ReadTimeout     time.Duration `json:"read_timeout" mapstructure:"read_timeout"`
-	WriteTimeout    time.Duration `json:"write_timeout" mapstructure:"write_timeout"`
-	MaxConnections  int           `json:"max_connections" mapstructure:"max_connections"`
+	ReadTimeout     time.Duration `json:"read_timeout" mapstructure:"read_timeout"`   // 收到請求的第一個 byte 後讀完整個訊框的期限 (0 表示不限)
+	WriteTimeout    time.Duration `json:"write_timeout" mapstructure:"write_timeout"` // 每次寫出回應的期限 (0 表示不限)
+	IdleTimeout     time.Duration `json:"idle_timeout" mapstructure:"idle_timeout"`   // 連線閒置 (等待下一個請求) 超過此時間即關閉 (0 表示不限)
+	MaxConnections  int           `json:"max_connections" mapstructure:"max_connections"` // 每個 Slave 的同時連線數上限 (0 表示不限)
 	GracefulTimeout time.Duration `json:"graceful_timeout" mapstructure:"graceful_timeout"`
+	MaxADUSize      int           `json:"max_adu_size" mapstructure:"max_adu_size"` // 請求/回應 ADU 上限 (bytes)
+	BindRetryInterval time.Duration `json:"bind_retry_interval" mapstructure:"bind_retry_interval"` // 位址已被占用時的重試間隔 (0 表示不重試)
+	BindRetryMax      int           `json:"bind_retry_max" mapstructure:"bind_retry_max"`           // 重試次數上限 (0 表示不限)
+	Listener          string        `json:"listener" mapstructure:"listener"`                       // per_slave (預設，每個 Slave 各自監聽) | shared (共用 listener，依目的 IP 分派)
+	OriginalDst       bool          `json:"original_dst" mapstructure:"original_dst"`               // shared 模式以 SO_ORIGINAL_DST 取得目的 IP (搭配 iptables REDIRECT，僅 Linux)
+	ExtraPorts        []int         `json:"extra_ports,omitempty" mapstructure:"extra_ports"`       // 每個 Slave 除 port 外同時監聽的埠號 (例如 [5020])
+	SingleMaster      string        `json:"single_master,omitempty" mapstructure:"single_master"`   // 每個 Slave 同時只服務一個連線: 空值 (停用) | refuse | queue
+	MaxInFlight       int           `json:"max_in_flight,omitempty" mapstructure:"max_in_flight"`   // 每條連線同時處理的 pipelined 請求數 (0、1 表示依序處理)
 }
 
+// 單一 Master 模式 (模擬只有一個 socket 的設備)
+const (
+	SingleMasterRefuse = "refuse" // 已有連線時以 RST 拒絕其他連線
+	SingleMasterQueue  = "queue"  // 其他連線排隊，前一個連線關閉後才開始處理其請求
+)
+
 // NetworkConfig 網路配置
 type NetworkConfig struct {
 	Interface string    `json:"interface" mapstructure:"interface"`
 	IPRanges  []IPRange `json:"ip_ranges" mapstructure:"ip_ranges"`
+
+	// 虛擬 IP 的配置方式：alias (預設，直接加在 interface 上) | dummy | macvlan (建立專用介面，避免大量 IP 加在主要網卡上)
+	Mode   string `json:"mode,omitempty" mapstructure:"mode"`
+	Link   string `json:"link,omitempty" mapstructure:"link"`     // dummy/macvlan 專用介面名稱 (預設 modbussim0)
+	Parent string `json:"parent,omitempty" mapstructure:"parent"` // macvlan 的上層介面 (預設 interface)
+	MTU    int    `json:"mtu,omitempty" mapstructure:"mtu"`       // 專用介面的 MTU (0 = 核心預設)
+
+	// 記錄已配置 IP 的狀態檔 (讓之後另一個程序的 network teardown/list 知道哪些 IP 由模擬器建立)
+	StateFile string `json:"state_file" mapstructure:"state_file"`
+
+	// 配置後對每個 IP 發送 gratuitous ARP (IPv6 為 unsolicited NA)，讓上游交換器與路由器立即學習 (預設開啟)
+	Announce bool `json:"announce" mapstructure:"announce"`
+	// 每秒通告的 IP 數 (0 = 依 slaves.ramp_up_rate，皆未設定時不限制)
+	AnnounceRate float64 `json:"announce_rate,omitempty" mapstructure:"announce_rate"`
 }
 
+// 虛擬 IP 的配置方式
+const (
+	NetworkModeAlias   = "alias"   // 以別名加在既有網路介面上
+	NetworkModeDummy   = "dummy"   // 建立 dummy 介面 (需自行將 IP 路由到本機)
+	NetworkModeMacvlan = "macvlan" // 建立掛在上層介面的 macvlan (bridge 模式，各 IP 以獨立 MAC 回應 ARP)
+)
+
+// DefaultNetworkLink dummy/macvlan 模式的預設專用介面名稱
+const DefaultNetworkLink = "modbussim0"
+
 // IPRange IP 範圍
 type IPRange struct {
 	Start string `json:"start" mapstructure:"start"`
 	End   string `json:"end" mapstructure:"end"`
 	CIDR  string `json:"cidr" mapstructure:"cidr"`
+
+	// Interface 此範圍所在的網路介面，覆寫 network.interface；
+	// 指定時範圍內的 Slave 也只在該介面上收送封包 (SO_BINDTODEVICE)
+	Interface string `json:"interface,omitempty" mapstructure:"interface"`
+
+	// Transport 範圍內 Slave 的 Modbus 傳輸方式：tcp (預設) | udp (相同的 MBAP 訊框，每個 datagram 一個請求)
+	Transport string `json:"transport,omitempty" mapstructure:"transport"`
 }
 
+// Modbus 傳輸方式
+const (
+	TransportTCP = "tcp"
+	TransportUDP = "udp" // Modbus UDP (部分舊型 RTU 使用)
+)
+
 // SlavesConfig Slave 配置
 type SlavesConfig struct {
 	Count            int                     `json:"count" mapstructure:"count"`
 	UnitIDStart      uint8                   `json:"unit_id_start" mapstructure:"unit_id_start"`
+	Profile          string                  `json:"profile" mapstructure:"profile"`
 	DefaultRegisters []RegisterDefinition    `json:"default_registers" mapstructure:"default_registers"`
+	RegistersFile    string                  `json:"registers_file,omitempty" mapstructure:"registers_file"` // 外部暫存器對應檔 (JSON/YAML，相對於配置檔；default_registers 中相同位址者覆寫檔案內容)
+	Addressing       string                  `json:"addressing,omitempty" mapstructure:"addressing"` // default_registers 的位址慣例: auto (預設) | protocol | plc | modicon
+	ProfileFunctions map[string][]uint8      `json:"profile_functions,omitempty" mapstructure:"profile_functions"` // 設定檔 -> 支援的功能碼 (覆寫設定檔宣告的集合，其他功能碼回應 0x01)
+	Tags             map[string][]string     `json:"tags,omitempty" mapstructure:"tags"` // 標籤 -> IP/CIDR 清單
+	RefreshInterval  time.Duration           `json:"refresh_interval,omitempty" mapstructure:"refresh_interval"` // 量測值內部更新週期，覆寫設備設定檔的預設 (0 = 依設定檔)
+	RegisterSharing  string                  `json:"register_sharing,omitempty" mapstructure:"register_sharing"` // shared (預設，共用設定檔基準，寫入時複製) | independent (各自完整的暫存器)
+	Randomize        NominalRandomization    `json:"randomize,omitempty" mapstructure:"randomize"`
+	Units            []UnitConfig            `json:"units,omitempty" mapstructure:"units"` // 每個端點後方的邏輯設備 (第一個為主設備，覆寫 unit_id_start)
+	UnknownUnit      string                  `json:"unknown_unit,omitempty" mapstructure:"unknown_unit"` // 未配置的 Unit ID: silent (預設，不回應) | gateway_exception
+	DownstreamTimeout time.Duration          `json:"downstream_timeout,omitempty" mapstructure:"downstream_timeout"` // gateway_exception 模式回應 0x0B 前的下游逾時 (0 = 1s)
+	RequestRateLimit float64                 `json:"request_rate_limit,omitempty" mapstructure:"request_rate_limit"` // 每個 Slave 每秒請求數上限，超過時回應 0x06 (0 = 不限)
+	RequestBurst     int                     `json:"request_burst,omitempty" mapstructure:"request_burst"` // 允許的瞬間請求數 (0 = request_rate_limit，至少 1)
+	Identity         string                  `json:"identity,omitempty" mapstructure:"identity"` // Slave 身分來源: static (預設，依配置) | pod (Kubernetes StatefulSet 的 Pod 環境變數)
+	RampUpRate       float64                 `json:"ramp_up_rate,omitempty" mapstructure:"ramp_up_rate"` // 啟動時每秒上線的 Slave 數 (0 = 同時啟動)
+	RampDownRate     float64                 `json:"ramp_down_rate,omitempty" mapstructure:"ramp_down_rate"` // 停止時每秒下線的 Slave 數 (0 = 同時停止；受 graceful_timeout 限制)
+	DeviceClock      DeviceClockConfig       `json:"device_clock,omitempty" mapstructure:"device_clock"`
+	Demand           DemandConfig            `json:"demand,omitempty" mapstructure:"demand"`
+	Tariff           TariffConfig            `json:"tariff,omitempty" mapstructure:"tariff"`
+}
+
+// 設備時鐘暫存器的格式
+const (
+	DeviceClockFormatDateTime = "datetime" // 年、月、日、時、分、秒各佔 1 個暫存器
+	DeviceClockFormatUnix     = "unix"     // Unix 秒 (uint32，高位字在前，佔 2 個暫存器)
+)
+
+// DeviceClockConfig 設備時鐘 (各 Slave 各自走時的日期時間暫存器，可設定漂移與偏差，供測試 EMS 的時間戳記與校時邏輯)
+type DeviceClockConfig struct {
+	Enabled        bool          `json:"enabled" mapstructure:"enabled"`
+	Register       uint16        `json:"register,omitempty" mapstructure:"register"` // 起始保持暫存器 (預設 40200)；Master 寫入時以寫入的時間校時
+	Format         string        `json:"format,omitempty" mapstructure:"format"` // datetime (預設) | unix
+	TimeZone       string        `json:"time_zone,omitempty" mapstructure:"time_zone"` // datetime 格式的時區 (IANA 名稱，預設 UTC)
+	DriftPPM       float64       `json:"drift_ppm,omitempty" mapstructure:"drift_ppm"` // 走時誤差 (ppm，正值為走快；50 ppm 約每天快 4.3 秒)
+	DriftSpread    float64       `json:"drift_spread,omitempty" mapstructure:"drift_spread"` // 各 Slave 的走時誤差在 drift_ppm ± drift_spread 內隨機
+	Skew           time.Duration `json:"skew,omitempty" mapstructure:"skew"` // 啟動時的時間偏差 (正值為超前)
+	SkewSpread     time.Duration `json:"skew_spread,omitempty" mapstructure:"skew_spread"` // 各 Slave 的偏差在 skew ± skew_spread 內隨機
+	DeviceIDObject uint8         `json:"device_id_object,omitempty" mapstructure:"device_id_object"` // 以 FC 43 (Read Device Identification) 的擴充物件提供設備時間 (0x80-0xFF，0 = 不提供)
 }
 
+// 最大需量的重設週期
+const (
+	DemandPeakResetMonthly = "monthly" // 每月 1 日 00:00 (計費週期)
+	DemandPeakResetDaily   = "daily"   // 每日 00:00
+	DemandPeakResetNever   = "never"   // 不重設
+)
+
+// DemandConfig 需量與區間電能 (依有功功率以模擬時鐘計算，供測試 EMS 的計費模組)
+type DemandConfig struct {
+	Enabled      bool          `json:"enabled" mapstructure:"enabled"`
+	Register     uint16        `json:"register,omitempty" mapstructure:"register"` // 起始保持暫存器 (預設 40220，佔 10 個暫存器)
+	Window       time.Duration `json:"window,omitempty" mapstructure:"window"` // 滑動需量的時窗 (預設 15m)
+	Subintervals int           `json:"subintervals,omitempty" mapstructure:"subintervals"` // 時窗的子區間數，每個子區間結束時更新需量 (預設 15)
+	Interval     time.Duration `json:"interval,omitempty" mapstructure:"interval"` // 區間電能的週期 (預設 15m)
+	PeakReset    string        `json:"peak_reset,omitempty" mapstructure:"peak_reset"` // 最大需量的重設週期: monthly (預設) | daily | never
+	TimeZone     string        `json:"time_zone,omitempty" mapstructure:"time_zone"` // 區間與重設週期對齊的時區 (IANA 名稱，預設 UTC)
+}
+
+// TariffConfig 多費率電能 (依分時電價時段將有功電能累積至 T1-T4，依模擬時鐘切換，供測試 EMS 的費率處理)
+type TariffConfig struct {
+	Enabled  bool           `json:"enabled" mapstructure:"enabled"`
+	Register uint16         `json:"register,omitempty" mapstructure:"register"` // 起始保持暫存器 (預設 40230，目前費率 + T1-T4 共 9 個暫存器)
+	Schedule []TariffPeriod `json:"schedule,omitempty" mapstructure:"schedule"` // 分時電價時段 (預設 00:00 T1、07:00 T2、17:00 T3、22:00 T1)
+	TimeZone string         `json:"time_zone,omitempty" mapstructure:"time_zone"` // 時段的時區 (IANA 名稱，預設 UTC)
+}
+
+// TariffPeriod 分時電價時段：自 start 起至下一個時段開始前計入 tariff
+type TariffPeriod struct {
+	Start  string   `json:"start" mapstructure:"start"` // 開始時間 (HH:MM)
+	Tariff int      `json:"tariff" mapstructure:"tariff"` // 費率 1-4
+	Days   []string `json:"days,omitempty" mapstructure:"days"` // 適用的星期 (mon-sun，空白 = 每天)
+}
+
+// UnitConfig 閘道後方以 Unit ID 定址的邏輯設備
+type UnitConfig struct {
+	UnitID  uint8  `json:"unit_id" mapstructure:"unit_id"`
+	Profile string `json:"profile,omitempty" mapstructure:"profile"` // 設備設定檔 (空字串 = slaves.profile)
+}
+
+// NominalRandomization 每個 Slave 的額定值與初始電能隨機化 (讓整批 Slave 看起來是不同的電表)
+type NominalRandomization struct {
+	Enabled       bool    `json:"enabled" mapstructure:"enabled"`
+	VoltageSpread float64 `json:"voltage_spread,omitempty" mapstructure:"voltage_spread"` // 額定電壓 ± V (0 = 2V)
+	CurrentMean   float64 `json:"current_mean,omitempty" mapstructure:"current_mean"`     // 額定電流的常態分佈平均 (A，0 = 15.5A)
+	CurrentStdDev float64 `json:"current_stddev,omitempty" mapstructure:"current_stddev"` // 額定電流的標準差 (A，0 = 平均的 20%)
+	EnergyMax     float64 `json:"energy_max,omitempty" mapstructure:"energy_max"`         // 初始累計電能上限 (kWh，0 = 10000)
+}
+
+// 請求未配置的 Unit ID 時的行為
+const (
+	UnknownUnitSilent           = "silent"            // 不回應 (Master 自行逾時)
+	UnknownUnitGatewayException = "gateway_exception" // 等待下游逾時後回應 Gateway Target Device Failed to Respond (0x0B)
+)
+
+// Slave 身分來源
+const (
+	SlaveIdentityStatic = "static" // 依配置的 IP 範圍與 unit_id_start
+	SlaveIdentityPod    = "pod"    // 依 Pod 的 IP 與 StatefulSet 序號 (水平擴展時每個 Pod 為不同的 Slave)
+)
+
+// DefaultDownstreamTimeout gateway_exception 模式的預設下游逾時
+const DefaultDownstreamTimeout = time.Second
+
+// Slave 暫存器的配置方式
+const (
+	RegisterSharingShared      = "shared"      // 同一設定檔的 Slave 共用一份基準，寫入時才複製該頁
+	RegisterSharingIndependent = "independent" // 每個 Slave 各自建立完整的暫存器
+)
+
 // RegisterDefinition 暫存器定義
 type RegisterDefinition struct {
 	Address     uint16   `json:"address" mapstructure:"address"`
@@ -58,12 +250,156 @@ type RegisterDefinition struct {
 	DefaultValue float64 `json:"default_value" mapstructure:"default_value"`
 	Unit        string   `json:"unit" mapstructure:"unit"`
 	Writable    bool     `json:"writable" mapstructure:"writable"`
+	Expression  string   `json:"expression,omitempty" mapstructure:"expression"` // 衍生值運算式，例如 "40001 * 40002 * 40006"
+	DefaultText string   `json:"default_text,omitempty" mapstructure:"default_text"` // string(N) 類型的初始內容 (ASCII)
+
+	source string // 來源的暫存器對應檔 (配置檔內的定義為空字串)
+}
+
+// RedundancyConfig 備援配對配置
+type RedundancyConfig struct {
+	StandbyMode string          `json:"standby_mode" mapstructure:"standby_mode"` // refuse | silent
+	Pairs       []RedundantPair `json:"pairs" mapstructure:"pairs"`
+}
+
+// RedundantPair 主備配對 (同一時間僅作用中的一端回應)
+type RedundantPair struct {
+	Name             string        `json:"name" mapstructure:"name"`
+	Primary          string        `json:"primary" mapstructure:"primary"`
+	Standby          string        `json:"standby" mapstructure:"standby"`
+	FailoverInterval time.Duration `json:"failover_interval,omitempty" mapstructure:"failover_interval"`
+}
+
+// Modbus TCP 接入模式
+const (
+	ListenerPerSlave = "per_slave" // 每個 Slave 各自監聽 IP:port
+	ListenerShared   = "shared"    // 每個埠號一個 listener，依連線的目的 IP 分派給 Slave
+)
+
+// 備援端行為模式
+const (
+	StandbyModeRefuse = "refuse" // 關閉 listener，拒絕連線
+	StandbyModeSilent = "silent" // 接受連線但不回應
+)
+
+// ProtectionConfig 故障注入保護配置 (保護時段或標籤內的 Slave 不注入故障)
+type ProtectionConfig struct {
+	Windows []ProtectedWindow `json:"windows" mapstructure:"windows"`
+	Tags    []string          `json:"tags" mapstructure:"tags"` // 永遠受保護的 Slave 標籤
+}
+
+// ProtectedWindow 每日保護時段 (本地時間，可跨午夜)
+type ProtectedWindow struct {
+	Name  string   `json:"name" mapstructure:"name"`
+	Start string   `json:"start" mapstructure:"start"` // HH:MM
+	End   string   `json:"end" mapstructure:"end"`     // HH:MM
+	Tags  []string `json:"tags,omitempty" mapstructure:"tags"` // 僅保護指定標籤，空值表示全部 Slave
+}
+
+// FailureDomain 故障域 (例如同一交換器/閘道下的 Slave)，停擺時域內 Slave 同時離線、同時恢復
+type FailureDomain struct {
+	Name           string        `json:"name" mapstructure:"name"`
+	Targets        []string      `json:"targets,omitempty" mapstructure:"targets"`                 // IP/CIDR
+	Tags           []string      `json:"tags,omitempty" mapstructure:"tags"`                       // Slave 標籤
+	OutageInterval time.Duration `json:"outage_interval,omitempty" mapstructure:"outage_interval"` // 自動停擺間隔 (恢復後起算)，0 表示僅手動觸發
+	OutageDuration time.Duration `json:"outage_duration,omitempty" mapstructure:"outage_duration"` // 每次停擺時間 (預設 30s)
+}
+
+// BootStormConfig 開機風暴 (模擬全場斷電後復電，所有 Slave 幾乎同時上線)
+type BootStormConfig struct {
+	Outage  time.Duration `json:"outage" mapstructure:"outage"`             // 斷電時間
+	Stagger time.Duration `json:"stagger" mapstructure:"stagger"`           // 復電後各 Slave 在此時間窗內隨機上線，0 表示同時上線
+	Targets []string      `json:"targets,omitempty" mapstructure:"targets"` // IP/CIDR，空值表示全部
+}
+
+// DecommissionConfig 除役排程 (Slave 到期後永久移除：關閉 listener 並自 Slave 列表移除，可選擇一併移除虛擬 IP)
+type DecommissionConfig struct {
+	RemoveIP bool               `json:"remove_ip" mapstructure:"remove_ip"` // 除役時自網路介面移除 Slave 的虛擬 IP (僅 Linux，需 root)
+	Rules    []DecommissionRule `json:"rules" mapstructure:"rules"`
+}
+
+// DecommissionRule 除役規則 (依順序比對，第一個符合的規則生效；lifetime 與 at 擇一)
+type DecommissionRule struct {
+	Name     string        `json:"name" mapstructure:"name"`
+	Targets  []string      `json:"targets,omitempty" mapstructure:"targets"`   // IP/CIDR
+	Tags     []string      `json:"tags,omitempty" mapstructure:"tags"`         // Slave 標籤；targets 與 tags 皆空表示全部 Slave
+	Lifetime time.Duration `json:"lifetime,omitempty" mapstructure:"lifetime"` // Slave 啟動後的最大存活時間
+	At       string        `json:"at,omitempty" mapstructure:"at"`             // 指定除役時間 (RFC3339)
+	Jitter   time.Duration `json:"jitter,omitempty" mapstructure:"jitter"`     // 各 Slave 額外隨機延後 0~jitter，讓成員陸續除役
+}
+
+// ACLRule 用戶端存取控制 (依順序比對 Slave，第一個符合的規則生效；未符合任何規則的 Slave 不限制)
+type ACLRule struct {
+	Name    string   `json:"name" mapstructure:"name"`
+	Targets []string `json:"targets,omitempty" mapstructure:"targets"` // IP/CIDR
+	Tags    []string `json:"tags,omitempty" mapstructure:"tags"`       // Slave 標籤；targets 與 tags 皆空表示全部 Slave
+	Allow   []string `json:"allow" mapstructure:"allow"`               // 允許連線的用戶端 IP/CIDR，空值表示全部拒絕
+	Action  string   `json:"action,omitempty" mapstructure:"action"`   // 其他來源的處理: reject (預設) | exception
+}
+
+// 不在允許清單的用戶端的處理方式
+const (
+	ACLActionReject    = "reject"    // 接受後立即以 RST 關閉連線 (UDP 不回應)
+	ACLActionException = "exception" // 保持連線，每個請求回應 Gateway Path Unavailable (0x0A)
+)
+
+// FunctionOverrideRule 功能碼行為覆寫 (重現特定廠牌設備對某些功能碼的怪異行為；
+// 所有符合 Slave 的規則皆生效，同一功能碼以先列出的規則為準)
+type FunctionOverrideRule struct {
+	Name      string        `json:"name" mapstructure:"name"`
+	Targets   []string      `json:"targets,omitempty" mapstructure:"targets"`     // IP/CIDR
+	Tags      []string      `json:"tags,omitempty" mapstructure:"tags"`           // Slave 標籤
+	Profiles  []string      `json:"profiles,omitempty" mapstructure:"profiles"`   // 設備設定檔；targets、tags、profiles 皆空表示全部 Slave
+	Functions []uint8       `json:"functions" mapstructure:"functions"`           // 套用的功能碼 (例如 [16])
+	Exception string        `json:"exception,omitempty" mapstructure:"exception"` // 一律回應的例外 (例如 slave_device_busy)，省略時照常處理
+	Delay     time.Duration `json:"delay,omitempty" mapstructure:"delay"`         // 回應前額外延遲
+}
+
+// WriteHookRule 寫入回呼規則 (Master 寫入指定的線圈或保持暫存器後更新其他位址，模擬控制命令的回授；
+// 所有符合 Slave 的規則皆生效，位址依 Slave 的位址慣例)
+type WriteHookRule struct {
+	Name    string            `json:"name" mapstructure:"name"`
+	Targets []string          `json:"targets,omitempty" mapstructure:"targets"` // IP/CIDR
+	Tags    []string          `json:"tags,omitempty" mapstructure:"tags"`       // Slave 標籤；targets 與 tags 皆空表示全部 Slave
+	Table   string            `json:"table" mapstructure:"table"`               // 觸發的資料表: coils | holding_registers
+	Address uint16            `json:"address" mapstructure:"address"`
+	Value   *uint16           `json:"value,omitempty" mapstructure:"value"`     // 僅在寫入此值時觸發 (線圈為 0/1，省略表示任何值)
+	Delay   time.Duration     `json:"delay,omitempty" mapstructure:"delay"`     // 寫入後延遲多久執行動作
+	Actions []WriteHookAction `json:"actions" mapstructure:"actions"`
+}
+
+// WriteHookAction 寫入回呼的動作
+type WriteHookAction struct {
+	Table   string  `json:"table" mapstructure:"table"` // coils | discrete_inputs | input_registers | holding_registers
+	Address uint16  `json:"address" mapstructure:"address"`
+	Value   *uint16 `json:"value,omitempty" mapstructure:"value"` // 省略時沿用 Master 寫入的值
+}
+
+// DriftConfig 配置漂移檢查 (比對各 Slave 的暫存器元資料與可寫入值是否偏離設定檔或重新設定的基準)
+type DriftConfig struct {
+	Interval time.Duration `json:"interval" mapstructure:"interval"` // 定期檢查間隔，0 表示僅透過管理 API 檢查
+}
+
+// ClockConfig 模擬時鐘配置 (加速電能累積、日負載曲線與場景持續時間，縮短回歸測試)
+type ClockConfig struct {
+	Speed    float64 `json:"speed" mapstructure:"speed"`       // 模擬時間倍率 (1 = 實際時間，60 = 實際 1 分鐘模擬 1 小時)
+	Register uint16  `json:"register" mapstructure:"register"` // 寫入模擬時間 (Unix 秒，uint32 佔 2 個暫存器) 的保持暫存器 (0 = 不寫入)
+}
+
+// PollingConfig 輪詢分析配置 (找出讀取頻率遠高於數值變化頻率的暫存器，產生各 Master 的輪詢效率報告)
+type PollingConfig struct {
+	Enabled      bool    `json:"enabled" mapstructure:"enabled"`
+	MinPolls     int     `json:"min_polls" mapstructure:"min_polls"`           // 列為熱點所需的最少輪詢次數
+	HotSpotRatio float64 `json:"hot_spot_ratio" mapstructure:"hot_spot_ratio"` // 輪詢次數至少為變化次數的幾倍才列為熱點
+	MaxBlocks    int     `json:"max_blocks" mapstructure:"max_blocks"`         // 追蹤的讀取區塊上限 (Master × Slave × 範圍)
 }
 
 // ScenarioConfig 場景配置
 type ScenarioConfig struct {
 	DefaultScenario string                    `json:"default_scenario" mapstructure:"default_scenario"`
 	UpdateInterval  time.Duration             `json:"update_interval" mapstructure:"update_interval"`
+	Workers         int                       `json:"workers,omitempty" mapstructure:"workers"`       // 集中式更新的 worker 數 (0 = 每個 Slave 各自的 ticker)
+	BatchSize       int                       `json:"batch_size,omitempty" mapstructure:"batch_size"` // 集中式更新每批的 Slave 數 (0 = 100)
 	Scenarios       map[string]ScenarioParams `json:"scenarios" mapstructure:"scenarios"`
 }
 
@@ -75,7 +411,191 @@ type ScenarioParams struct {
 	FrequencyVariance float64     `json:"frequency_variance" mapstructure:"frequency_variance"`
 	JitterMin       time.Duration `json:"jitter_min" mapstructure:"jitter_min"`
 	JitterMax       time.Duration `json:"jitter_max" mapstructure:"jitter_max"`
+	JitterDistribution string     `json:"jitter_distribution,omitempty" mapstructure:"jitter_distribution"` // fixed | uniform (預設) | normal | lognormal | pareto
+	JitterMean      time.Duration `json:"jitter_mean,omitempty" mapstructure:"jitter_mean"` // fixed/normal/lognormal 的平均延遲 (預設為 min-max 中點)
+	JitterStdDev    time.Duration `json:"jitter_stddev,omitempty" mapstructure:"jitter_stddev"` // normal/lognormal 的標準差 (預設為平均值的 1/4)
+	JitterAlpha     float64       `json:"jitter_alpha,omitempty" mapstructure:"jitter_alpha"` // pareto 形狀參數 (預設 1.5，越小尾巴越長)
+	JitterCap       time.Duration `json:"jitter_cap,omitempty" mapstructure:"jitter_cap"` // 延遲上限 (0 = 不限)
+	JitterTimeoutRate float64     `json:"jitter_timeout_rate,omitempty" mapstructure:"jitter_timeout_rate"` // 完全不回應的請求比例 (0-1)
 	PacketLossRate  float64       `json:"packet_loss_rate" mapstructure:"packet_loss_rate"`
+	ImbalancePhase  string        `json:"imbalance_phase,omitempty" mapstructure:"imbalance_phase"`
+	ImbalanceRatio  float64       `json:"imbalance_ratio,omitempty" mapstructure:"imbalance_ratio"`
+	FlapUp          time.Duration `json:"flap_up,omitempty" mapstructure:"flap_up"`
+	FlapDown        time.Duration `json:"flap_down,omitempty" mapstructure:"flap_down"`
+	DrainRate       float64       `json:"drain_rate,omitempty" mapstructure:"drain_rate"`
+	FreezeRegisters []string      `json:"freeze_registers,omitempty" mapstructure:"freeze_registers"`
+	TimestampRegister uint16      `json:"timestamp_register,omitempty" mapstructure:"timestamp_register"`
+	FragmentSize    int           `json:"fragment_size,omitempty" mapstructure:"fragment_size"`
+	FragmentDelay   time.Duration `json:"fragment_delay,omitempty" mapstructure:"fragment_delay"`
+	ExceptionRate   float64       `json:"exception_rate,omitempty" mapstructure:"exception_rate"`
+	ExceptionWeights map[string]float64 `json:"exception_weights,omitempty" mapstructure:"exception_weights"`
+	CorruptRate     float64       `json:"corrupt_rate,omitempty" mapstructure:"corrupt_rate"`
+	CorruptModes    []string      `json:"corrupt_modes,omitempty" mapstructure:"corrupt_modes"`
+	LoadCurve       []LoadPoint   `json:"load_curve,omitempty" mapstructure:"load_curve"`
+	LoadMin         float64       `json:"load_min,omitempty" mapstructure:"load_min"`
+	LoadMax         float64       `json:"load_max,omitempty" mapstructure:"load_max"`
+	LoadPeakHour    float64       `json:"load_peak_hour,omitempty" mapstructure:"load_peak_hour"`
+	TimeScale       float64       `json:"time_scale,omitempty" mapstructure:"time_scale"` // 1 = 實際時間，60 = 1 分鐘模擬 1 小時
+	Targets         []string      `json:"targets,omitempty" mapstructure:"targets"`
+	Script          string        `json:"script,omitempty" mapstructure:"script"` // Lua 腳本路徑 (script 場景)
+	Plugin          string        `json:"plugin,omitempty" mapstructure:"plugin"` // 外掛 gRPC 位址 (plugin 場景)
+	PluginOptions   map[string]string `json:"plugin_options,omitempty" mapstructure:"plugin_options"` // 傳給外掛的選項
+	Capture         string        `json:"capture,omitempty" mapstructure:"capture"` // 擷取檔或時間軸 JSON 路徑 (replay 場景)
+	CapturePort     uint16        `json:"capture_port,omitempty" mapstructure:"capture_port"` // 擷取檔中伺服器的埠號 (預設 502)
+	CaptureUnit     uint8         `json:"capture_unit,omitempty" mapstructure:"capture_unit"` // 僅重播指定 Unit ID (0 = 全部)
+	CaptureLoop     bool          `json:"capture_loop,omitempty" mapstructure:"capture_loop"` // 播完後從頭重播
+	CommandRegister uint16        `json:"command_register,omitempty" mapstructure:"command_register"` // 觸發長時間命令的保持暫存器 (long_command 場景)
+	StatusRegister  uint16        `json:"status_register,omitempty" mapstructure:"status_register"` // 命令狀態暫存器 (0 閒置 / 1 執行中 / 2 完成)
+	CommandDuration time.Duration `json:"command_duration,omitempty" mapstructure:"command_duration"` // 命令執行時間
+	ChurnRate       float64       `json:"churn_rate,omitempty" mapstructure:"churn_rate"` // 每個 Slave 每分鐘強制中斷的連線數 (connection_churn 場景)
+	ChurnModes      []string      `json:"churn_modes,omitempty" mapstructure:"churn_modes"` // 中斷方式: rst、fin (預設隨機)
+	MismatchRate    float64       `json:"mismatch_rate,omitempty" mapstructure:"mismatch_rate"` // 錯亂的回應比例 (transaction_mismatch 場景，預設 0.1)
+	MismatchModes   []string      `json:"mismatch_modes,omitempty" mapstructure:"mismatch_modes"` // wrong、previous、reorder (預設隨機)
+	ReorderWait     time.Duration `json:"reorder_wait,omitempty" mapstructure:"reorder_wait"` // reorder 延後的回應最多等待下一個請求的時間 (預設 200ms)
+	HarmonicTHD     float64       `json:"harmonic_thd,omitempty" mapstructure:"harmonic_thd"` // 諧波事件期間的電壓 THD (%，harmonic_distortion 場景，預設 8)
+	HarmonicOrder   int           `json:"harmonic_order,omitempty" mapstructure:"harmonic_order"` // 諧波事件期間增大的諧波次數: 3、5、7 (預設 5)
+	HarmonicInterval time.Duration `json:"harmonic_interval,omitempty" mapstructure:"harmonic_interval"` // 兩次諧波事件開始的間隔 (預設 1m，事件持續 duration，預設 20s)
+	FlickerPst      float64       `json:"flicker_pst,omitempty" mapstructure:"flicker_pst"` // 諧波事件期間的短時閃爍 Pst (預設 1.2)
+	Components      []string      `json:"components,omitempty" mapstructure:"components"` // 同時運行的場景，依序套用 (composite 場景)
+	ChaosScenarios  []string      `json:"chaos_scenarios,omitempty" mapstructure:"chaos_scenarios"` // 隨機挑選的擾動場景 (chaos 場景，預設 voltage_sag、connection_flap、exception_storm、jitter)
+	ChaosIntervalMin time.Duration `json:"chaos_interval_min,omitempty" mapstructure:"chaos_interval_min"` // 兩次擾動的間隔下限 (預設 30s)
+	ChaosIntervalMax time.Duration `json:"chaos_interval_max,omitempty" mapstructure:"chaos_interval_max"` // 兩次擾動的間隔上限 (預設 2m)
+	ChaosDurationMin time.Duration `json:"chaos_duration_min,omitempty" mapstructure:"chaos_duration_min"` // 每次擾動的持續時間下限 (預設 15s)
+	ChaosDurationMax time.Duration `json:"chaos_duration_max,omitempty" mapstructure:"chaos_duration_max"` // 每次擾動的持續時間上限 (預設 1m)
+	ChaosFractionMin float64      `json:"chaos_fraction_min,omitempty" mapstructure:"chaos_fraction_min"` // 每次擾動的 Slave 比例下限 (0-1，預設 0.05，至少 1 個)
+	ChaosFractionMax float64      `json:"chaos_fraction_max,omitempty" mapstructure:"chaos_fraction_max"` // 每次擾動的 Slave 比例上限 (0-1，預設 0.2)
+	ChaosMaxActive  int           `json:"chaos_max_active,omitempty" mapstructure:"chaos_max_active"` // 同時進行的擾動上限 (預設 2)
+}
+
+// Validate 驗證場景參數 (profile 為 Slave 的設備設定檔，用於檢查凍結暫存器)
+func (p ScenarioParams) Validate(name string, profile *DeviceProfile) error {
+	if name == ScenarioComposite.String() && len(p.Components) == 0 {
+		return fmt.Errorf(T("場景 %s 必須指定 components"), name)
+	}
+	seen := make(map[string]bool, len(p.Components))
+	for _, component := range p.Components {
+		scenario := ParseScenarioType(component)
+		if scenario.String() != component || scenario == ScenarioNormal || scenario == ScenarioComposite || scenario == ScenarioChaos {
+			return fmt.Errorf(T("場景 %s 的 components 無效: %s (需為 normal、composite、chaos 以外的場景)"), name, component)
+		}
+		if seen[component] {
+			return fmt.Errorf(T("場景 %s 的 components 重複: %s"), name, component)
+		}
+		seen[component] = true
+	}
+	for _, disturbance := range p.ChaosScenarios {
+		scenario := ParseScenarioType(disturbance)
+		if scenario.String() != disturbance || scenario == ScenarioNormal || scenario == ScenarioChaos {
+			return fmt.Errorf(T("場景 %s 的 chaos_scenarios 無效: %s (需為 normal、chaos 以外的場景)"), name, disturbance)
+		}
+	}
+	if p.ChaosIntervalMin < 0 || p.ChaosIntervalMax < 0 || p.ChaosDurationMin < 0 || p.ChaosDurationMax < 0 || p.ChaosMaxActive < 0 {
+		return fmt.Errorf(T("場景 %s 的混沌模式間隔、持續時間與擾動上限不可為負"), name)
+	}
+	if (p.ChaosIntervalMax > 0 && p.ChaosIntervalMin > p.ChaosIntervalMax) || (p.ChaosDurationMax > 0 && p.ChaosDurationMin > p.ChaosDurationMax) {
+		return fmt.Errorf(T("場景 %s 的混沌模式下限不可大於上限"), name)
+	}
+	if p.ChaosFractionMin < 0 || p.ChaosFractionMin > 1 || p.ChaosFractionMax < 0 || p.ChaosFractionMax > 1 {
+		return fmt.Errorf(T("場景 %s 的 chaos_fraction_min/max 必須介於 0-1"), name)
+	}
+	if p.ChaosFractionMax > 0 && p.ChaosFractionMin > p.ChaosFractionMax {
+		return fmt.Errorf(T("場景 %s 的混沌模式下限不可大於上限"), name)
+	}
+	if p.PacketLossRate < 0 || p.PacketLossRate > 1 {
+		return fmt.Errorf(T("場景 %s 的 packet_loss_rate 必須介於 0-1: %f"), name, p.PacketLossRate)
+	}
+	if p.ExceptionRate < 0 || p.ExceptionRate > 1 {
+		return fmt.Errorf(T("場景 %s 的 exception_rate 必須介於 0-1: %f"), name, p.ExceptionRate)
+	}
+	for exception, weight := range p.ExceptionWeights {
+		if _, ok := ExceptionCodeNames[exception]; !ok {
+			return fmt.Errorf(T("場景 %s 的例外名稱無效: %s"), name, exception)
+		}
+		if weight < 0 {
+			return fmt.Errorf(T("場景 %s 的例外權重不可為負: %s"), name, exception)
+		}
+	}
+	if p.CorruptRate < 0 || p.CorruptRate > 1 {
+		return fmt.Errorf(T("場景 %s 的 corrupt_rate 必須介於 0-1: %f"), name, p.CorruptRate)
+	}
+	for _, mode := range p.CorruptModes {
+		if !isCorruptMode(mode) {
+			return fmt.Errorf(T("場景 %s 的損壞模式無效: %s"), name, mode)
+		}
+	}
+	if p.JitterDistribution != "" && !isDelayDistribution(p.JitterDistribution) {
+		return fmt.Errorf(T("場景 %s 的延遲分佈無效: %s (可用: fixed, uniform, normal, lognormal, pareto)"), name, p.JitterDistribution)
+	}
+	if p.JitterMean < 0 || p.JitterStdDev < 0 || p.JitterAlpha < 0 || p.JitterCap < 0 {
+		return fmt.Errorf(T("場景 %s 的延遲分佈參數不可為負"), name)
+	}
+	if p.JitterTimeoutRate < 0 || p.JitterTimeoutRate > 1 {
+		return fmt.Errorf(T("場景 %s 的 jitter_timeout_rate 必須介於 0-1: %f"), name, p.JitterTimeoutRate)
+	}
+	if p.MismatchRate < 0 || p.MismatchRate > 1 {
+		return fmt.Errorf(T("場景 %s 的 mismatch_rate 必須介於 0-1: %f"), name, p.MismatchRate)
+	}
+	for _, mode := range p.MismatchModes {
+		if !isMismatchMode(mode) {
+			return fmt.Errorf(T("場景 %s 的交易錯亂模式無效: %s (可用: wrong, previous, reorder)"), name, mode)
+		}
+	}
+	if p.HarmonicTHD < 0 || p.FlickerPst < 0 || p.HarmonicInterval < 0 {
+		return fmt.Errorf(T("場景 %s 的 harmonic_thd、harmonic_interval 與 flicker_pst 不可為負"), name)
+	}
+	if p.HarmonicOrder != 0 && !isHarmonicOrder(p.HarmonicOrder) {
+		return fmt.Errorf(T("場景 %s 的諧波次數無效: %d (可用: 3, 5, 7)"), name, p.HarmonicOrder)
+	}
+	if p.ChurnRate < 0 {
+		return fmt.Errorf(T("場景 %s 的 churn_rate 不可為負: %v"), name, p.ChurnRate)
+	}
+	for _, mode := range p.ChurnModes {
+		if !isChurnMode(mode) {
+			return fmt.Errorf(T("場景 %s 的連線中斷方式無效: %s (可用: rst, fin)"), name, mode)
+		}
+	}
+	for _, point := range p.LoadCurve {
+		if point.Hour < 0 || point.Hour >= 24 || point.Factor < 0 {
+			return fmt.Errorf(T("場景 %s 的負載曲線點無效: hour=%v factor=%v"), name, point.Hour, point.Factor)
+		}
+	}
+	if p.TimeScale < 0 {
+		return fmt.Errorf(T("場景 %s 的 time_scale 不可為負: %v"), name, p.TimeScale)
+	}
+	if p.Script != "" {
+		if err := ValidateScript(p.Script); err != nil {
+			return fmt.Errorf(T("場景 %s 的腳本無效: %w"), name, err)
+		}
+	}
+	if p.Plugin != "" {
+		if err := ValidatePluginAddress(p.Plugin); err != nil {
+			return fmt.Errorf(T("場景 %s 的外掛位址無效: %w"), name, err)
+		}
+	}
+	if p.Capture != "" {
+		if err := ValidateCapture(p.Capture, p.CapturePort); err != nil {
+			return fmt.Errorf(T("場景 %s 的擷取檔無效: %w"), name, err)
+		}
+	}
+	for _, reg := range p.FreezeRegisters {
+		if !profile.HasRegister(reg) {
+			return fmt.Errorf(T("場景 %s 的凍結暫存器不存在於設定檔 %s: %s"), name, profile.Name, reg)
+		}
+	}
+	for _, target := range p.Targets {
+		if net.ParseIP(target) == nil {
+			if _, _, err := net.ParseCIDR(target); err != nil {
+				return fmt.Errorf(T("場景 %s 的目標無效: %s"), name, target)
+			}
+		}
+	}
+	return nil
+}
+
+// LoadPoint 負載曲線點 (hour: 0-24，factor: 相對額定電流的倍率)
+type LoadPoint struct {
+	Hour   float64 `json:"hour" mapstructure:"hour"`
+	Factor float64 `json:"factor" mapstructure:"factor"`
 }
 
 // LoggingConfig 日誌配置
@@ -90,8 +610,132 @@ type MetricsConfig struct {
 	Enabled  bool   `json:"enabled" mapstructure:"enabled"`
 	Endpoint string `json:"endpoint" mapstructure:"endpoint"`
 	Port     int    `json:"port" mapstructure:"port"`
+	UI       bool   `json:"ui" mapstructure:"ui"` // 於 /ui/ 提供網頁儀表板
+
+	// 健康 Slave 占應運行數的比例低於此百分比時 /ready 回應 503 (0 表示只要引擎運行中即就緒)
+	MinHealthyPercent float64 `json:"min_healthy_percent,omitempty" mapstructure:"min_healthy_percent"`
+
+	RegisterValues RegisterMetricsConfig `json:"register_values" mapstructure:"register_values"`
+	PerSlave       SlaveMetricsConfig    `json:"per_slave" mapstructure:"per_slave"`
+}
+
+// RegisterMetricsConfig 暫存器值指標配置 (每個 Slave × 暫存器一條時序，預設關閉)
+type RegisterMetricsConfig struct {
+	Enabled   bool     `json:"enabled" mapstructure:"enabled"`
+	Registers []string `json:"registers" mapstructure:"registers"`   // 僅輸出指定名稱，空值表示全部已定義暫存器
+	MaxSlaves int      `json:"max_slaves" mapstructure:"max_slaves"` // 輸出的 Slave 數上限 (依 ID 排序)
+}
+
+// SlaveMetricsConfig 每個 Slave 的指標配置 (依 slave_ip、unit_id、scenario 標籤輸出)
+type SlaveMetricsConfig struct {
+	Enabled   bool `json:"enabled" mapstructure:"enabled"`
+	MaxSlaves int  `json:"max_slaves" mapstructure:"max_slaves"` // 輸出的 Slave 數上限 (依 ID 排序，0 表示不限制)
+}
+
+// TracingConfig OpenTelemetry 追蹤配置 (以 OTLP/HTTP JSON 匯出 span，並作為延遲直方圖的 exemplar)
+type TracingConfig struct {
+	Enabled       bool          `json:"enabled" mapstructure:"enabled"`
+	Endpoint      string        `json:"endpoint" mapstructure:"endpoint"` // 空值時依 OTEL_EXPORTER_OTLP_* 環境變數
+	ServiceName   string        `json:"service_name" mapstructure:"service_name"`
+	SampleRate    float64       `json:"sample_rate" mapstructure:"sample_rate"` // 0-1
+	FlushInterval time.Duration `json:"flush_interval" mapstructure:"flush_interval"`
+}
+
+// AuditConfig 請求稽核日誌 (每筆 Modbus 請求寫入一行 JSON，供事後分析 EMS 的輪詢與寫入)
+type AuditConfig struct {
+	Enabled    bool   `json:"enabled" mapstructure:"enabled"`
+	Path       string `json:"path" mapstructure:"path"`
+	MaxSizeMB  int    `json:"max_size_mb" mapstructure:"max_size_mb"` // 超過時輪替 (0 表示不輪替)
+	MaxBackups int    `json:"max_backups" mapstructure:"max_backups"` // 保留的輪替檔數 (0 表示不保留)
+}
+
+// PersistenceConfig 狀態持久化 (定期 checkpoint 暫存器狀態，異常終止後重新啟動時自動還原)
+type PersistenceConfig struct {
+	Enabled  bool          `json:"enabled" mapstructure:"enabled"`
+	Driver   string        `json:"driver" mapstructure:"driver"`     // 儲存方式 (file | log)
+	Path     string        `json:"path" mapstructure:"path"`         // 儲存位置 (file 為目錄，log 為檔案)
+	Interval time.Duration `json:"interval" mapstructure:"interval"` // checkpoint 間隔
 }
 
+// MQTTConfig MQTT 發布 (以 Sparkplug B 鏡像各 Slave 的已定義暫存器，供 MQTT 端的系統使用相同的模擬資料)
+type MQTTConfig struct {
+	Enabled    bool          `json:"enabled" mapstructure:"enabled"`
+	Broker     string        `json:"broker" mapstructure:"broker"`             // tcp://host:1883 | tls://host:8883
+	ClientID   string        `json:"client_id" mapstructure:"client_id"`       // 空值為 modbussim-<edge_node_id>
+	Username   string        `json:"username" mapstructure:"username"`
+	Password   string        `json:"password" mapstructure:"password"`
+	GroupID    string        `json:"group_id" mapstructure:"group_id"`         // Sparkplug 群組 ID
+	EdgeNodeID string        `json:"edge_node_id" mapstructure:"edge_node_id"` // 空值為主機名稱
+	Interval   time.Duration `json:"interval" mapstructure:"interval"`         // 檢查並發布變化 (DDATA) 的間隔
+	KeepAlive  time.Duration `json:"keep_alive" mapstructure:"keep_alive"`
+	Registers  []string      `json:"registers" mapstructure:"registers"` // 僅發布指定名稱，空值表示全部已定義暫存器
+	Targets    []string      `json:"targets" mapstructure:"targets"`     // 發布的 Slave (IP 或 CIDR)，與 tags 皆空表示全部
+	Tags       []string      `json:"tags" mapstructure:"tags"`
+}
+
+// OPCUAConfig OPC UA 鏡像伺服器 (以 OPC UA 提供各 Slave 的已定義暫存器，每個 Slave 一個資料夾，供 Modbus↔OPC UA 閘道測試)
+type OPCUAConfig struct {
+	Enabled  bool     `json:"enabled" mapstructure:"enabled"`
+	Listen   string   `json:"listen" mapstructure:"listen"`     // 監聽位址 (opc.tcp)
+	Writable bool     `json:"writable" mapstructure:"writable"` // 允許 OPC UA client 寫入 writable 的暫存器
+	Targets  []string `json:"targets" mapstructure:"targets"`   // 提供的 Slave (IP 或 CIDR)，與 tags 皆空表示全部
+	Tags     []string `json:"tags" mapstructure:"tags"`
+}
+
+// IEC104Config IEC 60870-5-104 從站模式 (每個 Slave 在自己的 IP 上另外提供 IEC 104 伺服器，
+// 已定義的暫存器以短浮點數測量值提供，資訊物件位址 (IOA) 為暫存器位址、公共位址 (CA) 為 Unit ID)
+type IEC104Config struct {
+	Enabled  bool     `json:"enabled" mapstructure:"enabled"`
+	Port     int      `json:"port" mapstructure:"port"`         // 監聽埠 (預設 2404)
+	Profiles []string `json:"profiles" mapstructure:"profiles"` // 提供 IEC 104 的設備設定檔 (依主設備的設定檔)，空值表示全部
+}
+
+// BACnetConfig BACnet/IP 設備模式 (每個 Slave 在自己的 IP 上另外以 UDP 提供一個 BACnet 設備，
+// 選定的暫存器以 Analog Input (唯讀) 或 Analog Value (可寫) 物件提供，物件 instance 為暫存器位址)
+type BACnetConfig struct {
+	Enabled      bool     `json:"enabled" mapstructure:"enabled"`
+	Port         int      `json:"port" mapstructure:"port"`                   // UDP 監聽埠 (預設 47808)
+	InstanceBase int      `json:"instance_base" mapstructure:"instance_base"` // 設備 instance 為 instance_base 加上 IP 位址的末 16 位元
+	Broadcast    []string `json:"broadcast" mapstructure:"broadcast"`         // 接收 Who-Is 廣播的位址，空值表示僅回應直接送達的 Who-Is
+	Registers    []string `json:"registers" mapstructure:"registers"`         // 僅提供指定名稱，空值表示全部數值暫存器
+	Targets      []string `json:"targets" mapstructure:"targets"`             // 提供的 Slave (IP 或 CIDR)，與 tags 皆空表示全部
+	Tags         []string `json:"tags" mapstructure:"tags"`
+}
+
+// PrivilegeConfig 權限降級 (以 root 綁定 502 埠、配置虛擬 IP 後切換為一般使用者，僅 Linux)
+type PrivilegeConfig struct {
+	User         string   `json:"user" mapstructure:"user"`                 // 降級的目標使用者 (名稱或 UID)，空值表示不降級
+	Group        string   `json:"group" mapstructure:"group"`               // 目標群組，空值時使用該使用者的主要群組
+	Capabilities []string `json:"capabilities" mapstructure:"capabilities"` // 降級後保留的 capability
+}
+
+// DiagnosticsConfig 自我監控設備 (以 Modbus 提供模擬器自身的健康狀態)
+type DiagnosticsConfig struct {
+	Enabled        bool          `json:"enabled" mapstructure:"enabled"`
+	IP             string        `json:"ip" mapstructure:"ip"`                             // 監聽 IP (啟用時必填)
+	Port           int           `json:"port" mapstructure:"port"`                         // 0 表示與 server.port 相同
+	UnitID         uint8         `json:"unit_id" mapstructure:"unit_id"`
+	Interval       time.Duration `json:"interval" mapstructure:"interval"`                 // 更新週期
+	TickLagAlarm   time.Duration `json:"tick_lag_alarm" mapstructure:"tick_lag_alarm"`     // 更新延遲超過此值時告警 (0 表示不告警)
+	FDUsageAlarm   float64       `json:"fd_usage_alarm" mapstructure:"fd_usage_alarm"`     // 檔案描述子用量比例超過此值時告警 (0 表示不告警)
+	ErrorRateAlarm float64       `json:"error_rate_alarm" mapstructure:"error_rate_alarm"` // 週期內錯誤率超過此值時告警 (0 表示不告警)
+}
+
+// ClusterConfig 叢集模式 (coordinator 將 network.ip_ranges 分段分配給多台主機上的 agent，下發場景並彙整指標)
+type ClusterConfig struct {
+	Listen            string        `json:"listen,omitempty" mapstructure:"listen"`                         // coordinator 的 gRPC 監聽位址 (預設 :9700)
+	Coordinator       string        `json:"coordinator,omitempty" mapstructure:"coordinator"`               // agent 連線的 coordinator 位址
+	AgentID           string        `json:"agent_id,omitempty" mapstructure:"agent_id"`                     // agent 識別 (預設為主機名稱)
+	SlavesPerAgent    int           `json:"slaves_per_agent,omitempty" mapstructure:"slaves_per_agent"`     // 每個 agent 分配的 Slave 數 (0 = 依 agent 的 slaves.count)
+	HeartbeatInterval time.Duration `json:"heartbeat_interval,omitempty" mapstructure:"heartbeat_interval"` // agent 回報指標的間隔 (0 = 5s)
+}
+
+// 叢集模式的預設值
+const (
+	DefaultClusterListen    = ":9700"
+	DefaultClusterHeartbeat = 5 * time.Second
+)
+
 // DefaultConfig 返回預設配置
 func DefaultConfig() *Config {
 	return &Config{
@@ -99,23 +743,29 @@ func DefaultConfig() *Config {
 			Port:            ModbusTCPDefaultPort,
 			ReadTimeout:     30 * time.Second,
 			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     DefaultIdleTimeout,
 			MaxConnections:  10000,
 			GracefulTimeout: 10 * time.Second,
+			MaxADUSize:      ModbusTCPMaxADULength,
+			Listener:        ListenerPerSlave,
 		},
 		Network: NetworkConfig{
 			Interface: "eth0",
 			IPRanges:  []IPRange{},
+			Announce:  true,
+			StateFile: DefaultNetworkStateFile,
 		},
 		Slaves: SlavesConfig{
 			Count:       100,
 			UnitIDStart: 1,
+			Profile:     ProfileSinglePhase,
 			DefaultRegisters: []RegisterDefinition{
 				{Address: 40001, Name: "LineVoltage", DataType: "uint16", Scale: 10, DefaultValue: 220.0, Unit: "V", Writable: false},
 				{Address: 40002, Name: "LineCurrent", DataType: "uint16", Scale: 100, DefaultValue: 15.50, Unit: "A", Writable: false},
 				{Address: 40003, Name: "Frequency", DataType: "uint16", Scale: 100, DefaultValue: 60.00, Unit: "Hz", Writable: false},
 				{Address: 40004, Name: "TotalEnergy", DataType: "uint32", Scale: 1, DefaultValue: 0, Unit: "kWh", Writable: false},
 				{Address: 40006, Name: "PowerFactor", DataType: "uint16", Scale: 1000, DefaultValue: 0.95, Unit: "", Writable: false},
-				{Address: 40007, Name: "ActivePower", DataType: "uint32", Scale: 10, DefaultValue: 3300, Unit: "W", Writable: false},
+				{Address: 40007, Name: "ActivePower", DataType: "uint32", Scale: 10, DefaultValue: 3300, Unit: "W", Writable: false, Expression: "40001 * 40002 * 40006"},
 			},
 		},
 		Scenario: ScenarioConfig{
@@ -141,6 +791,99 @@ func DefaultConfig() *Config {
 					Enabled:        true,
 					PacketLossRate: 0.05, // 5% 封包丟失
 				},
+				"phase_imbalance": {
+					Enabled:        true,
+					ImbalancePhase: "a",
+					ImbalanceRatio: 0.10, // 單相偏移 10%
+				},
+				"connection_flap": {
+					Enabled:  true,
+					FlapUp:   15 * time.Second,
+					FlapDown: 5 * time.Second,
+				},
+				"slow_drain": {
+					Enabled:   true,
+					DrainRate: 10, // 10 bytes/sec
+				},
+				"data_freeze": {
+					Enabled:         true,
+					FreezeRegisters: []string{"LineVoltage", "LineCurrent", "ActivePower"},
+				},
+				"fragmented_response": {
+					Enabled:       true,
+					FragmentSize:  3,
+					FragmentDelay: 50 * time.Millisecond,
+				},
+				"corrupted_response": {
+					Enabled:      true,
+					CorruptRate:  0.1, // 10% 回應損壞
+					CorruptModes: []string{"byte_count", "transaction_id", "truncate", "garbage"},
+				},
+				"load_profile": {
+					Enabled:      true,
+					LoadMin:      0.3, // 夜間 30% 負載
+					LoadMax:      1.0,
+					LoadPeakHour: 14,
+					TimeScale:    1,
+				},
+				"script": {
+					Enabled: false, // 設定 script 路徑後啟用
+				},
+				"plugin": {
+					Enabled: false, // 設定 plugin 位址後啟用
+				},
+				"replay": {
+					Enabled: false, // 設定 capture 擷取檔後啟用
+				},
+				"long_command": {
+					Enabled:         true,
+					CommandRegister: DefaultCommandRegister,
+					StatusRegister:  DefaultStatusRegister,
+					CommandDuration: DefaultCommandDuration,
+				},
+				"transaction_mismatch": {
+					Enabled:       true,
+					MismatchRate:  DefaultMismatchRate,
+					MismatchModes: []string{MismatchModeWrong, MismatchModePrevious, MismatchModeReorder},
+					ReorderWait:   DefaultReorderWait,
+				},
+				"harmonic_distortion": {
+					Enabled:          true,
+					Duration:         DefaultHarmonicDuration,
+					HarmonicTHD:      DefaultHarmonicTHD,
+					HarmonicOrder:    DefaultHarmonicOrder,
+					HarmonicInterval: DefaultHarmonicInterval,
+					FlickerPst:       DefaultFlickerPst,
+				},
+				"connection_churn": {
+					Enabled:    true,
+					ChurnRate:  DefaultChurnRate,
+					ChurnModes: []string{ChurnModeRST, ChurnModeFIN},
+				},
+				"exception_storm": {
+					Enabled:       true,
+					ExceptionRate: 0.2, // 20% 請求回應例外
+					ExceptionWeights: map[string]float64{
+						"illegal_data_address": 0.25,
+						"slave_device_failure": 0.25,
+						"slave_device_busy":    0.5,
+					},
+				},
+				"composite": {
+					Enabled:    true,
+					Components: []string{"voltage_sag", "jitter", "packet_loss"}, // 同時運行，依序套用
+				},
+				"chaos": {
+					Enabled:          true,
+					ChaosScenarios:   append([]string(nil), DefaultChaosScenarios...),
+					ChaosIntervalMin: DefaultChaosIntervalMin,
+					ChaosIntervalMax: DefaultChaosIntervalMax,
+					ChaosDurationMin: DefaultChaosDurationMin,
+					ChaosDurationMax: DefaultChaosDurationMax,
+					ChaosFractionMin: DefaultChaosFractionMin,
+					ChaosFractionMax: DefaultChaosFractionMax,
+					ChaosMaxActive:   DefaultChaosMaxActive,
+				},
 			},
 		},
 		Logging: LoggingConfig{
@@ -152,7 +895,105 @@ func DefaultConfig() *Config {
 			Enabled:  true,
 			Endpoint: "/metrics",
 			Port:     9090,
+			UI:       true,
+			RegisterValues: RegisterMetricsConfig{
+				Enabled:   false,
+				Registers: []string{},
+				MaxSlaves: 100,
+			},
+			PerSlave: SlaveMetricsConfig{
+				Enabled:   true,
+				MaxSlaves: 1000,
+			},
+		},
+		Tracing: TracingConfig{
+			Enabled:       false,
+			ServiceName:   DefaultTracingServiceName,
+			SampleRate:    DefaultTracingSampleRate,
+			FlushInterval: DefaultTracingFlushInterval,
+		},
+		Audit: AuditConfig{
+			Enabled:    false,
+			Path:       DefaultAuditPath,
+			MaxSizeMB:  DefaultAuditMaxSizeMB,
+			MaxBackups: DefaultAuditMaxBackups,
+		},
+		Persistence: PersistenceConfig{
+			Enabled:  false,
+			Driver:   PersistenceDriverFile,
+			Path:     DefaultPersistencePath,
+			Interval: DefaultPersistenceInterval,
+		},
+		MQTT: MQTTConfig{
+			Enabled:   false,
+			GroupID:   DefaultMQTTGroupID,
+			Interval:  DefaultMQTTInterval,
+			KeepAlive: DefaultMQTTKeepAlive,
+			Registers: []string{},
+			Targets:   []string{},
+			Tags:      []string{},
+		},
+		OPCUA: OPCUAConfig{
+			Enabled: false,
+			Listen:  DefaultOPCUAListen,
+			Targets: []string{},
+			Tags:    []string{},
+		},
+		IEC104: IEC104Config{
+			Enabled:  false,
+			Port:     DefaultIEC104Port,
+			Profiles: []string{},
+		},
+		BACnet: BACnetConfig{
+			Enabled:   false,
+			Port:      DefaultBACnetPort,
+			Broadcast: []string{DefaultBACnetBroadcast},
+			Registers: []string{},
+			Targets:   []string{},
+			Tags:      []string{},
 		},
+		Redundancy: RedundancyConfig{
+			StandbyMode: StandbyModeRefuse,
+			Pairs:       []RedundantPair{},
+		},
+		Protection: ProtectionConfig{
+			Windows: []ProtectedWindow{},
+			Tags:    []string{},
+		},
+		FailureDomains: []FailureDomain{},
+		BootStorm: BootStormConfig{
+			Outage:  DefaultBootStormOutage,
+			Stagger: DefaultBootStormStagger,
+		},
+		Decommission: DecommissionConfig{
+			Rules: []DecommissionRule{},
+		},
+		WriteHooks:        []WriteHookRule{},
+		ACL:               []ACLRule{},
+		FunctionOverrides: []FunctionOverrideRule{},
+		Drift: DriftConfig{
+			Interval: DefaultDriftInterval,
+		},
+		Clock: ClockConfig{
+			Speed: 1,
+		},
+		Polling: PollingConfig{
+			Enabled:      false,
+			MinPolls:     DefaultPollMinPolls,
+			HotSpotRatio: DefaultPollHotSpotRatio,
+			MaxBlocks:    DefaultPollMaxBlocks,
+		},
+		Privilege: PrivilegeConfig{
+			Capabilities: []string{DefaultPrivilegeCapability},
+		},
+		Diagnostics: DiagnosticsConfig{
+			UnitID:         DefaultDiagnosticsUnitID,
+			Interval:       DefaultDiagnosticsInterval,
+			TickLagAlarm:   DefaultDiagnosticsTickLagAlarm,
+			FDUsageAlarm:   DefaultDiagnosticsFDUsageAlarm,
+			ErrorRateAlarm: DefaultDiagnosticsErrorRateAlarm,
+		},
+		Language: LangAuto,
 	}
 }
 
@@ -176,17 +1017,26 @@ func LoadConfig(configPath string) (*Config, error) {
 
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return nil, fmt.Errorf("讀取配置檔失敗: %w", err)
+			return nil, fmt.Errorf(T("讀取配置檔失敗: %w"), err)
 		}
 		// 配置檔不存在，使用預設值
 	}
 
 	if err := viper.Unmarshal(cfg); err != nil {
-		return nil, fmt.Errorf("解析配置失敗: %w", err)
+		return nil, fmt.Errorf(T("解析配置失敗: %w"), err)
+	}
+
+	// 展開外部暫存器對應檔 (未設定 default_registers 時不以內建預設覆寫)
+	configDir := "."
+	if used := viper.ConfigFileUsed(); used != "" {
+		configDir = filepath.Dir(used)
+	}
+	if err := cfg.Slaves.resolveRegistersFile(configDir, viper.IsSet("slaves.default_registers")); err != nil {
+		return nil, err
 	}
 
 	if err := cfg.Validate(); err != nil {
-		return nil, fmt.Errorf("配置驗證失敗: %w", err)
+		return nil, fmt.Errorf(T("配置驗證失敗: %w"), err)
 	}
 
 	return cfg, nil
@@ -195,50 +1045,726 @@ func LoadConfig(configPath string) (*Config, error) {
 // Validate 驗證配置
 func (c *Config) Validate() error {
 	if c.Server.Port < 1 || c.Server.Port > 65535 {
-		return fmt.Errorf("無效的埠號: %d", c.Server.Port)
+		return fmt.Errorf(T("無效的埠號: %d"), c.Server.Port)
+	}
+
+	ports := map[int]bool{c.Server.Port: true}
+	for _, port := range c.Server.ExtraPorts {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf(T("無效的埠號: %d"), port)
+		}
+		if ports[port] {
+			return fmt.Errorf(T("extra_ports 的埠號重複: %d"), port)
+		}
+		ports[port] = true
+	}
+
+	switch c.Server.SingleMaster {
+	case "", SingleMasterRefuse, SingleMasterQueue:
+	default:
+		return fmt.Errorf(T("不支援的單一 Master 模式: %s (可用: refuse, queue)"), c.Server.SingleMaster)
+	}
+
+	if c.Server.MaxADUSize != 0 && (c.Server.MaxADUSize < ModbusTCPMinADULength || c.Server.MaxADUSize > ModbusTCPMaxADULength) {
+		return fmt.Errorf(T("無效的 ADU 上限: %d (範圍 %d-%d)"), c.Server.MaxADUSize, ModbusTCPMinADULength, ModbusTCPMaxADULength)
+	}
+
+	if c.Server.ReadTimeout < 0 || c.Server.WriteTimeout < 0 || c.Server.IdleTimeout < 0 {
+		return fmt.Errorf(T("連線逾時不可為負: read_timeout=%s write_timeout=%s idle_timeout=%s"),
+			c.Server.ReadTimeout, c.Server.WriteTimeout, c.Server.IdleTimeout)
+	}
+
+	if c.Server.MaxConnections < 0 {
+		return fmt.Errorf(T("連線數上限不可為負: %d"), c.Server.MaxConnections)
+	}
+
+	if c.Server.MaxInFlight < 0 {
+		return fmt.Errorf(T("同時處理的請求數上限不可為負: %d"), c.Server.MaxInFlight)
+	}
+
+	switch c.Server.Listener {
+	case "", ListenerPerSlave:
+		if c.Server.OriginalDst {
+			return errors.New(T("original_dst 僅適用於 shared listener"))
+		}
+	case ListenerShared:
+	default:
+		return fmt.Errorf(T("不支援的 listener 模式: %s (可用: per_slave, shared)"), c.Server.Listener)
+	}
+
+	if c.Server.BindRetryInterval < 0 || c.Server.BindRetryMax < 0 {
+		return fmt.Errorf(T("綁定重試設定不可為負: interval=%v max=%d"), c.Server.BindRetryInterval, c.Server.BindRetryMax)
 	}
 
 	if c.Slaves.Count < 1 {
-		return fmt.Errorf("Slave 數量必須大於 0")
+		return errors.New(T("Slave 數量必須大於 0"))
 	}
 
 	if c.Slaves.Count > 10000 {
-		return fmt.Errorf("Slave 數量超過上限 (最大 10000)")
+		return errors.New(T("Slave 數量超過上限 (最大 10000)"))
+	}
+
+	if _, err := ParseLanguage(c.Language); err != nil {
+		return err
+	}
+
+	if c.Slaves.RefreshInterval < 0 {
+		return fmt.Errorf(T("量測值更新週期不可為負: %v"), c.Slaves.RefreshInterval)
+	}
+
+	if r := c.Slaves.Randomize; r.VoltageSpread < 0 || r.CurrentMean < 0 || r.CurrentStdDev < 0 || r.EnergyMax < 0 {
+		return errors.New(T("randomize 的參數不可為負"))
+	}
+
+	if err := c.Slaves.DeviceClock.Validate(); err != nil {
+		return err
+	}
+	if err := c.Slaves.Demand.Validate(); err != nil {
+		return err
+	}
+	if err := c.Slaves.Tariff.Validate(); err != nil {
+		return err
+	}
+	if err := c.validateComputedRegisters(); err != nil {
+		return err
+	}
+
+	switch c.Slaves.RegisterSharing {
+	case "", RegisterSharingShared, RegisterSharingIndependent:
+	default:
+		return fmt.Errorf(T("不支援的暫存器配置方式: %s (可用: shared, independent)"), c.Slaves.RegisterSharing)
+	}
+
+	if c.Slaves.Profile != "" {
+		if _, ok := GetDeviceProfile(c.Slaves.Profile); !ok {
+			return fmt.Errorf(T("未知的設備設定檔: %s"), c.Slaves.Profile)
+		}
+	}
+
+	seenUnits := make(map[uint8]bool)
+	for _, unit := range c.Slaves.Units {
+		if unit.UnitID < 1 || unit.UnitID > 247 {
+			return fmt.Errorf(T("邏輯設備的 Unit ID 必須介於 1-247: %d"), unit.UnitID)
+		}
+		if seenUnits[unit.UnitID] {
+			return fmt.Errorf(T("邏輯設備的 Unit ID 重複: %d"), unit.UnitID)
+		}
+		seenUnits[unit.UnitID] = true
+		if unit.Profile != "" {
+			profile, ok := GetDeviceProfile(unit.Profile)
+			if !ok {
+				return fmt.Errorf(T("未知的設備設定檔: %s"), unit.Profile)
+			}
+			if err := profile.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+
+	for name, functions := range c.Slaves.ProfileFunctions {
+		if _, ok := GetDeviceProfile(name); !ok {
+			return fmt.Errorf(T("未知的設備設定檔: %s"), name)
+		}
+		if len(functions) == 0 {
+			return fmt.Errorf(T("設備設定檔 %s 支援的功能碼不可為空"), name)
+		}
+		for _, function := range functions {
+			if function == 0 || function >= 0x80 {
+				return fmt.Errorf(T("設備設定檔 %s 的功能碼無效: %d"), name, function)
+			}
+		}
+	}
+
+	switch c.Slaves.UnknownUnit {
+	case "", UnknownUnitSilent, UnknownUnitGatewayException:
+	default:
+		return fmt.Errorf(T("不支援的未配置 Unit ID 行為: %s (可用: silent, gateway_exception)"), c.Slaves.UnknownUnit)
+	}
+	if c.Slaves.DownstreamTimeout < 0 {
+		return fmt.Errorf(T("下游逾時不可為負: %v"), c.Slaves.DownstreamTimeout)
+	}
+
+	if c.Slaves.RequestRateLimit < 0 || c.Slaves.RequestBurst < 0 {
+		return fmt.Errorf(T("請求速率限制不可為負: request_rate_limit=%v request_burst=%d"), c.Slaves.RequestRateLimit, c.Slaves.RequestBurst)
+	}
+
+	switch c.Slaves.Identity {
+	case "", SlaveIdentityStatic, SlaveIdentityPod:
+	default:
+		return fmt.Errorf(T("不支援的 Slave 身分來源: %s (可用: static, pod)"), c.Slaves.Identity)
+	}
+	if c.Slaves.RampUpRate < 0 || c.Slaves.RampDownRate < 0 || c.Network.AnnounceRate < 0 {
+		return fmt.Errorf(T("逐步上線/下線速率不可為負: ramp_up_rate=%v ramp_down_rate=%v announce_rate=%v"),
+			c.Slaves.RampUpRate, c.Slaves.RampDownRate, c.Network.AnnounceRate)
+	}
+
+	profileName := c.Slaves.Profile
+	if profileName == "" {
+		profileName = ProfileSinglePhase
+	}
+	profile, _ := GetDeviceProfile(profileName)
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+
+	addressing, err := ParseAddressMode(c.Slaves.Addressing)
+	if err != nil {
+		return err
+	}
+	if len(c.Slaves.DefaultRegisters) > 0 {
+		if err := ValidateRegisterDefinitions(c.Slaves.DefaultRegisters, addressing); err != nil {
+			return fmt.Errorf("default_registers: %w", err)
+		}
+	}
+
+	if c.Scenario.Workers < 0 {
+		return fmt.Errorf(T("場景更新 worker 數不可為負: %d"), c.Scenario.Workers)
+	}
+	if c.Scenario.BatchSize < 0 {
+		return fmt.Errorf(T("場景更新批次大小不可為負: %d"), c.Scenario.BatchSize)
+	}
+
+	for name, params := range c.Scenario.Scenarios {
+		if err := params.Validate(name, profile); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Redundancy.Validate(); err != nil {
+		return fmt.Errorf(T("備援配對驗證失敗: %w"), err)
+	}
+	// 配對的兩端都須位於 IP 範圍內 (未配置範圍時由引擎啟動時確認)
+	if len(c.Network.IPRanges) > 0 {
+		for _, pair := range c.Redundancy.Pairs {
+			for _, member := range []string{pair.Primary, pair.Standby} {
+				if !c.Network.Contains(net.ParseIP(member)) {
+					return fmt.Errorf(T("配對 %s 的 %s 不在 network.ip_ranges 內"), pair.Name, member)
+				}
+			}
+		}
+	}
+
+	for tag, targets := range c.Slaves.Tags {
+		for _, target := range targets {
+			if net.ParseIP(target) == nil {
+				if _, _, err := net.ParseCIDR(target); err != nil {
+					return fmt.Errorf(T("標籤 %s 的目標無效: %s"), tag, target)
+				}
+			}
+		}
+	}
+
+	if err := c.Protection.Validate(c.Slaves.Tags); err != nil {
+		return fmt.Errorf(T("故障注入保護驗證失敗: %w"), err)
+	}
+
+	domains := make(map[string]bool)
+	for _, d := range c.FailureDomains {
+		if domains[d.Name] {
+			return fmt.Errorf(T("故障域名稱重複: %s"), d.Name)
+		}
+		domains[d.Name] = true
+		if err := d.Validate(c.Slaves.Tags); err != nil {
+			return fmt.Errorf(T("故障域驗證失敗: %w"), err)
+		}
+	}
+
+	if err := c.BootStorm.Validate(); err != nil {
+		return err
+	}
+
+	rules := make(map[string]bool)
+	for _, rule := range c.Decommission.Rules {
+		if rules[rule.Name] {
+			return fmt.Errorf(T("除役規則名稱重複: %s"), rule.Name)
+		}
+		rules[rule.Name] = true
+		if err := rule.Validate(c.Slaves.Tags); err != nil {
+			return fmt.Errorf(T("除役規則驗證失敗: %w"), err)
+		}
+	}
+
+	hooks := make(map[string]bool)
+	for _, hook := range c.WriteHooks {
+		if hooks[hook.Name] {
+			return fmt.Errorf(T("寫入回呼名稱重複: %s"), hook.Name)
+		}
+		hooks[hook.Name] = true
+		if err := hook.Validate(c.Slaves.Tags); err != nil {
+			return fmt.Errorf(T("寫入回呼驗證失敗: %w"), err)
+		}
+	}
+
+	overrides := make(map[string]bool)
+	for _, rule := range c.FunctionOverrides {
+		if overrides[rule.Name] {
+			return fmt.Errorf(T("功能碼覆寫規則名稱重複: %s"), rule.Name)
+		}
+		overrides[rule.Name] = true
+		if err := rule.Validate(c.Slaves.Tags); err != nil {
+			return fmt.Errorf(T("功能碼覆寫規則驗證失敗: %w"), err)
+		}
+	}
+
+	acls := make(map[string]bool)
+	for _, rule := range c.ACL {
+		if acls[rule.Name] {
+			return fmt.Errorf(T("存取控制規則名稱重複: %s"), rule.Name)
+		}
+		acls[rule.Name] = true
+		if err := rule.Validate(c.Slaves.Tags); err != nil {
+			return fmt.Errorf(T("存取控制規則驗證失敗: %w"), err)
+		}
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Cluster.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Persistence.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.MQTT.Validate(c.Slaves.Tags); err != nil {
+		return err
+	}
+
+	if err := c.OPCUA.Validate(c.Slaves.Tags); err != nil {
+		return err
+	}
+
+	if err := c.IEC104.Validate(c.Server.Port); err != nil {
+		return err
+	}
+
+	if err := c.BACnet.Validate(c.Slaves.Tags); err != nil {
+		return err
+	}
+
+	if c.Polling.MinPolls < 0 || c.Polling.HotSpotRatio < 0 || c.Polling.MaxBlocks < 0 {
+		return fmt.Errorf(T("輪詢分析設定不可為負: min_polls=%d hot_spot_ratio=%v max_blocks=%d"),
+			c.Polling.MinPolls, c.Polling.HotSpotRatio, c.Polling.MaxBlocks)
+	}
+
+	if c.Drift.Interval < 0 {
+		return fmt.Errorf(T("漂移檢查間隔不可為負: %v"), c.Drift.Interval)
+	}
+
+	if c.Clock.Speed < 0 {
+		return fmt.Errorf(T("模擬時鐘倍率不可為負: %v"), c.Clock.Speed)
+	}
+
+	if c.Metrics.RegisterValues.MaxSlaves < 0 || c.Metrics.PerSlave.MaxSlaves < 0 {
+		return fmt.Errorf(T("指標 Slave 數上限不可為負: register_values=%d per_slave=%d"),
+			c.Metrics.RegisterValues.MaxSlaves, c.Metrics.PerSlave.MaxSlaves)
+	}
+
+	if c.Metrics.MinHealthyPercent < 0 || c.Metrics.MinHealthyPercent > 100 {
+		return fmt.Errorf(T("健康 Slave 比例門檻必須介於 0-100: %v"), c.Metrics.MinHealthyPercent)
+	}
+
+	if c.Tracing.SampleRate < 0 || c.Tracing.SampleRate > 1 {
+		return fmt.Errorf(T("追蹤取樣率必須介於 0-1: %v"), c.Tracing.SampleRate)
+	}
+	if c.Tracing.Endpoint != "" {
+		if u, err := url.Parse(c.Tracing.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf(T("無效的追蹤端點: %s"), c.Tracing.Endpoint)
+		}
+	}
+
+	if c.Privilege.Group != "" && c.Privilege.User == "" {
+		return errors.New(T("指定降級群組時必須同時指定使用者"))
+	}
+	if _, err := parseCapabilities(c.Privilege.Capabilities); err != nil {
+		return err
+	}
+
+	if c.Audit.MaxSizeMB < 0 || c.Audit.MaxBackups < 0 {
+		return fmt.Errorf(T("稽核日誌的大小與保留數不可為負: max_size_mb=%d max_backups=%d"), c.Audit.MaxSizeMB, c.Audit.MaxBackups)
+	}
+	if c.Audit.Enabled && c.Audit.Path == "" {
+		return errors.New(T("啟用稽核日誌時必須指定 path"))
 	}
 
 	for _, ipRange := range c.Network.IPRanges {
 		if err := ipRange.Validate(); err != nil {
-			return fmt.Errorf("IP 範圍驗證失敗: %w", err)
+			return fmt.Errorf(T("IP 範圍驗證失敗: %w"), err)
 		}
 	}
 
+	if err := c.Network.validateMode(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// validateMode 驗證虛擬 IP 的配置方式與專用介面參數
+func (n *NetworkConfig) validateMode() error {
+	switch n.Mode {
+	case "", NetworkModeAlias:
+		return nil
+	case NetworkModeDummy, NetworkModeMacvlan:
+	default:
+		return fmt.Errorf(T("不支援的網路配置方式: %s (可用: alias, dummy, macvlan)"), n.Mode)
+	}
+	// 介面名稱上限 15 字元 (IFNAMSIZ)
+	if len(n.DedicatedLink()) > 15 {
+		return fmt.Errorf(T("網路介面名稱過長 (最多 15 字元): %s"), n.DedicatedLink())
+	}
+	if n.MTU != 0 && (n.MTU < 68 || n.MTU > 65535) {
+		return fmt.Errorf(T("MTU 必須介於 68-65535: %d"), n.MTU)
+	}
+	if n.Mode == NetworkModeMacvlan && n.ParentInterface() == "" {
+		return errors.New(T("macvlan 模式必須指定 parent 或 interface"))
+	}
+	return nil
+}
+
+// DedicatedLink dummy/macvlan 模式的專用介面名稱 (alias 模式為空字串)
+func (n *NetworkConfig) DedicatedLink() string {
+	if n.Mode != NetworkModeDummy && n.Mode != NetworkModeMacvlan {
+		return ""
+	}
+	if n.Link == "" {
+		return DefaultNetworkLink
+	}
+	return n.Link
+}
+
+// ParentInterface macvlan 的上層介面
+func (n *NetworkConfig) ParentInterface() string {
+	if n.Parent != "" {
+		return n.Parent
+	}
+	return n.Interface
+}
+
 // Validate 驗證 IP 範圍
 func (r *IPRange) Validate() error {
+	switch r.Transport {
+	case "", TransportTCP, TransportUDP:
+	default:
+		return fmt.Errorf(T("不支援的傳輸方式: %s (可用: tcp, udp)"), r.Transport)
+	}
+
 	if r.CIDR != "" {
 		_, _, err := net.ParseCIDR(r.CIDR)
 		if err != nil {
-			return fmt.Errorf("無效的 CIDR: %s", r.CIDR)
+			return fmt.Errorf(T("無效的 CIDR: %s"), r.CIDR)
 		}
 		return nil
 	}
 
 	if r.Start == "" || r.End == "" {
-		return fmt.Errorf("必須指定 Start 和 End 或 CIDR")
+		return errors.New(T("必須指定 Start 和 End 或 CIDR"))
 	}
 
 	startIP := net.ParseIP(r.Start)
 	if startIP == nil {
-		return fmt.Errorf("無效的起始 IP: %s", r.Start)
+		return fmt.Errorf(T("無效的起始 IP: %s"), r.Start)
 	}
 
 	endIP := net.ParseIP(r.End)
 	if endIP == nil {
-		return fmt.Errorf("無效的結束 IP: %s", r.End)
+		return fmt.Errorf(T("無效的結束 IP: %s"), r.End)
+	}
+
+	return nil
+}
+
+// Validate 驗證自我監控設備
+func (d *DiagnosticsConfig) Validate() error {
+	if d.Interval < 0 || d.TickLagAlarm < 0 || d.FDUsageAlarm < 0 || d.ErrorRateAlarm < 0 {
+		return errors.New(T("自我監控設備的週期與告警門檻不可為負"))
+	}
+	if d.Port < 0 || d.Port > 65535 {
+		return fmt.Errorf(T("無效的埠號: %d"), d.Port)
+	}
+	if !d.Enabled {
+		return nil
+	}
+	if net.ParseIP(d.IP) == nil {
+		return fmt.Errorf(T("自我監控設備需指定有效的 IP: %q"), d.IP)
 	}
+	if d.UnitID == 0 {
+		return errors.New(T("自我監控設備的 Unit ID 不可為 0"))
+	}
+	return nil
+}
 
+// Validate 驗證叢集模式
+func (c *ClusterConfig) Validate() error {
+	if c.SlavesPerAgent < 0 || c.HeartbeatInterval < 0 {
+		return fmt.Errorf(T("叢集設定不可為負: slaves_per_agent=%d heartbeat_interval=%v"), c.SlavesPerAgent, c.HeartbeatInterval)
+	}
+	for _, address := range []string{c.Listen, c.Coordinator} {
+		if address == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return fmt.Errorf(T("無效的叢集位址: %s"), address)
+		}
+	}
+	return nil
+}
+
+// heartbeat agent 回報指標的間隔
+func (c *ClusterConfig) heartbeat() time.Duration {
+	if c.HeartbeatInterval > 0 {
+		return c.HeartbeatInterval
+	}
+	return DefaultClusterHeartbeat
+}
+
+// Validate 驗證備援配對
+func (r *RedundancyConfig) Validate() error {
+	switch r.StandbyMode {
+	case "", StandbyModeRefuse, StandbyModeSilent:
+	default:
+		return fmt.Errorf(T("無效的備援模式: %s"), r.StandbyMode)
+	}
+
+	names := make(map[string]bool)
+	for _, pair := range r.Pairs {
+		if pair.Name == "" {
+			return errors.New(T("配對必須指定名稱"))
+		}
+		if names[pair.Name] {
+			return fmt.Errorf(T("配對名稱重複: %s"), pair.Name)
+		}
+		names[pair.Name] = true
+
+		if net.ParseIP(pair.Primary) == nil || net.ParseIP(pair.Standby) == nil {
+			return fmt.Errorf(T("配對 %s 的 IP 無效: %s / %s"), pair.Name, pair.Primary, pair.Standby)
+		}
+		if pair.Primary == pair.Standby {
+			return fmt.Errorf(T("配對 %s 的主備 IP 相同"), pair.Name)
+		}
+	}
+	return nil
+}
+
+// Validate 驗證故障注入保護配置
+func (p *ProtectionConfig) Validate(tags map[string][]string) error {
+	for _, tag := range p.Tags {
+		if _, ok := tags[tag]; !ok {
+			return fmt.Errorf(T("未定義的 Slave 標籤: %s"), tag)
+		}
+	}
+
+	for _, w := range p.Windows {
+		if _, err := parseClock(w.Start); err != nil {
+			return fmt.Errorf(T("保護時段 %s 的開始時間無效: %s"), w.Name, w.Start)
+		}
+		if _, err := parseClock(w.End); err != nil {
+			return fmt.Errorf(T("保護時段 %s 的結束時間無效: %s"), w.Name, w.End)
+		}
+		for _, tag := range w.Tags {
+			if _, ok := tags[tag]; !ok {
+				return fmt.Errorf(T("保護時段 %s 使用未定義的 Slave 標籤: %s"), w.Name, tag)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate 驗證故障域
+func (d FailureDomain) Validate(tags map[string][]string) error {
+	if d.Name == "" {
+		return errors.New(T("故障域必須指定名稱"))
+	}
+	if len(d.Targets) == 0 && len(d.Tags) == 0 {
+		return fmt.Errorf(T("故障域 %s 必須指定 targets 或 tags"), d.Name)
+	}
+	for _, target := range d.Targets {
+		if net.ParseIP(target) == nil {
+			if _, _, err := net.ParseCIDR(target); err != nil {
+				return fmt.Errorf(T("故障域 %s 的目標無效: %s"), d.Name, target)
+			}
+		}
+	}
+	for _, tag := range d.Tags {
+		if _, ok := tags[tag]; !ok {
+			return fmt.Errorf(T("故障域 %s 使用未定義的 Slave 標籤: %s"), d.Name, tag)
+		}
+	}
+	if d.OutageInterval < 0 || d.OutageDuration < 0 {
+		return fmt.Errorf(T("故障域 %s 的停擺時間不可為負"), d.Name)
+	}
+	return nil
+}
+
+// Validate 驗證除役規則
+func (d DecommissionRule) Validate(tags map[string][]string) error {
+	if d.Name == "" {
+		return errors.New(T("除役規則必須指定名稱"))
+	}
+	if (d.Lifetime > 0) == (d.At != "") {
+		return fmt.Errorf(T("除役規則 %s 必須指定 lifetime 或 at 其中之一"), d.Name)
+	}
+	if d.Lifetime < 0 || d.Jitter < 0 {
+		return fmt.Errorf(T("除役規則 %s 的時間不可為負"), d.Name)
+	}
+	if d.At != "" {
+		if _, err := time.Parse(time.RFC3339, d.At); err != nil {
+			return fmt.Errorf(T("除役規則 %s 的 at 必須為 RFC3339 時間: %s"), d.Name, d.At)
+		}
+	}
+	for _, target := range d.Targets {
+		if net.ParseIP(target) == nil {
+			if _, _, err := net.ParseCIDR(target); err != nil {
+				return fmt.Errorf(T("除役規則 %s 的目標無效: %s"), d.Name, target)
+			}
+		}
+	}
+	for _, tag := range d.Tags {
+		if _, ok := tags[tag]; !ok {
+			return fmt.Errorf(T("除役規則 %s 使用未定義的 Slave 標籤: %s"), d.Name, tag)
+		}
+	}
+	return nil
+}
+
+// Validate 驗證開機風暴設定
+func (b BootStormConfig) Validate() error {
+	if b.Outage < 0 || b.Stagger < 0 {
+		return fmt.Errorf(T("開機風暴的斷電時間與錯開時間不可為負: outage=%v stagger=%v"), b.Outage, b.Stagger)
+	}
+	for _, target := range b.Targets {
+		if net.ParseIP(target) == nil {
+			if _, _, err := net.ParseCIDR(target); err != nil {
+				return fmt.Errorf(T("開機風暴的目標無效: %s"), target)
+			}
+		}
+	}
+	return nil
+}
+
+// registerSpan 由模擬器寫入的連續保持暫存器
+type registerSpan struct {
+	name  string
+	start uint16
+	size  int
+}
+
+// computedRegisterSpans 各項功能由模擬器寫入的保持暫存器 (未啟用的功能不列入)
+func (c *Config) computedRegisterSpans() []registerSpan {
+	var spans []registerSpan
+	if c.Clock.Register != 0 {
+		spans = append(spans, registerSpan{"clock.register", c.Clock.Register, 2})
+	}
+	if d := c.Slaves.DeviceClock; d.Enabled {
+		clock := deviceClock{register: d.Register, format: d.Format}
+		if clock.register == 0 {
+			clock.register = DefaultDeviceClockRegister
+		}
+		spans = append(spans, registerSpan{"slaves.device_clock", clock.register, clock.size()})
+	}
+	if d := c.Slaves.Demand; d.Enabled {
+		register := d.Register
+		if register == 0 {
+			register = DefaultDemandRegister
+		}
+		spans = append(spans, registerSpan{"slaves.demand", register, demandOffsetLastInterval + 2})
+	}
+	if t := c.Slaves.Tariff; t.Enabled {
+		register := t.Register
+		if register == 0 {
+			register = DefaultTariffRegister
+		}
+		spans = append(spans, registerSpan{"slaves.tariff", register, 1 + tariffCount*2})
+	}
+	if params, ok := c.Scenario.Scenarios["long_command"]; ok && params.Enabled {
+		command, status, _ := longCommandRegisters(params)
+		spans = append(spans,
+			registerSpan{"long_command.command_register", command, 1},
+			registerSpan{"long_command.status_register", status, 1},
+		)
+	}
+	return spans
+}
+
+// validateComputedRegisters 驗證各項功能寫入的保持暫存器彼此不重疊
+func (c *Config) validateComputedRegisters() error {
+	spans := c.computedRegisterSpans()
+	for i, a := range spans {
+		for _, b := range spans[i+1:] {
+			if int(a.start) < int(b.start)+b.size && int(b.start) < int(a.start)+a.size {
+				return fmt.Errorf(T("%s (%d-%d) 與 %s (%d-%d) 的暫存器位址重疊"),
+					a.name, a.start, int(a.start)+a.size-1, b.name, b.start, int(b.start)+b.size-1)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate 驗證設備時鐘設定
+func (d DeviceClockConfig) Validate() error {
+	switch d.Format {
+	case "", DeviceClockFormatDateTime, DeviceClockFormatUnix:
+	default:
+		return fmt.Errorf(T("不支援的設備時鐘格式: %s (可用: datetime, unix)"), d.Format)
+	}
+	if d.TimeZone != "" {
+		if _, err := time.LoadLocation(d.TimeZone); err != nil {
+			return fmt.Errorf(T("無效的設備時鐘時區: %s"), d.TimeZone)
+		}
+	}
+	if d.DriftSpread < 0 || d.SkewSpread < 0 {
+		return fmt.Errorf(T("設備時鐘的 drift_spread 與 skew_spread 不可為負: drift_spread=%v skew_spread=%v"), d.DriftSpread, d.SkewSpread)
+	}
+	if d.DriftPPM <= -1e6 {
+		return fmt.Errorf(T("設備時鐘的 drift_ppm 必須大於 -1000000: %v"), d.DriftPPM)
+	}
+	if d.DeviceIDObject != 0 && d.DeviceIDObject < 0x80 {
+		return fmt.Errorf(T("設備時鐘的 device_id_object 必須介於 0x80-0xFF: 0x%02X"), d.DeviceIDObject)
+	}
+	return nil
+}
+
+// Validate 驗證需量設定
+func (d DemandConfig) Validate() error {
+	if d.Window < 0 || d.Interval < 0 || d.Subintervals < 0 {
+		return errors.New(T("需量的 window、interval 與 subintervals 不可為負"))
+	}
+	if d.Window > 24*time.Hour || d.Interval > 24*time.Hour {
+		return errors.New(T("需量的 window 與 interval 不可超過 24h"))
+	}
+	window, subintervals := durationOr(d.Window, DefaultDemandWindow), d.Subintervals
+	if subintervals == 0 {
+		subintervals = DefaultDemandSubintervals
+	}
+	if window/time.Duration(subintervals) < time.Second {
+		return fmt.Errorf(T("需量的子區間不可短於 1s: window=%v subintervals=%d"), window, subintervals)
+	}
+	switch d.PeakReset {
+	case "", DemandPeakResetMonthly, DemandPeakResetDaily, DemandPeakResetNever:
+	default:
+		return fmt.Errorf(T("不支援的最大需量重設週期: %s (可用: monthly, daily, never)"), d.PeakReset)
+	}
+	if d.TimeZone != "" {
+		if _, err := time.LoadLocation(d.TimeZone); err != nil {
+			return fmt.Errorf(T("無效的需量時區: %s"), d.TimeZone)
+		}
+	}
+	return nil
+}
+
+// Validate 驗證多費率電能設定
+func (t TariffConfig) Validate() error {
+	if _, err := parseTariffSchedule(t.Schedule); err != nil {
+		return err
+	}
+	if t.TimeZone != "" {
+		if _, err := time.LoadLocation(t.TimeZone); err != nil {
+			return fmt.Errorf(T("無效的費率時區: %s"), t.TimeZone)
+		}
+	}
 	return nil
 }
 
@@ -246,11 +1772,11 @@ func (r *IPRange) Validate() error {
 func (c *Config) SaveConfig(path string) error {
 	data, err := json.MarshalIndent(c, "", "  ")
 	if err != nil {
-		return fmt.Errorf("序列化配置失敗: %w", err)
+		return fmt.Errorf(T("序列化配置失敗: %w"), err)
 	}
 
 	if err := os.WriteFile(path, data, 0644); err != nil {
-		return fmt.Errorf("寫入配置檔失敗: %w", err)
+		return fmt.Errorf(T("寫入配置檔失敗: %w"), err)
 	}
 
 	return nil
@@ -271,6 +1797,70 @@ func (c *Config) ExpandIPRanges() ([]net.IP, error) {
 	return ips, nil
 }
 
+// Contains 判斷 IP 是否在範圍內
+func (r *IPRange) Contains(ip net.IP) bool {
+	if r.CIDR != "" {
+		_, ipNet, err := net.ParseCIDR(r.CIDR)
+		return err == nil && ipNet.Contains(ip)
+	}
+
+	ip4 := ip.To4()
+	startIP := net.ParseIP(r.Start).To4()
+	endIP := net.ParseIP(r.End).To4()
+	if ip4 == nil || startIP == nil || endIP == nil {
+		return false
+	}
+	return bytes.Compare(ip4, startIP) >= 0 && bytes.Compare(ip4, endIP) <= 0
+}
+
+// Contains IP 是否位於任一範圍內
+func (n *NetworkConfig) Contains(ip net.IP) bool {
+	for _, r := range n.IPRanges {
+		if r.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// InterfaceFor IP 所在的網路介面：第一個包含該 IP 且指定 interface 的範圍，否則為 network.interface
+// (explicit 表示由範圍明確指定)
+func (n *NetworkConfig) InterfaceFor(ip net.IP) (name string, explicit bool) {
+	for _, r := range n.IPRanges {
+		if r.Interface != "" && r.Contains(ip) {
+			return r.Interface, true
+		}
+	}
+	return n.Interface, false
+}
+
+// TransportFor IP 的 Modbus 傳輸方式：第一個包含該 IP 且指定 transport 的範圍，否則為 tcp
+func (n *NetworkConfig) TransportFor(ip net.IP) string {
+	for _, r := range n.IPRanges {
+		if r.Transport != "" && r.Contains(ip) {
+			return r.Transport
+		}
+	}
+	return TransportTCP
+}
+
+// Interfaces 使用到的所有網路介面 (network.interface 與各範圍指定的介面，不重複)
+func (n *NetworkConfig) Interfaces() []string {
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	add(n.Interface)
+	for _, r := range n.IPRanges {
+		add(r.Interface)
+	}
+	return names
+}
+
 // Expand 展開 IP 範圍
 func (r *IPRange) Expand() ([]net.IP, error) {
 	if r.CIDR != "" {
@@ -305,7 +1895,7 @@ func expandRange(start, end string) ([]net.IP, error) {
 	endIP := net.ParseIP(end).To4()
 
 	if startIP == nil || endIP == nil {
-		return nil, fmt.Errorf("無效的 IP 範圍: %s - %s", start, end)
+		return nil, fmt.Errorf(T("無效的 IP 範圍: %s - %s"), start, end)
 	}
 
 	var ips []net.IP
@@ -322,6 +1912,25 @@ func expandRange(start, end string) ([]net.IP, error) {
 	return ips, nil
 }
 
+// MatchTargets 判斷 IP 是否符合目標清單 (IP 或 CIDR)，空清單表示全部符合
+func MatchTargets(ip net.IP, targets []string) bool {
+	if len(targets) == 0 {
+		return true
+	}
+	for _, target := range targets {
+		if _, ipNet, err := net.ParseCIDR(target); err == nil {
+			if ipNet.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if targetIP := net.ParseIP(target); targetIP != nil && targetIP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 func incIP(ip net.IP) {
 	for j := len(ip) - 1; j >= 0; j-- {
 		ip[j]++
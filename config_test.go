@@ -1,13 +1,24 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net"
 	"os"
+	"os/user"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -44,17 +55,978 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "extra port same as port",
+			modify: func(c *Config) {
+				c.Server.ExtraPorts = []int{5020, c.Server.Port}
+			},
+			wantErr: true,
+		},
+		{
+			name: "acl invalid allow address",
+			modify: func(c *Config) {
+				c.ACL = []ACLRule{{Name: "ems-only", Allow: []string{"10.0.0.0/33"}}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid profile functions",
+			modify: func(c *Config) {
+				c.Slaves.ProfileFunctions = map[string][]uint8{ProfileSinglePhase: {3, 6}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "profile functions unknown profile",
+			modify: func(c *Config) {
+				c.Slaves.ProfileFunctions = map[string][]uint8{"vendor_x": {3}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "profile functions empty",
+			modify: func(c *Config) {
+				c.Slaves.ProfileFunctions = map[string][]uint8{ProfileSinglePhase: {}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid function overrides",
+			modify: func(c *Config) {
+				c.FunctionOverrides = []FunctionOverrideRule{
+					{Name: "busy-writes", Targets: []string{"192.168.1.10"}, Functions: []uint8{16}, Exception: "slave_device_busy"},
+					{Name: "slow-input", Profiles: []string{ProfileSinglePhase}, Functions: []uint8{4}, Delay: 2 * time.Second},
+				}
+			},
+			wantErr: false,
+		},
+		{
+			name: "function override unknown exception",
+			modify: func(c *Config) {
+				c.FunctionOverrides = []FunctionOverrideRule{{Name: "busy-writes", Functions: []uint8{16}, Exception: "busy"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "function override without behaviour",
+			modify: func(c *Config) {
+				c.FunctionOverrides = []FunctionOverrideRule{{Name: "noop", Functions: []uint8{3}}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "acl unknown action",
+			modify: func(c *Config) {
+				c.ACL = []ACLRule{{Name: "ems-only", Allow: []string{"10.0.0.5"}, Action: "drop"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid acl",
+			modify: func(c *Config) {
+				c.ACL = []ACLRule{{Name: "ems-only", Targets: []string{"192.168.1.0/24"}, Allow: []string{"10.0.0.5", "10.1.0.0/16"}, Action: ACLActionException}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid extra ports",
+			modify: func(c *Config) {
+				c.Server.ExtraPorts = []int{5020, 1502}
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid jitter distribution",
+			modify: func(c *Config) {
+				params := c.Scenario.Scenarios["jitter"]
+				params.JitterDistribution = "weibull"
+				c.Scenario.Scenarios["jitter"] = params
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid jitter timeout rate",
+			modify: func(c *Config) {
+				params := c.Scenario.Scenarios["jitter"]
+				params.JitterDistribution = DelayLognormal
+				params.JitterTimeoutRate = 1.5
+				c.Scenario.Scenarios["jitter"] = params
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid mismatch mode",
+			modify: func(c *Config) {
+				params := c.Scenario.Scenarios["transaction_mismatch"]
+				params.MismatchModes = []string{"swap"}
+				c.Scenario.Scenarios["transaction_mismatch"] = params
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid harmonic order",
+			modify: func(c *Config) {
+				params := c.Scenario.Scenarios["harmonic_distortion"]
+				params.HarmonicOrder = 9
+				c.Scenario.Scenarios["harmonic_distortion"] = params
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid composite component",
+			modify: func(c *Config) {
+				c.Scenario.Scenarios["composite"] = ScenarioParams{Components: []string{"jitter", "composite"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate composite component",
+			modify: func(c *Config) {
+				c.Scenario.Scenarios["composite"] = ScenarioParams{Components: []string{"jitter", "jitter"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid device clock format",
+			modify: func(c *Config) {
+				c.Slaves.DeviceClock = DeviceClockConfig{Enabled: true, Format: "iso"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "device clock object in basic range",
+			modify: func(c *Config) {
+				c.Slaves.DeviceClock = DeviceClockConfig{Enabled: true, DeviceIDObject: 0x02}
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid demand peak reset",
+			modify: func(c *Config) {
+				c.Slaves.Demand = DemandConfig{Enabled: true, PeakReset: "weekly"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "demand subinterval too short",
+			modify: func(c *Config) {
+				c.Slaves.Demand = DemandConfig{Enabled: true, Window: 10 * time.Second, Subintervals: 20}
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid tariff schedule",
+			modify: func(c *Config) {
+				c.Slaves.Tariff = TariffConfig{Enabled: true, Schedule: []TariffPeriod{{Start: "07:00", Tariff: 5}}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid tariff day",
+			modify: func(c *Config) {
+				c.Slaves.Tariff = TariffConfig{Enabled: true, Schedule: []TariffPeriod{{Start: "7:30", Tariff: 2, Days: []string{"weekday"}}}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "clock register overlaps long command",
+			modify: func(c *Config) {
+				c.Clock.Register = 40100
+			},
+			wantErr: true,
+		},
+		{
+			name: "demand overlaps tariff",
+			modify: func(c *Config) {
+				c.Slaves.Demand = DemandConfig{Enabled: true, Register: 40225}
+				c.Slaves.Tariff = TariffConfig{Enabled: true}
+			},
+			wantErr: true,
+		},
+		{
+			name: "computed registers at default addresses",
+			modify: func(c *Config) {
+				c.Clock.Register = 40120
+				c.Slaves.DeviceClock = DeviceClockConfig{Enabled: true}
+				c.Slaves.Demand = DemandConfig{Enabled: true}
+				c.Slaves.Tariff = TariffConfig{Enabled: true}
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative clock speed",
+			modify: func(c *Config) {
+				c.Clock.Speed = -1
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid chaos scenario",
+			modify: func(c *Config) {
+				c.Scenario.Scenarios["chaos"] = ScenarioParams{ChaosScenarios: []string{"jitter", "chaos"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "chaos fraction out of range",
+			modify: func(c *Config) {
+				c.Scenario.Scenarios["chaos"] = ScenarioParams{ChaosFractionMin: 0.5, ChaosFractionMax: 1.5}
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid churn mode",
+			modify: func(c *Config) {
+				params := c.Scenario.Scenarios["connection_churn"]
+				params.ChurnModes = []string{"rst", "abort"}
+				c.Scenario.Scenarios["connection_churn"] = params
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid single master",
+			modify: func(c *Config) {
+				c.Server.SingleMaster = SingleMasterQueue
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid single master",
+			modify: func(c *Config) {
+				c.Server.SingleMaster = "share"
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid max ADU size",
+			modify: func(c *Config) {
+				c.Server.MaxADUSize = 300
+			},
+			wantErr: true,
+		},
 		{
 			name: "invalid slave count - zero",
 			modify: func(c *Config) {
-				c.Slaves.Count = 0
+				c.Slaves.Count = 0
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid slave count - too high",
+			modify: func(c *Config) {
+				c.Slaves.Count = 20000
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative bind retry interval",
+			modify: func(c *Config) {
+				c.Server.BindRetryInterval = -time.Second
+			},
+			wantErr: true,
+		},
+		{
+			name: "redundant pair with same IPs",
+			modify: func(c *Config) {
+				c.Redundancy.Pairs = []RedundantPair{{Name: "p1", Primary: "10.0.0.1", Standby: "10.0.0.1"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid standby mode",
+			modify: func(c *Config) {
+				c.Redundancy.StandbyMode = "hot"
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid redundant pair",
+			modify: func(c *Config) {
+				c.Redundancy.Pairs = []RedundantPair{{Name: "p1", Primary: "10.0.0.1", Standby: "10.0.0.2"}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "redundant pair member outside ip ranges",
+			modify: func(c *Config) {
+				c.Network.IPRanges = []IPRange{{CIDR: "10.0.0.0/31"}}
+				c.Redundancy.Pairs = []RedundantPair{{Name: "p1", Primary: "10.0.0.1", Standby: "10.0.0.2"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "redundant pair within ip ranges",
+			modify: func(c *Config) {
+				c.Network.IPRanges = []IPRange{{Start: "10.0.0.1", End: "10.0.0.2"}}
+				c.Redundancy.Pairs = []RedundantPair{{Name: "p1", Primary: "10.0.0.1", Standby: "10.0.0.2"}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown profile",
+			modify: func(c *Config) {
+				c.Slaves.Profile = "no_such_meter"
+			},
+			wantErr: true,
+		},
+		{
+			name: "three phase profile",
+			modify: func(c *Config) {
+				c.Slaves.Profile = ProfileThreePhase
+			},
+			wantErr: false,
+		},
+		{
+			name: "overlapping registers",
+			modify: func(c *Config) {
+				c.Slaves.DefaultRegisters = append(c.Slaves.DefaultRegisters,
+					RegisterDefinition{Address: 40005, Name: "Overlap", DataType: "uint16", Scale: 1})
+			},
+			wantErr: true,
+		},
+		{
+			name: "64-bit and string registers",
+			modify: func(c *Config) {
+				c.Slaves.DefaultRegisters = append(c.Slaves.DefaultRegisters,
+					RegisterDefinition{Address: 40020, Name: "ImportEnergy", DataType: "uint64", Scale: 1000, Unit: "Wh"},
+					RegisterDefinition{Address: 40024, Name: "Model", DataType: "string(16)", DefaultText: "PM2100"})
+			},
+			wantErr: false,
+		},
+		{
+			name: "string default text too long",
+			modify: func(c *Config) {
+				c.Slaves.DefaultRegisters = append(c.Slaves.DefaultRegisters,
+					RegisterDefinition{Address: 40024, Name: "Model", DataType: "string(4)", DefaultText: "PM2100"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid addressing",
+			modify: func(c *Config) {
+				c.Slaves.Addressing = "one_based"
+			},
+			wantErr: true,
+		},
+		{
+			name: "plc addressing rejects 4xxxx registers",
+			modify: func(c *Config) {
+				c.Slaves.Addressing = string(AddressModePLC)
+			},
+			wantErr: true,
+		},
+		{
+			name: "register address out of range",
+			modify: func(c *Config) {
+				c.Slaves.DefaultRegisters = append(c.Slaves.DefaultRegisters,
+					RegisterDefinition{Address: 50000, Name: "Tail", DataType: "uint32", Scale: 1})
+			},
+			wantErr: true,
+		},
+		{
+			name: "zero scale register",
+			modify: func(c *Config) {
+				c.Slaves.DefaultRegisters[0].Scale = 0
+			},
+			wantErr: true,
+		},
+		{
+			name: "writable accumulator",
+			modify: func(c *Config) {
+				for i := range c.Slaves.DefaultRegisters {
+					if c.Slaves.DefaultRegisters[i].Name == "TotalEnergy" {
+						c.Slaves.DefaultRegisters[i].Writable = true
+					}
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "expression unknown register",
+			modify: func(c *Config) {
+				c.Slaves.DefaultRegisters = append(c.Slaves.DefaultRegisters,
+					RegisterDefinition{Address: 40020, Name: "Apparent", DataType: "uint32", Scale: 10, Expression: "LineVoltage * NoSuchCurrent"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "expression syntax error",
+			modify: func(c *Config) {
+				c.Slaves.DefaultRegisters = append(c.Slaves.DefaultRegisters,
+					RegisterDefinition{Address: 40020, Name: "Apparent", DataType: "uint32", Scale: 10, Expression: "40001 * (40002"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "expression circular reference",
+			modify: func(c *Config) {
+				c.Slaves.DefaultRegisters = append(c.Slaves.DefaultRegisters,
+					RegisterDefinition{Address: 40020, Name: "A", DataType: "uint16", Scale: 1, Expression: "B + 1"},
+					RegisterDefinition{Address: 40021, Name: "B", DataType: "uint16", Scale: 1, Expression: "A + 1"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid scenario script",
+			modify: func(c *Config) {
+				params := c.Scenario.Scenarios["script"]
+				params.Script = "no_such_script.lua"
+				c.Scenario.Scenarios["script"] = params
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid scenario capture",
+			modify: func(c *Config) {
+				params := c.Scenario.Scenarios["replay"]
+				params.Capture = "no_such_capture.pcap"
+				c.Scenario.Scenarios["replay"] = params
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative polling hot spot ratio",
+			modify: func(c *Config) {
+				c.Polling.HotSpotRatio = -1
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative drift interval",
+			modify: func(c *Config) {
+				c.Drift.Interval = -time.Second
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative boot storm stagger",
+			modify: func(c *Config) {
+				c.BootStorm.Stagger = -time.Second
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid boot storm target",
+			modify: func(c *Config) {
+				c.BootStorm.Targets = []string{"not-an-ip"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "decommission rule without lifetime or at",
+			modify: func(c *Config) {
+				c.Decommission.Rules = []DecommissionRule{{Name: "retire"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "decommission rule with invalid at",
+			modify: func(c *Config) {
+				c.Decommission.Rules = []DecommissionRule{{Name: "retire", At: "tomorrow"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid decommission rule",
+			modify: func(c *Config) {
+				c.Decommission.Rules = []DecommissionRule{{Name: "retire", Targets: []string{"192.168.1.0/24"}, Lifetime: time.Hour}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "write hook on input register",
+			modify: func(c *Config) {
+				c.WriteHooks = []WriteHookRule{{Name: "trip", Table: "input_registers", Actions: []WriteHookAction{{Table: "coils"}}}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "write hook without actions",
+			modify: func(c *Config) {
+				c.WriteHooks = []WriteHookRule{{Name: "trip", Table: "coils", Address: 10}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid write hook",
+			modify: func(c *Config) {
+				c.WriteHooks = []WriteHookRule{{
+					Name: "trip", Table: "coils", Address: 10, Delay: time.Second,
+					Actions: []WriteHookAction{{Table: "discrete_inputs", Address: 10}},
+				}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative audit max size",
+			modify: func(c *Config) {
+				c.Audit.MaxSizeMB = -1
+			},
+			wantErr: true,
+		},
+		{
+			name: "audit enabled without path",
+			modify: func(c *Config) {
+				c.Audit.Enabled = true
+				c.Audit.Path = ""
+			},
+			wantErr: true,
+		},
+		{
+			name: "log persistence driver",
+			modify: func(c *Config) {
+				c.Persistence.Enabled = true
+				c.Persistence.Driver = PersistenceDriverLog
+			},
+			wantErr: false,
+		},
+		{
+			name: "unsupported persistence driver",
+			modify: func(c *Config) {
+				c.Persistence.Enabled = true
+				c.Persistence.Driver = "sqlite"
+			},
+			wantErr: true,
+		},
+		{
+			name: "mqtt without broker",
+			modify: func(c *Config) {
+				c.MQTT.Enabled = true
+			},
+			wantErr: true,
+		},
+		{
+			name: "mqtt group id with wildcard",
+			modify: func(c *Config) {
+				c.MQTT.Enabled = true
+				c.MQTT.Broker = "tcp://broker:1883"
+				c.MQTT.GroupID = "site/1"
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid mqtt",
+			modify: func(c *Config) {
+				c.MQTT.Enabled = true
+				c.MQTT.Broker = "tls://broker"
+				c.MQTT.Registers = []string{"LineVoltage"}
+			},
+			wantErr: false,
+		},
+		{
+			name: "opcua listen without port",
+			modify: func(c *Config) {
+				c.OPCUA.Enabled = true
+				c.OPCUA.Listen = "0.0.0.0"
+			},
+			wantErr: true,
+		},
+		{
+			name: "opcua undefined tag",
+			modify: func(c *Config) {
+				c.OPCUA.Enabled = true
+				c.OPCUA.Tags = []string{"meters"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid opcua",
+			modify: func(c *Config) {
+				c.OPCUA.Enabled = true
+				c.OPCUA.Targets = []string{"10.0.0.0/24"}
+			},
+			wantErr: false,
+		},
+		{
+			name: "iec104 port same as modbus",
+			modify: func(c *Config) {
+				c.IEC104.Enabled = true
+				c.IEC104.Port = c.Server.Port
+			},
+			wantErr: true,
+		},
+		{
+			name: "iec104 unknown profile",
+			modify: func(c *Config) {
+				c.IEC104.Enabled = true
+				c.IEC104.Profiles = []string{"substation"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid iec104",
+			modify: func(c *Config) {
+				c.IEC104.Enabled = true
+				c.IEC104.Profiles = []string{ProfileThreePhase}
+			},
+			wantErr: false,
+		},
+		{
+			name: "bacnet instance base out of range",
+			modify: func(c *Config) {
+				c.BACnet.Enabled = true
+				c.BACnet.InstanceBase = 4194000
+			},
+			wantErr: true,
+		},
+		{
+			name: "bacnet invalid broadcast",
+			modify: func(c *Config) {
+				c.BACnet.Enabled = true
+				c.BACnet.Broadcast = []string{"fe80::1"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid bacnet",
+			modify: func(c *Config) {
+				c.BACnet.Enabled = true
+				c.BACnet.InstanceBase = 100000
+				c.BACnet.Targets = []string{"10.0.0.0/24"}
+			},
+			wantErr: false,
+		},
+		{
+			name: "persistence without interval",
+			modify: func(c *Config) {
+				c.Persistence.Enabled = true
+				c.Persistence.Interval = 0
+			},
+			wantErr: true,
+		},
+		{
+			name: "privilege group without user",
+			modify: func(c *Config) {
+				c.Privilege.Group = "nogroup"
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown privilege capability",
+			modify: func(c *Config) {
+				c.Privilege.Capabilities = []string{"sys_admin"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative idle timeout",
+			modify: func(c *Config) {
+				c.Server.IdleTimeout = -time.Second
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown listener mode",
+			modify: func(c *Config) {
+				c.Server.Listener = "pooled"
+			},
+			wantErr: true,
+		},
+		{
+			name: "original dst without shared listener",
+			modify: func(c *Config) {
+				c.Server.OriginalDst = true
+			},
+			wantErr: true,
+		},
+		{
+			name: "diagnostics without ip",
+			modify: func(c *Config) {
+				c.Diagnostics.Enabled = true
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative diagnostics alarm",
+			modify: func(c *Config) {
+				c.Diagnostics.ErrorRateAlarm = -0.1
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative max connections",
+			modify: func(c *Config) {
+				c.Server.MaxConnections = -1
+			},
+			wantErr: true,
+		},
+		{
+			name: "min healthy percent above 100",
+			modify: func(c *Config) {
+				c.Metrics.MinHealthyPercent = 101
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative max in flight",
+			modify: func(c *Config) {
+				c.Server.MaxInFlight = -1
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative refresh interval",
+			modify: func(c *Config) {
+				c.Slaves.RefreshInterval = -time.Second
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative randomize voltage spread",
+			modify: func(c *Config) {
+				c.Slaves.Randomize = NominalRandomization{Enabled: true, VoltageSpread: -1}
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid units",
+			modify: func(c *Config) {
+				c.Slaves.Units = []UnitConfig{{UnitID: 1}, {UnitID: 2, Profile: "three_phase"}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "duplicate unit ID",
+			modify: func(c *Config) {
+				c.Slaves.Units = []UnitConfig{{UnitID: 1}, {UnitID: 1}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "unit ID out of range",
+			modify: func(c *Config) {
+				c.Slaves.Units = []UnitConfig{{UnitID: 248}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid unknown unit behavior",
+			modify: func(c *Config) {
+				c.Slaves.UnknownUnit = "reject"
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative downstream timeout",
+			modify: func(c *Config) {
+				c.Slaves.UnknownUnit = UnknownUnitGatewayException
+				c.Slaves.DownstreamTimeout = -time.Second
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative request rate limit",
+			modify: func(c *Config) {
+				c.Slaves.RequestRateLimit = -1
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative slaves per agent",
+			modify: func(c *Config) {
+				c.Cluster.SlavesPerAgent = -1
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid coordinator address",
+			modify: func(c *Config) {
+				c.Cluster.Coordinator = "coordinator"
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid slave identity",
+			modify: func(c *Config) {
+				c.Slaves.Identity = "hostname"
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative ramp up rate",
+			modify: func(c *Config) {
+				c.Slaves.RampUpRate = -1
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid ramp rates",
+			modify: func(c *Config) {
+				c.Slaves.RampUpRate = 50
+				c.Slaves.RampDownRate = 100
+				c.Network.AnnounceRate = 200
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid network mode",
+			modify: func(c *Config) {
+				c.Network.Mode = "bridge"
+			},
+			wantErr: true,
+		},
+		{
+			name: "network link name too long",
+			modify: func(c *Config) {
+				c.Network.Mode = NetworkModeDummy
+				c.Network.Link = "modbussimulator0"
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid network MTU",
+			modify: func(c *Config) {
+				c.Network.Mode = NetworkModeMacvlan
+				c.Network.MTU = 10
+			},
+			wantErr: true,
+		},
+		{
+			name: "macvlan without parent",
+			modify: func(c *Config) {
+				c.Network.Interface = ""
+				c.Network.Mode = NetworkModeMacvlan
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid dummy network",
+			modify: func(c *Config) {
+				c.Network.Mode = NetworkModeDummy
+				c.Network.MTU = 9000
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown unit profile",
+			modify: func(c *Config) {
+				c.Slaves.Units = []UnitConfig{{UnitID: 1}, {UnitID: 2, Profile: "nonexistent"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative scenario workers",
+			modify: func(c *Config) {
+				c.Scenario.Workers = -1
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative scenario batch size",
+			modify: func(c *Config) {
+				c.Scenario.BatchSize = -1
+			},
+			wantErr: true,
+		},
+		{
+			name: "independent register sharing",
+			modify: func(c *Config) {
+				c.Slaves.RegisterSharing = RegisterSharingIndependent
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid register sharing",
+			modify: func(c *Config) {
+				c.Slaves.RegisterSharing = "copy"
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative per-slave metrics cap",
+			modify: func(c *Config) {
+				c.Metrics.PerSlave.MaxSlaves = -1
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid scenario plugin address",
+			modify: func(c *Config) {
+				params := c.Scenario.Scenarios["plugin"]
+				params.Plugin = "localhost"
+				c.Scenario.Scenarios["plugin"] = params
+			},
+			wantErr: true,
+		},
+		{
+			name: "protection window with tag",
+			modify: func(c *Config) {
+				c.Slaves.Tags = map[string][]string{"billing": {"192.168.1.0/28"}}
+				c.Protection.Windows = []ProtectedWindow{{Name: "nightly", Start: "01:00", End: "03:00", Tags: []string{"billing"}}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "protection window invalid time",
+			modify: func(c *Config) {
+				c.Protection.Windows = []ProtectedWindow{{Name: "nightly", Start: "25:00", End: "03:00"}}
 			},
 			wantErr: true,
 		},
 		{
-			name: "invalid slave count - too high",
+			name: "protection unknown tag",
 			modify: func(c *Config) {
-				c.Slaves.Count = 20000
+				c.Protection.Tags = []string{"critical"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown freeze register",
+			modify: func(c *Config) {
+				c.Scenario.Scenarios["data_freeze"] = ScenarioParams{FreezeRegisters: []string{"VoltageA"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid failure domain",
+			modify: func(c *Config) {
+				c.Slaves.Tags = map[string][]string{"gw1": {"192.168.1.0/28"}}
+				c.FailureDomains = []FailureDomain{{Name: "gw1", Tags: []string{"gw1"}, OutageInterval: time.Hour}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "failure domain without members",
+			modify: func(c *Config) {
+				c.FailureDomains = []FailureDomain{{Name: "gw1"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate failure domain",
+			modify: func(c *Config) {
+				c.FailureDomains = []FailureDomain{
+					{Name: "gw1", Targets: []string{"192.168.1.0/28"}},
+					{Name: "gw1", Targets: []string{"192.168.1.16/28"}},
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "tracing invalid sample rate",
+			modify: func(c *Config) {
+				c.Tracing.SampleRate = 1.5
+			},
+			wantErr: true,
+		},
+		{
+			name: "tracing invalid endpoint",
+			modify: func(c *Config) {
+				c.Tracing.Endpoint = "localhost:4318"
+			},
+			wantErr: true,
+		},
+		{
+			name: "english language",
+			modify: func(c *Config) {
+				c.Language = "en_US.UTF-8"
+			},
+			wantErr: false,
+		},
+		{
+			name: "unsupported language",
+			modify: func(c *Config) {
+				c.Language = "fr"
 			},
 			wantErr: true,
 		},
@@ -110,6 +1082,16 @@ func TestIPRange_Validate(t *testing.T) {
 			r:       IPRange{},
 			wantErr: true,
 		},
+		{
+			name:    "udp transport",
+			r:       IPRange{CIDR: "192.168.1.0/24", Transport: TransportUDP},
+			wantErr: false,
+		},
+		{
+			name:    "unknown transport",
+			r:       IPRange{CIDR: "192.168.1.0/24", Transport: "rtu"},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -182,6 +1164,607 @@ func TestConfig_SaveAndLoad(t *testing.T) {
 	assert.Equal(t, cfg.Server.Port, loadedCfg.Server.Port)
 }
 
+func TestLoadConfig_RegistersFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(tmpDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	write("common/power.yaml", `
+registers:
+  - {address: 40001, name: LineVoltage, data_type: uint16, scale: 10, unit: V}
+  - {address: 40002, name: LineCurrent, data_type: uint16, scale: 100, unit: A}
+`)
+	// 廠商對應檔引用共用定義並覆寫電流的倍率
+	write("meters/acuvim.yaml", `
+include: [../common/power.yaml]
+registers:
+  - {address: 40002, name: LineCurrent, data_type: uint16, scale: 1000, unit: A}
+  - {address: 40010, name: TotalEnergy, data_type: uint32, scale: 1, unit: kWh}
+`)
+	write("config.json", `{"slaves": {"count": 1, "registers_file": "meters/acuvim.yaml"}}`)
+
+	cfg, err := LoadConfig(filepath.Join(tmpDir, "config.json"))
+	require.NoError(t, err)
+	require.Len(t, cfg.Slaves.DefaultRegisters, 3, "未設定 default_registers 時不混入內建預設")
+	assert.Equal(t, "LineVoltage", cfg.Slaves.DefaultRegisters[0].Name)
+	assert.Equal(t, 1000.0, cfg.Slaves.DefaultRegisters[1].Scale)
+	assert.Equal(t, uint16(40010), cfg.Slaves.DefaultRegisters[2].Address)
+
+	// 配置檔的 default_registers 覆寫相同位址
+	write("config.json", `{"slaves": {"count": 1, "registers_file": "meters/acuvim.yaml",
+		"default_registers": [{"address": 40001, "name": "PhaseVoltage", "data_type": "uint16", "scale": 100, "unit": "V"}]}}`)
+	cfg, err = LoadConfig(filepath.Join(tmpDir, "config.json"))
+	require.NoError(t, err)
+	require.Len(t, cfg.Slaves.DefaultRegisters, 3)
+	assert.Equal(t, "PhaseVoltage", cfg.Slaves.DefaultRegisters[0].Name)
+
+	// 循環引用與合併後的重疊皆拒絕
+	write("common/power.yaml", "include: [../meters/acuvim.yaml]\n")
+	_, err = LoadConfig(filepath.Join(tmpDir, "config.json"))
+	assert.ErrorContains(t, err, "循環")
+
+	write("common/power.yaml", `
+registers:
+  - {address: 40009, name: Demand, data_type: uint32, scale: 1, unit: W}
+`)
+	_, err = LoadConfig(filepath.Join(tmpDir, "config.json"))
+	assert.ErrorContains(t, err, "acuvim.yaml", "錯誤指出定義所在的檔案")
+}
+
+func TestNetworkConfig_InterfaceFor(t *testing.T) {
+	network := NetworkConfig{
+		Interface: "eth0",
+		IPRanges: []IPRange{
+			{CIDR: "192.168.1.0/24"},
+			{Start: "10.10.0.11", End: "10.10.0.60", Interface: "eth1"},
+			{CIDR: "172.16.5.0/25", Interface: "bond0"},
+		},
+	}
+
+	name, explicit := network.InterfaceFor(net.ParseIP("10.10.0.60"))
+	assert.Equal(t, "eth1", name)
+	assert.True(t, explicit)
+
+	name, explicit = network.InterfaceFor(net.ParseIP("172.16.5.100"))
+	assert.Equal(t, "bond0", name)
+	assert.True(t, explicit)
+
+	// 未指定介面的範圍與範圍外的 IP 使用預設介面
+	name, explicit = network.InterfaceFor(net.ParseIP("192.168.1.5"))
+	assert.Equal(t, "eth0", name)
+	assert.False(t, explicit)
+	name, _ = network.InterfaceFor(net.ParseIP("10.10.0.61"))
+	assert.Equal(t, "eth0", name)
+
+	assert.Equal(t, []string{"eth0", "eth1", "bond0"}, network.Interfaces())
+}
+
+func TestNetworkConfig_DedicatedLink(t *testing.T) {
+	network := NetworkConfig{Interface: "eth0"}
+	assert.Empty(t, network.DedicatedLink(), "alias 模式不建立專用介面")
+
+	network.Mode = NetworkModeDummy
+	assert.Equal(t, DefaultNetworkLink, network.DedicatedLink())
+
+	network.Mode, network.Link = NetworkModeMacvlan, "mbs0"
+	assert.Equal(t, "mbs0", network.DedicatedLink())
+	assert.Equal(t, "eth0", network.ParentInterface())
+	network.Parent = "bond0"
+	assert.Equal(t, "bond0", network.ParentInterface())
+
+	// 未指定介面的範圍配置在專用介面上
+	provisioner, ok := NewNetworkProvisioner(network, nil).(interface{ network() NetworkConfig })
+	require.True(t, ok)
+	assert.Equal(t, "mbs0", provisioner.network().Interface)
+}
+
+func TestAnnouncePackets(t *testing.T) {
+	mac := net.HardwareAddr{0x02, 0xfc, 0, 0, 0, 1}
+
+	// gratuitous ARP: 廣播的 ARP request，sender 與 target 皆為宣告的 IP
+	frame := gratuitousARP(mac, net.ParseIP("192.168.1.105"))
+	require.Len(t, frame, 42)
+	assert.Equal(t, []byte(broadcastMAC), frame[0:6])
+	assert.Equal(t, []byte(mac), frame[6:12])
+	assert.Equal(t, []byte{0x08, 0x06}, frame[12:14])
+	assert.Equal(t, []byte{0, 1, 0x08, 0x00, 6, 4, 0, 1}, frame[14:22])
+	assert.Equal(t, []byte(mac), frame[22:28])
+	assert.Equal(t, []byte{192, 168, 1, 105}, frame[28:32])
+	assert.Equal(t, make([]byte, 6), frame[32:38])
+	assert.Equal(t, []byte{192, 168, 1, 105}, frame[38:42])
+
+	// unsolicited NA: Override 旗標與目標鏈路層位址選項
+	ip := net.ParseIP("fd00::105")
+	msg := unsolicitedNA(mac, ip)
+	require.Len(t, msg, 32)
+	assert.Equal(t, []byte{136, 0, 0, 0, 0x20, 0, 0, 0}, msg[0:8])
+	assert.Equal(t, []byte(ip), msg[8:24])
+	assert.Equal(t, append([]byte{2, 1}, mac...), msg[24:32])
+}
+
+func TestNetworkState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "network-state.json")
+
+	// 狀態檔不存在時為空的狀態
+	state, err := loadNetworkState(path)
+	require.NoError(t, err)
+	assert.Empty(t, state.Addresses)
+
+	state.add(net.ParseIP("192.168.1.101"), "eth0")
+	state.add(net.ParseIP("10.10.0.11"), "eth1")
+	state.add(net.ParseIP("192.168.1.101"), "modbussim0")
+	require.NoError(t, state.save(path))
+
+	loaded, err := loadNetworkState(path)
+	require.NoError(t, err)
+	assert.Equal(t, []provisionedAddress{
+		{IP: "192.168.1.101", Interface: "modbussim0"},
+		{IP: "10.10.0.11", Interface: "eth1"},
+	}, loaded.Addresses)
+
+	// 全部移除後刪除狀態檔
+	loaded.remove(net.ParseIP("192.168.1.101"))
+	loaded.remove(net.ParseIP("10.10.0.11"))
+	require.NoError(t, loaded.save(path))
+	assert.NoFileExists(t, path)
+
+	assert.Equal(t, "eth0:mbs", ownerLabel("eth0"))
+	assert.Empty(t, ownerLabel("enp0s31f6.1000"), "超過 15 字元不標記")
+}
+
+func TestCommandProvisioner_Commands(t *testing.T) {
+	v4, v6 := net.ParseIP("192.168.1.101"), net.ParseIP("fd00::101")
+	tests := []struct {
+		name    string
+		command func(iface string, ip net.IP) []string
+		iface   string
+		ip      net.IP
+		want    []string
+	}{
+		{"darwin add ipv4", ifconfigAddCommand, "en0", v4,
+			[]string{"ifconfig", "en0", "alias", "192.168.1.101", "255.255.255.255"}},
+		{"darwin add ipv6", ifconfigAddCommand, "en0", v6,
+			[]string{"ifconfig", "en0", "inet6", "fd00::101", "prefixlen", "128", "alias"}},
+		{"darwin remove ipv4", ifconfigRemoveCommand, "en0", v4,
+			[]string{"ifconfig", "en0", "-alias", "192.168.1.101"}},
+		{"darwin remove ipv6", ifconfigRemoveCommand, "en0", v6,
+			[]string{"ifconfig", "en0", "inet6", "fd00::101", "-alias"}},
+		{"windows add ipv4", netshAddCommand, "Ethernet 2", v4,
+			[]string{"netsh", "interface", "ipv4", "add", "address", "Ethernet 2", "192.168.1.101", "255.255.255.255", "store=active", "skipassource=true"}},
+		{"windows add ipv6", netshAddCommand, "Ethernet 2", v6,
+			[]string{"netsh", "interface", "ipv6", "add", "address", "Ethernet 2", "fd00::101", "store=active", "skipassource=true"}},
+		{"windows remove ipv4", netshRemoveCommand, "Ethernet 2", v4,
+			[]string{"netsh", "interface", "ipv4", "delete", "address", "Ethernet 2", "192.168.1.101", "store=active"}},
+		{"windows remove ipv6", netshRemoveCommand, "Ethernet 2", v6,
+			[]string{"netsh", "interface", "ipv6", "delete", "address", "Ethernet 2", "fd00::101", "store=active"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.command(tt.iface, tt.ip))
+		})
+	}
+}
+
+// newTestCommandProvisioner 以記錄指令的假執行器取代系統指令的 CommandProvisioner；
+// live 為各介面目前的 IP (成功的指令會更新)，fail 為要失敗的指令
+func newTestCommandProvisioner(stateFile string, live map[string]map[string]bool, fail map[string]bool) (*CommandProvisioner, *[]string) {
+	var calls []string
+	p := &CommandProvisioner{
+		BaseProvisioner: BaseProvisioner{InterfaceName: "eth0", Logger: zap.NewNop(), StateFile: stateFile},
+		addCommand: func(iface string, ip net.IP) []string {
+			return []string{"add", iface, ip.String()}
+		},
+		removeCommand: func(iface string, ip net.IP) []string {
+			return []string{"remove", iface, ip.String()}
+		},
+		run: func(ctx context.Context, args []string) error {
+			command := strings.Join(args, " ")
+			calls = append(calls, command)
+			if fail[command] {
+				return errors.New("exit status 1")
+			}
+			if live[args[1]] == nil {
+				live[args[1]] = make(map[string]bool)
+			}
+			live[args[1]][args[2]] = args[0] == "add"
+			return nil
+		},
+		addrs: func(name string) (map[string]bool, error) {
+			addrs, ok := live[name]
+			if !ok {
+				return nil, fmt.Errorf("找不到網路介面 %s", name)
+			}
+			return addrs, nil
+		},
+	}
+	return p, &calls
+}
+
+func TestCommandProvisioner_Reconcile(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "network-state.json")
+	ipStrings := func(ips []net.IP) []string {
+		var out []string
+		for _, ip := range ips {
+			out = append(out, ip.String())
+		}
+		return out
+	}
+	stateIPs := func() []string {
+		state, err := loadNetworkState(path)
+		require.NoError(t, err)
+		var out []string
+		for _, addr := range state.Addresses {
+			out = append(out, addr.IP)
+		}
+		return out
+	}
+
+	// 狀態檔中已有一個不存在的 IP (例如重開機後)
+	stale := &networkState{}
+	stale.add(net.ParseIP("192.168.1.120"), "eth0")
+	require.NoError(t, stale.save(path))
+
+	// .101 原本就存在，.103 新增失敗：其餘 IP 仍會配置，只記錄實際新增的 IP
+	live := map[string]map[string]bool{"eth0": {"192.168.1.101": true}}
+	fail := map[string]bool{"add eth0 192.168.1.103": true}
+	p, calls := newTestCommandProvisioner(path, live, fail)
+	require.NoError(t, p.Setup(ctx, []IPRange{{Start: "192.168.1.101", End: "192.168.1.104"}}))
+	assert.Equal(t, []string{
+		"add eth0 192.168.1.102",
+		"add eth0 192.168.1.103",
+		"add eth0 192.168.1.104",
+	}, *calls)
+	assert.Equal(t, []string{"192.168.1.101", "192.168.1.102", "192.168.1.104"}, ipStrings(p.ConfiguredIPs))
+	assert.Equal(t, []string{"192.168.1.120", "192.168.1.102", "192.168.1.104"}, stateIPs())
+
+	// 網路介面不存在時不執行任何指令
+	*calls = nil
+	err := p.Setup(ctx, []IPRange{{Start: "10.10.0.1", End: "10.10.0.2", Interface: "eth9"}})
+	assert.ErrorContains(t, err, "eth9")
+	assert.Empty(t, *calls)
+
+	// 另一個程序 (network list/teardown) 依狀態檔比對：不列出已不存在的 IP
+	other, calls := newTestCommandProvisioner(path, live, map[string]bool{"remove eth0 192.168.1.104": true})
+	ips, err := other.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"192.168.1.102", "192.168.1.104"}, ipStrings(ips))
+
+	// teardown：移除失敗的 IP 保留在狀態檔，已不存在的 IP 只清除記錄
+	require.NoError(t, other.Teardown(ctx))
+	assert.Equal(t, []string{
+		"remove eth0 192.168.1.102",
+		"remove eth0 192.168.1.104",
+	}, *calls)
+	assert.Equal(t, []string{"192.168.1.104"}, stateIPs())
+	assert.True(t, live["eth0"]["192.168.1.101"], "原本就存在的 IP 不移除")
+	assert.False(t, live["eth0"]["192.168.1.102"])
+}
+
+func TestCommandProvisioner_RemoveFailure(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "network-state.json")
+
+	live := map[string]map[string]bool{"eth0": {}}
+	fail := map[string]bool{"remove eth0 192.168.1.102": true}
+	p, calls := newTestCommandProvisioner(path, live, fail)
+	require.NoError(t, p.Setup(ctx, []IPRange{{Start: "192.168.1.101", End: "192.168.1.103"}}))
+
+	// 中途失敗時回傳錯誤並停止，已移除的 IP 從狀態檔與已配置清單中清除
+	*calls = nil
+	err := p.Remove(ctx, net.ParseIP("192.168.1.101"), net.ParseIP("192.168.1.102"), net.ParseIP("192.168.1.103"))
+	assert.ErrorContains(t, err, "192.168.1.102")
+	assert.Equal(t, []string{
+		"remove eth0 192.168.1.101",
+		"remove eth0 192.168.1.102",
+	}, *calls)
+	assert.Len(t, p.ConfiguredIPs, 2)
+	assert.True(t, p.ConfiguredIPs[0].Equal(net.ParseIP("192.168.1.102")))
+
+	state, err := loadNetworkState(path)
+	require.NoError(t, err)
+	assert.Equal(t, []provisionedAddress{
+		{IP: "192.168.1.102", Interface: "eth0"},
+		{IP: "192.168.1.103", Interface: "eth0"},
+	}, state.Addresses)
+}
+
+func TestGenerateCompose(t *testing.T) {
+	opts := DefaultComposeOptions()
+	opts.Count = 12
+	opts.Subnet = "10.20.0.0/28"
+	opts.StartIP = "10.20.0.1"
+	opts.Gateway = "10.20.0.5"
+
+	data, err := GenerateCompose(opts)
+	require.NoError(t, err)
+	out := string(data)
+
+	// 略過閘道，服務名稱依數量補零
+	assert.Contains(t, out, "  modbussim-01:\n")
+	assert.Contains(t, out, "  modbussim-12:\n")
+	assert.Contains(t, out, "ipv4_address: 10.20.0.4\n")
+	assert.NotContains(t, out, "ipv4_address: 10.20.0.5\n")
+	assert.Contains(t, out, "ipv4_address: 10.20.0.13\n")
+	assert.Contains(t, out, `"--ip", "10.20.0.13", "--count", "1"`)
+	assert.Contains(t, out, "gateway: 10.20.0.5\n")
+
+	// 可用位址不足 (10.20.0.15 為廣播位址)
+	opts.Count = 14
+	_, err = GenerateCompose(opts)
+	assert.Error(t, err)
+
+	opts = DefaultComposeOptions()
+	opts.Subnet = "10.20.0.0/24"
+	opts.Gateway = "10.30.0.1"
+	_, err = GenerateCompose(opts)
+	assert.Error(t, err, "閘道不在網段內")
+}
+
+func TestGenerateK8s(t *testing.T) {
+	opts := DefaultK8sOptions()
+	opts.Replicas = 3
+	opts.Namespace = "sim"
+
+	data, err := GenerateK8s(opts)
+	require.NoError(t, err)
+	out := string(data)
+	assert.Contains(t, out, "kind: StatefulSet\n")
+	assert.Contains(t, out, "  replicas: 3\n")
+	assert.Contains(t, out, "  clusterIP: None\n")
+	assert.Contains(t, out, `"--identity", "pod", "--count", "1"`)
+	assert.Contains(t, out, "fieldPath: status.podIP\n")
+	assert.NotContains(t, out, "NetworkAttachmentDefinition")
+
+	// Multus 附加網路：自網段的第一個可用位址起配置，排除閘道
+	opts.Subnet = "10.20.0.0/24"
+	opts.Gateway = "10.20.0.1"
+	data, err = GenerateK8s(opts)
+	require.NoError(t, err)
+	out = string(data)
+	assert.Contains(t, out, "kind: NetworkAttachmentDefinition\n")
+	assert.Contains(t, out, "k8s.v1.cni.cncf.io/networks: modbussim-macvlan\n")
+	assert.Contains(t, out, `\"range_start\":\"10.20.0.2\"`)
+	assert.Contains(t, out, `\"exclude\":[\"10.20.0.1/32\"]`)
+	assert.Contains(t, out, "value: net1\n")
+
+	opts.RangeEnd = "10.20.0.3"
+	_, err = GenerateK8s(opts)
+	assert.Error(t, err, "配置範圍不足 3 個位址")
+
+	opts = DefaultK8sOptions()
+	opts.Name = "Modbus_Sim"
+	_, err = GenerateK8s(opts)
+	assert.Error(t, err, "名稱不符合 DNS-1123")
+}
+
+func TestGenerateSystemd(t *testing.T) {
+	opts := DefaultSystemdOptions()
+	opts.User = "modbus"
+	opts.SetupNetwork = true
+	opts.Snapshot = "/var/lib/modbus sim/state.json"
+	opts.StartTimeout = 90 * time.Second
+
+	data, err := GenerateSystemd(opts)
+	require.NoError(t, err)
+	out := string(data)
+	assert.Contains(t, out, "Type=notify\n")
+	assert.Contains(t, out, "ExecStart=/usr/local/bin/modbussim start -c /etc/modbussim/config.json --setup-network --user modbus --snapshot \"/var/lib/modbus sim/state.json\"\n")
+	assert.Contains(t, out, "TimeoutStartSec=90s\n")
+
+	opts = DefaultSystemdOptions()
+	opts.Config = "config.json"
+	_, err = GenerateSystemd(opts)
+	assert.Error(t, err, "相對路徑")
+
+	opts = DefaultSystemdOptions()
+	opts.Name = "modbus sim"
+	_, err = GenerateSystemd(opts)
+	assert.Error(t, err, "unit 名稱含空白")
+}
+
+func TestGenerateGrafana(t *testing.T) {
+	data, err := GenerateGrafana(DefaultGrafanaOptions())
+	require.NoError(t, err)
+
+	var dashboard struct {
+		UID    string `json:"uid"`
+		Panels []struct {
+			ID      int    `json:"id"`
+			Type    string `json:"type"`
+			Targets []struct {
+				Expr string `json:"expr"`
+			} `json:"targets"`
+		} `json:"panels"`
+		Annotations struct {
+			List []struct {
+				Expr string `json:"expr"`
+			} `json:"list"`
+		} `json:"annotations"`
+	}
+	require.NoError(t, json.Unmarshal(data, &dashboard))
+	assert.Equal(t, "modbussim", dashboard.UID)
+	require.Len(t, dashboard.Annotations.List, 1)
+
+	// 查詢引用的指標都必須由 MetricsCollector 輸出 (直方圖另有 _bucket 序列)
+	descs := make(chan *prometheus.Desc, 128)
+	(&MetricsCollector{}).Describe(descs)
+	close(descs)
+	exported := make(map[string]bool)
+	for desc := range descs {
+		name := regexp.MustCompile(`fqName: "(\w+)"`).FindStringSubmatch(desc.String())[1]
+		exported[name] = true
+		exported[name+"_bucket"] = true
+	}
+
+	exprs := []string{dashboard.Annotations.List[0].Expr}
+	ids := make(map[int]bool)
+	hasSlavePanels := false
+	for _, panel := range dashboard.Panels {
+		assert.False(t, ids[panel.ID], "面板 ID 重複: %d", panel.ID)
+		ids[panel.ID] = true
+		for _, target := range panel.Targets {
+			exprs = append(exprs, target.Expr)
+			hasSlavePanels = hasSlavePanels || strings.Contains(target.Expr, "modbussim_slave_")
+		}
+	}
+	assert.True(t, hasSlavePanels)
+	for _, expr := range exprs {
+		for _, name := range regexp.MustCompile(`modbussim_\w+`).FindAllString(expr, -1) {
+			assert.True(t, exported[name], "未輸出的指標 %s (%s)", name, expr)
+		}
+	}
+
+	opts := DefaultGrafanaOptions()
+	opts.PerSlave = false
+	data, err = GenerateGrafana(opts)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "modbussim_slave_")
+
+	opts.UID = "modbus sim"
+	_, err = GenerateGrafana(opts)
+	assert.Error(t, err, "UID 含空白")
+}
+
+func TestSdNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	sent, err := sdNotify("READY=1")
+	require.NoError(t, err)
+	assert.False(t, sent, "未由 systemd 啟動時不通知")
+
+	dir, err := os.MkdirTemp("", "sd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	t.Setenv("NOTIFY_SOCKET", path)
+	sent, err = sdNotify("READY=1")
+	require.NoError(t, err)
+	assert.True(t, sent)
+
+	buf := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "READY=1", string(buf[:n]))
+}
+
+func TestPodIdentityFromEnv(t *testing.T) {
+	env := map[string]string{
+		"POD_NAME":      "modbussim-7",
+		"POD_NAMESPACE": "sim",
+		"POD_IP":        "10.244.1.23",
+	}
+	id, err := PodIdentityFromEnv(func(key string) string { return env[key] })
+	require.NoError(t, err)
+	assert.Equal(t, 7, id.Ordinal)
+	assert.Equal(t, "10.244.1.23", id.IP.String())
+	assert.Equal(t, "sim/modbussim-7", id.seedKey())
+
+	// 未注入 POD_NAME 時沿用 HOSTNAME
+	delete(env, "POD_NAME")
+	env["HOSTNAME"] = "modbussim-0"
+	id, err = PodIdentityFromEnv(func(key string) string { return env[key] })
+	require.NoError(t, err)
+	assert.Equal(t, 0, id.Ordinal)
+
+	env["HOSTNAME"] = "modbussim-abc"
+	_, err = PodIdentityFromEnv(func(key string) string { return env[key] })
+	assert.Error(t, err, "不是 StatefulSet 的 Pod 名稱")
+
+	env["HOSTNAME"] = "modbussim-1"
+	delete(env, "POD_IP")
+	_, err = PodIdentityFromEnv(func(key string) string { return env[key] })
+	assert.Error(t, err)
+}
+
+func TestMatchTargets(t *testing.T) {
+	ip := net.ParseIP("192.168.1.105")
+
+	assert.True(t, MatchTargets(ip, nil), "空清單應全部符合")
+	assert.True(t, MatchTargets(ip, []string{"192.168.1.105"}))
+	assert.True(t, MatchTargets(ip, []string{"10.0.0.1", "192.168.1.0/24"}))
+	assert.False(t, MatchTargets(ip, []string{"192.168.2.0/24", "192.168.1.106"}))
+}
+
+func TestProtectedWindow_Contains(t *testing.T) {
+	at := func(clock string) time.Time {
+		tm, _ := time.Parse("15:04", clock)
+		return tm
+	}
+
+	billing := ProtectedWindow{Name: "billing", Start: "01:00", End: "03:30"}
+	assert.True(t, billing.contains(at("01:00")))
+	assert.True(t, billing.contains(at("03:29")))
+	assert.False(t, billing.contains(at("03:30")))
+	assert.False(t, billing.contains(at("12:00")))
+
+	// 跨午夜
+	overnight := ProtectedWindow{Name: "overnight", Start: "23:00", End: "02:00"}
+	assert.True(t, overnight.contains(at("23:30")))
+	assert.True(t, overnight.contains(at("01:59")))
+	assert.False(t, overnight.contains(at("02:00")))
+}
+
+func TestLanguage(t *testing.T) {
+	defer SetLanguage(LangZhTW)
+
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "en_US.UTF-8")
+	SetLanguage(LangAuto)
+	assert.Equal(t, LangEn, Language())
+	assert.Equal(t, "invalid port: 0", fmt.Sprintf(T("無效的埠號: %d"), 0))
+	assert.Equal(t, "未收錄的訊息", T("未收錄的訊息"), "未收錄的訊息應沿用原文")
+
+	t.Setenv("LANG", "zh_TW.UTF-8")
+	SetLanguage(LangAuto)
+	assert.Equal(t, LangZhTW, Language())
+	assert.Equal(t, "無效的埠號: 0", fmt.Sprintf(T("無效的埠號: %d"), 0))
+
+	SetLanguage("en")
+	assert.Equal(t, LangEn, Language())
+}
+
+func TestScanLanguageFlag(t *testing.T) {
+	assert.Equal(t, "en", scanLanguageFlag([]string{"start", "--lang=en"}))
+	assert.Equal(t, "zh-TW", scanLanguageFlag([]string{"--lang", "zh-TW", "start"}))
+	assert.Equal(t, LangAuto, scanLanguageFlag([]string{"start", "--", "--lang=en"}))
+}
+
+func TestPIDFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "modbussim.pid")
+
+	require.NoError(t, writePIDFile(path))
+	pid, err := readPIDFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), pid)
+	removePIDFile(path)
+	assert.NoFileExists(t, path)
+
+	// 指向執行中的其他程序時拒絕啟動，且不移除他人的 PID 檔案
+	require.NoError(t, os.WriteFile(path, []byte(strconv.Itoa(os.Getppid())+"\n"), 0644))
+	assert.Error(t, writePIDFile(path))
+	removePIDFile(path)
+	assert.FileExists(t, path)
+
+	_, err = readPIDFile(filepath.Join(t.TempDir(), "missing.pid"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRestartArgs(t *testing.T) {
+	assert.Equal(t, []string{"-c", "sim.json", "start", "--daemon", "--count", "50"},
+		restartArgs([]string{"-c", "sim.json", "restart", "--wait", "10s", "--count", "50"}))
+	assert.Equal(t, []string{"start", "--daemon", "--pid-file", "/tmp/sim.pid"},
+		restartArgs([]string{"restart", "--pid-file", "/tmp/sim.pid", "--wait=5s"}))
+}
+
 func TestIncIP(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -201,3 +1784,143 @@ func TestIncIP(t *testing.T) {
 		})
 	}
 }
+
+func TestParseCapabilities(t *testing.T) {
+	caps, err := parseCapabilities([]string{"net_bind_service", "net_raw"})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{10, 13}, caps)
+
+	_, err = parseCapabilities([]string{"sys_admin"})
+	assert.Error(t, err)
+
+	assert.Equal(t, [2]uint32{1<<10 | 1<<12, 0}, capabilityMasks([]uint{10, 12}))
+	assert.Equal(t, [2]uint32{0, 1 << 1}, capabilityMasks([]uint{33}), "第 32 個以後的 capability 在第二組")
+}
+
+// testPrivilegeLookup 只認得 modbus (1000) 與 root 使用者、dialout (20) 群組
+func testPrivilegeLookup() privilegeLookup {
+	users := map[string]*user.User{
+		"modbus": {Username: "modbus", Uid: "1000", Gid: "1000"},
+		"root":   {Username: "root", Uid: "0", Gid: "0"},
+	}
+	groups := map[string]*user.Group{"dialout": {Name: "dialout", Gid: "20"}}
+	return privilegeLookup{
+		lookupUser: func(name string) (*user.User, error) {
+			if u, ok := users[name]; ok {
+				return u, nil
+			}
+			return nil, user.UnknownUserError(name)
+		},
+		lookupUserID: func(uid string) (*user.User, error) {
+			for _, u := range users {
+				if u.Uid == uid {
+					return u, nil
+				}
+			}
+			return nil, user.UnknownUserIdError(0)
+		},
+		lookupGroup: func(name string) (*user.Group, error) {
+			if g, ok := groups[name]; ok {
+				return g, nil
+			}
+			return nil, user.UnknownGroupError(name)
+		},
+		lookupGroupID: func(gid string) (*user.Group, error) {
+			for _, g := range groups {
+				if g.Gid == gid {
+					return g, nil
+				}
+			}
+			return nil, user.UnknownGroupIdError(gid)
+		},
+	}
+}
+
+func TestResolvePrivileges(t *testing.T) {
+	lookup := testPrivilegeLookup()
+
+	tests := []struct {
+		name    string
+		cfg     PrivilegeConfig
+		want    privilegeTarget
+		wantErr bool
+	}{
+		{"user name", PrivilegeConfig{User: "modbus"}, privilegeTarget{UID: 1000, GID: 1000, Caps: []uint{}}, false},
+		{"user id", PrivilegeConfig{User: "1000"}, privilegeTarget{UID: 1000, GID: 1000, Caps: []uint{}}, false},
+		{"unknown numeric user", PrivilegeConfig{User: "2000"}, privilegeTarget{UID: 2000, GID: 2000, Caps: []uint{}}, false},
+		{"group override", PrivilegeConfig{User: "modbus", Group: "dialout"}, privilegeTarget{UID: 1000, GID: 20, Caps: []uint{}}, false},
+		{"numeric group", PrivilegeConfig{User: "modbus", Group: "30"}, privilegeTarget{UID: 1000, GID: 30, Caps: []uint{}}, false},
+		{"capabilities", PrivilegeConfig{User: "modbus", Capabilities: []string{"net_bind_service"}},
+			privilegeTarget{UID: 1000, GID: 1000, Caps: []uint{10}}, false},
+		{"root", PrivilegeConfig{User: "root"}, privilegeTarget{}, true},
+		{"uid zero", PrivilegeConfig{User: "0"}, privilegeTarget{}, true},
+		{"unknown user", PrivilegeConfig{User: "nobody"}, privilegeTarget{}, true},
+		{"unknown group", PrivilegeConfig{User: "modbus", Group: "wheel"}, privilegeTarget{}, true},
+		{"unknown capability", PrivilegeConfig{User: "modbus", Capabilities: []string{"sys_admin"}}, privilegeTarget{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolvePrivileges(tt.cfg, lookup)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// recordingPrivilegeOps 記錄降級的系統呼叫順序，fail 指定的呼叫回傳錯誤
+type recordingPrivilegeOps struct {
+	calls []string
+	fail  string
+}
+
+func (r *recordingPrivilegeOps) record(call string) error {
+	r.calls = append(r.calls, call)
+	if call == r.fail {
+		return errors.New("operation not permitted")
+	}
+	return nil
+}
+
+func (r *recordingPrivilegeOps) KeepCaps(keep bool) error {
+	return r.record(fmt.Sprintf("keepcaps %v", keep))
+}
+
+func (r *recordingPrivilegeOps) Setgroups(gids []int) error {
+	return r.record(fmt.Sprintf("setgroups %v", gids))
+}
+
+func (r *recordingPrivilegeOps) Setgid(gid int) error {
+	return r.record(fmt.Sprintf("setgid %d", gid))
+}
+
+func (r *recordingPrivilegeOps) Setuid(uid int) error {
+	return r.record(fmt.Sprintf("setuid %d", uid))
+}
+
+func (r *recordingPrivilegeOps) Capset(masks [2]uint32) error {
+	return r.record(fmt.Sprintf("capset %#x %#x", masks[0], masks[1]))
+}
+
+func TestApplyPrivilegeDrop(t *testing.T) {
+	// 保留 capability：先設定 KEEPCAPS，群組在 UID 之前切換，最後才縮減 capability
+	ops := &recordingPrivilegeOps{}
+	require.NoError(t, applyPrivilegeDrop(ops, privilegeTarget{UID: 1000, GID: 20, Caps: []uint{10}}))
+	assert.Equal(t, []string{
+		"keepcaps true", "setgroups [20]", "setgid 20", "setuid 1000", "capset 0x400 0x0", "keepcaps false",
+	}, ops.calls)
+
+	// 不保留 capability：不使用 KEEPCAPS 與 capset
+	ops = &recordingPrivilegeOps{}
+	require.NoError(t, applyPrivilegeDrop(ops, privilegeTarget{UID: 1000, GID: 1000}))
+	assert.Equal(t, []string{"setgroups [1000]", "setgid 1000", "setuid 1000"}, ops.calls)
+
+	// 切換群組失敗時不再切換 UID (避免以原本的群組繼續運行)
+	ops = &recordingPrivilegeOps{fail: "setgid 20"}
+	err := applyPrivilegeDrop(ops, privilegeTarget{UID: 1000, GID: 20})
+	assert.ErrorContains(t, err, "setgid")
+	assert.Equal(t, []string{"setgroups [20]", "setgid 20"}, ops.calls)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultPIDFile 背景執行與 stop/restart 預設的 PID 檔案
+const DefaultPIDFile = "/var/run/modbussim.pid"
+
+// DaemonReadyTimeout 等待背景程序完成啟動的時間上限
+const DaemonReadyTimeout = 2 * time.Minute
+
+// daemonEnv 標示背景子程序的環境變數 (子程序不再轉入背景)
+const daemonEnv = "MODBUSSIM_DAEMON"
+
+// daemonReadyFD 子程序回報就緒的管線 (exec.Cmd.ExtraFiles 的第一個)
+const daemonReadyFD = 3
+
+// isDaemonChild 是否為 start --daemon 建立的背景子程序
+func isDaemonChild() bool {
+	return os.Getenv(daemonEnv) == "1"
+}
+
+// startDaemon 以相同參數在新的 session 中重新執行 (脫離終端機)，等到子程序回報就緒才返回；
+// 子程序的標準輸出與錯誤寫入 logFile (空字串表示捨棄)
+func startDaemon(args []string, logFile string) (int, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf(T("取得執行檔路徑失敗: %w"), err)
+	}
+	if logFile == "" {
+		logFile = os.DevNull
+	}
+	out, err := os.OpenFile(logFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, fmt.Errorf(T("開啟日誌檔失敗: %w"), err)
+	}
+	defer out.Close()
+
+	ready, notify, err := os.Pipe()
+	if err != nil {
+		return 0, err
+	}
+	defer ready.Close()
+
+	cmd := exec.Command(exe, args...)
+	cmd.Env = append(os.Environ(), daemonEnv+"=1")
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.ExtraFiles = []*os.File{notify}
+	cmd.SysProcAttr = daemonSysProcAttr()
+	if err := cmd.Start(); err != nil {
+		notify.Close()
+		return 0, fmt.Errorf(T("啟動背景程序失敗: %w"), err)
+	}
+	notify.Close()
+
+	// 子程序啟動失敗而結束時管線隨之關閉
+	result := make(chan bool, 1)
+	go func() {
+		buf := make([]byte, 1)
+		n, _ := ready.Read(buf)
+		result <- n == 1
+	}()
+	select {
+	case ok := <-result:
+		if !ok {
+			return 0, fmt.Errorf(T("背景程序啟動失敗 (詳見 %s)"), logFile)
+		}
+	case <-time.After(DaemonReadyTimeout):
+		return 0, fmt.Errorf(T("等待背景程序啟動逾時 (PID %d)"), cmd.Process.Pid)
+	}
+
+	pid := cmd.Process.Pid
+	cmd.Process.Release()
+	return pid, nil
+}
+
+// restartArgs 將 restart 的命令列參數轉為 start --daemon 的參數 (移除 restart 專用的 --wait)
+func restartArgs(args []string) []string {
+	out := make([]string, 0, len(args)+1)
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "restart":
+			out = append(out, "start", "--daemon")
+		case arg == "--wait":
+			i++
+		case strings.HasPrefix(arg, "--wait="):
+		default:
+			out = append(out, arg)
+		}
+	}
+	return out
+}
+
+// notifyDaemonReady 背景子程序啟動完成時通知等待中的父程序 (非背景子程序時不做任何事)
+func notifyDaemonReady() {
+	if !isDaemonChild() {
+		return
+	}
+	f := os.NewFile(daemonReadyFD, "daemon-ready")
+	if f == nil {
+		return
+	}
+	f.Write([]byte{1})
+	f.Close()
+}
+
+// readPIDFile 讀取 PID 檔案
+func readPIDFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf(T("讀取 PID 檔案失敗: %w"), err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err == nil && pid <= 0 {
+		err = strconv.ErrRange
+	}
+	if err != nil {
+		return 0, fmt.Errorf(T("解析 PID 失敗: %w"), err)
+	}
+	return pid, nil
+}
+
+// checkPIDFile 確認 PID 檔案沒有指向執行中的程序 (遺留的 PID 檔案視為不存在)
+func checkPIDFile(path string) error {
+	pid, err := readPIDFile(path)
+	if err != nil {
+		return nil
+	}
+	if pid != os.Getpid() && processAlive(pid) {
+		return fmt.Errorf(T("已有執行中的實例 (PID %d，%s)"), pid, path)
+	}
+	return nil
+}
+
+// writePIDFile 寫入本程序的 PID；檔案中的程序仍在執行時回傳錯誤
+func writePIDFile(path string) error {
+	if err := checkPIDFile(path); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644); err != nil {
+		return fmt.Errorf(T("寫入 PID 檔案失敗: %w"), err)
+	}
+	return nil
+}
+
+// removePIDFile 結束時移除 PID 檔案 (內容已不是本程序時保留，例如已由新的實例覆寫)
+func removePIDFile(path string) {
+	if pid, err := readPIDFile(path); err != nil || pid != os.Getpid() {
+		return
+	}
+	if err := os.Remove(path); err != nil {
+		// 降級權限後可能無法移除 /var/run 下的檔案；遺留的 PID 檔案於下次啟動時覆寫
+		logger.Warn(T("移除 PID 檔案失敗"), zap.String("path", path), zap.Error(err))
+	}
+}
+
+// stopProcess 對 PID 檔案中的程序發送 SIGTERM；wait 大於 0 時等待程序結束
+func stopProcess(pidFile string, wait time.Duration) (int, error) {
+	pid, err := readPIDFile(pidFile)
+	if err != nil {
+		return 0, err
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return pid, fmt.Errorf(T("找不到程序: %w"), err)
+	}
+	if err := process.Signal(syscall.SIGTERM); err != nil {
+		return pid, fmt.Errorf(T("發送信號失敗: %w"), err)
+	}
+	if wait <= 0 {
+		return pid, nil
+	}
+
+	deadline := time.Now().Add(wait)
+	for processAlive(pid) {
+		if time.Now().After(deadline) {
+			return pid, fmt.Errorf(T("等待程序結束逾時 (PID %d)"), pid)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return pid, nil
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// dashboardFiles 網頁儀表板的靜態檔案 (以管理 API 取得資料，不需另外建置)
+//
+//go:embed web
+var dashboardFiles embed.FS
+
+// RegisterDashboard 於 /ui/ 提供網頁儀表板，根路徑轉址至 /ui/
+func RegisterDashboard(mux *http.ServeMux) {
+	files, err := fs.Sub(dashboardFiles, "web")
+	if err != nil {
+		panic(err) // 嵌入的目錄必定存在
+	}
+	mux.Handle("GET /ui/", http.StripPrefix("/ui/", http.FileServerFS(files)))
+	mux.Handle("GET /{$}", http.RedirectHandler("/ui/", http.StatusFound))
+}
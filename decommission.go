@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DecommissionManual 手動除役 (管理 API) 的規則名稱
+const DecommissionManual = "manual"
+
+// decommissionPlan Slave 的除役時間 (at 為零值表示沒有符合的規則)
+type decommissionPlan struct {
+	rule string
+	at   time.Time
+}
+
+// DecommissionEvent Slave 除役事件
+type DecommissionEvent struct {
+	SlaveID   string    `json:"slave_id"`
+	IP        string    `json:"ip"`
+	UnitID    uint8     `json:"unit_id"`
+	Rule      string    `json:"rule"`
+	At        time.Time `json:"at"`
+	Uptime    string    `json:"uptime"`
+	Requests  uint64    `json:"requests"`
+	IPRemoved bool      `json:"ip_removed"`
+}
+
+// ScheduledDecommission 排定的除役
+type ScheduledDecommission struct {
+	SlaveID string    `json:"slave_id"`
+	Rule    string    `json:"rule"`
+	At      time.Time `json:"at"`
+}
+
+// DecommissionStatus 除役排程與已除役的 Slave
+type DecommissionStatus struct {
+	Scheduled      []ScheduledDecommission `json:"scheduled"`
+	Decommissioned []DecommissionEvent     `json:"decommissioned"`
+}
+
+// decommissionRule 第一個符合 Slave 的除役規則
+func (c *Config) decommissionRule(ip net.IP) (DecommissionRule, bool) {
+	for _, rule := range c.Decommission.Rules {
+		if len(rule.Targets) == 0 && len(rule.Tags) == 0 {
+			return rule, true
+		}
+		if len(rule.Targets) > 0 && MatchTargets(ip, rule.Targets) {
+			return rule, true
+		}
+		for _, tag := range rule.Tags {
+			if c.hasTag(ip, tag) {
+				return rule, true
+			}
+		}
+	}
+	return DecommissionRule{}, false
+}
+
+// planDecommission 依規則決定 Slave 的除役時間
+func (e *Engine) planDecommission(slave *Slave) decommissionPlan {
+	rule, ok := e.config.decommissionRule(slave.IP)
+	if !ok {
+		return decommissionPlan{}
+	}
+
+	var at time.Time
+	if rule.At != "" {
+		at, _ = time.Parse(time.RFC3339, rule.At) // 已於配置驗證時檢查
+	} else {
+		at = slave.GetStats().StartTime.Add(rule.Lifetime)
+	}
+	if rule.Jitter > 0 {
+		at = at.Add(time.Duration(scenarioRandom().Int63n(int64(rule.Jitter))))
+	}
+	return decommissionPlan{rule: rule.Name, at: at}
+}
+
+// dueDecommission 已到期、待除役的 Slave
+type dueDecommission struct {
+	slave *Slave
+	rule  string
+}
+
+// refreshDecommissionPlansLocked 為新加入的 Slave 排定除役時間，並回傳已到期的 Slave (呼叫端需持有 e.decomMu)
+func (e *Engine) refreshDecommissionPlansLocked(now time.Time) (due []dueDecommission) {
+	if e.decomPlans == nil {
+		e.decomPlans = make(map[string]decommissionPlan)
+	}
+	for _, slave := range e.ListSlaves() {
+		plan, ok := e.decomPlans[slave.ID]
+		if !ok {
+			plan = e.planDecommission(slave)
+			e.decomPlans[slave.ID] = plan
+		}
+		if !plan.at.IsZero() && !now.Before(plan.at) {
+			due = append(due, dueDecommission{slave: slave, rule: plan.rule})
+		}
+	}
+	return due
+}
+
+// Decommission 立即除役指定的 Slave
+func (e *Engine) Decommission(slave *Slave) (DecommissionEvent, error) {
+	return e.decommission(slave, DecommissionManual)
+}
+
+// decommission 永久移除 Slave：停止 listener 與場景更新、自 Slave 列表移除，並視配置移除虛擬 IP
+func (e *Engine) decommission(slave *Slave, rule string) (DecommissionEvent, error) {
+	e.mu.Lock()
+	if current, ok := e.slaves[slave.ID]; !ok || current != slave {
+		e.mu.Unlock()
+		return DecommissionEvent{}, fmt.Errorf(T("找不到 Slave: %s"), slave.ID)
+	}
+	delete(e.slaves, slave.ID)
+
+	// 累計值併入引擎統計，避免 Slave 移除後計數器倒退
+	stats := slave.GetStats()
+	e.stats.SlaveCount--
+	e.stats.ActiveSlaves--
+	e.stats.TotalRequests += stats.RequestCount.Load()
+	e.stats.TotalErrors += stats.ErrorCount.Load()
+	e.stats.BytesReceived += stats.BytesReceived.Load()
+	e.stats.BytesSent += stats.BytesSent.Load()
+	e.stats.TotalFlaps += stats.FlapCount.Load()
+	e.stats.RejectedConnections += stats.RejectedConns.Load()
+	e.stats.ForcedDisconnects += stats.ForcedDisconnects.Load()
+	e.stats.RateLimitedRequests += stats.RateLimited.Load()
+	e.stats.TimedOutConnections += stats.TimedOutConns.Load()
+
+	// 其他 Slave 仍使用同一 IP 時保留
+	shared := false
+	for _, other := range e.slaves {
+		if other.IP.Equal(slave.IP) {
+			shared = true
+			break
+		}
+	}
+	e.mu.Unlock()
+
+	if err := slave.Stop(context.Background()); err != nil {
+		e.logger.Warn(T("停止 Slave 失敗"), zap.String("id", slave.ID), zap.Error(err))
+	}
+
+	now := time.Now()
+	event := DecommissionEvent{
+		SlaveID:  slave.ID,
+		UnitID:   slave.UnitID,
+		Rule:     rule,
+		At:       now,
+		Uptime:   now.Sub(stats.StartTime).Round(time.Second).String(),
+		Requests: stats.RequestCount.Load(),
+	}
+	if slave.IP != nil {
+		event.IP = slave.IP.String()
+	}
+
+	if e.config.Decommission.RemoveIP && !shared && slave.IP != nil && !slave.IP.IsUnspecified() && !slave.IP.IsLoopback() {
+		provisioner := NewNetworkProvisioner(e.config.Network, e.logger)
+		if err := provisioner.Remove(context.Background(), slave.IP); err != nil {
+			e.logger.Warn(T("除役時移除虛擬 IP 失敗"), zap.String("id", slave.ID), zap.Error(err))
+		} else {
+			event.IPRemoved = true
+		}
+	}
+
+	e.decomMu.Lock()
+	delete(e.decomPlans, slave.ID)
+	e.decommissioned = append(e.decommissioned, event)
+	e.decomMu.Unlock()
+
+	e.logger.Warn(T("Slave 已除役"),
+		zap.String("audit", "slave_decommissioned"),
+		zap.String("id", slave.ID),
+		zap.String("rule", rule),
+		zap.String("uptime", event.Uptime),
+		zap.Bool("ip_removed", event.IPRemoved),
+	)
+
+	return event, nil
+}
+
+// initDecommission 清除上次運行的排程並為已啟動的 Slave 排定除役時間
+func (e *Engine) initDecommission() {
+	e.decomMu.Lock()
+	defer e.decomMu.Unlock()
+
+	e.decomPlans = nil
+	e.refreshDecommissionPlansLocked(time.Now())
+}
+
+// DecommissionStatus 排定的除役與已除役的 Slave
+func (e *Engine) DecommissionStatus() DecommissionStatus {
+	e.decomMu.Lock()
+	defer e.decomMu.Unlock()
+
+	status := DecommissionStatus{
+		Scheduled:      []ScheduledDecommission{},
+		Decommissioned: append([]DecommissionEvent{}, e.decommissioned...),
+	}
+	for id, plan := range e.decomPlans {
+		if plan.at.IsZero() {
+			continue
+		}
+		status.Scheduled = append(status.Scheduled, ScheduledDecommission{SlaveID: id, Rule: plan.rule, At: plan.at})
+	}
+	sort.Slice(status.Scheduled, func(i, j int) bool { return status.Scheduled[i].At.Before(status.Scheduled[j].At) })
+	return status
+}
+
+// DecommissionedSlaves 已除役的 Slave 數
+func (e *Engine) DecommissionedSlaves() int {
+	e.decomMu.Lock()
+	defer e.decomMu.Unlock()
+	return len(e.decommissioned)
+}
+
+// runDecommissionScheduler 定期為 Slave 排定除役時間並除役到期的 Slave
+func (e *Engine) runDecommissionScheduler(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			e.decomMu.Lock()
+			due := e.refreshDecommissionPlansLocked(now)
+			e.decomMu.Unlock()
+
+			for _, d := range due {
+				if _, err := e.decommission(d.slave, d.rule); err != nil {
+					e.logger.Debug(T("除役失敗"), zap.String("id", d.slave.ID), zap.Error(err))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// 回應延遲分佈
+const (
+	DelayFixed     = "fixed"     // 固定延遲 jitter_mean
+	DelayUniform   = "uniform"   // jitter_min-jitter_max 均勻分佈 (預設)
+	DelayNormal    = "normal"    // 常態分佈 (jitter_mean、jitter_stddev)
+	DelayLognormal = "lognormal" // 對數常態分佈 (以延遲的 jitter_mean、jitter_stddev 換算)，右偏
+	DelayPareto    = "pareto"    // 柏拉圖分佈 (下限 jitter_min、形狀 jitter_alpha)，長尾
+)
+
+// DelayDistributions 所有回應延遲分佈
+var DelayDistributions = []string{DelayFixed, DelayUniform, DelayNormal, DelayLognormal, DelayPareto}
+
+// DefaultParetoAlpha 柏拉圖分佈的預設形狀參數 (越小尾巴越長，≤ 1 時平均值發散)
+const DefaultParetoAlpha = 1.5
+
+// isDelayDistribution 是否為有效的回應延遲分佈
+func isDelayDistribution(distribution string) bool {
+	for _, d := range DelayDistributions {
+		if d == distribution {
+			return true
+		}
+	}
+	return false
+}
+
+// DelayModel 回應延遲模型 (由 RequestHandler 對每個請求取樣)
+type DelayModel struct {
+	Distribution string
+	Min, Max     time.Duration // uniform 的範圍；pareto 以 Min 為下限
+	Mean, StdDev time.Duration // fixed、normal、lognormal 使用
+	Alpha        float64       // pareto 形狀參數
+	Cap          time.Duration // 所有分佈的延遲上限 (0 表示不限)
+	TimeoutRate  float64       // 完全不回應的請求比例 (模擬回應逾時)
+}
+
+// Sample 取樣一次延遲 (不小於 0，且不超過 Cap)
+func (m DelayModel) Sample(random *lockedRand) time.Duration {
+	var delay float64
+	switch m.Distribution {
+	case DelayFixed:
+		delay = float64(m.Mean)
+	case DelayNormal:
+		delay = float64(m.Mean) + random.NormFloat64()*float64(m.StdDev)
+	case DelayLognormal:
+		if m.Mean <= 0 {
+			break
+		}
+		// 由延遲的平均值與標準差換算對數常態分佈的 μ、σ
+		mean, stddev := float64(m.Mean), float64(m.StdDev)
+		sigma2 := math.Log(1 + stddev*stddev/(mean*mean))
+		mu := math.Log(mean) - sigma2/2
+		delay = math.Exp(mu + random.NormFloat64()*math.Sqrt(sigma2))
+	case DelayPareto:
+		alpha := m.Alpha
+		if alpha <= 0 {
+			alpha = DefaultParetoAlpha
+		}
+		// 反函數取樣：x_m / U^(1/α)，U ∈ (0, 1]
+		delay = float64(m.Min) / math.Pow(1-random.Float64(), 1/alpha)
+	default:
+		delay = float64(m.Min)
+		if m.Max > m.Min {
+			delay += float64(random.Int63n(int64(m.Max - m.Min)))
+		}
+	}
+
+	if delay < 0 || math.IsNaN(delay) {
+		delay = 0
+	}
+	if m.Cap > 0 && delay > float64(m.Cap) {
+		return m.Cap
+	}
+	if delay > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(delay)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 需量計算預設值
+const (
+	DefaultDemandRegister     = 40220
+	DefaultDemandWindow       = 15 * time.Minute
+	DefaultDemandSubintervals = 15
+	DefaultDemandInterval     = 15 * time.Minute
+)
+
+// 需量暫存器 (相對於 slaves.demand.register 的位移)
+const (
+	demandOffsetDemand       = 0 // 滑動需量 (kW)
+	demandOffsetPeak         = 2 // 最大需量 (kW)
+	demandOffsetPeakTime     = 4 // 最大需量發生時間 (Unix 秒)
+	demandOffsetInterval     = 6 // 目前區間的電能 (kWh)
+	demandOffsetLastInterval = 8 // 上一個區間的電能 (kWh)
+)
+
+// demandMeter 依有功功率計算滑動需量、最大需量與區間電能 (依模擬時鐘，區間自每日 00:00 起對齊)
+type demandMeter struct {
+	register  uint16
+	window    time.Duration
+	sub       time.Duration
+	interval  time.Duration
+	peakReset string
+	location  *time.Location
+
+	mu            sync.Mutex
+	last          time.Time // 上次累積的時間
+	subStart      time.Time // 目前子區間的開始
+	subEnergy     float64   // 目前子區間的電能 (kWh)
+	completed     []float64 // 最近完成的子區間電能 (最多 window/sub 個)
+	demand        float64
+	peak          float64
+	peakAt        time.Time
+	intervalStart time.Time
+	intervalKWh   float64
+	lastKWh       float64
+}
+
+// newDemandMeter 依設定建立需量計算 (未設定的參數使用預設值)
+func newDemandMeter(cfg DemandConfig) *demandMeter {
+	m := &demandMeter{
+		register:  cfg.Register,
+		window:    durationOr(cfg.Window, DefaultDemandWindow),
+		interval:  durationOr(cfg.Interval, DefaultDemandInterval),
+		peakReset: cfg.PeakReset,
+		location:  time.UTC,
+	}
+	if m.register == 0 {
+		m.register = DefaultDemandRegister
+	}
+	subintervals := cfg.Subintervals
+	if subintervals == 0 {
+		subintervals = DefaultDemandSubintervals
+	}
+	m.sub = m.window / time.Duration(subintervals)
+	if m.peakReset == "" {
+		m.peakReset = DemandPeakResetMonthly
+	}
+	if cfg.TimeZone != "" {
+		if location, err := time.LoadLocation(cfg.TimeZone); err == nil {
+			m.location = location
+		}
+	}
+	return m
+}
+
+// alignTime t 所在的週期 (長度 d，自 t 當日 00:00 起對齊) 的開始
+func alignTime(t time.Time, d time.Duration, location *time.Location) time.Time {
+	t = t.In(location)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, location)
+	return midnight.Add(t.Sub(midnight).Truncate(d))
+}
+
+// accumulate 以功率 power (W) 累積至 now：跨越子區間或區間邊界時依時間比例分配電能，並於子區間結束時更新需量
+func (m *demandMeter) accumulate(power float64, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.last.IsZero() {
+		m.last = now
+		m.subStart = alignTime(now, m.sub, m.location)
+		m.intervalStart = alignTime(now, m.interval, m.location)
+		return
+	}
+	for m.last.Before(now) {
+		subEnd, intervalEnd := m.subStart.Add(m.sub), m.intervalStart.Add(m.interval)
+		next := now
+		if subEnd.Before(next) {
+			next = subEnd
+		}
+		if intervalEnd.Before(next) {
+			next = intervalEnd
+		}
+
+		energy := power * next.Sub(m.last).Hours() / 1000
+		m.subEnergy += energy
+		m.intervalKWh += energy
+		m.last = next
+
+		if !next.Before(subEnd) {
+			m.closeSubinterval(next)
+		}
+		if !next.Before(intervalEnd) {
+			m.lastKWh, m.intervalKWh = m.intervalKWh, 0
+			m.intervalStart = alignTime(next, m.interval, m.location)
+		}
+	}
+}
+
+// closeSubinterval 結束子區間：需量為時窗內子區間電能的總和除以時窗長度，並更新最大需量
+func (m *demandMeter) closeSubinterval(at time.Time) {
+	m.completed = append(m.completed, m.subEnergy)
+	if n := int(m.window / m.sub); len(m.completed) > n {
+		m.completed = m.completed[len(m.completed)-n:]
+	}
+	m.subEnergy = 0
+	m.subStart = alignTime(at, m.sub, m.location)
+
+	total := 0.0
+	for _, energy := range m.completed {
+		total += energy
+	}
+	m.demand = total / m.window.Hours()
+
+	if m.peakExpired(at) {
+		m.peak, m.peakAt = 0, time.Time{}
+	}
+	if m.demand > m.peak {
+		m.peak, m.peakAt = m.demand, at
+	}
+}
+
+// peakExpired 最大需量是否已進入新的重設週期 (每日或每月)
+func (m *demandMeter) peakExpired(at time.Time) bool {
+	if m.peakAt.IsZero() {
+		return false
+	}
+	peakAt, at := m.peakAt.In(m.location), at.In(m.location)
+	switch m.peakReset {
+	case DemandPeakResetDaily:
+		return peakAt.YearDay() != at.YearDay() || peakAt.Year() != at.Year()
+	case DemandPeakResetMonthly:
+		return peakAt.Month() != at.Month() || peakAt.Year() != at.Year()
+	default:
+		return false
+	}
+}
+
+// write 將需量與區間電能寫入暫存器
+func (m *demandMeter) write(registers *RegisterMap) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	registers.SetScaledValue(m.register+demandOffsetDemand, m.demand)
+	registers.SetScaledValue(m.register+demandOffsetPeak, m.peak)
+	peakAt := 0.0
+	if !m.peakAt.IsZero() {
+		peakAt = float64(m.peakAt.Unix())
+	}
+	registers.SetScaledValue(m.register+demandOffsetPeakTime, peakAt)
+	registers.SetScaledValue(m.register+demandOffsetInterval, m.intervalKWh)
+	registers.SetScaledValue(m.register+demandOffsetLastInterval, m.lastKWh)
+}
+
+// demandRegisters 需量暫存器的定義
+func demandRegisters(register uint16) []RegisterDefinition {
+	return []RegisterDefinition{
+		{Address: register + demandOffsetDemand, Name: "Demand", DataType: "uint32", Scale: 1000, Unit: "kW"},
+		{Address: register + demandOffsetPeak, Name: "PeakDemand", DataType: "uint32", Scale: 1000, Unit: "kW"},
+		{Address: register + demandOffsetPeakTime, Name: "PeakDemandTime", DataType: "uint32", Scale: 1, Unit: "s"},
+		{Address: register + demandOffsetInterval, Name: "IntervalEnergy", DataType: "uint32", Scale: 1000, Unit: "kWh"},
+		{Address: register + demandOffsetLastInterval, Name: "LastIntervalEnergy", DataType: "uint32", Scale: 1000, Unit: "kWh"},
+	}
+}
+
+// initDemand 依 slaves.demand 定義需量暫存器並建立需量計算；暫存器與設定檔的暫存器重疊時停用並記錄警告
+func (s *Slave) initDemand() {
+	if s.config == nil || !s.config.Slaves.Demand.Enabled {
+		return
+	}
+	meter := newDemandMeter(s.config.Slaves.Demand)
+	if err := s.defineComputedRegisters(demandRegisters(meter.register)); err != nil {
+		s.logger.Warn(T("需量暫存器定義失敗，停用需量計算"), zap.String("id", s.ID), zap.Error(err))
+		return
+	}
+	s.demand = meter
+}
+
+// defineComputedRegisters 定義由 Slave 計算的唯讀暫存器 (不可與設定檔的暫存器重疊)
+func (s *Slave) defineComputedRegisters(defs []RegisterDefinition) error {
+	for _, def := range defs {
+		if existing, ok := s.registers.GetDefinition(def.Address); ok {
+			return fmt.Errorf(T("暫存器 %s (%d) 與 %s 位址重疊"), def.Name, def.Address, existing.Name)
+		}
+		dataType, err := ParseDataType(def.DataType)
+		if err != nil {
+			return err
+		}
+		if err := s.registers.DefineRegister(def.Address, def.Name, dataType, def.Scale, def.Unit, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateDemand 以目前的有功功率累積需量與區間電能，並寫入需量暫存器
+func (s *Slave) updateDemand() {
+	if s.demand == nil {
+		return
+	}
+	power, _ := scenarioRegisters(s.registers, s.model).GetScaledValue(40007)
+	s.demand.accumulate(power, scenarioNow())
+	s.demand.write(s.registers)
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 設備時鐘預設值
+const (
+	DefaultDeviceClockRegister = 40200
+	DefaultDeviceVendorName    = "modbus-simulator"
+)
+
+// Read Device Identification (FC 43 / MEI 0x0E)
+const (
+	meiReadDeviceIdentification = 0x0E
+
+	deviceIDBasic      = 0x01 // 基本物件串流讀取 (0x00-0x02)
+	deviceIDRegular    = 0x02 // 一般物件串流讀取 (0x00-0x7F)
+	deviceIDExtended   = 0x03 // 擴充物件串流讀取 (全部)
+	deviceIDIndividual = 0x04 // 讀取單一物件
+
+	deviceIDConformity = 0x83 // 支援基本與擴充物件，可串流及單一讀取
+)
+
+// deviceClock 設備自己的時鐘：以模擬時鐘為參考，依走時誤差累積偏差，Master 寫入時間暫存器時校時
+type deviceClock struct {
+	register uint16
+	format   string
+	location *time.Location
+	object   uint8
+	driftPPM float64
+
+	mu       sync.Mutex
+	ref      time.Time // 上次校時的參考時間 (模擬時鐘)
+	device   time.Time // 上次校時時設備的時間
+	syncedAt time.Time // 上次由 Master 或管理 API 校時的參考時間 (零值表示未校時)
+}
+
+// newDeviceClock 依設定建立設備時鐘；random 決定各 Slave 在 spread 範圍內的走時誤差與偏差
+func newDeviceClock(cfg DeviceClockConfig, random *lockedRand) *deviceClock {
+	c := &deviceClock{
+		register: cfg.Register,
+		format:   cfg.Format,
+		location: time.UTC,
+		object:   cfg.DeviceIDObject,
+		driftPPM: cfg.DriftPPM + (random.Float64()*2-1)*cfg.DriftSpread,
+	}
+	if c.register == 0 {
+		c.register = DefaultDeviceClockRegister
+	}
+	if c.format == "" {
+		c.format = DeviceClockFormatDateTime
+	}
+	if cfg.TimeZone != "" {
+		if location, err := time.LoadLocation(cfg.TimeZone); err == nil {
+			c.location = location
+		}
+	}
+	skew := cfg.Skew + time.Duration((random.Float64()*2-1)*float64(cfg.SkewSpread))
+	c.ref = scenarioNow()
+	c.device = c.ref.Add(skew)
+	return c
+}
+
+// at 參考時間為 ref 時設備的時間
+func (c *deviceClock) at(ref time.Time) time.Time {
+	elapsed := float64(ref.Sub(c.ref)) * (1 + c.driftPPM/1e6)
+	return c.device.Add(time.Duration(elapsed)).In(c.location)
+}
+
+// Now 設備目前的時間
+func (c *deviceClock) Now() time.Time {
+	ref := scenarioNow()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.at(ref)
+}
+
+// Set 將設備時間設為 t (之後依相同的走時誤差前進)
+func (c *deviceClock) Set(t time.Time) {
+	ref := scenarioNow()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ref, c.device, c.syncedAt = ref, t, ref
+}
+
+// size 時間佔用的暫存器數
+func (c *deviceClock) size() int {
+	if c.format == DeviceClockFormatUnix {
+		return 2
+	}
+	return 6
+}
+
+// words 時間 t 的暫存器值
+func (c *deviceClock) words(t time.Time) []uint16 {
+	if c.format == DeviceClockFormatUnix {
+		ts := uint32(t.Unix())
+		return []uint16{uint16(ts >> 16), uint16(ts)}
+	}
+	return []uint16{uint16(t.Year()), uint16(t.Month()), uint16(t.Day()), uint16(t.Hour()), uint16(t.Minute()), uint16(t.Second())}
+}
+
+// parse 將暫存器值轉為時間；日期時間超出範圍時回傳 false
+func (c *deviceClock) parse(words []uint16) (time.Time, bool) {
+	if c.format == DeviceClockFormatUnix {
+		return time.Unix(int64(uint32(words[0])<<16|uint32(words[1])), 0).In(c.location), true
+	}
+	year, month, day, hour, minute, second := int(words[0]), int(words[1]), int(words[2]), int(words[3]), int(words[4]), int(words[5])
+	if month < 1 || month > 12 || day < 1 || day > 31 || hour > 23 || minute > 59 || second > 59 {
+		return time.Time{}, false
+	}
+	t := time.Date(year, time.Month(month), day, hour, minute, second, 0, c.location)
+	if t.Day() != day {
+		return time.Time{}, false // 例如 2 月 30 日
+	}
+	return t, true
+}
+
+// initDeviceClock 依 slaves.device_clock 建立設備時鐘並寫入初始時間 (各 Slave 的走時誤差與偏差由亂數種子導出)
+func (s *Slave) initDeviceClock() {
+	if s.config == nil || !s.config.Slaves.DeviceClock.Enabled {
+		return
+	}
+	random := scenarioRandom()
+	if s.seed != 0 {
+		random = newLockedRand(slaveSeed(s.seed, s.seedKey, randomStreamClock))
+	}
+	s.clock = newDeviceClock(s.config.Slaves.DeviceClock, random)
+	s.updateDeviceClock()
+}
+
+// updateDeviceClock 將設備時間寫入時間暫存器
+func (s *Slave) updateDeviceClock() {
+	if s.clock == nil {
+		return
+	}
+	s.registers.WriteHoldingRegisters(s.clock.register, s.clock.words(s.clock.Now()))
+}
+
+// deviceClockWrite Master 的寫入涵蓋時間暫存器時，回傳寫入後的時間 (ok 為 false 表示與時間暫存器無關)；
+// 寫入後不是有效的日期時間時回應 Illegal Data Value
+func (s *Slave) deviceClockWrite(registers *RegisterMap, pdu uint16, values []uint16) (t time.Time, ok bool, err error) {
+	if s.clock == nil || registers != s.registers {
+		return time.Time{}, false, nil
+	}
+	address, found := registers.PDUAddress(RegisterTypeHoldingRegister, pdu)
+	if !found {
+		return time.Time{}, false, nil
+	}
+	start, end := int(s.clock.register), int(s.clock.register)+s.clock.size()
+	if int(address)+len(values) <= start || int(address) >= end {
+		return time.Time{}, false, nil
+	}
+
+	// 以目前的暫存器值套上寫入的值 (可只寫入部分欄位，例如只校正秒)
+	words, readErr := registers.ReadHoldingRegisters(s.clock.register, uint16(s.clock.size()))
+	if readErr != nil {
+		return time.Time{}, false, nil
+	}
+	for i, value := range values {
+		if index := int(address) + i - start; index >= 0 && index < len(words) {
+			words[index] = value
+		}
+	}
+	t, valid := s.clock.parse(words)
+	if !valid {
+		return time.Time{}, false, &ModbusError{Code: ExceptionCodeIllegalDataValue}
+	}
+	return t, true, nil
+}
+
+// syncDeviceClock 將設備時間設為 t
+func (s *Slave) syncDeviceClock(t time.Time) {
+	before := s.clock.Now()
+	s.clock.Set(t)
+	s.logger.Info(T("設備時鐘已校時"),
+		zap.String("id", s.ID),
+		zap.Time("from", before),
+		zap.Time("to", t),
+	)
+}
+
+// DeviceClockStatus 設備時鐘狀態
+type DeviceClockStatus struct {
+	Time      time.Time     `json:"time"`                // 設備時間
+	Reference time.Time     `json:"reference"`           // 參考時間 (模擬時鐘)
+	Offset    time.Duration `json:"offset"`              // 設備時間與參考時間的差 (正值為超前)
+	DriftPPM  float64       `json:"drift_ppm"`           // 走時誤差
+	SyncedAt  *time.Time    `json:"synced_at,omitempty"` // 上次校時的參考時間
+}
+
+// DeviceClockStatus 設備時鐘的目前時間與偏差；未啟用設備時鐘時回傳 false
+func (s *Slave) DeviceClockStatus() (DeviceClockStatus, bool) {
+	if s.clock == nil {
+		return DeviceClockStatus{}, false
+	}
+	ref := scenarioNow()
+	s.clock.mu.Lock()
+	defer s.clock.mu.Unlock()
+	device := s.clock.at(ref)
+	status := DeviceClockStatus{
+		Time:      device,
+		Reference: ref,
+		Offset:    device.Sub(ref),
+		DriftPPM:  s.clock.driftPPM,
+	}
+	if !s.clock.syncedAt.IsZero() {
+		synced := s.clock.syncedAt
+		status.SyncedAt = &synced
+	}
+	return status, true
+}
+
+// SetDeviceClock 將設備時間設為 t 並立即寫入時間暫存器 (模擬人為設錯或校時)
+func (s *Slave) SetDeviceClock(t time.Time) error {
+	if s.clock == nil {
+		return fmt.Errorf(T("slave %s 未啟用設備時鐘"), s.ID)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.syncDeviceClock(t)
+	s.updateDeviceClock()
+	s.syncRegistersToServer()
+	return nil
+}
+
+// readDeviceIdentification 處理 Read Device Identification (FC 43 / MEI 0x0E)：
+// 基本物件為廠商、產品代碼 (設備設定檔) 與版本，擴充物件 device_id_object 為設備時間 (RFC 3339)；
+// 未設定 device_id_object 時回應 Illegal Function
+func (s *Slave) readDeviceIdentification(data []byte) ([]byte, error) {
+	if s.clock == nil || s.clock.object == 0 {
+		return nil, &ModbusError{Code: ExceptionCodeIllegalFunction}
+	}
+	if len(data) != 3 {
+		return nil, &ModbusError{Code: ExceptionCodeIllegalDataValue}
+	}
+	if data[0] != meiReadDeviceIdentification {
+		return nil, &ModbusError{Code: ExceptionCodeIllegalFunction}
+	}
+	code, objectID := data[1], data[2]
+
+	profile := ProfileSinglePhase
+	if s.config != nil && s.config.Slaves.Profile != "" {
+		profile = s.config.Slaves.Profile
+	}
+	objects := map[uint8]string{
+		0x00:           DefaultDeviceVendorName,
+		0x01:           profile,
+		0x02:           Version,
+		s.clock.object: s.clock.Now().Format(time.RFC3339),
+	}
+	ids := []uint8{0x00, 0x01, 0x02, s.clock.object}
+
+	var selected []uint8
+	switch code {
+	case deviceIDBasic, deviceIDRegular, deviceIDExtended:
+		limit := uint8(0x02)
+		if code == deviceIDRegular {
+			limit = 0x7F
+		} else if code == deviceIDExtended {
+			limit = 0xFF
+		}
+		// 起始物件不存在時從 0x00 開始
+		if _, ok := objects[objectID]; !ok || objectID > limit {
+			objectID = 0x00
+		}
+		for _, id := range ids {
+			if id >= objectID && id <= limit {
+				selected = append(selected, id)
+			}
+		}
+	case deviceIDIndividual:
+		if _, ok := objects[objectID]; !ok {
+			return nil, &ModbusError{Code: ExceptionCodeIllegalDataAddress}
+		}
+		selected = []uint8{objectID}
+	default:
+		return nil, &ModbusError{Code: ExceptionCodeIllegalDataValue}
+	}
+
+	response := []byte{meiReadDeviceIdentification, code, deviceIDConformity, 0x00, 0x00, byte(len(selected))}
+	for _, id := range selected {
+		value := objects[id]
+		response = append(response, id, byte(len(value)))
+		response = append(response, value...)
+	}
+	return response, nil
+}
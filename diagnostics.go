@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"runtime"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 自我監控設備預設值
+const (
+	DefaultDiagnosticsUnitID         = 247
+	DefaultDiagnosticsInterval       = time.Second
+	DefaultDiagnosticsTickLagAlarm   = 500 * time.Millisecond
+	DefaultDiagnosticsFDUsageAlarm   = 0.8
+	DefaultDiagnosticsErrorRateAlarm = 0.05
+)
+
+// 自我監控設備的輸入暫存器 (FC04，PDU 位址；32 位元值為高位字在前)
+const (
+	DiagRegUptime          = 0  // 運行秒數 (uint32)
+	DiagRegSlaves          = 2  // Slave 數
+	DiagRegActiveSlaves    = 3  // 運行中的 Slave 數
+	DiagRegOfflineSlaves   = 4  // 離線的 Slave 數
+	DiagRegTickLag         = 5  // 更新延遲 (ms)
+	DiagRegOpenFDs         = 6  // 開啟的檔案描述子數 (uint32)
+	DiagRegFDLimit         = 8  // 檔案描述子上限 (uint32)
+	DiagRegFDUsage         = 10 // 檔案描述子用量 (‰)
+	DiagRegRequestRate     = 11 // 週期內每秒請求數 (uint32)
+	DiagRegErrorRate       = 13 // 週期內錯誤率 (‰)
+	DiagRegGoroutines      = 14 // goroutine 數 (uint32)
+	DiagRegHeapMB          = 16 // heap 使用量 (MB)
+	DiagRegConnections     = 17 // Modbus TCP 連線數 (uint32)
+	DiagRegAlarms          = 19 // 告警位元 (與離散輸入相同)
+	diagnosticsInputLength = 20
+)
+
+// 自我監控設備的告警 (離散輸入 FC02 的位址，亦為 DiagRegAlarms 的位元)
+const (
+	DiagAlarmTickLag   = 0 // 更新延遲超過 tick_lag_alarm
+	DiagAlarmFDUsage   = 1 // 檔案描述子用量超過 fd_usage_alarm
+	DiagAlarmErrorRate = 2 // 錯誤率超過 error_rate_alarm
+	diagnosticsAlarms  = 3
+)
+
+// diagnosticsAlarmNames 告警名稱 (日誌用)
+var diagnosticsAlarmNames = [diagnosticsAlarms]string{"tick_lag", "fd_usage", "error_rate"}
+
+// diagnosticsModel 自我監控設備的設備模型：每個更新週期將引擎的健康狀態寫入暫存器
+type diagnosticsModel struct {
+	engine *Engine
+	config DiagnosticsConfig
+	logger *zap.Logger
+
+	mu           sync.Mutex
+	lastTick     time.Time
+	lastRequests uint64
+	lastErrors   uint64
+	alarms       [diagnosticsAlarms]bool
+}
+
+// newDiagnosticsRegisterMap 自我監控設備的暫存器 (僅輸入暫存器與離散輸入，寫入一律回應 Illegal Data Address)
+func newDiagnosticsRegisterMap() *RegisterMap {
+	return NewRegisterMap(0, diagnosticsAlarms, diagnosticsInputLength, 0)
+}
+
+// Update 更新健康狀態與告警
+func (m *diagnosticsModel) Update(registers *RegisterMap, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := m.engine.Stats()
+
+	// 更新延遲：實際間隔超出預期週期的部分 (反映 GC 與排程壓力)
+	var lag, elapsed time.Duration
+	if !m.lastTick.IsZero() {
+		elapsed = now.Sub(m.lastTick)
+		if lag = elapsed - m.config.Interval; lag < 0 {
+			lag = 0
+		}
+	}
+	m.lastTick = now
+
+	// 週期內的請求率與錯誤率 (計數器倒退時視為 0，例如引擎重新啟動)
+	var requests, errs uint64
+	if stats.TotalRequests >= m.lastRequests && stats.TotalErrors >= m.lastErrors {
+		requests, errs = stats.TotalRequests-m.lastRequests, stats.TotalErrors-m.lastErrors
+	}
+	m.lastRequests, m.lastErrors = stats.TotalRequests, stats.TotalErrors
+	var requestRate, errorRate float64
+	if elapsed > 0 {
+		requestRate = float64(requests) / elapsed.Seconds()
+	}
+	if requests > 0 {
+		errorRate = float64(errs) / float64(requests)
+	}
+
+	openFDs, fdLimit, fdOK := fdUsage()
+	var fdRatio float64
+	if fdOK && fdLimit > 0 {
+		fdRatio = float64(openFDs) / float64(fdLimit)
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	alarms := [diagnosticsAlarms]bool{
+		DiagAlarmTickLag:   m.config.TickLagAlarm > 0 && lag > m.config.TickLagAlarm,
+		DiagAlarmFDUsage:   m.config.FDUsageAlarm > 0 && fdRatio > m.config.FDUsageAlarm,
+		DiagAlarmErrorRate: m.config.ErrorRateAlarm > 0 && errorRate > m.config.ErrorRateAlarm,
+	}
+	var alarmBits uint16
+	for i, on := range alarms {
+		if on {
+			alarmBits |= 1 << i
+		}
+		registers.SetDiscreteInput(uint16(i), on)
+	}
+
+	setU16 := func(address int, v uint64) {
+		registers.SetInputRegister(uint16(address), uint16(min(v, 0xFFFF)))
+	}
+	setU32 := func(address int, v uint64) {
+		v = min(v, 0xFFFFFFFF)
+		registers.SetInputRegister(uint16(address), uint16(v>>16))
+		registers.SetInputRegister(uint16(address+1), uint16(v))
+	}
+	setU32(DiagRegUptime, uint64(now.Sub(stats.StartTime).Seconds()))
+	setU16(DiagRegSlaves, uint64(stats.SlaveCount))
+	setU16(DiagRegActiveSlaves, uint64(max(stats.ActiveSlaves, 0)))
+	setU16(DiagRegOfflineSlaves, uint64(stats.OfflineSlaves))
+	setU16(DiagRegTickLag, uint64(lag.Milliseconds()))
+	setU32(DiagRegOpenFDs, openFDs)
+	setU32(DiagRegFDLimit, fdLimit)
+	setU16(DiagRegFDUsage, uint64(fdRatio*1000))
+	setU32(DiagRegRequestRate, uint64(requestRate))
+	setU16(DiagRegErrorRate, uint64(errorRate*1000))
+	setU32(DiagRegGoroutines, uint64(runtime.NumGoroutine()))
+	setU16(DiagRegHeapMB, mem.HeapAlloc>>20)
+	setU32(DiagRegConnections, uint64(stats.ActiveConnections))
+	setU16(DiagRegAlarms, uint64(alarmBits))
+
+	// 告警狀態改變時記錄
+	for i, on := range alarms {
+		if on == m.alarms[i] {
+			continue
+		}
+		fields := []zap.Field{
+			zap.String("alarm", diagnosticsAlarmNames[i]),
+			zap.Duration("tick_lag", lag),
+			zap.Float64("fd_usage", fdRatio),
+			zap.Float64("error_rate", errorRate),
+		}
+		if on {
+			m.logger.Warn(T("自我監控告警"), fields...)
+		} else {
+			m.logger.Info(T("自我監控告警解除"), fields...)
+		}
+	}
+	m.alarms = alarms
+}
+
+// startDiagnostics 啟動自我監控設備 (不列入 Slave 列表，不受場景、故障注入與除役影響)
+func (e *Engine) startDiagnostics(ctx context.Context) error {
+	cfg := e.config.Diagnostics
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultDiagnosticsInterval
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = e.config.Server.Port
+	}
+	ip := net.ParseIP(cfg.IP)
+
+	logger := e.logger.With(zap.String("slave_id", fmt.Sprintf("%s:%d", ip.String(), port)))
+	registers := newDiagnosticsRegisterMap()
+	model := &diagnosticsModel{engine: e, config: cfg, logger: logger}
+	model.Update(registers, time.Now()) // 第一次輪詢即可讀到數值
+
+	opts := []SlaveOption{
+		WithUnitID(cfg.UnitID),
+		WithRegisters(registers),
+		WithModel(model),
+		WithRefreshInterval(cfg.Interval),
+		WithLogger(logger),
+	}
+	e.mu.RLock()
+	pool := e.listeners
+	e.mu.RUnlock()
+	if pool != nil {
+		opts = append(opts, WithListenerPool(pool))
+	}
+
+	slave := NewSlave(ip, port, e.config, opts...)
+	if err := slave.Start(ctx); err != nil {
+		return fmt.Errorf(T("啟動自我監控設備失敗: %w"), err)
+	}
+
+	e.mu.Lock()
+	e.diagnostics = slave
+	e.mu.Unlock()
+
+	e.logger.Info(T("已啟動自我監控設備"),
+		zap.String("addr", slave.listenAddr()),
+		zap.Uint8("unit_id", cfg.UnitID),
+	)
+	return nil
+}
+
+// stopDiagnostics 停止自我監控設備
+func (e *Engine) stopDiagnostics(ctx context.Context) {
+	e.mu.Lock()
+	slave := e.diagnostics
+	e.diagnostics = nil
+	e.mu.Unlock()
+
+	if slave != nil {
+		slave.Stop(ctx)
+	}
+}
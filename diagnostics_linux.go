@@ -0,0 +1,21 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// fdUsage 開啟的檔案描述子數與上限 (RLIMIT_NOFILE 軟限制)
+func fdUsage() (open, limit uint64, ok bool) {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return 0, 0, false
+	}
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+		return 0, 0, false
+	}
+	return uint64(len(entries)), rlimit.Cur, true
+}
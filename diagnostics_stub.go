@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// fdUsage 非 Linux 平台不提供檔案描述子用量
+func fdUsage() (open, limit uint64, ok bool) {
+	return 0, 0, false
+}
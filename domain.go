@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultDomainOutageDuration 故障域預設停擺時間
+const DefaultDomainOutageDuration = 30 * time.Second
+
+// domainState 故障域的執行期狀態
+type domainState struct {
+	domain      FailureDomain
+	members     []*Slave // 停擺中實際離線的成員
+	down        bool
+	until       time.Time
+	lastOutage  time.Time
+	lastRestore time.Time
+	outages     uint64
+}
+
+// DomainStatus 故障域狀態
+type DomainStatus struct {
+	Name       string    `json:"name"`
+	Members    int       `json:"members"`
+	Down       bool      `json:"down"`
+	Until      time.Time `json:"until,omitempty"`
+	Outages    uint64    `json:"outages"`
+	LastOutage time.Time `json:"last_outage,omitempty"`
+}
+
+// inDomain Slave 是否屬於指定故障域
+func (c *Config) inDomain(ip net.IP, d FailureDomain) bool {
+	if len(d.Targets) > 0 && MatchTargets(ip, d.Targets) {
+		return true
+	}
+	for _, tag := range d.Tags {
+		if c.hasTag(ip, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// initDomains 依配置建立故障域
+func (e *Engine) initDomains() {
+	e.domainsMu.Lock()
+	defer e.domainsMu.Unlock()
+
+	e.domains = make(map[string]*domainState)
+	for _, d := range e.config.FailureDomains {
+		e.domains[d.Name] = &domainState{domain: d, lastRestore: e.stats.StartTime}
+	}
+}
+
+// domainMembers 取得故障域內的 Slave
+func (e *Engine) domainMembers(d FailureDomain) []*Slave {
+	var members []*Slave
+	for _, slave := range e.ListSlaves() {
+		if e.config.inDomain(slave.IP, d) {
+			members = append(members, slave)
+		}
+	}
+	return members
+}
+
+// DomainOutage 讓故障域內所有 Slave 同時離線，經過 duration 後一起恢復 (0 表示使用配置值)
+func (e *Engine) DomainOutage(name string, duration time.Duration) (DomainStatus, error) {
+	e.domainsMu.Lock()
+	defer e.domainsMu.Unlock()
+
+	state, ok := e.domains[name]
+	if !ok {
+		return DomainStatus{}, fmt.Errorf(T("找不到故障域: %s"), name)
+	}
+	if duration <= 0 {
+		duration = state.domain.OutageDuration
+	}
+	if duration <= 0 {
+		duration = DefaultDomainOutageDuration
+	}
+
+	now := time.Now()
+	if state.down {
+		// 已在停擺中：僅延長
+		state.until = now.Add(duration)
+		return state.status(), nil
+	}
+
+	var members []*Slave
+	var protected int
+	for _, slave := range e.domainMembers(state.domain) {
+		if e.protectionReason(slave.IP, now) != "" {
+			protected++
+			continue
+		}
+		members = append(members, slave)
+	}
+	if protected > 0 {
+		e.suppressedCount.Add(uint64(protected))
+		e.logger.Warn(T("故障域停擺略過受保護的 Slave"),
+			zap.String("audit", "fault_suppressed"),
+			zap.String("domain", name),
+			zap.Int("protected", protected),
+		)
+	}
+
+	// 先標記再離線，避免斷線閃斷場景在停擺期間讓成員恢復上線
+	for _, slave := range members {
+		slave.domainOutage.Store(true)
+	}
+	for _, slave := range members {
+		slave.GoOffline()
+	}
+
+	state.members = members
+	state.down = true
+	state.until = now.Add(duration)
+	state.lastOutage = now
+	state.outages++
+
+	e.logger.Warn(T("故障域停擺"),
+		zap.String("domain", name),
+		zap.Int("members", len(members)),
+		zap.Duration("duration", duration),
+	)
+
+	return state.status(), nil
+}
+
+// RestoreDomain 讓停擺中的故障域所有成員一起恢復上線
+func (e *Engine) RestoreDomain(name string) (DomainStatus, error) {
+	e.domainsMu.Lock()
+	defer e.domainsMu.Unlock()
+
+	state, ok := e.domains[name]
+	if !ok {
+		return DomainStatus{}, fmt.Errorf(T("找不到故障域: %s"), name)
+	}
+	e.restoreDomainLocked(state, time.Now())
+	return state.status(), nil
+}
+
+// restoreDomainLocked 恢復故障域 (呼叫端需持有 e.domainsMu)
+func (e *Engine) restoreDomainLocked(state *domainState, now time.Time) {
+	if !state.down {
+		return
+	}
+
+	for _, slave := range state.members {
+		slave.domainOutage.Store(false)
+	}
+	for _, slave := range state.members {
+		if slave.heldOffline() {
+			continue // 開機風暴斷電中，由開機風暴恢復
+		}
+		if err := slave.GoOnline(); err != nil {
+			e.logger.Warn(T("恢復上線失敗"), zap.String("id", slave.ID), zap.Error(err))
+		}
+	}
+
+	e.logger.Info(T("故障域已恢復"),
+		zap.String("domain", state.domain.Name),
+		zap.Int("members", len(state.members)),
+		zap.Duration("outage", now.Sub(state.lastOutage)),
+	)
+
+	state.members = nil
+	state.down = false
+	state.until = time.Time{}
+	state.lastRestore = now
+}
+
+// ListDomains 列出所有故障域狀態
+func (e *Engine) ListDomains() []DomainStatus {
+	e.domainsMu.Lock()
+	defer e.domainsMu.Unlock()
+
+	result := make([]DomainStatus, 0, len(e.domains))
+	for _, state := range e.domains {
+		status := state.status()
+		if !state.down {
+			status.Members = len(e.domainMembers(state.domain))
+		}
+		result = append(result, status)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// domainStats 停擺次數總和與停擺中的故障域數
+func (e *Engine) domainStats() (outages uint64, down int) {
+	e.domainsMu.Lock()
+	defer e.domainsMu.Unlock()
+
+	for _, state := range e.domains {
+		outages += state.outages
+		if state.down {
+			down++
+		}
+	}
+	return outages, down
+}
+
+func (s *domainState) status() DomainStatus {
+	return DomainStatus{
+		Name:       s.domain.Name,
+		Members:    len(s.members),
+		Down:       s.down,
+		Until:      s.until,
+		Outages:    s.outages,
+		LastOutage: s.lastOutage,
+	}
+}
+
+// runDomainScheduler 到期時恢復停擺中的故障域，並依 outage_interval 自動觸發停擺
+func (e *Engine) runDomainScheduler(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			var due []string
+			e.domainsMu.Lock()
+			for name, state := range e.domains {
+				if state.down {
+					if !now.Before(state.until) {
+						e.restoreDomainLocked(state, now)
+					}
+					continue
+				}
+				interval := state.domain.OutageInterval
+				if interval > 0 && now.Sub(state.lastRestore) >= interval {
+					due = append(due, name)
+				}
+			}
+			e.domainsMu.Unlock()
+
+			for _, name := range due {
+				if _, err := e.DomainOutage(name, 0); err != nil {
+					e.logger.Warn(T("自動故障域停擺失敗"), zap.String("domain", name), zap.Error(err))
+				}
+			}
+		}
+	}
+}
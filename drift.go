@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultDriftInterval 預設漂移檢查間隔
+const DefaultDriftInterval = time.Minute
+
+// 漂移欄位
+const (
+	DriftMissing    = "missing"    // 基準中有、運行中缺少的暫存器
+	DriftUnexpected = "unexpected" // 運行中多出的暫存器
+	DriftValue      = "value"      // 可寫入暫存器的值與基準不同
+)
+
+// registerBaseline Slave 暫存器的比對基準 (元資料與可寫入暫存器的值)
+type registerBaseline struct {
+	metas  []RegisterMeta
+	values map[uint16]float64
+	at     time.Time
+}
+
+// RegisterDrift 單一暫存器與基準的差異
+type RegisterDrift struct {
+	Address  uint16 `json:"address"`
+	Name     string `json:"name"`
+	Field    string `json:"field"` // missing | unexpected | value 或元資料欄位 (name、data_type、scale、unit、writable、expression)
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+// SlaveDrift 單一 Slave 的漂移
+type SlaveDrift struct {
+	Slave      string          `json:"slave"`
+	BaselineAt time.Time       `json:"baseline_at,omitempty"` // 重新設定基準的時間，使用設定檔時為空
+	Drifts     []RegisterDrift `json:"drifts"`
+}
+
+// DriftReport 全部 Slave 的漂移檢查結果
+type DriftReport struct {
+	CheckedAt time.Time    `json:"checked_at"`
+	Profile   string       `json:"profile"`
+	Total     int          `json:"total"`
+	Drifted   int          `json:"drifted"`
+	Slaves    []SlaveDrift `json:"slaves"` // 僅列出有漂移者
+}
+
+// BaselineStatus 重新設定基準的結果
+type BaselineStatus struct {
+	Rebaselined int       `json:"rebaselined"`
+	At          time.Time `json:"at"`
+}
+
+// snapshotBaseline 擷取暫存器映射表目前的元資料與可寫入暫存器的值
+func snapshotBaseline(rm *RegisterMap) *registerBaseline {
+	baseline := &registerBaseline{
+		metas:  rm.Definitions(),
+		values: make(map[uint16]float64),
+		at:     time.Now(),
+	}
+	for _, meta := range baseline.metas {
+		if !meta.Writable {
+			continue
+		}
+		if value, err := rm.GetScaledValue(meta.Address); err == nil {
+			baseline.values[meta.Address] = value
+		}
+	}
+	return baseline
+}
+
+// diff 比對暫存器映射表與基準 (僅比較可寫入暫存器的值，其餘由場景持續更新)
+func (b *registerBaseline) diff(rm *RegisterMap) []RegisterDrift {
+	var drifts []RegisterDrift
+
+	live := make(map[uint16]RegisterMeta)
+	for _, meta := range rm.Definitions() {
+		live[meta.Address] = meta
+	}
+
+	for _, want := range b.metas {
+		got, ok := live[want.Address]
+		if !ok {
+			drifts = append(drifts, RegisterDrift{Address: want.Address, Name: want.Name, Field: DriftMissing})
+			continue
+		}
+		delete(live, want.Address)
+
+		field := func(name, expected, actual string) {
+			if expected != actual {
+				drifts = append(drifts, RegisterDrift{Address: want.Address, Name: want.Name, Field: name, Expected: expected, Actual: actual})
+			}
+		}
+		field("name", want.Name, got.Name)
+		field("data_type", want.DataType.String(), got.DataType.String())
+		field("scale", formatDriftFloat(want.Scale), formatDriftFloat(got.Scale))
+		field("unit", want.Unit, got.Unit)
+		field("writable", strconv.FormatBool(want.Writable), strconv.FormatBool(got.Writable))
+		field("expression", want.Expression, got.Expression)
+
+		if expected, ok := b.values[want.Address]; ok {
+			if actual, err := rm.GetScaledValue(want.Address); err == nil {
+				field(DriftValue, formatDriftFloat(expected), formatDriftFloat(actual))
+			}
+		}
+	}
+
+	for _, meta := range live {
+		drifts = append(drifts, RegisterDrift{Address: meta.Address, Name: meta.Name, Field: DriftUnexpected})
+	}
+	sort.SliceStable(drifts, func(i, j int) bool { return drifts[i].Address < drifts[j].Address })
+	return drifts
+}
+
+// formatDriftFloat 以最短表示輸出數值
+func formatDriftFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// driftProfileName 指派給 Slave 的設定檔名稱 (未指定時為單相電表)
+func (e *Engine) driftProfileName() string {
+	if e.config.Slaves.Profile != "" {
+		return e.config.Slaves.Profile
+	}
+	return ProfileSinglePhase
+}
+
+// initDrift 依設定檔建立共用基準並清除個別基準 (與 startSlave 建立暫存器的方式一致)
+func (e *Engine) initDrift() {
+	rm := DefaultRegisterMap()
+	if profile, ok := GetDeviceProfile(e.config.Slaves.Profile); ok {
+		var err error
+		if rm, err = profile.NewRegisterMap(); err != nil {
+			rm = nil
+		}
+	}
+
+	e.driftMu.Lock()
+	defer e.driftMu.Unlock()
+	e.profileBaseline = nil
+	if rm != nil {
+		e.profileBaseline = snapshotBaseline(rm)
+		e.profileBaseline.at = time.Time{}
+	}
+	e.baselines = make(map[string]*registerBaseline)
+	e.drifted = make(map[string]bool)
+}
+
+// CheckDrift 比對所有 Slave 的暫存器與基準 (個別重新設定過基準者以其基準為準，否則以設定檔為準)
+func (e *Engine) CheckDrift() DriftReport {
+	slaves := e.ListSlaves()
+	sort.Slice(slaves, func(i, j int) bool { return slaves[i].ID < slaves[j].ID })
+
+	e.driftMu.Lock()
+	defer e.driftMu.Unlock()
+
+	report := DriftReport{
+		CheckedAt: time.Now(),
+		Profile:   e.driftProfileName(),
+		Total:     len(slaves),
+		Slaves:    []SlaveDrift{},
+	}
+	if e.profileBaseline == nil {
+		return report
+	}
+
+	drifted := make(map[string]bool)
+	for _, slave := range slaves {
+		baseline := e.profileBaseline
+		if b, ok := e.baselines[slave.ID]; ok {
+			baseline = b
+		}
+
+		drifts := baseline.diff(slave.Registers())
+		if len(drifts) == 0 {
+			if e.drifted[slave.ID] {
+				e.logger.Info(T("配置漂移已消除"), zap.String("slave", slave.ID))
+			}
+			continue
+		}
+
+		drifted[slave.ID] = true
+		report.Slaves = append(report.Slaves, SlaveDrift{Slave: slave.ID, BaselineAt: baseline.at, Drifts: drifts})
+		if !e.drifted[slave.ID] {
+			e.logger.Warn(T("偵測到配置漂移"),
+				zap.String("slave", slave.ID),
+				zap.Int("registers", len(drifts)),
+				zap.String("first_field", drifts[0].Field),
+				zap.Uint16("first_address", drifts[0].Address),
+			)
+		}
+	}
+	e.drifted = drifted
+	report.Drifted = len(drifted)
+	return report
+}
+
+// Rebaseline 以 Slave 目前的暫存器元資料與可寫入值作為新的基準 (未指定時為全部 Slave)
+func (e *Engine) Rebaseline(slaves ...*Slave) BaselineStatus {
+	if len(slaves) == 0 {
+		slaves = e.ListSlaves()
+	}
+
+	e.driftMu.Lock()
+	defer e.driftMu.Unlock()
+
+	if e.baselines == nil {
+		e.baselines = make(map[string]*registerBaseline)
+	}
+	status := BaselineStatus{At: time.Now()}
+	for _, slave := range slaves {
+		baseline := snapshotBaseline(slave.Registers())
+		baseline.at = status.At
+		e.baselines[slave.ID] = baseline
+		status.Rebaselined++
+	}
+
+	e.logger.Info(T("已重新設定配置基準"), zap.Int("slaves", status.Rebaselined))
+	return status
+}
+
+// DriftedSlaves 上次檢查時有漂移的 Slave 數
+func (e *Engine) DriftedSlaves() int {
+	e.driftMu.Lock()
+	defer e.driftMu.Unlock()
+	return len(e.drifted)
+}
+
+// runDriftChecker 定期檢查配置漂移
+func (e *Engine) runDriftChecker(ctx context.Context) {
+	ticker := time.NewTicker(e.config.Drift.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.CheckDrift()
+		}
+	}
+}
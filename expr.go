@@ -0,0 +1,452 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 運算式中視為暫存器參照的整數範圍 (保持暫存器 4x 位址)
+const (
+	exprMinAddress = 40001
+	exprMaxAddress = 49999
+)
+
+// Expression 暫存器運算式 (衍生值)
+//
+// 支援 + - * / 與括號，運算元為數值常數、暫存器名稱或 4x 位址 (40001-49999 的整數)；
+// 一律以工程值 (已套用 scale) 計算。可選的 "位址 =" 前綴需與所屬暫存器位址一致，
+// 例如 "40007 = 40001 * 40002 * 40006" 或 "LineVoltage * LineCurrent * PowerFactor"。
+type Expression struct {
+	source string
+	target uint16 // "位址 =" 前綴指定的位址，0 表示未指定
+	root   exprNode
+	refs   []string
+}
+
+// exprNode 運算式語法樹節點
+type exprNode interface {
+	eval(lookup func(ref string) (float64, error)) (float64, error)
+}
+
+type exprNumber float64
+
+func (n exprNumber) eval(func(string) (float64, error)) (float64, error) {
+	return float64(n), nil
+}
+
+type exprRef string
+
+func (r exprRef) eval(lookup func(string) (float64, error)) (float64, error) {
+	return lookup(string(r))
+}
+
+type exprNegate struct{ x exprNode }
+
+func (n exprNegate) eval(lookup func(string) (float64, error)) (float64, error) {
+	v, err := n.x.eval(lookup)
+	return -v, err
+}
+
+type exprBinary struct {
+	op          byte
+	left, right exprNode
+}
+
+func (n exprBinary) eval(lookup func(string) (float64, error)) (float64, error) {
+	l, err := n.left.eval(lookup)
+	if err != nil {
+		return 0, err
+	}
+	r, err := n.right.eval(lookup)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case '+':
+		return l + r, nil
+	case '-':
+		return l - r, nil
+	case '*':
+		return l * r, nil
+	default:
+		if r == 0 {
+			return 0, errors.New(T("運算式除以零"))
+		}
+		return l / r, nil
+	}
+}
+
+// ParseExpression 解析暫存器運算式
+func ParseExpression(source string) (*Expression, error) {
+	expr := &Expression{source: source}
+
+	body := source
+	if lhs, rhs, ok := strings.Cut(source, "="); ok {
+		target, err := strconv.ParseUint(strings.TrimSpace(lhs), 10, 16)
+		if err != nil || target < exprMinAddress || target > exprMaxAddress {
+			return nil, fmt.Errorf(T("運算式左側必須是暫存器位址: %s"), strings.TrimSpace(lhs))
+		}
+		expr.target = uint16(target)
+		body = rhs
+	}
+
+	p := &exprParser{src: body}
+	root, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, p.errorf()
+	}
+	expr.root = root
+
+	seen := make(map[string]bool)
+	for _, ref := range p.refs {
+		if !seen[ref] {
+			seen[ref] = true
+			expr.refs = append(expr.refs, ref)
+		}
+	}
+	return expr, nil
+}
+
+// String 運算式原文
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Refs 運算式參照的暫存器 (名稱或位址字串，依出現順序且不重複)
+func (e *Expression) Refs() []string {
+	return e.refs
+}
+
+// Eval 以 lookup 取得參照值並計算結果
+func (e *Expression) Eval(lookup func(ref string) (float64, error)) (float64, error) {
+	return e.root.eval(lookup)
+}
+
+// exprParser 遞迴下降解析器
+type exprParser struct {
+	src  string
+	pos  int
+	refs []string
+}
+
+func (p *exprParser) errorf() error {
+	return fmt.Errorf(T("運算式語法錯誤: %q (位置 %d)"), p.src, p.pos+1)
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// parseSum sum := product (('+' | '-') product)*
+func (p *exprParser) parseSum() (exprNode, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) || (p.src[p.pos] != '+' && p.src[p.pos] != '-') {
+			return left, nil
+		}
+		op := p.src[p.pos]
+		p.pos++
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = exprBinary{op: op, left: left, right: right}
+	}
+}
+
+// parseProduct product := unary (('*' | '/') unary)*
+func (p *exprParser) parseProduct() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) || (p.src[p.pos] != '*' && p.src[p.pos] != '/') {
+			return left, nil
+		}
+		op := p.src[p.pos]
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = exprBinary{op: op, left: left, right: right}
+	}
+}
+
+// parseUnary unary := '-' unary | primary
+func (p *exprParser) parseUnary() (exprNode, error) {
+	p.skipSpace()
+	if p.pos < len(p.src) && p.src[p.pos] == '-' {
+		p.pos++
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return exprNegate{x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary primary := number | name | '(' sum ')'
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return nil, p.errorf()
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case c == '(':
+		p.pos++
+		inner, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.pos >= len(p.src) || p.src[p.pos] != ')' {
+			return nil, p.errorf()
+		}
+		p.pos++
+		return inner, nil
+
+	case isExprDigit(c) || c == '.':
+		start := p.pos
+		for p.pos < len(p.src) && (isExprDigit(p.src[p.pos]) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		token := p.src[start:p.pos]
+		// 4x 範圍內的整數視為暫存器位址；要當常數使用時請加小數點 (例如 40001.0)
+		if addr, err := strconv.ParseUint(token, 10, 16); err == nil && addr >= exprMinAddress && addr <= exprMaxAddress {
+			p.refs = append(p.refs, token)
+			return exprRef(token), nil
+		}
+		value, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			p.pos = start
+			return nil, p.errorf()
+		}
+		return exprNumber(value), nil
+
+	case isExprLetter(c):
+		start := p.pos
+		for p.pos < len(p.src) && (isExprLetter(p.src[p.pos]) || isExprDigit(p.src[p.pos])) {
+			p.pos++
+		}
+		name := p.src[start:p.pos]
+		p.refs = append(p.refs, name)
+		return exprRef(name), nil
+	}
+
+	return nil, p.errorf()
+}
+
+func isExprDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isExprLetter(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// resolveExprRef 將參照 (名稱或位址字串) 對應到暫存器位址
+func resolveExprRef(ref string, names map[string]uint16, addresses map[uint16]bool) (uint16, bool) {
+	if addr, err := strconv.ParseUint(ref, 10, 16); err == nil {
+		return uint16(addr), addresses[uint16(addr)]
+	}
+	addr, ok := names[ref]
+	return addr, ok
+}
+
+// orderExpressions 依相依關係排序衍生暫存器 (被參照者先計算)，有循環參照時回傳錯誤
+func orderExpressions(deps map[uint16][]uint16) ([]uint16, error) {
+	targets := make([]uint16, 0, len(deps))
+	for addr := range deps {
+		targets = append(targets, addr)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i] < targets[j] })
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[uint16]int, len(deps))
+	order := make([]uint16, 0, len(deps))
+
+	var visit func(addr uint16) error
+	visit = func(addr uint16) error {
+		switch state[addr] {
+		case visiting:
+			return fmt.Errorf(T("運算式循環參照: %d"), addr)
+		case done:
+			return nil
+		}
+		state[addr] = visiting
+		for _, dep := range deps[addr] {
+			if _, derived := deps[dep]; derived {
+				if err := visit(dep); err != nil {
+					return err
+				}
+			}
+		}
+		state[addr] = done
+		order = append(order, addr)
+		return nil
+	}
+
+	for _, addr := range targets {
+		if err := visit(addr); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// validateExpressions 檢查暫存器定義中的運算式 (語法、位址前綴、參照存在、無循環)
+func validateExpressions(defs []RegisterDefinition) error {
+	names := make(map[string]uint16, len(defs))
+	addresses := make(map[uint16]bool, len(defs))
+	for _, def := range defs {
+		names[def.Name] = def.Address
+		addresses[def.Address] = true
+	}
+
+	deps := make(map[uint16][]uint16)
+	for _, def := range defs {
+		if def.Expression == "" {
+			continue
+		}
+		_, addrs, err := compileExpression(def.Address, def.Expression, names, addresses)
+		if err != nil {
+			return fmt.Errorf(T("暫存器 %s (%d): %w"), def.Name, def.Address, err)
+		}
+		if def.Writable {
+			return fmt.Errorf(T("暫存器 %s (%d): 衍生暫存器不可設為可寫入"), def.Name, def.Address)
+		}
+		deps[def.Address] = addrs
+	}
+
+	_, err := orderExpressions(deps)
+	return err
+}
+
+// compileExpression 解析運算式並解析參照位址
+func compileExpression(address uint16, source string, names map[string]uint16, addresses map[uint16]bool) (*Expression, []uint16, error) {
+	expr, err := ParseExpression(source)
+	if err != nil {
+		return nil, nil, err
+	}
+	if expr.target != 0 && expr.target != address {
+		return nil, nil, fmt.Errorf(T("運算式左側位址 %d 與暫存器位址 %d 不符"), expr.target, address)
+	}
+
+	addrs := make([]uint16, 0, len(expr.refs))
+	for _, ref := range expr.refs {
+		addr, ok := resolveExprRef(ref, names, addresses)
+		if !ok {
+			return nil, nil, fmt.Errorf(T("運算式參照未定義的暫存器: %s"), ref)
+		}
+		addrs = append(addrs, addr)
+	}
+	return expr, addrs, nil
+}
+
+// derivedRegister 已編譯的衍生暫存器
+type derivedRegister struct {
+	address uint16
+	expr    *Expression
+	refs    map[string]uint16
+}
+
+// SetExpression 設定暫存器的運算式，之後由 EvaluateExpressions 依相依順序計算
+// (參照的暫存器需已定義)
+func (rm *RegisterMap) SetExpression(address uint16, source string) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	meta, ok := rm.definitions[address]
+	if !ok {
+		return fmt.Errorf(T("暫存器 %d 未定義"), address)
+	}
+
+	names := make(map[string]uint16, len(rm.definitions))
+	addresses := make(map[uint16]bool, len(rm.definitions))
+	for addr, m := range rm.definitions {
+		names[m.Name] = addr
+		addresses[addr] = true
+	}
+
+	expr, addrs, err := compileExpression(address, source, names, addresses)
+	if err != nil {
+		return err
+	}
+
+	derived := make(map[uint16]*derivedRegister, len(rm.derived)+1)
+	for _, d := range rm.derived {
+		derived[d.address] = d
+	}
+	refs := make(map[string]uint16, len(addrs))
+	for i, ref := range expr.refs {
+		refs[ref] = addrs[i]
+	}
+	derived[address] = &derivedRegister{address: address, expr: expr, refs: refs}
+
+	deps := make(map[uint16][]uint16, len(derived))
+	for addr, d := range derived {
+		for _, dep := range d.refs {
+			deps[addr] = append(deps[addr], dep)
+		}
+		if deps[addr] == nil {
+			deps[addr] = []uint16{}
+		}
+	}
+	order, err := orderExpressions(deps)
+	if err != nil {
+		return err
+	}
+
+	rm.ownMetaLocked()
+	meta = rm.definitions[address]
+	rm.derived = rm.derived[:0]
+	for _, addr := range order {
+		rm.derived = append(rm.derived, derived[addr])
+	}
+	meta.Expression = source
+	return nil
+}
+
+// EvaluateExpressions 依相依順序重新計算所有衍生暫存器，回傳第一個錯誤 (其餘暫存器照常計算)
+func (rm *RegisterMap) EvaluateExpressions() error {
+	rm.mu.RLock()
+	derived := make([]*derivedRegister, len(rm.derived))
+	copy(derived, rm.derived)
+	rm.mu.RUnlock()
+
+	var firstErr error
+	for _, d := range derived {
+		value, err := d.expr.Eval(func(ref string) (float64, error) {
+			return rm.GetScaledValue(d.refs[ref])
+		})
+		if err == nil {
+			err = rm.SetScaledValue(d.address, value)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf(T("暫存器 %d 運算式 %q: %w"), d.address, d.expr, err)
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// FunctionExceptionNames 功能碼覆寫可用的例外名稱
+var FunctionExceptionNames = map[string]uint8{
+	"illegal_function":           ExceptionCodeIllegalFunction,
+	"illegal_data_address":       ExceptionCodeIllegalDataAddress,
+	"illegal_data_value":         ExceptionCodeIllegalDataValue,
+	"slave_device_failure":       ExceptionCodeSlaveDeviceFailure,
+	"acknowledge":                ExceptionCodeAcknowledge,
+	"slave_device_busy":          ExceptionCodeSlaveDeviceBusy,
+	"memory_parity_error":        ExceptionCodeMemoryParityError,
+	"gateway_path_unavailable":   ExceptionCodeGatewayPathUnavailable,
+	"gateway_target_no_response": ExceptionCodeGatewayTargetNoResponse,
+}
+
+// functionSet 設備支援的功能碼 (nil 表示所有已實作的功能碼)
+type functionSet map[uint8]bool
+
+// newFunctionSet 建立支援的功能碼集合 (未指定時為 nil)
+func newFunctionSet(functions []uint8) functionSet {
+	if len(functions) == 0 {
+		return nil
+	}
+	set := make(functionSet, len(functions))
+	for _, function := range functions {
+		set[function] = true
+	}
+	return set
+}
+
+// supports 是否支援該功能碼
+func (f functionSet) supports(function uint8) bool {
+	return f == nil || f[function]
+}
+
+// WithSupportedFunctions 限制主設備支援的功能碼，其他功能碼回應 Illegal Function (0x01)
+func WithSupportedFunctions(functions ...uint8) SlaveOption {
+	return func(s *Slave) {
+		s.functions = newFunctionSet(functions)
+	}
+}
+
+// FunctionOverride 單一功能碼的行為覆寫
+type FunctionOverride struct {
+	Name      string
+	Exception uint8         // 一律回應的例外碼 (0 表示照常處理)
+	Delay     time.Duration // 回應前額外延遲
+}
+
+// WithFunctionOverride 覆寫指定功能碼的行為；同一功能碼已有覆寫時保留先加入者
+func WithFunctionOverride(override FunctionOverride, functions ...uint8) SlaveOption {
+	return func(s *Slave) {
+		if s.overrides == nil {
+			s.overrides = make(map[uint8]FunctionOverride)
+		}
+		for _, function := range functions {
+			if _, ok := s.overrides[function]; !ok {
+				s.overrides[function] = override
+			}
+		}
+	}
+}
+
+// applyOverride 套用功能碼覆寫：先延遲，再視需要以例外取代正常處理
+func (h *RequestHandler) applyOverride(frame *tcpFrame) (response []byte, overridden bool) {
+	override, ok := h.slave.overrides[frame.Function]
+	if !ok {
+		return nil, false
+	}
+	if override.Delay > 0 {
+		time.Sleep(override.Delay)
+	}
+	if override.Exception == 0 {
+		return nil, false
+	}
+	h.logger.Debug(T("功能碼覆寫，回應例外"),
+		zap.String("rule", override.Name),
+		zap.Uint8("function", frame.Function),
+		zap.Uint8("exception", override.Exception),
+	)
+	return frame.exception(override.Exception), true
+}
+
+// --- 配置 ---
+
+// Validate 驗證功能碼覆寫規則
+func (r FunctionOverrideRule) Validate(tags map[string][]string) error {
+	if r.Name == "" {
+		return errors.New(T("功能碼覆寫規則必須指定名稱"))
+	}
+	if len(r.Functions) == 0 {
+		return fmt.Errorf(T("功能碼覆寫規則 %s 必須指定功能碼"), r.Name)
+	}
+	for _, function := range r.Functions {
+		if function == 0 || function >= 0x80 {
+			return fmt.Errorf(T("功能碼覆寫規則 %s 的功能碼無效: %d"), r.Name, function)
+		}
+	}
+	if r.Exception != "" {
+		if _, ok := FunctionExceptionNames[r.Exception]; !ok {
+			return fmt.Errorf(T("功能碼覆寫規則 %s 的例外名稱無效: %s"), r.Name, r.Exception)
+		}
+	}
+	if r.Exception == "" && r.Delay <= 0 {
+		return fmt.Errorf(T("功能碼覆寫規則 %s 必須指定 exception 或 delay"), r.Name)
+	}
+	for _, target := range r.Targets {
+		if net.ParseIP(target) == nil {
+			if _, _, err := net.ParseCIDR(target); err != nil {
+				return fmt.Errorf(T("功能碼覆寫規則 %s 的目標無效: %s"), r.Name, target)
+			}
+		}
+	}
+	for _, tag := range r.Tags {
+		if _, ok := tags[tag]; !ok {
+			return fmt.Errorf(T("功能碼覆寫規則 %s 使用未定義的 Slave 標籤: %s"), r.Name, tag)
+		}
+	}
+	for _, profile := range r.Profiles {
+		if _, ok := GetDeviceProfile(profile); !ok {
+			return fmt.Errorf(T("功能碼覆寫規則 %s 的設備設定檔不存在: %s"), r.Name, profile)
+		}
+	}
+	return nil
+}
+
+// profileFunctions 設定檔支援的功能碼 (slaves.profile_functions 優先於設定檔宣告的集合；nil 表示全部)
+func (c *Config) profileFunctions(profile *DeviceProfile) []uint8 {
+	if functions, ok := c.Slaves.ProfileFunctions[profile.Name]; ok {
+		return functions
+	}
+	return profile.Functions
+}
+
+// override 轉為 Slave 的功能碼覆寫
+func (r FunctionOverrideRule) override() FunctionOverride {
+	return FunctionOverride{
+		Name:      r.Name,
+		Exception: FunctionExceptionNames[r.Exception],
+		Delay:     r.Delay,
+	}
+}
+
+// functionOverrideRules 符合 Slave (IP 或設備設定檔) 的所有功能碼覆寫規則 (依配置順序)
+func (c *Config) functionOverrideRules(ip net.IP, profile string) []FunctionOverrideRule {
+	var rules []FunctionOverrideRule
+	for _, rule := range c.FunctionOverrides {
+		if rule.matches(c, ip, profile) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// matches 規則是否套用到 Slave (符合 targets、tags、profiles 任一項即可)
+func (r FunctionOverrideRule) matches(c *Config, ip net.IP, profile string) bool {
+	if len(r.Targets) == 0 && len(r.Tags) == 0 && len(r.Profiles) == 0 {
+		return true
+	}
+	if len(r.Targets) > 0 && MatchTargets(ip, r.Targets) {
+		return true
+	}
+	for _, tag := range r.Tags {
+		if c.hasTag(ip, tag) {
+			return true
+		}
+	}
+	for _, p := range r.Profiles {
+		if p == profile {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package main
+
+import "os"
+
+// syncDir 將目錄項目寫入磁碟 (更名後 fsync 目錄，當機後新的檔名才確定存在)
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package main
+
+// syncDir Windows 無法開啟目錄 fsync；NTFS 的更名由檔案系統日誌保證，不需另外處理
+func syncDir(dir string) error {
+	return nil
+}
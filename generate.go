@@ -0,0 +1,508 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ComposeOptions docker-compose 部署檔的參數
+type ComposeOptions struct {
+	Count   int    // 容器 (Slave) 數
+	Subnet  string // macvlan 網段 (CIDR)
+	Gateway string // 網段的閘道 (空字串 = 網段的第一個位址)
+	StartIP string // 第一個容器的 IP (空字串 = 閘道的下一個位址)
+	Parent  string // macvlan 的上層介面
+	Image   string // 模擬器映像
+	Name    string // 服務名稱前綴
+	Port    int    // Modbus TCP 埠號
+	Profile string // 設備設定檔 (空字串 = 映像內配置檔的設定)
+	Config  string // 掛載到每個容器的主機配置檔 (空字串 = 映像內建的配置檔)
+}
+
+// DefaultComposeOptions 預設的 docker-compose 參數
+func DefaultComposeOptions() ComposeOptions {
+	return ComposeOptions{
+		Count:  10,
+		Parent: "eth0",
+		Image:  "modbussim:latest",
+		Name:   "modbussim",
+		Port:   ModbusTCPDefaultPort,
+	}
+}
+
+// composeNamePattern docker-compose 服務名稱允許的字元
+var composeNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// composeService 單一容器
+type composeService struct {
+	Name    string
+	IP      string
+	Command []string
+}
+
+// composeTemplate 每個容器一個 Slave，各自以 macvlan 網路上的獨立 IP 監聽
+var composeTemplate = template.Must(template.New("compose").Funcs(template.FuncMap{"quote": strconv.Quote}).Parse(
+	`# 由 modbussim generate docker-compose 產生：{{len .Services}} 個容器，各自擁有 macvlan 網路上的獨立 IP
+x-modbussim: &modbussim
+  image: {{quote .Image}}
+  restart: unless-stopped
+{{- if .Config}}
+  volumes:
+    - {{quote (print .Config ":/app/configs/config.json:ro")}}
+{{- end}}
+  logging:
+    driver: json-file
+    options:
+      max-size: "10m"
+      max-file: "3"
+
+services:
+{{- range .Services}}
+  {{.Name}}:
+    <<: *modbussim
+    command: [{{range $i, $arg := .Command}}{{if $i}}, {{end}}{{quote $arg}}{{end}}]
+    networks:
+      modbus:
+        ipv4_address: {{.IP}}
+{{- end}}
+
+networks:
+  modbus:
+    driver: macvlan
+    driver_opts:
+      parent: {{quote .Parent}}
+    ipam:
+      config:
+        - subnet: {{.Subnet}}
+          gateway: {{.Gateway}}
+`))
+
+// GenerateCompose 產生 docker-compose 部署檔 (供禁止在主機上配置 IP 別名的環境使用)
+func GenerateCompose(opts ComposeOptions) ([]byte, error) {
+	if opts.Count < 1 {
+		return nil, errors.New(T("Slave 數量必須大於 0"))
+	}
+	if !composeNamePattern.MatchString(opts.Name) {
+		return nil, fmt.Errorf(T("無效的服務名稱: %s"), opts.Name)
+	}
+	if opts.Port < 1 || opts.Port > 65535 {
+		return nil, fmt.Errorf(T("無效的埠號: %d"), opts.Port)
+	}
+	if opts.Profile != "" {
+		if _, ok := GetDeviceProfile(opts.Profile); !ok {
+			return nil, fmt.Errorf(T("未知的設備設定檔: %s"), opts.Profile)
+		}
+	}
+
+	subnet, gateway, ips, err := composeAddresses(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	width := len(strconv.Itoa(opts.Count))
+	services := make([]composeService, len(ips))
+	for i, ip := range ips {
+		command := []string{"start", "-c", "/app/configs/config.json", "--ip", ip.String(), "--count", "1", "--port", strconv.Itoa(opts.Port)}
+		if opts.Profile != "" {
+			command = append(command, "--profile", opts.Profile)
+		}
+		services[i] = composeService{
+			Name:    fmt.Sprintf("%s-%0*d", opts.Name, width, i+1),
+			IP:      ip.String(),
+			Command: command,
+		}
+	}
+
+	var buf bytes.Buffer
+	err = composeTemplate.Execute(&buf, struct {
+		ComposeOptions
+		Subnet   string
+		Gateway  string
+		Services []composeService
+	}{opts, subnet.String(), gateway.String(), services})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// composeAddresses 解析網段與閘道，並自起始 IP 依序配置 (略過閘道與廣播位址) 各容器的 IP
+func composeAddresses(opts ComposeOptions) (*net.IPNet, net.IP, []net.IP, error) {
+	_, subnet, err := net.ParseCIDR(opts.Subnet)
+	if err != nil || subnet.IP.To4() == nil {
+		return nil, nil, nil, fmt.Errorf(T("無效的 CIDR: %s"), opts.Subnet)
+	}
+
+	gateway := net.ParseIP(opts.Gateway).To4()
+	if opts.Gateway == "" {
+		gateway = make(net.IP, net.IPv4len)
+		copy(gateway, subnet.IP.To4())
+		incIP(gateway)
+	}
+	if gateway == nil || !subnet.Contains(gateway) {
+		return nil, nil, nil, fmt.Errorf(T("閘道 %s 不在網段 %s 內"), opts.Gateway, subnet)
+	}
+
+	next := net.ParseIP(opts.StartIP).To4()
+	if opts.StartIP == "" {
+		next = make(net.IP, net.IPv4len)
+		copy(next, gateway)
+		incIP(next)
+	}
+	if next == nil || !subnet.Contains(next) {
+		return nil, nil, nil, fmt.Errorf(T("無效的起始 IP: %s"), opts.StartIP)
+	}
+
+	broadcast := make(net.IP, net.IPv4len)
+	for i := range broadcast {
+		broadcast[i] = subnet.IP.To4()[i] | ^subnet.Mask[i]
+	}
+
+	ips := make([]net.IP, 0, opts.Count)
+	for len(ips) < opts.Count {
+		if !subnet.Contains(next) || next.Equal(broadcast) {
+			return nil, nil, nil, fmt.Errorf(T("網段 %s 的可用位址不足 %d 個"), subnet, opts.Count)
+		}
+		if !next.Equal(gateway) && !next.Equal(subnet.IP) {
+			ip := make(net.IP, net.IPv4len)
+			copy(ip, next)
+			ips = append(ips, ip)
+		}
+		incIP(next)
+	}
+	return subnet, gateway, ips, nil
+}
+
+// K8sOptions Kubernetes 部署檔的參數
+type K8sOptions struct {
+	Replicas  int    // Pod (Slave) 數
+	Name      string // StatefulSet/Service 名稱
+	Namespace string // 命名空間 (空字串 = kubectl 的目前命名空間)
+	Image     string // 模擬器映像
+	Port      int    // Modbus TCP 埠號
+	Profile   string // 設備設定檔 (空字串 = 映像內配置檔的設定)
+	ConfigMap string // 掛載為 /app/configs 的 ConfigMap (空字串 = 映像內建的配置檔)
+
+	// Multus：以 macvlan 附加網路給每個 Pod 現場網段上的 IP (Subnet 空字串 = 以叢集網路的 Pod IP 監聽)
+	Subnet     string // 附加網路的網段 (CIDR)
+	RangeStart string // whereabouts 配置的第一個 IP (空字串 = 網段的第一個可用位址)
+	RangeEnd   string // whereabouts 配置的最後一個 IP (空字串 = 網段的最後一個可用位址)
+	Gateway    string // 附加網路的閘道 (空字串 = 不設定)
+	Parent     string // 節點上 macvlan 的上層介面
+}
+
+// DefaultK8sOptions 預設的 Kubernetes 參數
+func DefaultK8sOptions() K8sOptions {
+	return K8sOptions{
+		Replicas: 10,
+		Name:     "modbussim",
+		Image:    "modbussim:latest",
+		Port:     ModbusTCPDefaultPort,
+		Parent:   "eth0",
+	}
+}
+
+// k8sNamePattern Kubernetes 資源名稱 (DNS-1123 label)
+var k8sNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// k8sAttachmentInterface Multus 附加網路在 Pod 內的介面名稱
+const k8sAttachmentInterface = "net1"
+
+// k8sTemplate 每個 Pod 一個 Slave：StatefulSet 序號決定 Unit ID 與亂數序列，水平擴展只需調整 replicas
+// (使用附加網路時 Slave 只在 net1 上監聽，kubelet 無法以 Pod IP 探測，不設 readinessProbe)
+var k8sTemplate = template.Must(template.New("k8s").Funcs(template.FuncMap{"quote": strconv.Quote}).Parse(
+	`# 由 modbussim generate k8s 產生：{{.Replicas}} 個 Pod，每個 Pod 以 Pod 身分 (slaves.identity=pod) 運行一個 Slave
+{{- if .Attachment}}
+---
+apiVersion: k8s.cni.cncf.io/v1
+kind: NetworkAttachmentDefinition
+metadata:
+  name: {{.Attachment}}
+{{- if .Namespace}}
+  namespace: {{.Namespace}}
+{{- end}}
+spec:
+  config: {{quote .AttachmentConfig}}
+{{- end}}
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: {{.Name}}
+{{- if .Namespace}}
+  namespace: {{.Namespace}}
+{{- end}}
+  labels:
+    app.kubernetes.io/name: {{.Name}}
+spec:
+  clusterIP: None
+  selector:
+    app.kubernetes.io/name: {{.Name}}
+  ports:
+    - name: modbus
+      port: {{.Port}}
+      targetPort: modbus
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: {{.Name}}
+{{- if .Namespace}}
+  namespace: {{.Namespace}}
+{{- end}}
+  labels:
+    app.kubernetes.io/name: {{.Name}}
+spec:
+  serviceName: {{.Name}}
+  replicas: {{.Replicas}}
+  podManagementPolicy: Parallel
+  selector:
+    matchLabels:
+      app.kubernetes.io/name: {{.Name}}
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/name: {{.Name}}
+{{- if .Attachment}}
+      annotations:
+        k8s.v1.cni.cncf.io/networks: {{.Attachment}}
+{{- end}}
+    spec:
+      containers:
+        - name: modbussim
+          image: {{quote .Image}}
+          args: [{{range $i, $arg := .Args}}{{if $i}}, {{end}}{{quote $arg}}{{end}}]
+          env:
+            - name: POD_NAME
+              valueFrom:
+                fieldRef:
+                  fieldPath: metadata.name
+            - name: POD_NAMESPACE
+              valueFrom:
+                fieldRef:
+                  fieldPath: metadata.namespace
+            - name: POD_IP
+              valueFrom:
+                fieldRef:
+                  fieldPath: status.podIP
+{{- if .Attachment}}
+            - name: MODBUSSIM_POD_INTERFACE
+              value: {{.Interface}}
+{{- end}}
+          ports:
+            - name: modbus
+              containerPort: {{.Port}}
+{{- if not .Attachment}}
+          readinessProbe:
+            tcpSocket:
+              port: modbus
+            periodSeconds: 10
+{{- end}}
+{{- if .ConfigMap}}
+          volumeMounts:
+            - name: config
+              mountPath: /app/configs
+              readOnly: true
+      volumes:
+        - name: config
+          configMap:
+            name: {{.ConfigMap}}
+{{- end}}
+`))
+
+// GenerateK8s 產生 Kubernetes 部署檔 (StatefulSet、headless Service，指定網段時另加 Multus NetworkAttachmentDefinition)
+func GenerateK8s(opts K8sOptions) ([]byte, error) {
+	if opts.Replicas < 1 {
+		return nil, errors.New(T("Slave 數量必須大於 0"))
+	}
+	if !k8sNamePattern.MatchString(opts.Name) {
+		return nil, fmt.Errorf(T("無效的 Kubernetes 資源名稱: %s"), opts.Name)
+	}
+	for _, name := range []string{opts.Namespace, opts.ConfigMap} {
+		if name != "" && !k8sNamePattern.MatchString(name) {
+			return nil, fmt.Errorf(T("無效的 Kubernetes 資源名稱: %s"), name)
+		}
+	}
+	if opts.Port < 1 || opts.Port > 65535 {
+		return nil, fmt.Errorf(T("無效的埠號: %d"), opts.Port)
+	}
+	if opts.Profile != "" {
+		if _, ok := GetDeviceProfile(opts.Profile); !ok {
+			return nil, fmt.Errorf(T("未知的設備設定檔: %s"), opts.Profile)
+		}
+	}
+
+	args := []string{"start", "-c", "/app/configs/config.json", "--identity", SlaveIdentityPod, "--count", "1", "--port", strconv.Itoa(opts.Port)}
+	if opts.Profile != "" {
+		args = append(args, "--profile", opts.Profile)
+	}
+
+	var attachment, attachmentConfig string
+	if opts.Subnet != "" {
+		config, err := k8sAttachmentConfig(opts)
+		if err != nil {
+			return nil, err
+		}
+		attachment, attachmentConfig = opts.Name+"-macvlan", config
+	}
+
+	var buf bytes.Buffer
+	err := k8sTemplate.Execute(&buf, struct {
+		K8sOptions
+		Args             []string
+		Attachment       string
+		AttachmentConfig string
+		Interface        string
+	}{opts, args, attachment, attachmentConfig, k8sAttachmentInterface})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// k8sAttachmentConfig Multus 附加網路的 CNI 配置 (macvlan + whereabouts，跨節點配置不重複的 IP)
+func k8sAttachmentConfig(opts K8sOptions) (string, error) {
+	compose := ComposeOptions{Count: opts.Replicas, Subnet: opts.Subnet, Gateway: opts.Gateway, StartIP: opts.RangeStart}
+	subnet, gateway, ips, err := composeAddresses(compose)
+	if err != nil {
+		return "", err
+	}
+
+	// whereabouts 不會自動避開閘道，明確排除
+	ipam := map[string]any{
+		"type":        "whereabouts",
+		"range":       subnet.String(),
+		"range_start": ips[0].String(),
+		"exclude":     []string{gateway.String() + "/32"},
+	}
+	if opts.RangeEnd != "" {
+		end := net.ParseIP(opts.RangeEnd).To4()
+		if end == nil || !subnet.Contains(end) || bytes.Compare(end, ips[len(ips)-1]) < 0 {
+			return "", fmt.Errorf(T("網段 %s 的可用位址不足 %d 個"), subnet, opts.Replicas)
+		}
+		ipam["range_end"] = end.String()
+	}
+	if opts.Gateway != "" {
+		ipam["gateway"] = gateway.String()
+	}
+
+	config, err := json.Marshal(map[string]any{
+		"cniVersion": "0.3.1",
+		"type":       "macvlan",
+		"master":     opts.Parent,
+		"mode":       "bridge",
+		"ipam":       ipam,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(config), nil
+}
+
+// SystemdOptions systemd unit 的參數
+type SystemdOptions struct {
+	Name         string        // unit 名稱 (不含 .service)
+	Binary       string        // 模擬器執行檔 (絕對路徑)
+	Config       string        // 配置檔 (絕對路徑)
+	User         string        // 綁定後降級的使用者 (空字串 = 以 root 運行)
+	Group        string        // 綁定後降級的群組 (空字串 = 使用者的主要群組)
+	SetupNetwork bool          // 啟動時配置虛擬 IP
+	Snapshot     string        // 關閉時保存、啟動時接續的快照檔 (空字串 = 不保存)
+	StartTimeout time.Duration // 等待所有 Slave 完成綁定的上限 (TimeoutStartSec)
+}
+
+// DefaultSystemdOptions 預設的 systemd 參數
+func DefaultSystemdOptions() SystemdOptions {
+	return SystemdOptions{
+		Name:         "modbussim",
+		Binary:       "/usr/local/bin/modbussim",
+		Config:       "/etc/modbussim/config.json",
+		StartTimeout: 5 * time.Minute,
+	}
+}
+
+// systemdNamePattern systemd unit 名稱可用的字元
+var systemdNamePattern = regexp.MustCompile(`^[a-zA-Z0-9:_.@-]+$`)
+
+// systemdTemplate Type=notify：所有 Slave 完成綁定後模擬器才回報 READY=1，
+// 依賴此服務的 unit 啟動時整個 Slave 群已可輪詢
+var systemdTemplate = template.Must(template.New("systemd").Funcs(template.FuncMap{"arg": systemdArg}).Parse(
+	`# 由 modbussim generate systemd 產生：安裝為 /etc/systemd/system/{{.Name}}.service
+[Unit]
+Description=Modbus TCP slave simulator
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=notify
+NotifyAccess=main
+ExecStart={{range $i, $arg := .Args}}{{if $i}} {{end}}{{arg $arg}}{{end}}
+Restart=on-failure
+RestartSec=5s
+TimeoutStartSec={{.TimeoutStart}}
+LimitNOFILE=1048576
+
+[Install]
+WantedBy=multi-user.target
+`))
+
+// systemdArg 跳脫 ExecStart 的參數：% 與 $ 重複一次，含空白或引號時加上雙引號
+func systemdArg(arg string) string {
+	arg = strings.NewReplacer("%", "%%", "$", "$$").Replace(arg)
+	if arg == "" || strings.ContainsAny(arg, " \t\"'\\;") {
+		return strconv.Quote(arg)
+	}
+	return arg
+}
+
+// GenerateSystemd 產生 systemd service unit (Type=notify，所有 Slave 完成綁定後才視為啟動完成)
+func GenerateSystemd(opts SystemdOptions) ([]byte, error) {
+	if !systemdNamePattern.MatchString(opts.Name) {
+		return nil, fmt.Errorf(T("無效的 unit 名稱: %s"), opts.Name)
+	}
+	for _, path := range []string{opts.Binary, opts.Config} {
+		if !filepath.IsAbs(path) {
+			return nil, fmt.Errorf(T("systemd unit 必須使用絕對路徑: %s"), path)
+		}
+	}
+	if opts.Snapshot != "" && !filepath.IsAbs(opts.Snapshot) {
+		return nil, fmt.Errorf(T("systemd unit 必須使用絕對路徑: %s"), opts.Snapshot)
+	}
+	if opts.StartTimeout <= 0 {
+		return nil, fmt.Errorf(T("無效的啟動逾時: %s"), opts.StartTimeout)
+	}
+
+	args := []string{opts.Binary, "start", "-c", opts.Config}
+	if opts.SetupNetwork {
+		args = append(args, "--setup-network")
+	}
+	if opts.User != "" {
+		args = append(args, "--user", opts.User)
+	}
+	if opts.Group != "" {
+		args = append(args, "--group", opts.Group)
+	}
+	if opts.Snapshot != "" {
+		args = append(args, "--snapshot", opts.Snapshot)
+	}
+
+	var buf bytes.Buffer
+	err := systemdTemplate.Execute(&buf, struct {
+		SystemdOptions
+		Args         []string
+		TimeoutStart string
+	}{opts, args, fmt.Sprintf("%ds", int64((opts.StartTimeout+time.Second-1)/time.Second))})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
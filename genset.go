@@ -0,0 +1,191 @@
+package main
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// 發電機組 (Genset) 暫存器位址
+const (
+	AddrGensetState      uint16 = 40050 // 運轉狀態 (0 停機、1 起動中、2 運轉中、3 額定輸出)
+	AddrGensetSpeed      uint16 = 40051 // 引擎轉速 (rpm)
+	AddrGensetFuelLevel  uint16 = 40052 // 燃油存量 (%)
+	AddrGensetRunHours   uint16 = 40053 // 累計運轉時數 (h)
+	AddrGensetPower      uint16 = 40055 // 輸出功率 (W)
+	AddrGensetRatedPower uint16 = 40057 // 額定功率 (kW)
+	AddrGensetCrankTime  uint16 = 40058 // 起動 (cranking) 時間 (s，可寫入)
+	AddrGensetRampTime   uint16 = 40059 // 運轉後升至額定輸出的時間 (s，可寫入)
+)
+
+// 發電機組線圈位址
+const (
+	CoilGensetStart   uint16 = 0 // 起動指令 (可寫入，ON 起動、OFF 停機)
+	CoilGensetRunning uint16 = 1 // 引擎運轉狀態
+)
+
+// 發電機組運轉狀態
+const (
+	GensetStopped  = 0
+	GensetCranking = 1
+	GensetRunning  = 2
+	GensetRated    = 3
+)
+
+// ProfileGenset 發電機組設定檔名稱
+const ProfileGenset = "genset"
+
+// 發電機組預設規格與模型參數
+const (
+	defaultGensetRatedPowerKW = 100.0
+	defaultGensetCrankSeconds = 5.0
+	defaultGensetRampSeconds  = 10.0
+	gensetCrankSpeed          = 250.0  // 起動馬達帶動的轉速 (rpm)
+	gensetRatedSpeed          = 1800.0 // 額定轉速 (rpm，4 極 60 Hz)
+	gensetTankHours           = 8.0    // 額定輸出時滿油箱可運轉的時數
+	gensetIdleFuelRatio       = 0.3    // 無載時的油耗相對額定輸出的比例
+)
+
+func init() {
+	RegisterDeviceProfile(&DeviceProfile{
+		Name:        ProfileGenset,
+		Description: "發電機組 (單相暫存器 + 運轉狀態、轉速、燃油存量、運轉時數、輸出功率、起動線圈)",
+		Registers: append(singlePhaseRegisters(),
+			RegisterDefinition{Address: AddrGensetState, Name: "GensetState", DataType: "uint16", Scale: 1, DefaultValue: GensetStopped, Unit: ""},
+			RegisterDefinition{Address: AddrGensetSpeed, Name: "EngineSpeed", DataType: "uint16", Scale: 1, DefaultValue: 0, Unit: "rpm"},
+			RegisterDefinition{Address: AddrGensetFuelLevel, Name: "FuelLevel", DataType: "uint16", Scale: 10, DefaultValue: 100.0, Unit: "%"},
+			RegisterDefinition{Address: AddrGensetRunHours, Name: "RunHours", DataType: "uint32", Scale: 10, DefaultValue: 0, Unit: "h"},
+			RegisterDefinition{Address: AddrGensetPower, Name: "GensetPower", DataType: "uint32", Scale: 1, DefaultValue: 0, Unit: "W"},
+			RegisterDefinition{Address: AddrGensetRatedPower, Name: "RatedPower", DataType: "uint16", Scale: 1, DefaultValue: defaultGensetRatedPowerKW, Unit: "kW"},
+			RegisterDefinition{Address: AddrGensetCrankTime, Name: "CrankTime", DataType: "uint16", Scale: 1, DefaultValue: defaultGensetCrankSeconds, Unit: "s", Writable: true},
+			RegisterDefinition{Address: AddrGensetRampTime, Name: "RampTime", DataType: "uint16", Scale: 1, DefaultValue: defaultGensetRampSeconds, Unit: "s", Writable: true},
+		),
+		NewModel: func() DeviceModel { return &GensetModel{} },
+	})
+}
+
+// GensetModel 發電機組模型：起動線圈 ON 後依序經過起動 (CrankTime)、運轉並將輸出線性升至額定 (RampTime)、額定輸出；
+// 起動線圈 OFF 或燃油耗盡時停機。運轉時累計運轉時數並依負載消耗燃油
+type GensetModel struct {
+	lastUpdate time.Time
+	startAt    time.Time // 起動指令生效的時間 (零值表示停機)
+	runHours   float64
+	fuel       float64
+	fuelRead   float64     // 上次寫入後暫存器的燃油存量；與暫存器不同時表示經管理 API 加油
+	reload     atomic.Bool // 快照還原後，下次更新自暫存器重新載入狀態
+}
+
+// Update 依起動線圈與經過時間推進起動程序，並更新轉速、輸出功率、運轉時數與燃油存量
+func (m *GensetModel) Update(registers *RegisterMap, now time.Time) {
+	crank := gensetSeconds(registers, AddrGensetCrankTime)
+	ramp := gensetSeconds(registers, AddrGensetRampTime)
+	if m.lastUpdate.IsZero() || m.reload.Swap(false) {
+		m.load(registers, now, crank, ramp)
+		return
+	}
+	last := m.lastUpdate
+	m.lastUpdate = now
+
+	start, _ := registers.ReadCoil(CoilGensetStart)
+	rated, _ := registers.GetScaledValue(AddrGensetRatedPower)
+	if rated <= 0 {
+		rated = defaultGensetRatedPowerKW
+	}
+	if fuel, _ := registers.GetScaledValue(AddrGensetFuelLevel); fuel != m.fuelRead {
+		m.fuel = fuel
+	}
+
+	switch {
+	case !start || m.fuel <= 0:
+		m.startAt = time.Time{}
+	case m.startAt.IsZero():
+		m.startAt = now
+	}
+
+	state, power := gensetOutput(m.startAt, now, crank, ramp, rated*1000)
+
+	// 起動完成後才計入運轉時數與油耗 (油耗依目前負載，無載時為額定的 gensetIdleFuelRatio)
+	if !m.startAt.IsZero() {
+		from := m.startAt.Add(crank)
+		if from.Before(last) {
+			from = last
+		}
+		if hours := now.Sub(from).Hours(); hours > 0 {
+			load := power / (rated * 1000)
+			m.runHours += hours
+			m.fuel -= hours * 100 / gensetTankHours * (gensetIdleFuelRatio + (1-gensetIdleFuelRatio)*load)
+		}
+	}
+	if m.fuel <= 0 {
+		m.fuel = 0
+		m.startAt = time.Time{}
+		state, power = GensetStopped, 0
+	}
+
+	m.write(registers, state, power)
+}
+
+// load 自暫存器載入運轉時數、燃油存量與狀態 (運轉中的機組依狀態推回起動時間，不重新起動)
+func (m *GensetModel) load(registers *RegisterMap, now time.Time, crank, ramp time.Duration) {
+	m.lastUpdate = now
+	m.runHours, _ = registers.GetScaledValue(AddrGensetRunHours)
+	m.fuel, _ = registers.GetScaledValue(AddrGensetFuelLevel)
+	m.fuelRead = m.fuel
+
+	state, _ := registers.GetScaledValue(AddrGensetState)
+	switch int(state) {
+	case GensetCranking:
+		m.startAt = now
+	case GensetRunning:
+		m.startAt = now.Add(-crank)
+	case GensetRated:
+		m.startAt = now.Add(-crank - ramp)
+	default:
+		m.startAt = time.Time{}
+	}
+}
+
+// write 將狀態、轉速、輸出功率、運轉時數與燃油存量寫入暫存器
+func (m *GensetModel) write(registers *RegisterMap, state int, power float64) {
+	speed := 0.0
+	switch state {
+	case GensetCranking:
+		speed = gensetCrankSpeed
+	case GensetRunning, GensetRated:
+		speed = gensetRatedSpeed
+	}
+	registers.SetScaledValue(AddrGensetState, float64(state))
+	registers.SetScaledValue(AddrGensetSpeed, speed)
+	registers.SetScaledValue(AddrGensetPower, power)
+	registers.SetScaledValue(AddrGensetRunHours, m.runHours)
+	registers.SetScaledValue(AddrGensetFuelLevel, m.fuel)
+	m.fuelRead, _ = registers.GetScaledValue(AddrGensetFuelLevel)
+	registers.WriteCoil(CoilGensetRunning, state >= GensetRunning)
+}
+
+// RestoreState 快照還原後自暫存器重新載入狀態
+func (m *GensetModel) RestoreState(registers *RegisterMap) {
+	m.reload.Store(true)
+}
+
+// gensetOutput 起動指令於 startAt 生效時，now 的運轉狀態與輸出功率 (W)
+func gensetOutput(startAt, now time.Time, crank, ramp time.Duration, rated float64) (int, float64) {
+	if startAt.IsZero() {
+		return GensetStopped, 0
+	}
+	elapsed := now.Sub(startAt)
+	switch {
+	case elapsed < crank:
+		return GensetCranking, 0
+	case elapsed < crank+ramp:
+		return GensetRunning, rated * float64(elapsed-crank) / float64(ramp)
+	default:
+		return GensetRated, rated
+	}
+}
+
+// gensetSeconds 讀取以秒為單位的時間暫存器
+func gensetSeconds(registers *RegisterMap, address uint16) time.Duration {
+	seconds, _ := registers.GetScaledValue(address)
+	return time.Duration(math.Max(seconds, 0) * float64(time.Second))
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// GrafanaOptions Grafana 儀表板的參數
+type GrafanaOptions struct {
+	Title      string // 儀表板標題
+	UID        string // 儀表板 UID (匯入時覆寫相同 UID 的儀表板)
+	Datasource string // 預設的 Prometheus 資料來源 (空字串 = Grafana 的預設資料來源)
+	Refresh    string // 自動重新整理間隔 (空字串 = 不自動重新整理)
+	PerSlave   bool   // 加入每個 Slave 的面板 (需啟用 metrics.per_slave)
+}
+
+// DefaultGrafanaOptions 預設的 Grafana 參數
+func DefaultGrafanaOptions() GrafanaOptions {
+	return GrafanaOptions{
+		Title:    "Modbus Simulator",
+		UID:      "modbussim",
+		Refresh:  "10s",
+		PerSlave: true,
+	}
+}
+
+// grafanaUIDPattern Grafana 儀表板 UID (最長 40 字元)
+var grafanaUIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,40}$`)
+
+// grafanaSchemaVersion 產生的儀表板 JSON 結構版本
+const grafanaSchemaVersion = 39
+
+// grafanaWidth Grafana 儀表板的格線寬度
+const grafanaWidth = 24
+
+// 所有查詢共用的 instance 篩選 (對應儀表板變數 $instance)
+const grafanaInstance = `instance=~"$instance"`
+
+type grafanaDatasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+// grafanaPrometheus 以儀表板變數 $datasource 選擇的資料來源
+var grafanaPrometheus = &grafanaDatasource{Type: "prometheus", UID: "${datasource}"}
+
+type grafanaGridPos struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+type grafanaTarget struct {
+	RefID        string `json:"refId"`
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat,omitempty"`
+	Format       string `json:"format,omitempty"`
+	Instant      bool   `json:"instant,omitempty"`
+}
+
+type grafanaFieldConfig struct {
+	Defaults  grafanaFieldDefaults `json:"defaults"`
+	Overrides []any                `json:"overrides"`
+}
+
+type grafanaFieldDefaults struct {
+	Unit string `json:"unit,omitempty"`
+}
+
+type grafanaPanel struct {
+	ID          int                 `json:"id"`
+	Type        string              `json:"type"`
+	Title       string              `json:"title"`
+	Description string              `json:"description,omitempty"`
+	GridPos     grafanaGridPos      `json:"gridPos"`
+	Datasource  *grafanaDatasource  `json:"datasource,omitempty"`
+	Targets     []grafanaTarget     `json:"targets,omitempty"`
+	FieldConfig *grafanaFieldConfig `json:"fieldConfig,omitempty"`
+	Options     map[string]any      `json:"options,omitempty"`
+	Collapsed   *bool               `json:"collapsed,omitempty"`
+}
+
+// grafanaLayout 由左至右、由上而下排列面板並編號
+type grafanaLayout struct {
+	panels  []grafanaPanel
+	x, y, h int // h 為目前這一列最高的面板
+}
+
+// row 以收合列分隔區段 (另起一列)
+func (l *grafanaLayout) row(title string) {
+	l.newline()
+	collapsed := false
+	l.panels = append(l.panels, grafanaPanel{
+		ID:        len(l.panels) + 1,
+		Type:      "row",
+		Title:     title,
+		GridPos:   grafanaGridPos{X: 0, Y: l.y, W: grafanaWidth, H: 1},
+		Collapsed: &collapsed,
+	})
+	l.y++
+}
+
+// add 放入寬 w、高 h 的面板，目前這一列放不下時換列
+func (l *grafanaLayout) add(w, h int, panel grafanaPanel) {
+	if l.x+w > grafanaWidth {
+		l.newline()
+	}
+	panel.ID = len(l.panels) + 1
+	panel.GridPos = grafanaGridPos{X: l.x, Y: l.y, W: w, H: h}
+	if panel.Datasource == nil {
+		panel.Datasource = grafanaPrometheus
+	}
+	for i := range panel.Targets {
+		panel.Targets[i].RefID = string(rune('A' + i))
+	}
+	l.panels = append(l.panels, panel)
+	l.x += w
+	l.h = max(l.h, h)
+}
+
+func (l *grafanaLayout) newline() {
+	l.y += l.h
+	l.x, l.h = 0, 0
+}
+
+// grafanaStat 單一數值面板
+func grafanaStat(title, expr, unit string) grafanaPanel {
+	return grafanaPanel{
+		Type:        "stat",
+		Title:       title,
+		Targets:     []grafanaTarget{{Expr: expr}},
+		FieldConfig: &grafanaFieldConfig{Defaults: grafanaFieldDefaults{Unit: unit}, Overrides: []any{}},
+		Options: map[string]any{
+			"reduceOptions": map[string]any{"calcs": []string{"lastNotNull"}, "fields": "", "values": false},
+			"colorMode":     "value",
+			"graphMode":     "area",
+		},
+	}
+}
+
+// grafanaTimeSeries 時間序列面板
+func grafanaTimeSeries(title, unit string, targets ...grafanaTarget) grafanaPanel {
+	return grafanaPanel{
+		Type:        "timeseries",
+		Title:       title,
+		Targets:     targets,
+		FieldConfig: &grafanaFieldConfig{Defaults: grafanaFieldDefaults{Unit: unit}, Overrides: []any{}},
+		Options: map[string]any{
+			"legend":  map[string]any{"displayMode": "list", "placement": "bottom", "showLegend": true},
+			"tooltip": map[string]any{"mode": "multi", "sort": "desc"},
+		},
+	}
+}
+
+// grafanaDashboard 儀表板：整體概況、請求延遲、場景與 (選用的) 每個 Slave 區段
+func grafanaDashboard(opts GrafanaOptions) map[string]any {
+	sel := "{" + grafanaInstance + "}"
+	rate := func(metric string) string {
+		return fmt.Sprintf("sum(rate(%s%s[$__rate_interval]))", metric, sel)
+	}
+	quantile := func(q string) string {
+		return fmt.Sprintf("histogram_quantile(%s, sum by (le) (rate(modbussim_request_duration_seconds_bucket%s[$__rate_interval])))", q, sel)
+	}
+
+	var l grafanaLayout
+
+	l.row("Fleet overview")
+	l.add(4, 4, grafanaStat("Active slaves", "sum(modbussim_slaves_active"+sel+")", "none"))
+	l.add(4, 4, grafanaStat("Offline slaves", "sum(modbussim_slaves_offline"+sel+")", "none"))
+	l.add(4, 4, grafanaStat("Pending bind / ramp-up", "sum(modbussim_bind_pending"+sel+") + sum(modbussim_ramp_pending"+sel+")", "none"))
+	l.add(4, 4, grafanaStat("Open connections", "sum(modbussim_connections_active"+sel+")", "none"))
+	l.add(4, 4, grafanaStat("Requests / s", rate("modbussim_requests_total"), "reqps"))
+	l.add(4, 4, grafanaStat("Error ratio", rate("modbussim_errors_total")+" / "+rate("modbussim_requests_total"), "percentunit"))
+	l.add(12, 8, grafanaTimeSeries("Requests", "reqps",
+		grafanaTarget{Expr: rate("modbussim_requests_total"), LegendFormat: "requests"},
+		grafanaTarget{Expr: rate("modbussim_errors_total"), LegendFormat: "errors"},
+		grafanaTarget{Expr: rate("modbussim_requests_rate_limited_total"), LegendFormat: "rate limited"},
+	))
+	l.add(12, 8, grafanaTimeSeries("Connections", "short",
+		grafanaTarget{Expr: "sum(modbussim_connections_active" + sel + ")", LegendFormat: "open"},
+		grafanaTarget{Expr: rate("modbussim_connections_rejected_total"), LegendFormat: "rejected / s"},
+		grafanaTarget{Expr: rate("modbussim_connections_forced_closed_total"), LegendFormat: "forced closed / s"},
+		grafanaTarget{Expr: rate("modbussim_connections_timed_out_total"), LegendFormat: "timed out / s"},
+	))
+	l.add(12, 8, grafanaTimeSeries("Slave availability", "none",
+		grafanaTarget{Expr: "sum(modbussim_slaves_active" + sel + ")", LegendFormat: "active"},
+		grafanaTarget{Expr: "sum(modbussim_slaves_offline" + sel + ")", LegendFormat: "offline"},
+		grafanaTarget{Expr: "sum(modbussim_slaves_standby" + sel + ")", LegendFormat: "standby"},
+		grafanaTarget{Expr: "sum(modbussim_domains_down" + sel + ")", LegendFormat: "failure domains down"},
+	))
+	l.add(12, 8, grafanaTimeSeries("Traffic", "Bps",
+		grafanaTarget{Expr: rate("modbussim_bytes_received_total"), LegendFormat: "received"},
+		grafanaTarget{Expr: rate("modbussim_bytes_sent_total"), LegendFormat: "sent"},
+	))
+
+	l.row("Latency")
+	l.add(12, 8, grafanaTimeSeries("Request latency quantiles", "s",
+		grafanaTarget{Expr: quantile("0.5"), LegendFormat: "p50"},
+		grafanaTarget{Expr: quantile("0.95"), LegendFormat: "p95"},
+		grafanaTarget{Expr: quantile("0.99"), LegendFormat: "p99"},
+	))
+	heatmap := grafanaPanel{
+		Type:  "heatmap",
+		Title: "Request latency distribution",
+		Targets: []grafanaTarget{{
+			Expr:         "sum by (le) (increase(modbussim_request_duration_seconds_bucket" + sel + "[$__rate_interval]))",
+			LegendFormat: "{{le}}",
+			Format:       "heatmap",
+		}},
+		Options: map[string]any{
+			"calculate": false,
+			"yAxis":     map[string]any{"unit": "s"},
+			"cellGap":   1,
+			"color":     map[string]any{"mode": "scheme", "scheme": "Oranges"},
+		},
+	}
+	l.add(12, 8, heatmap)
+
+	l.row("Scenarios")
+	l.add(24, 6, grafanaPanel{
+		Type:        "state-timeline",
+		Title:       "Active scenario",
+		Description: "Fleet-wide scenario over time (modbussim_scenario_active)",
+		Targets: []grafanaTarget{{
+			Expr:         "max by (scenario) (modbussim_scenario_active" + sel + ") > 0",
+			LegendFormat: "{{scenario}}",
+		}},
+		Options: map[string]any{
+			"showValue":   "never",
+			"mergeValues": true,
+			"legend":      map[string]any{"displayMode": "list", "placement": "bottom", "showLegend": false},
+		},
+	})
+	l.add(12, 8, grafanaTimeSeries("Slaves per scenario", "none",
+		grafanaTarget{Expr: "sum by (scenario) (modbussim_scenario_slaves" + sel + ") > 0", LegendFormat: "{{scenario}}"},
+	))
+	l.add(12, 8, grafanaPanel{
+		Type:        "bargauge",
+		Title:       "Time in scenario (dashboard range)",
+		Targets:     []grafanaTarget{{Expr: "sum by (scenario) (increase(modbussim_time_in_scenario_seconds_total" + sel + "[$__range])) > 0", LegendFormat: "{{scenario}}", Instant: true}},
+		FieldConfig: &grafanaFieldConfig{Defaults: grafanaFieldDefaults{Unit: "s"}, Overrides: []any{}},
+		Options: map[string]any{
+			"orientation":   "horizontal",
+			"displayMode":   "gradient",
+			"reduceOptions": map[string]any{"calcs": []string{"lastNotNull"}, "fields": "", "values": false},
+		},
+	})
+	l.add(12, 8, grafanaTimeSeries("Scenario transitions", "none",
+		grafanaTarget{Expr: "sum by (scenario) (increase(modbussim_scenario_transitions_total" + sel + "[$__rate_interval])) > 0", LegendFormat: "{{scenario}}"},
+	))
+	l.add(12, 8, grafanaTimeSeries("Suppressed fault injections", "none",
+		grafanaTarget{Expr: "sum(increase(modbussim_fault_injections_suppressed_total" + sel + "[$__rate_interval]))", LegendFormat: "suppressed"},
+	))
+
+	if opts.PerSlave {
+		l.row("Slaves")
+		l.add(12, 8, grafanaTimeSeries("Top 10 slaves by requests", "reqps",
+			grafanaTarget{Expr: "topk(10, sum by (slave_ip) (rate(modbussim_slave_requests_total" + sel + "[$__rate_interval])))", LegendFormat: "{{slave_ip}}"},
+		))
+		l.add(12, 8, grafanaTimeSeries("Top 10 slaves by errors", "reqps",
+			grafanaTarget{Expr: "topk(10, sum by (slave_ip) (rate(modbussim_slave_errors_total" + sel + "[$__rate_interval]))) > 0", LegendFormat: "{{slave_ip}}"},
+		))
+		l.add(24, 10, grafanaPanel{
+			Type:        "table",
+			Title:       "Slaves not polled recently",
+			Description: "Slaves whose last request is older than 30 seconds",
+			Targets: []grafanaTarget{{
+				Expr:    "max by (slave_ip, unit_id, scenario) (modbussim_slave_last_request_age_seconds" + sel + ") > 30",
+				Format:  "table",
+				Instant: true,
+			}},
+			FieldConfig: &grafanaFieldConfig{Defaults: grafanaFieldDefaults{Unit: "s"}, Overrides: []any{}},
+			Options:     map[string]any{"sortBy": []map[string]any{{"displayName": "Value", "desc": true}}},
+		})
+	}
+
+	datasource := map[string]any{
+		"type":  "datasource",
+		"name":  "datasource",
+		"label": "Data source",
+		"query": "prometheus",
+	}
+	if opts.Datasource != "" {
+		datasource["current"] = map[string]any{"text": opts.Datasource, "value": opts.Datasource}
+	}
+	instance := map[string]any{
+		"type":       "query",
+		"name":       "instance",
+		"label":      "Instance",
+		"datasource": grafanaPrometheus,
+		"query":      map[string]any{"query": "label_values(modbussim_slaves_total, instance)", "refId": "instance"},
+		"refresh":    2,
+		"includeAll": true,
+		"multi":      true,
+		"allValue":   ".*",
+		"current":    map[string]any{"text": "All", "value": "$__all"},
+	}
+
+	return map[string]any{
+		"title":         opts.Title,
+		"uid":           opts.UID,
+		"tags":          []string{"modbussim"},
+		"timezone":      "browser",
+		"schemaVersion": grafanaSchemaVersion,
+		"editable":      true,
+		"refresh":       opts.Refresh,
+		"time":          map[string]any{"from": "now-1h", "to": "now"},
+		"templating":    map[string]any{"list": []any{datasource, instance}},
+		"annotations": map[string]any{"list": []any{
+			map[string]any{
+				"name":        "Scenarios",
+				"datasource":  grafanaPrometheus,
+				"enable":      true,
+				"iconColor":   "orange",
+				"expr":        `max by (scenario) (modbussim_scenario_active{scenario!="normal",` + grafanaInstance + `}) == 1`,
+				"step":        "15s",
+				"titleFormat": "{{scenario}}",
+				"textFormat":  "Scenario {{scenario}} active",
+			},
+		}},
+		"panels": l.panels,
+	}
+}
+
+// GenerateGrafana 產生 Grafana 儀表板 JSON (整體概況、延遲直方圖、場景面板，並以場景切換作為 annotation)
+func GenerateGrafana(opts GrafanaOptions) ([]byte, error) {
+	if opts.Title == "" {
+		return nil, errors.New(T("儀表板標題不可為空"))
+	}
+	if !grafanaUIDPattern.MatchString(opts.UID) {
+		return nil, fmt.Errorf(T("無效的儀表板 UID: %s"), opts.UID)
+	}
+
+	data, err := json.MarshalIndent(grafanaDashboard(opts), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
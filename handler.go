@@ -1,22 +1,36 @@
 package main
 
 import (
-	"math/rand"
+	"encoding/binary"
+	"errors"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
 )
 
 // RequestHandler Modbus 請求處理器
+// 套用場景的延遲抖動與封包丟失後交由 Slave 處理，並依功能碼讀寫 Slave 對外提供的暫存器
 type RequestHandler struct {
 	slave  *Slave
 	logger *zap.Logger
 
-	// 場景相關
-	jitterEnabled    bool
-	jitterMin        time.Duration
-	jitterMax        time.Duration
-	packetLossRate   float64
+	// 場景相關 (套用場景與每個更新週期設定)
+	mu             sync.RWMutex
+	jitter         *DelayModel // nil 表示不延遲
+	packetLossRate float64
+
+	// 延遲抖動與封包丟失的亂數來源 (nil 表示場景共用的來源)
+	random *lockedRand
+}
+
+// registerImage Slave 對外提供的暫存器 (每個更新週期自 RegisterMap 同步，期間的輪詢讀到相同的值；由 Slave.mu 保護)
+// 以 PDU 位址直接索引，不套用 RegisterMap 的 40001 位址換算
+type registerImage struct {
+	holding   pagedStore[uint16]
+	input     pagedStore[uint16]
+	coils     pagedStore[bool]
+	discretes pagedStore[bool]
 }
 
 // NewRequestHandler 建立請求處理器
@@ -27,195 +41,353 @@ func NewRequestHandler(slave *Slave, logger *zap.Logger) *RequestHandler {
 	}
 }
 
-// SetJitter 設定延遲抖動
-func (h *RequestHandler) SetJitter(enabled bool, min, max time.Duration) {
-	h.jitterEnabled = enabled
-	h.jitterMin = min
-	h.jitterMax = max
+// SetDelayModel 設定回應延遲模型 (nil 表示不延遲)
+func (h *RequestHandler) SetDelayModel(model *DelayModel) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.jitter = model
 }
 
 // SetPacketLoss 設定封包丟失率
 func (h *RequestHandler) SetPacketLoss(rate float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	h.packetLossRate = rate
 }
 
-// applyJitter 套用延遲抖動
-func (h *RequestHandler) applyJitter() {
-	if !h.jitterEnabled {
+// applyScenario 依場景設定延遲抖動與封包丟失 (場景未實作 RequestJitter/RequestLoss 時關閉)
+func (h *RequestHandler) applyScenario(handler ScenarioHandler, params ScenarioParams) {
+	if composite, ok := handler.(*CompositeScenario); ok {
+		h.SetDelayModel(composite.delayModel())
+		h.SetPacketLoss(composite.LossRate(params))
 		return
 	}
+	if jitter, ok := handler.(RequestJitter); ok {
+		model := jitter.JitterModel(params)
+		h.SetDelayModel(&model)
+	} else {
+		h.SetDelayModel(nil)
+	}
 
-	jitter := h.jitterMin + time.Duration(rand.Int63n(int64(h.jitterMax-h.jitterMin)))
-	time.Sleep(jitter)
+	var rate float64
+	if loss, ok := handler.(RequestLoss); ok {
+		rate = loss.LossRate(params)
+	}
+	h.SetPacketLoss(rate)
+}
+
+// applyJitter 依延遲模型延遲回應；回傳 true 表示此請求模擬回應逾時，不應回應
+func (h *RequestHandler) applyJitter() (timeout bool) {
+	h.mu.RLock()
+	model := h.jitter
+	h.mu.RUnlock()
+	if model == nil {
+		return false
+	}
+
+	if model.TimeoutRate > 0 && h.rand().Float64() < model.TimeoutRate {
+		return true
+	}
+	time.Sleep(model.Sample(h.rand()))
+	return false
 }
 
 // shouldDropPacket 判斷是否應該丟棄封包
 func (h *RequestHandler) shouldDropPacket() bool {
-	if h.packetLossRate <= 0 {
+	h.mu.RLock()
+	rate := h.packetLossRate
+	h.mu.RUnlock()
+	if rate <= 0 {
 		return false
 	}
-	return rand.Float64() < h.packetLossRate
+	return h.rand().Float64() < rate
 }
 
-// HandleReadCoils 處理讀取線圈請求 (FC 01)
-func (h *RequestHandler) HandleReadCoils(address, quantity uint16) ([]bool, error) {
-	h.applyJitter()
+// rand 延遲抖動與封包丟失的亂數來源
+func (h *RequestHandler) rand() *lockedRand {
+	if h.random != nil {
+		return h.random
+	}
+	return scenarioRandom()
+}
+
+// Handle 處理一個請求 (含場景的例外注入)；封包丟失或模擬回應逾時時 dropped 為 true，呼叫端不應回應
+func (h *RequestHandler) Handle(frame *tcpFrame) (response []byte, hasError, dropped bool) {
+	if h.applyJitter() {
+		h.logger.Debug(T("模擬回應逾時，不回應請求"), zap.Uint8("function", frame.Function))
+		return nil, false, true
+	}
 
 	if h.shouldDropPacket() {
-		return nil, nil // 模擬封包丟失
+		h.logger.Debug(T("模擬封包丟失，不回應請求"), zap.Uint8("function", frame.Function))
+		return nil, false, true
+	}
+
+	if response, limited := h.applyRateLimit(frame); limited {
+		return response, true, false
+	}
+
+	// 閘道後方沒有此 Unit ID 的設備：不回應，或如同串列埠閘道等待下游逾時後回應 0x0B
+	if !h.slave.hasUnit(frame.UnitID) {
+		timeout, ok := h.slave.downstreamTimeout()
+		if !ok {
+			h.logger.Debug(T("未配置的 Unit ID，不回應請求"), zap.Uint8("unit_id", frame.UnitID))
+			return nil, false, true
+		}
+		time.Sleep(timeout)
+		h.logger.Debug(T("未配置的 Unit ID，下游逾時"), zap.Uint8("unit_id", frame.UnitID), zap.Duration("timeout", timeout))
+		return frame.exception(ExceptionCodeGatewayTargetNoResponse), true, false
+	}
+
+	// 設備 (依設定檔) 未實作的功能碼
+	if !h.slave.supportsFunction(frame.UnitID, frame.Function) {
+		h.logger.Debug(T("設備不支援的功能碼"), zap.Uint8("unit_id", frame.UnitID), zap.Uint8("function", frame.Function))
+		return frame.exception(ExceptionCodeIllegalFunction), true, false
+	}
+
+	if response, overridden := h.applyOverride(frame); overridden {
+		return response, true, false
+	}
+
+	response, hasError = h.slave.processFrame(frame)
+	return response, hasError, false
+}
+
+// HandleRequest 依功能碼解析並對 t 執行請求，回傳回應 PDU 功能碼之後的資料 (呼叫端需持有 slave.mu)
+func (h *RequestHandler) HandleRequest(t requestTarget, function uint8, data []byte) ([]byte, error) {
+	switch function {
+	case FuncCodeReadCoils, FuncCodeReadDiscreteInputs:
+		address, quantity, err := parseRange(data, 4, MaxCoilsPerRead)
+		if err != nil {
+			return nil, err
+		}
+		read := h.HandleReadCoils
+		if function == FuncCodeReadDiscreteInputs {
+			read = h.HandleReadDiscreteInputs
+		}
+		bits, err := read(t, address, quantity)
+		if err != nil {
+			return nil, err
+		}
+		packed := CoilsToByte(bits)
+		return append([]byte{byte(len(packed))}, packed...), nil
+
+	case FuncCodeReadHoldingRegisters, FuncCodeReadInputRegisters:
+		address, quantity, err := parseRange(data, 4, MaxRegistersPerRead)
+		if err != nil {
+			return nil, err
+		}
+		read := h.HandleReadHoldingRegisters
+		if function == FuncCodeReadInputRegisters {
+			read = h.HandleReadInputRegisters
+		}
+		registers, err := read(t, address, quantity)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{byte(len(registers) * 2)}, RegistersToBytes(registers)...), nil
+
+	case FuncCodeWriteSingleCoil:
+		if len(data) != 4 {
+			return nil, &ModbusError{Code: ExceptionCodeIllegalDataValue}
+		}
+		address, value := binary.BigEndian.Uint16(data[0:2]), binary.BigEndian.Uint16(data[2:4])
+		if value != 0x0000 && value != 0xFF00 {
+			return nil, &ModbusError{Code: ExceptionCodeIllegalDataValue}
+		}
+		if err := h.HandleWriteSingleCoil(t, address, value == 0xFF00); err != nil {
+			return nil, err
+		}
+		return data, nil
+
+	case FuncCodeWriteSingleRegister:
+		if len(data) != 4 {
+			return nil, &ModbusError{Code: ExceptionCodeIllegalDataValue}
+		}
+		if err := h.HandleWriteSingleRegister(t, binary.BigEndian.Uint16(data[0:2]), binary.BigEndian.Uint16(data[2:4])); err != nil {
+			return nil, err
+		}
+		return data, nil
+
+	case FuncCodeWriteMultipleCoils:
+		address, quantity, err := parseRange(data, 5, MaxCoilsPerWrite)
+		if err != nil {
+			return nil, err
+		}
+		if count := (int(quantity) + 7) / 8; int(data[4]) != count || len(data) != 5+count {
+			return nil, &ModbusError{Code: ExceptionCodeIllegalDataValue}
+		}
+		if err := h.HandleWriteMultipleCoils(t, address, ByteToCoils(data[5:], int(quantity))); err != nil {
+			return nil, err
+		}
+		return data[:4], nil
+
+	case FuncCodeWriteMultipleRegisters:
+		address, quantity, err := parseRange(data, 5, MaxRegistersPerWrite)
+		if err != nil {
+			return nil, err
+		}
+		if count := int(quantity) * 2; int(data[4]) != count || len(data) != 5+count {
+			return nil, &ModbusError{Code: ExceptionCodeIllegalDataValue}
+		}
+		if err := h.HandleWriteMultipleRegisters(t, address, BytesToRegisters(data[5:])); err != nil {
+			return nil, err
+		}
+		return data[:4], nil
+
+	case FuncCodeEncapsulatedInterface:
+		return h.slave.readDeviceIdentification(data)
+
+	default:
+		return nil, &ModbusError{Code: ExceptionCodeIllegalFunction}
 	}
+}
 
-	coils, err := h.slave.registers.ReadCoils(address, quantity)
+// parseRange 解析請求的起始位址與數量 (資料長度至少 minLength，數量須介於 1 與 limit)
+func parseRange(data []byte, minLength int, limit uint16) (address, quantity uint16, err error) {
+	if len(data) < minLength {
+		return 0, 0, &ModbusError{Code: ExceptionCodeIllegalDataValue}
+	}
+	address, quantity = binary.BigEndian.Uint16(data[0:2]), binary.BigEndian.Uint16(data[2:4])
+	if quantity == 0 || quantity > limit {
+		return 0, 0, &ModbusError{Code: ExceptionCodeIllegalDataValue}
+	}
+	return address, quantity, nil
+}
+
+// imageSpan 位址範圍在長度 size 的暫存器內的索引，超出時回傳 Illegal Data Address
+func imageSpan(size int, address uint16, quantity int) (start, end int, err error) {
+	start, end = int(address), int(address)+quantity
+	if end > size {
+		return 0, 0, &ModbusError{Code: ExceptionCodeIllegalDataAddress}
+	}
+	return start, end, nil
+}
+
+// HandleReadCoils 處理讀取線圈請求 (FC 01)
+func (h *RequestHandler) HandleReadCoils(t requestTarget, address, quantity uint16) ([]bool, error) {
+	start, end, err := imageSpan(t.image.coils.Len(), address, int(quantity))
 	if err != nil {
-		h.slave.recordRequest(0, 0, true)
-		h.logger.Debug("讀取線圈失敗",
+		h.logger.Debug(T("讀取線圈失敗"),
 			zap.Uint16("address", address),
 			zap.Uint16("quantity", quantity),
 			zap.Error(err),
 		)
 		return nil, err
 	}
-
-	h.slave.recordRequest(8, 3+(int(quantity)+7)/8, false)
-	return coils, nil
+	return t.image.coils.Slice(start, end), nil
 }
 
 // HandleReadDiscreteInputs 處理讀取離散輸入請求 (FC 02)
-func (h *RequestHandler) HandleReadDiscreteInputs(address, quantity uint16) ([]bool, error) {
-	h.applyJitter()
-
-	if h.shouldDropPacket() {
-		return nil, nil
-	}
-
-	inputs, err := h.slave.registers.ReadDiscreteInputs(address, quantity)
+func (h *RequestHandler) HandleReadDiscreteInputs(t requestTarget, address, quantity uint16) ([]bool, error) {
+	start, end, err := imageSpan(t.image.discretes.Len(), address, int(quantity))
 	if err != nil {
-		h.slave.recordRequest(0, 0, true)
-		h.logger.Debug("讀取離散輸入失敗",
+		h.logger.Debug(T("讀取離散輸入失敗"),
 			zap.Uint16("address", address),
 			zap.Uint16("quantity", quantity),
 			zap.Error(err),
 		)
 		return nil, err
 	}
-
-	h.slave.recordRequest(8, 3+(int(quantity)+7)/8, false)
-	return inputs, nil
+	return t.image.discretes.Slice(start, end), nil
 }
 
 // HandleReadHoldingRegisters 處理讀取保持暫存器請求 (FC 03)
-func (h *RequestHandler) HandleReadHoldingRegisters(address, quantity uint16) ([]uint16, error) {
-	h.applyJitter()
-
-	if h.shouldDropPacket() {
-		return nil, nil
-	}
-
-	registers, err := h.slave.registers.ReadHoldingRegisters(address, quantity)
+func (h *RequestHandler) HandleReadHoldingRegisters(t requestTarget, address, quantity uint16) ([]uint16, error) {
+	start, end, err := imageSpan(t.image.holding.Len(), address, int(quantity))
 	if err != nil {
-		h.slave.recordRequest(0, 0, true)
-		h.logger.Debug("讀取保持暫存器失敗",
+		h.logger.Debug(T("讀取保持暫存器失敗"),
 			zap.Uint16("address", address),
 			zap.Uint16("quantity", quantity),
 			zap.Error(err),
 		)
 		return nil, err
 	}
-
-	h.slave.recordRequest(8, 3+int(quantity)*2, false)
-	return registers, nil
+	return t.image.holding.Slice(start, end), nil
 }
 
 // HandleReadInputRegisters 處理讀取輸入暫存器請求 (FC 04)
-func (h *RequestHandler) HandleReadInputRegisters(address, quantity uint16) ([]uint16, error) {
-	h.applyJitter()
-
-	if h.shouldDropPacket() {
-		return nil, nil
-	}
-
-	registers, err := h.slave.registers.ReadInputRegisters(address, quantity)
+func (h *RequestHandler) HandleReadInputRegisters(t requestTarget, address, quantity uint16) ([]uint16, error) {
+	start, end, err := imageSpan(t.image.input.Len(), address, int(quantity))
 	if err != nil {
-		h.slave.recordRequest(0, 0, true)
-		h.logger.Debug("讀取輸入暫存器失敗",
+		h.logger.Debug(T("讀取輸入暫存器失敗"),
 			zap.Uint16("address", address),
 			zap.Uint16("quantity", quantity),
 			zap.Error(err),
 		)
 		return nil, err
 	}
-
-	h.slave.recordRequest(8, 3+int(quantity)*2, false)
-	return registers, nil
+	return t.image.input.Slice(start, end), nil
 }
 
 // HandleWriteSingleCoil 處理寫入單一線圈請求 (FC 05)
-func (h *RequestHandler) HandleWriteSingleCoil(address uint16, value bool) error {
-	h.applyJitter()
+func (h *RequestHandler) HandleWriteSingleCoil(t requestTarget, address uint16, value bool) error {
+	return h.HandleWriteMultipleCoils(t, address, []bool{value})
+}
 
-	if h.shouldDropPacket() {
-		return nil
-	}
+// HandleWriteSingleRegister 處理寫入單一暫存器請求 (FC 06)
+func (h *RequestHandler) HandleWriteSingleRegister(t requestTarget, address, value uint16) error {
+	return h.HandleWriteMultipleRegisters(t, address, []uint16{value})
+}
 
-	meta, ok := h.slave.registers.GetDefinition(address)
-	if ok && !meta.Writable {
-		h.slave.recordRequest(0, 0, true)
-		return &ModbusError{Code: ExceptionCodeIllegalDataAddress}
+// HandleWriteMultipleCoils 處理寫入多個線圈請求 (FC 15)
+func (h *RequestHandler) HandleWriteMultipleCoils(t requestTarget, address uint16, values []bool) error {
+	start, _, err := imageSpan(t.image.coils.Len(), address, len(values))
+	if err == nil {
+		err = writeAtPDU(t.registers, RegisterTypeCoil, address, func(addr uint16) error {
+			return t.registers.WriteCoils(addr, values)
+		})
 	}
-
-	if err := h.slave.registers.WriteCoil(address, value); err != nil {
-		h.slave.recordRequest(0, 0, true)
-		h.logger.Debug("寫入線圈失敗",
+	if err != nil {
+		h.logger.Debug(T("寫入多個線圈失敗"),
 			zap.Uint16("address", address),
-			zap.Bool("value", value),
+			zap.Int("count", len(values)),
 			zap.Error(err),
 		)
 		return err
 	}
 
-	h.slave.recordRequest(8, 8, false)
+	// 同時寫入對外提供的暫存器，下次輪詢即可讀回
+	t.image.coils.Write(start, values)
+	h.slave.fireWriteHooks(t.registers, RegisterTypeCoil, address, coilValues(values))
 	return nil
 }
 
-// HandleWriteSingleRegister 處理寫入單一暫存器請求 (FC 06)
-func (h *RequestHandler) HandleWriteSingleRegister(address, value uint16) error {
-	h.applyJitter()
-
-	if h.shouldDropPacket() {
-		return nil
-	}
-
-	meta, ok := h.slave.registers.GetDefinition(address)
-	if ok && !meta.Writable {
-		h.slave.recordRequest(0, 0, true)
-		return &ModbusError{Code: ExceptionCodeIllegalDataAddress}
-	}
-
-	if err := h.slave.registers.WriteHoldingRegister(address, value); err != nil {
-		h.slave.recordRequest(0, 0, true)
-		h.logger.Debug("寫入暫存器失敗",
+// HandleWriteMultipleRegisters 處理寫入多個暫存器請求 (FC 16)
+func (h *RequestHandler) HandleWriteMultipleRegisters(t requestTarget, address uint16, values []uint16) error {
+	start, _, err := imageSpan(t.image.holding.Len(), address, len(values))
+	if err != nil {
+		h.logger.Debug(T("寫入多個暫存器失敗"),
 			zap.Uint16("address", address),
-			zap.Uint16("value", value),
+			zap.Int("count", len(values)),
 			zap.Error(err),
 		)
 		return err
 	}
 
-	h.slave.recordRequest(8, 8, false)
-	return nil
-}
-
-// HandleWriteMultipleCoils 處理寫入多個線圈請求 (FC 15)
-func (h *RequestHandler) HandleWriteMultipleCoils(address uint16, values []bool) error {
-	h.applyJitter()
+	// 定義為唯讀的暫存器拒絕寫入 (與實體設備相同，回應 Illegal Data Address)
+	for i := range values {
+		if meta, ok := h.holdingDefinition(t.registers, address+uint16(i)); ok && !meta.Writable {
+			h.logger.Debug(T("寫入唯讀暫存器"),
+				zap.Uint16("address", address+uint16(i)),
+				zap.String("name", meta.Name),
+			)
+			return &ModbusError{Code: ExceptionCodeIllegalDataAddress}
+		}
+	}
 
-	if h.shouldDropPacket() {
-		return nil
+	// 寫入設備時鐘的時間暫存器即校時 (寫入後不是有效的日期時間時拒絕)
+	synced, sync, err := h.slave.deviceClockWrite(t.registers, address, values)
+	if err != nil {
+		h.logger.Debug(T("設備時鐘的時間無效"), zap.Uint16("address", address), zap.Uint16s("values", values))
+		return err
 	}
 
-	if err := h.slave.registers.WriteCoils(address, values); err != nil {
-		h.slave.recordRequest(0, 0, true)
-		h.logger.Debug("寫入多個線圈失敗",
+	err = writeAtPDU(t.registers, RegisterTypeHoldingRegister, address, func(addr uint16) error {
+		return t.registers.WriteHoldingRegisters(addr, values)
+	})
+	if err != nil {
+		h.logger.Debug(T("寫入多個暫存器失敗"),
 			zap.Uint16("address", address),
 			zap.Int("count", len(values)),
 			zap.Error(err),
@@ -223,30 +395,43 @@ func (h *RequestHandler) HandleWriteMultipleCoils(address uint16, values []bool)
 		return err
 	}
 
-	h.slave.recordRequest(9+(len(values)+7)/8, 8, false)
+	if sync {
+		h.slave.syncDeviceClock(synced)
+	}
+
+	// 同時寫入對外提供的暫存器，下次輪詢即可讀回
+	t.image.holding.Write(start, values)
+	h.slave.fireWriteHooks(t.registers, RegisterTypeHoldingRegister, address, values)
 	return nil
 }
 
-// HandleWriteMultipleRegisters 處理寫入多個暫存器請求 (FC 16)
-func (h *RequestHandler) HandleWriteMultipleRegisters(address uint16, values []uint16) error {
-	h.applyJitter()
-
-	if h.shouldDropPacket() {
-		return nil
+// writeAtPDU 將 PDU 位址換算為映射表位址慣例下的位址後寫入 (無對應位址時回應 Illegal Data Address)
+func writeAtPDU(registers *RegisterMap, table RegisterType, pdu uint16, write func(address uint16) error) error {
+	address, ok := registers.PDUAddress(table, pdu)
+	if !ok {
+		return &ModbusError{Code: ExceptionCodeIllegalDataAddress}
 	}
+	return write(address)
+}
 
-	if err := h.slave.registers.WriteHoldingRegisters(address, values); err != nil {
-		h.slave.recordRequest(0, 0, true)
-		h.logger.Debug("寫入多個暫存器失敗",
-			zap.Uint16("address", address),
-			zap.Int("count", len(values)),
-			zap.Error(err),
-		)
-		return err
+// holdingDefinition PDU 位址的保持暫存器定義 (相容模式的定義可使用 40001 起算的位址或 PDU 位址)
+func (h *RequestHandler) holdingDefinition(registers *RegisterMap, address uint16) (*RegisterMeta, bool) {
+	if registers.Addressing() != AddressModeAuto {
+		defined, ok := registers.PDUAddress(RegisterTypeHoldingRegister, address)
+		if !ok {
+			return nil, false
+		}
+		return registers.GetDefinition(defined)
 	}
-
-	h.slave.recordRequest(9+len(values)*2, 8, false)
-	return nil
+	if address <= 0xFFFF-40001 {
+		if meta, ok := registers.GetDefinition(address + 40001); ok {
+			return meta, true
+		}
+	}
+	if address < 40001 {
+		return registers.GetDefinition(address)
+	}
+	return nil, false
 }
 
 // ModbusError Modbus 異常錯誤
@@ -257,18 +442,29 @@ type ModbusError struct {
 func (e *ModbusError) Error() string {
 	switch e.Code {
 	case ExceptionCodeIllegalFunction:
-		return "非法功能碼"
+		return T("非法功能碼")
 	case ExceptionCodeIllegalDataAddress:
-		return "非法資料位址"
+		return T("非法資料位址")
 	case ExceptionCodeIllegalDataValue:
-		return "非法資料值"
+		return T("非法資料值")
 	case ExceptionCodeSlaveDeviceFailure:
-		return "從站設備故障"
+		return T("從站設備故障")
 	case ExceptionCodeAcknowledge:
-		return "確認"
+		return T("確認")
 	case ExceptionCodeSlaveDeviceBusy:
-		return "從站設備忙碌"
+		return T("從站設備忙碌")
+	case ExceptionCodeGatewayTargetNoResponse:
+		return T("閘道目標設備未回應")
 	default:
-		return "未知錯誤"
+		return T("未知錯誤")
+	}
+}
+
+// exceptionCode 錯誤對應的例外碼 (RegisterMap 的錯誤皆為位址超出範圍)
+func exceptionCode(err error) uint8 {
+	var modbusErr *ModbusError
+	if errors.As(err, &modbusErr) {
+		return modbusErr.Code
 	}
+	return ExceptionCodeIllegalDataAddress
 }
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// harnessMu 同一時間只允許一個 ScenarioHarness 替換場景的時間與亂數來源
+var harnessMu sync.Mutex
+
+// DefaultHarnessStart 虛擬時鐘預設起始時間 (本地時間午夜，方便驗證依時段變化的場景)
+var DefaultHarnessStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+
+// ScenarioHarness 以虛擬時鐘執行場景並記錄每個暫存器的數值軌跡，讓場景的單元測試不需 sleep 或真實計時器
+//
+//	h := NewScenarioHarness(&VoltageSagScenario{}, WithHarnessParams(params))
+//	defer h.Close()
+//	h.Run(30*time.Second, time.Second)
+//	assert.Less(t, h.Series("LineVoltage").At(5*time.Second), 200.0)
+//
+// 存在期間場景的時間與亂數來源皆被替換 (亂數以固定種子產生，結果可重現)，用完須呼叫 Close。
+type ScenarioHarness struct {
+	handler   ScenarioHandler
+	registers *RegisterMap
+	params    ScenarioParams
+	model     DeviceModel
+	clock     *VirtualClock
+	start     time.Time
+	seed      int64
+	restore   func()
+
+	series map[uint16]*Series
+}
+
+// HarnessOption ScenarioHarness 配置選項
+type HarnessOption func(*ScenarioHarness)
+
+// WithHarnessRegisters 設定暫存器映射表 (預設為 DefaultRegisterMap)
+func WithHarnessRegisters(rm *RegisterMap) HarnessOption {
+	return func(h *ScenarioHarness) {
+		h.registers = rm
+	}
+}
+
+// WithHarnessParams 設定場景參數
+func WithHarnessParams(params ScenarioParams) HarnessOption {
+	return func(h *ScenarioHarness) {
+		h.params = params
+	}
+}
+
+// WithHarnessModel 設定設備模型 (每次更新後以虛擬時間呼叫)
+func WithHarnessModel(model DeviceModel) HarnessOption {
+	return func(h *ScenarioHarness) {
+		h.model = model
+	}
+}
+
+// WithHarnessStart 設定虛擬時鐘起始時間
+func WithHarnessStart(start time.Time) HarnessOption {
+	return func(h *ScenarioHarness) {
+		h.start = start
+	}
+}
+
+// WithHarnessSeed 設定亂數種子 (預設 1)
+func WithHarnessSeed(seed int64) HarnessOption {
+	return func(h *ScenarioHarness) {
+		h.seed = seed
+	}
+}
+
+// NewScenarioHarness 建立場景測試環境；場景實作 ScenarioActivator 時如同 Slave 切換場景般先呼叫 Activate
+func NewScenarioHarness(handler ScenarioHandler, opts ...HarnessOption) *ScenarioHarness {
+	h := &ScenarioHarness{
+		handler: handler,
+		start:   DefaultHarnessStart,
+		seed:    1,
+		series:  make(map[uint16]*Series),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.registers == nil {
+		h.registers = DefaultRegisterMap()
+	}
+	h.clock = NewVirtualClock(h.start)
+
+	harnessMu.Lock()
+	h.restore = setScenarioSources(h.clock, newLockedRand(h.seed))
+
+	h.Activate()
+	h.record()
+	return h
+}
+
+// Close 還原場景的時間與亂數來源
+func (h *ScenarioHarness) Close() {
+	if h.restore == nil {
+		return
+	}
+	h.restore()
+	h.restore = nil
+	harnessMu.Unlock()
+}
+
+// Activate 模擬切換進場景
+func (h *ScenarioHarness) Activate() {
+	if activator, ok := h.handler.(ScenarioActivator); ok {
+		activator.Activate(h.registers)
+	}
+}
+
+// SetParams 變更後續更新使用的場景參數
+func (h *ScenarioHarness) SetParams(params ScenarioParams) {
+	h.params = params
+}
+
+// Step 推進虛擬時鐘 d 後執行一次更新並記錄
+func (h *ScenarioHarness) Step(d time.Duration) {
+	h.clock.Advance(d)
+	h.handler.Update(h.registers, h.params)
+	if h.model != nil {
+		h.model.Update(h.registers, h.clock.Now())
+	}
+	h.record()
+}
+
+// Run 以 interval 為間隔持續更新 duration (模擬 scenario.update_interval 的計時器)
+func (h *ScenarioHarness) Run(duration, interval time.Duration) {
+	for elapsed := time.Duration(0); elapsed+interval <= duration; elapsed += interval {
+		h.Step(interval)
+	}
+}
+
+// Reset 呼叫場景的 Reset 並記錄
+func (h *ScenarioHarness) Reset() {
+	h.handler.Reset(h.registers)
+	h.record()
+}
+
+// Clock 虛擬時鐘
+func (h *ScenarioHarness) Clock() *VirtualClock {
+	return h.clock
+}
+
+// Registers 暫存器映射表
+func (h *ScenarioHarness) Registers() *RegisterMap {
+	return h.registers
+}
+
+// Elapsed 自起始時間經過的虛擬時間
+func (h *ScenarioHarness) Elapsed() time.Duration {
+	return h.clock.Now().Sub(h.start)
+}
+
+// Series 依暫存器名稱取得軌跡 (未定義時為空軌跡)
+func (h *ScenarioHarness) Series(name string) *Series {
+	for _, series := range h.series {
+		if series.Name == name {
+			return series
+		}
+	}
+	return &Series{Name: name}
+}
+
+// SeriesAt 依位址取得軌跡 (未定義時為空軌跡)
+func (h *ScenarioHarness) SeriesAt(address uint16) *Series {
+	if series, ok := h.series[address]; ok {
+		return series
+	}
+	return &Series{Address: address}
+}
+
+// record 記錄所有已定義暫存器的目前值
+func (h *ScenarioHarness) record() {
+	elapsed := h.Elapsed()
+	for _, meta := range h.registers.Definitions() {
+		value, err := h.registers.GetScaledValue(meta.Address)
+		if err != nil {
+			continue
+		}
+		series, ok := h.series[meta.Address]
+		if !ok {
+			series = &Series{Name: meta.Name, Address: meta.Address}
+			h.series[meta.Address] = series
+		}
+		series.Points = append(series.Points, SeriesPoint{Elapsed: elapsed, Value: value})
+	}
+}
+
+// SeriesPoint 軌跡上的一點
+type SeriesPoint struct {
+	Elapsed time.Duration
+	Value   float64
+}
+
+// Series 單一暫存器的數值軌跡 (工程值，依時間排序)
+type Series struct {
+	Name    string
+	Address uint16
+	Points  []SeriesPoint
+}
+
+// Len 點數
+func (s *Series) Len() int {
+	return len(s.Points)
+}
+
+// Values 所有值
+func (s *Series) Values() []float64 {
+	values := make([]float64, len(s.Points))
+	for i, p := range s.Points {
+		values[i] = p.Value
+	}
+	return values
+}
+
+// First 第一個值 (空軌跡為 0)
+func (s *Series) First() float64 {
+	if len(s.Points) == 0 {
+		return 0
+	}
+	return s.Points[0].Value
+}
+
+// Last 最後一個值 (空軌跡為 0)
+func (s *Series) Last() float64 {
+	if len(s.Points) == 0 {
+		return 0
+	}
+	return s.Points[len(s.Points)-1].Value
+}
+
+// At 指定時間點 (含) 之前最後記錄的值
+func (s *Series) At(elapsed time.Duration) float64 {
+	var value float64
+	for _, p := range s.Points {
+		if p.Elapsed > elapsed {
+			break
+		}
+		value = p.Value
+	}
+	return value
+}
+
+// Between 取出 [from, to) 區間的軌跡
+func (s *Series) Between(from, to time.Duration) *Series {
+	out := &Series{Name: s.Name, Address: s.Address}
+	for _, p := range s.Points {
+		if p.Elapsed >= from && p.Elapsed < to {
+			out.Points = append(out.Points, p)
+		}
+	}
+	return out
+}
+
+// Min 最小值 (空軌跡為 0)
+func (s *Series) Min() float64 {
+	if len(s.Points) == 0 {
+		return 0
+	}
+	minimum := s.Points[0].Value
+	for _, p := range s.Points[1:] {
+		minimum = min(minimum, p.Value)
+	}
+	return minimum
+}
+
+// Max 最大值 (空軌跡為 0)
+func (s *Series) Max() float64 {
+	if len(s.Points) == 0 {
+		return 0
+	}
+	maximum := s.Points[0].Value
+	for _, p := range s.Points[1:] {
+		maximum = max(maximum, p.Value)
+	}
+	return maximum
+}
+
+// Changes 相鄰兩點數值不同的次數
+func (s *Series) Changes() int {
+	changes := 0
+	for i := 1; i < len(s.Points); i++ {
+		if s.Points[i].Value != s.Points[i-1].Value {
+			changes++
+		}
+	}
+	return changes
+}
+
+// Monotonic 數值是否從未下降 (例如電能累計量)
+func (s *Series) Monotonic() bool {
+	for i := 1; i < len(s.Points); i++ {
+		if s.Points[i].Value < s.Points[i-1].Value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package main
+
+// Slave 群的健康狀態
+const (
+	HealthStatusHealthy  = "healthy"  // 健康 Slave 比例達門檻
+	HealthStatusDegraded = "degraded" // 健康 Slave 比例低於 metrics.min_healthy_percent
+	HealthStatusStarting = "starting" // 引擎尚未運行
+)
+
+// FleetHealth Slave 群的健康狀態 (/health 與 /ready 的回應)
+type FleetHealth struct {
+	Status     string `json:"status"`
+	Configured int    `json:"configured_slaves"` // 應運行的 Slave 數 (扣除已除役的 Slave)
+	Bound      int    `json:"bound_slaves"`      // 已綁定的 Slave 數
+	Healthy    int    `json:"healthy_slaves"`    // 運行中 (含備援端) 的 Slave 數
+	Failing    int    `json:"failing_slaves"`    // 應運行但不健康的 Slave 數 (以下各項的總和)
+	Offline    int    `json:"offline_slaves"`    // 斷線模擬中
+	Stopped    int    `json:"stopped_slaves"`    // 已綁定但停止 (例如故障網域中斷)
+	Pending    int    `json:"pending_slaves"`    // 逐步上線或綁定衝突重試中
+	Failed     int    `json:"failed_slaves"`     // 啟動失敗且不再重試
+
+	BoundPercent      float64 `json:"bound_percent"`
+	HealthyPercent    float64 `json:"healthy_percent"`
+	MinHealthyPercent float64 `json:"min_healthy_percent"`
+}
+
+// FleetHealth 統計 Slave 群的健康狀態；minPercent 為健康 Slave 比例的門檻 (0 表示不檢查)
+func (e *Engine) FleetHealth(minPercent float64) FleetHealth {
+	stats := e.Stats()
+	h := FleetHealth{
+		Configured:        max(stats.ConfiguredSlaves-stats.DecommissionedSlaves, 0),
+		Bound:             stats.SlaveCount,
+		Pending:           stats.BindPending + stats.RampPending,
+		MinHealthyPercent: minPercent,
+	}
+	for _, slave := range e.ListSlaves() {
+		switch slave.State() {
+		case SlaveStateRunning, SlaveStateStandby:
+			h.Healthy++
+		case SlaveStateOffline:
+			h.Offline++
+		default:
+			h.Stopped++
+		}
+	}
+	h.Failed = max(h.Configured-h.Bound-h.Pending, 0)
+	h.Failing = h.Offline + h.Stopped + h.Pending + h.Failed
+
+	h.BoundPercent, h.HealthyPercent = 100, 100
+	if h.Configured > 0 {
+		h.BoundPercent = float64(h.Bound) / float64(h.Configured) * 100
+		h.HealthyPercent = float64(h.Healthy) / float64(h.Configured) * 100
+	}
+
+	switch {
+	case e.State() != EngineStateRunning:
+		h.Status = HealthStatusStarting
+	case h.HealthyPercent < minPercent:
+		h.Status = HealthStatusDegraded
+	default:
+		h.Status = HealthStatusHealthy
+	}
+	return h
+}
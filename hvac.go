@@ -0,0 +1,151 @@
+package main
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// 熱泵/空調 (HVAC) 暫存器位址
+const (
+	AddrHVACSupplyTemp      uint16 = 40040 // 出水溫度 (°C)
+	AddrHVACReturnTemp      uint16 = 40041 // 回水溫度 (°C)
+	AddrHVACSetpoint        uint16 = 40042 // 出水溫度設定值 (°C，可寫入)
+	AddrHVACOutdoorTemp     uint16 = 40043 // 外氣溫度 (°C)
+	AddrHVACCOP             uint16 = 40044 // 性能係數 COP
+	AddrHVACCompressorPower uint16 = 40045 // 壓縮機耗電功率 (W)
+	AddrHVACThermalPower    uint16 = 40047 // 冷/熱能輸出 (W)
+	AddrHVACCapacity        uint16 = 40049 // 額定冷/熱能力 (kW)
+)
+
+// 熱泵/空調線圈位址
+const (
+	CoilHVACEnable     uint16 = 0 // 運轉許可 (可寫入，新建的暫存器映射表預設啟用)
+	CoilHVACCompressor uint16 = 1 // 壓縮機運轉狀態
+)
+
+// ProfileHVAC 熱泵/空調設定檔名稱
+const ProfileHVAC = "hvac"
+
+// 熱泵/空調預設規格與模型參數
+const (
+	defaultHVACCapacityKW = 30.0
+	hvacIndoorTemp        = 22.0             // 室內溫度：停機時水溫趨近此值，設定值低於此值為冷氣、高於為暖氣
+	hvacDesignDeltaT      = 5.0              // 運轉時的出回水溫差 (°C)
+	hvacHysteresis        = 0.5              // 壓縮機啟停的溫度遲滯 (°C)
+	hvacRunTimeConstant   = 2 * time.Minute  // 運轉時水溫的時間常數
+	hvacIdleTimeConstant  = 10 * time.Minute // 停機時水溫的時間常數
+	hvacCarnotEfficiency  = 0.45             // 實際 COP 相對卡諾 COP 的比例
+)
+
+func init() {
+	RegisterDeviceProfile(&DeviceProfile{
+		Name:        ProfileHVAC,
+		Description: "熱泵/空調 (單相暫存器 + 出回水溫度、出水溫度設定值、COP、壓縮機功率、壓縮機狀態線圈)",
+		Registers: append(singlePhaseRegisters(),
+			RegisterDefinition{Address: AddrHVACSupplyTemp, Name: "SupplyTemp", DataType: "int16", Scale: 10, DefaultValue: 12.0, Unit: "°C"},
+			RegisterDefinition{Address: AddrHVACReturnTemp, Name: "ReturnTemp", DataType: "int16", Scale: 10, DefaultValue: 12.0, Unit: "°C"},
+			RegisterDefinition{Address: AddrHVACSetpoint, Name: "SupplySetpoint", DataType: "int16", Scale: 10, DefaultValue: 7.0, Unit: "°C", Writable: true},
+			RegisterDefinition{Address: AddrHVACOutdoorTemp, Name: "OutdoorTemp", DataType: "int16", Scale: 10, DefaultValue: 30.0, Unit: "°C"},
+			RegisterDefinition{Address: AddrHVACCOP, Name: "COP", DataType: "uint16", Scale: 100, DefaultValue: 0, Unit: ""},
+			RegisterDefinition{Address: AddrHVACCompressorPower, Name: "CompressorPower", DataType: "uint32", Scale: 1, DefaultValue: 0, Unit: "W"},
+			RegisterDefinition{Address: AddrHVACThermalPower, Name: "ThermalPower", DataType: "uint32", Scale: 1, DefaultValue: 0, Unit: "W"},
+			RegisterDefinition{Address: AddrHVACCapacity, Name: "Capacity", DataType: "uint16", Scale: 1, DefaultValue: defaultHVACCapacityKW, Unit: "kW"},
+		),
+		Coils:    []uint16{CoilHVACEnable},
+		NewModel: func() DeviceModel { return &HVACModel{} },
+	})
+}
+
+// HVACModel 熱泵/空調模型：壓縮機依出水溫度設定值以遲滯啟停，出回水溫度以一階動態趨近目標，
+// COP 依出水與外氣溫度 (卡諾 COP × 0.45) 計算；所有狀態皆在暫存器與線圈中
+type HVACModel struct {
+	lastUpdate time.Time
+	reload     atomic.Bool // 快照還原後，下次更新重新開始計時 (不以還原前的時間推進水溫)
+}
+
+// Update 依經過時間推進出回水溫度，並更新壓縮機狀態、COP 與功率
+func (m *HVACModel) Update(registers *RegisterMap, now time.Time) {
+	if m.lastUpdate.IsZero() || m.reload.Swap(false) {
+		m.lastUpdate = now
+		return
+	}
+	elapsed := now.Sub(m.lastUpdate)
+	m.lastUpdate = now
+
+	supply, _ := registers.GetScaledValue(AddrHVACSupplyTemp)
+	ret, _ := registers.GetScaledValue(AddrHVACReturnTemp)
+	setpoint, _ := registers.GetScaledValue(AddrHVACSetpoint)
+	outdoor, _ := registers.GetScaledValue(AddrHVACOutdoorTemp)
+	capacity, _ := registers.GetScaledValue(AddrHVACCapacity)
+	enabled, _ := registers.ReadCoil(CoilHVACEnable)
+	running, _ := registers.ReadCoil(CoilHVACCompressor)
+	if capacity <= 0 {
+		capacity = defaultHVACCapacityKW
+	}
+
+	// 設定值低於室內溫度為冷氣 (降低水溫)，否則為暖氣 (提高水溫)
+	direction := 1.0
+	if setpoint < hvacIndoorTemp {
+		direction = -1.0
+	}
+	// 遲滯：水溫越過設定值 hvacHysteresis 後停機，偏離設定值 hvacHysteresis 後再啟動
+	deviation := (setpoint - supply) * direction
+	switch {
+	case !enabled:
+		running = false
+	case running && deviation <= -hvacHysteresis:
+		running = false
+	case !running && deviation >= hvacHysteresis:
+		running = true
+	}
+
+	// 一階動態：運轉時出水溫度趨近略超過設定值的溫度，回水溫度維持設計溫差；停機時趨近室內溫度
+	supplyTarget, returnTarget, tau := hvacIndoorTemp, supply, hvacIdleTimeConstant
+	if running {
+		supplyTarget = setpoint + direction*2*hvacHysteresis
+		tau = hvacRunTimeConstant
+	}
+	alpha := 1 - math.Exp(-elapsed.Seconds()/tau.Seconds())
+	supply += (supplyTarget - supply) * alpha
+	if running {
+		returnTarget = supply - direction*hvacDesignDeltaT
+	}
+	ret += (returnTarget - ret) * alpha
+
+	cop := hvacCOP(supply, outdoor, direction < 0)
+	thermal, power := 0.0, 0.0
+	if running {
+		thermal = capacity * 1000
+		power = thermal / cop
+	}
+
+	registers.SetScaledValue(AddrHVACSupplyTemp, supply)
+	registers.SetScaledValue(AddrHVACReturnTemp, ret)
+	registers.SetScaledValue(AddrHVACCOP, cop)
+	registers.SetScaledValue(AddrHVACCompressorPower, power)
+	registers.SetScaledValue(AddrHVACThermalPower, thermal)
+	registers.WriteCoil(CoilHVACCompressor, running)
+}
+
+// RestoreState 快照還原後重新開始計時 (運轉許可與壓縮機狀態以還原的線圈為準)
+func (m *HVACModel) RestoreState(registers *RegisterMap) {
+	m.reload.Store(true)
+}
+
+// hvacCOP 依出水與外氣溫度計算 COP (卡諾 COP × hvacCarnotEfficiency，限制在 1-8)
+func hvacCOP(supply, outdoor float64, cooling bool) float64 {
+	cold, hot := supply, outdoor
+	if !cooling {
+		cold, hot = outdoor, supply
+	}
+	lift := hot - cold
+	if lift <= 0 {
+		return 8
+	}
+	useful := cold + 273.15
+	if !cooling {
+		useful = hot + 273.15
+	}
+	return math.Max(1, math.Min(8, hvacCarnotEfficiency*useful/lift))
+}
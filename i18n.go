@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// 支援的訊息語系
+const (
+	LangZhTW = "zh-TW" // 繁體中文 (預設)
+	LangEn   = "en"    // 英文
+	LangAuto = "auto"  // 依 LC_ALL / LC_MESSAGES / LANG 偵測
+)
+
+// currentLang 目前的訊息語系
+var currentLang atomic.Value
+
+// ParseLanguage 正規化語系名稱 (接受 zh-TW、zh_TW.UTF-8、en、en_US.UTF-8、auto 等寫法)
+func ParseLanguage(s string) (string, error) {
+	tag := strings.ToLower(strings.TrimSpace(s))
+	if i := strings.IndexAny(tag, ".@"); i >= 0 {
+		tag = tag[:i]
+	}
+	tag = strings.ReplaceAll(tag, "_", "-")
+
+	switch {
+	case tag == "" || tag == LangAuto:
+		return LangAuto, nil
+	case tag == "zh" || strings.HasPrefix(tag, "zh-"):
+		return LangZhTW, nil
+	case tag == "en" || strings.HasPrefix(tag, "en-"):
+		return LangEn, nil
+	}
+	return "", fmt.Errorf(T("不支援的語系: %s"), s)
+}
+
+// DetectLanguage 依環境變數偵測語系，僅在明確為英文時切換，其餘維持繁體中文
+func DetectLanguage() string {
+	for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(key)
+		if value == "" {
+			continue
+		}
+		if lang, err := ParseLanguage(value); err == nil && lang == LangEn {
+			return LangEn
+		}
+		return LangZhTW
+	}
+	return LangZhTW
+}
+
+// SetLanguage 設定訊息語系，auto 或無法辨識時依環境變數偵測
+func SetLanguage(lang string) {
+	parsed, err := ParseLanguage(lang)
+	if err != nil || parsed == LangAuto {
+		parsed = DetectLanguage()
+	}
+	currentLang.Store(parsed)
+}
+
+// Language 目前的訊息語系
+func Language() string {
+	if lang, ok := currentLang.Load().(string); ok {
+		return lang
+	}
+	return LangZhTW
+}
+
+// T 翻譯訊息 (以繁體中文原文為鍵，目錄未收錄時回傳原文)
+func T(msg string) string {
+	if Language() != LangEn {
+		return msg
+	}
+	if translated, ok := messagesEn[msg]; ok {
+		return translated
+	}
+	return msg
+}
+
+// scanLanguageFlag 在 cobra 解析前取得 --lang 參數，使說明文字也能套用語系
+func scanLanguageFlag(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if value, ok := strings.CutPrefix(arg, "--lang="); ok {
+			return value
+		}
+		if arg == "--lang" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return LangAuto
+}
+
+// localizeCommand 翻譯命令樹的說明文字與 flag 說明
+func localizeCommand(cmd *cobra.Command) {
+	cmd.Short = T(cmd.Short)
+	cmd.Long = T(cmd.Long)
+
+	localizeFlag := func(f *pflag.Flag) { f.Usage = T(f.Usage) }
+	cmd.Flags().VisitAll(localizeFlag)
+	cmd.PersistentFlags().VisitAll(localizeFlag)
+
+	for _, sub := range cmd.Commands() {
+		localizeCommand(sub)
+	}
+}
@@ -0,0 +1,978 @@
+package main
+
+// messagesEn 英文訊息目錄 (以繁體中文原文為鍵，未收錄的訊息沿用原文)
+var messagesEn = map[string]string{
+	// 管理 API
+	"無效的 duration: %s":   "invalid duration: %s",
+	"無效的 period: %s":     "invalid period: %s",
+	"找不到 Slave: %s":      "slave not found: %s",
+	"解析請求失敗: %w":         "failed to parse request: %w",
+	"序列化請求失敗: %w":        "failed to encode request: %w",
+	"建立請求失敗: %w":         "failed to create request: %w",
+	"連線管理 API 失敗: %w":    "failed to connect to admin API: %w",
+	"管理 API 錯誤: %s":      "admin API error: %s",
+	"管理 API 錯誤: HTTP %d": "admin API error: HTTP %d",
+	"解析回應失敗: %w":         "failed to parse response: %w",
+
+	// 識別閃爍
+	"Slave 未啟動: %s": "slave not started: %s",
+	"開始識別閃爍":        "identification blink started",
+	"識別閃爍結束":        "identification blink finished",
+
+	// CLI
+	"Modbus TCP 壓力測試模擬器": "Modbus TCP stress test simulator",
+	"專為能源管理系統 (EMS) 設計的高併發 Modbus TCP 模擬器。\n目標單機模擬 1,000+ 個獨立 IP 實體。": "High-concurrency Modbus TCP simulator built for energy management systems (EMS).\nTargets 1,000+ independent IP instances on a single host.",
+	"初始化日誌失敗: %w":                                           "failed to initialize logger: %w",
+	"載入配置檔失敗，使用預設配置":                                        "failed to load config file, using defaults",
+	"啟動模擬器":                                                 "Start the simulator",
+	"啟動 Modbus TCP 模擬器，開始監聽連線請求。":                           "Start the Modbus TCP simulator and listen for connections.",
+	"未知的設備設定檔: %s":                                          "unknown device profile: %s",
+	"啟動 Modbus 模擬器":                                         "starting Modbus simulator",
+	"啟動引擎失敗: %w":                                            "failed to start engine: %w",
+	"啟動指標伺服器失敗":                                             "failed to start metrics server",
+	"指標伺服器已啟動":                                              "metrics server started",
+	"收到關閉信號":                                                "received shutdown signal",
+	"關閉引擎失敗":                                                "failed to stop engine",
+	"模擬器已停止":                                                "simulator stopped",
+	"停止模擬器":                                                 "Stop the simulator",
+	"停止正在運行的 Modbus TCP 模擬器。":                               "Stop the running Modbus TCP simulator.",
+	"讀取 PID 檔案失敗: %w":                                       "failed to read PID file: %w",
+	"解析 PID 失敗: %w":                                         "failed to parse PID: %w",
+	"找不到程序: %w":                                             "process not found: %w",
+	"發送信號失敗: %w":                                            "failed to send signal: %w",
+	"已發送停止信號到 PID %d\n":                                     "stop signal sent to PID %d\n",
+	"查看運行狀態":                                                "Show running status",
+	"顯示模擬器的當前運行狀態和統計資訊。":                                    "Show the current status and statistics of the simulator.",
+	"狀態查詢功能尚未實作":                                            "status query is not implemented yet",
+	"請使用 metrics endpoint 查看詳細狀態":                           "use the metrics endpoint for detailed status",
+	"網路管理命令":                                                "Network management commands",
+	"管理虛擬 IP 配置。":                                           "Manage virtual IP configuration.",
+	"建立虛擬 IP":                                               "Create virtual IPs",
+	"在指定的網路介面上建立虛擬 IP 位址。":                                  "Create virtual IP addresses on the given network interface.",
+	"設置網路失敗: %w":                                            "failed to set up network: %w",
+	"虛擬 IP 設置完成":                                            "virtual IP setup complete",
+	"移除虛擬 IP":                                               "Remove virtual IPs",
+	"移除已配置的虛擬 IP 位址。":                                       "Remove configured virtual IP addresses.",
+	"移除網路失敗: %w":                                            "failed to tear down network: %w",
+	"虛擬 IP 已移除":                                             "virtual IPs removed",
+	"列出已配置 IP":                                              "List configured IPs",
+	"列出目前已配置的虛擬 IP 位址。":                                     "List the currently configured virtual IP addresses.",
+	"列出 IP 失敗: %w":                                          "failed to list IPs: %w",
+	"目前沒有配置虛擬 IP":                                           "no virtual IPs configured",
+	"已配置的虛擬 IP (%d 個):\n":                                   "configured virtual IPs (%d):\n",
+	"場景管理命令":                                                "Scenario management commands",
+	"管理模擬場景。":                                               "Manage simulation scenarios.",
+	"列出可用場景":                                                "List available scenarios",
+	"列出所有可用的模擬場景。":                                          "List all available simulation scenarios.",
+	"正常波動 (電壓 ±0.5%, 頻率 ±0.05%)":                            "normal fluctuation (voltage ±0.5%, frequency ±0.05%)",
+	"電壓驟降至 80%":                                             "voltage sag to 80%",
+	"網路延遲 100-500ms":                                        "network delay 100-500ms",
+	"封包丟失模擬 (5%)":                                           "packet loss simulation (5%)",
+	"三相不平衡 (單相電壓 -10%、電流 +10%)":                             "phase imbalance (single-phase voltage -10%, current +10%)",
+	"斷線閃斷 (離線 5s / 上線 15s 交替)":                              "connection flapping (offline 5s / online 15s)",
+	"慢速回應 (逐位元組寫出，10 bytes/sec)":                            "slow response (byte-by-byte writes, 10 bytes/sec)",
+	"資料凍結 (指定暫存器停止更新，模擬感測器卡死)":                              "data freeze (selected registers stop updating, simulating a stuck sensor)",
+	"分段回應 (每 3 bytes 一個 TCP 區段，間隔 50ms)":                    "fragmented response (one TCP segment per 3 bytes, 50ms apart)",
+	"例外風暴 (20% 請求回應 Illegal Data Address / Busy / Failure)": "exception storm (20% of requests answered with Illegal Data Address / Busy / Failure)",
+	"損壞回應 (10% 回應 Byte Count/Transaction ID 錯誤、截斷或亂碼)": "corrupted response (10% of responses with bad Byte Count/Transaction ID, truncated or garbage)",
+	"日負載曲線 (電流/功率依 24 小時曲線變化，14:00 尖峰)":                "daily load curve (current/power follow a 24-hour curve, peak at 14:00)",
+	"可用的模擬場景:":                   "available scenarios:",
+	"套用場景":                       "Apply a scenario",
+	"套用指定的模擬場景。":                 "Apply the given simulation scenario.",
+	"套用場景: %s":                   "applying scenario: %s",
+	" (持續 %v)":                   " (for %v)",
+	"重設為正常模式":                    "Reset to normal mode",
+	"重設模擬器為正常運行模式。":              "Reset the simulator to normal operation.",
+	"主備配對命令":                     "Redundant pair commands",
+	"查看與切換運行中實例的主備配對。":           "Inspect and switch redundant pairs of the running instance.",
+	"列出主備配對":                     "List redundant pairs",
+	"目前沒有主備配對":                   "no redundant pairs",
+	"主備切換":                       "Fail over a pair",
+	"切換指定配對的作用端與備援端。":            "Swap the active and standby ends of the given pair.",
+	"配對 %s 已切換: 作用端 %s，備援端 %s\n": "pair %s switched: active %s, standby %s\n",
+	"Slave 操作命令":                 "Slave commands",
+	"操作運行中實例的個別 Slave。":          "Operate on individual slaves of the running instance.",
+	"識別閃爍":                       "Identification blink",
+	"讓指定 Slave 的暫存器依樣式輪替並週期切換線圈，方便在 EMS 端辨識對應的設備。": "Cycle a register pattern and toggle a coil on the given slave so the matching device can be identified on the EMS side.",
+	"Slave %s 已停止識別閃爍\n":           "slave %s identification blink stopped\n",
+	"Slave %s 識別閃爍中: 暫存器 %d 樣式 %v": "slave %s blinking: register %d pattern %v",
+	"，線圈 %d 週期切換":                  ", toggling coil %d",
+	"，至 %s\n":                      ", until %s\n",
+	"暫存器內容雜湊":                      "Register content checksum",
+	"取得 Slave 暫存器內容的雜湊；未指定 Slave 時列出全部，搭配 --expect 僅列出不符者。": "Get register content checksums; lists all slaves when none is given, --expect lists only mismatches.",
+	"共 %d 個 Slave：符合 %d，不符 %d\n": "%d slaves: %d matched, %d mismatched\n",
+	"%d 個 Slave 的暫存器內容與預期不符":     "%d slaves have register contents that differ from the expected checksum",
+	"配置管理命令":                     "Config management commands",
+	"管理配置檔。":                     "Manage config files.",
+	"驗證配置檔":                      "Validate a config file",
+	"驗證指定的配置檔是否有效。":              "Check whether the given config file is valid.",
+	"配置驗證失敗: %w":                 "config validation failed: %w",
+	"配置驗證通過":                     "config is valid",
+	"生成範例配置":                     "Generate a sample config",
+	"生成範例配置檔。":                   "Generate a sample config file.",
+	"生成配置失敗: %w":                 "failed to generate config: %w",
+	"範例配置已生成: %s\n":              "sample config written: %s\n",
+	"產生部署檔":                      "Generate deployment files",
+	"產生以容器或 systemd 部署模擬器的設定檔，以及監控用的 Grafana 儀表板。": "Generate files for deploying the simulator in containers or under systemd, plus a Grafana dashboard for monitoring.",
+	"產生 docker-compose 部署檔": "Generate a docker-compose file",
+	"產生 docker-compose 部署檔：N 個容器各自以 macvlan 網路上的獨立 IP 運行一個 Slave，適用於不允許在主機上配置 IP 別名的環境。": "Generate a docker-compose file: N containers each running one slave on its own IP on a macvlan network, for environments that forbid host-level IP aliasing.",
+	"產生部署檔失敗: %w":             "failed to generate deployment file: %w",
+	"部署檔已產生: %s (%d 個容器)\n":   "deployment file written: %s (%d containers)\n",
+	"無效的服務名稱: %s":             "invalid service name: %s",
+	"閘道 %s 不在網段 %s 內":         "gateway %s is not in subnet %s",
+	"網段 %s 的可用位址不足 %d 個":      "subnet %s has fewer than %d usable addresses",
+	"容器 (Slave) 數量":           "number of containers (slaves)",
+	"macvlan 網段 (CIDR，必填)":    "macvlan subnet (CIDR, required)",
+	"網段的閘道 (預設為網段的第一個位址)":     "subnet gateway (default first address of the subnet)",
+	"第一個容器的 IP (預設為閘道的下一個位址)": "IP of the first container (default the address after the gateway)",
+	"macvlan 的上層介面":           "macvlan parent interface",
+	"模擬器映像":                   "simulator image",
+	"服務名稱前綴":                  "service name prefix",
+	"掛載到每個容器的主機配置檔":           "host config file mounted into each container",
+	"產生 Kubernetes 部署檔":       "Generate Kubernetes manifests",
+	"產生 StatefulSet 與 headless Service：每個 Pod 運行一個 Slave，依 Pod 環境變數取得身分 (StatefulSet 序號決定 Unit ID)，調整 replicas 即可跨節點水平擴展；指定 --subnet 時另產生 Multus NetworkAttachmentDefinition，讓每個 Pod 擁有現場網段上的 IP。": "Generate a StatefulSet and headless Service: each pod runs one slave and takes its identity from the pod environment (the StatefulSet ordinal determines the unit ID), so the fleet scales across nodes by changing replicas; with --subnet a Multus NetworkAttachmentDefinition is added so each pod gets an IP on the field subnet.",
+	"部署檔已產生: %s (%d 個 Pod)\n":                       "deployment file written: %s (%d pods)\n",
+	"無效的 Kubernetes 資源名稱: %s":                       "invalid Kubernetes resource name: %s",
+	"Pod (Slave) 數量":                                "number of pods (slaves)",
+	"StatefulSet 與 Service 名稱":                      "StatefulSet and Service name",
+	"命名空間 (預設為 kubectl 的目前命名空間)":                    "namespace (default the current kubectl namespace)",
+	"掛載為 /app/configs 的 ConfigMap":                  "ConfigMap mounted at /app/configs",
+	"Multus macvlan 附加網路的網段 (CIDR，未指定時以 Pod IP 監聽)": "subnet of the Multus macvlan attachment (CIDR; listen on the pod IP when omitted)",
+	"附加網路配置的第一個 IP":                                 "first IP allocated on the attachment",
+	"附加網路配置的最後一個 IP":                                "last IP allocated on the attachment",
+	"附加網路的閘道":                                       "gateway of the attachment",
+	"節點上 macvlan 的上層介面":                             "macvlan parent interface on the node",
+	"Slave 身分來源 (static, pod)":                      "slave identity source (static, pod)",
+	"不支援的 Slave 身分來源: %s (可用: static, pod)":         "unsupported slave identity source: %s (available: static, pod)",
+	"取得 Pod 身分失敗: %w":                               "failed to get pod identity: %w",
+	"以 Pod 身分運行":                                    "running with pod identity",
+	"未設定環境變數 %s":                                    "environment variable %s is not set",
+	"Pod 名稱 %s 不含 StatefulSet 序號":                   "pod name %s has no StatefulSet ordinal",
+	"無效的 IP 位址: %s":                                 "invalid IP address: %s",
+	"網路介面 %s 沒有 IPv4 位址":                            "network interface %s has no IPv4 address",
+	"輸出檔案路徑 (預設輸出到 stdout)":                         "output file path (default stdout)",
+	"agent 可承載的 Slave 數必須大於 0":                      "agent capacity must be greater than 0",
+	"agent 已加入叢集":                                   "agent joined the cluster",
+	"coordinator 分配了不同的範圍，重新啟動 agent 後生效":           "coordinator assigned a different range; restart the agent to apply it",
+	"以一個 coordinator 將 Slave 範圍與場景分配給多台主機上的 agent，並彙整各 agent 的指標。": "Use one coordinator to distribute slave ranges and scenarios to agents on multiple hosts and aggregate their metrics.",
+	"以叢集 agent 啟動模擬器":                                     "Start the simulator as a cluster agent",
+	"加入叢集失敗: %w":                                          "failed to join the cluster: %w",
+	"取得主機名稱失敗: %w":                                        "failed to get hostname: %w",
+	"叢集 coordinator 已啟動":                                  "cluster coordinator started",
+	"叢集 coordinator 需要配置 network.ip_ranges":               "cluster coordinator requires network.ip_ranges",
+	"叢集場景已變更":                                             "cluster scenario changed",
+	"叢集場景已變更為 %s (%d 個 agent)\n":                          "cluster scenario changed to %s (%d agents)\n",
+	"叢集模式命令":                                              "Cluster mode commands",
+	"叢集設定不可為負: slaves_per_agent=%d heartbeat_interval=%v": "cluster settings must not be negative: slaves_per_agent=%d heartbeat_interval=%v",
+	"向 coordinator 取得要模擬的 Slave 範圍後啟動引擎，定期回報指標並跟隨叢集的場景。": "Get the slave range to simulate from the coordinator, start the engine, report metrics periodically and follow the cluster scenario.",
+	"啟動叢集 coordinator": "Start the cluster coordinator",
+	"啟動叢集控制平面：依 agent 加入順序將 network.ip_ranges 分段分配，下發場景，並在指標埠提供彙整指標與 /api/cluster 管理 API。": "Start the cluster control plane: split network.ip_ranges among agents in join order, push scenarios, and serve aggregated metrics and the /api/cluster admin API on the metrics port.",
+	"回報 coordinator 失敗":          "failed to report to coordinator",
+	"場景: %s，已分配 %d/%d 個 Slave\n": "scenario: %s, %d/%d slaves assigned\n",
+	"套用叢集場景失敗":                   "failed to apply cluster scenario",
+	"已取得叢集分配":                    "received cluster assignment",
+	"已恢復與 coordinator 的連線":       "connection to coordinator restored",
+	"未指定 coordinator 位址":         "coordinator address not specified",
+	"未知的場景: %s":                  "unknown scenario: %s",
+	"沒有可分配的 Slave IP":            "no slave IPs left to assign",
+	"無效的叢集位址: %s":                "invalid cluster address: %s",
+	"監聽失敗: %w":                   "failed to listen: %w",
+	"目前沒有 agent":                 "no agents yet",
+	"缺少 agent 識別":                "missing agent ID",
+	"變更叢集場景":                     "Change the cluster scenario",
+	"變更整個叢集的場景，各 agent 於下次回報時套用。": "Change the scenario of the whole cluster; agents apply it on their next report.",
+	"連線 coordinator 失敗，稍後重試":      "failed to connect to coordinator, retrying",
+	"重新加入叢集失敗":                    "failed to rejoin the cluster",
+	"顯示 coordinator 的場景與各 agent 的分配及最近一次回報 (--api 指向 coordinator 的指標埠)。": "Show the coordinator scenario and the assignment and latest report of each agent (--api points to the coordinator metrics port).",
+	"顯示叢集狀態":                     "Show cluster status",
+	"gRPC 監聽位址 (預設 :9700)":       "gRPC listen address (default :9700)",
+	"coordinator 位址 (host:port)": "coordinator address (host:port)",
+	"agent 識別 (預設為主機名稱)":         "agent ID (default hostname)",
+	"agent 可承載的 Slave 數":         "number of slaves the agent can host",
+	"啟動前依分配建立虛擬 IP":              "provision the assigned virtual IPs before starting",
+	"Slave 啟動失敗":                 "Slave failed to start",
+	"Slave 逐步上線中":                "Slaves ramping up",
+	"Slave 逐步上線完成":               "Slave ramp-up complete",
+	"逐步下線逾時，其餘 Slave 立即停止":       "Ramp-down timed out, stopping the remaining slaves immediately",
+	"Slave 將逐步上線":                "Slaves will ramp up gradually",
+	"逐步上線/下線速率不可為負: ramp_up_rate=%v ramp_down_rate=%v announce_rate=%v": "Ramp rates must not be negative: ramp_up_rate=%v ramp_down_rate=%v announce_rate=%v",
+	"暫存器對應檔循環引用: %s":                                    "Register map include cycle: %s",
+	"讀取暫存器對應檔 %s 失敗: %w":                                "Failed to read register map %s: %w",
+	"解析暫存器對應檔 %s 失敗: %w":                                "Failed to parse register map %s: %w",
+	"暫存器 %s (%d): 位址超出範圍 (保持暫存器 %s)":                    "Register %s (%d): address out of range (holding registers %s)",
+	"暫存器 %s (%d) 與 %s (%d, 佔用 %d 個暫存器) 位址重疊":            "Register %s (%d) overlaps %s (%d, spanning %d registers)",
+	"字串長度必須介於 1-%d: %s":                                 "String length must be between 1 and %d: %s",
+	"暫存器 %s (%d) 為 %s，不是數值":                             "Register %s (%d) is %s, not a number",
+	"暫存器 %d 不是字串類型":                                     "Register %d is not a string type",
+	"暫存器 %s (%d): 字串長度 %d 超過 %d":                        "Register %s (%d): string length %d exceeds %d",
+	"暫存器 %s (%d): 字串只能包含 ASCII 字元":                      "Register %s (%d): strings may only contain ASCII characters",
+	"暫存器 %s (%d): 字串類型不可設定運算式":                          "Register %s (%d): string registers cannot have an expression",
+	"不支援的位址慣例: %s (可用: auto, protocol, plc, modicon)":   "Unsupported addressing mode: %s (available: auto, protocol, plc, modicon)",
+	"寫入回呼執行失敗":                                          "Write hook failed",
+	"觸發寫入回呼":                                            "Write hook triggered",
+	"寫入回呼必須指定名稱":                                        "write hook must have a name",
+	"寫入回呼 %s 的 table 必須為 coils 或 holding_registers: %s": "write hook %s: table must be coils or holding_registers: %s",
+	"寫入回呼 %s 的 delay 不可為負":                              "write hook %s: delay must not be negative",
+	"寫入回呼 %s 必須指定至少一個動作":                                "write hook %s must have at least one action",
+	"寫入回呼 %s 的動作使用不支援的資料表: %s":                          "write hook %s: unsupported action table: %s",
+	"寫入回呼 %s 的目標無效: %s":                                 "write hook %s: invalid target: %s",
+	"寫入回呼 %s 使用未定義的 Slave 標籤: %s":                       "write hook %s uses undefined slave tag: %s",
+	"寫入回呼名稱重複: %s":                                      "duplicate write hook name: %s",
+	"寫入回呼驗證失敗: %w":                                      "write hook validation failed: %w",
+	"%d 個 Slave 還原失敗":                                   "%d slaves failed to restore",
+	"不支援的快照版本: %d":                                      "unsupported snapshot version: %d",
+	"保存快照":                                              "Save a snapshot",
+	"保存快照失敗":                                            "Failed to save snapshot",
+	"保存或還原運行中實例所有 Slave 的暫存器內容、累計電能與目前的場景。": "Save or restore register contents, accumulated energy and the current scenario of all slaves in a running instance.",
+	"寫入快照檔失敗: %w": "failed to write snapshot file: %w",
+	"將快照檔還原至運行中的實例 (依 IP 對應 Slave，尚未啟動的 Slave 於啟動時還原)。": "Restore a snapshot file into a running instance (slaves are matched by IP; slaves not yet started are restored when they start).",
+	"將運行中實例的狀態保存為 JSON 快照檔。":                            "Save the state of a running instance as a JSON snapshot file.",
+	"已保存 %d 個 Slave 的快照至 %s\n":                          "saved snapshot of %d slaves to %s\n",
+	"已保存快照": "Snapshot saved",
+	"已還原 %d 個 Slave (待啟動 %d，失敗 %d)，場景: %s\n": "restored %d slaves (%d pending, %d failed), scenario: %s\n",
+	"已還原快照":                                                        "Snapshot restored",
+	"序列化快照失敗: %w":                                                  "failed to serialize snapshot: %w",
+	"快照中的 Slave IP 無效":                                             "Invalid slave IP in snapshot",
+	"快照中的 Unit ID 未配置: %d":                                         "unit ID in snapshot is not configured: %d",
+	"快照區段超出範圍: %d-%d (共 %d 個)":                                     "snapshot block out of range: %d-%d (table size %d)",
+	"快照檔不存在，以初始狀態啟動":                                               "Snapshot file not found, starting from initial state",
+	"狀態快照命令":                                                       "State snapshot commands",
+	"解析快照檔 %s 失敗: %w":                                              "failed to parse snapshot file %s: %w",
+	"讀取快照檔失敗: %w":                                                  "failed to read snapshot file: %w",
+	"還原 Slave 快照失敗":                                                "Failed to restore slave snapshot",
+	"還原快照":                                                         "Restore a snapshot",
+	"checkpoint 間隔不可為負: %v":                                        "checkpoint interval must not be negative: %v",
+	"保存 checkpoint 失敗":                                             "failed to save checkpoint",
+	"啟用狀態持久化時必須指定 checkpoint 間隔":                                   "interval is required when persistence is enabled",
+	"啟用狀態持久化時必須指定 path":                                            "path is required when persistence is enabled",
+	"建立狀態目錄失敗: %w":                                                 "failed to create state directory: %w",
+	"解析 %s 失敗: %w":                                                 "failed to parse %s: %w",
+	"讀取狀態目錄失敗: %w":                                                 "failed to read state directory: %w",
+	"還原 checkpoint 失敗":                                             "failed to restore checkpoint",
+	"開啟狀態儲存失敗: %w":                                                 "failed to open state store: %w",
+	"關閉狀態儲存失敗":                                                     "failed to close state store",
+	"Unit ID 無效: %s":                                               "invalid unit ID: %s",
+	"value 必須為數值或字串":                                               "value must be a number or a string",
+	"不支援的資料表: %s":                                                  "unsupported table: %s",
+	"位址範圍無效: %d (%d 個)":                                            "invalid address range: %d (%d registers)",
+	"找不到 Unit ID: %d":                                              "unit ID not found: %d",
+	"MQTT PUBLISH 封包格式錯誤":                                          "malformed MQTT PUBLISH packet",
+	"MQTT broker 位址無效: %s (格式 tcp://host:port)":                    "invalid MQTT broker address: %s (format tcp://host:port)",
+	"MQTT broker 回應非預期的封包: %d":                                     "unexpected packet from MQTT broker: %d",
+	"MQTT broker 拒絕連線 (return code %d)":                            "MQTT broker refused connection (return code %d)",
+	"MQTT 封包剩餘長度無效":                                                "invalid MQTT remaining length",
+	"MQTT 發布使用未定義的 Slave 標籤: %s":                                   "MQTT publisher uses undefined slave tag: %s",
+	"MQTT 發布的目標無效: %s":                                             "invalid MQTT publisher target: %s",
+	"MQTT 的 interval 與 keep_alive 不可為負: interval=%v keep_alive=%v": "MQTT interval and keep_alive must not be negative: interval=%v keep_alive=%v",
+	"MQTT 連線中斷，稍後重新連線":                                             "MQTT connection lost, reconnecting",
+	"Sparkplug ID 不可包含 /、+ 或 #: %s":                                "Sparkplug IDs must not contain /, + or #: %s",
+	"啟用 MQTT 發布時必須指定 group_id":                                     "group_id is required when MQTT publishing is enabled",
+	"啟用 MQTT 發布時必須指定 interval":                                     "interval is required when MQTT publishing is enabled",
+	"已連線至 MQTT broker":                                             "connected to MQTT broker",
+	"收到 Sparkplug 重新出生命令":                                          "received Sparkplug rebirth command",
+	"解析 Sparkplug 命令失敗":                                            "failed to parse Sparkplug command",
+	"OPC UA 伺服器使用未定義的 Slave 標籤: %s":                                "OPC UA server references undefined slave tag: %s",
+	"OPC UA 伺服器未啟動":                                                "OPC UA server not started",
+	"OPC UA 伺服器的目標無效: %s":                                          "invalid OPC UA server target: %s",
+	"OPC UA 伺服器監聽失敗: %w":                                           "OPC UA server failed to listen: %w",
+	"OPC UA 監聽位址無效: %s":                                            "invalid OPC UA listen address: %s",
+	"OPC UA 訊息格式錯誤":                                                "malformed OPC UA message",
+	"OPC UA 連線結束":                                                  "OPC UA connection closed",
+	"OPC UA 連線錯誤 0x%08X: %s":                                       "OPC UA connection error 0x%08X: %s",
+	"已啟動 OPC UA 伺服器":                                               "OPC UA server started",
+	"IEC 104 APDU 長度無效: %d":                                        "invalid IEC 104 APDU length: %d",
+	"IEC 104 ASDU 長度不足: %d":                                        "IEC 104 ASDU too short: %d",
+	"IEC 104 不支援的 U 格式功能: 0x%02x":                                  "unsupported IEC 104 U-format function: 0x%02x",
+	"IEC 104 使用未知的設備設定檔: %s":                                       "IEC 104 references unknown device profile: %s",
+	"IEC 104 序號錯誤: 收到 %d，預期 %d":                                    "IEC 104 sequence error: received %d, expected %d",
+	"IEC 104 監聽埠無效: %d (需介於 1-65535 且不同於 Modbus 埠)":                "invalid IEC 104 port: %d (must be 1-65535 and differ from the Modbus port)",
+	"IEC 104 確認的序號無效: %d":                                          "invalid IEC 104 acknowledged sequence number: %d",
+	"IEC 104 等待確認逾時 (t1)":                                          "IEC 104 acknowledgement timeout (t1)",
+	"IEC 104 起始字元錯誤: 0x%02x":                                       "invalid IEC 104 start byte: 0x%02x",
+	"IEC 104 連線中斷":                                                 "IEC 104 connection closed",
+	"BACnet Who-Is 廣播接收未啟動":                                        "BACnet Who-Is broadcast receiver not started",
+	"BACnet 回應失敗":                                                  "Failed to send BACnet response",
+	"BACnet 廣播位址無效: %s":                                            "Invalid BACnet broadcast address: %s",
+	"BACnet 接收封包失敗":                                                "Failed to receive BACnet packet",
+	"BACnet 監聽埠無效: %d":                                             "Invalid BACnet listen port: %d",
+	"BACnet 設備 instance 起始值無效: %d (需介於 0-%d)":                      "Invalid BACnet device instance base: %d (must be 0-%d)",
+	"BACnet 設備使用未定義的 Slave 標籤: %s":                                 "BACnet device mode uses undefined slave tag: %s",
+	"BACnet 設備的目標無效: %s":                                           "Invalid BACnet device target: %s",
+	"datagram 長度與 MBAP 長度不符: %d":                                   "Datagram length does not match MBAP length: %d",
+	"不支援的傳輸方式: %s (可用: tcp, udp)":                                  "Unsupported transport: %s (available: tcp, udp)",
+	"寫出回應失敗":                                                       "Failed to write response",
+	"接收 datagram 失敗":                                               "Failed to receive datagram",
+	"extra_ports 的埠號重複: %d":                                        "Duplicate port in extra_ports: %d",
+	"存取控制規則 %s 使用未定義的 Slave 標籤: %s":                                "ACL rule %s uses undefined slave tag: %s",
+	"存取控制規則 %s 的 action 不支援: %s (可用: reject, exception)":           "Unsupported action in ACL rule %s: %s (available: reject, exception)",
+	"存取控制規則 %s 的位址無效: %s":                                          "Invalid address in ACL rule %s: %s",
+	"存取控制規則名稱重複: %s":                                               "Duplicate ACL rule name: %s",
+	"存取控制規則必須指定名稱":                                                 "ACL rule must have a name",
+	"存取控制規則驗證失敗: %w":                                               "ACL rule validation failed: %w",
+	"用戶端不在允許清單，拒絕連線":                                               "Client not in allowlist, rejecting connection",
+	"不支援的單一 Master 模式: %s (可用: refuse, queue)":                     "unsupported single master mode: %s (available: refuse, queue)",
+	"場景 %s 的 churn_rate 不可為負: %v":                                  "scenario %s churn_rate must not be negative: %v",
+	"場景 %s 的連線中斷方式無效: %s (可用: rst, fin)":                           "scenario %s has an invalid churn mode: %s (available: rst, fin)",
+	"強制中斷連線":                                                       "forcibly closed connection",
+	"連線擾動 (每分鐘隨機以 RST 或 FIN 中斷 6 條既有連線)":                           "connection churn (reset or close 6 established connections per minute at random)",
+	"模擬回應逾時，不回應請求":                                                 "simulating response timeout, not responding to request",
+	"場景 %s 的延遲分佈無效: %s (可用: fixed, uniform, normal, lognormal, pareto)": "scenario %s has an invalid delay distribution: %s (available: fixed, uniform, normal, lognormal, pareto)",
+	"場景 %s 的延遲分佈參數不可為負":                                                 "scenario %s delay distribution parameters must not be negative",
+	"場景 %s 的 jitter_timeout_rate 必須介於 0-1: %f":                          "scenario %s jitter_timeout_rate must be between 0 and 1: %f",
+	"功能碼覆寫規則 %s 使用未定義的 Slave 標籤: %s":                                    "function override rule %s uses an undefined slave tag: %s",
+	"功能碼覆寫規則 %s 必須指定 exception 或 delay":                                 "function override rule %s must specify exception or delay",
+	"功能碼覆寫規則 %s 必須指定功能碼":                                                "function override rule %s must specify function codes",
+	"功能碼覆寫規則 %s 的例外名稱無效: %s":                                            "function override rule %s has an invalid exception name: %s",
+	"功能碼覆寫規則 %s 的功能碼無效: %d":                                             "function override rule %s has an invalid function code: %d",
+	"功能碼覆寫規則 %s 的目標無效: %s":                                              "function override rule %s has an invalid target: %s",
+	"功能碼覆寫規則 %s 的設備設定檔不存在: %s":                                          "function override rule %s references an unknown device profile: %s",
+	"功能碼覆寫規則名稱重複: %s":                                                   "duplicate function override rule name: %s",
+	"功能碼覆寫規則必須指定名稱":                                                     "function override rule must have a name",
+	"功能碼覆寫規則驗證失敗: %w":                                                   "function override rule validation failed: %w",
+	"功能碼覆寫，回應例外":                                                        "function override, responding with exception",
+	"設備不支援的功能碼":                                                         "function code not supported by device",
+	"設備設定檔 %s 支援的功能碼不可為空":                                               "supported function codes of device profile %s must not be empty",
+	"設備設定檔 %s 的功能碼無效: %d":                                               "device profile %s has an invalid function code: %d",
+	"場景 %s 的 mismatch_rate 必須介於 0-1: %f":                                "scenario %s mismatch_rate must be between 0 and 1: %f",
+	"場景 %s 的交易錯亂模式無效: %s (可用: wrong, previous, reorder)":                "scenario %s has an invalid mismatch mode: %s (available: wrong, previous, reorder)",
+	"交易錯亂 (10% 回應使用錯誤或上一個 Transaction ID，或與下一個回應對調順序)":                  "transaction mismatch (10% of responses use a wrong or previous transaction ID, or swap order with the next response)",
+	"同時處理的請求數上限不可為負: %d":                                                "max_in_flight must not be negative: %d",
+	"超過請求速率限制，回應 Busy":                                                  "Request rate limit exceeded, responding busy",
+	"請求速率限制不可為負: request_rate_limit=%v request_burst=%d":                "request rate limit must not be negative: request_rate_limit=%v request_burst=%d",
+	"Slave:     %s (%s)\n":                                              "Slave:      %s (%s)\n",
+	"Unit ID:   %v\n":                                                   "Unit IDs:   %v\n",
+	"上次請求:  %s\n":                                                       "Last request: %s\n",
+	"列出 Slave":                                                          "List slaves",
+	"列出指定 Slave 的狀態、統計與所有已定義暫存器的工程值 (依暫存器名稱與單位解碼)；--unit 查看閘道後方的其他設備。": "Show a slave's state, statistics and the engineering value of every defined register (decoded by register name and unit); --unit inspects another device behind the gateway.",
+	"列出運行中實例的所有 Slave；每秒請求數 (RPS) 由間隔 --interval 的兩次查詢計算，0 表示不計算。":     "List all slaves of the running instance; requests per second (RPS) are computed from two queries --interval apart, 0 skips it.",
+	"場景:      %s\n":    "Scenario:   %s\n",
+	"查看 Slave 詳細資訊":    "Inspect a slave",
+	"狀態:      %s\n":    "State:      %s\n",
+	"目前沒有 Slave":       "No slaves",
+	"請求/錯誤: %d / %d\n": "Requests/errors: %d / %d\n",
+	"連線數:    %d\n":     "Connections: %d\n",
+	"以工程值寫入指定 Slave 的已定義暫存器 (--name 或 --address)，寫入後輸出讀回的值；管理 API 寫入不受 writable 限制。": "Write an engineering value to a defined register of a slave (--name or --address) and print the value read back; admin API writes ignore writable.",
+	"以暫存器名稱讀寫運行中實例的已定義暫存器，數值為工程值 (依定義的縮放與資料類型換算)。":                                   "Read and write defined registers of the running instance by name; values are engineering values (converted using the defined scale and data type).",
+	"位址 %d 沒有已定義的暫存器":                 "no defined register at address %d",
+	"寫入暫存器":                           "Write a register",
+	"必須以 --name 或 --address 指定暫存器":    "specify the register with --name or --address",
+	"找不到暫存器: %s":                      "register not found: %s",
+	"數值超出 %s 暫存器 %s 的範圍: %v (縮放後 %v)": "value out of range for %s register %s: %v (%v after scaling)",
+	"暫存器 %s 為 %s，數值無效: %s":            "register %s is %s, invalid value: %s",
+	"暫存器讀寫命令":                         "Register read/write commands",
+	"讀取指定 Slave 的已定義暫存器 (--name 或 --address)，輸出工程值與原始值。": "Read a defined register of a slave (--name or --address) and print its engineering and raw values.",
+	"讀取暫存器": "Read a register",
+	"以工程值寫入所有符合 --targets 的 Slave 的同名暫存器 (例如模擬全系統的頻率事件)；任一 Slave 的值無效時全部不寫入。": "Write an engineering value to the same-named register of every slave matching --targets (e.g. to simulate a system-wide frequency event); nothing is written if the value is invalid for any slave.",
+	"已寫入 %d 個 Slave 的 %s = %s":        "%d slaves written: %s = %s",
+	"批次寫入多個 Slave 的暫存器":               "Write a register on many slaves",
+	"批次寫入暫存器":                         "Bulk register write",
+	"數值無效: %s (字串類型的暫存器請加上 --string)": "invalid number: %s (add --string for string registers)",
+	"暫存器 %s 為 %s，value 必須為字串":         "register %s is %s, value must be a string",
+	"暫存器 %s 為 %s，value 必須為數值":         "register %s is %s, value must be a number",
+	"沒有符合的 Slave 定義暫存器: %s":           "no matching slave defines register: %s",
+	"無效的目標: %s":                       "invalid target: %s",
+	"，略過 %d 個沒有此暫存器的 Slave":           ", skipped %d slaves without this register",
+	"計算每秒請求數的取樣間隔 (0 不計算)":            "sampling interval for requests per second (0 to skip)",
+	"閘道後方設備的 Unit ID (預設為主設備)":        "Unit ID of a device behind the gateway (default: main device)",
+	"Slave 的 IP 或 ID (ip:port)":       "slave IP or ID (ip:port)",
+	"暫存器名稱 (不分大小寫)":                   "register name (case-insensitive)",
+	"暫存器位址 (未指定 --name 時使用)":          "register address (used when --name is not given)",
+	"要寫入的工程值 (字串類型為字串)":               "engineering value to write (text for string registers)",
+	"要寫入的工程值":                         "engineering value to write",
+	"僅寫入指定 IP/CIDR 的 Slave (預設全部)":    "only write slaves in these IPs/CIDRs (default: all)",
+	"以字串寫入 (字串類型的暫存器)":                "write as text (string registers)",
+	"停止 PID 檔案中的實例並等待其結束，再以相同的 start 參數在背景啟動 (沒有執行中的實例時直接啟動)。": "Stop the instance in the PID file, wait for it to exit, then start in the background with the same start flags (starts directly when no instance is running).",
+	"取得執行檔路徑失敗: %w":                              "failed to get executable path: %w",
+	"啟動背景程序失敗: %w":                               "failed to start background process: %w",
+	"寫入 PID 檔案失敗: %w":                            "failed to write PID file: %w",
+	"已有執行中的實例 (PID %d，%s)":                       "an instance is already running (PID %d, %s)",
+	"模擬器已停止 (PID %d)\n":                          "simulator stopped (PID %d)\n",
+	"模擬器已在背景啟動 (PID %d，%s)\n":                    "simulator started in the background (PID %d, %s)\n",
+	"沒有執行中的實例，直接啟動":                              "No running instance, starting",
+	"移除 PID 檔案失敗":                                "Failed to remove PID file",
+	"等待程序結束逾時 (PID %d)":                          "timed out waiting for process to exit (PID %d)",
+	"等待背景程序啟動逾時 (PID %d)":                        "timed out waiting for background process to start (PID %d)",
+	"背景程序啟動失敗 (詳見 %s)":                           "background process failed to start (see %s)",
+	"重新啟動模擬器":                                    "Restart the simulator",
+	"開啟日誌檔失敗: %w":                                "failed to open log file: %w",
+	"啟動時自此快照檔還原 (檔案存在時)，關閉時保存":                   "restore from this snapshot file at startup (if it exists) and save on shutdown",
+	"PID 檔案路徑 (背景執行時預設為 /var/run/modbussim.pid)": "PID file path (default /var/run/modbussim.pid when running in the background)",
+	"背景執行時的日誌檔 (預設捨棄)":                           "log file when running in the background (discarded by default)",
+	"在背景執行 (完成啟動後返回)":                            "run in the background (returns once started)",
+	"等待原本的實例結束的時間上限":                             "how long to wait for the running instance to exit",
+	"等待程序結束的時間上限 (0 表示發送信號後立即返回)":                "how long to wait for the process to exit (0 returns right after signalling)",
+	"無效的 unit 名稱: %s":                            "invalid unit name: %s",
+	"systemd unit 必須使用絕對路徑: %s":                  "systemd units require absolute paths: %s",
+	"無效的啟動逾時: %s":                                "invalid start timeout: %s",
+	"等待 %d 個 Slave 完成綁定":                         "waiting for %d slaves to bind",
+	"%d 個 Slave 運行中":                             "%d slaves running",
+	"通知 systemd 失敗":                              "Failed to notify systemd",
+	"產生 systemd service unit":                    "Generate a systemd service unit",
+	"產生 Type=notify 的 systemd service unit：模擬器於所有 Slave 完成綁定後才回報就緒，依賴此服務的 unit 啟動時整個 Slave 群已可輪詢。配置檔取自 -c (轉為絕對路徑，未指定時為 /etc/modbussim/config.json)。": "Generate a Type=notify systemd service unit: the simulator reports readiness only after every slave has bound, so units that depend on it start once the whole fleet is pollable. The config file comes from -c (made absolute; defaults to /etc/modbussim/config.json).",
+	"systemd unit 已產生: %s\n":               "systemd unit generated: %s\n",
+	"unit 名稱 (不含 .service)":                "Unit name (without .service)",
+	"模擬器執行檔的絕對路徑":                          "Absolute path of the simulator binary",
+	"綁定埠號後降級為此使用者":                         "Drop to this user after binding ports",
+	"啟動時配置虛擬 IP":                           "Configure virtual IPs at startup",
+	"關閉時保存、啟動時接續的快照檔 (絕對路徑)":               "Snapshot file saved on shutdown and resumed on startup (absolute path)",
+	"等待所有 Slave 完成綁定的上限 (TimeoutStartSec)": "Maximum time to wait for all slaves to bind (TimeoutStartSec)",
+	"產生 Grafana 儀表板":                       "Generate a Grafana dashboard",
+	"產生對應本模擬器 Prometheus 指標的 Grafana 儀表板 JSON：整體概況、請求延遲直方圖、場景面板與每個 Slave 的面板，並以場景切換作為 annotation 標出故障注入期間。": "Generate a Grafana dashboard JSON wired to the simulator's Prometheus metrics: fleet overview, request latency histograms, scenario panels and per-slave panels, with scenario switches as annotations marking fault injection periods.",
+	"儀表板已產生: %s\n":               "Dashboard generated: %s\n",
+	"儀表板標題不可為空":                  "dashboard title must not be empty",
+	"無效的儀表板 UID: %s":             "invalid dashboard UID: %s",
+	"儀表板標題":                      "Dashboard title",
+	"儀表板 UID (匯入時覆寫相同 UID 的儀表板)": "Dashboard UID (importing overwrites a dashboard with the same UID)",
+	"預設的 Prometheus 資料來源 (預設為 Grafana 的預設資料來源)": "Default Prometheus data source (defaults to Grafana's default data source)",
+	"自動重新整理間隔 (空字串 = 不自動重新整理)":                  "Auto-refresh interval (empty = no auto-refresh)",
+	"加入每個 Slave 的面板 (需啟用 metrics.per_slave)":    "Include per-slave panels (requires metrics.per_slave)",
+	"健康 Slave 比例門檻必須介於 0-100: %v":               "healthy slave threshold must be between 0 and 100: %v",
+	"解析場景參數失敗: %w":                              "failed to parse scenario parameters: %w",
+	"場景參數 %s 無法即時調整，需重新套用場景":                    "scenario parameter %s cannot be tuned at runtime; re-apply the scenario instead",
+	"未指定要調整的場景參數":                               "no scenario parameters to tune",
+	"已調整場景參數":                                   "Scenario parameters tuned",
+	"已還原場景參數":                                   "Scenario parameters restored",
+	"即時調整場景參數":                                  "Tune scenario parameters at runtime",
+	"即時調整運行中實例的場景參數 (例如 packet_loss_rate=0.05 jitter_max=500ms)，立即套用到運行該場景的 Slave，不需重新套用場景。清單以逗號分隔 (corrupt_modes=truncate,garbage)，對應表以點號指定 (exception_weights.slave_device_busy=3)；未指定參數時顯示目前生效的參數。": "Tune scenario parameters of a running instance (e.g. packet_loss_rate=0.05 jitter_max=500ms); changes take effect immediately on slaves running the scenario without re-applying it. Lists are comma-separated (corrupt_modes=truncate,garbage) and maps use dotted keys (exception_weights.slave_device_busy=3). Without parameters, shows the parameters in effect.",
+	"場景 %s 的參數已還原為配置檔的設定 (%d 個 Slave)\n":                                 "Parameters of scenario %s restored to the config file settings (%d slaves)\n",
+	"場景 %s 的參數已調整 (%d 個 Slave)\n":                                        "Parameters of scenario %s tuned (%d slaves)\n",
+	"無效的參數 (需為 key=value): %s":                                           "invalid parameter (expected key=value): %s",
+	"調整的場景 (預設為目前的整體場景)":                                                 "Scenario to tune (defaults to the current fleet-wide scenario)",
+	"捨棄調整，還原為配置檔的參數":                                                     "Discard tuning and restore the config file parameters",
+	"場景 %s 的 packet_loss_rate 必須介於 0-1: %f":                              "scenario %s: packet_loss_rate must be between 0-1: %f",
+	"組合場景 (同時運行 components 列出的場景，預設 voltage_sag + jitter + packet_loss)": "Composite (runs the scenarios listed in components together, default voltage_sag + jitter + packet_loss)",
+	"場景 %s 必須指定 components":                                              "scenario %s: components is required",
+	"場景 %s 的 components 無效: %s (需為 normal、composite、chaos 以外的場景)":        "scenario %s: invalid component: %s (must be a scenario other than normal, composite and chaos)",
+	"場景 %s 的 components 重複: %s":                                          "scenario %s: duplicate component: %s",
+	"場景 %s 的 chaos_scenarios 無效: %s (需為 normal、chaos 以外的場景)":             "scenario %s: invalid chaos_scenarios entry: %s (must be a scenario other than normal and chaos)",
+	"場景 %s 的混沌模式間隔、持續時間與擾動上限不可為負":                                        "scenario %s: chaos interval, duration and max active must not be negative",
+	"場景 %s 的混沌模式下限不可大於上限":                                                "scenario %s: chaos minimum must not exceed maximum",
+	"場景 %s 的 chaos_fraction_min/max 必須介於 0-1":                            "scenario %s: chaos_fraction_min/max must be between 0 and 1",
+	"混沌模式開始":   "Chaos mode started",
+	"混沌模式結束":   "Chaos mode stopped",
+	"混沌模式注入擾動": "Chaos mode injected disturbance",
+	"混沌模式擾動結束": "Chaos mode disturbance ended",
+	"混沌模式 (每 30s-2m 隨機挑選 5-20% 的 Slave 短暫套用 sag/flap/例外風暴/jitter)": "Chaos mode (every 30s-2m briefly applies sag/flap/exception storm/jitter to a random 5-20% of slaves)",
+	"查看混沌模式狀態": "Show chaos mode status",
+	"查看運行中實例的混沌模式 (整體場景為 chaos 時隨機注入擾動) 的狀態、下次注入時間與進行中的擾動。": "Show the chaos mode status of a running instance (random disturbances injected while the fleet scenario is chaos), the next injection time and active disturbances.",
+	"混沌模式未運行 (累計注入 %d 次擾動)\n":        "Chaos mode is not running (%d disturbances injected in total)\n",
+	"混沌模式運行中: 下次注入 %s，累計注入 %d 次擾動\n": "Chaos mode running: next injection at %s, %d disturbances injected in total\n",
+	"  %-20s 至 %s: %s\n": "  %-20s until %s: %s\n",
+	"已調整模擬時鐘倍率":          "Simulation clock speed changed",
+	"查看或調整模擬時鐘":          "Show or adjust the simulation clock",
+	"查看運行中實例的模擬時間與倍率；--speed 調整倍率 (例如 60 = 實際 1 分鐘模擬 1 小時)，電能累積、日負載曲線與場景持續時間隨之加速。": "Show the simulated time and speed of a running instance; --speed changes the multiplier (e.g. 60 = 1 simulated hour per real minute), accelerating energy accumulation, daily load profiles and scenario durations.",
+	"模擬時鐘倍率不可為負: %v":               "simulation clock speed must not be negative: %v",
+	"模擬時鐘倍率必須大於 0: %v":             "simulation clock speed must be greater than 0: %v",
+	"模擬時鐘已加速":                      "Simulation clock accelerated",
+	"模擬時間: %s (倍率 %gx，啟動後經過 %s)\n": "Simulated time: %s (speed %gx, %s elapsed since start)\n",
+	"模擬時鐘倍率 (例如 60 = 實際 1 分鐘模擬 1 小時，預設使用配置的 clock.speed)": "simulation clock speed (e.g. 60 = 1 simulated hour per real minute; defaults to clock.speed from the config)",
+	"調整模擬時鐘倍率":                            "change the simulation clock speed",
+	"slave %s 未啟用設備時鐘":                    "slave %s has no device clock enabled",
+	"上次校時: %s\n":                          "Last synced: %s\n",
+	"不支援的設備時鐘格式: %s (可用: datetime, unix)": "unsupported device clock format: %s (available: datetime, unix)",
+	"必須指定 time 或 offset 其中之一":             "exactly one of time or offset must be specified",
+	"查看 Slave 設備時鐘 (slaves.device_clock) 的時間、與參考時間的偏差及走時誤差；--offset 或 --time 設定設備時間 (--offset 0s 即校時)。": "Show a slave's device clock (slaves.device_clock): its time, offset from the reference time and drift; --offset or --time sets the device time (--offset 0s synchronizes it).",
+	"查看或設定設備時鐘":      "Show or set a device clock",
+	"無效的 offset: %s": "invalid offset: %s",
+	"無效的 time: %s":   "invalid time: %s",
+	"無效的設備時鐘時區: %s":  "invalid device clock time zone: %s",
+	"設備時鐘已校時":        "Device clock synchronized",
+	"設備時鐘的 device_id_object 必須介於 0x80-0xFF: 0x%02X":                         "device clock device_id_object must be between 0x80 and 0xFF: 0x%02X",
+	"設備時鐘的 drift_ppm 必須大於 -1000000: %v":                                     "device clock drift_ppm must be greater than -1000000: %v",
+	"設備時鐘的 drift_spread 與 skew_spread 不可為負: drift_spread=%v skew_spread=%v": "device clock drift_spread and skew_spread must not be negative: drift_spread=%v skew_spread=%v",
+	"設備時鐘的時間無效":                                                             "Invalid device clock time",
+	"設備時間: %s (偏差 %s，走時誤差 %+.1f ppm)\n":                                     "Device time: %s (offset %s, drift %+.1f ppm)\n",
+	"將設備時間設為參考時間加上此偏差 (例如 -30s，0s 即校時)":                                     "set the device time to the reference time plus this offset (e.g. -30s; 0s synchronizes)",
+	"將設備時間設為此時間 (RFC 3339)":                                                 "set the device time to this time (RFC 3339)",
+	"諧波失真 (每 1m 發生 20s 的諧波事件，電壓 THD 升至 8%、以第 5 次諧波為主，閃爍 Pst 1.2)":           "harmonic distortion (a 20s harmonic event every 1m: voltage THD rises to 8%, dominated by the 5th harmonic, flicker Pst 1.2)",
+	"場景 %s 的 harmonic_thd、harmonic_interval 與 flicker_pst 不可為負":             "scenario %s: harmonic_thd, harmonic_interval and flicker_pst must not be negative",
+	"場景 %s 的諧波次數無效: %d (可用: 3, 5, 7)":                                       "scenario %s: invalid harmonic order: %d (available: 3, 5, 7)",
+	"需量暫存器定義失敗，停用需量計算":                                                      "failed to define demand registers, demand calculation disabled",
+	"需量的 window、interval 與 subintervals 不可為負":                               "demand window, interval and subintervals must not be negative",
+	"需量的 window 與 interval 不可超過 24h":                                        "demand window and interval must not exceed 24h",
+	"需量的子區間不可短於 1s: window=%v subintervals=%d":                              "demand subinterval must not be shorter than 1s: window=%v subintervals=%d",
+	"不支援的最大需量重設週期: %s (可用: monthly, daily, never)":                          "unsupported peak demand reset period: %s (available: monthly, daily, never)",
+	"無效的需量時區: %s":                                                           "invalid demand time zone: %s",
+	"暫存器 %s (%d) 與 %s 位址重疊":                                                 "register %s (%d) overlaps %s",
+	"無效的費率時段開始時間: %s (格式為 HH:MM)":                                           "invalid tariff period start time: %s (format HH:MM)",
+	"費率時段 %s 的費率必須介於 1-4: %d":                                               "tariff period %s: tariff must be between 1-4: %d",
+	"費率時段 %s 的星期無效: %s (可用: mon, tue, wed, thu, fri, sat, sun)":             "tariff period %s: invalid day: %s (available: mon, tue, wed, thu, fri, sat, sun)",
+	"多費率電能暫存器定義失敗，停用多費率電能":                                                  "failed to define tariff energy registers, multi-tariff energy disabled",
+	"無效的費率時區: %s":                                                           "invalid tariff time zone: %s",
+	"無效的 coil: %d":                                                          "invalid coil: %d",
+	"%s (%d-%d) 與 %s (%d-%d) 的暫存器位址重疊":                                      "registers of %s (%d-%d) and %s (%d-%d) overlap",
+	"配對 %s 的 %s 不在 network.ip_ranges 內":                                     "pair %s member %s is not within network.ip_ranges",
+	"配對 %s 的 %s 不是本實例的 Slave (須位於 IP 範圍內、存在於本機且在 slaves.count 之內)":          "pair %s member %s is not a slave of this instance (it must be within the IP ranges, present on this host and within slaves.count)",
+	"不支援的持久化儲存: %s (可用: file, log)":                                         "unsupported persistence driver: %s (available: file, log)",
+	"開啟狀態檔失敗: %w":                                                           "failed to open state file: %w",
+	"讀取狀態檔失敗: %w":                                                           "failed to read state file: %w",
+	"截斷狀態檔失敗: %w":                                                           "failed to truncate state file: %w",
+	"狀態檔已關閉":                                                                "state file is closed",
+	"重寫狀態檔失敗: %w":                                                           "failed to rewrite state file: %w",
+	"顯示版本資訊":                                                                "Show version information",
+	"配置檔路徑":                                                                 "config file path",
+	"運行中實例的管理 API 位址":                                                       "admin API address of the running instance",
+	"起始 IP 位址":                                                              "start IP address",
+	"Slave 數量":                                                              "number of slaves",
+	"監聽埠號":                                                                  "listen port",
+	"設備設定檔 (single_phase, three_phase, battery)":                            "device profile (single_phase, three_phase, battery)",
+	"PID 檔案路徑":                                                              "PID file path",
+	"網路介面":                                                                  "network interface",
+	"起始 IP":                                                                 "start IP",
+	"結束 IP":                                                                 "end IP",
+	"CIDR 表示法":                                                              "CIDR notation",
+	"macvlan 的上層介面 (預設為 --interface)":                                       "macvlan parent interface (default --interface)",
+	"專用介面的 MTU":                                                             "MTU of the dedicated interface",
+	"虛擬 IP 配置方式 (alias, dummy, macvlan)":                                    "virtual IP mode (alias, dummy, macvlan)",
+	"dummy/macvlan 專用介面名稱 (預設 modbussim0)":                                  "dummy/macvlan dedicated interface name (default modbussim0)",
+	"場景持續時間":                                                                "scenario duration",
+	"閃爍持續時間":                                                                "blink duration",
+	"閃爍的保持暫存器位址":                                                            "holding register address to blink",
+	"週期切換的線圈位址 (-1 不切換)":                                                    "coil address to toggle (-1 to disable)",
+	"停止閃爍並還原":                                                               "stop blinking and restore",
+	"預期的雜湊值 (僅列出不符者)":                                                       "expected checksum (list mismatches only)",
+	"輸出檔案路徑":                                                                "output file path",
+
+	// 配置
+	"讀取配置檔失敗: %w":                         "failed to read config file: %w",
+	"解析配置失敗: %w":                          "failed to parse config: %w",
+	"無效的埠號: %d":                           "invalid port: %d",
+	"無效的 ADU 上限: %d (範圍 %d-%d)":           "invalid max ADU size: %d (range %d-%d)",
+	"Slave 數量必須大於 0":                      "slave count must be greater than 0",
+	"Slave 數量超過上限 (最大 10000)":             "slave count exceeds limit (max 10000)",
+	"場景 %s 的 exception_rate 必須介於 0-1: %f": "scenario %s: exception_rate must be between 0-1: %f",
+	"場景 %s 的例外名稱無效: %s":                   "scenario %s: invalid exception name: %s",
+	"場景 %s 的例外權重不可為負: %s":                 "scenario %s: exception weight must not be negative: %s",
+	"場景 %s 的 corrupt_rate 必須介於 0-1: %f":   "scenario %s: corrupt_rate must be between 0-1: %f",
+	"場景 %s 的損壞模式無效: %s":                   "scenario %s: invalid corrupt mode: %s",
+	"場景 %s 的負載曲線點無效: hour=%v factor=%v":   "scenario %s: invalid load curve point: hour=%v factor=%v",
+	"場景 %s 的 time_scale 不可為負: %v":         "scenario %s: time_scale must not be negative: %v",
+	"場景 %s 的凍結暫存器不存在於設定檔 %s: %s":          "scenario %s: frozen register not found in profile %s: %s",
+	"場景 %s 的目標無效: %s":                     "scenario %s: invalid target: %s",
+	"備援配對驗證失敗: %w":                        "redundancy validation failed: %w",
+	"標籤 %s 的目標無效: %s":                     "tag %s: invalid target: %s",
+	"故障注入保護驗證失敗: %w":                      "fault injection protection validation failed: %w",
+	"追蹤取樣率必須介於 0-1: %v":                   "tracing sample_rate must be between 0-1: %v",
+	"無效的追蹤端點: %s":                         "invalid tracing endpoint: %s",
+	"故障域名稱重複: %s":                         "duplicate failure domain name: %s",
+	"故障域驗證失敗: %w":                         "failure domain validation failed: %w",
+	"故障域必須指定名稱":                           "failure domain name is required",
+	"故障域 %s 必須指定 targets 或 tags":          "failure domain %s: targets or tags must be specified",
+	"故障域 %s 的目標無效: %s":                    "failure domain %s: invalid target: %s",
+	"故障域 %s 使用未定義的 Slave 標籤: %s":          "failure domain %s: undefined slave tag: %s",
+	"故障域 %s 的停擺時間不可為負":                    "failure domain %s: outage times must not be negative",
+	"IP 範圍驗證失敗: %w":                       "IP range validation failed: %w",
+	"無效的 CIDR: %s":                        "invalid CIDR: %s",
+	"必須指定 Start 和 End 或 CIDR":             "either Start and End or CIDR must be specified",
+	"無效的起始 IP: %s":                        "invalid start IP: %s",
+	"無效的結束 IP: %s":                        "invalid end IP: %s",
+	"無效的備援模式: %s":                         "invalid standby mode: %s",
+	"配對必須指定名稱":                            "pair name is required",
+	"配對名稱重複: %s":                          "duplicate pair name: %s",
+	"配對 %s 的 IP 無效: %s / %s":              "pair %s: invalid IP: %s / %s",
+	"配對 %s 的主備 IP 相同":                     "pair %s: primary and standby IPs are identical",
+	"未定義的 Slave 標籤: %s":                   "undefined slave tag: %s",
+	"保護時段 %s 的開始時間無效: %s":                 "protected window %s: invalid start time: %s",
+	"保護時段 %s 的結束時間無效: %s":                 "protected window %s: invalid end time: %s",
+	"保護時段 %s 使用未定義的 Slave 標籤: %s":         "protected window %s: undefined slave tag: %s",
+	"序列化配置失敗: %w":                         "failed to encode config: %w",
+	"寫入配置檔失敗: %w":                         "failed to write config file: %w",
+	"無效的 IP 範圍: %s - %s":                  "invalid IP range: %s - %s",
+
+	// 功能碼處理與 Modbus 例外
+	"讀取線圈失敗":       "failed to read coils",
+	"讀取離散輸入失敗":     "failed to read discrete inputs",
+	"讀取保持暫存器失敗":    "failed to read holding registers",
+	"讀取輸入暫存器失敗":    "failed to read input registers",
+	"寫入多個線圈失敗":     "failed to write multiple coils",
+	"寫入多個暫存器失敗":    "failed to write multiple registers",
+	"寫入唯讀暫存器":      "write to read-only register",
+	"模擬封包丟失，不回應請求": "simulated packet loss, request not answered",
+	"非法功能碼":        "illegal function",
+	"非法資料位址":       "illegal data address",
+	"非法資料值":        "illegal data value",
+	"從站設備故障":       "slave device failure",
+	"確認":           "acknowledge",
+	"從站設備忙碌":       "slave device busy",
+	"閘道目標設備未回應":    "gateway target device failed to respond",
+	"未知錯誤":         "unknown error",
+
+	// 程式進入點
+	"錯誤: %v\n": "error: %v\n",
+
+	// 指標
+	"啟動指標伺服器": "starting metrics server",
+	"指標伺服器錯誤": "metrics server error",
+
+	// 虛擬 IP
+	"找不到網路介面 %s: %w":                             "network interface %s not found: %w",
+	"網路介面 %s 已存在且類型為 %s":                         "network interface %s already exists with type %s",
+	"建立網路介面 %s 失敗: %w":                           "failed to create network interface %s: %w",
+	"設定網路介面 %s 的 MTU 失敗: %w":                     "failed to set MTU of network interface %s: %w",
+	"啟用網路介面 %s 失敗: %w":                           "failed to bring up network interface %s: %w",
+	"刪除網路介面 %s 失敗: %w":                           "failed to delete network interface %s: %w",
+	"已建立專用網路介面":                                  "created dedicated network interface",
+	"已刪除專用網路介面":                                  "deleted dedicated network interface",
+	"專用網路介面不存在":                                  "dedicated network interface does not exist",
+	"不支援的網路配置方式: %s (可用: alias, dummy, macvlan)": "unsupported network mode: %s (available: alias, dummy, macvlan)",
+	"網路介面名稱過長 (最多 15 字元): %s":                    "network interface name too long (max 15 characters): %s",
+	"MTU 必須介於 68-65535: %d":                      "MTU must be between 68-65535: %d",
+	"macvlan 模式必須指定 parent 或 interface":          "macvlan mode requires parent or interface",
+	"展開 IP 範圍失敗: %w":                             "failed to expand IP range: %w",
+	"正在設置虛擬 IP":                                  "setting up virtual IPs",
+	"IP 已存在":                                     "IP already exists",
+	"IP 已不存在":                                    "IP no longer exists",
+	"dummy/macvlan 模式僅在 Linux 上支援":               "dummy/macvlan mode is only supported on Linux",
+	"讀取網路狀態檔失敗: %w":                              "failed to read network state file: %w",
+	"解析網路狀態檔失敗: %w":                              "failed to parse network state file: %w",
+	"寫入網路狀態檔失敗: %w":                              "failed to write network state file: %w",
+	"更新網路狀態檔失敗":                                  "failed to update network state file",
+	"狀態檔中有已不存在的 IP (執行 network teardown 清除)":     "state file lists IPs that no longer exist (run network teardown to clean up)",
+	"添加 IP 失敗":                                   "failed to add IP",
+	"已添加 IP":                                     "IP added",
+	"已發送 ARP/NDP 通告":                             "sent ARP/NDP announcements",
+	"發送 gratuitous ARP 失敗":                       "failed to send gratuitous ARP",
+	"發送 unsolicited NA 失敗":                       "failed to send unsolicited NA",
+	"正在移除虛擬 IP":                                  "removing virtual IPs",
+	"移除 IP 失敗":                                   "failed to remove IP",
+	"已移除 IP":                                     "IP removed",
+	"虛擬 IP 移除完成":                                 "virtual IP removal complete",
+
+	// 虛擬 IP (非 Linux)
+	"此平台不支援虛擬 IP 配置，使用模擬模式": "virtual IP configuration is not supported on this platform, running in simulation mode",
+	"此平台不支援虛擬 IP 移除，使用模擬模式": "virtual IP removal is not supported on this platform, running in simulation mode",
+	"取得本地 IP 失敗: %w":        "failed to get local IPs: %w",
+
+	// 設備設定檔
+	"設備設定檔 %s: %w":                            "device profile %s: %w",
+	"暫存器 %s (%d): %w":                         "register %s (%d): %w",
+	"暫存器 %s (%d): scale 不可為 0":                "register %s (%d): scale must not be 0",
+	"暫存器 %s (%d): 累計量 (%s) 不可設為可寫入":           "register %s (%d): accumulator (%s) must not be writable",
+	"暫存器名稱重複: %s (%d 與 %d)":                   "duplicate register name: %s (%d and %d)",
+	"暫存器 %s (%d, %s 佔用 %d-%d) 與 %s (%d) 位址重疊": "register %s (%d, %s spans %d-%d) overlaps %s (%d)",
+
+	// 故障注入保護
+	"已抑制故障注入":   "fault injection suppressed",
+	"保護結束，恢復場景": "protection ended, scenario resumed",
+
+	// 協定
+	"未知的資料類型: %s": "unknown data type: %s",
+
+	// 主備配對
+	"設定主備配對失敗":       "failed to set up redundant pair",
+	"主備配對已建立":        "redundant pair established",
+	"找不到主備配對: %s":    "redundant pair not found: %s",
+	"切換配對 %s 失敗: %w": "failed to fail over pair %s: %w",
+	"已抑制自動主備切換":      "automatic failover suppressed",
+	"自動主備切換失敗":       "automatic failover failed",
+
+	// 暫存器
+	"線圈位址超出範圍: %d":       "coil address out of range: %d",
+	"線圈位址超出範圍: %d-%d":    "coil address out of range: %d-%d",
+	"離散輸入位址超出範圍: %d":     "discrete input address out of range: %d",
+	"離散輸入位址超出範圍: %d-%d":  "discrete input address out of range: %d-%d",
+	"輸入暫存器位址超出範圍: %d":    "input register address out of range: %d",
+	"輸入暫存器位址超出範圍: %d-%d": "input register address out of range: %d-%d",
+	"保持暫存器位址超出範圍: %d":    "holding register address out of range: %d",
+	"保持暫存器位址超出範圍: %d-%d": "holding register address out of range: %d-%d",
+	"無效位址: %d":           "invalid address: %d",
+
+	// 引擎
+	"引擎已經在運行中":                    "engine is already running",
+	"正在啟動引擎":                      "starting engine",
+	"取得綁定 IP 失敗: %w":              "failed to get bind IPs: %w",
+	"建立 Slave %s 暫存器失敗: %w":       "failed to create registers for slave %s: %w",
+	"啟動 Slave %s 失敗: %w":          "failed to start slave %s: %w",
+	"部分 Slaves 啟動失敗":              "some slaves failed to start",
+	"所有 Slaves 啟動失敗: %v":          "all slaves failed to start: %v",
+	"引擎啟動完成":                      "engine started",
+	"正在停止引擎":                      "stopping engine",
+	"停止 Slave 失敗":                 "failed to stop slave",
+	"停止引擎超時":                      "engine stop timed out",
+	"引擎已停止":                       "engine stopped",
+	"配置的 IP 範圍不存在於本機，回退為 0.0.0.0": "configured IP ranges are not present on this host, falling back to 0.0.0.0",
+
+	// Slave
+	"slave %s 已經在運行中":  "slave %s is already running",
+	"監聽 %s 失敗: %w":     "failed to listen on %s: %w",
+	"Slave 已啟動":        "slave started",
+	"Slave 已停止":        "slave stopped",
+	"Slave 已離線 (模擬斷線)": "slave offline (simulated disconnect)",
+	"Slave 已恢復上線":      "slave back online",
+	"重新監聽 %s 失敗: %w":   "failed to re-listen on %s: %w",
+	"恢復上線失敗":           "failed to come back online",
+
+	// TCP 接入層
+	"接受連線失敗":          "failed to accept connection",
+	"讀取請求失敗":          "failed to read request",
+	"無效的協定識別碼: %d":    "invalid protocol identifier: %d",
+	"無效的 MBAP 長度: %d": "invalid MBAP length: %d",
+
+	// 語系
+	"訊息語系 (zh-TW, en, auto)":       "message language (zh-TW, en, auto)",
+	"亂數種子 (0 = 依時間產生；指定相同種子可重現模擬)": "random seed (0 = derive from time; the same seed reproduces a run)",
+	"不支援的語系: %s":                   "unsupported language: %s",
+
+	// 追蹤
+	"已啟用追蹤":           "tracing enabled",
+	"匯出 span 失敗":      "failed to export spans",
+	"追蹤佇列已滿，已丟棄 span": "tracing queue full, spans dropped",
+
+	// 故障域
+	"故障域命令": "Failure domain commands",
+	"查看運行中實例的故障域，並觸發或結束域內 Slave 同時斷線。": "Inspect failure domains of the running instance and start or end domain-wide outages.",
+	"列出故障域":   "List failure domains",
+	"目前沒有故障域": "no failure domains",
+	"故障域停擺":   "Failure domain outage",
+	"讓指定故障域內的所有 Slave 同時離線，經過指定時間後一起恢復；--restore 提前恢復。": "Take all slaves in the given failure domain offline at once and bring them back together after the given time; --restore ends it early.",
+	"故障域 %s 已恢復\n":                     "failure domain %s restored\n",
+	"故障域 %s 停擺中: %d 個 Slave 離線，至 %s\n": "failure domain %s down: %d slaves offline until %s\n",
+	"停擺時間 (預設使用配置的 outage_duration)":   "outage duration (defaults to the configured outage_duration)",
+	"提前恢復":              "restore early",
+	"找不到故障域: %s":        "failure domain not found: %s",
+	"故障域停擺略過受保護的 Slave": "failure domain outage skipped protected slaves",
+	"故障域已恢復":            "failure domain restored",
+	"自動故障域停擺失敗":         "automatic failure domain outage failed",
+
+	// 運算式
+	"運算式除以零":                    "division by zero in expression",
+	"運算式左側必須是暫存器位址: %s":         "expression left-hand side must be a register address: %s",
+	"運算式語法錯誤: %q (位置 %d)":       "expression syntax error: %q (position %d)",
+	"運算式循環參照: %d":               "circular expression reference: %d",
+	"暫存器 %s (%d): 衍生暫存器不可設為可寫入": "register %s (%d): derived register cannot be writable",
+	"運算式左側位址 %d 與暫存器位址 %d 不符":   "expression target address %d does not match register address %d",
+	"運算式參照未定義的暫存器: %s":          "expression references undefined register: %s",
+	"暫存器 %d 未定義":                "register %d is not defined",
+	"暫存器 %d 運算式 %q: %w":         "register %d expression %q: %w",
+
+	// 綁定衝突
+	"綁定重試設定不可為負: interval=%v max=%d": "bind retry settings must not be negative: interval=%v max=%d",
+	"位址 %s 已被占用 (%s)":                "address %s already in use (%s)",
+	"位址 %s 已被占用":                     "address %s already in use",
+	"監聽位址衝突":                         "listener bind conflict",
+	"監聽位址衝突已解除":                      "listener bind conflict cleared",
+	"放棄重試綁定":                         "giving up bind retries",
+
+	// 腳本場景
+	"Lua 腳本 (依 script 指定的腳本更新暫存器)": "Lua script (registers updated by the script given in script)",
+	"場景 %s 的腳本無效: %w":              "scenario %s has an invalid script: %w",
+	"腳本執行失敗":                       "script execution failed",
+	"載入腳本失敗":                       "failed to load script",
+	"未定義的暫存器: %s":                  "undefined register: %s",
+	"需要暫存器位址或名稱":                   "register address or name expected",
+
+	// 外掛場景
+	"外部外掛 (以 gRPC 呼叫 plugin 指定的外掛程序)": "external plugin (gRPC calls to the plugin process given in plugin)",
+	"場景 %s 的外掛位址無效: %w":               "scenario %s has an invalid plugin address: %w",
+	"連線場景外掛失敗":                        "failed to connect to scenario plugin",
+	"呼叫場景外掛失敗":                        "scenario plugin call failed",
+	"場景外掛已恢復":                         "scenario plugin recovered",
+	"已連線到場景外掛":                        "connected to scenario plugin",
+	"不支援的外掛訊息類型: %T":                  "unsupported plugin message type: %T",
+	"缺少 socket 路徑: %s":                "missing socket path: %s",
+	"無效的連接埠: %s":                      "invalid port: %s",
+
+	// 配置漂移
+	"漂移檢查間隔不可為負: %v":                  "drift check interval must not be negative: %v",
+	"偵測到配置漂移":                         "configuration drift detected",
+	"配置漂移已消除":                         "configuration drift cleared",
+	"已重新設定配置基準":                       "configuration baseline reset",
+	"共 %d 個 Slave (設定檔 %s)：%d 個有漂移\n": "%d slaves (profile %s): %d drifted\n",
+	"%d 個 Slave 的配置偏離基準":              "%d slaves drifted from their baseline",
+	"已重新設定 %d 個 Slave 的基準\n":          "baseline reset for %d slaves\n",
+	"配置漂移命令":                          "Configuration drift commands",
+	"檢查運行中實例各 Slave 的暫存器元資料與可寫入值是否偏離設定檔，並可重新設定基準。": "Check whether each slave's register metadata and writable values in a running instance have drifted from the profile, and reset the baseline.",
+	"檢查配置漂移": "Check configuration drift",
+	"立即比對所有 Slave 與基準，列出差異；有漂移時以非零狀態結束。": "Compare all slaves with their baseline now and list differences; exits non-zero when drift is found.",
+	"重新設定基準": "Reset baseline",
+	"以 Slave 目前的暫存器元資料與可寫入值作為新的比對基準；未指定 Slave 時套用至全部。": "Use the slave's current register metadata and writable values as the new baseline; applies to all slaves when none is given.",
+
+	// 輪詢分析
+	"輪詢分析設定不可為負: min_polls=%d hot_spot_ratio=%v max_blocks=%d": "polling analysis settings must not be negative: min_polls=%d hot_spot_ratio=%v max_blocks=%d",
+	"輪詢分析未啟用 (polling.enabled)":                                "polling analysis is not enabled (polling.enabled)",
+	"輪詢效率報告": "Polling efficiency report",
+	"列出各 Master 的輪詢效率與讀取頻率遠高於數值變化頻率的熱點 (需啟用 polling.enabled)；--reset 清除觀察結果。": "List each master's polling efficiency and hot spots polled far more often than they change (requires polling.enabled); --reset clears the observations.",
+	"已清除輪詢觀察結果":                               "polling observations cleared",
+	"尚未觀察到讀取請求":                               "no read requests observed yet",
+	"Master %s: %d 次輪詢，%d 次未變化 (效率 %.1f%%)\n": "master %s: %d polls, %d unchanged (efficiency %.1f%%)\n",
+	"僅列出指定 Master (IP)":                       "only list the given master (IP)",
+	"清除觀察結果並重新起算":                             "clear observations and start over",
+
+	// 擷取重播
+	"不支援的擷取檔格式 (僅支援 pcap 與 pcapng)": "unsupported capture format (only pcap and pcapng are supported)",
+	"擷取檔已截斷: %w":                    "capture file is truncated: %w",
+	"封包長度無效: %d":                    "invalid packet length: %d",
+	"區塊長度無效: %d":                    "invalid block length: %d",
+	"解析時間軸失敗: %w":                   "failed to parse timeline: %w",
+	"載入擷取檔失敗":                       "failed to load capture",
+	"場景 %s 的擷取檔無效: %w":              "scenario %s has an invalid capture: %w",
+	"擷取重播 (依 capture 指定的 pcap 擷取檔重現暫存器變化)": "capture replay (reproduce register changes from the pcap capture given by capture)",
+	"解析 Modbus 擷取檔": "Parse a Modbus capture",
+	"由 pcap/pcapng 擷取檔的回應與寫入請求重建暫存器狀態時間軸並列出摘要；--output 將時間軸存成 JSON，可作為 replay 場景的回歸測試資料。": "Reconstruct the register state timeline from the responses and write requests in a pcap/pcapng capture and print a summary; --output saves the timeline as JSON for use as a replay scenario regression fixture.",
+	"解析擷取檔失敗: %w":                        "failed to parse capture: %w",
+	"開始時間: %s，時長 %s\n":                   "start: %s, duration %s\n",
+	"封包 %d，請求 %d，回應 %d (例外 %d，未配對 %d)\n": "%d packets, %d requests, %d responses (%d exceptions, %d unmatched)\n",
+	"儲存時間軸失敗: %w":                        "failed to save timeline: %w",
+	"時間軸已儲存: %s\n":                       "timeline saved: %s\n",
+	"擷取檔中 Modbus 伺服器的埠號":                 "Modbus server port in the capture",
+	"將時間軸存成 JSON 檔":                      "save the timeline as a JSON file",
+
+	// 負載測試
+	"不支援的功能碼: %s":          "unsupported function code: %s",
+	"功能碼權重無效: %s":          "invalid function code weight: %s",
+	"功能碼權重總和必須大於 0":        "function code weights must sum to more than 0",
+	"未指定目標":                "no target specified",
+	"目標位址無效: %s":           "invalid target address: %s",
+	"請求速率不可為負: %v":         "request rate must not be negative: %v",
+	"每次讀寫數量必須介於 1-123: %d": "quantity per request must be between 1 and 123: %d",
+	"Modbus 負載測試":          "Modbus load test",
+	"以 Modbus TCP Master 身分對目標 (或模擬器本身) 發送請求，可設定並發連線數、功能碼比例與請求速率，並回報延遲百分位數。": "Act as a Modbus TCP master sending requests to a target (or the simulator itself) with configurable concurrency, function code mix and request rate, and report latency percentiles.",
+	"目標 %s，%d 條連線，耗時 %s\n":         "target %s, %d connections, took %s\n",
+	"請求 %d，錯誤 %d，吞吐量 %.1f req/s\n": "%d requests, %d errors, throughput %.1f req/s\n",
+	"目標 host:port (可重複指定，連線依序分配)":  "target host:port (repeatable; connections are assigned in turn)",
+	"並發連線數":             "number of concurrent connections",
+	"合計每秒請求數 (0 = 不限速)": "total requests per second (0 = unlimited)",
+	"測試時間":              "test duration",
+	"請求總數 (達到即結束，0 = 依測試時間)": "total number of requests (stop when reached; 0 = use duration)",
+	"單次請求逾時":                     "per-request timeout",
+	"起始位址 (0 起算)":                "start address (0-based)",
+	"每次讀寫的數量":                    "quantity per request",
+	"功能碼權重 (例如 3=70,4=20,16=10)": "function code weights (e.g. 3=70,4=20,16=10)",
+	"以 JSON 輸出結果":                "print the result as JSON",
+
+	// 位元表批次操作
+	"位元表為空": "bitmap is empty",
+	"位元表長度不足: %d 位元組無法容納 %d 個位元":  "bitmap too short: %d bytes cannot hold %d bits",
+	"位元表編碼無效: %s (可用 hex、base64)": "invalid bitmap encoding: %s (use hex or base64)",
+	"位址無效: %s":    "invalid address: %s",
+	"數量無效: %s":    "invalid count: %s",
+	"解析位元表失敗: %w": "failed to decode bitmap: %w",
+	"位元表批次讀寫":     "Bulk bitmap read/write",
+	"以 hex 或 base64 位元表 (LSB 先) 批次讀取或設定線圈；--discrete 改為離散輸入，--set 指定要寫入的位元表。": "Read or set coils in bulk as a hex or base64 bitmap (LSB first); --discrete targets discrete inputs, --set gives the bitmap to write.",
+	"已寫入 %d 個位元 (起始位址 %d)\n":             "wrote %d bits (start address %d)\n",
+	"讀寫離散輸入 (預設為線圈)":                     "read/write discrete inputs (default: coils)",
+	"位元數 (讀取時 0 = 到結尾，寫入時 0 = 位元表的全部位元)": "number of bits (read: 0 = to the end; write: 0 = every bit in the bitmap)",
+	"位元表編碼 (hex, base64)":                "bitmap encoding (hex, base64)",
+	"要寫入的位元表":                            "bitmap to write",
+
+	// 每個 Slave 的指標
+	"指標 Slave 數上限不可為負: register_values=%d per_slave=%d": "metrics slave cap must not be negative: register_values=%d per_slave=%d",
+
+	// 長時間命令場景
+	"長時間命令 (寫入命令暫存器回應 Acknowledge，狀態暫存器 10s 後由執行中轉為完成)": "Long-running command (writing the command register answers Acknowledge; the status register goes from in-progress to complete after 10s)",
+
+	// 量測值更新週期
+	"量測值更新週期不可為負: %v":                                        "measurement refresh interval must not be negative: %v",
+	"場景更新 worker 數不可為負: %d":                                  "scenario update workers must not be negative: %d",
+	"場景更新批次大小不可為負: %d":                                       "scenario update batch size must not be negative: %d",
+	"randomize 的參數不可為負":                                      "randomize parameters must not be negative",
+	"邏輯設備的 Unit ID 必須介於 1-247: %d":                           "logical device unit ID must be between 1-247: %d",
+	"邏輯設備的 Unit ID 重複: %d":                                   "duplicate logical device unit ID: %d",
+	"未配置的 Unit ID，不回應請求":                                     "unconfigured unit ID, not responding",
+	"未配置的 Unit ID，下游逾時":                                      "unconfigured unit ID, downstream timed out",
+	"不支援的未配置 Unit ID 行為: %s (可用: silent, gateway_exception)": "unsupported unknown unit ID behavior: %s (available: silent, gateway_exception)",
+	"下游逾時不可為負: %v":                                           "downstream timeout must not be negative: %v",
+	"不支援的暫存器配置方式: %s (可用: shared, independent)":              "unsupported register sharing mode: %s (available: shared, independent)",
+
+	// 開機風暴
+	"開機風暴的斷電時間與錯開時間不可為負: outage=%v stagger=%v": "boot storm outage and stagger must not be negative: outage=%v stagger=%v",
+	"開機風暴的目標無效: %s":                            "invalid boot storm target: %s",
+	"引擎未運行":                                    "engine is not running",
+	"開機風暴進行中":                                  "a boot storm is already in progress",
+	"沒有進行中的開機風暴":                               "no boot storm has been started",
+	"開機風暴略過受保護的 Slave":                         "boot storm skipped protected slaves",
+	"開機風暴：全場斷電":                                "boot storm: site power lost",
+	"開機風暴：電力恢復，Slave 開始上線":                     "boot storm: power restored, slaves coming online",
+	"開機風暴完成":                                   "boot storm complete",
+	"無效的 outage: %s":                           "invalid outage: %s",
+	"無效的 stagger: %s":                          "invalid stagger: %s",
+	"開機風暴":                                     "Boot storm",
+	"模擬全場斷電後復電：Slave 同時離線，經過 --outage 後在 --stagger 時間窗內幾乎同時上線，用於測試 EMS 的重新連線湧入；--status 查看進度，--restore 立即復電。": "Simulate a site power loss and restoration: slaves go offline together, then after --outage come back online nearly simultaneously within the --stagger window, to test the EMS reconnection surge; --status shows progress, --restore restores power immediately.",
+	"開機風暴 %s: %d/%d 個 Slave 已上線 (斷電 %s，復電 %s，錯開 %s)\n": "boot storm %s: %d/%d slaves online (power off %s, restore %s, stagger %s)\n",
+	"略過 %d 個受保護的 Slave\n":                              "skipped %d protected slaves\n",
+	"斷電時間 (預設使用配置的 boot_storm.outage)":                 "power outage duration (default: boot_storm.outage from config)",
+	"復電後 Slave 上線的錯開時間窗 (0 = 同時上線，預設使用配置值)":            "window over which slaves come back online after power returns (0 = all at once; default from config)",
+	"僅影響指定 IP/CIDR (預設全部)":                             "only affect the given IPs/CIDRs (default: all)",
+	"查看進度":                                             "show progress",
+	"立即復電":                                             "restore power immediately",
+
+	// Slave 除役
+	"除役規則名稱重複: %s":                    "duplicate decommission rule name: %s",
+	"除役規則驗證失敗: %w":                    "decommission rule validation failed: %w",
+	"除役規則必須指定名稱":                      "decommission rule must have a name",
+	"除役規則 %s 必須指定 lifetime 或 at 其中之一": "decommission rule %s must set exactly one of lifetime or at",
+	"除役規則 %s 的時間不可為負":                 "decommission rule %s durations must not be negative",
+	"除役規則 %s 的 at 必須為 RFC3339 時間: %s": "decommission rule %s: at must be an RFC3339 time: %s",
+	"除役規則 %s 的目標無效: %s":               "decommission rule %s has an invalid target: %s",
+	"除役規則 %s 使用未定義的 Slave 標籤: %s":     "decommission rule %s uses an undefined slave tag: %s",
+	"移除 IP %s 失敗: %w":                 "failed to remove IP %s: %w",
+	"除役時移除虛擬 IP 失敗":                   "failed to remove virtual IP during decommission",
+	"Slave 已除役":                       "slave decommissioned",
+	"除役失敗":                            "decommission failed",
+	"Slave 除役":                        "Decommission a slave",
+	"立即永久移除指定的 Slave (關閉 listener，視配置移除虛擬 IP)；未指定 Slave 時列出排定的除役與已除役的 Slave。": "Permanently remove the given slave now (closes its listener and, if configured, removes its virtual IP); without a slave, lists scheduled and completed decommissions.",
+	"Slave %s 已除役 (運行 %s，%d 個請求)\n": "slave %s decommissioned (uptime %s, %d requests)\n",
+	"%-22s 排定於 %s 除役 (規則 %s)\n":     "%-22s scheduled for decommission at %s (rule %s)\n",
+	"%-22s 已於 %s 除役 (規則 %s)\n":      "%-22s decommissioned at %s (rule %s)\n",
+	"排定 %d 個，已除役 %d 個\n":            "%d scheduled, %d decommissioned\n",
+
+	// 請求稽核日誌
+	"稽核日誌的大小與保留數不可為負: max_size_mb=%d max_backups=%d": "audit log size and backups must not be negative: max_size_mb=%d max_backups=%d",
+	"啟用稽核日誌時必須指定 path":                               "audit.path is required when the audit log is enabled",
+	"已啟用稽核日誌":                                        "audit log enabled",
+	"開啟稽核日誌 %s 失敗: %w":                               "failed to open audit log %s: %w",
+	"稽核日誌輪替失敗":                                       "audit log rotation failed",
+	"寫入稽核日誌失敗":                                       "failed to write audit log",
+	"稽核日誌有請求未能寫入":                                    "some requests could not be written to the audit log",
+	"關閉稽核日誌失敗":                                       "failed to close audit log",
+
+	// 連線數上限
+	"連線數上限不可為負: %d": "max_connections must not be negative: %d",
+	"連線數已達上限，拒絕連線":  "connection limit reached, rejecting connection",
+
+	// 權限降級
+	"降級權限需以 root 啟動 (目前 euid=%d)":       "dropping privileges requires starting as root (current euid=%d)",
+	"降級權限失敗: %w":                        "failed to drop privileges: %w",
+	"已降級權限":                             "privileges dropped",
+	"找不到使用者: %s":                        "user not found: %s",
+	"降級的目標使用者不可為 root":                  "the user to drop privileges to must not be root",
+	"找不到群組: %s":                         "group not found: %s",
+	"保留 capability 需以 CGO_ENABLED=0 建置": "retaining capabilities requires a CGO_ENABLED=0 build",
+	"權限降級僅在 Linux 上支援":                  "dropping privileges is only supported on Linux",
+	"指定降級群組時必須同時指定使用者":                  "privilege.group requires privilege.user",
+	"不支援的 capability: %s (可用: %s)":      "unsupported capability: %s (available: %s)",
+	"設置虛擬 IP 失敗: %w":                    "failed to set up virtual IPs: %w",
+	"綁定埠號後降級為此使用者 (需以 root 啟動，僅 Linux)": "drop to this user after binding ports (requires starting as root, Linux only)",
+	"降級的群組 (預設為使用者的主要群組)":               "group to drop to (defaults to the user's primary group)",
+	"啟動前依配置建立虛擬 IP":                     "create the configured virtual IPs before starting",
+
+	// 多網路介面
+	"IP 不在指定的網路介面上，略過": "IP is not on the configured interface, skipping",
+	"綁定網路介面 %s 失敗: %w": "failed to bind to interface %s: %w",
+
+	// 連線逾時
+	"連線逾時不可為負: read_timeout=%s write_timeout=%s idle_timeout=%s": "connection timeouts must not be negative: read_timeout=%s write_timeout=%s idle_timeout=%s",
+	"連線閒置逾時，關閉連線":                                                "connection idle timeout, closing connection",
+	"讀取請求逾時，關閉連線":                                                "request read timeout, closing connection",
+	"寫出回應逾時，關閉連線":                                                "response write timeout, closing connection",
+
+	// 共用 listener
+	"SO_ORIGINAL_DST 僅在 Linux 上支援":                 "SO_ORIGINAL_DST is only supported on Linux",
+	"original_dst 僅適用於 shared listener":            "original_dst requires the shared listener",
+	"shared listener 不套用 IP 範圍的網路介面綁定":             "the shared listener does not apply per-range interface binding",
+	"不支援的 listener 模式: %s (可用: per_slave, shared)": "unsupported listener mode: %s (available: per_slave, shared)",
+	"不支援的連線類型: %T":                                 "unsupported connection type: %T",
+	"取得原始目的位址失敗":                                   "failed to get original destination address",
+	"已建立共用 listener":                               "shared listener created",
+	"建立場景更新群組":                                     "scenario update group created",
+
+	// 自我監控設備
+	"自我監控設備的週期與告警門檻不可為負":    "diagnostics interval and alarm thresholds must not be negative",
+	"自我監控設備需指定有效的 IP: %q":   "diagnostics requires a valid ip: %q",
+	"自我監控設備的 Unit ID 不可為 0": "diagnostics unit_id must not be 0",
+	"自我監控告警":                "self-monitoring alarm raised",
+	"自我監控告警解除":              "self-monitoring alarm cleared",
+	"啟動自我監控設備失敗: %w":        "failed to start diagnostics device: %w",
+	"已啟動自我監控設備":             "diagnostics device started",
+	"自我監控設備未啟動":             "diagnostics device not started",
+}
@@ -0,0 +1,695 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultIEC104Port IEC 60870-5-104 預設監聽埠
+const DefaultIEC104Port = 2404
+
+// APCI 參數 (IEC 60870-5-104 建議值)
+const (
+	iec104K  = 12               // 未獲確認的 I 格式 APDU 上限，超過時延後送出
+	iec104W  = 8                // 收到 w 個 I 格式 APDU 後立即以 S 格式確認
+	iec104T1 = 15 * time.Second // 送出的 I 格式或 TESTFR 等待確認的逾時
+	iec104T2 = 10 * time.Second // 沒有資料可送時確認收到的 I 格式的期限
+	iec104T3 = 20 * time.Second // 閒置多久後送出 TESTFR
+)
+
+// APDU 格式
+const (
+	iec104StartByte = 0x68
+	iec104MaxLength = 253                 // 長度欄位的上限 (控制欄位 4 bytes + ASDU)
+	iec104MaxASDU   = iec104MaxLength - 4 // ASDU 的上限
+	iec104SeqModulo = 32768               // 序號為 15 位元
+	iec104HeaderLen = 6                   // ASDU 標頭 (類型、可變結構限定詞、2 bytes 傳送原因、2 bytes 公共位址)
+	iec104IOALen    = 3
+)
+
+// U 格式功能
+const (
+	iec104StartDTAct = 0x07
+	iec104StartDTCon = 0x0B
+	iec104StopDTAct  = 0x13
+	iec104StopDTCon  = 0x23
+	iec104TestFRAct  = 0x43
+	iec104TestFRCon  = 0x83
+)
+
+// ASDU 類型識別
+const (
+	iec104MeasuredFloat     = 13  // M_ME_NC_1 短浮點數測量值
+	iec104MeasuredFloatTime = 36  // M_ME_TF_1 帶 CP56Time2a 時標的短浮點數測量值
+	iec104EndOfInit         = 70  // M_EI_NA_1 初始化結束
+	iec104Interrogation     = 100 // C_IC_NA_1 總召喚
+	iec104Read              = 102 // C_RD_NA_1 讀命令
+	iec104ClockSync         = 103 // C_CS_NA_1 時鐘同步
+)
+
+// 傳送原因 (COT)
+const (
+	iec104CotSpontaneous  = 3
+	iec104CotInitialized  = 4
+	iec104CotRequest      = 5
+	iec104CotActivation   = 6
+	iec104CotActCon       = 7
+	iec104CotDeactivation = 8
+	iec104CotDeactCon     = 9
+	iec104CotActTerm      = 10
+	iec104CotInterrogated = 20 // 回應站總召喚
+	iec104CotUnknownType  = 44
+	iec104CotUnknownCause = 45
+	iec104CotUnknownCA    = 46
+	iec104CotUnknownIOA   = 47
+	iec104Negative        = 0x40 // 否定確認 (P/N 位元)
+)
+
+// iec104BroadcastCA 廣播的公共位址 (總召喚與時鐘同步作用於所有邏輯設備)
+const iec104BroadcastCA = 0xFFFF
+
+// 每個 ASDU 可容納的資訊物件數 (IOA + 短浮點數 + 品質描述詞，時標另加 7 bytes)
+const (
+	iec104FloatsPerASDU      = (iec104MaxASDU - iec104HeaderLen) / (iec104IOALen + 5)
+	iec104TimedFloatsPerASDU = (iec104MaxASDU - iec104HeaderLen) / (iec104IOALen + 5 + 7)
+)
+
+// WithIEC104 在 Slave 的 IP 上另外於 port 提供 IEC 60870-5-104 伺服器
+func WithIEC104(port int) SlaveOption {
+	return func(s *Slave) {
+		s.protocols = append(s.protocols, func(s *Slave) protocolListener {
+			return newIEC104Listener(s, s.protocolAddr(port))
+		})
+	}
+}
+
+// iec104Device 以公共位址 (Unit ID) 定址的設備
+type iec104Device struct {
+	ca        uint16
+	registers *RegisterMap
+}
+
+// iec104Devices 公共位址對應的設備 (廣播位址為全部設備)
+func (s *Slave) iec104Devices(ca uint16) []iec104Device {
+	devices := make([]iec104Device, 0, 1+len(s.units))
+	if ca == iec104BroadcastCA || ca == uint16(s.UnitID) {
+		devices = append(devices, iec104Device{ca: uint16(s.UnitID), registers: s.registers})
+	}
+	for _, id := range s.unitIDs() {
+		if id != s.UnitID && (ca == iec104BroadcastCA || ca == uint16(id)) {
+			devices = append(devices, iec104Device{ca: uint16(id), registers: s.units[id].registers})
+		}
+	}
+	return devices
+}
+
+// iec104Point 資訊物件 (已定義的數值暫存器，位址即 IOA)
+type iec104Point struct {
+	ioa   uint32
+	value float32
+}
+
+// iec104Points 設備的資訊物件 (字串類型除外，依位址排序)
+func iec104Points(registers *RegisterMap) []iec104Point {
+	defs := registers.Definitions()
+	points := make([]iec104Point, 0, len(defs))
+	for _, meta := range defs {
+		if meta.DataType.IsString() {
+			continue
+		}
+		value, err := registers.GetScaledValue(meta.Address)
+		if err != nil {
+			continue
+		}
+		points = append(points, iec104Point{ioa: uint32(meta.Address), value: float32(value)})
+	}
+	return points
+}
+
+// --- 接入層 ---
+
+// iec104Listener Slave 的 IEC 104 接入層 (與 Modbus listener 一同開啟與關閉，離線模擬時同樣斷線)
+type iec104Listener struct {
+	slave    *Slave
+	addr     string
+	listener net.Listener
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func newIEC104Listener(slave *Slave, addr string) *iec104Listener {
+	return &iec104Listener{
+		slave: slave,
+		addr:  addr,
+		conns: make(map[net.Conn]struct{}),
+	}
+}
+
+// Listen 開始監聽並在背景接受連線
+func (l *iec104Listener) Listen() error {
+	var lc net.ListenConfig
+	if l.slave.iface != "" {
+		lc.Control = bindToDevice(l.slave.iface)
+	}
+	listener, err := lc.Listen(context.Background(), "tcp", l.addr)
+	if err != nil {
+		return err
+	}
+	l.listener = listener
+
+	l.wg.Add(1)
+	go l.acceptLoop()
+	return nil
+}
+
+// Addr 監聽位址
+func (l *iec104Listener) Addr() string {
+	return l.addr
+}
+
+// Close 關閉 listener 與所有既有連線，並等待處理 goroutine 結束
+func (l *iec104Listener) Close() {
+	l.listener.Close()
+
+	l.mu.Lock()
+	l.closed = true
+	for conn := range l.conns {
+		conn.Close()
+	}
+	l.mu.Unlock()
+
+	l.wg.Wait()
+}
+
+func (l *iec104Listener) acceptLoop() {
+	defer l.wg.Done()
+
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				l.slave.logger.Warn(T("接受連線失敗"), zap.String("addr", l.addr), zap.Error(err))
+			}
+			return
+		}
+
+		l.mu.Lock()
+		if l.closed {
+			l.mu.Unlock()
+			conn.Close()
+			return
+		}
+		l.conns[conn] = struct{}{}
+		l.wg.Add(1)
+		l.mu.Unlock()
+
+		go l.serveConn(conn)
+	}
+}
+
+// serveConn 處理單一連線，結束時記錄原因
+func (l *iec104Listener) serveConn(conn net.Conn) {
+	defer l.wg.Done()
+	defer func() {
+		l.mu.Lock()
+		delete(l.conns, conn)
+		l.mu.Unlock()
+		conn.Close()
+	}()
+
+	_, writeTimeout, _ := l.slave.connTimeouts()
+	c := &iec104Conn{
+		slave: l.slave,
+		out:   &deadlineWriter{conn: conn, timeout: writeTimeout},
+		last:  make(map[iec104Key]float32),
+	}
+	err := c.serve(conn)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+		l.slave.logger.Debug(T("IEC 104 連線中斷"), zap.String("remote", conn.RemoteAddr().String()), zap.Error(err))
+	}
+}
+
+// --- 連線 ---
+
+// iec104APDU 一個 APDU (控制欄位與 ASDU)
+type iec104APDU struct {
+	control [4]byte
+	asdu    []byte
+}
+
+// iec104Key 自發傳送比對用的資訊物件識別
+type iec104Key struct {
+	ca  uint16
+	ioa uint32
+}
+
+// iec104Conn 單一連線的狀態 (僅由 serve 的 goroutine 存取)
+type iec104Conn struct {
+	slave *Slave
+	out   io.Writer
+
+	started  bool        // 已收到 STARTDT (可送出 I 格式)
+	initSent bool        // 已送出初始化結束
+	sendSeq  uint16      // 下一個送出的序號 N(S)
+	recvSeq  uint16      // 預期收到的序號 N(R)
+	ackSeq   uint16      // 對方已確認至此序號 (不含)
+	sentAt   []time.Time // 尚未確認的 I 格式的送出時間
+	pending  [][]byte    // 超過 k 而延後送出的 ASDU
+
+	unacked    int       // 收到但尚未確認的 I 格式數
+	unackedAt  time.Time // 最早一個未確認的 I 格式的收到時間
+	lastRecv   time.Time
+	testFRSent time.Time // 送出 TESTFR 生效的時間 (零值表示未等待確認)
+
+	last map[iec104Key]float32 // 上次送出的值 (自發傳送只送出變化的值)
+}
+
+// serve 讀取 APDU 並回應，依更新週期自發傳送變化的測量值
+func (c *iec104Conn) serve(conn net.Conn) error {
+	frames := make(chan iec104APDU)
+	readErr := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		reader := bufio.NewReader(conn)
+		for {
+			apdu, err := readIEC104APDU(reader)
+			if err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case frames <- apdu:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	interval := c.slave.updateInterval()
+	if interval <= 0 {
+		interval = time.Second
+	}
+	scan := time.NewTicker(interval)
+	defer scan.Stop()
+	timers := time.NewTicker(time.Second)
+	defer timers.Stop()
+
+	c.lastRecv = time.Now()
+	for {
+		select {
+		case err := <-readErr:
+			return err
+		case apdu := <-frames:
+			c.lastRecv = time.Now()
+			if c.slave.silentStandby.Load() {
+				continue // silent 備援端：讀取但不回應
+			}
+			if err := c.handle(apdu); err != nil {
+				return err
+			}
+		case <-scan.C:
+			if c.started {
+				if err := c.sendChanges(); err != nil {
+					return err
+				}
+			}
+		case now := <-timers.C:
+			if err := c.checkTimers(now); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// checkTimers 逾時 t1 時中斷連線，t2 到期時確認收到的 I 格式，閒置 t3 後送出 TESTFR
+func (c *iec104Conn) checkTimers(now time.Time) error {
+	if len(c.sentAt) > 0 && now.Sub(c.sentAt[0]) > iec104T1 {
+		return errors.New(T("IEC 104 等待確認逾時 (t1)"))
+	}
+	if !c.testFRSent.IsZero() && now.Sub(c.testFRSent) > iec104T1 {
+		return errors.New(T("IEC 104 等待確認逾時 (t1)"))
+	}
+	if c.unacked > 0 && now.Sub(c.unackedAt) >= iec104T2 {
+		if err := c.sendS(); err != nil {
+			return err
+		}
+	}
+	if c.testFRSent.IsZero() && now.Sub(c.lastRecv) >= iec104T3 {
+		c.testFRSent = now
+		return c.sendU(iec104TestFRAct)
+	}
+	return nil
+}
+
+// handle 處理一個 APDU
+func (c *iec104Conn) handle(apdu iec104APDU) error {
+	ctrl := apdu.control
+	switch {
+	case ctrl[0]&0x01 == 0: // I 格式
+		ns := binary.LittleEndian.Uint16(ctrl[0:]) >> 1
+		if ns != c.recvSeq {
+			return fmt.Errorf(T("IEC 104 序號錯誤: 收到 %d，預期 %d"), ns, c.recvSeq)
+		}
+		c.recvSeq = (c.recvSeq + 1) % iec104SeqModulo
+		if c.unacked == 0 {
+			c.unackedAt = time.Now()
+		}
+		c.unacked++
+		if err := c.acknowledge(binary.LittleEndian.Uint16(ctrl[2:]) >> 1); err != nil {
+			return err
+		}
+		if c.started {
+			if err := c.handleASDU(apdu.asdu); err != nil {
+				return err
+			}
+		}
+		if c.unacked >= iec104W {
+			return c.sendS()
+		}
+		return nil
+
+	case ctrl[0]&0x03 == 0x01: // S 格式
+		return c.acknowledge(binary.LittleEndian.Uint16(ctrl[2:]) >> 1)
+
+	default: // U 格式
+		switch ctrl[0] {
+		case iec104StartDTAct:
+			c.started = true
+			if err := c.sendU(iec104StartDTCon); err != nil {
+				return err
+			}
+			// 自目前的值開始比對變化 (完整的值由總召喚取得)
+			for _, d := range c.slave.iec104Devices(iec104BroadcastCA) {
+				for _, p := range iec104Points(d.registers) {
+					c.last[iec104Key{d.ca, p.ioa}] = p.value
+				}
+			}
+			if !c.initSent {
+				c.initSent = true
+				return c.sendEndOfInit()
+			}
+			return nil
+		case iec104StopDTAct:
+			c.started = false
+			if c.unacked > 0 {
+				if err := c.sendS(); err != nil {
+					return err
+				}
+			}
+			return c.sendU(iec104StopDTCon)
+		case iec104TestFRAct:
+			return c.sendU(iec104TestFRCon)
+		case iec104TestFRCon:
+			c.testFRSent = time.Time{}
+			return nil
+		default:
+			return fmt.Errorf(T("IEC 104 不支援的 U 格式功能: 0x%02x"), ctrl[0])
+		}
+	}
+}
+
+// acknowledge 對方確認至 nr (不含) 的 I 格式，釋出延後送出的 ASDU
+func (c *iec104Conn) acknowledge(nr uint16) error {
+	acked := int((nr - c.ackSeq + iec104SeqModulo) % iec104SeqModulo)
+	if acked > len(c.sentAt) {
+		return fmt.Errorf(T("IEC 104 確認的序號無效: %d"), nr)
+	}
+	c.ackSeq = nr
+	c.sentAt = c.sentAt[acked:]
+
+	for len(c.pending) > 0 && len(c.sentAt) < iec104K {
+		asdu := c.pending[0]
+		c.pending = c.pending[1:]
+		if err := c.writeI(asdu); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// --- ASDU ---
+
+// handleASDU 處理總召喚、讀命令與時鐘同步，其餘類型以否定確認回應
+func (c *iec104Conn) handleASDU(asdu []byte) error {
+	if len(asdu) < iec104HeaderLen+iec104IOALen {
+		return fmt.Errorf(T("IEC 104 ASDU 長度不足: %d"), len(asdu))
+	}
+	typeID, cause := asdu[0], asdu[2]&0x3F
+	originator := asdu[3]
+	ca := binary.LittleEndian.Uint16(asdu[4:])
+	ioa := uint32(asdu[6]) | uint32(asdu[7])<<8 | uint32(asdu[8])<<16
+
+	switch typeID {
+	case iec104Interrogation, iec104Read, iec104ClockSync:
+	default:
+		return c.send(mirrorIEC104(asdu, iec104CotUnknownType|iec104Negative))
+	}
+	devices := c.slave.iec104Devices(ca)
+	if len(devices) == 0 || (typeID == iec104Read && ca == iec104BroadcastCA) {
+		return c.send(mirrorIEC104(asdu, iec104CotUnknownCA|iec104Negative))
+	}
+
+	switch typeID {
+	case iec104Interrogation:
+		switch cause {
+		case iec104CotActivation:
+			if err := c.send(mirrorIEC104(asdu, iec104CotActCon)); err != nil {
+				return err
+			}
+			for _, d := range devices {
+				points := iec104Points(d.registers)
+				for _, p := range points {
+					c.last[iec104Key{d.ca, p.ioa}] = p.value
+				}
+				if err := c.sendPoints(d.ca, points, iec104CotInterrogated, originator, false); err != nil {
+					return err
+				}
+			}
+			return c.send(mirrorIEC104(asdu, iec104CotActTerm))
+		case iec104CotDeactivation:
+			return c.send(mirrorIEC104(asdu, iec104CotDeactCon))
+		}
+
+	case iec104Read:
+		if cause == iec104CotRequest {
+			meta, ok := devices[0].registers.GetDefinition(uint16(ioa))
+			if !ok || ioa > math.MaxUint16 || meta.DataType.IsString() {
+				return c.send(mirrorIEC104(asdu, iec104CotUnknownIOA|iec104Negative))
+			}
+			value, _ := devices[0].registers.GetScaledValue(meta.Address)
+			return c.sendPoints(devices[0].ca, []iec104Point{{ioa: ioa, value: float32(value)}}, iec104CotRequest, originator, false)
+		}
+
+	case iec104ClockSync:
+		if cause == iec104CotActivation {
+			return c.send(mirrorIEC104(asdu, iec104CotActCon))
+		}
+	}
+	return c.send(mirrorIEC104(asdu, iec104CotUnknownCause|iec104Negative))
+}
+
+// sendChanges 自發傳送與上次送出不同的測量值 (帶時標)
+func (c *iec104Conn) sendChanges() error {
+	for _, d := range c.slave.iec104Devices(iec104BroadcastCA) {
+		var changed []iec104Point
+		for _, p := range iec104Points(d.registers) {
+			key := iec104Key{d.ca, p.ioa}
+			if last, ok := c.last[key]; ok && last == p.value {
+				continue
+			}
+			c.last[key] = p.value
+			changed = append(changed, p)
+		}
+		if err := c.sendPoints(d.ca, changed, iec104CotSpontaneous, 0, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendPoints 以 M_ME_NC_1 (或帶時標的 M_ME_TF_1) 送出測量值，超過單一 ASDU 容量時分批
+func (c *iec104Conn) sendPoints(ca uint16, points []iec104Point, cause, originator byte, timed bool) error {
+	typeID, perASDU := byte(iec104MeasuredFloat), iec104FloatsPerASDU
+	if timed {
+		typeID, perASDU = iec104MeasuredFloatTime, iec104TimedFloatsPerASDU
+	}
+	now := scenarioNow()
+	for len(points) > 0 {
+		batch := points[:min(perASDU, len(points))]
+		points = points[len(batch):]
+
+		asdu := appendIEC104Header(nil, typeID, len(batch), cause, originator, ca)
+		for _, p := range batch {
+			asdu = appendIEC104IOA(asdu, p.ioa)
+			asdu = binary.LittleEndian.AppendUint32(asdu, math.Float32bits(p.value))
+			asdu = append(asdu, 0) // 品質描述詞：有效
+			if timed {
+				asdu = appendCP56Time2a(asdu, now)
+			}
+		}
+		if err := c.send(asdu); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendEndOfInit 送出各設備的初始化結束 (M_EI_NA_1，原因為本地電源開啟)
+func (c *iec104Conn) sendEndOfInit() error {
+	for _, d := range c.slave.iec104Devices(iec104BroadcastCA) {
+		asdu := appendIEC104Header(nil, iec104EndOfInit, 1, iec104CotInitialized, 0, d.ca)
+		asdu = appendIEC104IOA(asdu, 0)
+		asdu = append(asdu, 0)
+		if err := c.send(asdu); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send 以 I 格式送出 ASDU (未確認的已達 k 個時延後)
+func (c *iec104Conn) send(asdu []byte) error {
+	if len(c.sentAt) >= iec104K {
+		c.pending = append(c.pending, asdu)
+		return nil
+	}
+	return c.writeI(asdu)
+}
+
+// writeI 送出 I 格式 (同時確認所有收到的 I 格式)
+func (c *iec104Conn) writeI(asdu []byte) error {
+	ctrl := binary.LittleEndian.AppendUint16(nil, c.sendSeq<<1)
+	ctrl = binary.LittleEndian.AppendUint16(ctrl, c.recvSeq<<1)
+	if err := c.write(ctrl, asdu); err != nil {
+		return err
+	}
+	c.sendSeq = (c.sendSeq + 1) % iec104SeqModulo
+	c.sentAt = append(c.sentAt, time.Now())
+	c.unacked = 0
+	return nil
+}
+
+// sendS 以 S 格式確認收到的 I 格式
+func (c *iec104Conn) sendS() error {
+	ctrl := binary.LittleEndian.AppendUint16([]byte{0x01, 0x00}, c.recvSeq<<1)
+	c.unacked = 0
+	return c.write(ctrl, nil)
+}
+
+// sendU 送出 U 格式
+func (c *iec104Conn) sendU(function byte) error {
+	return c.write([]byte{function, 0, 0, 0}, nil)
+}
+
+func (c *iec104Conn) write(ctrl, asdu []byte) error {
+	frame := make([]byte, 0, 2+len(ctrl)+len(asdu))
+	frame = append(frame, iec104StartByte, byte(len(ctrl)+len(asdu)))
+	frame = append(frame, ctrl...)
+	frame = append(frame, asdu...)
+	_, err := c.out.Write(frame)
+	return err
+}
+
+// --- 編碼 ---
+
+// readIEC104APDU 讀取一個 APDU
+func readIEC104APDU(r *bufio.Reader) (iec104APDU, error) {
+	start, err := r.ReadByte()
+	if err != nil {
+		return iec104APDU{}, err
+	}
+	if start != iec104StartByte {
+		return iec104APDU{}, fmt.Errorf(T("IEC 104 起始字元錯誤: 0x%02x"), start)
+	}
+	length, err := r.ReadByte()
+	if err != nil {
+		return iec104APDU{}, err
+	}
+	if length < 4 || length > iec104MaxLength {
+		return iec104APDU{}, fmt.Errorf(T("IEC 104 APDU 長度無效: %d"), length)
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return iec104APDU{}, err
+	}
+	apdu := iec104APDU{asdu: buf[4:]}
+	copy(apdu.control[:], buf)
+	return apdu, nil
+}
+
+// mirrorIEC104 以指定的傳送原因回傳收到的 ASDU (確認、結束與否定確認)
+func mirrorIEC104(asdu []byte, cause byte) []byte {
+	mirrored := append([]byte(nil), asdu...)
+	mirrored[2] = cause
+	return mirrored
+}
+
+// appendIEC104Header 附加 ASDU 標頭 (count 個非連續的資訊物件)
+func appendIEC104Header(b []byte, typeID byte, count int, cause, originator byte, ca uint16) []byte {
+	b = append(b, typeID, byte(count), cause, originator)
+	return binary.LittleEndian.AppendUint16(b, ca)
+}
+
+// appendIEC104IOA 附加 3 bytes 的資訊物件位址
+func appendIEC104IOA(b []byte, ioa uint32) []byte {
+	return append(b, byte(ioa), byte(ioa>>8), byte(ioa>>16))
+}
+
+// appendCP56Time2a 附加 7 bytes 時標 (毫秒、分、時、日與星期、月、年)
+func appendCP56Time2a(b []byte, t time.Time) []byte {
+	ms := t.Second()*1000 + t.Nanosecond()/int(time.Millisecond)
+	b = binary.LittleEndian.AppendUint16(b, uint16(ms))
+	weekday := int(t.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+	return append(b, byte(t.Minute()), byte(t.Hour()), byte(t.Day())|byte(weekday)<<5, byte(t.Month()), byte(t.Year()%100))
+}
+
+// --- 配置 ---
+
+// Validate 驗證 IEC 104 從站模式設定
+func (c *IEC104Config) Validate(modbusPort int) error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Port < 1 || c.Port > 65535 || c.Port == modbusPort {
+		return fmt.Errorf(T("IEC 104 監聽埠無效: %d (需介於 1-65535 且不同於 Modbus 埠)"), c.Port)
+	}
+	for _, name := range c.Profiles {
+		if _, ok := GetDeviceProfile(name); !ok {
+			return fmt.Errorf(T("IEC 104 使用未知的設備設定檔: %s"), name)
+		}
+	}
+	return nil
+}
+
+// serves 使用該設定檔的 Slave 是否提供 IEC 104 (profiles 為空表示全部)
+func (c *IEC104Config) serves(profile string) bool {
+	if !c.Enabled {
+		return false
+	}
+	if len(c.Profiles) == 0 {
+		return true
+	}
+	for _, name := range c.Profiles {
+		if name == profile {
+			return true
+		}
+	}
+	return false
+}
@@ -3,11 +3,30 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/goburrow/modbus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -107,6 +126,213 @@ func TestSlaveIntegration(t *testing.T) {
 	})
 }
 
+func TestSlaveFlapIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Server.Port = 5505
+
+	slave := NewSlave(nil, config.Server.Port, config, WithLogger(logger))
+	ctx := context.Background()
+	require.NoError(t, slave.Start(ctx))
+	defer slave.Stop(ctx)
+
+	handler := modbus.NewTCPClientHandler("127.0.0.1:5505")
+	handler.Timeout = 2 * time.Second
+	require.NoError(t, handler.Connect())
+	defer handler.Close()
+	client := modbus.NewClient(handler)
+
+	_, err := client.ReadHoldingRegisters(0, 1)
+	require.NoError(t, err)
+
+	// 離線：既有連線被中斷，新連線被拒絕
+	slave.GoOffline()
+	assert.Equal(t, SlaveStateOffline, slave.State())
+	assert.Equal(t, uint64(1), slave.GetStats().FlapCount.Load())
+
+	_, err = client.ReadHoldingRegisters(0, 1)
+	assert.Error(t, err)
+
+	_, err = net.DialTimeout("tcp", "127.0.0.1:5505", time.Second)
+	assert.Error(t, err)
+
+	// 恢復上線
+	require.NoError(t, slave.GoOnline())
+	assert.Equal(t, SlaveStateRunning, slave.State())
+
+	handler.Close()
+	require.NoError(t, handler.Connect())
+	_, err = client.ReadHoldingRegisters(0, 1)
+	require.NoError(t, err)
+}
+
+func TestBatterySetpointIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Server.Port = 5506
+
+	profile, ok := GetDeviceProfile(ProfileBattery)
+	require.True(t, ok)
+	rm, err := profile.NewRegisterMap()
+	require.NoError(t, err)
+
+	slave := NewSlave(nil, config.Server.Port, config, WithLogger(logger), WithRegisters(rm), WithModel(profile.NewModel()))
+	ctx := context.Background()
+	require.NoError(t, slave.Start(ctx))
+	defer slave.Stop(ctx)
+
+	handler := modbus.NewTCPClientHandler("127.0.0.1:5506")
+	handler.Timeout = 2 * time.Second
+	require.NoError(t, handler.Connect())
+	defer handler.Close()
+	client := modbus.NewClient(handler)
+
+	// 寫入充電功率設定值 36000 W (int32，40023-40024)
+	_, err = client.WriteMultipleRegisters(AddrBatterySetpoint-40001, 2, []byte{0x00, 0x00, 0x8C, 0xA0})
+	require.NoError(t, err)
+
+	// 寫入值應回寫至暫存器映射表，並在下次同步後仍可讀回
+	setpoint, err := slave.Registers().GetScaledValue(AddrBatterySetpoint)
+	require.NoError(t, err)
+	assert.InDelta(t, 36000, setpoint, 0.1)
+
+	time.Sleep(1500 * time.Millisecond)
+	results, err := client.ReadHoldingRegisters(AddrBatterySetpoint-40001, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x00, 0x8C, 0xA0}, results)
+}
+
+func TestTracingExemplarIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	// 模擬 OTLP collector，收集匯出的 span
+	exported := make(chan []byte, 4)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		exported <- body
+	}))
+	defer collector.Close()
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Server.Port = 5507
+	config.Tracing = TracingConfig{Enabled: true, Endpoint: collector.URL, SampleRate: 1, FlushInterval: time.Hour}
+
+	tracer := NewTracer(config.Tracing, logger)
+	tracer.Start()
+	latency := NewLatencyHistogram(DefaultLatencyBuckets)
+
+	slave := NewSlave(nil, config.Server.Port, config, WithLogger(logger), WithTracer(tracer), WithLatencyHistogram(latency))
+	ctx := context.Background()
+	require.NoError(t, slave.Start(ctx))
+	defer slave.Stop(ctx)
+
+	handler := modbus.NewTCPClientHandler("127.0.0.1:5507")
+	handler.Timeout = 2 * time.Second
+	require.NoError(t, handler.Connect())
+	defer handler.Close()
+	_, err := modbus.NewClient(handler).ReadHoldingRegisters(10, 3)
+	require.NoError(t, err)
+
+	// 回應寫出後才記錄延遲
+	require.Eventually(t, func() bool { return latency.Count() == 1 }, time.Second, 10*time.Millisecond)
+	tracer.Stop()
+
+	var payload struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []Span `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	require.NoError(t, json.Unmarshal(<-exported, &payload))
+	spans := payload.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 1)
+	assert.Equal(t, "modbus/ReadHoldingRegisters", spans[0].Name)
+	assert.Len(t, spans[0].TraceID, 32)
+
+	// 交易屬性：Slave、位址與數量
+	attrs := map[string]string{}
+	for _, attr := range spans[0].Attributes {
+		switch {
+		case attr.Value.StringValue != nil:
+			attrs[attr.Key] = *attr.Value.StringValue
+		case attr.Value.IntValue != nil:
+			attrs[attr.Key] = *attr.Value.IntValue
+		}
+	}
+	assert.Equal(t, slave.ID, attrs["modbussim.slave_id"])
+	assert.Equal(t, "3", attrs["modbus.function_code"])
+	assert.Equal(t, "10", attrs["modbus.address"])
+	assert.Equal(t, "3", attrs["modbus.quantity"])
+	assert.Equal(t, "127.0.0.1", attrs["client.address"])
+	assert.NotEmpty(t, attrs["client.port"])
+
+	// OpenMetrics 輸出帶有指向該 trace 的 exemplar，Prometheus 格式則不帶
+	var om, prom bytes.Buffer
+	latency.write(&om, "modbussim_request_duration_seconds", true)
+	latency.write(&prom, "modbussim_request_duration_seconds", false)
+	assert.Contains(t, om.String(), `# {trace_id="`+spans[0].TraceID+`"}`)
+	assert.False(t, strings.Contains(prom.String(), "trace_id"))
+	assert.Contains(t, prom.String(), "modbussim_request_duration_seconds_count 1")
+}
+
+func TestDomainOutageIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5508
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	config.FailureDomains = []FailureDomain{{Name: "gateway-1", Targets: []string{"127.0.0.0/8"}}}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	dial := func() error {
+		conn, err := net.DialTimeout("tcp", "127.0.0.1:5508", time.Second)
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}
+	require.NoError(t, dial())
+
+	status, err := engine.DomainOutage("gateway-1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, status.Down)
+	assert.Equal(t, 1, status.Members)
+	assert.Error(t, dial(), "停擺期間應拒絕連線")
+
+	stats := engine.Stats()
+	assert.Equal(t, 1, stats.DomainsDown)
+	assert.Equal(t, uint64(1), stats.DomainOutages)
+	assert.Equal(t, 1, stats.OfflineSlaves)
+
+	status, err = engine.RestoreDomain("gateway-1")
+	require.NoError(t, err)
+	assert.False(t, status.Down)
+	assert.NoError(t, dial())
+
+	_, err = engine.DomainOutage("unknown", 0)
+	assert.Error(t, err)
+}
+
 func TestEngineIntegration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
@@ -170,3 +396,3242 @@ func BenchmarkSlaveConnections(b *testing.B) {
 		handler.Close()
 	}
 }
+
+func TestBindConflictIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	// 先占用位址，模擬其他程序已在監聽
+	holder, err := net.Listen("tcp", "127.0.0.1:5509")
+	require.NoError(t, err)
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5509
+	config.Server.BindRetryInterval = 100 * time.Millisecond
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx), "有待重試的 Slave 時引擎應照常啟動")
+	defer engine.Stop(ctx)
+
+	stats := engine.Stats()
+	assert.GreaterOrEqual(t, stats.BindConflicts, uint64(1))
+	assert.Equal(t, 1, stats.BindPending)
+	assert.Empty(t, engine.ListSlaves())
+
+	// 衝突解除後自動綁定
+	holder.Close()
+	require.Eventually(t, func() bool { return len(engine.ListSlaves()) == 1 }, 5*time.Second, 50*time.Millisecond)
+	assert.Equal(t, 0, engine.Stats().BindPending)
+
+	conn, err := net.DialTimeout("tcp", "127.0.0.1:5509", time.Second)
+	require.NoError(t, err)
+	conn.Close()
+}
+
+func TestDriftIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5510
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	report := engine.CheckDrift()
+	assert.Equal(t, 1, report.Total)
+	assert.Equal(t, 0, report.Drifted, "剛啟動時應與設定檔一致")
+
+	// 模擬測試程式改動元資料後未還原
+	slave := engine.ListSlaves()[0]
+	slave.Registers().DefineRegister(40006, "PowerFactor", DataTypeUint16, 100, "", false)
+	slave.Registers().DefineRegister(40020, "Setpoint", DataTypeUint16, 1, "W", true)
+
+	report = engine.CheckDrift()
+	require.Equal(t, 1, report.Drifted)
+	fields := map[string]RegisterDrift{}
+	for _, d := range report.Slaves[0].Drifts {
+		fields[d.Field] = d
+	}
+	assert.Equal(t, "1000", fields["scale"].Expected)
+	assert.Equal(t, "100", fields["scale"].Actual)
+	assert.Equal(t, uint16(40020), fields[DriftUnexpected].Address)
+	assert.Equal(t, 1, engine.Stats().DriftedSlaves)
+
+	// 重新設定基準後，可寫入暫存器的值也納入比對
+	status := engine.Rebaseline(slave)
+	assert.Equal(t, 1, status.Rebaselined)
+	assert.Equal(t, 0, engine.CheckDrift().Drifted)
+
+	require.NoError(t, slave.Registers().SetScaledValue(40020, 500))
+	report = engine.CheckDrift()
+	require.Equal(t, 1, report.Drifted)
+	assert.Equal(t, DriftValue, report.Slaves[0].Drifts[0].Field)
+	assert.Equal(t, "500", report.Slaves[0].Drifts[0].Actual)
+	assert.False(t, report.Slaves[0].BaselineAt.IsZero())
+}
+
+func TestPollingIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5511
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	config.Polling.Enabled = true
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	handler := modbus.NewTCPClientHandler("127.0.0.1:5511")
+	handler.Timeout = 5 * time.Second
+	require.NoError(t, handler.Connect())
+	defer handler.Close()
+	client := modbus.NewClient(handler)
+
+	// 反覆讀取未使用的暫存器 (數值不會變化)
+	for i := 0; i < 25; i++ {
+		_, err := client.ReadHoldingRegisters(100, 2)
+		require.NoError(t, err)
+	}
+	_, err := client.ReadHoldingRegisters(0, 2)
+	require.NoError(t, err)
+
+	report := engine.Polls().Report("")
+	require.Len(t, report.Masters, 1)
+	master := report.Masters[0]
+	assert.Equal(t, "127.0.0.1", master.Master)
+	assert.Equal(t, 2, master.Blocks)
+	assert.Equal(t, uint64(26), master.Polls)
+	assert.Equal(t, uint64(24), master.RedundantPolls)
+
+	require.Len(t, master.HotSpots, 1, "僅讀取 25 次的靜態區塊列為熱點")
+	spot := master.HotSpots[0]
+	assert.Equal(t, uint8(FuncCodeReadHoldingRegisters), spot.Function)
+	assert.Equal(t, uint16(100), spot.Address)
+	assert.Equal(t, uint64(0), spot.Changes)
+	assert.Empty(t, spot.SuggestedInterval)
+	assert.Equal(t, []uint16{100, 101}, spot.StaticRegisters)
+	assert.Equal(t, uint64(24), engine.Stats().RedundantPolls)
+
+	assert.Empty(t, engine.Polls().Report("10.0.0.1").Masters)
+	engine.Polls().Reset()
+	assert.Empty(t, engine.Polls().Report("").Masters)
+
+	// 每秒輪詢、每 10 秒變化一次：建議間隔為平均變化間隔
+	tracker := NewPollTracker(PollingConfig{})
+	start := time.Now()
+	for i := 0; i <= 100; i++ {
+		tracker.Record("10.0.0.1", "slave", FuncCodeReadHoldingRegisters, 0, 2, []byte{0, byte(i / 10), 0, 0}, start.Add(time.Duration(i)*time.Second))
+	}
+	spot = tracker.Report("").Masters[0].HotSpots[0]
+	assert.Equal(t, uint64(10), spot.Changes)
+	assert.Equal(t, "1s", spot.PollInterval)
+	assert.Equal(t, "10s", spot.SuggestedInterval)
+	assert.Equal(t, []uint16{1}, spot.StaticRegisters)
+}
+
+func TestBenchIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5512
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	mix, err := ParseBenchMix("3=3,4=1")
+	require.NoError(t, err)
+
+	// 依請求總數結束
+	report, err := RunBench(ctx, BenchConfig{
+		Targets:     []string{"127.0.0.1:5512"},
+		Concurrency: 4,
+		Requests:    200,
+		Quantity:    5,
+		Mix:         mix,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 200, report.Requests)
+	assert.Zero(t, report.Errors)
+	require.Len(t, report.Functions, 2)
+	assert.Equal(t, uint8(FuncCodeReadHoldingRegisters), report.Functions[0].Function)
+	assert.Greater(t, report.Functions[0].Requests, report.Functions[1].Requests)
+	assert.LessOrEqual(t, report.Latency.P50, report.Latency.P99)
+	assert.LessOrEqual(t, report.Latency.P99, report.Latency.Max)
+	assert.Greater(t, report.Throughput, 0.0)
+
+	// 限速：1 秒 50 req/s
+	report, err = RunBench(ctx, BenchConfig{
+		Targets:     []string{"127.0.0.1:5512"},
+		Concurrency: 2,
+		Rate:        50,
+		Duration:    time.Second,
+	})
+	require.NoError(t, err)
+	assert.InDelta(t, 50, report.Requests, 10)
+
+	// 超出範圍的位址回報例外
+	report, err = RunBench(ctx, BenchConfig{
+		Targets:  []string{"127.0.0.1:5512"},
+		Requests: 5,
+		Address:  9999,
+		Quantity: 10,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 5, report.Errors)
+	assert.Equal(t, map[string]int{"exception:2": 5}, report.ErrorKinds)
+
+	_, err = ParseBenchMix("7=1")
+	assert.Error(t, err)
+	_, err = ParseBenchMix("3=0")
+	assert.Error(t, err)
+}
+
+func TestBitmapAPIIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5513
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	mux := http.NewServeMux()
+	NewAdminAPI(engine, logger).Register(mux)
+	api := httptest.NewServer(mux)
+	defer api.Close()
+
+	// 以 base64 一次設定線圈 0-999 (全部為 1)
+	bitmap := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xff}, 125))
+	var written BitmapWriteResult
+	require.NoError(t, callAdminAPI(api.URL, "PUT", "/api/slaves/127.0.0.1/coils",
+		Bitmap{Count: 1000, Encoding: BitmapBase64, Bitmap: bitmap}, &written))
+	assert.Equal(t, 1000, written.Written)
+
+	// 離散輸入 8-15 以 hex 設定
+	require.NoError(t, callAdminAPI(api.URL, "PUT", "/api/slaves/127.0.0.1/discrete_inputs",
+		Bitmap{Address: 8, Bitmap: "0x81"}, &written))
+	assert.Equal(t, 8, written.Written)
+
+	var got Bitmap
+	require.NoError(t, callAdminAPI(api.URL, "GET", "/api/slaves/127.0.0.1/coils?address=996&count=8", nil, &got))
+	assert.Equal(t, Bitmap{Address: 996, Count: 8, Encoding: BitmapHex, Bitmap: "0f"}, got)
+
+	// Master 立即讀到寫入的值
+	handler := modbus.NewTCPClientHandler("127.0.0.1:5513")
+	handler.Timeout = 5 * time.Second
+	require.NoError(t, handler.Connect())
+	defer handler.Close()
+	client := modbus.NewClient(handler)
+
+	coils, err := client.ReadCoils(992, 16)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xff, 0x00}, coils)
+	inputs, err := client.ReadDiscreteInputs(8, 8)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x81}, inputs)
+
+	err = callAdminAPI(api.URL, "PUT", "/api/slaves/127.0.0.1/coils", Bitmap{Address: 9999, Bitmap: "ff"}, nil)
+	assert.Error(t, err, "超出範圍")
+	err = callAdminAPI(api.URL, "PUT", "/api/slaves/127.0.0.1/coils", Bitmap{Bitmap: "zz"}, nil)
+	assert.Error(t, err, "hex 無效")
+	err = callAdminAPI(api.URL, "GET", "/api/slaves/127.0.0.1/coils?encoding=bin", nil, nil)
+	assert.Error(t, err, "編碼無效")
+}
+
+func TestPerSlaveMetricsIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	if runtime.GOOS != "linux" {
+		t.Skip("需要 127.0.0.0/8 全段可連線 (Linux)")
+	}
+
+	// 兩個 Slave 都由引擎依 IP 範圍啟動：shared listener 以 original_dst 分派時不要求 IP 存在於本機，
+	// 沒有 iptables 轉向時依連線的本機位址分派 (Linux 上 lo 接收整個 127.0.0.0/8)
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 2
+	config.Server.Port = 5514
+	config.Server.Listener = ListenerShared
+	config.Server.OriginalDst = true
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.2"}}
+	config.Metrics.PerSlave.MaxSlaves = 1
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+	require.Len(t, engine.ListSlaves(), 2)
+
+	connect := func(ip string) (*modbus.TCPClientHandler, modbus.Client) {
+		slave, ok := engine.GetSlave(net.ParseIP(ip))
+		require.True(t, ok)
+		handler := modbus.NewTCPClientHandler(net.JoinHostPort(ip, "5514"))
+		handler.SlaveId = slave.UnitID
+		handler.Timeout = 5 * time.Second
+		require.NoError(t, handler.Connect())
+		return handler, modbus.NewClient(handler)
+	}
+
+	// 127.0.0.1 一個請求；127.0.0.2 三個請求加一個錯誤
+	first, client := connect("127.0.0.1")
+	defer first.Close()
+	_, err := client.ReadHoldingRegisters(0, 2)
+	require.NoError(t, err)
+
+	second, client := connect("127.0.0.2")
+	defer second.Close()
+	for i := 0; i < 3; i++ {
+		_, err = client.ReadHoldingRegisters(0, 2)
+		require.NoError(t, err)
+	}
+	_, err = client.ReadHoldingRegisters(65000, 100)
+	require.Error(t, err, "位址超出範圍")
+
+	// 統計於回應寫出後才記錄，等待兩個 Slave 都記錄完畢
+	require.Eventually(t, func() bool {
+		total := uint64(0)
+		for _, slave := range engine.ListSlaves() {
+			total += slave.GetStats().RequestCount.Load()
+		}
+		return total == 5
+	}, time.Second, 5*time.Millisecond)
+
+	metrics := NewMetricsCollector(engine, logger)
+	server := httptest.NewServer(http.HandlerFunc(metrics.handleMetrics))
+	defer server.Close()
+
+	scrape := func(accept string) string {
+		req, err := http.NewRequest("GET", server.URL, nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", accept)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	// 每個 Slave 以 slave_ip、unit_id、scenario 標籤輸出，超過上限的 Slave (依 ID 排序排在後面) 不輸出
+	text := scrape("text/plain")
+	labels := `{scenario="normal",slave_ip="127.0.0.1",unit_id="1"}`
+	assert.Contains(t, text, "modbussim_slave_requests_total"+labels+" 1\n")
+	assert.Contains(t, text, "modbussim_slave_errors_total"+labels+" 0\n")
+	assert.Contains(t, text, "modbussim_slave_connections"+labels+" 1\n")
+	assert.Contains(t, text, "modbussim_slave_last_request_age_seconds"+labels)
+	assert.NotContains(t, text, `slave_ip="127.0.0.2"`)
+
+	// 取消上限後第二個 Slave 輸出自己的流量
+	config.Metrics.PerSlave.MaxSlaves = 0
+	text = scrape("text/plain")
+	labels = `{scenario="normal",slave_ip="127.0.0.2",unit_id="2"}`
+	assert.Contains(t, text, "modbussim_slave_requests_total"+labels+" 4\n")
+	assert.Contains(t, text, "modbussim_slave_errors_total"+labels+" 1\n")
+	assert.Contains(t, text, "modbussim_slave_connections"+labels+" 1\n")
+	assert.Contains(t, text, "modbussim_slave_last_request_age_seconds"+labels)
+	assert.Contains(t, text, `modbussim_slave_requests_total{scenario="normal",slave_ip="127.0.0.1",unit_id="1"} 1`+"\n")
+
+	om := scrape("application/openmetrics-text")
+	assert.Contains(t, om, "# TYPE modbussim_slave_requests counter")
+	assert.True(t, strings.HasSuffix(om, "# EOF\n"))
+
+	// 場景生命週期：切換後目前場景為 1，其他場景為 0
+	require.NoError(t, engine.ApplyScenario(ScenarioVoltageSag))
+	text = scrape("text/plain")
+	assert.Contains(t, text, `modbussim_scenario_active{scenario="voltage_sag"} 1`+"\n")
+	assert.Contains(t, text, `modbussim_scenario_active{scenario="normal"} 0`+"\n")
+	assert.Contains(t, text, `modbussim_scenario_transitions_total{scenario="voltage_sag"} 1`+"\n")
+	assert.Contains(t, text, `modbussim_scenario_slaves{scenario="voltage_sag"} 2`+"\n")
+	assert.Contains(t, text, `modbussim_time_in_scenario_seconds_total{scenario="normal"}`)
+	assert.Contains(t, text, `modbussim_slave_scenario_info{scenario="voltage_sag",slave_ip="127.0.0.1",unit_id="1"} 1`+"\n")
+}
+
+func TestHealthThresholdIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5557
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	config.Metrics.MinHealthyPercent = 50
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	metrics := NewMetricsCollector(engine, logger)
+	probe := func(handler http.HandlerFunc) (int, FleetHealth) {
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest("GET", "/", nil))
+		var health FleetHealth
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &health))
+		return recorder.Code, health
+	}
+
+	code, health := probe(metrics.handleReady)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, HealthStatusHealthy, health.Status)
+	assert.Equal(t, 1, health.Configured)
+	assert.Equal(t, 100.0, health.BoundPercent)
+
+	// 唯一的 Slave 離線，健康比例 0% 低於門檻：/ready 回應 503，/health 仍為 200
+	slave := engine.ListSlaves()[0]
+	slave.GoOffline()
+	code, health = probe(metrics.handleReady)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, HealthStatusDegraded, health.Status)
+	assert.Equal(t, 1, health.Failing)
+	assert.Equal(t, 1, health.Offline)
+	assert.Equal(t, 0.0, health.HealthyPercent)
+
+	code, health = probe(metrics.handleHealth)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, HealthStatusDegraded, health.Status)
+
+	require.NoError(t, slave.GoOnline())
+	code, _ = probe(metrics.handleReady)
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestLongCommandIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5515
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	config.Scenario.UpdateInterval = 100 * time.Millisecond
+	params := config.Scenario.Scenarios["long_command"]
+	params.CommandDuration = 500 * time.Millisecond
+	config.Scenario.Scenarios["long_command"] = params
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+	require.NoError(t, engine.ApplyScenario(ScenarioLongCommand))
+
+	handler := modbus.NewTCPClientHandler("127.0.0.1:5515")
+	handler.Timeout = 5 * time.Second
+	require.NoError(t, handler.Connect())
+	defer handler.Close()
+	client := modbus.NewClient(handler)
+
+	exceptionCode := func(err error) byte {
+		var modbusErr *modbus.ModbusError
+		require.ErrorAs(t, err, &modbusErr)
+		return modbusErr.ExceptionCode
+	}
+	status := func() uint16 {
+		results, err := client.ReadHoldingRegisters(100, 1)
+		require.NoError(t, err)
+		return binary.BigEndian.Uint16(results)
+	}
+
+	// 寫入命令暫存器 (40100 = PDU 位址 99)：回應 Acknowledge，寫入生效，狀態為執行中
+	_, err := client.WriteSingleRegister(99, 7)
+	assert.Equal(t, byte(ExceptionCodeAcknowledge), exceptionCode(err))
+	command, err := client.ReadHoldingRegisters(99, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x07}, command)
+	assert.Equal(t, LongCommandInProgress, status())
+
+	// 執行中再次寫入回應忙碌且不生效
+	_, err = client.WriteSingleRegister(99, 8)
+	assert.Equal(t, byte(ExceptionCodeSlaveDeviceBusy), exceptionCode(err))
+	command, err = client.ReadHoldingRegisters(99, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x07}, command)
+
+	assert.Eventually(t, func() bool { return status() == LongCommandComplete }, 3*time.Second, 50*time.Millisecond)
+}
+
+func TestRefreshIntervalIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5516
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	config.Scenario.UpdateInterval = 20 * time.Millisecond
+	config.Slaves.RefreshInterval = 500 * time.Millisecond
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	handler := modbus.NewTCPClientHandler("127.0.0.1:5516")
+	handler.Timeout = 5 * time.Second
+	require.NoError(t, handler.Connect())
+	defer handler.Close()
+	client := modbus.NewClient(handler)
+
+	// 以 20ms 輪詢 1.2 秒：量測值每 500ms 才更新，輪詢間大多讀到相同的值
+	var previous []byte
+	polls, changes := 0, 0
+	for deadline := time.Now().Add(1200 * time.Millisecond); time.Now().Before(deadline); polls++ {
+		results, err := client.ReadHoldingRegisters(0, 3)
+		require.NoError(t, err)
+		if previous != nil && !bytes.Equal(previous, results) {
+			changes++
+		}
+		previous = results
+		time.Sleep(20 * time.Millisecond)
+	}
+	assert.Greater(t, polls, 20)
+	assert.GreaterOrEqual(t, changes, 1)
+	assert.LessOrEqual(t, changes, 3)
+}
+
+func TestBootStormIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5517
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	config.Scenario.UpdateInterval = 50 * time.Millisecond
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	mux := http.NewServeMux()
+	NewAdminAPI(engine, logger).Register(mux)
+	api := httptest.NewServer(mux)
+	defer api.Close()
+
+	online := func() bool {
+		conn, err := net.DialTimeout("tcp", "127.0.0.1:5517", time.Second)
+		if err == nil {
+			conn.Close()
+		}
+		return err == nil
+	}
+	require.True(t, online())
+
+	var status BootStormStatus
+	require.NoError(t, callAdminAPI(api.URL, "POST", "/api/bootstorm",
+		BootStormRequest{Outage: "600ms", Stagger: "300ms"}, &status))
+	assert.Equal(t, BootStormPowerOff, status.Phase)
+	assert.Equal(t, 1, status.Members)
+
+	// 斷電期間場景更新不會讓 Slave 恢復上線，再次觸發回應衝突
+	time.Sleep(300 * time.Millisecond)
+	assert.False(t, online())
+	assert.Error(t, callAdminAPI(api.URL, "POST", "/api/bootstorm", nil, nil), "開機風暴進行中")
+
+	assert.Eventually(t, func() bool { return online() }, 3*time.Second, 50*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		status, _ := engine.BootStormStatus()
+		return status.Phase == BootStormComplete
+	}, time.Second, 20*time.Millisecond)
+
+	require.NoError(t, callAdminAPI(api.URL, "GET", "/api/bootstorm", nil, &status))
+	assert.Equal(t, 1, status.Online)
+	assert.False(t, status.CompletedAt.Before(status.RestoreAt))
+
+	// 立即復電
+	require.NoError(t, callAdminAPI(api.URL, "POST", "/api/bootstorm", BootStormRequest{Outage: "1h"}, &status))
+	require.NoError(t, callAdminAPI(api.URL, "DELETE", "/api/bootstorm", nil, &status))
+	assert.Eventually(t, func() bool { return online() }, 3*time.Second, 50*time.Millisecond)
+}
+
+func TestDecommissionIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5518
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	config.Decommission.Rules = []DecommissionRule{{Name: "short-lived", Targets: []string{"127.0.0.1"}, Lifetime: 1500 * time.Millisecond}}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	// 未符合規則的 Slave 不排定除役，僅能手動除役
+	extra := NewSlave(net.ParseIP("127.0.0.2"), config.Server.Port, config, WithLogger(logger))
+	engine.mu.Lock()
+	engine.slaves[extra.ID] = extra
+	engine.stats.SlaveCount++
+	engine.mu.Unlock()
+
+	mux := http.NewServeMux()
+	NewAdminAPI(engine, logger).Register(mux)
+	api := httptest.NewServer(mux)
+	defer api.Close()
+
+	online := func() bool {
+		conn, err := net.DialTimeout("tcp", "127.0.0.1:5518", time.Second)
+		if err == nil {
+			conn.Close()
+		}
+		return err == nil
+	}
+	require.True(t, online())
+
+	var status DecommissionStatus
+	require.NoError(t, callAdminAPI(api.URL, "GET", "/api/decommissions", nil, &status))
+	require.Len(t, status.Scheduled, 1)
+	assert.Equal(t, "127.0.0.1:5518", status.Scheduled[0].SlaveID)
+	assert.Equal(t, "short-lived", status.Scheduled[0].Rule)
+
+	// 存活時間到期後 listener 關閉並自 Slave 列表移除
+	assert.Eventually(t, func() bool { return !online() }, 4*time.Second, 50*time.Millisecond)
+	_, ok := engine.GetSlaveByID("127.0.0.1:5518")
+	assert.False(t, ok)
+
+	var event DecommissionEvent
+	require.NoError(t, callAdminAPI(api.URL, "POST", "/api/slaves/127.0.0.2/decommission", nil, &event))
+	assert.Equal(t, DecommissionManual, event.Rule)
+	assert.Equal(t, "127.0.0.2", event.IP)
+	assert.Error(t, callAdminAPI(api.URL, "POST", "/api/slaves/127.0.0.2/decommission", nil, nil))
+
+	require.NoError(t, callAdminAPI(api.URL, "GET", "/api/decommissions", nil, &status))
+	assert.Empty(t, status.Scheduled)
+	require.Len(t, status.Decommissioned, 2)
+	assert.Equal(t, "short-lived", status.Decommissioned[0].Rule)
+	assert.False(t, status.Decommissioned[0].IPRemoved)
+
+	stats := engine.Stats()
+	assert.Equal(t, 0, stats.SlaveCount)
+	assert.Equal(t, 2, stats.DecommissionedSlaves)
+}
+
+func TestAuditLogIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Server.Port = 5519
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	audit, err := NewAuditLog(AuditConfig{Path: path}, logger)
+	require.NoError(t, err)
+
+	slave := NewSlave(nil, config.Server.Port, config, WithLogger(logger), WithAuditLog(audit))
+	ctx := context.Background()
+	require.NoError(t, slave.Start(ctx))
+	defer slave.Stop(ctx)
+
+	handler := modbus.NewTCPClientHandler("127.0.0.1:5519")
+	handler.Timeout = 2 * time.Second
+	require.NoError(t, handler.Connect())
+	defer handler.Close()
+	client := modbus.NewClient(handler)
+
+	_, err = client.ReadHoldingRegisters(2, 4)
+	require.NoError(t, err)
+	client.WriteMultipleCoils(3, 10, []byte{0xFF, 0x01})
+	_, err = client.ReadHoldingRegisters(65000, 100)
+	require.Error(t, err)
+
+	// 回應寫出後才記錄
+	var lines []string
+	require.Eventually(t, func() bool {
+		audit.flush()
+		data, err := os.ReadFile(path)
+		lines = strings.Split(strings.TrimSpace(string(data)), "\n")
+		return err == nil && len(lines) == 3
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, audit.Close())
+
+	var records []AuditRecord
+	for _, line := range lines {
+		var rec AuditRecord
+		require.NoError(t, json.Unmarshal([]byte(line), &rec))
+		records = append(records, rec)
+	}
+
+	assert.Equal(t, "127.0.0.1", records[0].ClientIP)
+	assert.NotZero(t, records[0].ClientPort)
+	assert.Equal(t, slave.ID, records[0].SlaveID)
+	assert.Equal(t, uint8(FuncCodeReadHoldingRegisters), records[0].FunctionCode)
+	require.NotNil(t, records[0].Address)
+	assert.Equal(t, uint16(2), *records[0].Address)
+	assert.Equal(t, uint16(4), *records[0].Quantity)
+	assert.Equal(t, uint8(FuncCodeReadHoldingRegisters), records[0].ResponseCode)
+	assert.Zero(t, records[0].ExceptionCode)
+
+	assert.Equal(t, uint8(FuncCodeWriteMultipleCoils), records[1].FunctionCode)
+	assert.Equal(t, []uint16{1, 1, 1, 1, 1, 1, 1, 1, 1, 0}, records[1].Values)
+
+	assert.Equal(t, uint8(FuncCodeReadHoldingRegisters|0x80), records[2].ResponseCode)
+	assert.Equal(t, uint8(ExceptionCodeIllegalDataAddress), records[2].ExceptionCode)
+	assert.Greater(t, records[2].TransactionID, records[0].TransactionID)
+
+	// 超過大小上限時輪替，僅保留 max_backups 個舊檔
+	rotated := filepath.Join(t.TempDir(), "rotate.jsonl")
+	audit, err = NewAuditLog(AuditConfig{Path: rotated, MaxBackups: 2}, logger)
+	require.NoError(t, err)
+	audit.maxSize = 512
+	for i := 0; i < 20; i++ {
+		audit.Record(records[0])
+	}
+	require.NoError(t, audit.Close())
+
+	for _, name := range []string{rotated, rotated + ".1", rotated + ".2"} {
+		info, err := os.Stat(name)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, info.Size(), int64(512))
+	}
+	_, err = os.Stat(rotated + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestMaxConnectionsIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5520
+	config.Server.MaxConnections = 2
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	connect := func() *modbus.TCPClientHandler {
+		handler := modbus.NewTCPClientHandler("127.0.0.1:5520")
+		handler.Timeout = time.Second
+		require.NoError(t, handler.Connect())
+		return handler
+	}
+	first, second := connect(), connect()
+	defer second.Close()
+	for _, handler := range []*modbus.TCPClientHandler{first, second} {
+		_, err := modbus.NewClient(handler).ReadHoldingRegisters(0, 1)
+		require.NoError(t, err)
+	}
+
+	// 第三條連線被接受後立即關閉，既有連線不受影響
+	rejected := connect()
+	_, err := modbus.NewClient(rejected).ReadHoldingRegisters(0, 1)
+	assert.Error(t, err)
+	rejected.Close()
+	_, err = modbus.NewClient(second).ReadHoldingRegisters(0, 1)
+	require.NoError(t, err)
+
+	stats := engine.Stats()
+	assert.Equal(t, 2, stats.ActiveConnections)
+	assert.Equal(t, uint64(1), stats.RejectedConnections)
+
+	metrics := NewMetricsCollector(engine, logger)
+	metrics.collect()
+	server := httptest.NewServer(http.HandlerFunc(metrics.handleMetrics))
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Contains(t, string(body), "modbussim_connections_active 2\n")
+	assert.Contains(t, string(body), "modbussim_connections_rejected_total 1\n")
+
+	// 釋放一條連線後可再連入
+	first.Close()
+	require.Eventually(t, func() bool { return engine.Stats().ActiveConnections == 1 }, time.Second, 10*time.Millisecond)
+	third := connect()
+	defer third.Close()
+	_, err = modbus.NewClient(third).ReadHoldingRegisters(0, 1)
+	require.NoError(t, err)
+}
+
+func TestInterfaceBindingIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	if runtime.GOOS != "linux" {
+		t.Skip("SO_BINDTODEVICE 僅在 Linux 上支援")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5521
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1", Interface: "lo"}}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	slaves := engine.ListSlaves()
+	require.Len(t, slaves, 1)
+	assert.Equal(t, "lo", slaves[0].iface)
+
+	handler := modbus.NewTCPClientHandler("127.0.0.1:5521")
+	handler.Timeout = time.Second
+	require.NoError(t, handler.Connect())
+	defer handler.Close()
+
+	_, err := modbus.NewClient(handler).ReadHoldingRegisters(0, 1)
+	require.NoError(t, err)
+}
+
+func TestConnectionTimeoutIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5522
+	config.Server.IdleTimeout = 300 * time.Millisecond
+	config.Server.ReadTimeout = 200 * time.Millisecond
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	// 閒置期間內的請求不受影響
+	handler := modbus.NewTCPClientHandler("127.0.0.1:5522")
+	handler.Timeout = time.Second
+	require.NoError(t, handler.Connect())
+	defer handler.Close()
+	client := modbus.NewClient(handler)
+	for i := 0; i < 3; i++ {
+		_, err := client.ReadHoldingRegisters(0, 1)
+		require.NoError(t, err)
+		time.Sleep(150 * time.Millisecond)
+	}
+
+	// 閒置超過 idle_timeout 後連線被回收
+	require.Eventually(t, func() bool { return engine.Stats().ActiveConnections == 0 },
+		2*time.Second, 50*time.Millisecond)
+
+	// 只送出半個訊框時以 read_timeout 關閉
+	conn, err := net.Dial("tcp", "127.0.0.1:5522")
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte{0x00, 0x01, 0x00})
+	require.NoError(t, err)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	start := time.Now()
+	_, err = conn.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Less(t, time.Since(start), 300*time.Millisecond, "應以 read_timeout 而非 idle_timeout 關閉")
+
+	assert.Equal(t, uint64(2), engine.Stats().TimedOutConnections)
+}
+
+func TestSharedListenerIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	if runtime.GOOS != "linux" {
+		t.Skip("需要 127.0.0.0/8 全段可連線 (Linux)")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5523
+	config.Server.Listener = ListenerShared
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	// 第二個 Slave 使用 127.0.0.2 (未配置於本機，但 Linux 上 lo 接收整個 127.0.0.0/8)
+	second, err := engine.startSlave(ctx, net.ParseIP("127.0.0.2"), 1)
+	require.NoError(t, err)
+	engine.mu.Lock()
+	engine.slaves[second.ID] = second
+	engine.stats.SlaveCount++
+	engine.mu.Unlock()
+
+	connect := func(ip string) *modbus.TCPClientHandler {
+		handler := modbus.NewTCPClientHandler(ip + ":5523")
+		handler.Timeout = time.Second
+		require.NoError(t, handler.Connect())
+		return handler
+	}
+	first := connect("127.0.0.1")
+	defer first.Close()
+	other := connect("127.0.0.2")
+	defer other.Close()
+
+	// 依目的 IP 分派：寫入其中一個 Slave 不影響另一個
+	_, err = modbus.NewClient(first).WriteSingleRegister(100, 1234)
+	require.NoError(t, err)
+	results, err := modbus.NewClient(first).ReadHoldingRegisters(100, 1)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(1234), binary.BigEndian.Uint16(results))
+	results, err = modbus.NewClient(other).ReadHoldingRegisters(100, 1)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(0), binary.BigEndian.Uint16(results))
+
+	slaves := engine.ListSlaves()
+	for _, slave := range slaves {
+		assert.Equal(t, 1, slave.ConnCount(), slave.ID)
+	}
+
+	// 離線的 Slave 無法拒絕連線，改為接受後立即關閉
+	second.GoOffline()
+	conn, err := net.Dial("tcp", "127.0.0.2:5523")
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, uint64(1), engine.Stats().UnroutedConnections)
+
+	// 恢復上線後不需重新綁定即可連線
+	require.NoError(t, second.GoOnline())
+	again := connect("127.0.0.2")
+	defer again.Close()
+	_, err = modbus.NewClient(again).ReadHoldingRegisters(100, 1)
+	require.NoError(t, err)
+}
+
+func TestDiagnosticsDeviceIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5524
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	config.Diagnostics.Enabled = true
+	config.Diagnostics.IP = "127.0.0.1"
+	config.Diagnostics.Port = 5525
+	config.Diagnostics.Interval = 100 * time.Millisecond
+	config.Diagnostics.TickLagAlarm = 0
+	config.Diagnostics.ErrorRateAlarm = 0.1
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	// 自我監控設備不列入 Slave 列表
+	assert.Len(t, engine.ListSlaves(), 1)
+
+	diag := modbus.NewTCPClientHandler("127.0.0.1:5525")
+	diag.Timeout = time.Second
+	diag.SlaveId = DefaultDiagnosticsUnitID
+	require.NoError(t, diag.Connect())
+	defer diag.Close()
+	client := modbus.NewClient(diag)
+
+	results, err := client.ReadInputRegisters(0, diagnosticsInputLength)
+	require.NoError(t, err)
+	reg := func(address int) uint16 { return binary.BigEndian.Uint16(results[address*2:]) }
+	assert.Equal(t, uint16(1), reg(DiagRegSlaves))
+	assert.Equal(t, uint16(1), reg(DiagRegActiveSlaves))
+	assert.NotZero(t, reg(DiagRegGoroutines+1))
+	assert.Equal(t, uint16(0), reg(DiagRegAlarms))
+
+	// 唯讀設備
+	_, err = client.WriteSingleRegister(0, 1)
+	assert.Error(t, err)
+
+	// 模擬的 Slave 大量回應例外後觸發錯誤率告警
+	handler := modbus.NewTCPClientHandler("127.0.0.1:5524")
+	handler.Timeout = time.Second
+	require.NoError(t, handler.Connect())
+	defer handler.Close()
+	for i := 0; i < 5; i++ {
+		_, err := modbus.NewClient(handler).ReadHoldingRegisters(65000, 10)
+		require.Error(t, err)
+	}
+
+	require.Eventually(t, func() bool {
+		bits, err := client.ReadDiscreteInputs(DiagAlarmErrorRate, 1)
+		return err == nil && bits[0]&1 == 1
+	}, 2*time.Second, 50*time.Millisecond)
+}
+
+func TestRequestHandlerIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5526
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	config.Scenario.UpdateInterval = 100 * time.Millisecond
+	jitter := config.Scenario.Scenarios["jitter"]
+	jitter.JitterMin, jitter.JitterMax = 300*time.Millisecond, 300*time.Millisecond
+	config.Scenario.Scenarios["jitter"] = jitter
+	loss := config.Scenario.Scenarios["packet_loss"]
+	loss.PacketLossRate = 1
+	config.Scenario.Scenarios["packet_loss"] = loss
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	handler := modbus.NewTCPClientHandler("127.0.0.1:5526")
+	handler.Timeout = time.Second
+	require.NoError(t, handler.Connect())
+	defer handler.Close()
+	client := modbus.NewClient(handler)
+
+	exceptionCode := func(err error) byte {
+		var modbusErr *modbus.ModbusError
+		require.ErrorAs(t, err, &modbusErr)
+		return modbusErr.ExceptionCode
+	}
+
+	// 不支援的功能碼
+	_, err := client.ReadFIFOQueue(0)
+	assert.Equal(t, byte(ExceptionCodeIllegalFunction), exceptionCode(err))
+
+	// 唯讀暫存器 (40001 LineVoltage = PDU 位址 0) 拒絕寫入
+	_, err = client.WriteSingleRegister(0, 1)
+	assert.Equal(t, byte(ExceptionCodeIllegalDataAddress), exceptionCode(err))
+
+	// 線圈寫入回寫至暫存器映射表，更新週期之後仍可讀回
+	_, err = client.WriteSingleCoil(5, 0xFF00)
+	require.NoError(t, err)
+	time.Sleep(300 * time.Millisecond)
+	results, err := client.ReadCoils(5, 1)
+	require.NoError(t, err)
+	assert.Equal(t, byte(1), results[0]&1)
+
+	// 延遲抖動作用於實際連線
+	require.NoError(t, engine.ApplyScenario(ScenarioJitter))
+	start := time.Now()
+	_, err = client.ReadHoldingRegisters(0, 1)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+
+	// 封包丟失：請求不回應
+	require.NoError(t, engine.ApplyScenario(ScenarioPacketLoss))
+	_, err = client.ReadHoldingRegisters(0, 1)
+	assert.Error(t, err)
+
+	require.NoError(t, engine.ApplyScenario(ScenarioNormal))
+	handler.Close()
+	require.NoError(t, handler.Connect())
+	_, err = client.ReadHoldingRegisters(0, 1)
+	assert.NoError(t, err)
+}
+
+func TestScenarioUpdaterIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5527
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	config.Scenario.UpdateInterval = 100 * time.Millisecond
+	config.Scenario.Workers = 2
+	config.Scenario.BatchSize = 1
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	// 由集中式更新器更新，量測值隨週期變化並同步到對外提供的暫存器
+	handler := modbus.NewTCPClientHandler("127.0.0.1:5527")
+	handler.Timeout = time.Second
+	require.NoError(t, handler.Connect())
+	defer handler.Close()
+	client := modbus.NewClient(handler)
+
+	first, err := client.ReadHoldingRegisters(0, 2)
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		results, err := client.ReadHoldingRegisters(0, 2)
+		return err == nil && !bytes.Equal(results, first)
+	}, 2*time.Second, 50*time.Millisecond)
+
+	// 停止後更新器隨引擎關閉
+	require.NoError(t, engine.Stop(ctx))
+	assert.Nil(t, engine.updater)
+}
+
+func TestUnitMultiplexingIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Slaves.Units = []UnitConfig{{UnitID: 1}, {UnitID: 2, Profile: ProfileThreePhase}}
+	config.Server.Port = 5528
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	read := func(unitID byte) ([]byte, error) {
+		handler := modbus.NewTCPClientHandler("127.0.0.1:5528")
+		handler.Timeout = 500 * time.Millisecond
+		handler.SlaveId = unitID
+		if err := handler.Connect(); err != nil {
+			return nil, err
+		}
+		defer handler.Close()
+		return modbus.NewClient(handler).ReadHoldingRegisters(0, 20)
+	}
+
+	// 同一個端點的兩個 Unit ID 各自回應不同設定檔的暫存器
+	first, err := read(1)
+	require.NoError(t, err)
+	second, err := read(2)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+
+	// 未配置的 Unit ID 不回應
+	_, err = read(3)
+	assert.Error(t, err)
+}
+
+func TestRampIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Slaves.RampUpRate = 5
+	config.Slaves.RampDownRate = 5
+	config.Server.Port = 5529
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx), "逐步上線時引擎應立即啟動完成")
+
+	// Slave 於背景上線，完成後不再有待上線的數量
+	require.Eventually(t, func() bool { return len(engine.ListSlaves()) == 1 }, 5*time.Second, 50*time.Millisecond)
+	assert.Equal(t, 0, engine.Stats().RampPending)
+	assert.Equal(t, 1, engine.Stats().SlaveCount)
+
+	conn, err := net.DialTimeout("tcp", "127.0.0.1:5529", time.Second)
+	require.NoError(t, err)
+	conn.Close()
+
+	require.NoError(t, engine.Stop(ctx))
+	_, err = net.DialTimeout("tcp", "127.0.0.1:5529", 200*time.Millisecond)
+	assert.Error(t, err)
+}
+
+func TestAddressingIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	RegisterDeviceProfile(&DeviceProfile{
+		Name:       "plc_addressed_meter",
+		Addressing: AddressModePLC,
+		Registers: []RegisterDefinition{
+			{Address: 1, Name: "Voltage", DataType: "uint16", Scale: 10, DefaultValue: 230, Unit: "V"},
+			{Address: 2, Name: "Setpoint", DataType: "uint16", Scale: 1, Writable: true},
+		},
+	})
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Slaves.Profile = "plc_addressed_meter"
+	config.Server.Port = 5530
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	handler := modbus.NewTCPClientHandler("127.0.0.1:5530")
+	handler.Timeout = time.Second
+	require.NoError(t, handler.Connect())
+	defer handler.Close()
+	client := modbus.NewClient(handler)
+
+	// PLC 位址 1 為 PDU 位址 0
+	results, err := client.ReadHoldingRegisters(0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x08, 0xFC, 0x00, 0x00}, results)
+
+	// 唯讀與可寫入的判斷依相同的換算
+	_, err = client.WriteSingleRegister(0, 1)
+	assert.Error(t, err)
+	_, err = client.WriteSingleRegister(1, 42)
+	require.NoError(t, err)
+
+	slave := engine.ListSlaves()[0]
+	value, err := slave.Registers().GetScaledValue(2)
+	require.NoError(t, err)
+	assert.Equal(t, 42.0, value)
+}
+
+func TestWriteHookIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5531
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	closed := uint16(0)
+	config.WriteHooks = []WriteHookRule{
+		{
+			// 寫入跳脫線圈 10 後，斷路器狀態 (離散輸入 10) 延遲切換，並將跳脫線圈復歸
+			Name: "breaker", Table: "coils", Address: 10, Delay: 300 * time.Millisecond,
+			Actions: []WriteHookAction{
+				{Table: "discrete_inputs", Address: 10},
+				{Table: "coils", Address: 10, Value: &closed},
+			},
+		},
+		{
+			// 設定值立即回授到輸入暫存器
+			Name: "feedback", Table: "holding_registers", Address: 40501,
+			Actions: []WriteHookAction{{Table: "input_registers", Address: 500}},
+		},
+	}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	handler := modbus.NewTCPClientHandler("127.0.0.1:5531")
+	handler.Timeout = time.Second
+	require.NoError(t, handler.Connect())
+	defer handler.Close()
+	client := modbus.NewClient(handler)
+
+	_, err := client.WriteSingleRegister(500, 1234)
+	require.NoError(t, err)
+	results, err := client.ReadInputRegisters(500, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x04, 0xD2}, results, "未設定延遲的回呼應在回應前完成")
+
+	_, err = client.WriteSingleCoil(10, 0xFF00)
+	require.NoError(t, err)
+	results, err = client.ReadDiscreteInputs(10, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x00}, results, "延遲期間斷路器狀態不變")
+
+	require.Eventually(t, func() bool {
+		results, err := client.ReadDiscreteInputs(10, 1)
+		return err == nil && results[0] == 0x01
+	}, 2*time.Second, 50*time.Millisecond)
+	results, err = client.ReadCoils(10, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x00}, results)
+}
+
+func TestSnapshotIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5532
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+
+	slave := engine.ListSlaves()[0]
+	require.NoError(t, slave.Registers().SetScaledValue(40004, 4321))
+	require.NoError(t, slave.Registers().WriteHoldingRegister(500, 77))
+	snap := engine.Snapshot()
+	require.NoError(t, engine.Stop(ctx))
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	require.NoError(t, SaveSnapshot(path, snap))
+	loaded, err := LoadSnapshot(path)
+	require.NoError(t, err)
+
+	// 逐步上線時 Slave 尚未啟動，於啟動時還原
+	config.Slaves.RampUpRate = 5
+	engine = NewEngine(config, logger)
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+	result, err := engine.RestoreSnapshot(loaded)
+	require.NoError(t, err)
+	assert.Equal(t, RestoreResult{Scenario: "normal", Pending: 1}, result)
+
+	require.Eventually(t, func() bool { return len(engine.ListSlaves()) == 1 }, 5*time.Second, 50*time.Millisecond)
+	handler := modbus.NewTCPClientHandler("127.0.0.1:5532")
+	handler.Timeout = time.Second
+	require.NoError(t, handler.Connect())
+	defer handler.Close()
+	client := modbus.NewClient(handler)
+
+	results, err := client.ReadHoldingRegisters(500, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x4D}, results)
+	energy, err := engine.ListSlaves()[0].Registers().GetScaledValue(40004)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, energy, 4321.0, "電能自快照接續累積")
+}
+
+func TestPersistenceIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5533
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	config.Persistence.Enabled = true
+	config.Persistence.Path = t.TempDir()
+	config.Persistence.Interval = 100 * time.Millisecond
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+
+	slave := engine.ListSlaves()[0]
+	require.NoError(t, slave.Registers().WriteHoldingRegister(500, 88))
+	require.NoError(t, engine.ApplyScenario(ScenarioJitter))
+
+	// 等待定期 checkpoint 寫入 (不經過停止前的 checkpoint，模擬異常終止)
+	store, err := NewStateStore(config.Persistence)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		snap, err := store.Load()
+		if err != nil || snap == nil || snap.Scenario != "jitter" || len(snap.Slaves) != 1 {
+			return false
+		}
+		blocks := snap.Slaves[0].Devices[0].Registers.HoldingRegisters
+		for _, block := range blocks {
+			if block.Start <= 500 && 500 < block.Start+len(block.Values) {
+				return block.Values[500-block.Start] == 88
+			}
+		}
+		return false
+	}, 5*time.Second, 50*time.Millisecond)
+	require.NoError(t, engine.Stop(ctx))
+
+	engine = NewEngine(config, logger)
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+	assert.Equal(t, ScenarioJitter, engine.GetScenario())
+
+	handler := modbus.NewTCPClientHandler("127.0.0.1:5533")
+	handler.Timeout = time.Second
+	require.NoError(t, handler.Connect())
+	defer handler.Close()
+	client := modbus.NewClient(handler)
+
+	results, err := client.ReadHoldingRegisters(500, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x58}, results)
+}
+
+func TestLogPersistenceIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5567
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	config.Persistence.Enabled = true
+	config.Persistence.Driver = PersistenceDriverLog
+	config.Persistence.Path = filepath.Join(t.TempDir(), "state.log")
+	config.Persistence.Interval = 100 * time.Millisecond
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	require.NoError(t, engine.ListSlaves()[0].Registers().WriteHoldingRegister(500, 77))
+	require.NoError(t, engine.ApplyScenario(ScenarioJitter))
+	time.Sleep(300 * time.Millisecond) // 經過數次定期 checkpoint
+	require.NoError(t, engine.Stop(ctx))
+
+	engine = NewEngine(config, logger)
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+	assert.Equal(t, ScenarioJitter, engine.GetScenario())
+	value, err := engine.ListSlaves()[0].Registers().ReadHoldingRegisters(500, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{77}, value)
+}
+
+func TestBulkRegisterWriteIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5556
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	config.Scenario.UpdateInterval = time.Hour
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	// 另外加入兩個 (未監聽的) Slave
+	engine.mu.Lock()
+	for _, ip := range []string{"127.0.0.2", "127.0.0.3"} {
+		extra := NewSlave(net.ParseIP(ip), config.Server.Port, config, WithLogger(logger))
+		engine.slaves[extra.ID] = extra
+	}
+	engine.mu.Unlock()
+
+	mux := http.NewServeMux()
+	NewAdminAPI(engine, logger).Register(mux)
+	api := httptest.NewServer(mux)
+	defer api.Close()
+
+	frequency := func(ip string) float64 {
+		slave, ok := engine.GetSlave(net.ParseIP(ip))
+		require.True(t, ok)
+		value, err := slave.Registers().GetScaledValue(40003)
+		require.NoError(t, err)
+		return value
+	}
+	before := frequency("127.0.0.3")
+
+	var result RegisterBulkWriteResult
+	require.NoError(t, callAdminAPI(api.URL, "PUT", "/api/registers/frequency",
+		RegisterBulkWriteRequest{Value: 59.8, Targets: []string{"127.0.0.1", "127.0.0.2/32"}}, &result))
+	assert.Equal(t, []string{"127.0.0.1:5556", "127.0.0.2:5556"}, result.Written)
+	assert.InDelta(t, 59.8, frequency("127.0.0.1"), 0.01)
+	assert.InDelta(t, 59.8, frequency("127.0.0.2"), 0.01)
+	assert.Equal(t, before, frequency("127.0.0.3"), "不符合 targets 的 Slave 不寫入")
+
+	// 經過一次場景更新後仍以寫入的值為中心波動
+	for _, ip := range []string{"127.0.0.1", "127.0.0.2"} {
+		slave, ok := engine.GetSlave(net.ParseIP(ip))
+		require.True(t, ok)
+		slave.updateByScenario()
+		assert.InDelta(t, 59.8, frequency(ip), 0.05)
+	}
+
+	// 任一 Slave 的值無效時全部不寫入
+	err := callAdminAPI(api.URL, "PUT", "/api/registers/Frequency", RegisterBulkWriteRequest{Value: 900.0}, nil)
+	assert.Error(t, err, "縮放後超出 uint16 範圍")
+	assert.Equal(t, before, frequency("127.0.0.3"))
+
+	err = callAdminAPI(api.URL, "PUT", "/api/registers/NoSuchRegister", RegisterBulkWriteRequest{Value: 1.0}, nil)
+	assert.Error(t, err)
+}
+
+func TestMeasuredRegisterWriteIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5566
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	config.Scenario.UpdateInterval = time.Hour
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	mux := http.NewServeMux()
+	NewAdminAPI(engine, logger).Register(mux)
+	api := httptest.NewServer(mux)
+	defer api.Close()
+
+	slave := engine.ListSlaves()[0]
+	require.NoError(t, callAdminAPI(api.URL, "PUT", "/api/slaves/127.0.0.1/registers/40003",
+		RegisterWriteRequest{Value: 59.8}, nil))
+	require.NoError(t, callAdminAPI(api.URL, "PUT", "/api/slaves/127.0.0.1/registers/40001",
+		RegisterWriteRequest{Value: 200.0}, nil))
+
+	// 經過一次場景更新後仍以寫入的值為中心波動
+	slave.updateByScenario()
+	frequency, err := slave.Registers().GetScaledValue(40003)
+	require.NoError(t, err)
+	assert.InDelta(t, 59.8, frequency, 0.05)
+	voltage, err := slave.Registers().GetScaledValue(40001)
+	require.NoError(t, err)
+	assert.InDelta(t, 200.0, voltage, 1.5)
+}
+
+func TestDashboardIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5534
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	mux := http.NewServeMux()
+	NewAdminAPI(engine, logger).Register(mux)
+	RegisterDashboard(mux)
+	api := httptest.NewServer(mux)
+	defer api.Close()
+
+	resp, err := http.Get(api.URL + "/")
+	require.NoError(t, err)
+	page, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(page), "/api/slaves", "根路徑轉址至儀表板")
+
+	var slaves []SlaveSummary
+	require.NoError(t, callAdminAPI(api.URL, "GET", "/api/slaves", nil, &slaves))
+	require.Len(t, slaves, 1)
+	assert.Equal(t, "running", slaves[0].State)
+	assert.Equal(t, "normal", slaves[0].Scenario)
+
+	// 已定義暫存器以工程值寫入
+	var registers []RegisterValue
+	require.NoError(t, callAdminAPI(api.URL, "GET", "/api/slaves/127.0.0.1/registers", nil, &registers))
+	require.NotEmpty(t, registers)
+	require.NoError(t, callAdminAPI(api.URL, "PUT", "/api/slaves/127.0.0.1/registers/40004",
+		RegisterWriteRequest{Value: 1234.0}, nil))
+	energy, err := engine.ListSlaves()[0].Registers().GetScaledValue(40004)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, energy, 1234.0)
+
+	// 縮放後超出資料類型範圍的值不寫入 (LineVoltage 為 uint16，scale 10)
+	err = callAdminAPI(api.URL, "PUT", "/api/slaves/127.0.0.1/registers/40001", RegisterWriteRequest{Value: 7000.0}, nil)
+	assert.Error(t, err, "超出 uint16 範圍")
+
+	// 原始表格寫入後 Master 立即讀到
+	var table RegisterTable
+	require.NoError(t, callAdminAPI(api.URL, "PUT", "/api/slaves/127.0.0.1/tables/holding_registers",
+		RegisterTable{Address: 500, Values: []uint16{7, 8}}, &table))
+	require.NoError(t, callAdminAPI(api.URL, "GET", "/api/slaves/127.0.0.1/tables/holding_registers?address=500&count=2", nil, &table))
+	assert.Equal(t, []uint16{7, 8}, table.Values)
+
+	handler := modbus.NewTCPClientHandler("127.0.0.1:5534")
+	handler.Timeout = 5 * time.Second
+	require.NoError(t, handler.Connect())
+	defer handler.Close()
+	results, err := modbus.NewClient(handler).ReadHoldingRegisters(500, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x07, 0x00, 0x08}, results)
+
+	err = callAdminAPI(api.URL, "PUT", "/api/slaves/127.0.0.1/tables/holding_registers",
+		RegisterTable{Address: 65535, Values: []uint16{1, 2}}, nil)
+	assert.Error(t, err, "超出範圍")
+
+	// 套用場景
+	var status ScenarioStatus
+	require.NoError(t, callAdminAPI(api.URL, "GET", "/api/scenario", nil, &status))
+	assert.Contains(t, status.Available, "voltage_sag")
+	require.NoError(t, callAdminAPI(api.URL, "PUT", "/api/scenario", ScenarioRequest{Scenario: "voltage_sag"}, &status))
+	assert.Equal(t, ScenarioVoltageSag, engine.GetScenario())
+	assert.Error(t, callAdminAPI(api.URL, "PUT", "/api/scenario", ScenarioRequest{Scenario: "meltdown"}, nil))
+}
+
+func TestSparkplugIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	// 模擬 broker：確認 CONNECT 後轉交收到的 PUBLISH
+	broker, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer broker.Close()
+
+	type message struct {
+		topic   string
+		payload *sparkplugPayload
+	}
+	messages := make(chan message, 100)
+	connects := make(chan mqttConnectOptions, 1)
+	conns := make(chan net.Conn, 1)
+	go func() {
+		conn, err := broker.Accept()
+		if err != nil {
+			return
+		}
+		conns <- conn
+		reader := bufio.NewReader(conn)
+		packet, err := readMQTTPacket(reader)
+		if err != nil || packet.Type != mqttConnect {
+			return
+		}
+		// 協定名稱 (6) + 版本 (1) + flags (1) + keep alive (2) 之後為 client ID 與遺囑主題
+		clientID, rest, _ := consumeMQTTString(packet.Body[10:])
+		willTopic, _, _ := consumeMQTTString(rest)
+		connects <- mqttConnectOptions{ClientID: clientID, WillTopic: willTopic, WillQoS: packet.Body[7] >> 3 & 0x03}
+		conn.Write(encodeMQTTPacket(mqttConnack, 0, []byte{0, 0}))
+		for {
+			packet, err := readMQTTPacket(reader)
+			if err != nil {
+				close(messages)
+				return
+			}
+			if packet.Type != mqttPublish {
+				continue
+			}
+			topic, data, _, _ := decodeMQTTPublish(packet)
+			payload, err := unmarshalSparkplugPayload(data)
+			if !assert.NoError(t, err) {
+				continue
+			}
+			messages <- message{topic, payload}
+		}
+	}()
+	next := func(topic string) *sparkplugPayload {
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case m, ok := <-messages:
+				require.True(t, ok, "等待 %s 時連線中斷", topic)
+				if m.topic == topic {
+					return m.payload
+				}
+			case <-timeout:
+				t.Fatalf("未收到 %s", topic)
+			}
+		}
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5535
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	config.MQTT.Enabled = true
+	config.MQTT.Broker = "tcp://" + broker.Addr().String()
+	config.MQTT.EdgeNodeID = "sim1"
+	config.MQTT.Interval = 100 * time.Millisecond
+	config.MQTT.Registers = []string{"LineVoltage", "TotalEnergy"}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	stopped := false
+	defer func() {
+		if !stopped {
+			engine.Stop(ctx)
+		}
+	}()
+
+	connect := <-connects
+	assert.Equal(t, "modbussim-sim1", connect.ClientID)
+	assert.Equal(t, "spBv1.0/modbussim/NDEATH/sim1", connect.WillTopic)
+	assert.Equal(t, byte(1), connect.WillQoS)
+
+	birth := next("spBv1.0/modbussim/NBIRTH/sim1")
+	require.NotNil(t, birth.Seq)
+	assert.Equal(t, uint64(0), *birth.Seq)
+	assert.Equal(t, sparkplugBdSeq, birth.Metrics[0].Name)
+
+	device := next("spBv1.0/modbussim/DBIRTH/sim1/127.0.0.1")
+	require.Len(t, device.Metrics, 2)
+	assert.Equal(t, "LineVoltage", device.Metrics[0].Name)
+	assert.Equal(t, uint64(40001), device.Metrics[0].Alias)
+	assert.Equal(t, uint32(sparkplugDouble), device.Metrics[0].Datatype)
+	assert.Equal(t, "TotalEnergy", device.Metrics[1].Name)
+
+	// 變化的值以 DDATA (alias) 發布
+	require.NoError(t, engine.ListSlaves()[0].Registers().SetScaledValue(40004, 4321))
+	for found := false; !found; {
+		for _, m := range next("spBv1.0/modbussim/DDATA/sim1/127.0.0.1").Metrics {
+			if m.Alias == 40004 && m.Value.(float64) >= 4321 {
+				found = true
+			}
+		}
+	}
+
+	// 主機要求重新出生
+	conn := <-conns
+	command := &sparkplugPayload{Metrics: []sparkplugMetric{{Name: sparkplugRebirth, Datatype: sparkplugBoolean, Value: true}}}
+	conn.Write(encodeMQTTPacket(mqttPublish, 0, append(appendMQTTString(nil, "spBv1.0/modbussim/NCMD/sim1"), command.marshal()...)))
+	birth = next("spBv1.0/modbussim/NBIRTH/sim1")
+	assert.Equal(t, uint64(0), *birth.Seq)
+	next("spBv1.0/modbussim/DBIRTH/sim1/127.0.0.1")
+
+	// 停止時發布 DDEATH 與 NDEATH
+	require.NoError(t, engine.Stop(ctx))
+	stopped = true
+	next("spBv1.0/modbussim/DDEATH/sim1/127.0.0.1")
+	death := next("spBv1.0/modbussim/NDEATH/sim1")
+	assert.Equal(t, birth.Metrics[0].Value, death.Metrics[0].Value, "bdSeq 與 NBIRTH 相同")
+}
+
+func TestOPCUAIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5536
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	config.OPCUA.Enabled = true
+	config.OPCUA.Listen = "127.0.0.1:0"
+	config.OPCUA.Writable = true
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+	slave := engine.ListSlaves()[0]
+	require.NoError(t, slave.Registers().DefineRegister(40100, "Setpoint", DataTypeUint16, 10, "V", true))
+	require.NotNil(t, engine.OPCUAAddr())
+
+	conn, err := net.Dial("tcp", engine.OPCUAAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	reader := bufio.NewReader(conn)
+
+	send := func(messageType string, body []byte) {
+		header := make([]byte, 8)
+		copy(header, messageType+"F")
+		binary.LittleEndian.PutUint32(header[4:], uint32(8+len(body)))
+		_, err := conn.Write(append(header, body...))
+		require.NoError(t, err)
+	}
+	receive := func() (string, []byte) {
+		header := make([]byte, 8)
+		_, err := io.ReadFull(reader, header)
+		require.NoError(t, err)
+		body := make([]byte, binary.LittleEndian.Uint32(header[4:])-8)
+		_, err = io.ReadFull(reader, body)
+		require.NoError(t, err)
+		return string(header[:4]), body
+	}
+
+	// HEL/ACK (以最小的接收緩衝區確認回應分段)
+	hello := &opcuaWriter{}
+	for _, v := range []uint32{0, opcuaMinBufferSize, opcuaMinBufferSize, 0, 0} {
+		hello.uint32(v)
+	}
+	hello.string("opc.tcp://localhost:4840")
+	send("HEL", hello.b)
+	messageType, body := receive()
+	require.Equal(t, "ACKF", messageType)
+	ack := &opcuaReader{b: body}
+	ack.uint32()
+	ack.uint32()
+	assert.Equal(t, uint32(opcuaMinBufferSize), ack.uint32(), "伺服器的傳送緩衝區不超過 client 的接收緩衝區")
+
+	requestHeader := func(w *opcuaWriter, token opcuaNodeID) {
+		w.nodeID(token)
+		w.dateTime(time.Now())
+		w.uint32(1)
+		w.uint32(0)
+		w.string("")
+		w.uint32(0)
+		w.nodeID(opcuaNS0(0))
+		w.byte(0)
+	}
+
+	// OpenSecureChannel
+	open := &opcuaWriter{}
+	open.uint32(0)
+	open.string(opcuaSecurityPolicyNone)
+	open.bytes(nil)
+	open.bytes(nil)
+	open.uint32(1)
+	open.uint32(1)
+	open.nodeID(opcuaNS0(opcuaOpenSecureChannelRequest))
+	requestHeader(open, opcuaNS0(0))
+	open.uint32(0)
+	open.uint32(0)
+	open.uint32(opcuaMessageSecurityModeNone)
+	open.bytes(nil)
+	open.uint32(600000)
+	send("OPN", open.b)
+	messageType, body = receive()
+	require.Equal(t, "OPNF", messageType)
+	channelID := binary.LittleEndian.Uint32(body)
+	require.NotZero(t, channelID)
+
+	// call 送出請求並組合分段的回應，回傳回應類型、狀態與內容
+	requestID := uint32(1)
+	call := func(typeID uint32, token opcuaNodeID, build func(w *opcuaWriter)) (opcuaNodeID, uint32, *opcuaReader) {
+		requestID++
+		w := &opcuaWriter{}
+		w.uint32(channelID)
+		w.uint32(1)
+		w.uint32(requestID)
+		w.uint32(requestID)
+		w.nodeID(opcuaNS0(typeID))
+		requestHeader(w, token)
+		build(w)
+		send("MSG", w.b)
+
+		var payload []byte
+		for {
+			messageType, body := receive()
+			require.Equal(t, "MSG", messageType[:3])
+			require.Equal(t, requestID, binary.LittleEndian.Uint32(body[12:]))
+			payload = append(payload, body[16:]...)
+			if messageType[3] == 'F' {
+				break
+			}
+		}
+		r := &opcuaReader{b: payload}
+		responseType := r.nodeID()
+		r.int64()
+		r.uint32()
+		status := r.uint32()
+		r.byte()
+		r.arrayLength()
+		r.extensionObject()
+		require.NoError(t, r.err)
+		return responseType, status, r
+	}
+	browse := func(token, node opcuaNodeID) map[string]opcuaNodeID {
+		responseType, status, r := call(opcuaBrowseRequest, token, func(w *opcuaWriter) {
+			w.nodeID(opcuaNS0(0))
+			w.int64(0)
+			w.uint32(0)
+			w.uint32(0)
+			w.int32(1)
+			w.nodeID(node)
+			w.uint32(opcuaBrowseDirectionForward)
+			w.nodeID(opcuaNS0(opcuaHierarchical))
+			w.boolean(true)
+			w.uint32(0)
+			w.uint32(0x3F)
+		})
+		require.Equal(t, opcuaNS0(opcuaBrowseResponse), responseType)
+		require.Equal(t, opcuaGood, status)
+		require.Equal(t, 1, r.arrayLength())
+		require.Equal(t, opcuaGood, r.uint32())
+		r.bytes()
+		result := make(map[string]opcuaNodeID)
+		for n := r.arrayLength(); n > 0; n-- {
+			r.nodeID()
+			r.boolean()
+			target := r.nodeID()
+			name := r.qualifiedName()
+			r.byte()
+			r.string()
+			r.uint32()
+			r.nodeID()
+			result[name.Name] = target
+		}
+		require.NoError(t, r.err)
+		return result
+	}
+
+	// 未建立 session 的請求以 ServiceFault 回應
+	responseType, status, _ := call(opcuaBrowseRequest, opcuaNS0(0), func(w *opcuaWriter) {})
+	assert.Equal(t, opcuaNS0(opcuaServiceFault), responseType)
+	assert.Equal(t, opcuaBadSessionIDInvalid, status)
+
+	responseType, status, r := call(opcuaGetEndpointsRequest, opcuaNS0(0), func(w *opcuaWriter) {
+		w.string("opc.tcp://localhost:4840")
+		w.int32(-1)
+		w.int32(-1)
+	})
+	require.Equal(t, opcuaNS0(opcuaGetEndpointsResponse), responseType)
+	require.Equal(t, 1, r.arrayLength())
+	assert.Equal(t, "opc.tcp://localhost:4840", r.string())
+
+	// CreateSession + ActivateSession (匿名)
+	responseType, status, r = call(opcuaCreateSessionRequest, opcuaNS0(0), func(w *opcuaWriter) {})
+	require.Equal(t, opcuaNS0(opcuaCreateSessionResponse), responseType)
+	require.Equal(t, opcuaGood, status)
+	r.nodeID()
+	token := r.nodeID()
+	responseType, status, _ = call(opcuaActivateSessionRequest, token, func(w *opcuaWriter) {
+		w.string("")
+		w.bytes(nil)
+		w.int32(-1)
+		w.int32(-1)
+		identity := &opcuaWriter{}
+		identity.string(opcuaAnonymousIdentityPolicy)
+		w.nodeID(opcuaNS0(opcuaAnonymousIdentityToken))
+		w.byte(opcuaExtensionObjectBinary)
+		w.bytes(identity.b)
+		w.string("")
+		w.bytes(nil)
+	})
+	require.Equal(t, opcuaNS0(opcuaActivateSessionResponse), responseType)
+	require.Equal(t, opcuaGood, status)
+
+	// Objects 下每個 Slave 一個資料夾，資料夾下為已定義暫存器
+	objects := browse(token, opcuaNS0(opcuaObjectsFolder))
+	assert.Contains(t, objects, "Server")
+	folder, ok := objects["127.0.0.1"]
+	require.True(t, ok)
+	assert.Equal(t, opcuaNodeID{Namespace: 1, Name: "127.0.0.1"}, folder)
+	variables := browse(token, folder)
+	assert.Equal(t, opcuaNodeID{Namespace: 1, Name: "127.0.0.1/40001"}, variables["LineVoltage"])
+	assert.Equal(t, opcuaNodeID{Namespace: 1, Name: "127.0.0.1/40100"}, variables["Setpoint"])
+
+	// Read 回傳暫存器目前的工程值
+	require.NoError(t, slave.Registers().SetScaledValue(40004, 4321))
+	responseType, status, r = call(opcuaReadRequest, token, func(w *opcuaWriter) {
+		w.float64(0)
+		w.uint32(opcuaTimestampsToReturnBoth)
+		w.int32(3)
+		for _, node := range []struct {
+			id        opcuaNodeID
+			attribute uint32
+		}{
+			{variables["TotalEnergy"], opcuaAttributeValue},
+			{variables["LineVoltage"], opcuaAttributeDataType},
+			{opcuaNodeID{Namespace: 1, Name: "127.0.0.1/1"}, opcuaAttributeValue},
+		} {
+			w.nodeID(node.id)
+			w.uint32(node.attribute)
+			w.string("")
+			w.qualifiedName(opcuaQualifiedName{})
+		}
+	})
+	require.Equal(t, opcuaNS0(opcuaReadResponse), responseType)
+	require.Equal(t, 3, r.arrayLength())
+	assert.Equal(t, byte(0x0D), r.byte(), "值與兩種時間戳記")
+	assert.Equal(t, 4321.0, r.variant())
+	r.int64()
+	r.int64()
+	assert.Equal(t, byte(0x01), r.byte())
+	assert.Equal(t, byte(17), r.byte())
+	assert.Equal(t, opcuaNS0(opcuaDouble), r.nodeID())
+	assert.Equal(t, byte(0x02), r.byte())
+	assert.Equal(t, opcuaBadNodeIDUnknown, r.uint32())
+	require.NoError(t, r.err)
+
+	// Write 寫入 writable 的暫存器，其餘回應 BadNotWritable
+	responseType, status, r = call(opcuaWriteRequest, token, func(w *opcuaWriter) {
+		w.int32(2)
+		for _, id := range []opcuaNodeID{variables["Setpoint"], variables["LineVoltage"]} {
+			w.nodeID(id)
+			w.uint32(opcuaAttributeValue)
+			w.string("")
+			w.dataValue(12.3, opcuaGood, time.Time{}, time.Time{})
+		}
+	})
+	require.Equal(t, opcuaNS0(opcuaWriteResponse), responseType)
+	require.Equal(t, 2, r.arrayLength())
+	assert.Equal(t, opcuaGood, r.uint32())
+	assert.Equal(t, opcuaBadNotWritable, r.uint32())
+	value, err := slave.Registers().GetScaledValue(40100)
+	require.NoError(t, err)
+	assert.InDelta(t, 12.3, value, 0.01)
+
+	// 寫入後 Modbus 讀到相同的值
+	handler := modbus.NewTCPClientHandler("127.0.0.1:5536")
+	handler.SlaveId = slave.UnitID
+	require.NoError(t, handler.Connect())
+	defer handler.Close()
+	results, err := modbus.NewClient(handler).ReadHoldingRegisters(99, 1) // 40100 -> 位址 99
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 123}, results)
+}
+
+func TestSunSpecIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Slaves.Profile = ProfileSunSpecInverter
+	config.Slaves.Randomize.Enabled = true
+	config.Server.Port = 5537
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	config.Scenario.UpdateInterval = 50 * time.Millisecond
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	handler := modbus.NewTCPClientHandler("127.0.0.1:5537")
+	handler.Timeout = 5 * time.Second
+	require.NoError(t, handler.Connect())
+	defer handler.Close()
+	client := modbus.NewClient(handler)
+
+	// SunS 標記與 Common 模型標頭位於 PDU 40000
+	results, err := client.ReadHoldingRegisters(40000, 4)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{'S', 'u', 'n', 'S', 0x00, 0x01, 0x00, 66}, results)
+
+	// 模型 103 的 W (offset 12) 隨場景更新，比例因子 W_SF (offset 13) 為 0
+	assert.Eventually(t, func() bool {
+		results, err := client.ReadHoldingRegisters(40070, 16)
+		if err != nil || binary.BigEndian.Uint16(results) != SunSpecModelInverter {
+			return false
+		}
+		return binary.BigEndian.Uint16(results[2*(2+12):]) > 1000 && binary.BigEndian.Uint16(results[2*(2+13):]) == 0
+	}, 3*time.Second, 50*time.Millisecond)
+}
+
+func TestIEC104Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Slaves.UnitIDStart = 7
+	config.Server.Port = 5538
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	config.Scenario.UpdateInterval = 100 * time.Millisecond
+	config.IEC104.Enabled = true
+	config.IEC104.Port = 5539
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	conn, err := net.Dial("tcp", "127.0.0.1:5539")
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	reader := bufio.NewReader(conn)
+
+	receive := func() iec104APDU {
+		apdu, err := readIEC104APDU(reader)
+		require.NoError(t, err)
+		return apdu
+	}
+	var sendSeq uint16
+	sendI := func(asdu []byte) {
+		frame := []byte{iec104StartByte, byte(4 + len(asdu))}
+		frame = binary.LittleEndian.AppendUint16(frame, sendSeq<<1)
+		frame = binary.LittleEndian.AppendUint16(frame, 0)
+		sendSeq++
+		_, err := conn.Write(append(frame, asdu...))
+		require.NoError(t, err)
+	}
+	command := func(typeID, cause byte, ca uint16, ioa uint32, info ...byte) []byte {
+		asdu := appendIEC104Header(nil, typeID, 1, cause, 0, ca)
+		asdu = appendIEC104IOA(asdu, ioa)
+		return append(asdu, info...)
+	}
+	// 略過自發傳送的測量值，回傳下一個指定類型的 ASDU
+	receiveType := func(typeID byte) []byte {
+		for {
+			apdu := receive()
+			if len(apdu.asdu) > 0 && apdu.asdu[0] == typeID {
+				return apdu.asdu
+			}
+		}
+	}
+
+	// STARTDT 後送出初始化結束 (公共位址為 Unit ID)
+	_, err = conn.Write([]byte{iec104StartByte, 4, iec104StartDTAct, 0, 0, 0})
+	require.NoError(t, err)
+	assert.Equal(t, byte(iec104StartDTCon), receive().control[0])
+	asdu := receiveType(iec104EndOfInit)
+	assert.Equal(t, uint16(7), binary.LittleEndian.Uint16(asdu[4:]))
+
+	// 總召喚：確認、各暫存器的短浮點數 (IOA 為暫存器位址)、結束
+	sendI(command(iec104Interrogation, iec104CotActivation, 7, 0, 20))
+	asdu = receiveType(iec104Interrogation)
+	assert.Equal(t, byte(iec104CotActCon), asdu[2])
+	asdu = receiveType(iec104MeasuredFloat)
+	assert.Equal(t, byte(iec104CotInterrogated), asdu[2])
+	values := make(map[uint32]float32)
+	for i, object := 0, asdu[iec104HeaderLen:]; i < int(asdu[1]&0x7F); i, object = i+1, object[8:] {
+		ioa := uint32(object[0]) | uint32(object[1])<<8 | uint32(object[2])<<16
+		values[ioa] = math.Float32frombits(binary.LittleEndian.Uint32(object[3:]))
+	}
+	assert.InDelta(t, 220, values[40001], 5)
+	assert.InDelta(t, 60, values[40003], 0.5)
+	asdu = receiveType(iec104Interrogation)
+	assert.Equal(t, byte(iec104CotActTerm), asdu[2])
+
+	// 讀命令
+	sendI(command(iec104Read, iec104CotRequest, 7, 40003))
+	asdu = receiveType(iec104MeasuredFloat)
+	assert.Equal(t, byte(iec104CotRequest), asdu[2])
+	assert.InDelta(t, 60, math.Float32frombits(binary.LittleEndian.Uint32(asdu[9:])), 0.5)
+
+	// 未知的公共位址與不支援的類型以否定確認回應
+	sendI(command(iec104Read, iec104CotRequest, 8, 40003))
+	asdu = receiveType(iec104Read)
+	assert.Equal(t, byte(iec104CotUnknownCA|iec104Negative), asdu[2])
+	sendI(command(45, iec104CotActivation, 7, 1, 0x01))
+	asdu = receiveType(45)
+	assert.Equal(t, byte(iec104CotUnknownType|iec104Negative), asdu[2])
+
+	// 量測值變化時自發傳送帶時標的測量值
+	asdu = receiveType(iec104MeasuredFloatTime)
+	assert.Equal(t, byte(iec104CotSpontaneous), asdu[2])
+
+	// TESTFR
+	_, err = conn.Write([]byte{iec104StartByte, 4, iec104TestFRAct, 0, 0, 0})
+	require.NoError(t, err)
+	for {
+		apdu := receive()
+		if apdu.control[0] == iec104TestFRCon {
+			break
+		}
+	}
+}
+
+func TestBACnetIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5540
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	config.BACnet.Enabled = true
+	config.BACnet.Port = 5541
+	config.BACnet.InstanceBase = 1000
+	config.BACnet.Broadcast = []string{}
+	config.BACnet.Registers = []string{"LineVoltage", "Frequency"}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	conn, err := net.Dial("udp", "127.0.0.1:5541")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// 送出 APDU 並回傳回應的 APDU
+	request := func(apdu []byte) []byte {
+		_, err := conn.Write(encodeBACnetFrame(bacnetFrame{}, apdu))
+		require.NoError(t, err)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		buf := make([]byte, bacnetMaxFrame)
+		n, err := conn.Read(buf)
+		require.NoError(t, err)
+		frame, ok := parseBACnetFrame(buf[:n], nil)
+		require.True(t, ok)
+		return frame.apdu
+	}
+	readProperty := func(invokeID byte, object, property uint32, index ...uint32) []byte {
+		apdu := []byte{bacnetConfirmedRequest << 4, 0x05, invokeID, bacnetServiceReadProperty}
+		apdu = appendBACnetObjectID(apdu, 0, true, object)
+		apdu = appendBACnetUnsigned(apdu, 1, true, property)
+		for _, i := range index {
+			apdu = appendBACnetUnsigned(apdu, 2, true, i)
+		}
+		return request(apdu)
+	}
+	deviceID := bacnetObjectID(bacnetDevice, 1001) // instance_base + 127.0.0.1 的末 16 位元
+
+	// Who-Is (指定範圍) → I-Am
+	whoIs := []byte{bacnetUnconfirmedRequest << 4, bacnetServiceWhoIs}
+	whoIs = appendBACnetUnsigned(whoIs, 0, true, 1000)
+	whoIs = appendBACnetUnsigned(whoIs, 1, true, 1001)
+	apdu := request(whoIs)
+	require.Equal(t, []byte{bacnetUnconfirmedRequest << 4, bacnetServiceIAm}, apdu[:2])
+	assert.Equal(t, appendBACnetObjectID(nil, 12, false, deviceID), apdu[2:7])
+
+	// object-list：設備本身與選定的兩個暫存器
+	apdu = readProperty(1, deviceID, bacnetPropObjectList, 0)
+	require.Equal(t, []byte{bacnetComplexAck << 4, 1, bacnetServiceReadProperty}, apdu[:3])
+	assert.Equal(t, []byte{0x3E, 0x21, 3, 0x3F}, apdu[len(apdu)-4:])
+
+	// present-value 為換算後的 REAL，單位為 hertz
+	apdu = readProperty(2, bacnetObjectID(bacnetAnalogInput, 40003), bacnetPropPresentValue)
+	require.Equal(t, byte(bacnetComplexAck<<4), apdu[0])
+	require.Equal(t, []byte{0x3E, 0x44}, apdu[len(apdu)-7:len(apdu)-5])
+	assert.InDelta(t, 60, math.Float32frombits(binary.BigEndian.Uint32(apdu[len(apdu)-5:])), 0.5)
+	apdu = readProperty(3, bacnetObjectID(bacnetAnalogInput, 40003), bacnetPropUnits)
+	assert.Equal(t, []byte{0x3E, 0x91, 27, 0x3F}, apdu[len(apdu)-4:])
+
+	// 未選定的暫存器、未知屬性與非陣列屬性的 index 以 Error 回應
+	apdu = readProperty(4, bacnetObjectID(bacnetAnalogInput, 40002), bacnetPropPresentValue)
+	assert.Equal(t, []byte{bacnetErrorPDU << 4, 4, bacnetServiceReadProperty, 0x91, 1, 0x91, 31}, apdu)
+	apdu = readProperty(5, deviceID, 9999)
+	assert.Equal(t, []byte{bacnetErrorPDU << 4, 5, bacnetServiceReadProperty, 0x91, 2, 0x91, 32}, apdu)
+	apdu = readProperty(6, deviceID, bacnetPropVendorName, 1)
+	assert.Equal(t, []byte{bacnetErrorPDU << 4, 6, bacnetServiceReadProperty, 0x91, 2, 0x91, 50}, apdu)
+
+	// 不支援的確認服務以 Reject 回應
+	apdu = request([]byte{bacnetConfirmedRequest << 4, 0x05, 7, 15})
+	assert.Equal(t, []byte{bacnetRejectPDU << 4, 7, bacnetRejectUnrecognizedService}, apdu)
+}
+
+func TestModbusUDPIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5542
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1", Transport: TransportUDP}}
+	loss := config.Scenario.Scenarios["packet_loss"]
+	loss.PacketLossRate = 1
+	config.Scenario.Scenarios["packet_loss"] = loss
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	conn, err := net.Dial("udp", "127.0.0.1:5542")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// 讀取 40003 (Frequency，PDU 位址 2)；回應與 TCP 相同的 MBAP 訊框
+	request := []byte{0x12, 0x34, 0, 0, 0, 6, 1, FuncCodeReadHoldingRegisters, 0, 2, 0, 1}
+	receive := func(timeout time.Duration) ([]byte, error) {
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		buf := make([]byte, 300)
+		n, err := conn.Read(buf)
+		return buf[:n], err
+	}
+	_, err = conn.Write(request)
+	require.NoError(t, err)
+	response, err := receive(5 * time.Second)
+	require.NoError(t, err)
+	require.Len(t, response, 11)
+	assert.Equal(t, []byte{0x12, 0x34, 0, 0, 0, 5, 1, FuncCodeReadHoldingRegisters, 2}, response[:9])
+	assert.InDelta(t, 6000, int(binary.BigEndian.Uint16(response[9:])), 50)
+
+	// MBAP 長度與 datagram 不符時丟棄
+	_, err = conn.Write(append(request, 0))
+	require.NoError(t, err)
+	_, err = receive(300 * time.Millisecond)
+	assert.Error(t, err)
+
+	// 封包丟失場景：請求不回應
+	require.NoError(t, engine.ApplyScenario(ScenarioPacketLoss))
+	_, err = conn.Write(request)
+	require.NoError(t, err)
+	_, err = receive(300 * time.Millisecond)
+	assert.Error(t, err)
+
+	require.NoError(t, engine.ApplyScenario(ScenarioNormal))
+	_, err = conn.Write(request)
+	require.NoError(t, err)
+	_, err = receive(5 * time.Second)
+	assert.NoError(t, err)
+}
+
+func TestExtraPortsIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5543
+	config.Server.ExtraPorts = []int{5544}
+	config.Server.MaxConnections = 1
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	connect := func(addr string) *modbus.TCPClientHandler {
+		handler := modbus.NewTCPClientHandler(addr)
+		handler.Timeout = time.Second
+		require.NoError(t, handler.Connect())
+		return handler
+	}
+
+	// 兩個埠號提供同一份暫存器
+	primary := connect("127.0.0.1:5543")
+	want, err := modbus.NewClient(primary).ReadHoldingRegisters(2, 1)
+	require.NoError(t, err)
+
+	// 連線數上限以所有埠號合計：主要埠號已有一條連線，額外埠號的連線被關閉
+	rejected := connect("127.0.0.1:5544")
+	_, err = modbus.NewClient(rejected).ReadHoldingRegisters(2, 1)
+	assert.Error(t, err)
+	rejected.Close()
+
+	primary.Close()
+	require.Eventually(t, func() bool { return engine.Stats().ActiveConnections == 0 }, time.Second, 10*time.Millisecond)
+	extra := connect("127.0.0.1:5544")
+	got, err := modbus.NewClient(extra).ReadHoldingRegisters(2, 1)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+	assert.Equal(t, 1, engine.Stats().ActiveConnections)
+
+	// 離線模擬同時關閉所有埠號
+	slave := engine.ListSlaves()[0]
+	slave.GoOffline()
+	_, err = modbus.NewClient(extra).ReadHoldingRegisters(2, 1)
+	assert.Error(t, err)
+	extra.Close()
+	_, err = net.DialTimeout("tcp", "127.0.0.1:5544", time.Second)
+	assert.Error(t, err)
+
+	require.NoError(t, slave.GoOnline())
+	extra = connect("127.0.0.1:5544")
+	defer extra.Close()
+	_, err = modbus.NewClient(extra).ReadHoldingRegisters(2, 1)
+	assert.NoError(t, err)
+}
+
+func TestFunctionOverrideIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5551
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	config.FunctionOverrides = []FunctionOverrideRule{
+		{Name: "busy-writes", Targets: []string{"127.0.0.1"}, Functions: []uint8{FuncCodeWriteMultipleRegisters}, Exception: "slave_device_busy"},
+		{Name: "slow-input", Profiles: []string{ProfileSinglePhase}, Functions: []uint8{FuncCodeReadInputRegisters}, Delay: 300 * time.Millisecond},
+		// 同一功能碼以先列出的規則為準
+		{Name: "shadowed", Functions: []uint8{FuncCodeWriteMultipleRegisters}, Exception: "illegal_function"},
+	}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	handler := modbus.NewTCPClientHandler("127.0.0.1:5551")
+	handler.Timeout = time.Second
+	require.NoError(t, handler.Connect())
+	defer handler.Close()
+	client := modbus.NewClient(handler)
+
+	_, err := client.WriteMultipleRegisters(10, 1, []byte{0x00, 0x01})
+	var modbusErr *modbus.ModbusError
+	require.ErrorAs(t, err, &modbusErr)
+	assert.Equal(t, byte(ExceptionCodeSlaveDeviceBusy), modbusErr.ExceptionCode)
+
+	start := time.Now()
+	_, err = client.ReadInputRegisters(0, 1)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+
+	// 未覆寫的功能碼照常處理
+	start = time.Now()
+	_, err = client.ReadHoldingRegisters(0, 1)
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 300*time.Millisecond)
+}
+
+func TestProfileFunctionsIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5552
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	config.Slaves.Units = []UnitConfig{{UnitID: 1}, {UnitID: 2, Profile: ProfileThreePhase}}
+	config.Slaves.ProfileFunctions = map[string][]uint8{ProfileSinglePhase: {FuncCodeReadHoldingRegisters, FuncCodeWriteSingleRegister}}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	handler := modbus.NewTCPClientHandler("127.0.0.1:5552")
+	handler.Timeout = time.Second
+	require.NoError(t, handler.Connect())
+	defer handler.Close()
+	client := modbus.NewClient(handler)
+
+	// 主設備 (single_phase) 只實作 FC03/06
+	handler.SlaveId = 1
+	_, err := client.ReadHoldingRegisters(0, 1)
+	require.NoError(t, err)
+	_, err = client.ReadInputRegisters(0, 1)
+	var modbusErr *modbus.ModbusError
+	require.ErrorAs(t, err, &modbusErr)
+	assert.Equal(t, byte(ExceptionCodeIllegalFunction), modbusErr.ExceptionCode)
+
+	// 閘道後方的 three_phase 設備不受限制
+	handler.SlaveId = 2
+	_, err = client.ReadInputRegisters(0, 1)
+	assert.NoError(t, err)
+}
+
+func TestJitterDistributionIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	// 每種參數使用各自的引擎 (運行中的場景更新會讀取配置，不可直接修改)
+	run := func(t *testing.T, params ScenarioParams) (time.Duration, error) {
+		logger, _ := zap.NewDevelopment()
+		config := DefaultConfig()
+		config.Slaves.Count = 1
+		config.Server.Port = 5550
+		config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+		config.Scenario.UpdateInterval = 100 * time.Millisecond
+		config.Scenario.Scenarios["jitter"] = params
+
+		engine := NewEngine(config, logger)
+		ctx := context.Background()
+		require.NoError(t, engine.Start(ctx))
+		defer engine.Stop(ctx)
+		require.NoError(t, engine.ApplyScenario(ScenarioJitter))
+
+		handler := modbus.NewTCPClientHandler("127.0.0.1:5550")
+		handler.Timeout = time.Second
+		require.NoError(t, handler.Connect())
+		defer handler.Close()
+
+		start := time.Now()
+		_, err := modbus.NewClient(handler).ReadHoldingRegisters(0, 1)
+		return time.Since(start), err
+	}
+
+	// timeout 模式：回應不會到來
+	t.Run("timeout", func(t *testing.T) {
+		_, err := run(t, ScenarioParams{Enabled: true, JitterDistribution: DelayFixed, JitterMean: 200 * time.Millisecond, JitterTimeoutRate: 1})
+		assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	})
+
+	// 固定延遲
+	t.Run("fixed", func(t *testing.T) {
+		elapsed, err := run(t, ScenarioParams{Enabled: true, JitterDistribution: DelayFixed, JitterMean: 200 * time.Millisecond})
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, elapsed, 200*time.Millisecond)
+	})
+}
+
+func TestTransactionMismatchIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5553
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	config.Scenario.Scenarios["transaction_mismatch"] = ScenarioParams{Enabled: true, MismatchRate: 1, MismatchModes: []string{MismatchModeReorder}, ReorderWait: 300 * time.Millisecond}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+	require.NoError(t, engine.ApplyScenario(ScenarioTransactionMismatch))
+
+	conn, err := net.DialTimeout("tcp", "127.0.0.1:5553", time.Second)
+	require.NoError(t, err)
+	defer conn.Close()
+	request := func(id uint16) []byte {
+		return []byte{byte(id >> 8), byte(id), 0x00, 0x00, 0x00, 0x06, 0x01, 0x03, 0x00, 0x00, 0x00, 0x01}
+	}
+	readID := func() uint16 {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		response, err := readMBAPFrame(conn, ModbusTCPMaxADULength)
+		require.NoError(t, err)
+		return binary.BigEndian.Uint16(response[0:2])
+	}
+
+	// 兩個 pipelined 請求的回應順序對調
+	_, err = conn.Write(append(request(1), request(2)...))
+	require.NoError(t, err)
+	assert.Equal(t, uint16(2), readID())
+	assert.Equal(t, uint16(1), readID())
+
+	// 沒有下一個請求時，延後的回應在 reorder_wait 後寫出
+	start := time.Now()
+	_, err = conn.Write(request(3))
+	require.NoError(t, err)
+	assert.Equal(t, uint16(3), readID())
+	assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+}
+
+func TestPipelinedRequestsIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5554
+	config.Server.MaxInFlight = 4
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	// 每個讀取請求處理 300ms，依序處理時 4 個請求需 1.2s
+	config.FunctionOverrides = []FunctionOverrideRule{{Name: "slow-read", Functions: []uint8{0x03}, Delay: 300 * time.Millisecond}}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	conn, err := net.DialTimeout("tcp", "127.0.0.1:5554", time.Second)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var pipeline []byte
+	for id := 1; id <= 4; id++ {
+		pipeline = append(pipeline, byte(id>>8), byte(id), 0x00, 0x00, 0x00, 0x06, 0x01, 0x03, 0x00, 0x00, 0x00, 0x01)
+	}
+	start := time.Now()
+	_, err = conn.Write(pipeline)
+	require.NoError(t, err)
+
+	// 回應依完成順序寫出，每個 Transaction ID 各一個
+	ids := map[uint16]bool{}
+	for i := 0; i < 4; i++ {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		response, err := readMBAPFrame(conn, ModbusTCPMaxADULength)
+		require.NoError(t, err)
+		assert.Equal(t, byte(0x03), response[7])
+		ids[binary.BigEndian.Uint16(response[0:2])] = true
+	}
+	assert.Len(t, ids, 4)
+	assert.Less(t, time.Since(start), 900*time.Millisecond, "pipelined 請求應並行處理")
+}
+
+func BenchmarkPipelinedRequests(b *testing.B) {
+	for _, inFlight := range []int{1, 16} {
+		b.Run(fmt.Sprintf("max_in_flight=%d", inFlight), func(b *testing.B) {
+			config := DefaultConfig()
+			config.Slaves.Count = 1
+			config.Server.Port = 5555
+			config.Server.MaxInFlight = inFlight
+
+			slave := NewSlave(nil, config.Server.Port, config, WithLogger(zap.NewNop()))
+			ctx := context.Background()
+			slave.Start(ctx)
+			defer slave.Stop(ctx)
+
+			conn, err := net.DialTimeout("tcp", "127.0.0.1:5555", time.Second)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer conn.Close()
+
+			// 每次送出 16 個 pipelined 請求後讀回所有回應
+			const window = 16
+			var pipeline []byte
+			for id := 0; id < window; id++ {
+				pipeline = append(pipeline, byte(id>>8), byte(id), 0x00, 0x00, 0x00, 0x06, 0x01, 0x03, 0x00, 0x00, 0x00, 0x0A)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := conn.Write(pipeline); err != nil {
+					b.Fatal(err)
+				}
+				for j := 0; j < window; j++ {
+					if _, err := readMBAPFrame(conn, ModbusTCPMaxADULength); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestConnectionChurnIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	// 每種中斷方式使用各自的引擎 (運行中的場景更新會讀取配置，不可直接修改)
+	for _, tt := range []struct {
+		mode string
+		want error
+	}{
+		{ChurnModeRST, syscall.ECONNRESET},
+		{ChurnModeFIN, io.EOF},
+	} {
+		t.Run(tt.mode, func(t *testing.T) {
+			logger, _ := zap.NewDevelopment()
+			config := DefaultConfig()
+			config.Slaves.Count = 1
+			config.Server.Port = 5549
+			config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+			config.Scenario.UpdateInterval = 100 * time.Millisecond
+			// 每個更新週期中斷一條連線
+			config.Scenario.Scenarios["connection_churn"] = ScenarioParams{Enabled: true, ChurnRate: 600, ChurnModes: []string{tt.mode}}
+
+			engine := NewEngine(config, logger)
+			ctx := context.Background()
+			require.NoError(t, engine.Start(ctx))
+			defer engine.Stop(ctx)
+			require.NoError(t, engine.ApplyScenario(ScenarioConnectionChurn))
+
+			// 連線被伺服器端中斷前保持閒置，讀取結果即為中斷方式
+			conn, err := net.DialTimeout("tcp", "127.0.0.1:5549", time.Second)
+			require.NoError(t, err)
+			defer conn.Close()
+			conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+			_, err = conn.Read(make([]byte, 1))
+			assert.ErrorIs(t, err, tt.want)
+			assert.GreaterOrEqual(t, engine.Stats().ForcedDisconnects, uint64(1))
+		})
+	}
+}
+
+func TestSingleMasterIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	// 每種模式使用各自的引擎 (連線處理中會讀取配置，不可直接修改)
+	start := func(t *testing.T, mode string) {
+		logger, _ := zap.NewDevelopment()
+		config := DefaultConfig()
+		config.Slaves.Count = 1
+		config.Server.Port = 5548
+		config.Server.SingleMaster = mode
+		config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+
+		engine := NewEngine(config, logger)
+		ctx := context.Background()
+		require.NoError(t, engine.Start(ctx))
+		t.Cleanup(func() { engine.Stop(ctx) })
+	}
+	connect := func(t *testing.T) *modbus.TCPClientHandler {
+		handler := modbus.NewTCPClientHandler("127.0.0.1:5548")
+		handler.Timeout = time.Second
+		require.NoError(t, handler.Connect())
+		return handler
+	}
+
+	// refuse：已有 Master 時其他連線被拒
+	t.Run(SingleMasterRefuse, func(t *testing.T) {
+		start(t, SingleMasterRefuse)
+		first := connect(t)
+		defer first.Close()
+		_, err := modbus.NewClient(first).ReadHoldingRegisters(0, 1)
+		require.NoError(t, err)
+		second := connect(t)
+		defer second.Close()
+		_, err = modbus.NewClient(second).ReadHoldingRegisters(0, 1)
+		assert.Error(t, err)
+	})
+
+	// queue：第二個 Master 等到第一個斷線後才得到回應
+	t.Run(SingleMasterQueue, func(t *testing.T) {
+		start(t, SingleMasterQueue)
+		first := connect(t)
+		_, err := modbus.NewClient(first).ReadHoldingRegisters(0, 1)
+		require.NoError(t, err)
+
+		second := connect(t)
+		defer second.Close()
+		second.Timeout = 3 * time.Second
+		done := make(chan error, 1)
+		go func() {
+			_, err := modbus.NewClient(second).ReadHoldingRegisters(0, 1)
+			done <- err
+		}()
+
+		select {
+		case err := <-done:
+			t.Fatalf("second master served while first connected: %v", err)
+		case <-time.After(300 * time.Millisecond):
+		}
+
+		first.Close()
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(3 * time.Second):
+			t.Fatal("queued master not served after first disconnected")
+		}
+	})
+}
+
+func TestClientACLIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	start := func(port int, rules ...ACLRule) *Engine {
+		config := DefaultConfig()
+		config.Slaves.Count = 1
+		config.Server.Port = port
+		config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+		config.ACL = rules
+		engine := NewEngine(config, logger)
+		require.NoError(t, engine.Start(context.Background()))
+		t.Cleanup(func() { engine.Stop(context.Background()) })
+		return engine
+	}
+	read := func(addr string) error {
+		handler := modbus.NewTCPClientHandler(addr)
+		handler.Timeout = time.Second
+		if err := handler.Connect(); err != nil {
+			return err
+		}
+		defer handler.Close()
+		_, err := modbus.NewClient(handler).ReadHoldingRegisters(0, 1)
+		return err
+	}
+
+	// 不在允許清單：連線被重設
+	start(5545, ACLRule{Name: "ems-only", Allow: []string{"10.0.0.0/8"}})
+	err := read("127.0.0.1:5545")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, syscall.ECONNRESET)
+
+	// exception 模式：保持連線，請求回應 Gateway Path Unavailable
+	start(5546, ACLRule{Name: "ems-only", Targets: []string{"127.0.0.1"}, Allow: []string{"10.0.0.5"}, Action: ACLActionException})
+	var modbusErr *modbus.ModbusError
+	require.ErrorAs(t, read("127.0.0.1:5546"), &modbusErr)
+	assert.Equal(t, byte(ExceptionCodeGatewayPathUnavailable), modbusErr.ExceptionCode)
+
+	// 允許清單內的用戶端照常讀取
+	start(5547, ACLRule{Name: "local", Allow: []string{"127.0.0.0/8"}})
+	assert.NoError(t, read("127.0.0.1:5547"))
+}
+
+func TestDeviceClockIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5558
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+	config.Scenario.UpdateInterval = 100 * time.Millisecond
+	config.Slaves.DeviceClock = DeviceClockConfig{Enabled: true, Skew: -time.Hour, DeviceIDObject: 0x80}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+
+	handler := modbus.NewTCPClientHandler("127.0.0.1:5558")
+	handler.Timeout = 5 * time.Second
+	require.NoError(t, handler.Connect())
+	defer handler.Close()
+	client := modbus.NewClient(handler)
+
+	deviceTime := func() time.Time {
+		results, err := client.ReadHoldingRegisters(199, 6)
+		require.NoError(t, err)
+		w := BytesToRegisters(results)
+		return time.Date(int(w[0]), time.Month(w[1]), int(w[2]), int(w[3]), int(w[4]), int(w[5]), 0, time.UTC)
+	}
+	assert.WithinDuration(t, time.Now().Add(-time.Hour), deviceTime(), 2*time.Second)
+
+	// EMS 校時：寫入時間暫存器後設備時間以寫入的時間繼續走
+	now := time.Now().UTC()
+	data := RegistersToBytes([]uint16{uint16(now.Year()), uint16(now.Month()), uint16(now.Day()), uint16(now.Hour()), uint16(now.Minute()), uint16(now.Second())})
+	_, err := client.WriteMultipleRegisters(199, 6, data)
+	require.NoError(t, err)
+	time.Sleep(300 * time.Millisecond)
+	assert.WithinDuration(t, time.Now(), deviceTime(), 2*time.Second)
+	status, ok := engine.ListSlaves()[0].DeviceClockStatus()
+	require.True(t, ok)
+	assert.Less(t, status.Offset.Abs(), 2*time.Second)
+	assert.NotNil(t, status.SyncedAt)
+
+	// 無效的月份回應 Illegal Data Value
+	_, err = client.WriteSingleRegister(200, 13)
+	var modbusErr *modbus.ModbusError
+	require.ErrorAs(t, err, &modbusErr)
+	assert.Equal(t, byte(ExceptionCodeIllegalDataValue), modbusErr.ExceptionCode)
+
+	// FC 43 / MEI 0x0E 讀取設備時間物件
+	conn, err := net.DialTimeout("tcp", "127.0.0.1:5558", time.Second)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte{0x00, 0x01, 0x00, 0x00, 0x00, 0x05, 0x01, 0x2B, 0x0E, 0x04, 0x80})
+	require.NoError(t, err)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	response := make([]byte, 256)
+	n, err := conn.Read(response)
+	require.NoError(t, err)
+	require.Greater(t, n, 15)
+	assert.Equal(t, []byte{0x2B, 0x0E, 0x04, 0x83, 0x00, 0x00, 0x01, 0x80}, response[7:15])
+	reported, err := time.Parse(time.RFC3339, string(response[16:16+int(response[15])]))
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), reported, 2*time.Second)
+}
+
+// runCLI 以 api 為管理 API 執行 CLI 命令並回傳標準輸出；執行後將命令的參數還原為預設值
+func runCLI(t *testing.T, api string, cmd *cobra.Command, args []string, flags map[string]string) (string, error) {
+	t.Helper()
+	for name, value := range flags {
+		require.NoError(t, cmd.Flags().Set(name, value))
+	}
+	defer cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		f.Value.Set(f.DefValue)
+		f.Changed = false
+	})
+
+	previousURL, previousStdout := apiURL, os.Stdout
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	apiURL, os.Stdout = api, w
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+
+	runErr := cmd.RunE(cmd, args)
+	w.Close()
+	apiURL, os.Stdout = previousURL, previousStdout
+	return <-output, runErr
+}
+
+func TestBlinkAPIIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5559
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.1"}}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	defer engine.Stop(ctx)
+	slave, ok := engine.GetSlave(net.ParseIP("127.0.0.1"))
+	require.True(t, ok)
+	rm := slave.Registers()
+
+	mux := http.NewServeMux()
+	NewAdminAPI(engine, logger).Register(mux)
+	api := httptest.NewServer(mux)
+	defer api.Close()
+
+	// 未指定線圈時不切換線圈 (例如發電機組的起動線圈)
+	require.NoError(t, rm.WriteHoldingRegister(DefaultBlinkRegister, 0x0102))
+	var status BlinkStatus
+	require.NoError(t, callAdminAPI(api.URL, "POST", "/api/slaves/127.0.0.1/blink", BlinkRequest{Period: "20ms"}, &status))
+	assert.Equal(t, DefaultBlinkRegister, status.Register)
+	assert.Equal(t, -1, status.Coil)
+	time.Sleep(100 * time.Millisecond)
+	on, err := rm.ReadCoil(0)
+	require.NoError(t, err)
+	assert.False(t, on)
+	require.NoError(t, callAdminAPI(api.URL, "DELETE", "/api/slaves/127.0.0.1/blink", nil, nil))
+	restored, err := rm.ReadHoldingRegister(DefaultBlinkRegister)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(0x0102), restored)
+
+	// 可明確指定暫存器 0 與線圈
+	register, coil := uint16(0), 2
+	require.NoError(t, callAdminAPI(api.URL, "POST", "/api/slaves/127.0.0.1/blink",
+		BlinkRequest{Period: "1h", Register: &register, Coil: &coil}, &status))
+	assert.Equal(t, uint16(0), status.Register)
+	assert.Equal(t, 2, status.Coil)
+	require.NoError(t, callAdminAPI(api.URL, "DELETE", "/api/slaves/127.0.0.1/blink", nil, nil))
+
+	invalid := 70000
+	err = callAdminAPI(api.URL, "POST", "/api/slaves/127.0.0.1/blink", BlinkRequest{Coil: &invalid}, nil)
+	assert.Error(t, err, "線圈位址超出範圍")
+	err = callAdminAPI(api.URL, "POST", "/api/slaves/127.0.0.2/blink", BlinkRequest{}, nil)
+	assert.Error(t, err, "找不到 Slave")
+
+	// CLI：預設不切換線圈，--stop 還原
+	require.NoError(t, rm.WriteHoldingRegister(40150, 0x0304))
+	output, err := runCLI(t, api.URL, slaveBlinkCmd, []string{"127.0.0.1"}, map[string]string{"register": "40150"})
+	require.NoError(t, err)
+	assert.Contains(t, output, "40150")
+	value, err := rm.ReadHoldingRegister(40150)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultBlinkPattern[0], value)
+	on, err = rm.ReadCoil(0)
+	require.NoError(t, err)
+	assert.False(t, on)
+
+	_, err = runCLI(t, api.URL, slaveBlinkCmd, []string{"127.0.0.1"}, map[string]string{"stop": "true"})
+	require.NoError(t, err)
+	value, err = rm.ReadHoldingRegister(40150)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(0x0304), value)
+}
+
+func TestSlaveListInspectCLI(t *testing.T) {
+	last := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+	var listCalls int
+	var registersQuery string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/slaves", func(w http.ResponseWriter, r *http.Request) {
+		listCalls++
+		// 每次查詢 10.0.0.1 增加 5 個請求，10.0.0.2 沒有流量，10.0.0.3 在第二次查詢才出現
+		slaves := []SlaveSummary{
+			{ID: "10.0.0.1", IP: "10.0.0.1", UnitID: 1, UnitIDs: []int{2, 3}, State: "running", Scenario: "normal",
+				Connections: 2, Requests: uint64(100 + 5*listCalls), Errors: 1, LastRequest: &last},
+			{ID: "10.0.0.2", IP: "10.0.0.2", UnitID: 1, State: "stopped", Scenario: "offline", Requests: 7},
+		}
+		if listCalls > 1 {
+			slaves = append(slaves, SlaveSummary{ID: "10.0.0.3", IP: "10.0.0.3", UnitID: 1, State: "running", Scenario: "normal", Requests: 3})
+		}
+		writeJSON(w, http.StatusOK, slaves)
+	})
+	mux.HandleFunc("GET /api/slaves/{id}/registers", func(w http.ResponseWriter, r *http.Request) {
+		registersQuery = r.PathValue("id") + "?" + r.URL.RawQuery
+		writeJSON(w, http.StatusOK, []RegisterValue{
+			{Address: 40001, Name: "active_power", DataType: "int32", Unit: "kW", Value: 12.5, Raw: []uint16{0, 125}},
+			{Address: 40010, Name: "setpoint", DataType: "uint16", Unit: "%", Writable: true, Value: 80, Raw: []uint16{80}},
+			{Address: 40020, Name: "model", DataType: "string", Value: "MBS-1", Raw: []uint16{0x4d42, 0x532d, 0x3100}},
+		})
+	})
+	api := httptest.NewServer(mux)
+	defer api.Close()
+
+	// RPS 為兩次查詢的差除以取樣間隔；前一次查詢沒有的 Slave 不計算
+	output, err := runCLI(t, api.URL, slaveListCmd, nil, map[string]string{"interval": "100ms"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, listCalls)
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, fmt.Sprintf("%-16s %-10s %-10s %-20s %-8s %s", "IP", "UNITS", "STATE", "SCENARIO", "RPS", "LAST REQUEST"), lines[0])
+	assert.Equal(t, fmt.Sprintf("%-16s %-10s %-10s %-20s %-8s %s", "10.0.0.1", "1,2,3", "running", "normal", "50.0", "2026-10-16T09:30:00Z"), lines[1])
+	assert.Equal(t, fmt.Sprintf("%-16s %-10s %-10s %-20s %-8s %s", "10.0.0.2", "1", "stopped", "offline", "0.0", "-"), lines[2])
+	assert.Equal(t, fmt.Sprintf("%-16s %-10s %-10s %-20s %-8s %s", "10.0.0.3", "1", "running", "normal", "-", "-"), lines[3])
+
+	// --interval 0 只查詢一次，不計算 RPS
+	listCalls = 0
+	output, err = runCLI(t, api.URL, slaveListCmd, nil, map[string]string{"interval": "0"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, listCalls)
+	lines = strings.Split(strings.TrimSpace(output), "\n")
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[1], " -        2026-10-16T09:30:00Z")
+
+	// 經過時間不為正或計數器重置時不計算 (避免除以零)
+	before := []SlaveSummary{{ID: "a", Requests: 10}, {ID: "b", Requests: 50}}
+	after := []SlaveSummary{{ID: "a", Requests: 30}, {ID: "b", Requests: 5}}
+	assert.Empty(t, requestRates(before, after, 0))
+	assert.Empty(t, requestRates(before, after, -time.Second))
+	assert.Equal(t, map[string]float64{"a": 10}, requestRates(before, after, 2*time.Second))
+
+	// inspect：依 IP 查詢，--unit 查看閘道後方的設備
+	output, err = runCLI(t, api.URL, slaveInspectCmd, []string{"10.0.0.1"}, map[string]string{"unit": "2"})
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1?unit=2", registersQuery)
+	assert.Contains(t, output, "Slave:     10.0.0.1 (10.0.0.1)\n")
+	assert.Contains(t, output, "Unit ID:   [1 2 3]\n")
+	assert.Contains(t, output, "狀態:      running\n")
+	assert.Contains(t, output, "連線數:    2\n")
+	assert.Contains(t, output, "請求/錯誤: 110 / 1\n")
+	assert.Contains(t, output, "上次請求:  2026-10-16T09:30:00Z\n")
+	assert.Contains(t, output, fmt.Sprintf("%-8d %-24s %-14s %-6s %-8s %v\n", 40001, "active_power", "12.5", "kW", "int32", []uint16{0, 125}))
+	assert.Contains(t, output, fmt.Sprintf("%-8d %-24s %-14s %-6s %-8s %v\n", 40010, "setpoint", "80", "%", "uint16 rw", []uint16{80}))
+	assert.Contains(t, output, fmt.Sprintf("%-8d %-24s %-14s %-6s %-8s %v\n", 40020, "model", "MBS-1", "", "string", []uint16{0x4d42, 0x532d, 0x3100}))
+
+	// 沒有請求紀錄的 Slave 不顯示上次請求；未指定 --unit 時不帶查詢參數
+	output, err = runCLI(t, api.URL, slaveInspectCmd, []string{"10.0.0.2"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.2?", registersQuery)
+	assert.NotContains(t, output, "上次請求")
+
+	_, err = runCLI(t, api.URL, slaveInspectCmd, []string{"10.0.0.9"}, nil)
+	assert.ErrorContains(t, err, "10.0.0.9")
+
+	// 沒有 Slave
+	empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, []SlaveSummary{})
+	}))
+	defer empty.Close()
+	output, err = runCLI(t, empty.URL, slaveListCmd, nil, map[string]string{"interval": "10ms"})
+	require.NoError(t, err)
+	assert.Equal(t, "目前沒有 Slave\n", output)
+	_, err = runCLI(t, empty.URL, slaveInspectCmd, []string{"10.0.0.1"}, nil)
+	assert.Error(t, err)
+}
+
+// startPairEngine 以 127.0.0.1 (主) 與 127.0.0.2 (備) 組成的配對啟動引擎；shared listener 以 original_dst 分派時
+// 不要求 IP 存在於本機，沒有 iptables 轉向時依連線的本機位址分派 (Linux 上 lo 接收整個 127.0.0.0/8)
+func startPairEngine(t *testing.T, port int, modify func(*Config)) *Engine {
+	t.Helper()
+	if runtime.GOOS != "linux" {
+		t.Skip("需要 127.0.0.0/8 全段可連線 (Linux)")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 2
+	config.Server.Port = port
+	config.Server.Listener = ListenerShared
+	config.Server.OriginalDst = true
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.2"}}
+	config.Redundancy.Pairs = []RedundantPair{{Name: "meter-a", Primary: "127.0.0.1", Standby: "127.0.0.2"}}
+	if modify != nil {
+		modify(config)
+	}
+
+	engine := NewEngine(config, logger)
+	ctx := context.Background()
+	require.NoError(t, engine.Start(ctx))
+	t.Cleanup(func() { engine.Stop(ctx) })
+	return engine
+}
+
+// pairAnswers 以新的連線向 ip 送出一個讀取請求，回傳是否得到回應
+func pairAnswers(t *testing.T, engine *Engine, ip string) bool {
+	t.Helper()
+	slave, ok := engine.GetSlave(net.ParseIP(ip))
+	require.True(t, ok)
+	handler := modbus.NewTCPClientHandler(fmt.Sprintf("%s:%d", ip, engine.config.Server.Port))
+	handler.SlaveId = slave.UnitID
+	handler.Timeout = 300 * time.Millisecond
+	if err := handler.Connect(); err != nil {
+		return false
+	}
+	defer handler.Close()
+	_, err := modbus.NewClient(handler).ReadHoldingRegisters(0, 1)
+	return err == nil
+}
+
+func TestRedundancyFailoverIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	for _, tt := range []struct {
+		mode string
+		port int
+	}{
+		{StandbyModeRefuse, 5560},
+		{StandbyModeSilent, 5561},
+	} {
+		t.Run(tt.mode, func(t *testing.T) {
+			engine := startPairEngine(t, tt.port, func(c *Config) { c.Redundancy.StandbyMode = tt.mode })
+			primary, _ := engine.GetSlave(net.ParseIP("127.0.0.1"))
+			standby, _ := engine.GetSlave(net.ParseIP("127.0.0.2"))
+
+			// 啟動後僅主端回應
+			assert.True(t, pairAnswers(t, engine, "127.0.0.1"))
+			assert.False(t, pairAnswers(t, engine, "127.0.0.2"))
+			assert.False(t, primary.IsStandby())
+			assert.True(t, standby.IsStandby())
+
+			// refuse：備援端不接受連線 (shared listener 接受後立即關閉)；silent：連線保持但不回應
+			conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.2:%d", tt.port))
+			require.NoError(t, err)
+			_, err = conn.Write([]byte{0, 1, 0, 0, 0, 6, standby.UnitID, FuncCodeReadHoldingRegisters, 0, 0, 0, 1})
+			require.NoError(t, err)
+			conn.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+			_, err = conn.Read(make([]byte, 16))
+			conn.Close()
+			if tt.mode == StandbyModeRefuse {
+				assert.Equal(t, SlaveStateStandby, standby.State())
+				assert.True(t, errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET), "連線被關閉: %v", err)
+			} else {
+				assert.Equal(t, SlaveStateRunning, standby.State())
+				var netErr net.Error
+				require.ErrorAs(t, err, &netErr)
+				assert.True(t, netErr.Timeout(), "silent 備援端不回應也不關閉連線")
+			}
+
+			// 經由管理 API 切換：備援端接手，原主端不再回應
+			mux := http.NewServeMux()
+			NewAdminAPI(engine, engine.logger).Register(mux)
+			api := httptest.NewServer(mux)
+			defer api.Close()
+			var status PairStatus
+			require.NoError(t, callAdminAPI(api.URL, "POST", "/api/pairs/meter-a/failover", nil, &status))
+			assert.Equal(t, "127.0.0.2", status.Active)
+			assert.Equal(t, "127.0.0.1", status.Standby)
+			assert.Equal(t, uint64(1), status.Failovers)
+			assert.False(t, pairAnswers(t, engine, "127.0.0.1"))
+			assert.True(t, pairAnswers(t, engine, "127.0.0.2"))
+			assert.True(t, primary.IsStandby())
+			assert.False(t, standby.IsStandby())
+
+			// 再次切換回主端
+			status, err = engine.Failover("meter-a")
+			require.NoError(t, err)
+			assert.Equal(t, "127.0.0.1", status.Active)
+			assert.Equal(t, uint64(2), status.Failovers)
+			assert.True(t, pairAnswers(t, engine, "127.0.0.1"))
+			assert.False(t, pairAnswers(t, engine, "127.0.0.2"))
+
+			_, err = engine.Failover("meter-b")
+			assert.Error(t, err, "找不到配對")
+			assert.Error(t, callAdminAPI(api.URL, "POST", "/api/pairs/meter-b/failover", nil, nil))
+		})
+	}
+}
+
+func TestRedundancyFailoverSchedulerIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	engine := startPairEngine(t, 5562, func(c *Config) {
+		c.Redundancy.Pairs[0].FailoverInterval = 2 * time.Second
+	})
+	assert.True(t, pairAnswers(t, engine, "127.0.0.1"))
+	assert.False(t, pairAnswers(t, engine, "127.0.0.2"))
+
+	// 依 failover_interval 自動切換 (排程每秒檢查一次)
+	require.Eventually(t, func() bool {
+		return engine.ListPairs()[0].Failovers == 1
+	}, 5*time.Second, 20*time.Millisecond)
+	status := engine.ListPairs()[0]
+	assert.Equal(t, "127.0.0.2", status.Active)
+	assert.False(t, status.LastFailover.IsZero())
+	assert.True(t, pairAnswers(t, engine, "127.0.0.2"))
+	assert.False(t, pairAnswers(t, engine, "127.0.0.1"))
+}
+
+func TestRedundancyPairMemberMissingIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	// 備援端不在本實例要啟動的 Slave 中 (超過 slaves.count)：拒絕啟動，而不是只設定主端
+	logger, _ := zap.NewDevelopment()
+	config := DefaultConfig()
+	config.Slaves.Count = 1
+	config.Server.Port = 5563
+	config.Network.IPRanges = []IPRange{{Start: "127.0.0.1", End: "127.0.0.2"}}
+	config.Redundancy.Pairs = []RedundantPair{{Name: "meter-a", Primary: "127.0.0.1", Standby: "127.0.0.2"}}
+	require.NoError(t, config.Validate())
+
+	engine := NewEngine(config, logger)
+	err := engine.Start(context.Background())
+	assert.ErrorContains(t, err, "127.0.0.2")
+	assert.Equal(t, EngineStateStopped, engine.State())
+	assert.Empty(t, engine.ListSlaves())
+}
+
+func TestRedundancyLateStartIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	// 逐步上線：兩端都在 initPairs 之後才啟動，加入引擎時依配對設定角色
+	t.Run("ramp_up", func(t *testing.T) {
+		engine := startPairEngine(t, 5564, func(c *Config) { c.Slaves.RampUpRate = 10 })
+		require.Eventually(t, func() bool {
+			return len(engine.ListSlaves()) == 2 && engine.RampPending() == 0
+		}, 5*time.Second, 20*time.Millisecond)
+
+		standby, _ := engine.GetSlave(net.ParseIP("127.0.0.2"))
+		assert.True(t, standby.IsStandby())
+		assert.True(t, pairAnswers(t, engine, "127.0.0.1"))
+		assert.False(t, pairAnswers(t, engine, "127.0.0.2"))
+
+		_, err := engine.Failover("meter-a")
+		require.NoError(t, err)
+		assert.False(t, pairAnswers(t, engine, "127.0.0.1"))
+		assert.True(t, pairAnswers(t, engine, "127.0.0.2"))
+	})
+
+	// 綁定重試：衝突解除後啟動的 Slave 套用目前的場景與配對角色
+	t.Run("bind_retry", func(t *testing.T) {
+		holder, err := net.Listen("tcp", "127.0.0.1:5565")
+		require.NoError(t, err)
+		engine := startPairEngine(t, 5565, func(c *Config) { c.Server.BindRetryInterval = 100 * time.Millisecond })
+		assert.Empty(t, engine.ListSlaves())
+		assert.Equal(t, 2, engine.Stats().BindPending)
+
+		// 等待重試期間切換場景並手動切換配對
+		require.NoError(t, engine.ApplyScenario(ScenarioVoltageSag))
+		_, err = engine.Failover("meter-a")
+		require.NoError(t, err)
+
+		holder.Close()
+		require.Eventually(t, func() bool { return len(engine.ListSlaves()) == 2 }, 5*time.Second, 20*time.Millisecond)
+		for _, slave := range engine.ListSlaves() {
+			assert.Equal(t, ScenarioVoltageSag, slave.GetScenario(), slave.ID)
+		}
+		primary, _ := engine.GetSlave(net.ParseIP("127.0.0.1"))
+		assert.True(t, primary.IsStandby())
+		assert.False(t, pairAnswers(t, engine, "127.0.0.1"))
+		assert.True(t, pairAnswers(t, engine, "127.0.0.2"))
+	})
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultLatencyBuckets 請求延遲直方圖預設分界 (秒)
+var DefaultLatencyBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Exemplar 直方圖樣本，連結到產生該值的 trace
+type Exemplar struct {
+	TraceID   string
+	Value     float64
+	Timestamp time.Time
+}
+
+// LatencyHistogram 請求延遲直方圖，每個分界保留最近一筆被取樣請求的 exemplar
+type LatencyHistogram struct {
+	buckets []float64
+	counts  []atomic.Uint64 // 非累計，最後一格為 +Inf
+	sumBits atomic.Uint64
+
+	mu        sync.Mutex
+	exemplars []Exemplar
+}
+
+// NewLatencyHistogram 建立請求延遲直方圖
+func NewLatencyHistogram(buckets []float64) *LatencyHistogram {
+	return &LatencyHistogram{
+		buckets:   buckets,
+		counts:    make([]atomic.Uint64, len(buckets)+1),
+		exemplars: make([]Exemplar, len(buckets)+1),
+	}
+}
+
+// Observe 記錄一筆延遲 (秒)；traceID 非空時更新所屬分界的 exemplar
+func (h *LatencyHistogram) Observe(seconds float64, traceID string, at time.Time) {
+	idx := len(h.buckets)
+	for i, upper := range h.buckets {
+		if seconds <= upper {
+			idx = i
+			break
+		}
+	}
+	h.counts[idx].Add(1)
+
+	for {
+		old := h.sumBits.Load()
+		sum := math.Float64frombits(old) + seconds
+		if h.sumBits.CompareAndSwap(old, math.Float64bits(sum)) {
+			break
+		}
+	}
+
+	if traceID != "" {
+		h.mu.Lock()
+		h.exemplars[idx] = Exemplar{TraceID: traceID, Value: seconds, Timestamp: at}
+		h.mu.Unlock()
+	}
+}
+
+// Count 已記錄的請求數
+func (h *LatencyHistogram) Count() uint64 {
+	var total uint64
+	for i := range h.counts {
+		total += h.counts[i].Load()
+	}
+	return total
+}
+
+// snapshot 取得累計計數 (最後一格為 +Inf)、總和與各分界的 exemplar
+func (h *LatencyHistogram) snapshot() (cumulative []uint64, sum float64, exemplars []Exemplar) {
+	h.mu.Lock()
+	exemplars = make([]Exemplar, len(h.exemplars))
+	copy(exemplars, h.exemplars)
+	h.mu.Unlock()
+
+	cumulative = make([]uint64, len(h.counts))
+	var total uint64
+	for i := range h.counts {
+		total += h.counts[i].Load()
+		cumulative[i] = total
+	}
+	return cumulative, math.Float64frombits(h.sumBits.Load()), exemplars
+}
+
+// metric 轉為 Prometheus 直方圖 (exemplar 由 client 函式庫在 OpenMetrics 格式時輸出)
+func (h *LatencyHistogram) metric(desc *prometheus.Desc) prometheus.Metric {
+	cumulative, sum, exemplars := h.snapshot()
+
+	buckets := make(map[float64]uint64, len(h.buckets))
+	for i, upper := range h.buckets {
+		buckets[upper] = cumulative[i]
+	}
+	histogram := prometheus.MustNewConstHistogram(desc, cumulative[len(cumulative)-1], sum, buckets)
+
+	var samples []prometheus.Exemplar
+	for _, ex := range exemplars {
+		if ex.TraceID != "" {
+			samples = append(samples, prometheus.Exemplar{
+				Value:     ex.Value,
+				Labels:    prometheus.Labels{"trace_id": ex.TraceID},
+				Timestamp: ex.Timestamp,
+			})
+		}
+	}
+	if len(samples) == 0 {
+		return histogram
+	}
+	return prometheus.MustNewMetricWithExemplars(histogram, samples...)
+}
+
+// write 輸出直方圖樣本 (exemplar 僅在 OpenMetrics 格式輸出)
+func (h *LatencyHistogram) write(w io.Writer, name string, openMetrics bool) {
+	cumulative, sum, exemplars := h.snapshot()
+
+	for i, count := range cumulative {
+		le := "+Inf"
+		if i < len(h.buckets) {
+			le = strconv.FormatFloat(h.buckets[i], 'g', -1, 64)
+		}
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d", name, le, count)
+
+		if ex := exemplars[i]; openMetrics && ex.TraceID != "" {
+			fmt.Fprintf(w, " # {trace_id=%s} %g %.3f", promLabel(ex.TraceID), ex.Value,
+				float64(ex.Timestamp.UnixNano())/float64(time.Second))
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "%s_sum %f\n", name, sum)
+	fmt.Fprintf(w, "%s_count %d\n", name, cumulative[len(cumulative)-1])
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+// listenerPool shared 模式的共用 listener (每個埠號一個)
+//
+// 大量 Slave 不再各自擁有 accept 迴圈：連線由共用 listener 接受後，
+// 依目的 IP (本端位址，或 original_dst 時的 SO_ORIGINAL_DST) 分派給該 IP 的 Slave。
+type listenerPool struct {
+	originalDst bool
+
+	mu        sync.Mutex
+	listeners map[int]*sharedListener
+	closed    bool
+
+	// 目的 IP 沒有運行中 Slave 的連線數
+	unrouted atomic.Uint64
+
+	logger *zap.Logger
+}
+
+// sharedListener 單一埠號的共用 listener 與其路由表
+type sharedListener struct {
+	pool     *listenerPool
+	addr     string
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu     sync.RWMutex
+	routes map[string]*slaveListener // 目的 IP -> Slave 的接入層 ("" 表示未指定 IP 的 Slave，接收其餘連線)
+}
+
+// newListenerPool 建立共用 listener 池
+func newListenerPool(originalDst bool, logger *zap.Logger) *listenerPool {
+	return &listenerPool{
+		originalDst: originalDst,
+		listeners:   make(map[int]*sharedListener),
+		logger:      logger,
+	}
+}
+
+// routeKey Slave 在路由表中的鍵
+func routeKey(ip net.IP) string {
+	if ip == nil || ip.IsUnspecified() {
+		return ""
+	}
+	return ip.String()
+}
+
+// attach 將 Slave 的接入層登記到其埠號的共用 listener (必要時先建立 listener)
+// 同一 IP:port 已有其他 Slave 時回傳 EADDRINUSE，與各自監聽時的綁定衝突一致
+func (p *listenerPool) attach(l *slaveListener) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return net.ErrClosed
+	}
+
+	port := l.port
+	shared, ok := p.listeners[port]
+	if !ok {
+		listener, err := net.Listen("tcp", fmt.Sprintf("0.0.0.0:%d", port))
+		if err != nil {
+			return err
+		}
+		shared = &sharedListener{
+			pool:     p,
+			addr:     listener.Addr().String(),
+			listener: listener,
+			routes:   make(map[string]*slaveListener),
+		}
+		p.listeners[port] = shared
+
+		shared.wg.Add(1)
+		go shared.acceptLoop()
+
+		p.logger.Info(T("已建立共用 listener"),
+			zap.String("addr", shared.addr),
+			zap.Bool("original_dst", p.originalDst),
+		)
+	}
+
+	key := routeKey(l.slave.IP)
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+	if current, ok := shared.routes[key]; ok && current != l {
+		return &net.OpError{Op: "listen", Net: "tcp", Err: os.NewSyscallError("bind", syscall.EADDRINUSE)}
+	}
+	shared.routes[key] = l
+	return nil
+}
+
+// detach 自路由表移除 Slave 的接入層，之後送往該 IP 的連線在接受後立即關閉
+func (p *listenerPool) detach(l *slaveListener) {
+	p.mu.Lock()
+	shared, ok := p.listeners[l.port]
+	p.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	key := routeKey(l.slave.IP)
+	shared.mu.Lock()
+	if shared.routes[key] == l {
+		delete(shared.routes, key)
+	}
+	shared.mu.Unlock()
+}
+
+// Close 關閉所有共用 listener (既有連線由各 Slave 停止時關閉)
+func (p *listenerPool) Close() {
+	p.mu.Lock()
+	p.closed = true
+	listeners := p.listeners
+	p.listeners = make(map[int]*sharedListener)
+	p.mu.Unlock()
+
+	for _, shared := range listeners {
+		shared.listener.Close()
+		shared.wg.Wait()
+	}
+}
+
+// Unrouted 目的 IP 沒有運行中 Slave 而被關閉的連線數
+func (p *listenerPool) Unrouted() uint64 {
+	return p.unrouted.Load()
+}
+
+// acceptLoop 接受連線並分派給目的 IP 的 Slave
+func (s *sharedListener) acceptLoop() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				s.pool.logger.Warn(T("接受連線失敗"), zap.String("addr", s.addr), zap.Error(err))
+			}
+			return
+		}
+
+		target := s.route(conn)
+		if target == nil {
+			// 目的 IP 的 Slave 未運行 (離線、備援或已停止)：無法如各自監聽時拒絕連線，改為接受後立即關閉
+			s.pool.unrouted.Add(1)
+			conn.Close()
+			continue
+		}
+		target.accept(conn)
+	}
+}
+
+// route 依連線的目的 IP 找出 Slave 的接入層
+func (s *sharedListener) route(conn net.Conn) *slaveListener {
+	var dst net.IP
+	if s.pool.originalDst {
+		if ip, err := originalDst(conn); err == nil {
+			dst = ip
+		} else {
+			s.pool.logger.Debug(T("取得原始目的位址失敗"),
+				zap.String("remote", conn.RemoteAddr().String()),
+				zap.Error(err),
+			)
+		}
+	}
+	if dst == nil {
+		if addr, ok := conn.LocalAddr().(*net.TCPAddr); ok {
+			dst = addr.IP
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if l, ok := s.routes[routeKey(dst)]; ok {
+		return l
+	}
+	return s.routes[""]
+}
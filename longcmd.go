@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// 長時間命令的狀態暫存器值
+const (
+	LongCommandIdle       uint16 = 0 // 尚未收到命令
+	LongCommandInProgress uint16 = 1 // 執行中
+	LongCommandComplete   uint16 = 2 // 已完成
+)
+
+// 長時間命令場景的預設參數
+const (
+	DefaultCommandRegister = 40100
+	DefaultStatusRegister  = 40101
+	DefaultCommandDuration = 10 * time.Second
+)
+
+// --- Long Command Scenario ---
+
+// LongCommandScenario 長時間命令場景 - 模擬分接頭切換、繼電器測試等以非同步方式執行的慢速操作
+//
+// 寫入 command_register 時設備接受命令並回應例外 0x05 (Acknowledge)，寫入照常生效；
+// status_register 在 command_duration 內為 1 (執行中)，之後為 2 (完成)。
+// 執行期間再次寫入 command_register 回應例外 0x06 (Slave Device Busy) 且不生效，
+// 用於測試 Master 端的輪詢狀態機。
+type LongCommandScenario struct {
+	normalScenario NormalScenario
+
+	mu       sync.Mutex
+	commands map[*RegisterMap]time.Time // 執行中命令的開始時間
+}
+
+func (s *LongCommandScenario) Type() ScenarioType {
+	return ScenarioLongCommand
+}
+
+// Activate 進入場景時捨棄執行中的命令
+func (s *LongCommandScenario) Activate(registers *RegisterMap) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.commands, registers)
+}
+
+// InterceptRequest 寫入命令暫存器時回應 Acknowledge，執行中則回應 Busy
+func (s *LongCommandScenario) InterceptRequest(registers *RegisterMap, functionCode uint8, data []byte, params ScenarioParams) (uint8, bool) {
+	command, status, duration := longCommandRegisters(params)
+	if !writesHoldingRegister(registers, functionCode, data, command) {
+		return 0, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.completeLocked(registers, status, duration)
+
+	if _, running := s.commands[registers]; running {
+		return ExceptionCodeSlaveDeviceBusy, true
+	}
+
+	if s.commands == nil {
+		s.commands = make(map[*RegisterMap]time.Time)
+	}
+	s.commands[registers] = scenarioNow()
+	registers.WriteHoldingRegister(status, LongCommandInProgress)
+	return ExceptionCodeAcknowledge, true
+}
+
+func (s *LongCommandScenario) Update(registers *RegisterMap, params ScenarioParams) {
+	s.normalScenario.Update(registers, params)
+
+	_, status, duration := longCommandRegisters(params)
+	s.mu.Lock()
+	s.completeLocked(registers, status, duration)
+	s.mu.Unlock()
+}
+
+// completeLocked 執行時間已滿時將狀態改為完成 (呼叫端需持有 s.mu)
+func (s *LongCommandScenario) completeLocked(registers *RegisterMap, status uint16, duration time.Duration) {
+	started, running := s.commands[registers]
+	if !running || scenarioSince(started) < duration {
+		return
+	}
+	delete(s.commands, registers)
+	registers.WriteHoldingRegister(status, LongCommandComplete)
+}
+
+func (s *LongCommandScenario) Reset(registers *RegisterMap) {
+	s.mu.Lock()
+	delete(s.commands, registers)
+	s.mu.Unlock()
+
+	s.normalScenario.Reset(registers)
+}
+
+// longCommandRegisters 命令暫存器、狀態暫存器與執行時間 (未設定時使用預設值)
+func longCommandRegisters(params ScenarioParams) (command, status uint16, duration time.Duration) {
+	command, status, duration = params.CommandRegister, params.StatusRegister, params.CommandDuration
+	if command == 0 {
+		command = DefaultCommandRegister
+	}
+	if status == 0 {
+		status = DefaultStatusRegister
+	}
+	if duration <= 0 {
+		duration = DefaultCommandDuration
+	}
+	return command, status, duration
+}
+
+// writesHoldingRegister 請求 (FC06/FC16) 是否寫入指定的保持暫存器
+func writesHoldingRegister(registers *RegisterMap, functionCode uint8, data []byte, address uint16) bool {
+	if len(data) < 4 {
+		return false
+	}
+
+	start := int(binary.BigEndian.Uint16(data[0:2]))
+	quantity := 1
+	switch functionCode {
+	case FuncCodeWriteSingleRegister:
+	case FuncCodeWriteMultipleRegisters:
+		quantity = int(binary.BigEndian.Uint16(data[2:4]))
+	default:
+		return false
+	}
+
+	idx := registers.holdingIndex(address)
+	return idx >= start && idx < start+quantity
+}
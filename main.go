@@ -14,7 +14,7 @@ var (
 
 func main() {
 	if err := Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "錯誤: %v\n", err)
+		fmt.Fprintf(os.Stderr, T("錯誤: %v\n"), err)
 		os.Exit(1)
 	}
 }
@@ -4,10 +4,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 )
 
@@ -23,20 +28,49 @@ type MetricsCollector struct {
 	totalSlaves   int
 	activeSlaves  int
 	stoppedSlaves int
+	offlineSlaves int
+	standbySlaves int
+	domainsDown   int
+	bindPending   int
+	rampPending   int
+	driftedSlaves int
+	retiredSlaves int
+	activeConns   int
+	chaosSlaves   int
 
 	// 請求指標
 	totalRequests   atomic.Uint64
 	totalErrors     atomic.Uint64
 	bytesReceived   atomic.Uint64
 	bytesSent       atomic.Uint64
+	totalFlaps      atomic.Uint64
+	totalFailovers  atomic.Uint64
+	totalSuppressed atomic.Uint64
+	domainOutages   atomic.Uint64
+	bindConflicts   atomic.Uint64
+	redundantPolls  atomic.Uint64
+	rejectedConns   atomic.Uint64
+	forcedConns     atomic.Uint64
+	rateLimited     atomic.Uint64
+	timedOutConns   atomic.Uint64
+	unroutedConns   atomic.Uint64
+	chaosInjected   atomic.Uint64
 
 	// 場景指標
 	currentScenario string
+	seed            int64
 
 	// 歷史記錄 (用於計算速率)
 	requestHistory []requestSample
 	maxHistory     int
 
+	// HTTP 路由 (供管理 API 掛載)
+	mux *http.ServeMux
+
+	// Prometheus 註冊表與 /metrics 處理器
+	registry *prometheus.Registry
+	handler  http.Handler
+
 	// 參照
 	engine *Engine
 	logger *zap.Logger
@@ -54,11 +88,14 @@ type MetricsSnapshot struct {
 	Uptime          string    `json:"uptime"`
 	EngineState     string    `json:"engine_state"`
 	CurrentScenario string    `json:"current_scenario"`
+	Seed            int64     `json:"seed,string"` // 以字串輸出，避免 JSON 數值超出 2^53 時失真
 
 	// Slave 指標
 	TotalSlaves   int `json:"total_slaves"`
 	ActiveSlaves  int `json:"active_slaves"`
 	StoppedSlaves int `json:"stopped_slaves"`
+	OfflineSlaves int `json:"offline_slaves"`
+	StandbySlaves int `json:"standby_slaves"`
 
 	// 請求指標
 	TotalRequests   uint64  `json:"total_requests"`
@@ -67,6 +104,25 @@ type MetricsSnapshot struct {
 	RequestsPerSec  float64 `json:"requests_per_sec"`
 	BytesReceived   uint64  `json:"bytes_received"`
 	BytesSent       uint64  `json:"bytes_sent"`
+	TotalFlaps      uint64  `json:"total_flaps"`
+	TotalFailovers  uint64  `json:"total_failovers"`
+	TotalSuppressed uint64  `json:"total_suppressed_injections"`
+	DomainOutages   uint64  `json:"domain_outages"`
+	DomainsDown     int     `json:"domains_down"`
+	BindConflicts   uint64  `json:"bind_conflicts"`
+	BindPending     int     `json:"bind_pending"`
+	RampPending     int     `json:"ramp_pending"`
+	DriftedSlaves   int     `json:"drifted_slaves"`
+	RetiredSlaves   int     `json:"decommissioned_slaves"`
+	ActiveConns     int     `json:"connections_active"`
+	RejectedConns   uint64  `json:"connections_rejected"`
+	ForcedConns     uint64  `json:"connections_forced_closed"`
+	RateLimited     uint64  `json:"requests_rate_limited"`
+	TimedOutConns   uint64  `json:"connections_timed_out"`
+	UnroutedConns   uint64  `json:"connections_unrouted"`
+	RedundantPolls  uint64  `json:"redundant_polls"`
+	ChaosInjected   uint64  `json:"chaos_disturbances"`
+	ChaosSlaves     int     `json:"chaos_slaves"`
 
 	// 暫存器指標 (樣本)
 	SampleVoltage   float64 `json:"sample_voltage,omitempty"`
@@ -77,11 +133,30 @@ type MetricsSnapshot struct {
 
 // NewMetricsCollector 建立指標收集器
 func NewMetricsCollector(engine *Engine, logger *zap.Logger) *MetricsCollector {
-	return &MetricsCollector{
+	m := &MetricsCollector{
 		engine:     engine,
 		logger:     logger,
 		maxHistory: 60, // 保留 60 個樣本 (用於計算每秒速率)
+		mux:        http.NewServeMux(),
+		registry:   prometheus.NewRegistry(),
 	}
+	m.registry.MustRegister(m)
+	m.handler = promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{
+		ErrorLog:          zap.NewStdLog(logger),
+		ErrorHandling:     promhttp.ContinueOnError,
+		EnableOpenMetrics: true,
+	})
+	return m
+}
+
+// Registry 取得 Prometheus 註冊表 (可註冊額外的 collector)
+func (m *MetricsCollector) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Mux 取得 HTTP 路由 (需於 Start 前註冊)
+func (m *MetricsCollector) Mux() *http.ServeMux {
+	return m.mux
 }
 
 // Start 啟動指標收集
@@ -92,17 +167,17 @@ func (m *MetricsCollector) Start(endpoint string, port int) error {
 	go m.collectLoop()
 
 	// 啟動 HTTP 伺服器
-	mux := http.NewServeMux()
+	mux := m.mux
 	mux.HandleFunc(endpoint, m.handleMetrics)
 	mux.HandleFunc("/health", m.handleHealth)
 	mux.HandleFunc("/ready", m.handleReady)
 
 	addr := fmt.Sprintf(":%d", port)
-	m.logger.Info("啟動指標伺服器", zap.String("addr", addr))
+	m.logger.Info(T("啟動指標伺服器"), zap.String("addr", addr))
 
 	go func() {
 		if err := http.ListenAndServe(addr, mux); err != nil {
-			m.logger.Error("指標伺服器錯誤", zap.Error(err))
+			m.logger.Error(T("指標伺服器錯誤"), zap.Error(err))
 		}
 	}()
 
@@ -133,13 +208,35 @@ func (m *MetricsCollector) collect() {
 	m.engineState = m.engine.State().String()
 	m.totalSlaves = stats.SlaveCount
 	m.activeSlaves = stats.ActiveSlaves
+	m.offlineSlaves = stats.OfflineSlaves
+	m.standbySlaves = stats.StandbySlaves
+	m.domainsDown = stats.DomainsDown
+	m.bindPending = stats.BindPending
+	m.rampPending = stats.RampPending
+	m.driftedSlaves = stats.DriftedSlaves
+	m.retiredSlaves = stats.DecommissionedSlaves
+	m.activeConns = stats.ActiveConnections
+	m.chaosSlaves = stats.ChaosSlaves
 	m.currentScenario = m.engine.GetScenario().String()
+	m.seed = stats.Seed
 
 	// 更新累計值
 	m.totalRequests.Store(stats.TotalRequests)
 	m.totalErrors.Store(stats.TotalErrors)
 	m.bytesReceived.Store(stats.BytesReceived)
 	m.bytesSent.Store(stats.BytesSent)
+	m.totalFlaps.Store(stats.TotalFlaps)
+	m.totalFailovers.Store(stats.TotalFailovers)
+	m.totalSuppressed.Store(stats.SuppressedInjections)
+	m.domainOutages.Store(stats.DomainOutages)
+	m.bindConflicts.Store(stats.BindConflicts)
+	m.redundantPolls.Store(stats.RedundantPolls)
+	m.rejectedConns.Store(stats.RejectedConnections)
+	m.forcedConns.Store(stats.ForcedDisconnects)
+	m.rateLimited.Store(stats.RateLimitedRequests)
+	m.timedOutConns.Store(stats.TimedOutConnections)
+	m.unroutedConns.Store(stats.UnroutedConnections)
+	m.chaosInjected.Store(stats.ChaosDisturbances)
 
 	// 記錄歷史
 	sample := requestSample{
@@ -166,13 +263,35 @@ func (m *MetricsCollector) Snapshot() MetricsSnapshot {
 		Uptime:          time.Since(m.engineStartTime).String(),
 		EngineState:     m.engineState,
 		CurrentScenario: m.currentScenario,
+		Seed:            m.seed,
 		TotalSlaves:     m.totalSlaves,
 		ActiveSlaves:    m.activeSlaves,
-		StoppedSlaves:   m.totalSlaves - m.activeSlaves,
+		StoppedSlaves:   m.totalSlaves - m.activeSlaves - m.offlineSlaves - m.standbySlaves,
+		OfflineSlaves:   m.offlineSlaves,
+		StandbySlaves:   m.standbySlaves,
 		TotalRequests:   totalReqs,
 		TotalErrors:     totalErrs,
 		BytesReceived:   m.bytesReceived.Load(),
 		BytesSent:       m.bytesSent.Load(),
+		TotalFlaps:      m.totalFlaps.Load(),
+		TotalFailovers:  m.totalFailovers.Load(),
+		TotalSuppressed: m.totalSuppressed.Load(),
+		DomainOutages:   m.domainOutages.Load(),
+		DomainsDown:     m.domainsDown,
+		BindConflicts:   m.bindConflicts.Load(),
+		BindPending:     m.bindPending,
+		RampPending:     m.rampPending,
+		DriftedSlaves:   m.driftedSlaves,
+		RetiredSlaves:   m.retiredSlaves,
+		ActiveConns:     m.activeConns,
+		RejectedConns:   m.rejectedConns.Load(),
+		ForcedConns:     m.forcedConns.Load(),
+		RateLimited:     m.rateLimited.Load(),
+		TimedOutConns:   m.timedOutConns.Load(),
+		UnroutedConns:   m.unroutedConns.Load(),
+		RedundantPolls:  m.redundantPolls.Load(),
+		ChaosInjected:   m.chaosInjected.Load(),
+		ChaosSlaves:     m.chaosSlaves,
 	}
 
 	// 計算錯誤率
@@ -207,82 +326,304 @@ func (m *MetricsCollector) Snapshot() MetricsSnapshot {
 
 // handleMetrics 處理 /metrics 請求
 func (m *MetricsCollector) handleMetrics(w http.ResponseWriter, r *http.Request) {
-	snapshot := m.Snapshot()
-
 	// 檢查 Accept header
 	accept := r.Header.Get("Accept")
 	if accept == "application/json" || r.URL.Query().Get("format") == "json" {
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(snapshot)
+		json.NewEncoder(w).Encode(m.Snapshot())
 		return
 	}
 
-	// Prometheus 格式
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	// Prometheus 格式；Accept 含 OpenMetrics 時由 client 函式庫改用 OpenMetrics (才能攜帶 exemplar)
+	m.handler.ServeHTTP(w, r)
+}
 
-	fmt.Fprintf(w, "# HELP modbussim_uptime_seconds Uptime in seconds\n")
-	fmt.Fprintf(w, "# TYPE modbussim_uptime_seconds gauge\n")
-	fmt.Fprintf(w, "modbussim_uptime_seconds %f\n\n", time.Since(m.engineStartTime).Seconds())
+// 指標描述 (名稱沿用先前手寫輸出的 modbussim_*)
+var (
+	uptimeDesc = prometheus.NewDesc("modbussim_uptime_seconds", "Uptime in seconds", nil, nil)
+
+	seedDesc = prometheus.NewDesc("modbussim_seed_info", "Random seed of the current run (pass it to --seed to reproduce)",
+		[]string{"seed"}, nil)
+
+	requestDurationDesc = prometheus.NewDesc("modbussim_request_duration_seconds",
+		"Modbus request latency from frame received to response written", nil, nil)
+
+	registerValueDesc = prometheus.NewDesc("modbussim_register_value", "Scaled register value per slave",
+		[]string{"slave_ip", "unit_id", "register", "address", "unit"}, nil)
+
+	scenarioActiveDesc = prometheus.NewDesc("modbussim_scenario_active",
+		"Whether the scenario is the current fleet-wide scenario (1) or not (0)", []string{"scenario"}, nil)
+	scenarioTransitionsDesc = prometheus.NewDesc("modbussim_scenario_transitions_total",
+		"Total number of fleet-wide switches into the scenario", []string{"scenario"}, nil)
+	scenarioTimeDesc = prometheus.NewDesc("modbussim_time_in_scenario_seconds_total",
+		"Total seconds the fleet-wide scenario has been the scenario", []string{"scenario"}, nil)
+	scenarioSlavesDesc = prometheus.NewDesc("modbussim_scenario_slaves",
+		"Number of slaves currently running the scenario (scenario targets may apply it to part of the fleet)", []string{"scenario"}, nil)
+
+	slaveLabels = []string{"slave_ip", "unit_id", "scenario"}
+
+	slaveRequestsDesc = prometheus.NewDesc("modbussim_slave_requests_total",
+		"Total number of requests per slave", slaveLabels, nil)
+	slaveErrorsDesc = prometheus.NewDesc("modbussim_slave_errors_total",
+		"Total number of errors per slave", slaveLabels, nil)
+	slaveConnectionsDesc = prometheus.NewDesc("modbussim_slave_connections",
+		"Number of open Modbus TCP connections per slave", slaveLabels, nil)
+	slaveRejectedDesc = prometheus.NewDesc("modbussim_slave_connections_rejected_total",
+		"Total number of connections rejected by server.max_connections per slave", slaveLabels, nil)
+	slaveForcedDesc = prometheus.NewDesc("modbussim_slave_connections_forced_closed_total",
+		"Total number of connections reset or closed by the connection_churn scenario per slave", slaveLabels, nil)
+	slaveRateLimitedDesc = prometheus.NewDesc("modbussim_slave_requests_rate_limited_total",
+		"Total number of requests answered with Slave Device Busy by slaves.request_rate_limit per slave", slaveLabels, nil)
+	slaveLastRequestAgeDesc = prometheus.NewDesc("modbussim_slave_last_request_age_seconds",
+		"Seconds since the last request per slave (absent until the first request)", slaveLabels, nil)
+	slaveScenarioDesc = prometheus.NewDesc("modbussim_slave_scenario_info",
+		"Scenario currently running on each slave (always 1)", slaveLabels, nil)
+)
 
-	fmt.Fprintf(w, "# HELP modbussim_slaves_total Total number of slaves\n")
-	fmt.Fprintf(w, "# TYPE modbussim_slaves_total gauge\n")
-	fmt.Fprintf(w, "modbussim_slaves_total %d\n\n", snapshot.TotalSlaves)
+// fleetMetric 整體 (不分 Slave) 指標，數值取自指標快照
+type fleetMetric struct {
+	desc  *prometheus.Desc
+	kind  prometheus.ValueType
+	value func(MetricsSnapshot) float64
+}
 
-	fmt.Fprintf(w, "# HELP modbussim_slaves_active Active number of slaves\n")
-	fmt.Fprintf(w, "# TYPE modbussim_slaves_active gauge\n")
-	fmt.Fprintf(w, "modbussim_slaves_active %d\n\n", snapshot.ActiveSlaves)
+func gaugeMetric(name, help string, value func(MetricsSnapshot) float64) fleetMetric {
+	return fleetMetric{desc: prometheus.NewDesc(name, help, nil, nil), kind: prometheus.GaugeValue, value: value}
+}
 
-	fmt.Fprintf(w, "# HELP modbussim_requests_total Total number of requests\n")
-	fmt.Fprintf(w, "# TYPE modbussim_requests_total counter\n")
-	fmt.Fprintf(w, "modbussim_requests_total %d\n\n", snapshot.TotalRequests)
+func counterMetric(name, help string, value func(MetricsSnapshot) uint64) fleetMetric {
+	return fleetMetric{
+		desc:  prometheus.NewDesc(name, help, nil, nil),
+		kind:  prometheus.CounterValue,
+		value: func(s MetricsSnapshot) float64 { return float64(value(s)) },
+	}
+}
 
-	fmt.Fprintf(w, "# HELP modbussim_errors_total Total number of errors\n")
-	fmt.Fprintf(w, "# TYPE modbussim_errors_total counter\n")
-	fmt.Fprintf(w, "modbussim_errors_total %d\n\n", snapshot.TotalErrors)
+var fleetMetrics = []fleetMetric{
+	gaugeMetric("modbussim_slaves_total", "Total number of slaves",
+		func(s MetricsSnapshot) float64 { return float64(s.TotalSlaves) }),
+	gaugeMetric("modbussim_slaves_active", "Active number of slaves",
+		func(s MetricsSnapshot) float64 { return float64(s.ActiveSlaves) }),
+	gaugeMetric("modbussim_slaves_offline", "Number of slaves offline due to connection flap",
+		func(s MetricsSnapshot) float64 { return float64(s.OfflineSlaves) }),
+	counterMetric("modbussim_slave_flaps_total", "Total number of simulated connection drops",
+		func(s MetricsSnapshot) uint64 { return s.TotalFlaps }),
+	gaugeMetric("modbussim_slaves_standby", "Number of slaves in standby role",
+		func(s MetricsSnapshot) float64 { return float64(s.StandbySlaves) }),
+	counterMetric("modbussim_failovers_total", "Total number of redundant pair failovers",
+		func(s MetricsSnapshot) uint64 { return s.TotalFailovers }),
+	counterMetric("modbussim_domain_outages_total", "Total number of failure domain outages",
+		func(s MetricsSnapshot) uint64 { return s.DomainOutages }),
+	gaugeMetric("modbussim_domains_down", "Number of failure domains currently down",
+		func(s MetricsSnapshot) float64 { return float64(s.DomainsDown) }),
+	counterMetric("modbussim_bind_conflicts_total", "Total number of listener bind conflicts (address already in use)",
+		func(s MetricsSnapshot) uint64 { return s.BindConflicts }),
+	gaugeMetric("modbussim_bind_pending", "Number of slaves waiting to retry a conflicting bind",
+		func(s MetricsSnapshot) float64 { return float64(s.BindPending) }),
+	gaugeMetric("modbussim_ramp_pending", "Number of slaves waiting to come online during ramp-up",
+		func(s MetricsSnapshot) float64 { return float64(s.RampPending) }),
+	gaugeMetric("modbussim_drifted_slaves", "Number of slaves whose registers drifted from their baseline at the last drift check",
+		func(s MetricsSnapshot) float64 { return float64(s.DriftedSlaves) }),
+	gaugeMetric("modbussim_connections_active", "Number of open Modbus TCP connections across all slaves",
+		func(s MetricsSnapshot) float64 { return float64(s.ActiveConns) }),
+	counterMetric("modbussim_connections_rejected_total", "Total number of connections closed because a slave reached server.max_connections",
+		func(s MetricsSnapshot) uint64 { return s.RejectedConns }),
+	counterMetric("modbussim_connections_forced_closed_total", "Total number of established connections reset or closed by the connection_churn scenario",
+		func(s MetricsSnapshot) uint64 { return s.ForcedConns }),
+	counterMetric("modbussim_requests_rate_limited_total", "Total number of requests answered with Slave Device Busy because a slave exceeded slaves.request_rate_limit",
+		func(s MetricsSnapshot) uint64 { return s.RateLimited }),
+	counterMetric("modbussim_connections_timed_out_total", "Total number of connections closed by the server read, write or idle timeout",
+		func(s MetricsSnapshot) uint64 { return s.TimedOutConns }),
+	counterMetric("modbussim_connections_unrouted_total", "Total number of connections closed by the shared listener because no running slave owns the destination IP",
+		func(s MetricsSnapshot) uint64 { return s.UnroutedConns }),
+	counterMetric("modbussim_slaves_decommissioned_total", "Total number of slaves permanently removed by decommission rules or the admin API",
+		func(s MetricsSnapshot) uint64 { return uint64(s.RetiredSlaves) }),
+	counterMetric("modbussim_redundant_polls_total", "Total number of read polls whose response was unchanged since the previous poll (polling analysis)",
+		func(s MetricsSnapshot) uint64 { return s.RedundantPolls }),
+	counterMetric("modbussim_chaos_disturbances_total", "Total number of disturbances injected by the chaos scenario",
+		func(s MetricsSnapshot) uint64 { return s.ChaosInjected }),
+	gaugeMetric("modbussim_chaos_slaves", "Number of slaves currently running a chaos disturbance",
+		func(s MetricsSnapshot) float64 { return float64(s.ChaosSlaves) }),
+	counterMetric("modbussim_fault_injections_suppressed_total", "Total number of fault injections suppressed by protection rules",
+		func(s MetricsSnapshot) uint64 { return s.TotalSuppressed }),
+	counterMetric("modbussim_requests_total", "Total number of requests",
+		func(s MetricsSnapshot) uint64 { return s.TotalRequests }),
+	counterMetric("modbussim_errors_total", "Total number of errors",
+		func(s MetricsSnapshot) uint64 { return s.TotalErrors }),
+	gaugeMetric("modbussim_requests_per_second", "Requests per second",
+		func(s MetricsSnapshot) float64 { return s.RequestsPerSec }),
+	counterMetric("modbussim_bytes_received_total", "Total bytes received",
+		func(s MetricsSnapshot) uint64 { return s.BytesReceived }),
+	counterMetric("modbussim_bytes_sent_total", "Total bytes sent",
+		func(s MetricsSnapshot) uint64 { return s.BytesSent }),
+	gaugeMetric("modbussim_sample_voltage", "Sample voltage reading",
+		func(s MetricsSnapshot) float64 { return s.SampleVoltage }),
+	gaugeMetric("modbussim_sample_current", "Sample current reading",
+		func(s MetricsSnapshot) float64 { return s.SampleCurrent }),
+	gaugeMetric("modbussim_sample_frequency", "Sample frequency reading",
+		func(s MetricsSnapshot) float64 { return s.SampleFrequency }),
+	gaugeMetric("modbussim_sample_power", "Sample power reading",
+		func(s MetricsSnapshot) float64 { return s.SamplePower }),
+}
 
-	fmt.Fprintf(w, "# HELP modbussim_requests_per_second Requests per second\n")
-	fmt.Fprintf(w, "# TYPE modbussim_requests_per_second gauge\n")
-	fmt.Fprintf(w, "modbussim_requests_per_second %f\n\n", snapshot.RequestsPerSec)
+// Describe 實作 prometheus.Collector
+func (m *MetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- uptimeDesc
+	ch <- seedDesc
+	for _, metric := range fleetMetrics {
+		ch <- metric.desc
+	}
+	ch <- requestDurationDesc
+	ch <- scenarioActiveDesc
+	ch <- scenarioTransitionsDesc
+	ch <- scenarioTimeDesc
+	ch <- scenarioSlavesDesc
+	ch <- slaveRequestsDesc
+	ch <- slaveErrorsDesc
+	ch <- slaveConnectionsDesc
+	ch <- slaveRejectedDesc
+	ch <- slaveForcedDesc
+	ch <- slaveRateLimitedDesc
+	ch <- slaveLastRequestAgeDesc
+	ch <- slaveScenarioDesc
+	ch <- registerValueDesc
+}
 
-	fmt.Fprintf(w, "# HELP modbussim_bytes_received_total Total bytes received\n")
-	fmt.Fprintf(w, "# TYPE modbussim_bytes_received_total counter\n")
-	fmt.Fprintf(w, "modbussim_bytes_received_total %d\n\n", snapshot.BytesReceived)
+// Collect 實作 prometheus.Collector (每次抓取時依快照與 Slave 現況產生)
+func (m *MetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	snapshot := m.Snapshot()
 
-	fmt.Fprintf(w, "# HELP modbussim_bytes_sent_total Total bytes sent\n")
-	fmt.Fprintf(w, "# TYPE modbussim_bytes_sent_total counter\n")
-	fmt.Fprintf(w, "modbussim_bytes_sent_total %d\n\n", snapshot.BytesSent)
+	ch <- prometheus.MustNewConstMetric(uptimeDesc, prometheus.GaugeValue, time.Since(m.engineStartTime).Seconds())
+	ch <- prometheus.MustNewConstMetric(seedDesc, prometheus.GaugeValue, 1, strconv.FormatInt(snapshot.Seed, 10))
+	for _, metric := range fleetMetrics {
+		ch <- prometheus.MustNewConstMetric(metric.desc, metric.kind, metric.value(snapshot))
+	}
 
-	fmt.Fprintf(w, "# HELP modbussim_sample_voltage Sample voltage reading\n")
-	fmt.Fprintf(w, "# TYPE modbussim_sample_voltage gauge\n")
-	fmt.Fprintf(w, "modbussim_sample_voltage %f\n\n", snapshot.SampleVoltage)
+	if m.engine == nil {
+		return
+	}
+	ch <- m.engine.Latency().metric(requestDurationDesc)
+	m.collectScenarios(ch)
 
-	fmt.Fprintf(w, "# HELP modbussim_sample_current Sample current reading\n")
-	fmt.Fprintf(w, "# TYPE modbussim_sample_current gauge\n")
-	fmt.Fprintf(w, "modbussim_sample_current %f\n\n", snapshot.SampleCurrent)
+	cfg := m.engine.config.Metrics
+	if cfg.PerSlave.Enabled {
+		m.collectSlaves(ch, cfg.PerSlave)
+	}
+	if cfg.RegisterValues.Enabled {
+		m.collectRegisterValues(ch, cfg.RegisterValues)
+	}
+}
 
-	fmt.Fprintf(w, "# HELP modbussim_sample_frequency Sample frequency reading\n")
-	fmt.Fprintf(w, "# TYPE modbussim_sample_frequency gauge\n")
-	fmt.Fprintf(w, "modbussim_sample_frequency %f\n\n", snapshot.SampleFrequency)
+// limitSlaves 依 ID 排序後取前 maxSlaves 個 (0 表示不限制)，用於限制標籤基數
+func limitSlaves(slaves []*Slave, maxSlaves int) []*Slave {
+	sort.Slice(slaves, func(i, j int) bool { return slaves[i].ID < slaves[j].ID })
+	if maxSlaves > 0 && len(slaves) > maxSlaves {
+		slaves = slaves[:maxSlaves]
+	}
+	return slaves
+}
+
+// collectScenarios 輸出每個場景類型是否為目前的整體場景、切換次數、停留時間與運行中的 Slave 數
+func (m *MetricsCollector) collectScenarios(ch chan<- prometheus.Metric) {
+	running := make(map[ScenarioType]int)
+	for _, slave := range m.engine.ListSlaves() {
+		running[slave.GetScenario()]++
+	}
 
-	fmt.Fprintf(w, "# HELP modbussim_sample_power Sample power reading\n")
-	fmt.Fprintf(w, "# TYPE modbussim_sample_power gauge\n")
-	fmt.Fprintf(w, "modbussim_sample_power %f\n", snapshot.SamplePower)
+	for _, usage := range m.engine.ScenarioTimeline().Usage(time.Now()) {
+		name := usage.Scenario.String()
+		active := 0.0
+		if usage.Active {
+			active = 1
+		}
+		ch <- prometheus.MustNewConstMetric(scenarioActiveDesc, prometheus.GaugeValue, active, name)
+		ch <- prometheus.MustNewConstMetric(scenarioTransitionsDesc, prometheus.CounterValue, float64(usage.Transitions), name)
+		ch <- prometheus.MustNewConstMetric(scenarioTimeDesc, prometheus.CounterValue, usage.Time.Seconds(), name)
+		ch <- prometheus.MustNewConstMetric(scenarioSlavesDesc, prometheus.GaugeValue, float64(running[usage.Scenario]), name)
+	}
 }
 
-// handleHealth 處理 /health 請求
+// collectSlaves 輸出每個 Slave 的請求、錯誤、連線數、被拒與被強制中斷的連線數、目前的場景，以及最後請求距今秒數
+func (m *MetricsCollector) collectSlaves(ch chan<- prometheus.Metric, cfg SlaveMetricsConfig) {
+	now := time.Now()
+	for _, slave := range limitSlaves(m.engine.ListSlaves(), cfg.MaxSlaves) {
+		stats := slave.GetStats()
+		labels := []string{slave.IP.String(), strconv.Itoa(int(slave.UnitID)), slave.GetScenario().String()}
+
+		ch <- prometheus.MustNewConstMetric(slaveRequestsDesc, prometheus.CounterValue,
+			float64(stats.RequestCount.Load()), labels...)
+		ch <- prometheus.MustNewConstMetric(slaveErrorsDesc, prometheus.CounterValue,
+			float64(stats.ErrorCount.Load()), labels...)
+		ch <- prometheus.MustNewConstMetric(slaveConnectionsDesc, prometheus.GaugeValue,
+			float64(slave.ConnCount()), labels...)
+		ch <- prometheus.MustNewConstMetric(slaveRejectedDesc, prometheus.CounterValue,
+			float64(stats.RejectedConns.Load()), labels...)
+		ch <- prometheus.MustNewConstMetric(slaveForcedDesc, prometheus.CounterValue,
+			float64(stats.ForcedDisconnects.Load()), labels...)
+		ch <- prometheus.MustNewConstMetric(slaveRateLimitedDesc, prometheus.CounterValue,
+			float64(stats.RateLimited.Load()), labels...)
+		ch <- prometheus.MustNewConstMetric(slaveScenarioDesc, prometheus.GaugeValue, 1, labels...)
+
+		if last := stats.LastRequestTime.Load(); last > 0 {
+			ch <- prometheus.MustNewConstMetric(slaveLastRequestAgeDesc, prometheus.GaugeValue,
+				now.Sub(time.Unix(0, last)).Seconds(), labels...)
+		}
+	}
+}
+
+// collectRegisterValues 輸出每個 Slave 已定義暫存器的縮放值 (依配置限制基數)
+func (m *MetricsCollector) collectRegisterValues(ch chan<- prometheus.Metric, cfg RegisterMetricsConfig) {
+	wanted := make(map[string]bool, len(cfg.Registers))
+	for _, name := range cfg.Registers {
+		wanted[name] = true
+	}
+
+	for _, slave := range limitSlaves(m.engine.ListSlaves(), cfg.MaxSlaves) {
+		regs := slave.Registers()
+		for _, meta := range regs.Definitions() {
+			if len(wanted) > 0 && !wanted[meta.Name] {
+				continue
+			}
+			value, err := regs.GetScaledValue(meta.Address)
+			if err != nil {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(registerValueDesc, prometheus.GaugeValue, value,
+				slave.IP.String(), strconv.Itoa(int(slave.UnitID)), meta.Name, strconv.Itoa(int(meta.Address)), meta.Unit)
+		}
+	}
+}
+
+// promLabel 將字串轉為 Prometheus 標籤值 (含引號與跳脫)
+func promLabel(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, "\n", `\n`)
+	v = strings.ReplaceAll(v, `"`, `\"`)
+	return `"` + v + `"`
+}
+
+// handleHealth 處理 /health 請求 (存活檢查：一律回應 200，健康 Slave 比例低於門檻時狀態為 degraded)
 func (m *MetricsCollector) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
+	if m.engine == nil {
+		json.NewEncoder(w).Encode(map[string]string{"status": HealthStatusHealthy})
+		return
+	}
+	json.NewEncoder(w).Encode(m.engine.FleetHealth(m.engine.config.Metrics.MinHealthyPercent))
 }
 
-// handleReady 處理 /ready 請求
+// handleReady 處理 /ready 請求 (引擎未運行或健康 Slave 比例低於 metrics.min_healthy_percent 時回應 503)
 func (m *MetricsCollector) handleReady(w http.ResponseWriter, r *http.Request) {
-	if m.engine == nil || m.engine.State() != EngineStateRunning {
+	w.Header().Set("Content-Type", "application/json")
+	if m.engine == nil {
 		w.WriteHeader(http.StatusServiceUnavailable)
 		json.NewEncoder(w).Encode(map[string]string{"status": "not ready"})
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+	health := m.engine.FleetHealth(m.engine.config.Metrics.MinHealthyPercent)
+	if health.Status != HealthStatusHealthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(health)
 }
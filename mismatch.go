@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// 交易錯亂場景的模式
+const (
+	MismatchModeWrong    = "wrong"    // 以隨機的錯誤 Transaction ID 回應
+	MismatchModePrevious = "previous" // 以同一連線上一個請求的 Transaction ID 回應
+	MismatchModeReorder  = "reorder"  // 延後回應，待下一個 (pipelined) 請求的回應寫出後才寫出
+)
+
+// MismatchModes 所有交易錯亂模式
+var MismatchModes = []string{MismatchModeWrong, MismatchModePrevious, MismatchModeReorder}
+
+// 交易錯亂場景的預設參數
+const (
+	DefaultMismatchRate = 0.1
+	DefaultReorderWait  = 200 * time.Millisecond
+)
+
+// isMismatchMode 是否為有效的交易錯亂模式
+func isMismatchMode(mode string) bool {
+	for _, m := range MismatchModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// ResponseSequencer 需要改寫回應的 Transaction ID 或回應順序的場景實作此介面 (由 TCP 接入層對每條連線呼叫)
+// previous 為同一連線上一個請求的 Transaction ID (hasPrevious 為 false 表示這是第一個請求)；
+// hold 大於 0 時延後寫出回應，待下一個請求的回應寫出後才寫出，最多等待 hold
+type ResponseSequencer interface {
+	SequenceResponse(response []byte, previous uint16, hasPrevious bool, params ScenarioParams) (out []byte, hold time.Duration)
+}
+
+// --- Transaction Mismatch Scenario ---
+
+// TransactionMismatchScenario 交易錯亂場景 - 依比例以錯誤或上一個請求的 Transaction ID 回應，
+// 或將兩個 pipelined 請求的回應對調，測試 Master 的 MBAP 配對邏輯
+type TransactionMismatchScenario struct {
+	normalScenario NormalScenario
+}
+
+func (s *TransactionMismatchScenario) Type() ScenarioType {
+	return ScenarioTransactionMismatch
+}
+
+func (s *TransactionMismatchScenario) Update(registers *RegisterMap, params ScenarioParams) {
+	s.normalScenario.Update(registers, ScenarioParams{
+		VoltageVariance:   0.005,
+		FrequencyVariance: 0.0005,
+	})
+}
+
+func (s *TransactionMismatchScenario) Reset(registers *RegisterMap) {
+	s.normalScenario.Reset(registers)
+}
+
+// SequenceResponse 依 mismatch_rate 決定是否錯亂，模式由 mismatch_modes 隨機挑選；
+// 連線上的第一個回應沒有上一個 Transaction ID，previous 模式改以錯誤的 Transaction ID 回應
+func (s *TransactionMismatchScenario) SequenceResponse(response []byte, previous uint16, hasPrevious bool, params ScenarioParams) ([]byte, time.Duration) {
+	rate := params.MismatchRate
+	if rate <= 0 {
+		rate = DefaultMismatchRate
+	}
+	if len(response) < ModbusTCPHeaderLength || scenarioRandom().Float64() >= rate {
+		return response, 0
+	}
+	modes := params.MismatchModes
+	if len(modes) == 0 {
+		modes = MismatchModes
+	}
+
+	mode := modes[scenarioRandom().Intn(len(modes))]
+	if mode == MismatchModeReorder {
+		wait := params.ReorderWait
+		if wait <= 0 {
+			wait = DefaultReorderWait
+		}
+		return response, wait
+	}
+
+	out := append([]byte(nil), response...)
+	id := binary.BigEndian.Uint16(out[0:2])
+	if mode == MismatchModePrevious && hasPrevious && previous != id {
+		binary.BigEndian.PutUint16(out[0:2], previous)
+	} else {
+		// 加上 1-65535，必定與原本的 Transaction ID 不同
+		binary.BigEndian.PutUint16(out[0:2], id+uint16(1+scenarioRandom().Intn(0xFFFF)))
+	}
+	return out, 0
+}
+
+// responseSequence 連線上的回應順序狀態 (由 connResponses 保護)
+type responseSequence struct {
+	previous    uint16
+	hasPrevious bool
+	held        []byte        // 延後寫出的回應
+	wait        time.Duration // 延後的回應最多等待下一個請求的時間
+}
+
+// next 套用當前場景的 ResponseSequencer；hold 為 true 時回應已延後，本次不寫出
+func (q *responseSequence) next(s *Slave, request, response []byte) (out []byte, hold bool) {
+	previous, hasPrevious := q.previous, q.hasPrevious
+	q.previous, q.hasPrevious = binary.BigEndian.Uint16(request[0:2]), true
+
+	_, handler, params := s.currentScenario()
+	sequencer, ok := handler.(ResponseSequencer)
+	if !ok {
+		return response, false
+	}
+	out, wait := sequencer.SequenceResponse(response, previous, hasPrevious, params)
+	if wait <= 0 || q.held != nil {
+		return out, false
+	}
+	q.held, q.wait = out, wait
+	return nil, true
+}
+
+// flush 取出延後的回應 (沒有時回傳 nil)
+func (q *responseSequence) flush() []byte {
+	held := q.held
+	q.held = nil
+	return held
+}
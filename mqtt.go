@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// MQTT 3.1.1 封包類型
+const (
+	mqttConnect    = 1
+	mqttConnack    = 2
+	mqttPublish    = 3
+	mqttPuback     = 4
+	mqttSubscribe  = 8
+	mqttSuback     = 9
+	mqttPingreq    = 12
+	mqttPingresp   = 13
+	mqttDisconnect = 14
+)
+
+// mqttTimeout 連線、等待 CONNACK 與每次寫入的期限
+const mqttTimeout = 10 * time.Second
+
+// mqttPacket 一個 MQTT 控制封包 (flags 為固定標頭的低 4 位元)
+type mqttPacket struct {
+	Type  byte
+	Flags byte
+	Body  []byte
+}
+
+// mqttConnectOptions CONNECT 的參數
+type mqttConnectOptions struct {
+	ClientID    string
+	Username    string
+	Password    string
+	KeepAlive   time.Duration
+	WillTopic   string // 空值表示不設定遺囑訊息
+	WillPayload []byte
+	WillQoS     byte
+}
+
+// mqttClient 最小的 MQTT 3.1.1 客戶端 (QoS 0 發布與訂閱，遺囑訊息可為 QoS 1)，供 Sparkplug B 發布使用
+type mqttClient struct {
+	conn      net.Conn
+	writeMu   sync.Mutex
+	onMessage func(topic string, payload []byte)
+
+	done    chan struct{} // 連線中斷時關閉
+	errOnce sync.Once
+	err     error
+}
+
+// dialMQTT 連線至 broker 並完成 CONNECT (broker 為 tcp://host:port 或 tls://host:port，未指定埠時為 1883/8883)
+func dialMQTT(ctx context.Context, broker string, opts mqttConnectOptions, onMessage func(topic string, payload []byte)) (*mqttClient, error) {
+	addr, useTLS, err := parseMQTTBroker(broker)
+	if err != nil {
+		return nil, err
+	}
+	var conn net.Conn
+	dialer := &net.Dialer{Timeout: mqttTimeout}
+	if useTLS {
+		host, _, _ := net.SplitHostPort(addr)
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &mqttClient{conn: conn, onMessage: onMessage, done: make(chan struct{})}
+	if err := c.write(mqttConnect, 0, encodeMQTTConnect(opts)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetReadDeadline(time.Now().Add(mqttTimeout))
+	reader := bufio.NewReader(conn)
+	ack, err := readMQTTPacket(reader)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if ack.Type != mqttConnack || len(ack.Body) < 2 {
+		conn.Close()
+		return nil, fmt.Errorf(T("MQTT broker 回應非預期的封包: %d"), ack.Type)
+	}
+	if code := ack.Body[1]; code != 0 {
+		conn.Close()
+		return nil, fmt.Errorf(T("MQTT broker 拒絕連線 (return code %d)"), code)
+	}
+
+	go c.readLoop(reader, opts.KeepAlive)
+	if opts.KeepAlive > 0 {
+		go c.pingLoop(opts.KeepAlive)
+	}
+	return c, nil
+}
+
+// parseMQTTBroker 解析 broker 位址
+func parseMQTTBroker(broker string) (addr string, useTLS bool, err error) {
+	u, err := url.Parse(broker)
+	if err != nil || u.Host == "" {
+		return "", false, fmt.Errorf(T("MQTT broker 位址無效: %s (格式 tcp://host:port)"), broker)
+	}
+	port := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "tls", "ssl", "mqtts":
+		useTLS, port = true, "8883"
+	default:
+		return "", false, fmt.Errorf(T("MQTT broker 位址無效: %s (格式 tcp://host:port)"), broker)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	return net.JoinHostPort(u.Hostname(), port), useTLS, nil
+}
+
+// Publish 以 QoS 0 發布訊息
+func (c *mqttClient) Publish(topic string, payload []byte, retain bool) error {
+	body := appendMQTTString(nil, topic)
+	body = append(body, payload...)
+	var flags byte
+	if retain {
+		flags = 0x01
+	}
+	return c.write(mqttPublish, flags, body)
+}
+
+// Subscribe 以 QoS 0 訂閱 (不等待 SUBACK)
+func (c *mqttClient) Subscribe(filter string) error {
+	body := []byte{0, 1} // packet identifier
+	body = appendMQTTString(body, filter)
+	body = append(body, 0)
+	return c.write(mqttSubscribe, 0x02, body)
+}
+
+// Done 連線中斷時關閉
+func (c *mqttClient) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err 連線中斷的原因
+func (c *mqttClient) Err() error {
+	<-c.done
+	return c.err
+}
+
+// Close 送出 DISCONNECT 後關閉連線 (broker 收到 DISCONNECT 不發布遺囑訊息)
+func (c *mqttClient) Close() error {
+	err := c.write(mqttDisconnect, 0, nil)
+	c.fail(net.ErrClosed)
+	return err
+}
+
+func (c *mqttClient) write(packetType, flags byte, body []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(mqttTimeout))
+	if _, err := c.conn.Write(encodeMQTTPacket(packetType, flags, body)); err != nil {
+		c.fail(err)
+		return err
+	}
+	return nil
+}
+
+// fail 記錄第一個錯誤並關閉連線
+func (c *mqttClient) fail(err error) {
+	c.errOnce.Do(func() {
+		c.err = err
+		c.conn.Close()
+		close(c.done)
+	})
+}
+
+// readLoop 讀取 broker 送來的封包 (超過 1.5 倍 keep alive 未收到任何封包視為斷線)
+func (c *mqttClient) readLoop(reader *bufio.Reader, keepAlive time.Duration) {
+	for {
+		if keepAlive > 0 {
+			c.conn.SetReadDeadline(time.Now().Add(keepAlive * 3 / 2))
+		} else {
+			c.conn.SetReadDeadline(time.Time{})
+		}
+		packet, err := readMQTTPacket(reader)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		if packet.Type != mqttPublish {
+			continue
+		}
+		topic, payload, id, err := decodeMQTTPublish(packet)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		if packet.Flags&0x06 != 0 {
+			// QoS 1/2 訊息 (broker 依訂閱降為 QoS 0，此處僅為保險) 以 PUBACK 確認
+			c.write(mqttPuback, 0, binary.BigEndian.AppendUint16(nil, id))
+		}
+		if c.onMessage != nil {
+			c.onMessage(topic, payload)
+		}
+	}
+}
+
+// pingLoop 每半個 keep alive 送出 PINGREQ
+func (c *mqttClient) pingLoop(keepAlive time.Duration) {
+	ticker := time.NewTicker(keepAlive / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if c.write(mqttPingreq, 0, nil) != nil {
+				return
+			}
+		}
+	}
+}
+
+// --- 封包編碼 ---
+
+// encodeMQTTConnect CONNECT 的可變標頭與內容
+func encodeMQTTConnect(opts mqttConnectOptions) []byte {
+	flags := byte(0x02) // clean session
+	if opts.WillTopic != "" {
+		flags |= 0x04 | (opts.WillQoS&0x03)<<3
+	}
+	if opts.Username != "" {
+		flags |= 0x80
+	}
+	if opts.Password != "" {
+		flags |= 0x40
+	}
+
+	body := appendMQTTString(nil, "MQTT")
+	body = append(body, 4, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(opts.KeepAlive/time.Second))
+	body = appendMQTTString(body, opts.ClientID)
+	if opts.WillTopic != "" {
+		body = appendMQTTString(body, opts.WillTopic)
+		body = binary.BigEndian.AppendUint16(body, uint16(len(opts.WillPayload)))
+		body = append(body, opts.WillPayload...)
+	}
+	if opts.Username != "" {
+		body = appendMQTTString(body, opts.Username)
+	}
+	if opts.Password != "" {
+		body = appendMQTTString(body, opts.Password)
+	}
+	return body
+}
+
+// encodeMQTTPacket 加上固定標頭 (剩餘長度為可變長度整數)
+func encodeMQTTPacket(packetType, flags byte, body []byte) []byte {
+	b := []byte{packetType<<4 | flags&0x0F}
+	n := len(body)
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if n == 0 {
+			break
+		}
+	}
+	return append(b, body...)
+}
+
+// readMQTTPacket 讀取一個封包
+func readMQTTPacket(r *bufio.Reader) (mqttPacket, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return mqttPacket{}, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return mqttPacket{}, errors.New(T("MQTT 封包剩餘長度無效"))
+		}
+		digit, err := r.ReadByte()
+		if err != nil {
+			return mqttPacket{}, err
+		}
+		length += int(digit&0x7F) * multiplier
+		multiplier *= 128
+		if digit&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return mqttPacket{}, err
+	}
+	return mqttPacket{Type: header >> 4, Flags: header & 0x0F, Body: body}, nil
+}
+
+// decodeMQTTPublish 解析 PUBLISH 的主題、內容與 packet identifier (QoS 0 時為 0)
+func decodeMQTTPublish(p mqttPacket) (topic string, payload []byte, id uint16, err error) {
+	topic, rest, ok := consumeMQTTString(p.Body)
+	if !ok {
+		return "", nil, 0, errors.New(T("MQTT PUBLISH 封包格式錯誤"))
+	}
+	if p.Flags&0x06 != 0 {
+		if len(rest) < 2 {
+			return "", nil, 0, errors.New(T("MQTT PUBLISH 封包格式錯誤"))
+		}
+		id, rest = binary.BigEndian.Uint16(rest), rest[2:]
+	}
+	return topic, rest, id, nil
+}
+
+// appendMQTTString 以 2 bytes 長度前綴附加字串
+func appendMQTTString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// consumeMQTTString 讀取 2 bytes 長度前綴的字串
+func consumeMQTTString(b []byte) (string, []byte, bool) {
+	if len(b) < 2 {
+		return "", nil, false
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, false
+	}
+	return string(b[2 : 2+n]), b[2+n:], true
+}
@@ -2,7 +2,14 @@ package main
 
 import (
 	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
 	"net"
+	"os"
+	"path/filepath"
 
 	"go.uber.org/zap"
 )
@@ -15,6 +22,9 @@ type NetworkProvisioner interface {
 	// Teardown 移除虛擬 IP
 	Teardown(ctx context.Context) error
 
+	// Remove 移除指定的虛擬 IP
+	Remove(ctx context.Context, ips ...net.IP) error
+
 	// List 列出已配置的 IP
 	List(ctx context.Context) ([]net.IP, error)
 
@@ -22,16 +32,69 @@ type NetworkProvisioner interface {
 	Validate(ranges []IPRange) error
 }
 
-// NewNetworkProvisioner 建立網路配置器
-func NewNetworkProvisioner(interfaceName string, logger *zap.Logger) NetworkProvisioner {
-	return newPlatformProvisioner(interfaceName, logger)
+// NewNetworkProvisioner 建立網路配置器 (依 IP 範圍指定的介面配置，未指定者使用 config.Interface；
+// dummy/macvlan 模式下未指定者改用專用介面)
+func NewNetworkProvisioner(config NetworkConfig, logger *zap.Logger) NetworkProvisioner {
+	base := BaseProvisioner{
+		InterfaceName: config.Interface,
+		Ranges:        config.IPRanges,
+		Logger:        logger,
+	}
+	base.Announce = config.Announce
+	base.AnnounceRate = config.AnnounceRate
+	base.StateFile = config.StateFile
+	if link := config.DedicatedLink(); link != "" {
+		base.InterfaceName = link
+		base.Mode = config.Mode
+		base.Parent = config.ParentInterface()
+		base.MTU = config.MTU
+	}
+	return newPlatformProvisioner(base)
 }
 
 // BaseProvisioner 基礎配置器 (共用邏輯)
 type BaseProvisioner struct {
-	InterfaceName string
+	InterfaceName string    // 預設網路介面 (dummy/macvlan 模式為專用介面)
+	Ranges        []IPRange // 最近一次 Setup 的範圍，決定各 IP 所在的介面
 	Logger        *zap.Logger
 	ConfiguredIPs []net.IP
+
+	// 專用介面 (Mode 為空字串表示 alias 模式，不建立)
+	Mode   string
+	Parent string
+	MTU    int
+
+	// 配置後發送 ARP/NDP 通告 (AnnounceRate 為每秒通告的 IP 數，0 = 不限制)
+	Announce     bool
+	AnnounceRate float64
+
+	// 已配置 IP 的狀態檔 (空字串表示不記錄)
+	StateFile string
+}
+
+// dedicated 是否使用 dummy/macvlan 專用介面
+func (p *BaseProvisioner) dedicated() bool {
+	return p.Mode != ""
+}
+
+// network 目前的網路配置
+func (p *BaseProvisioner) network() NetworkConfig {
+	return NetworkConfig{Interface: p.InterfaceName, IPRanges: p.Ranges}
+}
+
+// interfaceFor IP 所在的網路介面
+func (p *BaseProvisioner) interfaceFor(ip net.IP) string {
+	network := p.network()
+	name, _ := network.InterfaceFor(ip)
+	return name
+}
+
+// rangeInterface IP 範圍所在的網路介面
+func (p *BaseProvisioner) rangeInterface(r IPRange) string {
+	if r.Interface != "" {
+		return r.Interface
+	}
+	return p.InterfaceName
 }
 
 // Validate 驗證 IP 範圍
@@ -44,6 +107,16 @@ func (p *BaseProvisioner) Validate(ranges []IPRange) error {
 	return nil
 }
 
+// forget 自已配置列表移除指定 IP
+func (p *BaseProvisioner) forget(ip net.IP) {
+	for i, configured := range p.ConfiguredIPs {
+		if configured.Equal(ip) {
+			p.ConfiguredIPs = append(p.ConfiguredIPs[:i], p.ConfiguredIPs[i+1:]...)
+			return
+		}
+	}
+}
+
 // expandAllRanges 展開所有 IP 範圍
 func (p *BaseProvisioner) expandAllRanges(ranges []IPRange) ([]net.IP, error) {
 	var allIPs []net.IP
@@ -56,3 +129,149 @@ func (p *BaseProvisioner) expandAllRanges(ranges []IPRange) ([]net.IP, error) {
 	}
 	return allIPs, nil
 }
+
+// gratuitousARP 宣告 ip 位於 mac 的 gratuitous ARP 訊框 (乙太網路廣播的 ARP request，sender 與 target 皆為 ip)
+func gratuitousARP(mac net.HardwareAddr, ip net.IP) []byte {
+	frame := make([]byte, 42)
+	copy(frame[0:6], broadcastMAC)
+	copy(frame[6:12], mac)
+	binary.BigEndian.PutUint16(frame[12:14], etherTypeARP)
+
+	arp := frame[14:]
+	binary.BigEndian.PutUint16(arp[0:2], 1)      // 硬體類型: 乙太網路
+	binary.BigEndian.PutUint16(arp[2:4], 0x0800) // 協定類型: IPv4
+	arp[4], arp[5] = 6, 4
+	binary.BigEndian.PutUint16(arp[6:8], 1) // request
+	copy(arp[8:14], mac)
+	copy(arp[14:18], ip.To4())
+	copy(arp[24:28], ip.To4())
+	return frame
+}
+
+// unsolicitedNA 宣告 ip 位於 mac 的 unsolicited Neighbor Advertisement (ICMPv6 訊息，checksum 由核心填入)
+func unsolicitedNA(mac net.HardwareAddr, ip net.IP) []byte {
+	msg := make([]byte, 32)
+	msg[0] = 136  // Neighbor Advertisement
+	msg[4] = 0x20 // Override 旗標 (非回應 solicitation，不設 Solicited)
+	copy(msg[8:24], ip.To16())
+	msg[24], msg[25] = 2, 1 // Target Link-Layer Address 選項，長度 8 bytes
+	copy(msg[26:32], mac)
+	return msg
+}
+
+// etherTypeARP ARP 的乙太網路類型
+const etherTypeARP = 0x0806
+
+// broadcastMAC 乙太網路廣播位址
+var broadcastMAC = net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+
+// DefaultNetworkStateFile 預設的網路狀態檔
+const DefaultNetworkStateFile = "/var/lib/modbussim/network-state.json"
+
+// ownerLabelSuffix 模擬器建立的 IPv4 位址標籤後綴 (標籤需以介面名稱開頭，例如 eth0:mbs)
+const ownerLabelSuffix = ":mbs"
+
+// ownerLabel 介面上由模擬器建立的位址標籤 (超過 15 字元上限時不標記)
+func ownerLabel(iface string) string {
+	if len(iface)+len(ownerLabelSuffix) > 15 {
+		return ""
+	}
+	return iface + ownerLabelSuffix
+}
+
+// networkState 已配置 IP 的狀態檔內容 (只記錄由模擬器新增的 IP，不含原本就存在的位址)
+type networkState struct {
+	Addresses []provisionedAddress `json:"addresses"`
+}
+
+// provisionedAddress 由模擬器新增的 IP
+type provisionedAddress struct {
+	IP        string `json:"ip"`
+	Interface string `json:"interface"`
+}
+
+// loadNetworkState 讀取狀態檔 (不存在時為空的狀態)
+func loadNetworkState(path string) (*networkState, error) {
+	state := &networkState{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf(T("讀取網路狀態檔失敗: %w"), err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf(T("解析網路狀態檔失敗: %w"), err)
+	}
+	return state, nil
+}
+
+// save 寫入狀態檔 (先寫入暫存檔再更名)；沒有任何 IP 時刪除狀態檔
+func (s *networkState) save(path string) error {
+	if len(s.Addresses) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf(T("寫入網路狀態檔失敗: %w"), err)
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf(T("寫入網路狀態檔失敗: %w"), err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf(T("寫入網路狀態檔失敗: %w"), err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf(T("寫入網路狀態檔失敗: %w"), err)
+	}
+	return nil
+}
+
+// add 記錄 IP 配置在 iface 上 (已記錄時更新介面)
+func (s *networkState) add(ip net.IP, iface string) {
+	for i, addr := range s.Addresses {
+		if addr.IP == ip.String() {
+			s.Addresses[i].Interface = iface
+			return
+		}
+	}
+	s.Addresses = append(s.Addresses, provisionedAddress{IP: ip.String(), Interface: iface})
+}
+
+// remove 移除 IP 的記錄
+func (s *networkState) remove(ip net.IP) {
+	for i, addr := range s.Addresses {
+		if addr.IP == ip.String() {
+			s.Addresses = append(s.Addresses[:i], s.Addresses[i+1:]...)
+			return
+		}
+	}
+}
+
+// updateState 讀取狀態檔、套用 update 後寫回 (未設定狀態檔時略過；失敗僅記錄警告，不影響 IP 配置)
+func (p *BaseProvisioner) updateState(update func(*networkState)) {
+	if p.StateFile == "" {
+		return
+	}
+	state, err := loadNetworkState(p.StateFile)
+	if err == nil {
+		update(state)
+		err = state.save(p.StateFile)
+	}
+	if err != nil && p.Logger != nil {
+		p.Logger.Warn(T("更新網路狀態檔失敗"), zap.String("path", p.StateFile), zap.Error(err))
+	}
+}
+
+// loadState 讀取狀態檔 (未設定狀態檔時為空的狀態)
+func (p *BaseProvisioner) loadState() (*networkState, error) {
+	if p.StateFile == "" {
+		return &networkState{}, nil
+	}
+	return loadNetworkState(p.StateFile)
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// CommandProvisioner 以系統指令配置虛擬 IP 的配置器 (macOS: ifconfig alias；Windows: netsh)
+// 與 Linux 相同地將新增的 IP 記錄到狀態檔，teardown/list 依狀態檔比對介面目前的位址
+type CommandProvisioner struct {
+	BaseProvisioner
+
+	// 新增與移除 IP 的指令 (第一個元素為執行檔)
+	addCommand    func(iface string, ip net.IP) []string
+	removeCommand func(iface string, ip net.IP) []string
+
+	// 執行指令與查詢介面位址的方式 (nil 表示實際執行系統指令與查詢介面，測試時替換)
+	run   func(ctx context.Context, args []string) error
+	addrs func(name string) (map[string]bool, error)
+}
+
+// ifconfigAddCommand macOS 新增 IP 的指令 (IPv4 以 /32、IPv6 以 /128 的 alias 加入)
+func ifconfigAddCommand(iface string, ip net.IP) []string {
+	if ip.To4() == nil {
+		return []string{"ifconfig", iface, "inet6", ip.String(), "prefixlen", "128", "alias"}
+	}
+	return []string{"ifconfig", iface, "alias", ip.String(), "255.255.255.255"}
+}
+
+// ifconfigRemoveCommand macOS 移除 IP 的指令
+func ifconfigRemoveCommand(iface string, ip net.IP) []string {
+	if ip.To4() == nil {
+		return []string{"ifconfig", iface, "inet6", ip.String(), "-alias"}
+	}
+	return []string{"ifconfig", iface, "-alias", ip.String()}
+}
+
+// netshAddCommand Windows 新增 IP 的指令 (store=active 重開機後即消失；skipassource 避免成為對外連線的來源位址)
+func netshAddCommand(iface string, ip net.IP) []string {
+	if ip.To4() == nil {
+		return []string{"netsh", "interface", "ipv6", "add", "address", iface, ip.String(), "store=active", "skipassource=true"}
+	}
+	return []string{"netsh", "interface", "ipv4", "add", "address", iface, ip.String(), "255.255.255.255", "store=active", "skipassource=true"}
+}
+
+// netshRemoveCommand Windows 移除 IP 的指令
+func netshRemoveCommand(iface string, ip net.IP) []string {
+	family := "ipv4"
+	if ip.To4() == nil {
+		family = "ipv6"
+	}
+	return []string{"netsh", "interface", family, "delete", "address", iface, ip.String(), "store=active"}
+}
+
+// runCommand 執行指令
+func (p *CommandProvisioner) runCommand(ctx context.Context, args []string) error {
+	if p.run != nil {
+		return p.run(ctx, args)
+	}
+	return runCommand(ctx, args)
+}
+
+// interfaceAddrs 網路介面目前的 IP
+func (p *CommandProvisioner) interfaceAddrs(name string) (map[string]bool, error) {
+	if p.addrs != nil {
+		return p.addrs(name)
+	}
+	return interfaceAddrs(name)
+}
+
+// Setup 設置虛擬 IP
+func (p *CommandProvisioner) Setup(ctx context.Context, ranges []IPRange) error {
+	// 驗證
+	if err := p.Validate(ranges); err != nil {
+		return err
+	}
+	if p.dedicated() {
+		return errors.New(T("dummy/macvlan 模式僅在 Linux 上支援"))
+	}
+	p.Ranges = ranges
+
+	// 先確認所有網路介面存在，避免只配置了一部分
+	live := make(map[string]map[string]bool)
+	for _, r := range ranges {
+		name := p.rangeInterface(r)
+		if _, ok := live[name]; ok {
+			continue
+		}
+		addrs, err := p.interfaceAddrs(name)
+		if err != nil {
+			return err
+		}
+		live[name] = addrs
+	}
+
+	// 記錄新增的 IP 到狀態檔 (中途取消時也記錄已新增的部分)
+	owned := make(map[string][]net.IP)
+	defer func() {
+		if len(owned) == 0 {
+			return
+		}
+		p.updateState(func(state *networkState) {
+			for name, ips := range owned {
+				for _, ip := range ips {
+					state.add(ip, name)
+				}
+			}
+		})
+	}()
+
+	successCount, total := 0, 0
+	for _, r := range ranges {
+		name := p.rangeInterface(r)
+
+		// 展開 IP 範圍
+		ips, err := r.Expand()
+		if err != nil {
+			return fmt.Errorf(T("展開 IP 範圍失敗: %w"), err)
+		}
+		total += len(ips)
+
+		p.Logger.Info(T("正在設置虛擬 IP"),
+			zap.String("interface", name),
+			zap.Int("count", len(ips)),
+		)
+
+		for _, ip := range ips {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+
+			// 原本就存在的 IP 不記錄到狀態檔
+			if live[name][ip.String()] {
+				p.Logger.Debug(T("IP 已存在"), zap.String("ip", ip.String()))
+				successCount++
+				p.ConfiguredIPs = append(p.ConfiguredIPs, ip)
+				continue
+			}
+
+			if err := p.runCommand(ctx, p.addCommand(name, ip)); err != nil {
+				p.Logger.Warn(T("添加 IP 失敗"),
+					zap.String("ip", ip.String()),
+					zap.String("interface", name),
+					zap.Error(err),
+				)
+				continue
+			}
+
+			successCount++
+			p.ConfiguredIPs = append(p.ConfiguredIPs, ip)
+			owned[name] = append(owned[name], ip)
+			p.Logger.Debug(T("已添加 IP"), zap.String("ip", ip.String()), zap.String("interface", name))
+		}
+	}
+
+	p.Logger.Info(T("虛擬 IP 設置完成"),
+		zap.Int("success", successCount),
+		zap.Int("total", total),
+	)
+
+	return nil
+}
+
+// Teardown 移除本程序配置與狀態檔記錄的虛擬 IP
+func (p *CommandProvisioner) Teardown(ctx context.Context) error {
+	state, err := p.loadState()
+	if err != nil {
+		return err
+	}
+	owned := p.ownedAddresses(state)
+
+	network := p.network()
+	p.Logger.Info(T("正在移除虛擬 IP"),
+		zap.Strings("interfaces", network.Interfaces()),
+		zap.Int("count", len(owned)),
+	)
+
+	removedCount, staleCount := 0, 0
+	for _, a := range owned {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		// 已不存在的 IP (例如已手動移除或重開機) 只清除記錄
+		if !a.Live {
+			staleCount++
+			state.remove(a.IP)
+			p.Logger.Debug(T("IP 已不存在"), zap.String("ip", a.IP.String()), zap.String("interface", a.Interface))
+			continue
+		}
+
+		if err := p.runCommand(ctx, p.removeCommand(a.Interface, a.IP)); err != nil {
+			p.Logger.Warn(T("移除 IP 失敗"),
+				zap.String("ip", a.IP.String()),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		removedCount++
+		state.remove(a.IP)
+		p.Logger.Debug(T("已移除 IP"), zap.String("ip", a.IP.String()))
+	}
+
+	p.ConfiguredIPs = nil
+	if p.StateFile != "" {
+		if err := state.save(p.StateFile); err != nil {
+			p.Logger.Warn(T("更新網路狀態檔失敗"), zap.String("path", p.StateFile), zap.Error(err))
+		}
+	}
+
+	p.Logger.Info(T("虛擬 IP 移除完成"),
+		zap.Int("removed", removedCount),
+		zap.Int("stale", staleCount),
+	)
+
+	return nil
+}
+
+// Remove 移除指定的虛擬 IP
+func (p *CommandProvisioner) Remove(ctx context.Context, ips ...net.IP) error {
+	var removed []net.IP
+	defer func() {
+		if len(removed) == 0 {
+			return
+		}
+		p.updateState(func(state *networkState) {
+			for _, ip := range removed {
+				state.remove(ip)
+			}
+		})
+	}()
+
+	for _, ip := range ips {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		if err := p.runCommand(ctx, p.removeCommand(p.interfaceFor(ip), ip)); err != nil {
+			return fmt.Errorf(T("移除 IP %s 失敗: %w"), ip.String(), err)
+		}
+
+		p.forget(ip)
+		removed = append(removed, ip)
+		p.Logger.Debug(T("已移除 IP"), zap.String("ip", ip.String()))
+	}
+
+	return nil
+}
+
+// List 列出由模擬器配置且目前仍存在的 IP (依狀態檔比對介面目前的位址)
+func (p *CommandProvisioner) List(ctx context.Context) ([]net.IP, error) {
+	state, err := p.loadState()
+	if err != nil {
+		return nil, err
+	}
+
+	var ips []net.IP
+	stale := 0
+	for _, a := range p.ownedAddresses(state) {
+		if !a.Live {
+			stale++
+			continue
+		}
+		ips = append(ips, a.IP)
+	}
+	if stale > 0 {
+		p.Logger.Warn(T("狀態檔中有已不存在的 IP (執行 network teardown 清除)"),
+			zap.String("path", p.StateFile),
+			zap.Int("count", stale),
+		)
+	}
+
+	return ips, nil
+}
+
+// commandOwnedAddress 由模擬器配置的 IP
+type commandOwnedAddress struct {
+	IP        net.IP
+	Interface string
+	Live      bool // 目前仍在介面上
+}
+
+// ownedAddresses 彙整本程序配置與狀態檔記錄的 IP，並比對介面目前的位址
+func (p *CommandProvisioner) ownedAddresses(state *networkState) []commandOwnedAddress {
+	live := make(map[string]map[string]bool)
+	lookup := func(name string) map[string]bool {
+		if addrs, ok := live[name]; ok {
+			return addrs
+		}
+		// 介面不存在時其上的 IP 都已不存在
+		addrs, _ := p.interfaceAddrs(name)
+		live[name] = addrs
+		return addrs
+	}
+
+	var owned []commandOwnedAddress
+	seen := make(map[string]bool)
+	add := func(ip net.IP, name string) {
+		if ip == nil || seen[ip.String()] {
+			return
+		}
+		seen[ip.String()] = true
+		owned = append(owned, commandOwnedAddress{IP: ip, Interface: name, Live: lookup(name)[ip.String()]})
+	}
+
+	for _, ip := range p.ConfiguredIPs {
+		add(ip, p.interfaceFor(ip))
+	}
+	for _, addr := range state.Addresses {
+		add(net.ParseIP(addr.IP), addr.Interface)
+	}
+
+	slices.SortFunc(owned, func(a, b commandOwnedAddress) int {
+		return bytes.Compare(a.IP.To16(), b.IP.To16())
+	})
+	return owned
+}
+
+// interfaceAddrs 網路介面目前的 IP
+func interfaceAddrs(name string) (map[string]bool, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, fmt.Errorf(T("找不到網路介面 %s: %w"), name, err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf(T("列出 IP 失敗: %w"), err)
+	}
+
+	ips := make(map[string]bool, len(addrs))
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			ips[ipNet.IP.String()] = true
+		}
+	}
+	return ips, nil
+}
+
+// runCommand 執行系統指令，失敗時錯誤訊息附上指令與輸出
+func runCommand(ctx context.Context, args []string) error {
+	output, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
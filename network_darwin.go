@@ -0,0 +1,12 @@
+//go:build darwin
+
+package main
+
+// newPlatformProvisioner macOS 以 ifconfig alias 配置虛擬 IP (需 root)
+func newPlatformProvisioner(base BaseProvisioner) NetworkProvisioner {
+	return &CommandProvisioner{
+		BaseProvisioner: base,
+		addCommand:      ifconfigAddCommand,
+		removeCommand:   ifconfigRemoveCommand,
+	}
+}
@@ -3,9 +3,14 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"fmt"
 	"net"
+	"slices"
+	"syscall"
+	"time"
 
 	"github.com/vishvananda/netlink"
 	"go.uber.org/zap"
@@ -14,110 +19,471 @@ import (
 // LinuxProvisioner Linux 網路配置器
 type LinuxProvisioner struct {
 	BaseProvisioner
-	link netlink.Link
+	links map[string]netlink.Link
 }
 
-func newPlatformProvisioner(interfaceName string, logger *zap.Logger) NetworkProvisioner {
+func newPlatformProvisioner(base BaseProvisioner) NetworkProvisioner {
 	return &LinuxProvisioner{
-		BaseProvisioner: BaseProvisioner{
-			InterfaceName: interfaceName,
-			Logger:        logger,
-		},
+		BaseProvisioner: base,
+		links:           make(map[string]netlink.Link),
 	}
 }
 
+// linkByName 取得網路介面 (快取查詢結果)
+func (p *LinuxProvisioner) linkByName(name string) (netlink.Link, error) {
+	if link, ok := p.links[name]; ok {
+		return link, nil
+	}
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return nil, fmt.Errorf(T("找不到網路介面 %s: %w"), name, err)
+	}
+	p.links[name] = link
+	return link, nil
+}
+
 // Setup 設置虛擬 IP (使用 netlink)
 func (p *LinuxProvisioner) Setup(ctx context.Context, ranges []IPRange) error {
 	// 驗證
 	if err := p.Validate(ranges); err != nil {
 		return err
 	}
+	p.Ranges = ranges
 
-	// 取得網路介面
-	link, err := netlink.LinkByName(p.InterfaceName)
+	if p.dedicated() {
+		if err := p.ensureLink(); err != nil {
+			return err
+		}
+	}
+
+	// 先確認所有網路介面存在，避免只配置了一部分
+	for _, r := range ranges {
+		if _, err := p.linkByName(p.rangeInterface(r)); err != nil {
+			return err
+		}
+	}
+
+	// 記錄新增的 IP 到狀態檔 (中途取消時也記錄已新增的部分)
+	owned := make(map[string][]net.IP)
+	defer func() {
+		if len(owned) == 0 {
+			return
+		}
+		p.updateState(func(state *networkState) {
+			for name, ips := range owned {
+				for _, ip := range ips {
+					state.add(ip, name)
+				}
+			}
+		})
+	}()
+
+	successCount, total := 0, 0
+	for _, r := range ranges {
+		name := p.rangeInterface(r)
+		link := p.links[name]
+
+		// 展開 IP 範圍
+		ips, err := r.Expand()
+		if err != nil {
+			return fmt.Errorf(T("展開 IP 範圍失敗: %w"), err)
+		}
+		total += len(ips)
+
+		p.Logger.Info(T("正在設置虛擬 IP"),
+			zap.String("interface", name),
+			zap.Int("count", len(ips)),
+		)
+
+		// 添加 IP
+		var added []net.IP
+		for _, ip := range ips {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+
+			addr := &netlink.Addr{
+				IPNet: &net.IPNet{
+					IP:   ip,
+					Mask: net.CIDRMask(32, 32),
+				},
+			}
+			if ip.To4() != nil {
+				addr.Label = ownerLabel(name)
+			}
+
+			if err := netlink.AddrAdd(link, addr); err != nil {
+				// 如果 IP 已存在，忽略錯誤 (原本就存在的 IP 不記錄到狀態檔)
+				if err.Error() == "file exists" {
+					p.Logger.Debug(T("IP 已存在"), zap.String("ip", ip.String()))
+					successCount++
+					p.ConfiguredIPs = append(p.ConfiguredIPs, ip)
+					added = append(added, ip)
+					continue
+				}
+				p.Logger.Warn(T("添加 IP 失敗"),
+					zap.String("ip", ip.String()),
+					zap.String("interface", name),
+					zap.Error(err),
+				)
+				continue
+			}
+
+			successCount++
+			p.ConfiguredIPs = append(p.ConfiguredIPs, ip)
+			added = append(added, ip)
+			owned[name] = append(owned[name], ip)
+			p.Logger.Debug(T("已添加 IP"), zap.String("ip", ip.String()), zap.String("interface", name))
+		}
+
+		if p.Announce {
+			p.announce(ctx, link, added)
+		}
+	}
+
+	p.Logger.Info(T("虛擬 IP 設置完成"),
+		zap.Int("success", successCount),
+		zap.Int("total", total),
+	)
+
+	return nil
+}
+
+// announce 對 ips 發送 gratuitous ARP (IPv6 為 unsolicited NA)，讓上游立即學習 IP 與 MAC 的對應，
+// 避免第一次輪詢新 IP 時等待 ARP 解析而逾時；失敗僅記錄警告
+func (p *LinuxProvisioner) announce(ctx context.Context, link netlink.Link, ips []net.IP) {
+	attrs := link.Attrs()
+	if len(attrs.HardwareAddr) != 6 || attrs.Flags&net.FlagLoopback != 0 || len(ips) == 0 {
+		return
+	}
+
+	var v4, v6 []net.IP
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			v4 = append(v4, ip)
+		} else {
+			v6 = append(v6, ip)
+		}
+	}
+
+	// 設定速率時逐一發送，避免上游一次收到上千個通告
+	interval := rampInterval(p.AnnounceRate)
+	sent := 0
+	if len(v4) > 0 {
+		n, err := sendGratuitousARP(ctx, attrs.Index, attrs.HardwareAddr, v4, interval)
+		if err != nil {
+			p.Logger.Warn(T("發送 gratuitous ARP 失敗"), zap.String("interface", attrs.Name), zap.Error(err))
+		}
+		sent += n
+	}
+	for i, ip := range v6 {
+		if (i > 0 || len(v4) > 0) && !pace(ctx, interval) {
+			break
+		}
+		if err := sendUnsolicitedNA(attrs.Index, attrs.HardwareAddr, ip); err != nil {
+			p.Logger.Warn(T("發送 unsolicited NA 失敗"), zap.String("ip", ip.String()), zap.Error(err))
+			continue
+		}
+		sent++
+	}
+
+	p.Logger.Info(T("已發送 ARP/NDP 通告"),
+		zap.String("interface", attrs.Name),
+		zap.Int("sent", sent),
+		zap.Int("total", len(ips)),
+	)
+}
+
+// sendGratuitousARP 以 AF_PACKET socket 在介面上廣播各 IP 的 gratuitous ARP (每個間隔 interval)，回傳成功發送的數量
+func sendGratuitousARP(ctx context.Context, ifindex int, mac net.HardwareAddr, ips []net.IP, interval time.Duration) (int, error) {
+	protocol := htons(etherTypeARP)
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(protocol))
+	if err != nil {
+		return 0, err
+	}
+	defer syscall.Close(fd)
+
+	addr := &syscall.SockaddrLinklayer{Protocol: protocol, Ifindex: ifindex, Halen: 6}
+	copy(addr.Addr[:], broadcastMAC)
+
+	sent := 0
+	for i, ip := range ips {
+		if i > 0 && !pace(ctx, interval) {
+			return sent, ctx.Err()
+		}
+		if err := syscall.Sendto(fd, gratuitousARP(mac, ip), 0, addr); err != nil {
+			return sent, fmt.Errorf("%s: %w", ip, err)
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// sendUnsolicitedNA 自 ip 向 all-nodes (ff02::1) 送出 unsolicited NA (hop limit 255)
+func sendUnsolicitedNA(ifindex int, mac net.HardwareAddr, ip net.IP) error {
+	fd, err := syscall.Socket(syscall.AF_INET6, syscall.SOCK_RAW, syscall.IPPROTO_ICMPV6)
 	if err != nil {
-		return fmt.Errorf("找不到網路介面 %s: %w", p.InterfaceName, err)
+		return err
 	}
-	p.link = link
+	defer syscall.Close(fd)
 
-	// 展開 IP 範圍
-	ips, err := p.expandAllRanges(ranges)
+	if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, syscall.IPV6_MULTICAST_HOPS, 255); err != nil {
+		return err
+	}
+	if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, syscall.IPV6_MULTICAST_IF, ifindex); err != nil {
+		return err
+	}
+	// 位址仍在重複位址偵測 (DAD) 中時無法作為來源，綁定失敗
+	src := &syscall.SockaddrInet6{}
+	copy(src.Addr[:], ip.To16())
+	if err := syscall.Bind(fd, src); err != nil {
+		return err
+	}
+
+	dst := &syscall.SockaddrInet6{ZoneId: uint32(ifindex)}
+	copy(dst.Addr[:], net.IPv6linklocalallnodes)
+	return syscall.Sendto(fd, unsolicitedNA(mac, ip), 0, dst)
+}
+
+// htons 主機位元組順序轉為網路位元組順序
+func htons(v uint16) uint16 {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	return binary.NativeEndian.Uint16(b[:])
+}
+
+// ensureLink 建立 dummy/macvlan 專用介面 (已存在時沿用)，設定 MTU 並啟用
+func (p *LinuxProvisioner) ensureLink() error {
+	link, err := netlink.LinkByName(p.InterfaceName)
+	if err == nil {
+		if link.Type() != p.Mode {
+			return fmt.Errorf(T("網路介面 %s 已存在且類型為 %s"), p.InterfaceName, link.Type())
+		}
+	} else {
+		attrs := netlink.NewLinkAttrs()
+		attrs.Name = p.InterfaceName
+		attrs.MTU = p.MTU
+
+		switch p.Mode {
+		case NetworkModeMacvlan:
+			parent, err := p.linkByName(p.Parent)
+			if err != nil {
+				return err
+			}
+			attrs.ParentIndex = parent.Attrs().Index
+			link = &netlink.Macvlan{LinkAttrs: attrs, Mode: netlink.MACVLAN_MODE_BRIDGE}
+		default:
+			link = &netlink.Dummy{LinkAttrs: attrs}
+		}
+		if err := netlink.LinkAdd(link); err != nil {
+			return fmt.Errorf(T("建立網路介面 %s 失敗: %w"), p.InterfaceName, err)
+		}
+		p.Logger.Info(T("已建立專用網路介面"),
+			zap.String("interface", p.InterfaceName),
+			zap.String("mode", p.Mode),
+			zap.String("parent", p.Parent),
+		)
+	}
+
+	if p.MTU != 0 && link.Attrs().MTU != p.MTU {
+		if err := netlink.LinkSetMTU(link, p.MTU); err != nil {
+			return fmt.Errorf(T("設定網路介面 %s 的 MTU 失敗: %w"), p.InterfaceName, err)
+		}
+	}
+	if err := netlink.LinkSetUp(link); err != nil {
+		return fmt.Errorf(T("啟用網路介面 %s 失敗: %w"), p.InterfaceName, err)
+	}
+	delete(p.links, p.InterfaceName)
+	return nil
+}
+
+// Teardown 移除虛擬 IP (dummy/macvlan 模式刪除專用介面，其上的 IP 一併移除)
+// 除了本程序配置的 IP，也移除狀態檔記錄與帶有模擬器標籤的 IP，因此可在另一個程序執行
+func (p *LinuxProvisioner) Teardown(ctx context.Context) error {
+	state, err := p.loadState()
 	if err != nil {
-		return fmt.Errorf("展開 IP 範圍失敗: %w", err)
+		return err
+	}
+	owned, err := p.ownedAddresses(state)
+	if err != nil {
+		return err
 	}
 
-	p.Logger.Info("正在設置虛擬 IP",
-		zap.String("interface", p.InterfaceName),
-		zap.Int("count", len(ips)),
+	network := p.network()
+	p.Logger.Info(T("正在移除虛擬 IP"),
+		zap.Strings("interfaces", network.Interfaces()),
+		zap.Int("count", len(owned)),
 	)
 
-	// 添加 IP
-	successCount := 0
-	for _, ip := range ips {
+	removedCount, staleCount := 0, 0
+	for _, a := range owned {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
 		}
 
-		addr := &netlink.Addr{
-			IPNet: &net.IPNet{
-				IP:   ip,
-				Mask: net.CIDRMask(32, 32),
-			},
+		// 已不存在的 IP (例如已手動移除或重開機) 只清除記錄
+		if !a.Live {
+			staleCount++
+			state.remove(a.IP)
+			p.Logger.Debug(T("IP 已不存在"), zap.String("ip", a.IP.String()), zap.String("interface", a.Interface))
+			continue
 		}
 
-		if err := netlink.AddrAdd(link, addr); err != nil {
-			// 如果 IP 已存在，忽略錯誤
-			if err.Error() == "file exists" {
-				p.Logger.Debug("IP 已存在", zap.String("ip", ip.String()))
-				successCount++
-				p.ConfiguredIPs = append(p.ConfiguredIPs, ip)
-				continue
-			}
-			p.Logger.Warn("添加 IP 失敗",
-				zap.String("ip", ip.String()),
+		link, err := p.linkByName(a.Interface)
+		if err != nil {
+			return err
+		}
+
+		if err := netlink.AddrDel(link, &netlink.Addr{IPNet: a.IPNet}); err != nil {
+			p.Logger.Warn(T("移除 IP 失敗"),
+				zap.String("ip", a.IP.String()),
 				zap.Error(err),
 			)
 			continue
 		}
 
-		successCount++
-		p.ConfiguredIPs = append(p.ConfiguredIPs, ip)
-		p.Logger.Debug("已添加 IP", zap.String("ip", ip.String()))
+		removedCount++
+		state.remove(a.IP)
+		p.Logger.Debug(T("已移除 IP"), zap.String("ip", a.IP.String()))
 	}
 
-	p.Logger.Info("虛擬 IP 設置完成",
-		zap.Int("success", successCount),
-		zap.Int("total", len(ips)),
+	if p.dedicated() {
+		if err := p.deleteLink(); err != nil {
+			return err
+		}
+	}
+
+	p.ConfiguredIPs = nil
+	if p.StateFile != "" {
+		if err := state.save(p.StateFile); err != nil {
+			p.Logger.Warn(T("更新網路狀態檔失敗"), zap.String("path", p.StateFile), zap.Error(err))
+		}
+	}
+
+	p.Logger.Info(T("虛擬 IP 移除完成"),
+		zap.Int("removed", removedCount),
+		zap.Int("stale", staleCount),
 	)
 
 	return nil
 }
 
-// Teardown 移除虛擬 IP
-func (p *LinuxProvisioner) Teardown(ctx context.Context) error {
-	if p.link == nil {
-		link, err := netlink.LinkByName(p.InterfaceName)
+// deleteLink 刪除 dummy/macvlan 專用介面 (不存在時略過)
+func (p *LinuxProvisioner) deleteLink() error {
+	link, err := netlink.LinkByName(p.InterfaceName)
+	if err != nil {
+		p.Logger.Debug(T("專用網路介面不存在"), zap.String("interface", p.InterfaceName))
+	} else if err := netlink.LinkDel(link); err != nil {
+		return fmt.Errorf(T("刪除網路介面 %s 失敗: %w"), p.InterfaceName, err)
+	} else {
+		p.Logger.Info(T("已刪除專用網路介面"), zap.String("interface", p.InterfaceName))
+	}
+	delete(p.links, p.InterfaceName)
+	return nil
+}
+
+// ownedAddress 由模擬器配置的 IP
+type ownedAddress struct {
+	*net.IPNet
+	Interface string
+	Live      bool // 目前仍在介面上
+}
+
+// ownedAddresses 彙整本程序配置的 IP、狀態檔記錄的 IP 與介面上帶有模擬器標籤的 IP，並比對目前的 netlink 狀態
+func (p *LinuxProvisioner) ownedAddresses(state *networkState) ([]ownedAddress, error) {
+	network := p.network()
+	names := network.Interfaces()
+	for _, addr := range state.Addresses {
+		if !slices.Contains(names, addr.Interface) {
+			names = append(names, addr.Interface)
+		}
+	}
+
+	// 各介面目前的位址 (介面不存在時其上的 IP 都已不存在)
+	live := make(map[string]map[string]netlink.Addr)
+	for _, name := range names {
+		link, err := netlink.LinkByName(name)
+		if err != nil {
+			continue
+		}
+		addrs, err := netlink.AddrList(link, netlink.FAMILY_ALL)
 		if err != nil {
-			return fmt.Errorf("找不到網路介面 %s: %w", p.InterfaceName, err)
+			return nil, fmt.Errorf(T("列出 IP 失敗: %w"), err)
+		}
+		live[name] = make(map[string]netlink.Addr, len(addrs))
+		for _, addr := range addrs {
+			live[name][addr.IP.String()] = addr
 		}
-		p.link = link
 	}
 
-	p.Logger.Info("正在移除虛擬 IP",
-		zap.String("interface", p.InterfaceName),
-		zap.Int("count", len(p.ConfiguredIPs)),
-	)
+	var owned []ownedAddress
+	seen := make(map[string]bool)
+	add := func(ip net.IP, name string) {
+		if ip == nil || seen[ip.String()] {
+			return
+		}
+		seen[ip.String()] = true
+		if addr, ok := live[name][ip.String()]; ok {
+			owned = append(owned, ownedAddress{IPNet: addr.IPNet, Interface: name, Live: true})
+			return
+		}
+		owned = append(owned, ownedAddress{IPNet: &net.IPNet{IP: ip, Mask: net.CIDRMask(32, 32)}, Interface: name})
+	}
 
-	removedCount := 0
 	for _, ip := range p.ConfiguredIPs {
+		add(ip, p.interfaceFor(ip))
+	}
+	for _, addr := range state.Addresses {
+		add(net.ParseIP(addr.IP), addr.Interface)
+	}
+	for _, name := range names {
+		label := ownerLabel(name)
+		if label == "" {
+			continue
+		}
+		for _, addr := range live[name] {
+			if addr.Label == label {
+				add(addr.IP, name)
+			}
+		}
+	}
+
+	slices.SortFunc(owned, func(a, b ownedAddress) int {
+		return bytes.Compare(a.IP.To16(), b.IP.To16())
+	})
+	return owned, nil
+}
+
+// Remove 移除指定的虛擬 IP
+func (p *LinuxProvisioner) Remove(ctx context.Context, ips ...net.IP) error {
+	var removed []net.IP
+	defer func() {
+		if len(removed) == 0 {
+			return
+		}
+		p.updateState(func(state *networkState) {
+			for _, ip := range removed {
+				state.remove(ip)
+			}
+		})
+	}()
+
+	for _, ip := range ips {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
 		}
 
+		link, err := p.linkByName(p.interfaceFor(ip))
+		if err != nil {
+			return err
+		}
+
 		addr := &netlink.Addr{
 			IPNet: &net.IPNet{
 				IP:   ip,
@@ -125,43 +491,89 @@ func (p *LinuxProvisioner) Teardown(ctx context.Context) error {
 			},
 		}
 
-		if err := netlink.AddrDel(p.link, addr); err != nil {
-			p.Logger.Warn("移除 IP 失敗",
-				zap.String("ip", ip.String()),
-				zap.Error(err),
-			)
-			continue
+		if err := netlink.AddrDel(link, addr); err != nil {
+			return fmt.Errorf(T("移除 IP %s 失敗: %w"), ip.String(), err)
 		}
 
-		removedCount++
-		p.Logger.Debug("已移除 IP", zap.String("ip", ip.String()))
+		p.forget(ip)
+		removed = append(removed, ip)
+		p.Logger.Debug(T("已移除 IP"), zap.String("ip", ip.String()))
 	}
 
-	p.ConfiguredIPs = nil
-
-	p.Logger.Info("虛擬 IP 移除完成",
-		zap.Int("removed", removedCount),
-	)
-
 	return nil
 }
 
-// List 列出已配置的 IP
+// List 列出由模擬器配置且目前仍存在的 IP (依狀態檔與位址標籤比對 netlink 狀態，不含介面原有的位址)
 func (p *LinuxProvisioner) List(ctx context.Context) ([]net.IP, error) {
-	link, err := netlink.LinkByName(p.InterfaceName)
+	state, err := p.loadState()
 	if err != nil {
-		return nil, fmt.Errorf("找不到網路介面 %s: %w", p.InterfaceName, err)
+		return nil, err
 	}
-
-	addrs, err := netlink.AddrList(link, netlink.FAMILY_V4)
+	owned, err := p.ownedAddresses(state)
 	if err != nil {
-		return nil, fmt.Errorf("列出 IP 失敗: %w", err)
+		return nil, err
 	}
 
 	var ips []net.IP
-	for _, addr := range addrs {
-		ips = append(ips, addr.IP)
+	stale := 0
+	for _, a := range owned {
+		if !a.Live {
+			stale++
+			continue
+		}
+		ips = append(ips, a.IP)
+	}
+	if stale > 0 {
+		p.Logger.Warn(T("狀態檔中有已不存在的 IP (執行 network teardown 清除)"),
+			zap.String("path", p.StateFile),
+			zap.Int("count", stale),
+		)
 	}
 
 	return ips, nil
 }
+
+// bindToDevice 回傳將 socket 限定於指定網路介面的 net.ListenConfig.Control (SO_BINDTODEVICE)
+func bindToDevice(name string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		err := c.Control(func(fd uintptr) {
+			sockErr = syscall.SetsockoptString(int(fd), syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, name)
+		})
+		if err != nil {
+			return err
+		}
+		if sockErr != nil {
+			return fmt.Errorf(T("綁定網路介面 %s 失敗: %w"), name, sockErr)
+		}
+		return nil
+	}
+}
+
+// soOriginalDst getsockopt(SOL_IP, SO_ORIGINAL_DST)：iptables REDIRECT/DNAT 前的目的位址
+const soOriginalDst = 80
+
+// originalDst 取得連線被 iptables 轉向前的目的 IP
+func originalDst(conn net.Conn) (net.IP, error) {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return nil, fmt.Errorf(T("不支援的連線類型: %T"), conn)
+	}
+	raw, err := tcpConn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var addr *syscall.IPv6Mreq // 與 sockaddr_in 同樣以 16 bytes 開頭：family(2) port(2) addr(4)
+	var sockErr error
+	err = raw.Control(func(fd uintptr) {
+		addr, sockErr = syscall.GetsockoptIPv6Mreq(int(fd), syscall.IPPROTO_IP, soOriginalDst)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if sockErr != nil {
+		return nil, sockErr
+	}
+	return net.IPv4(addr.Multiaddr[4], addr.Multiaddr[5], addr.Multiaddr[6], addr.Multiaddr[7]), nil
+}
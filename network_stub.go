@@ -1,4 +1,4 @@
-//go:build !linux
+//go:build !linux && !darwin && !windows
 
 package main
 
@@ -10,18 +10,13 @@ import (
 	"go.uber.org/zap"
 )
 
-// StubProvisioner 非 Linux 平台的 stub 配置器
+// StubProvisioner 其他平台 (非 Linux、macOS、Windows) 的 stub 配置器
 type StubProvisioner struct {
 	BaseProvisioner
 }
 
-func newPlatformProvisioner(interfaceName string, logger *zap.Logger) NetworkProvisioner {
-	return &StubProvisioner{
-		BaseProvisioner: BaseProvisioner{
-			InterfaceName: interfaceName,
-			Logger:        logger,
-		},
-	}
+func newPlatformProvisioner(base BaseProvisioner) NetworkProvisioner {
+	return &StubProvisioner{BaseProvisioner: base}
 }
 
 // Setup 設置虛擬 IP (stub)
@@ -30,15 +25,17 @@ func (p *StubProvisioner) Setup(ctx context.Context, ranges []IPRange) error {
 	if err := p.Validate(ranges); err != nil {
 		return err
 	}
+	p.Ranges = ranges
 
 	// 展開 IP 範圍
 	ips, err := p.expandAllRanges(ranges)
 	if err != nil {
-		return fmt.Errorf("展開 IP 範圍失敗: %w", err)
+		return fmt.Errorf(T("展開 IP 範圍失敗: %w"), err)
 	}
 
-	p.Logger.Warn("虛擬 IP 配置僅在 Linux 上支援，使用模擬模式",
-		zap.String("interface", p.InterfaceName),
+	network := p.network()
+	p.Logger.Warn(T("此平台不支援虛擬 IP 配置，使用模擬模式"),
+		zap.Strings("interfaces", network.Interfaces()),
 		zap.Int("count", len(ips)),
 	)
 
@@ -50,7 +47,7 @@ func (p *StubProvisioner) Setup(ctx context.Context, ranges []IPRange) error {
 
 // Teardown 移除虛擬 IP (stub)
 func (p *StubProvisioner) Teardown(ctx context.Context) error {
-	p.Logger.Warn("虛擬 IP 移除僅在 Linux 上支援，使用模擬模式",
+	p.Logger.Warn(T("此平台不支援虛擬 IP 移除，使用模擬模式"),
 		zap.String("interface", p.InterfaceName),
 		zap.Int("count", len(p.ConfiguredIPs)),
 	)
@@ -59,12 +56,25 @@ func (p *StubProvisioner) Teardown(ctx context.Context) error {
 	return nil
 }
 
+// Remove 移除指定的虛擬 IP (stub)
+func (p *StubProvisioner) Remove(ctx context.Context, ips ...net.IP) error {
+	p.Logger.Warn(T("此平台不支援虛擬 IP 移除，使用模擬模式"),
+		zap.String("interface", p.InterfaceName),
+		zap.Int("count", len(ips)),
+	)
+
+	for _, ip := range ips {
+		p.forget(ip)
+	}
+	return nil
+}
+
 // List 列出已配置的 IP (stub)
 func (p *StubProvisioner) List(ctx context.Context) ([]net.IP, error) {
 	// 在非 Linux 平台，返回本地 IP
 	addrs, err := net.InterfaceAddrs()
 	if err != nil {
-		return nil, fmt.Errorf("取得本地 IP 失敗: %w", err)
+		return nil, fmt.Errorf(T("取得本地 IP 失敗: %w"), err)
 	}
 
 	var ips []net.IP
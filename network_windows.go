@@ -0,0 +1,12 @@
+//go:build windows
+
+package main
+
+// newPlatformProvisioner Windows 以 netsh 配置虛擬 IP (需系統管理員權限；store=active 重開機後即消失)
+func newPlatformProvisioner(base BaseProvisioner) NetworkProvisioner {
+	return &CommandProvisioner{
+		BaseProvisioner: base,
+		addCommand:      netshAddCommand,
+		removeCommand:   netshRemoveCommand,
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import "math"
+
+// 預設額定值 (內建設定檔的預設量測值)
+const (
+	DefaultNominalVoltage   = 220.0
+	DefaultNominalCurrent   = 15.5
+	DefaultNominalFrequency = 60.0
+)
+
+// 額定值隨機化的預設參數
+const (
+	DefaultRandomizeVoltageSpread = 2.0     // ± V
+	DefaultRandomizeCurrentRatio  = 0.2     // 標準差相對於平均的比例
+	DefaultRandomizeEnergyMax     = 10000.0 // kWh
+)
+
+// MeterNominal 電表的額定量測值 (場景以此為波動的中心值)
+type MeterNominal struct {
+	Voltage   float64 `json:"voltage"`
+	Current   float64 `json:"current"`
+	Frequency float64 `json:"frequency"`
+}
+
+// defaultNominal 預設的額定量測值
+func defaultNominal() MeterNominal {
+	return MeterNominal{
+		Voltage:   DefaultNominalVoltage,
+		Current:   DefaultNominalCurrent,
+		Frequency: DefaultNominalFrequency,
+	}
+}
+
+// randomizeNominal 為單一 Slave 抽出額定電壓 (均勻分佈)、額定電流 (常態分佈) 與初始累計電能，
+// 設定為暫存器映射表的額定值並寫入對應的暫存器
+func randomizeNominal(registers *RegisterMap, cfg NominalRandomization, random *lockedRand) MeterNominal {
+	spread := cfg.VoltageSpread
+	if spread == 0 {
+		spread = DefaultRandomizeVoltageSpread
+	}
+	mean := cfg.CurrentMean
+	if mean == 0 {
+		mean = DefaultNominalCurrent
+	}
+	stddev := cfg.CurrentStdDev
+	if stddev == 0 {
+		stddev = mean * DefaultRandomizeCurrentRatio
+	}
+	energyMax := cfg.EnergyMax
+	if energyMax == 0 {
+		energyMax = DefaultRandomizeEnergyMax
+	}
+
+	nominal := registers.Nominal()
+	nominal.Voltage += (random.Float64()*2 - 1) * spread
+	// 電流截斷在平均的 ±3 個標準差內，且不為負
+	nominal.Current = math.Max(0, mean+math.Max(-3, math.Min(3, random.NormFloat64()))*stddev)
+	registers.SetNominal(nominal)
+
+	registers.SetScaledValue(40001, nominal.Voltage)
+	registers.SetScaledValue(40002, nominal.Current)
+	registers.SetScaledValue(40004, math.Floor(random.Float64()*energyMax))
+	registers.EvaluateExpressions()
+	updatePhases(registers, nominal.Voltage, nominal.Current, -1, 0)
+	return nominal
+}
+
+// setMeasuredNominal 管理 API 寫入電壓、電流或頻率 (40001-40003) 時一併更新額定值，
+// 場景改以寫入的值為中心繼續波動，寫入的值不會在下個更新週期被蓋回；其他位址不處理
+func setMeasuredNominal(registers *RegisterMap, address uint16, value float64) {
+	nominal := registers.Nominal()
+	switch address {
+	case 40001:
+		nominal.Voltage = value
+	case 40002:
+		nominal.Current = value
+	case 40003:
+		nominal.Frequency = value
+	default:
+		return
+	}
+	registers.SetNominal(nominal)
+}
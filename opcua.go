@@ -0,0 +1,1675 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// OPC UA 鏡像伺服器預設值
+const (
+	DefaultOPCUAListen  = ":4840"
+	OPCUANamespaceURI   = "urn:modbussim:slaves"
+	OPCUAApplicationURI = "urn:modbussim"
+)
+
+// OPC UA 通訊參數
+const (
+	opcuaBufferSize      = 65536            // 收發分段 (chunk) 的大小上限
+	opcuaMaxMessageSize  = 16 << 20         // 分段組合後的請求大小上限
+	opcuaChannelLifetime = time.Hour        // secure channel 的有效期 (client 應在到期前更新)
+	opcuaSessionTimeout  = 20 * time.Minute // 回應給 client 的 session 逾時 (session 隨連線關閉而結束)
+	opcuaNonceLength     = 32
+)
+
+// OPC UA 的 URI
+const (
+	opcuaSecurityPolicyNone = "http://opcfoundation.org/UA/SecurityPolicy#None"
+	opcuaTransportProfile   = "http://opcfoundation.org/UA-Profile/Transport/uatcp-uasc-uabinary"
+	opcuaNamespace0         = "http://opcfoundation.org/UA/"
+)
+
+// OPC UA 狀態碼 (僅列出使用到的)
+const (
+	opcuaGood                      uint32 = 0
+	opcuaBadDecodingError          uint32 = 0x80070000
+	opcuaBadServiceUnsupported     uint32 = 0x800B0000
+	opcuaBadNothingToDo            uint32 = 0x800F0000
+	opcuaBadIdentityTokenInvalid   uint32 = 0x80200000
+	opcuaBadSecureChannelIDInvalid uint32 = 0x80220000
+	opcuaBadSessionIDInvalid       uint32 = 0x80250000
+	opcuaBadSessionNotActivated    uint32 = 0x80270000
+	opcuaBadNodeIDUnknown          uint32 = 0x80340000
+	opcuaBadAttributeIDInvalid     uint32 = 0x80350000
+	opcuaBadNotWritable            uint32 = 0x803B0000
+	opcuaBadOutOfRange             uint32 = 0x803C0000
+	opcuaBadSecurityModeRejected   uint32 = 0x80540000
+	opcuaBadSecurityPolicyRejected uint32 = 0x80550000
+	opcuaBadTypeMismatch           uint32 = 0x80740000
+	opcuaBadTCPMessageTypeInvalid  uint32 = 0x807E0000
+	opcuaBadTCPMessageTooLarge     uint32 = 0x80800000
+	opcuaBadOutOfService           uint32 = 0x808D0000
+)
+
+// OPC UA 服務與結構的 binary encoding ID (僅列出使用到的)
+const (
+	opcuaServiceFault              = 397
+	opcuaFindServersRequest        = 422
+	opcuaFindServersResponse       = 425
+	opcuaGetEndpointsRequest       = 428
+	opcuaGetEndpointsResponse      = 431
+	opcuaOpenSecureChannelRequest  = 446
+	opcuaOpenSecureChannelResponse = 449
+	opcuaCreateSessionRequest      = 461
+	opcuaCreateSessionResponse     = 464
+	opcuaActivateSessionRequest    = 467
+	opcuaActivateSessionResponse   = 470
+	opcuaCloseSessionRequest       = 473
+	opcuaCloseSessionResponse      = 476
+	opcuaBrowseRequest             = 527
+	opcuaBrowseResponse            = 530
+	opcuaReadRequest               = 631
+	opcuaReadResponse              = 634
+	opcuaWriteRequest              = 673
+	opcuaWriteResponse             = 676
+	opcuaAnonymousIdentityToken    = 321
+)
+
+// OPC UA 節點屬性 ID
+const (
+	opcuaAttributeNodeID                  = 1
+	opcuaAttributeNodeClass               = 2
+	opcuaAttributeBrowseName              = 3
+	opcuaAttributeDisplayName             = 4
+	opcuaAttributeDescription             = 5
+	opcuaAttributeWriteMask               = 6
+	opcuaAttributeUserWriteMask           = 7
+	opcuaAttributeEventNotifier           = 12
+	opcuaAttributeValue                   = 13
+	opcuaAttributeDataType                = 14
+	opcuaAttributeValueRank               = 15
+	opcuaAttributeArrayDimensions         = 16
+	opcuaAttributeAccessLevel             = 17
+	opcuaAttributeUserAccessLevel         = 18
+	opcuaAttributeMinimumSamplingInterval = 19
+	opcuaAttributeHistorizing             = 20
+)
+
+// OPC UA 列舉值與旗標
+const (
+	opcuaProtocolVersion           = 0
+	opcuaMessageSecurityModeNone   = 1
+	opcuaSecureChannelRequestRenew = 1
+	opcuaApplicationTypeServer     = 0
+	opcuaUserTokenTypeAnonymous    = 0
+	opcuaAnonymousIdentityPolicy   = "anonymous"
+	opcuaEndpointSecurityLevel     = 0
+	opcuaTimestampsToReturnSource  = 0
+	opcuaTimestampsToReturnServer  = 1
+	opcuaTimestampsToReturnBoth    = 2
+	opcuaBrowseDirectionForward    = 0
+	opcuaBrowseDirectionInverse    = 1
+	opcuaNodeClassObject           = 1
+	opcuaNodeClassVariable         = 2
+	opcuaNodeClassObjectType       = 8
+	opcuaNodeClassVariableType     = 16
+	opcuaAccessLevelRead           = 0x01
+	opcuaAccessLevelWrite          = 0x02
+	opcuaValueRankScalar           = -1
+	opcuaValueRankOneDimension     = 1
+	opcuaMinBufferSize             = 8192
+	opcuaSymmetricHeaderSize       = 24 // 訊息標頭 8 + channel ID 4 + token ID 4 + 序號 8
+)
+
+// OPC UA 命名空間 0 的節點 (僅列出使用到的)
+const (
+	opcuaBoolean              = 1
+	opcuaDouble               = 11
+	opcuaString               = 12
+	opcuaReferences           = 31
+	opcuaNonHierarchical      = 32
+	opcuaHierarchical         = 33
+	opcuaHasChildren          = 34
+	opcuaOrganizes            = 35
+	opcuaHasTypeDefinition    = 40
+	opcuaAggregates           = 44
+	opcuaHasProperty          = 46
+	opcuaHasComponent         = 47
+	opcuaFolderType           = 61
+	opcuaBaseDataVariableType = 63
+	opcuaPropertyType         = 68
+	opcuaRootFolder           = 84
+	opcuaObjectsFolder        = 85
+	opcuaServerType           = 2004
+	opcuaServer               = 2253
+	opcuaServerArray          = 2254
+	opcuaNamespaceArray       = 2255
+)
+
+// opcuaSlavesNamespace Slave 節點的命名空間索引 (NamespaceArray 中的 OPCUANamespaceURI)
+const opcuaSlavesNamespace = 1
+
+// UA Binary 的編碼旗標
+const (
+	opcuaNodeIDTwoByte         = 0x00
+	opcuaNodeIDFourByte        = 0x01
+	opcuaNodeIDNumeric         = 0x02
+	opcuaNodeIDString          = 0x03
+	opcuaNodeIDGUID            = 0x04
+	opcuaNodeIDByteString      = 0x05
+	opcuaExpandedNamespaceURI  = 0x80
+	opcuaExpandedServerIndex   = 0x40
+	opcuaExtensionObjectBinary = 0x01
+	opcuaExtensionObjectXML    = 0x02
+)
+
+// opcuaReferenceParents 參考類型的父類型 (判斷 includeSubtypes)
+var opcuaReferenceParents = map[uint32]uint32{
+	opcuaNonHierarchical:   opcuaReferences,
+	opcuaHierarchical:      opcuaReferences,
+	opcuaHasChildren:       opcuaHierarchical,
+	opcuaOrganizes:         opcuaHierarchical,
+	opcuaHasTypeDefinition: opcuaNonHierarchical,
+	opcuaAggregates:        opcuaHasChildren,
+	opcuaHasProperty:       opcuaAggregates,
+	opcuaHasComponent:      opcuaAggregates,
+}
+
+// opcuaTypeNames 類型定義節點的名稱
+var opcuaTypeNames = map[uint32]string{
+	opcuaFolderType:           "FolderType",
+	opcuaBaseDataVariableType: "BaseDataVariableType",
+	opcuaPropertyType:         "PropertyType",
+	opcuaServerType:           "ServerType",
+}
+
+// opcuaNodeID OPC UA 節點 ID (Name 非空時為字串 ID，否則為數值 ID)
+type opcuaNodeID struct {
+	Namespace uint16
+	ID        uint32
+	Name      string
+}
+
+// opcuaNS0 命名空間 0 的數值節點 ID
+func opcuaNS0(id uint32) opcuaNodeID {
+	return opcuaNodeID{ID: id}
+}
+
+// opcuaQualifiedName 瀏覽名稱
+type opcuaQualifiedName struct {
+	Namespace uint16
+	Name      string
+}
+
+// opcuaLocalizedText 顯示文字 (不指定語系)
+type opcuaLocalizedText string
+
+// opcuaNode 位址空間中的一個節點
+type opcuaNode struct {
+	ID          opcuaNodeID
+	Class       uint32
+	BrowseName  opcuaQualifiedName
+	Description string
+	TypeDef     uint32
+
+	Parent    *opcuaNodeID // 根節點為 nil
+	ParentRef uint32       // 父節點指向此節點的參考類型
+
+	// 變數節點
+	DataType  uint32
+	ValueRank int32
+	Access    byte
+	Value     any    // 固定值 (命名空間 0 的變數)
+	Slave     *Slave // 暫存器變數所屬的 Slave
+	Meta      *RegisterMeta
+}
+
+// OPCUAServer 以 OPC UA (opc.tcp、UA Binary) 提供各 Slave 的已定義暫存器，僅支援 SecurityPolicy None 與匿名登入
+// 位址空間：Objects 下每個 Slave 一個資料夾 (ns=1;s=<IP>)，資料夾下為已定義暫存器的變數 (ns=1;s=<IP>/<位址>)；
+// 值即時讀取自暫存器，Slave 未運行時狀態為 BadOutOfService。支援 Browse、Read 與 Write (不支援訂閱，client 以 Read 輪詢)
+type OPCUAServer struct {
+	config OPCUAConfig
+	engine *Engine
+	logger *zap.Logger
+
+	listener net.Listener
+	wg       sync.WaitGroup
+	nextID   atomic.Uint32 // secure channel 與 session 的 ID
+	mu       sync.Mutex
+	conns    map[*opcuaConn]struct{}
+	sessions map[opcuaNodeID]*opcuaSession // 依 authentication token
+}
+
+// opcuaSession 一個 session (隨建立或最後啟用它的連線關閉而結束)
+type opcuaSession struct {
+	id        uint32
+	conn      *opcuaConn
+	activated bool
+}
+
+// NewOPCUAServer 建立 OPC UA 鏡像伺服器 (呼叫 Start 開始監聽)
+func NewOPCUAServer(config OPCUAConfig, engine *Engine, logger *zap.Logger) *OPCUAServer {
+	return &OPCUAServer{
+		config:   config,
+		engine:   engine,
+		logger:   logger.With(zap.String("component", "opcua")),
+		conns:    make(map[*opcuaConn]struct{}),
+		sessions: make(map[opcuaNodeID]*opcuaSession),
+	}
+}
+
+// Start 開始監聽並接受連線
+func (s *OPCUAServer) Start() error {
+	listener, err := net.Listen("tcp", s.config.Listen)
+	if err != nil {
+		return fmt.Errorf(T("OPC UA 伺服器監聽失敗: %w"), err)
+	}
+	s.listener = listener
+	s.wg.Add(1)
+	go s.acceptLoop()
+	s.logger.Info(T("已啟動 OPC UA 伺服器"), zap.String("addr", listener.Addr().String()))
+	return nil
+}
+
+// Addr 監聽位址
+func (s *OPCUAServer) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Stop 停止監聽並關閉所有連線
+func (s *OPCUAServer) Stop() {
+	s.listener.Close()
+	s.mu.Lock()
+	for c := range s.conns {
+		c.conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *OPCUAServer) acceptLoop() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		c := &opcuaConn{server: s, conn: conn, reader: bufio.NewReader(conn), partial: make(map[uint32][]byte)}
+		s.mu.Lock()
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			if err := c.serve(); err != nil && !errors.Is(err, net.ErrClosed) && !errors.Is(err, io.EOF) {
+				s.logger.Debug(T("OPC UA 連線結束"), zap.String("remote", conn.RemoteAddr().String()), zap.Error(err))
+			}
+			c.conn.Close()
+			s.mu.Lock()
+			delete(s.conns, c)
+			for token, session := range s.sessions {
+				if session.conn == c {
+					delete(s.sessions, token)
+				}
+			}
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// --- 連線 ---
+
+// opcuaConn 一個 client 連線 (一條 secure channel)
+type opcuaConn struct {
+	server *OPCUAServer
+	conn   net.Conn
+	reader *bufio.Reader
+
+	channelID   uint32
+	tokenID     uint32
+	seq         uint32
+	chunkSize   int               // 回應的分段大小 (client 的接收緩衝區)
+	endpointURL string            // HEL 中 client 連線的位址
+	partial     map[uint32][]byte // 尚未收齊分段的請求 (依 request ID)
+	partialSize int
+}
+
+// serve 處理 HEL 後持續處理訊息，直到 client 關閉 secure channel 或連線中斷
+func (c *opcuaConn) serve() error {
+	c.conn.SetReadDeadline(time.Now().Add(mqttTimeout))
+	messageType, _, body, err := c.readMessage()
+	if err != nil {
+		return err
+	}
+	if messageType != "HEL" {
+		return c.sendError(opcuaBadTCPMessageTypeInvalid, "expected HEL")
+	}
+	r := &opcuaReader{b: body}
+	r.uint32() // protocol version
+	receiveBufferSize := r.uint32()
+	sendBufferSize := r.uint32()
+	r.uint32() // max message size
+	r.uint32() // max chunk count
+	c.endpointURL = r.string()
+	if r.err != nil || receiveBufferSize < opcuaMinBufferSize || sendBufferSize < opcuaMinBufferSize {
+		return c.sendError(opcuaBadDecodingError, "invalid HEL")
+	}
+	c.chunkSize = int(min(receiveBufferSize, opcuaBufferSize))
+
+	ack := &opcuaWriter{}
+	ack.uint32(opcuaProtocolVersion)
+	ack.uint32(min(sendBufferSize, opcuaBufferSize)) // 伺服器的接收緩衝區
+	ack.uint32(uint32(c.chunkSize))
+	ack.uint32(opcuaMaxMessageSize)
+	ack.uint32(0) // 不限制分段數
+	if err := c.writeRaw("ACK", 'F', ack.b); err != nil {
+		return err
+	}
+
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(opcuaChannelLifetime * 5 / 4))
+		messageType, chunk, body, err := c.readMessage()
+		if err != nil {
+			return err
+		}
+		switch messageType {
+		case "OPN":
+			err = c.handleOpen(body)
+		case "MSG":
+			err = c.handleMessage(chunk, body)
+		case "CLO":
+			return nil
+		default:
+			err = c.sendError(opcuaBadTCPMessageTypeInvalid, messageType)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readMessage 讀取一個分段 (訊息類型、分段類型與標頭之後的內容)
+func (c *opcuaConn) readMessage() (string, byte, []byte, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return "", 0, nil, err
+	}
+	size := binary.LittleEndian.Uint32(header[4:])
+	if size < 8 || size > opcuaBufferSize {
+		return "", 0, nil, c.sendError(opcuaBadTCPMessageTooLarge, "")
+	}
+	body := make([]byte, size-8)
+	if _, err := io.ReadFull(c.reader, body); err != nil {
+		return "", 0, nil, err
+	}
+	return string(header[:3]), header[3], body, nil
+}
+
+// writeRaw 送出一個分段
+func (c *opcuaConn) writeRaw(messageType string, chunk byte, body []byte) error {
+	b := make([]byte, 8, 8+len(body))
+	copy(b, messageType)
+	b[3] = chunk
+	binary.LittleEndian.PutUint32(b[4:], uint32(8+len(body)))
+	c.conn.SetWriteDeadline(time.Now().Add(mqttTimeout))
+	_, err := c.conn.Write(append(b, body...))
+	return err
+}
+
+// sendError 送出 ERR 後結束連線
+func (c *opcuaConn) sendError(code uint32, reason string) error {
+	w := &opcuaWriter{}
+	w.uint32(code)
+	w.string(reason)
+	c.writeRaw("ERR", 'F', w.b)
+	return fmt.Errorf(T("OPC UA 連線錯誤 0x%08X: %s"), code, reason)
+}
+
+// nextSeq 下一個序號
+func (c *opcuaConn) nextSeq() uint32 {
+	c.seq++
+	return c.seq
+}
+
+// handleOpen 處理 OpenSecureChannel (開啟或更新，僅支援 SecurityPolicy None)
+func (c *opcuaConn) handleOpen(body []byte) error {
+	r := &opcuaReader{b: body}
+	channelID := r.uint32()
+	policy := r.string()
+	r.bytes()  // sender certificate
+	r.bytes()  // receiver certificate thumbprint
+	r.uint32() // sequence number
+	requestID := r.uint32()
+	typeID := r.nodeID()
+	header := r.requestHeader()
+	r.uint32() // client protocol version
+	requestType := r.uint32()
+	mode := r.uint32()
+	r.bytes()  // client nonce
+	r.uint32() // requested lifetime
+	if r.err != nil || typeID != opcuaNS0(opcuaOpenSecureChannelRequest) {
+		return c.sendError(opcuaBadDecodingError, "invalid OpenSecureChannel")
+	}
+	if policy != opcuaSecurityPolicyNone {
+		return c.sendError(opcuaBadSecurityPolicyRejected, policy)
+	}
+	if mode != opcuaMessageSecurityModeNone {
+		return c.sendError(opcuaBadSecurityModeRejected, strconv.FormatUint(uint64(mode), 10))
+	}
+	if requestType == opcuaSecureChannelRequestRenew {
+		if channelID != c.channelID || c.channelID == 0 {
+			return c.sendError(opcuaBadSecureChannelIDInvalid, "")
+		}
+		c.tokenID++
+	} else if c.channelID == 0 {
+		c.channelID = c.server.nextID.Add(1)
+		c.tokenID = 1
+	}
+
+	w := &opcuaWriter{}
+	w.uint32(c.channelID)
+	w.string(opcuaSecurityPolicyNone)
+	w.bytes(nil)
+	w.bytes(nil)
+	w.uint32(c.nextSeq())
+	w.uint32(requestID)
+	w.nodeID(opcuaNS0(opcuaOpenSecureChannelResponse))
+	w.responseHeader(header.handle, opcuaGood)
+	w.uint32(opcuaProtocolVersion)
+	w.uint32(c.channelID)
+	w.uint32(c.tokenID)
+	w.dateTime(time.Now())
+	w.uint32(uint32(opcuaChannelLifetime / time.Millisecond))
+	w.bytes(nil)
+	return c.writeRaw("OPN", 'F', w.b)
+}
+
+// handleMessage 組合分段後處理一個服務請求
+func (c *opcuaConn) handleMessage(chunk byte, body []byte) error {
+	r := &opcuaReader{b: body}
+	channelID := r.uint32()
+	r.uint32() // token ID
+	r.uint32() // sequence number
+	requestID := r.uint32()
+	if r.err != nil {
+		return c.sendError(opcuaBadDecodingError, "invalid MSG")
+	}
+	if channelID != c.channelID || c.channelID == 0 {
+		return c.sendError(opcuaBadSecureChannelIDInvalid, "")
+	}
+
+	switch chunk {
+	case 'C':
+		c.partialSize += len(r.b)
+		if c.partialSize > opcuaMaxMessageSize {
+			return c.sendError(opcuaBadTCPMessageTooLarge, "")
+		}
+		c.partial[requestID] = append(c.partial[requestID], r.b...)
+		return nil
+	case 'A':
+		c.partialSize -= len(c.partial[requestID])
+		delete(c.partial, requestID)
+		return nil
+	}
+	if prefix, ok := c.partial[requestID]; ok {
+		c.partialSize -= len(prefix)
+		delete(c.partial, requestID)
+		r.b = append(prefix, r.b...)
+	}
+
+	typeID, w := c.handleService(r)
+	return c.sendMessage(requestID, typeID, w.b)
+}
+
+// sendMessage 依 client 的接收緩衝區分段送出回應
+func (c *opcuaConn) sendMessage(requestID uint32, typeID uint32, body []byte) error {
+	w := &opcuaWriter{}
+	w.nodeID(opcuaNS0(typeID))
+	body = append(w.b, body...)
+
+	limit := c.chunkSize - opcuaSymmetricHeaderSize
+	for {
+		n := min(len(body), limit)
+		chunk := byte('F')
+		if n < len(body) {
+			chunk = 'C'
+		}
+		header := &opcuaWriter{}
+		header.uint32(c.channelID)
+		header.uint32(c.tokenID)
+		header.uint32(c.nextSeq())
+		header.uint32(requestID)
+		if err := c.writeRaw("MSG", chunk, append(header.b, body[:n]...)); err != nil {
+			return err
+		}
+		body = body[n:]
+		if chunk == 'F' {
+			return nil
+		}
+	}
+}
+
+// handleService 依請求類型呼叫服務，回傳回應類型與內容
+func (c *opcuaConn) handleService(r *opcuaReader) (uint32, *opcuaWriter) {
+	typeID := r.nodeID()
+	header := r.requestHeader()
+	if r.err != nil {
+		return c.serviceFault(header, opcuaBadDecodingError)
+	}
+
+	var (
+		responseType uint32
+		w            *opcuaWriter
+		status       uint32
+	)
+	switch typeID {
+	case opcuaNS0(opcuaFindServersRequest):
+		responseType, w = opcuaFindServersResponse, c.findServers(header)
+	case opcuaNS0(opcuaGetEndpointsRequest):
+		responseType, w = opcuaGetEndpointsResponse, c.getEndpoints(header, r)
+	case opcuaNS0(opcuaCreateSessionRequest):
+		responseType, w = opcuaCreateSessionResponse, c.createSession(header)
+	case opcuaNS0(opcuaActivateSessionRequest):
+		if status = c.activateSession(header, r); status == opcuaGood {
+			responseType, w = opcuaActivateSessionResponse, c.activateSessionResponse(header)
+		}
+	case opcuaNS0(opcuaCloseSessionRequest):
+		if status = c.closeSession(header); status == opcuaGood {
+			responseType, w = opcuaCloseSessionResponse, &opcuaWriter{}
+			w.responseHeader(header.handle, opcuaGood)
+		}
+	case opcuaNS0(opcuaBrowseRequest):
+		if status = c.checkSession(header); status == opcuaGood {
+			responseType, w = opcuaBrowseResponse, c.browse(header, r)
+		}
+	case opcuaNS0(opcuaReadRequest):
+		if status = c.checkSession(header); status == opcuaGood {
+			responseType, w = opcuaReadResponse, c.read(header, r)
+		}
+	case opcuaNS0(opcuaWriteRequest):
+		if status = c.checkSession(header); status == opcuaGood {
+			responseType, w = opcuaWriteResponse, c.write(header, r)
+		}
+	default:
+		status = opcuaBadServiceUnsupported
+	}
+	if status == opcuaGood && r.err != nil {
+		status = opcuaBadDecodingError
+	}
+	if status != opcuaGood {
+		return c.serviceFault(header, status)
+	}
+	return responseType, w
+}
+
+// serviceFault 以 ServiceFault 回應錯誤
+func (c *opcuaConn) serviceFault(header opcuaRequestHeader, status uint32) (uint32, *opcuaWriter) {
+	w := &opcuaWriter{}
+	w.responseHeader(header.handle, status)
+	return opcuaServiceFault, w
+}
+
+// --- 探索與 session ---
+
+// endpoint 伺服器的端點 (沿用 client 連線的位址，client 經由 NAT 或 port forward 連線時仍可使用)
+func (c *opcuaConn) endpoint(requested string) string {
+	if requested != "" {
+		return requested
+	}
+	if c.endpointURL != "" {
+		return c.endpointURL
+	}
+	return "opc.tcp://" + c.conn.LocalAddr().String()
+}
+
+func (c *opcuaConn) applicationDescription(w *opcuaWriter, endpoint string) {
+	w.string(OPCUAApplicationURI)
+	w.string(OPCUAApplicationURI)
+	w.localizedText("Modbus Simulator")
+	w.uint32(opcuaApplicationTypeServer)
+	w.string("") // gateway server URI
+	w.string("") // discovery profile URI
+	w.int32(1)
+	w.string(endpoint)
+}
+
+func (c *opcuaConn) endpointDescription(w *opcuaWriter, endpoint string) {
+	w.string(endpoint)
+	c.applicationDescription(w, endpoint)
+	w.bytes(nil) // server certificate
+	w.uint32(opcuaMessageSecurityModeNone)
+	w.string(opcuaSecurityPolicyNone)
+	w.int32(1)
+	w.string(opcuaAnonymousIdentityPolicy)
+	w.uint32(opcuaUserTokenTypeAnonymous)
+	w.string("") // issued token type
+	w.string("") // issuer endpoint URL
+	w.string("") // security policy URI
+	w.string(opcuaTransportProfile)
+	w.byte(opcuaEndpointSecurityLevel)
+}
+
+func (c *opcuaConn) findServers(header opcuaRequestHeader) *opcuaWriter {
+	w := &opcuaWriter{}
+	w.responseHeader(header.handle, opcuaGood)
+	w.int32(1)
+	c.applicationDescription(w, c.endpoint(""))
+	return w
+}
+
+func (c *opcuaConn) getEndpoints(header opcuaRequestHeader, r *opcuaReader) *opcuaWriter {
+	endpoint := c.endpoint(r.string())
+	w := &opcuaWriter{}
+	w.responseHeader(header.handle, opcuaGood)
+	w.int32(1)
+	c.endpointDescription(w, endpoint)
+	return w
+}
+
+func (c *opcuaConn) createSession(header opcuaRequestHeader) *opcuaWriter {
+	session := &opcuaSession{id: c.server.nextID.Add(1), conn: c}
+	token := opcuaNodeID{Namespace: opcuaSlavesNamespace, Name: hex.EncodeToString(opcuaNonce())}
+	c.server.mu.Lock()
+	c.server.sessions[token] = session
+	c.server.mu.Unlock()
+
+	w := &opcuaWriter{}
+	w.responseHeader(header.handle, opcuaGood)
+	w.nodeID(opcuaNodeID{Namespace: opcuaSlavesNamespace, ID: session.id})
+	w.nodeID(token)
+	w.float64(float64(opcuaSessionTimeout / time.Millisecond))
+	w.bytes(opcuaNonce())
+	w.bytes(nil) // server certificate
+	w.int32(1)
+	c.endpointDescription(w, c.endpoint(""))
+	w.int32(-1)  // server software certificates
+	w.string("") // server signature algorithm
+	w.bytes(nil) // server signature
+	w.uint32(opcuaMaxMessageSize)
+	return w
+}
+
+// activateSession 驗證身分 (僅接受匿名) 並將 session 綁定至此連線
+func (c *opcuaConn) activateSession(header opcuaRequestHeader, r *opcuaReader) uint32 {
+	r.string() // client signature algorithm
+	r.bytes()  // client signature
+	for n := r.arrayLength(); n > 0; n-- {
+		r.bytes() // software certificate
+		r.bytes() // signature
+	}
+	for n := r.arrayLength(); n > 0; n-- {
+		r.string() // locale ID
+	}
+	tokenType, _ := r.extensionObject()
+	if r.err != nil {
+		return opcuaBadDecodingError
+	}
+	if tokenType != opcuaNS0(0) && tokenType != opcuaNS0(opcuaAnonymousIdentityToken) {
+		return opcuaBadIdentityTokenInvalid
+	}
+
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	session, ok := c.server.sessions[header.authToken]
+	if !ok {
+		return opcuaBadSessionIDInvalid
+	}
+	session.conn = c
+	session.activated = true
+	return opcuaGood
+}
+
+func (c *opcuaConn) activateSessionResponse(header opcuaRequestHeader) *opcuaWriter {
+	w := &opcuaWriter{}
+	w.responseHeader(header.handle, opcuaGood)
+	w.bytes(opcuaNonce())
+	w.int32(0)  // results
+	w.int32(-1) // diagnostic infos
+	return w
+}
+
+func (c *opcuaConn) closeSession(header opcuaRequestHeader) uint32 {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	if _, ok := c.server.sessions[header.authToken]; !ok {
+		return opcuaBadSessionIDInvalid
+	}
+	delete(c.server.sessions, header.authToken)
+	return opcuaGood
+}
+
+// checkSession 確認請求使用已啟用的 session
+func (c *opcuaConn) checkSession(header opcuaRequestHeader) uint32 {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	session, ok := c.server.sessions[header.authToken]
+	switch {
+	case !ok:
+		return opcuaBadSessionIDInvalid
+	case !session.activated || session.conn != c:
+		return opcuaBadSessionNotActivated
+	}
+	return opcuaGood
+}
+
+// opcuaNonce 隨機的 nonce
+func opcuaNonce() []byte {
+	b := make([]byte, opcuaNonceLength)
+	rand.Read(b)
+	return b
+}
+
+// --- 位址空間 ---
+
+// slaves 提供的 Slave (符合 targets/tags，依 ID 排序)
+func (s *OPCUAServer) slaves() []*Slave {
+	var result []*Slave
+	for _, slave := range s.engine.ListSlaves() {
+		if s.config.matches(s.engine.config, slave.IP) {
+			result = append(result, slave)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// slave 依 IP 取得提供的 Slave
+func (s *OPCUAServer) slave(ip string) (*Slave, bool) {
+	addr := net.ParseIP(ip)
+	if addr == nil || addr.String() != ip || !s.config.matches(s.engine.config, addr) {
+		return nil, false
+	}
+	return s.engine.GetSlave(addr)
+}
+
+// lookup 依節點 ID 取得節點
+func (s *OPCUAServer) lookup(id opcuaNodeID) (*opcuaNode, bool) {
+	if id.Namespace == 0 && id.Name == "" {
+		return s.standardNode(id.ID)
+	}
+	if id.Namespace != opcuaSlavesNamespace || id.Name == "" {
+		return nil, false
+	}
+	ip, address, isVariable := strings.Cut(id.Name, "/")
+	slave, ok := s.slave(ip)
+	if !ok {
+		return nil, false
+	}
+	if !isVariable {
+		return s.folderNode(slave), true
+	}
+	addr, err := strconv.ParseUint(address, 10, 16)
+	if err != nil || strconv.FormatUint(addr, 10) != address {
+		return nil, false
+	}
+	meta, ok := slave.Registers().GetDefinition(uint16(addr))
+	if !ok {
+		return nil, false
+	}
+	return s.variableNode(slave, *meta), true
+}
+
+// standardNode 命名空間 0 中提供的節點
+func (s *OPCUAServer) standardNode(id uint32) (*opcuaNode, bool) {
+	node := &opcuaNode{ID: opcuaNS0(id), Class: opcuaNodeClassObject, TypeDef: opcuaFolderType}
+	parent := func(parent, ref uint32) {
+		p := opcuaNS0(parent)
+		node.Parent, node.ParentRef = &p, ref
+	}
+	switch id {
+	case opcuaRootFolder:
+		node.BrowseName.Name = "Root"
+	case opcuaObjectsFolder:
+		node.BrowseName.Name = "Objects"
+		parent(opcuaRootFolder, opcuaOrganizes)
+	case opcuaServer:
+		node.BrowseName.Name, node.TypeDef = "Server", opcuaServerType
+		parent(opcuaObjectsFolder, opcuaOrganizes)
+	case opcuaServerArray, opcuaNamespaceArray:
+		node.Class, node.TypeDef = opcuaNodeClassVariable, opcuaPropertyType
+		node.DataType, node.ValueRank, node.Access = opcuaString, opcuaValueRankOneDimension, opcuaAccessLevelRead
+		node.BrowseName.Name, node.Value = "ServerArray", []string{OPCUAApplicationURI}
+		if id == opcuaNamespaceArray {
+			node.BrowseName.Name, node.Value = "NamespaceArray", []string{opcuaNamespace0, OPCUANamespaceURI}
+		}
+		parent(opcuaServer, opcuaHasProperty)
+	default:
+		return nil, false
+	}
+	return node, true
+}
+
+// folderNode Slave 的資料夾
+func (s *OPCUAServer) folderNode(slave *Slave) *opcuaNode {
+	ip := slave.IP.String()
+	objects := opcuaNS0(opcuaObjectsFolder)
+	return &opcuaNode{
+		ID:          opcuaNodeID{Namespace: opcuaSlavesNamespace, Name: ip},
+		Class:       opcuaNodeClassObject,
+		BrowseName:  opcuaQualifiedName{Namespace: opcuaSlavesNamespace, Name: ip},
+		Description: fmt.Sprintf("Modbus slave %s (unit %d)", slave.ID, slave.UnitID),
+		TypeDef:     opcuaFolderType,
+		Parent:      &objects,
+		ParentRef:   opcuaOrganizes,
+		Slave:       slave,
+	}
+}
+
+// variableNode 已定義暫存器的變數 (字串類型為 String，其餘為縮放後的 Double)
+func (s *OPCUAServer) variableNode(slave *Slave, meta RegisterMeta) *opcuaNode {
+	ip := slave.IP.String()
+	folder := opcuaNodeID{Namespace: opcuaSlavesNamespace, Name: ip}
+	node := &opcuaNode{
+		ID:          opcuaNodeID{Namespace: opcuaSlavesNamespace, Name: ip + "/" + strconv.Itoa(int(meta.Address))},
+		Class:       opcuaNodeClassVariable,
+		BrowseName:  opcuaQualifiedName{Namespace: opcuaSlavesNamespace, Name: meta.Name},
+		Description: meta.Unit,
+		TypeDef:     opcuaBaseDataVariableType,
+		Parent:      &folder,
+		ParentRef:   opcuaHasComponent,
+		DataType:    opcuaDouble,
+		ValueRank:   opcuaValueRankScalar,
+		Access:      opcuaAccessLevelRead,
+		Slave:       slave,
+		Meta:        &meta,
+	}
+	if meta.DataType.IsString() {
+		node.DataType = opcuaString
+	}
+	if s.config.Writable && meta.Writable && meta.Expression == "" {
+		node.Access |= opcuaAccessLevelWrite
+	}
+	return node
+}
+
+// children 節點往下的階層參考
+func (s *OPCUAServer) children(node *opcuaNode) []*opcuaNode {
+	var ids []uint32
+	switch {
+	case node.ID == opcuaNS0(opcuaRootFolder):
+		ids = []uint32{opcuaObjectsFolder}
+	case node.ID == opcuaNS0(opcuaServer):
+		ids = []uint32{opcuaServerArray, opcuaNamespaceArray}
+	case node.ID == opcuaNS0(opcuaObjectsFolder):
+		server, _ := s.standardNode(opcuaServer)
+		result := []*opcuaNode{server}
+		for _, slave := range s.slaves() {
+			result = append(result, s.folderNode(slave))
+		}
+		return result
+	case node.Slave != nil && node.Meta == nil:
+		var result []*opcuaNode
+		for _, meta := range node.Slave.Registers().Definitions() {
+			result = append(result, s.variableNode(node.Slave, meta))
+		}
+		return result
+	}
+	var result []*opcuaNode
+	for _, id := range ids {
+		child, _ := s.standardNode(id)
+		result = append(result, child)
+	}
+	return result
+}
+
+// value 變數目前的值與狀態
+func (s *OPCUAServer) value(node *opcuaNode) (any, uint32) {
+	if node.Meta == nil {
+		return node.Value, opcuaGood
+	}
+	if node.Slave.State() != SlaveStateRunning {
+		return nil, opcuaBadOutOfService
+	}
+	registers := node.Slave.Registers()
+	if node.Meta.DataType.IsString() {
+		value, err := registers.GetString(node.Meta.Address)
+		if err != nil {
+			return nil, opcuaBadOutOfRange
+		}
+		return value, opcuaGood
+	}
+	value, err := registers.GetScaledValue(node.Meta.Address)
+	if err != nil {
+		return nil, opcuaBadOutOfRange
+	}
+	return value, opcuaGood
+}
+
+// attribute 節點的屬性值
+func (s *OPCUAServer) attribute(node *opcuaNode, attribute uint32) (any, uint32) {
+	switch attribute {
+	case opcuaAttributeNodeID:
+		return node.ID, opcuaGood
+	case opcuaAttributeNodeClass:
+		return int32(node.Class), opcuaGood
+	case opcuaAttributeBrowseName:
+		return node.BrowseName, opcuaGood
+	case opcuaAttributeDisplayName:
+		return opcuaLocalizedText(node.BrowseName.Name), opcuaGood
+	case opcuaAttributeDescription:
+		return opcuaLocalizedText(node.Description), opcuaGood
+	case opcuaAttributeWriteMask, opcuaAttributeUserWriteMask:
+		return uint32(0), opcuaGood
+	}
+	if node.Class == opcuaNodeClassObject {
+		if attribute == opcuaAttributeEventNotifier {
+			return byte(0), opcuaGood
+		}
+		return nil, opcuaBadAttributeIDInvalid
+	}
+	switch attribute {
+	case opcuaAttributeValue:
+		return s.value(node)
+	case opcuaAttributeDataType:
+		return opcuaNS0(node.DataType), opcuaGood
+	case opcuaAttributeValueRank:
+		return node.ValueRank, opcuaGood
+	case opcuaAttributeArrayDimensions:
+		if node.ValueRank == opcuaValueRankOneDimension {
+			return []uint32{0}, opcuaGood
+		}
+		return nil, opcuaGood
+	case opcuaAttributeAccessLevel, opcuaAttributeUserAccessLevel:
+		return node.Access, opcuaGood
+	case opcuaAttributeMinimumSamplingInterval:
+		// 暫存器依 Slave 的更新週期變化
+		if node.Slave != nil {
+			return float64(node.Slave.updateInterval()) / float64(time.Millisecond), opcuaGood
+		}
+		return float64(0), opcuaGood
+	case opcuaAttributeHistorizing:
+		return false, opcuaGood
+	}
+	return nil, opcuaBadAttributeIDInvalid
+}
+
+// writeValue 以工程值寫入暫存器變數
+func (s *OPCUAServer) writeValue(node *opcuaNode, value any) uint32 {
+	if node.Access&opcuaAccessLevelWrite == 0 {
+		return opcuaBadNotWritable
+	}
+	if node.Slave.State() != SlaveStateRunning {
+		return opcuaBadOutOfService
+	}
+	registers := node.Slave.Registers()
+	var err error
+	switch v := value.(type) {
+	case string:
+		if !node.Meta.DataType.IsString() {
+			return opcuaBadTypeMismatch
+		}
+		err = registers.SetString(node.Meta.Address, v)
+	case float64:
+		if node.Meta.DataType.IsString() {
+			return opcuaBadTypeMismatch
+		}
+		err = registers.SetScaledValue(node.Meta.Address, v)
+	default:
+		return opcuaBadTypeMismatch
+	}
+	if err != nil {
+		return opcuaBadOutOfRange
+	}
+	node.Slave.mu.Lock()
+	node.Slave.syncRegistersToServer()
+	node.Slave.mu.Unlock()
+	return opcuaGood
+}
+
+// --- Browse、Read、Write ---
+
+// opcuaReference 瀏覽結果的一個參考
+type opcuaReference struct {
+	refType uint32
+	forward bool
+	target  *opcuaNode
+}
+
+// referenceMatches 參考類型是否符合瀏覽條件 (空值表示全部)
+func opcuaReferenceMatches(refType uint32, want opcuaNodeID, includeSubtypes bool) bool {
+	if want == opcuaNS0(0) {
+		return true
+	}
+	if want.Namespace != 0 || want.Name != "" {
+		return false
+	}
+	for t := refType; ; {
+		if t == want.ID {
+			return true
+		}
+		parent, ok := opcuaReferenceParents[t]
+		if !includeSubtypes || !ok {
+			return false
+		}
+		t = parent
+	}
+}
+
+func (c *opcuaConn) browse(header opcuaRequestHeader, r *opcuaReader) *opcuaWriter {
+	r.nodeID() // view ID
+	r.int64()  // view timestamp
+	r.uint32() // view version
+	r.uint32() // requested max references per node (一律回傳全部，不使用 continuation point)
+	n := r.arrayLength()
+
+	w := &opcuaWriter{}
+	w.responseHeader(header.handle, opcuaNothingToDo(n))
+	w.int32(int32(n))
+	for i := 0; i < n && r.err == nil; i++ {
+		id := r.nodeID()
+		direction := r.uint32()
+		refType := r.nodeID()
+		includeSubtypes := r.boolean()
+		classMask := r.uint32()
+		r.uint32() // result mask (一律回傳所有欄位)
+
+		node, ok := c.server.lookup(id)
+		if !ok {
+			w.uint32(opcuaBadNodeIDUnknown)
+			w.bytes(nil)
+			w.int32(0)
+			continue
+		}
+		var refs []opcuaReference
+		if direction != opcuaBrowseDirectionInverse {
+			for _, child := range c.server.children(node) {
+				refs = append(refs, opcuaReference{child.ParentRef, true, child})
+			}
+			typeDef := &opcuaNode{
+				ID:         opcuaNS0(node.TypeDef),
+				Class:      opcuaNodeClassObjectType,
+				BrowseName: opcuaQualifiedName{Name: opcuaTypeNames[node.TypeDef]},
+			}
+			if node.Class == opcuaNodeClassVariable {
+				typeDef.Class = opcuaNodeClassVariableType
+			}
+			refs = append(refs, opcuaReference{opcuaHasTypeDefinition, true, typeDef})
+		}
+		if direction != opcuaBrowseDirectionForward && node.Parent != nil {
+			if parent, ok := c.server.lookup(*node.Parent); ok {
+				refs = append(refs, opcuaReference{node.ParentRef, false, parent})
+			}
+		}
+
+		w.uint32(opcuaGood)
+		w.bytes(nil) // continuation point
+		matched := refs[:0]
+		for _, ref := range refs {
+			if opcuaReferenceMatches(ref.refType, refType, includeSubtypes) && (classMask == 0 || classMask&ref.target.Class != 0) {
+				matched = append(matched, ref)
+			}
+		}
+		w.int32(int32(len(matched)))
+		for _, ref := range matched {
+			w.nodeID(opcuaNS0(ref.refType))
+			w.boolean(ref.forward)
+			w.nodeID(ref.target.ID)
+			w.qualifiedName(ref.target.BrowseName)
+			w.localizedText(ref.target.BrowseName.Name)
+			w.uint32(ref.target.Class)
+			w.nodeID(opcuaNS0(ref.target.TypeDef))
+		}
+	}
+	w.int32(-1) // diagnostic infos
+	return w
+}
+
+func (c *opcuaConn) read(header opcuaRequestHeader, r *opcuaReader) *opcuaWriter {
+	r.float64() // max age (一律讀取目前值)
+	timestamps := r.uint32()
+	n := r.arrayLength()
+
+	w := &opcuaWriter{}
+	w.responseHeader(header.handle, opcuaNothingToDo(n))
+	w.int32(int32(n))
+	now := time.Now()
+	for i := 0; i < n && r.err == nil; i++ {
+		id := r.nodeID()
+		attribute := r.uint32()
+		r.string()        // index range
+		r.qualifiedName() // data encoding
+
+		node, ok := c.server.lookup(id)
+		if !ok {
+			w.dataValue(nil, opcuaBadNodeIDUnknown, time.Time{}, time.Time{})
+			continue
+		}
+		value, status := c.server.attribute(node, attribute)
+		var source, server time.Time
+		if attribute == opcuaAttributeValue {
+			if timestamps == opcuaTimestampsToReturnSource || timestamps == opcuaTimestampsToReturnBoth {
+				source = now
+			}
+			if timestamps == opcuaTimestampsToReturnServer || timestamps == opcuaTimestampsToReturnBoth {
+				server = now
+			}
+		}
+		w.dataValue(value, status, source, server)
+	}
+	w.int32(-1) // diagnostic infos
+	return w
+}
+
+func (c *opcuaConn) write(header opcuaRequestHeader, r *opcuaReader) *opcuaWriter {
+	n := r.arrayLength()
+	results := make([]uint32, 0, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		id := r.nodeID()
+		attribute := r.uint32()
+		r.string() // index range
+		value := r.dataValue()
+		if r.err != nil {
+			break
+		}
+
+		node, ok := c.server.lookup(id)
+		switch {
+		case !ok:
+			results = append(results, opcuaBadNodeIDUnknown)
+		case attribute != opcuaAttributeValue || node.Meta == nil:
+			results = append(results, opcuaBadNotWritable)
+		default:
+			results = append(results, c.server.writeValue(node, value))
+		}
+	}
+
+	w := &opcuaWriter{}
+	w.responseHeader(header.handle, opcuaNothingToDo(n))
+	w.int32(int32(len(results)))
+	for _, status := range results {
+		w.uint32(status)
+	}
+	w.int32(-1) // diagnostic infos
+	return w
+}
+
+// opcuaNothingToDo 請求未包含任何項目時的服務結果
+func opcuaNothingToDo(n int) uint32 {
+	if n == 0 {
+		return opcuaBadNothingToDo
+	}
+	return opcuaGood
+}
+
+// --- 編碼 ---
+
+// opcuaRequestHeader 請求標頭中使用到的欄位
+type opcuaRequestHeader struct {
+	authToken opcuaNodeID
+	handle    uint32
+}
+
+// opcuaWriter UA Binary 編碼 (little endian)
+type opcuaWriter struct {
+	b []byte
+}
+
+func (w *opcuaWriter) byte(v byte) { w.b = append(w.b, v) }
+
+func (w *opcuaWriter) boolean(v bool) {
+	if v {
+		w.byte(1)
+	} else {
+		w.byte(0)
+	}
+}
+
+func (w *opcuaWriter) uint16(v uint16) { w.b = binary.LittleEndian.AppendUint16(w.b, v) }
+func (w *opcuaWriter) uint32(v uint32) { w.b = binary.LittleEndian.AppendUint32(w.b, v) }
+func (w *opcuaWriter) int32(v int32)   { w.uint32(uint32(v)) }
+func (w *opcuaWriter) int64(v int64)   { w.b = binary.LittleEndian.AppendUint64(w.b, uint64(v)) }
+func (w *opcuaWriter) float64(v float64) {
+	w.b = binary.LittleEndian.AppendUint64(w.b, math.Float64bits(v))
+}
+
+func (w *opcuaWriter) string(s string) {
+	w.int32(int32(len(s)))
+	w.b = append(w.b, s...)
+}
+
+// bytes ByteString (nil 為 null)
+func (w *opcuaWriter) bytes(b []byte) {
+	if b == nil {
+		w.int32(-1)
+		return
+	}
+	w.int32(int32(len(b)))
+	w.b = append(w.b, b...)
+}
+
+// dateTime 自 1601-01-01 起的 100 奈秒數 (零值為 0)
+func (w *opcuaWriter) dateTime(t time.Time) {
+	if t.IsZero() {
+		w.int64(0)
+		return
+	}
+	w.int64(t.UnixNano()/100 + 116444736000000000)
+}
+
+// nodeID 以最精簡的格式編碼節點 ID
+func (w *opcuaWriter) nodeID(id opcuaNodeID) {
+	switch {
+	case id.Name != "":
+		w.byte(opcuaNodeIDString)
+		w.uint16(id.Namespace)
+		w.string(id.Name)
+	case id.Namespace == 0 && id.ID <= math.MaxUint8:
+		w.byte(opcuaNodeIDTwoByte)
+		w.byte(byte(id.ID))
+	case id.Namespace <= math.MaxUint8 && id.ID <= math.MaxUint16:
+		w.byte(opcuaNodeIDFourByte)
+		w.byte(byte(id.Namespace))
+		w.uint16(uint16(id.ID))
+	default:
+		w.byte(opcuaNodeIDNumeric)
+		w.uint16(id.Namespace)
+		w.uint32(id.ID)
+	}
+}
+
+func (w *opcuaWriter) qualifiedName(name opcuaQualifiedName) {
+	w.uint16(name.Namespace)
+	w.string(name.Name)
+}
+
+// localizedText 僅含文字的 LocalizedText (空字串時兩者皆省略)
+func (w *opcuaWriter) localizedText(text string) {
+	if text == "" {
+		w.byte(0)
+		return
+	}
+	w.byte(0x02)
+	w.string(text)
+}
+
+func (w *opcuaWriter) responseHeader(handle, status uint32) {
+	w.dateTime(time.Now())
+	w.uint32(handle)
+	w.uint32(status)
+	w.byte(0)             // service diagnostics
+	w.int32(-1)           // string table
+	w.nodeID(opcuaNS0(0)) // additional header
+	w.byte(0)
+}
+
+// variant 編碼 Variant (nil 為空值)
+func (w *opcuaWriter) variant(v any) {
+	switch v := v.(type) {
+	case nil:
+		w.byte(0)
+	case bool:
+		w.byte(opcuaBoolean)
+		w.boolean(v)
+	case byte:
+		w.byte(3)
+		w.byte(v)
+	case int32:
+		w.byte(6)
+		w.int32(v)
+	case uint32:
+		w.byte(7)
+		w.uint32(v)
+	case float64:
+		w.byte(opcuaDouble)
+		w.float64(v)
+	case string:
+		w.byte(opcuaString)
+		w.string(v)
+	case []string:
+		w.byte(opcuaString | 0x80)
+		w.int32(int32(len(v)))
+		for _, s := range v {
+			w.string(s)
+		}
+	case []uint32:
+		w.byte(7 | 0x80)
+		w.int32(int32(len(v)))
+		for _, n := range v {
+			w.uint32(n)
+		}
+	case opcuaNodeID:
+		w.byte(17)
+		w.nodeID(v)
+	case opcuaQualifiedName:
+		w.byte(20)
+		w.qualifiedName(v)
+	case opcuaLocalizedText:
+		w.byte(21)
+		w.localizedText(string(v))
+	default:
+		panic(fmt.Sprintf("opcua: unsupported variant %T", v))
+	}
+}
+
+// dataValue 編碼 DataValue (狀態不為 Good 時不含值)
+func (w *opcuaWriter) dataValue(value any, status uint32, source, server time.Time) {
+	var mask byte
+	if status == opcuaGood {
+		mask |= 0x01
+	} else {
+		mask |= 0x02
+	}
+	if !source.IsZero() {
+		mask |= 0x04
+	}
+	if !server.IsZero() {
+		mask |= 0x08
+	}
+	w.byte(mask)
+	if mask&0x01 != 0 {
+		w.variant(value)
+	}
+	if mask&0x02 != 0 {
+		w.uint32(status)
+	}
+	if mask&0x04 != 0 {
+		w.dateTime(source)
+	}
+	if mask&0x08 != 0 {
+		w.dateTime(server)
+	}
+}
+
+// opcuaReader UA Binary 解碼 (發生錯誤後的讀取皆回傳零值，最後檢查 err)
+type opcuaReader struct {
+	b   []byte
+	err error
+}
+
+// fail 記錄格式錯誤
+func (r *opcuaReader) fail() {
+	if r.err == nil {
+		r.err = errors.New(T("OPC UA 訊息格式錯誤"))
+	}
+}
+
+func (r *opcuaReader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.b) {
+		r.fail()
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *opcuaReader) byte() byte {
+	if b := r.take(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *opcuaReader) boolean() bool { return r.byte() != 0 }
+
+func (r *opcuaReader) uint16() uint16 {
+	if b := r.take(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *opcuaReader) uint32() uint32 {
+	if b := r.take(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *opcuaReader) int32() int32 { return int32(r.uint32()) }
+
+func (r *opcuaReader) uint64() uint64 {
+	if b := r.take(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *opcuaReader) int64() int64     { return int64(r.uint64()) }
+func (r *opcuaReader) float64() float64 { return math.Float64frombits(r.uint64()) }
+
+// bytes ByteString (null 為 nil)
+func (r *opcuaReader) bytes() []byte {
+	n := r.int32()
+	if n < 0 {
+		return nil
+	}
+	return r.take(int(n))
+}
+
+func (r *opcuaReader) string() string { return string(r.bytes()) }
+
+// arrayLength 陣列長度 (null 為 0；超過剩餘資料量視為格式錯誤)
+func (r *opcuaReader) arrayLength() int {
+	n := r.int32()
+	if n < 0 {
+		return 0
+	}
+	if int(n) > len(r.b) {
+		r.fail()
+		return 0
+	}
+	return int(n)
+}
+
+// nodeID 解碼節點 ID (GUID 與 ByteString ID 以不會與本伺服器節點相同的字串表示)
+func (r *opcuaReader) nodeID() opcuaNodeID {
+	encoding := r.byte()
+	var id opcuaNodeID
+	switch encoding &^ (opcuaExpandedNamespaceURI | opcuaExpandedServerIndex) {
+	case opcuaNodeIDTwoByte:
+		id.ID = uint32(r.byte())
+	case opcuaNodeIDFourByte:
+		id.Namespace = uint16(r.byte())
+		id.ID = uint32(r.uint16())
+	case opcuaNodeIDNumeric:
+		id.Namespace = r.uint16()
+		id.ID = r.uint32()
+	case opcuaNodeIDString:
+		id.Namespace = r.uint16()
+		id.Name = r.string()
+	case opcuaNodeIDGUID:
+		id.Namespace = r.uint16()
+		id.Name = "\x00g=" + hex.EncodeToString(r.take(16))
+	case opcuaNodeIDByteString:
+		id.Namespace = r.uint16()
+		id.Name = "\x00b=" + hex.EncodeToString(r.bytes())
+	default:
+		r.fail()
+	}
+	if encoding&opcuaExpandedNamespaceURI != 0 {
+		r.string()
+	}
+	if encoding&opcuaExpandedServerIndex != 0 {
+		r.uint32()
+	}
+	return id
+}
+
+func (r *opcuaReader) qualifiedName() opcuaQualifiedName {
+	return opcuaQualifiedName{Namespace: r.uint16(), Name: r.string()}
+}
+
+// extensionObject 回傳類型與編碼後的內容
+func (r *opcuaReader) extensionObject() (opcuaNodeID, []byte) {
+	typeID := r.nodeID()
+	switch r.byte() {
+	case opcuaExtensionObjectBinary, opcuaExtensionObjectXML:
+		return typeID, r.bytes()
+	}
+	return typeID, nil
+}
+
+func (r *opcuaReader) requestHeader() opcuaRequestHeader {
+	var header opcuaRequestHeader
+	header.authToken = r.nodeID()
+	r.int64() // timestamp
+	header.handle = r.uint32()
+	r.uint32() // return diagnostics
+	r.string() // audit entry ID
+	r.uint32() // timeout hint
+	r.extensionObject()
+	return header
+}
+
+// variant 解碼純量 Variant (數值一律轉為 float64；不支援的類型回傳 nil)
+func (r *opcuaReader) variant() any {
+	encoding := r.byte()
+	if encoding&0xC0 != 0 {
+		// 陣列無法對應至單一暫存器
+		r.fail()
+		return nil
+	}
+	switch encoding {
+	case 0:
+		return nil
+	case opcuaBoolean:
+		if r.boolean() {
+			return float64(1)
+		}
+		return float64(0)
+	case 2: // SByte
+		return float64(int8(r.byte()))
+	case 3: // Byte
+		return float64(r.byte())
+	case 4: // Int16
+		return float64(int16(r.uint16()))
+	case 5: // UInt16
+		return float64(r.uint16())
+	case 6: // Int32
+		return float64(r.int32())
+	case 7: // UInt32
+		return float64(r.uint32())
+	case 8: // Int64
+		return float64(r.int64())
+	case 9: // UInt64
+		return float64(r.uint64())
+	case 10: // Float
+		return float64(math.Float32frombits(r.uint32()))
+	case opcuaDouble:
+		return r.float64()
+	case opcuaString:
+		return r.string()
+	}
+	r.fail()
+	return nil
+}
+
+// dataValue 解碼 DataValue 的值 (忽略狀態與時間戳記)
+func (r *opcuaReader) dataValue() any {
+	mask := r.byte()
+	var value any
+	if mask&0x01 != 0 {
+		value = r.variant()
+	}
+	if mask&0x02 != 0 {
+		r.uint32()
+	}
+	if mask&0x04 != 0 {
+		r.int64()
+	}
+	if mask&0x10 != 0 {
+		r.uint16()
+	}
+	if mask&0x08 != 0 {
+		r.int64()
+	}
+	if mask&0x20 != 0 {
+		r.uint16()
+	}
+	return value
+}
+
+// --- 配置 ---
+
+// Validate 驗證 OPC UA 伺服器設定
+func (o *OPCUAConfig) Validate(tags map[string][]string) error {
+	if !o.Enabled {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(o.Listen); err != nil {
+		return fmt.Errorf(T("OPC UA 監聽位址無效: %s"), o.Listen)
+	}
+	for _, target := range o.Targets {
+		if net.ParseIP(target) == nil {
+			if _, _, err := net.ParseCIDR(target); err != nil {
+				return fmt.Errorf(T("OPC UA 伺服器的目標無效: %s"), target)
+			}
+		}
+	}
+	for _, tag := range o.Tags {
+		if _, ok := tags[tag]; !ok {
+			return fmt.Errorf(T("OPC UA 伺服器使用未定義的 Slave 標籤: %s"), tag)
+		}
+	}
+	return nil
+}
+
+// matches Slave 是否提供 (targets 與 tags 皆空表示全部 Slave)
+func (o *OPCUAConfig) matches(c *Config, ip net.IP) bool {
+	if len(o.Targets) == 0 && len(o.Tags) == 0 {
+		return true
+	}
+	if len(o.Targets) > 0 && MatchTargets(ip, o.Targets) {
+		return true
+	}
+	for _, tag := range o.Tags {
+		if c.hasTag(ip, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// --- Engine ---
+
+// startOPCUA 啟動 OPC UA 鏡像伺服器
+func (e *Engine) startOPCUA() error {
+	server := NewOPCUAServer(e.config.OPCUA, e, e.logger)
+	if err := server.Start(); err != nil {
+		return err
+	}
+	e.mu.Lock()
+	e.opcua = server
+	e.mu.Unlock()
+	return nil
+}
+
+// stopOPCUA 停止 OPC UA 鏡像伺服器
+func (e *Engine) stopOPCUA() {
+	e.mu.Lock()
+	server := e.opcua
+	e.opcua = nil
+	e.mu.Unlock()
+	if server != nil {
+		server.Stop()
+	}
+}
+
+// OPCUAAddr OPC UA 伺服器的監聽位址 (未啟動時為 nil)
+func (e *Engine) OPCUAAddr() net.Addr {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.opcua == nil {
+		return nil
+	}
+	return e.opcua.Addr()
+}
@@ -0,0 +1,219 @@
+package main
+
+import "slices"
+
+// registerPageSize 暫存器分頁大小 (暫存器數)
+const registerPageSize = 1024
+
+// pagedStore 分頁配置的暫存器儲存區
+//
+// 頁面在定義暫存器或首次寫入非零值時才配置，未配置的頁面讀為零值；
+// 大量 Slave 只使用少數暫存器時，不必為整個位址空間 (預設每種 10000 個) 配置記憶體。
+// 頁面可由多個儲存區共用 (Share)，寫入共用頁面前先複製 (copy-on-write)。
+// 本身不加鎖，由持有者 (RegisterMap 或 Slave) 保護
+type pagedStore[T comparable] struct {
+	size   int
+	pages  [][]T  // 未配置的頁面為 nil
+	shared []bool // 與其他儲存區共用的頁面 (nil 表示沒有)
+	dirty  []bool // 上次 SyncTo 後寫入過的頁面 (nil 表示沒有)
+}
+
+// newPagedStore 建立長度為 size 的儲存區 (不配置任何頁面)
+func newPagedStore[T comparable](size int) pagedStore[T] {
+	return pagedStore[T]{
+		size:  size,
+		pages: make([][]T, (size+registerPageSize-1)/registerPageSize),
+	}
+}
+
+// Len 暫存器數
+func (p *pagedStore[T]) Len() int {
+	return p.size
+}
+
+// Pages 已配置的頁面數
+func (p *pagedStore[T]) Pages() int {
+	count := 0
+	for _, page := range p.pages {
+		if page != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// SharedPages 與其他儲存區共用的頁面數
+func (p *pagedStore[T]) SharedPages() int {
+	count := 0
+	for n, page := range p.pages {
+		if page != nil && p.isShared(n) {
+			count++
+		}
+	}
+	return count
+}
+
+// At 讀取索引 i 的值 (呼叫端需確認 i 在範圍內)
+func (p *pagedStore[T]) At(i int) T {
+	page := p.pages[i/registerPageSize]
+	if page == nil {
+		var zero T
+		return zero
+	}
+	return page[i%registerPageSize]
+}
+
+// Set 寫入索引 i 的值 (呼叫端需確認 i 在範圍內)；寫入零值到未配置的頁面時不配置
+func (p *pagedStore[T]) Set(i int, v T) {
+	n := i / registerPageSize
+	if p.pages[n] == nil {
+		var zero T
+		if v == zero {
+			return
+		}
+	}
+	p.page(n)[i%registerPageSize] = v
+}
+
+// Read 將 [start, start+len(dst)) 複製到 dst (呼叫端需確認範圍)
+func (p *pagedStore[T]) Read(start int, dst []T) {
+	for len(dst) > 0 {
+		n, offset := start/registerPageSize, start%registerPageSize
+		chunk := min(len(dst), registerPageSize-offset)
+		if page := p.pages[n]; page != nil {
+			copy(dst[:chunk], page[offset:])
+		} else {
+			clear(dst[:chunk])
+		}
+		dst, start = dst[chunk:], start+chunk
+	}
+}
+
+// Write 將 src 寫入 [start, start+len(src)) (呼叫端需確認範圍)；全為零值的區段不配置頁面
+func (p *pagedStore[T]) Write(start int, src []T) {
+	for len(src) > 0 {
+		n, offset := start/registerPageSize, start%registerPageSize
+		chunk := min(len(src), registerPageSize-offset)
+		if p.pages[n] != nil || !allZero(src[:chunk]) {
+			copy(p.page(n)[offset:], src[:chunk])
+		}
+		src, start = src[chunk:], start+chunk
+	}
+}
+
+// Slice 回傳 [start, end) 的複本 (呼叫端需確認範圍)
+func (p *pagedStore[T]) Slice(start, end int) []T {
+	result := make([]T, end-start)
+	p.Read(start, result)
+	return result
+}
+
+// Reserve 配置涵蓋 [start, end) 的頁面 (超出範圍的部分忽略；已配置的頁面維持共用)
+func (p *pagedStore[T]) Reserve(start, end int) {
+	for n := max(start, 0) / registerPageSize; n < len(p.pages) && n*registerPageSize < end; n++ {
+		if p.pages[n] == nil {
+			p.page(n)
+		}
+	}
+}
+
+// Share 回傳與 p 共用所有已配置頁面的複本；之後任一方寫入共用頁面時才複製該頁
+func (p *pagedStore[T]) Share() pagedStore[T] {
+	clone := pagedStore[T]{size: p.size, pages: slices.Clone(p.pages)}
+	for n, page := range p.pages {
+		if page != nil {
+			p.setShared(n, true)
+			clone.setShared(n, true)
+		}
+	}
+	return clone
+}
+
+// CopyTo 將內容複製到 dst；dst 的頁面配置與來源相同，已配置的頁面沿用原本的記憶體
+// (共用頁面不會被原地修改，直接共用而不複製)
+func (p *pagedStore[T]) CopyTo(dst *pagedStore[T]) {
+	if dst.size != p.size {
+		*dst = newPagedStore[T](p.size)
+	}
+	for n := range p.pages {
+		p.copyPageTo(dst, n)
+	}
+}
+
+// SyncTo 只將上次同步後寫入過的頁面複製到 dst 並清除寫入標記；
+// dst 長度不同 (例如尚未同步過) 時完整複製。dst 需為同一個來源持續同步的儲存區
+func (p *pagedStore[T]) SyncTo(dst *pagedStore[T]) {
+	if dst.size != p.size {
+		p.CopyTo(dst)
+		clear(p.dirty)
+		return
+	}
+	for n, dirty := range p.dirty {
+		if dirty {
+			p.copyPageTo(dst, n)
+			p.dirty[n] = false
+		}
+	}
+}
+
+// copyPageTo 將第 n 頁複製到 dst (dst 長度需相同)
+func (p *pagedStore[T]) copyPageTo(dst *pagedStore[T], n int) {
+	page := p.pages[n]
+	switch {
+	case page == nil:
+		dst.pages[n] = nil
+		dst.setShared(n, false)
+	case p.isShared(n):
+		dst.pages[n] = page
+		dst.setShared(n, true)
+	default:
+		if dst.isShared(n) {
+			dst.pages[n] = nil
+			dst.setShared(n, false)
+		}
+		copy(dst.page(n), page)
+	}
+}
+
+// page 取得可寫入的第 n 頁並標記為已寫入：未配置時配置 (最後一頁依長度截短)，共用時先複製
+func (p *pagedStore[T]) page(n int) []T {
+	switch {
+	case p.pages[n] == nil:
+		p.pages[n] = make([]T, min(registerPageSize, p.size-n*registerPageSize))
+	case p.isShared(n):
+		p.pages[n] = slices.Clone(p.pages[n])
+		p.shared[n] = false
+	}
+	if p.dirty == nil {
+		p.dirty = make([]bool, len(p.pages))
+	}
+	p.dirty[n] = true
+	return p.pages[n]
+}
+
+// isShared 第 n 頁是否與其他儲存區共用
+func (p *pagedStore[T]) isShared(n int) bool {
+	return p.shared != nil && p.shared[n]
+}
+
+// setShared 設定第 n 頁的共用旗標
+func (p *pagedStore[T]) setShared(n int, shared bool) {
+	if p.shared == nil {
+		if !shared {
+			return
+		}
+		p.shared = make([]bool, len(p.pages))
+	}
+	p.shared[n] = shared
+}
+
+// allZero values 是否全為零值
+func allZero[T comparable](values []T) bool {
+	var zero T
+	for _, v := range values {
+		if v != zero {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,463 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 狀態持久化預設值
+const (
+	PersistenceDriverFile      = "file"
+	PersistenceDriverLog       = "log"
+	DefaultPersistencePath     = "modbussim-state"
+	DefaultPersistenceInterval = 30 * time.Second
+)
+
+// StateStore 狀態持久化儲存 (定期 checkpoint 各 Slave 的狀態，重新啟動時自動還原)
+type StateStore interface {
+	// SaveSlave 保存一個 Slave 的狀態 (取代先前的 checkpoint)
+	SaveSlave(snap SlaveSnapshot) error
+	// SaveScenario 保存引擎目前的場景
+	SaveScenario(scenario string) error
+	// Load 讀取所有 checkpoint；尚無資料時回傳 nil
+	Load() (*Snapshot, error)
+	Close() error
+}
+
+// NewStateStore 依 persistence.driver 開啟儲存
+func NewStateStore(cfg PersistenceConfig) (StateStore, error) {
+	switch cfg.Driver {
+	case PersistenceDriverFile, "":
+		return newFileStateStore(cfg.Path)
+	case PersistenceDriverLog:
+		return newLogStateStore(cfg.Path)
+	default:
+		return nil, fmt.Errorf(T("不支援的持久化儲存: %s (可用: file, log)"), cfg.Driver)
+	}
+}
+
+// Validate 驗證狀態持久化設定
+func (p *PersistenceConfig) Validate() error {
+	if p.Interval < 0 {
+		return fmt.Errorf(T("checkpoint 間隔不可為負: %v"), p.Interval)
+	}
+	if !p.Enabled {
+		return nil
+	}
+	switch p.Driver {
+	case PersistenceDriverFile, PersistenceDriverLog, "":
+	default:
+		return fmt.Errorf(T("不支援的持久化儲存: %s (可用: file, log)"), p.Driver)
+	}
+	if p.Path == "" {
+		return errors.New(T("啟用狀態持久化時必須指定 path"))
+	}
+	if p.Interval == 0 {
+		return errors.New(T("啟用狀態持久化時必須指定 checkpoint 間隔"))
+	}
+	return nil
+}
+
+// --- file ---
+
+// fileStateStore 以目錄保存狀態：engine.json 為場景，slaves/ 下每個 Slave 一個 JSON 檔
+// (寫入暫存檔後更名，程序在寫入途中終止也不會留下不完整的檔案)
+type fileStateStore struct {
+	dir string
+	mu  sync.Mutex // 定期 checkpoint 與停止前的 checkpoint 不同時寫入同一個暫存檔
+}
+
+// fileStateEngine engine.json (log 儲存方式為 engine 紀錄) 的內容
+type fileStateEngine struct {
+	Version  int       `json:"version"`
+	Time     time.Time `json:"time"`
+	Scenario string    `json:"scenario"`
+}
+
+func newFileStateStore(dir string) (*fileStateStore, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "slaves"), 0755); err != nil {
+		return nil, fmt.Errorf(T("建立狀態目錄失敗: %w"), err)
+	}
+	return &fileStateStore{dir: dir}, nil
+}
+
+func (f *fileStateStore) SaveSlave(snap SlaveSnapshot) error {
+	// IPv6 位址的冒號在部分檔案系統不合法
+	name := strings.ReplaceAll(snap.IP, ":", "_") + ".json"
+	return f.write(filepath.Join(f.dir, "slaves", name), snap)
+}
+
+func (f *fileStateStore) SaveScenario(scenario string) error {
+	return f.write(filepath.Join(f.dir, "engine.json"), fileStateEngine{
+		Version:  SnapshotVersion,
+		Time:     time.Now(),
+		Scenario: scenario,
+	})
+}
+
+func (f *fileStateStore) write(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return writeFileAtomic(path, data)
+}
+
+// Load 讀取 engine.json 與各 Slave 的檔案；engine.json 不存在 (例如第一次 checkpoint 途中終止)
+// 時仍回傳已保存的 Slave，場景留空表示維持設定值
+func (f *fileStateStore) Load() (*Snapshot, error) {
+	snap := &Snapshot{Version: SnapshotVersion}
+	data, err := os.ReadFile(filepath.Join(f.dir, "engine.json"))
+	noEngine := os.IsNotExist(err)
+	switch {
+	case err == nil:
+		var engine fileStateEngine
+		if err := json.Unmarshal(data, &engine); err != nil {
+			return nil, fmt.Errorf(T("解析 %s 失敗: %w"), "engine.json", err)
+		}
+		if engine.Version != SnapshotVersion {
+			return nil, fmt.Errorf(T("不支援的快照版本: %d"), engine.Version)
+		}
+		snap.Time, snap.Scenario = engine.Time, engine.Scenario
+	case !noEngine:
+		return nil, fmt.Errorf(T("讀取狀態目錄失敗: %w"), err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(f.dir, "slaves", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if noEngine && len(paths) == 0 {
+		return nil, nil
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf(T("讀取狀態目錄失敗: %w"), err)
+		}
+		var slave SlaveSnapshot
+		if err := json.Unmarshal(data, &slave); err != nil {
+			return nil, fmt.Errorf(T("解析 %s 失敗: %w"), path, err)
+		}
+		snap.Slaves = append(snap.Slaves, slave)
+	}
+	return snap, nil
+}
+
+func (f *fileStateStore) Close() error {
+	return nil
+}
+
+// --- log ---
+
+// logCompactSize 日誌超過此大小且超過有效紀錄的兩倍時重寫
+const logCompactSize = 1 << 20
+
+// logStateStore 以單一檔案保存狀態：每次保存追加一筆紀錄 (長度、CRC32 與 JSON 內容) 並 fsync，
+// 讀取時以每個鍵的最後一筆為準；當機留下的不完整或損毀的尾端紀錄於開啟時截斷
+type logStateStore struct {
+	path        string
+	compactSize int64
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64             // 檔案大小 (最後一筆完整紀錄的結尾)
+	live     map[string][]byte // 各鍵最後一筆紀錄 (含標頭)
+	liveSize int64
+}
+
+// logRecord 日誌紀錄的內容
+type logRecord struct {
+	Key   string          `json:"key"` // engine 或 slave/<IP>
+	Value json.RawMessage `json:"value"`
+}
+
+// logHeaderSize 紀錄標頭：內容長度與 CRC32 (big-endian uint32)
+const logHeaderSize = 8
+
+func newLogStateStore(path string) (*logStateStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf(T("建立狀態目錄失敗: %w"), err)
+	}
+	l := &logStateStore{path: path, compactSize: logCompactSize}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// open 開啟日誌並讀取所有完整的紀錄，截斷其後不完整或 CRC 不符的部分
+func (l *logStateStore) open() error {
+	file, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf(T("開啟狀態檔失敗: %w"), err)
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf(T("讀取狀態檔失敗: %w"), err)
+	}
+
+	l.live, l.liveSize = make(map[string][]byte), 0
+	var offset int64
+	for {
+		entry, key, ok := decodeLogRecord(data[offset:])
+		if !ok {
+			break
+		}
+		l.put(key, entry)
+		offset += int64(len(entry))
+	}
+	if offset < int64(len(data)) {
+		if err := file.Truncate(offset); err != nil {
+			file.Close()
+			return fmt.Errorf(T("截斷狀態檔失敗: %w"), err)
+		}
+	}
+	l.file, l.size = file, offset
+	return nil
+}
+
+// encodeLogRecord 編碼一筆紀錄 (標頭與 JSON 內容)
+func encodeLogRecord(key string, v any) ([]byte, error) {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(logRecord{Key: key, Value: value})
+	if err != nil {
+		return nil, err
+	}
+	entry := make([]byte, logHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(entry[0:], uint32(len(payload)))
+	binary.BigEndian.PutUint32(entry[4:], crc32.ChecksumIEEE(payload))
+	copy(entry[logHeaderSize:], payload)
+	return entry, nil
+}
+
+// decodeLogRecord 解碼 data 開頭的一筆紀錄；資料不完整或 CRC 不符時 ok 為 false
+func decodeLogRecord(data []byte) (entry []byte, key string, ok bool) {
+	if len(data) < logHeaderSize {
+		return nil, "", false
+	}
+	n := int(binary.BigEndian.Uint32(data))
+	if len(data)-logHeaderSize < n {
+		return nil, "", false
+	}
+	payload := data[logHeaderSize : logHeaderSize+n]
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(data[4:]) {
+		return nil, "", false
+	}
+	var record logRecord
+	if err := json.Unmarshal(payload, &record); err != nil {
+		return nil, "", false
+	}
+	return data[:logHeaderSize+n], record.Key, true
+}
+
+// put 記錄鍵的最後一筆紀錄 (呼叫端需持有 l.mu 或尚未公開 l)
+func (l *logStateStore) put(key string, entry []byte) {
+	l.liveSize += int64(len(entry) - len(l.live[key]))
+	l.live[key] = entry
+}
+
+func (l *logStateStore) SaveSlave(snap SlaveSnapshot) error {
+	return l.save("slave/"+snap.IP, snap)
+}
+
+func (l *logStateStore) SaveScenario(scenario string) error {
+	return l.save("engine", fileStateEngine{
+		Version:  SnapshotVersion,
+		Time:     time.Now(),
+		Scenario: scenario,
+	})
+}
+
+// save 追加一筆紀錄並 fsync；寫入失敗時截斷回寫入前的大小，不在檔尾留下不完整的紀錄
+func (l *logStateStore) save(key string, v any) error {
+	entry, err := encodeLogRecord(key, v)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return errors.New(T("狀態檔已關閉"))
+	}
+	if _, err := l.file.Write(entry); err != nil {
+		l.file.Truncate(l.size)
+		return err
+	}
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+	l.size += int64(len(entry))
+	l.put(key, entry)
+
+	if l.size > l.compactSize && l.size > 2*l.liveSize {
+		return l.compact()
+	}
+	return nil
+}
+
+// compact 只保留各鍵的最後一筆紀錄重寫日誌 (寫入暫存檔後更名，失敗時沿用原本的檔案)
+func (l *logStateStore) compact() error {
+	keys := make([]string, 0, len(l.live))
+	for key := range l.live {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	data := make([]byte, 0, l.liveSize)
+	for _, key := range keys {
+		data = append(data, l.live[key]...)
+	}
+
+	// Windows 無法更名覆寫開啟中的檔案
+	l.file.Close()
+	l.file = nil
+	err := writeFileAtomic(l.path, data)
+	if openErr := l.open(); openErr != nil {
+		return openErr
+	}
+	if err != nil {
+		return fmt.Errorf(T("重寫狀態檔失敗: %w"), err)
+	}
+	return nil
+}
+
+func (l *logStateStore) Load() (*Snapshot, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.live) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, 0, len(l.live))
+	for key := range l.live {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	snap := &Snapshot{Version: SnapshotVersion}
+	for _, key := range keys {
+		var record logRecord
+		if err := json.Unmarshal(l.live[key][logHeaderSize:], &record); err != nil {
+			return nil, fmt.Errorf(T("解析 %s 失敗: %w"), key, err)
+		}
+		if key == "engine" {
+			var engine fileStateEngine
+			if err := json.Unmarshal(record.Value, &engine); err != nil {
+				return nil, fmt.Errorf(T("解析 %s 失敗: %w"), key, err)
+			}
+			if engine.Version != SnapshotVersion {
+				return nil, fmt.Errorf(T("不支援的快照版本: %d"), engine.Version)
+			}
+			snap.Time, snap.Scenario = engine.Time, engine.Scenario
+			continue
+		}
+		var slave SlaveSnapshot
+		if err := json.Unmarshal(record.Value, &slave); err != nil {
+			return nil, fmt.Errorf(T("解析 %s 失敗: %w"), key, err)
+		}
+		snap.Slaves = append(snap.Slaves, slave)
+	}
+	return snap, nil
+}
+
+func (l *logStateStore) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// --- Engine ---
+
+// openStateStore 開啟狀態儲存並讀取上次的 checkpoint (尚無資料時回傳 nil)
+func (e *Engine) openStateStore() (*Snapshot, error) {
+	store, err := NewStateStore(e.config.Persistence)
+	if err != nil {
+		return nil, err
+	}
+	snap, err := store.Load()
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	e.store = store
+	return snap, nil
+}
+
+// runCheckpoints 每隔 persistence.interval 保存所有 Slave 的狀態
+func (e *Engine) runCheckpoints(ctx context.Context, store StateStore) {
+	interval := e.config.Persistence.Interval
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// 寫入分散在半個間隔內，避免大量 Slave 同時寫入
+			slaves := e.ListSlaves()
+			e.checkpoint(ctx, store, slaves, interval/2/time.Duration(max(len(slaves), 1)))
+		}
+	}
+}
+
+// checkpoint 先保存場景，再依序保存 Slave，每個 Slave 間隔 spread；ctx 結束時中斷
+// (尚未啟動的 Slave 不會被覆寫，保留其上次的 checkpoint)
+func (e *Engine) checkpoint(ctx context.Context, store StateStore, slaves []*Slave, spread time.Duration) {
+	var failed int
+	var lastErr error
+	if err := store.SaveScenario(e.GetScenario().String()); err != nil {
+		failed++
+		lastErr = err
+	}
+	for i, slave := range slaves {
+		if i > 0 && !pace(ctx, spread) {
+			return
+		}
+		if err := store.SaveSlave(slave.Snapshot()); err != nil {
+			failed++
+			lastErr = err
+		}
+	}
+
+	if failed > 0 {
+		e.logger.Warn(T("保存 checkpoint 失敗"), zap.Int("failed", failed), zap.Error(lastErr))
+	}
+}
+
+// closeStateStore 關閉狀態儲存
+func (e *Engine) closeStateStore() {
+	if e.store == nil {
+		return
+	}
+	if err := e.store.Close(); err != nil {
+		e.logger.Warn(T("關閉狀態儲存失敗"), zap.Error(err))
+	}
+	e.store = nil
+}
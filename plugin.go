@@ -0,0 +1,525 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// 外掛協定 (見 plugin/scenario.proto)
+const (
+	pluginServiceName = "modbussim.plugin.v1.ScenarioPlugin"
+	pluginCallTimeout = time.Second
+)
+
+// PluginScenario 外掛場景 - 以 gRPC 呼叫外部程序實作的設備模型，第三方程式碼不需放在此 repo
+//
+// 每次更新送出所有已定義暫存器的工程值，外掛回傳要寫入的暫存器；未指定 plugin 時行為與正常場景相同。
+type PluginScenario struct {
+	normalScenario NormalScenario
+
+	mu        sync.Mutex
+	clients   map[string]*pluginClient
+	instances map[*RegisterMap]*pluginInstance
+	nextID    uint64
+}
+
+// pluginInstance 單一暫存器映射表在外掛端的實例
+type pluginInstance struct {
+	id        uint64
+	address   string
+	options   map[string]string
+	activated time.Time
+}
+
+// pluginClient 單一外掛位址的連線 (失敗與恢復時各記錄一次日誌，避免大量 Slave 洗版)
+type pluginClient struct {
+	address string
+	conn    *grpc.ClientConn
+
+	mu          sync.Mutex
+	described   bool // 已呼叫過 Describe
+	unavailable bool
+}
+
+func (s *PluginScenario) Type() ScenarioType {
+	return ScenarioPlugin
+}
+
+// Activate 進入場景時重新計算經過時間
+func (s *PluginScenario) Activate(registers *RegisterMap) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if instance, ok := s.instances[registers]; ok {
+		instance.activated = scenarioNow()
+	}
+}
+
+func (s *PluginScenario) Update(registers *RegisterMap, params ScenarioParams) {
+	if params.Plugin == "" {
+		s.normalScenario.Update(registers, params)
+		return
+	}
+
+	client, instance, err := s.lookup(registers, params)
+	if err != nil {
+		zap.L().Warn(T("連線場景外掛失敗"), zap.String("plugin", params.Plugin), zap.Error(err))
+		return
+	}
+
+	req := &pluginUpdateRequest{
+		InstanceID:     instance.id,
+		Registers:      pluginRegisters(registers),
+		Options:        instance.options,
+		ElapsedSeconds: scenarioSince(instance.activated).Seconds(),
+	}
+	resp := &pluginUpdateResponse{}
+	if !client.invoke("Update", req, resp) {
+		return
+	}
+	applyPluginWrites(registers, resp.Writes)
+
+	// 外掛寫入的輸入值變動後，衍生暫存器跟著重新計算
+	registers.EvaluateExpressions()
+}
+
+func (s *PluginScenario) Reset(registers *RegisterMap) {
+	s.mu.Lock()
+	var instance pluginInstance
+	var client *pluginClient
+	if p, ok := s.instances[registers]; ok {
+		instance = *p
+		client = s.clients[instance.address]
+	}
+	s.mu.Unlock()
+
+	if client == nil {
+		s.normalScenario.Reset(registers)
+		return
+	}
+
+	req := &pluginUpdateRequest{
+		InstanceID: instance.id,
+		Registers:  pluginRegisters(registers),
+		Options:    instance.options,
+		reset:      true,
+	}
+	resp := &pluginUpdateResponse{}
+	if client.invoke("Reset", req, resp) {
+		applyPluginWrites(registers, resp.Writes)
+		registers.EvaluateExpressions()
+	}
+}
+
+// lookup 取得外掛連線與暫存器映射表對應的實例 (必要時建立，實例以副本回傳)
+func (s *PluginScenario) lookup(registers *RegisterMap, params ScenarioParams) (*pluginClient, pluginInstance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.clients == nil {
+		s.clients = make(map[string]*pluginClient)
+		s.instances = make(map[*RegisterMap]*pluginInstance)
+	}
+
+	client, ok := s.clients[params.Plugin]
+	if !ok {
+		conn, err := grpc.NewClient(params.Plugin,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithDefaultCallOptions(grpc.ForceCodec(pluginCodec{})),
+		)
+		if err != nil {
+			return nil, pluginInstance{}, err
+		}
+		client = &pluginClient{address: params.Plugin, conn: conn}
+		s.clients[params.Plugin] = client
+	}
+
+	instance, ok := s.instances[registers]
+	if !ok {
+		s.nextID++
+		instance = &pluginInstance{id: s.nextID, activated: scenarioNow()}
+		s.instances[registers] = instance
+	}
+	instance.address = params.Plugin
+	instance.options = params.PluginOptions
+
+	return client, *instance, nil
+}
+
+// invoke 呼叫外掛方法；失敗時回傳 false (僅在狀態轉換時記錄日誌)
+func (c *pluginClient) invoke(method string, req, resp pluginMessage) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), pluginCallTimeout)
+	defer cancel()
+	err := c.conn.Invoke(ctx, "/"+pluginServiceName+"/"+method, req, resp)
+
+	c.mu.Lock()
+	if err != nil {
+		defer c.mu.Unlock()
+		if !c.unavailable {
+			c.unavailable = true
+			zap.L().Warn(T("呼叫場景外掛失敗"),
+				zap.String("plugin", c.address),
+				zap.String("method", method),
+				zap.Error(err),
+			)
+		}
+		return false
+	}
+	if c.unavailable {
+		c.unavailable = false
+		zap.L().Info(T("場景外掛已恢復"), zap.String("plugin", c.address))
+	}
+	describe := !c.described
+	c.described = true
+	c.mu.Unlock()
+
+	if describe {
+		c.describe()
+	}
+	return true
+}
+
+// describe 記錄外掛名稱 (首次呼叫成功後執行一次)
+func (c *pluginClient) describe() {
+	ctx, cancel := context.WithTimeout(context.Background(), pluginCallTimeout)
+	defer cancel()
+	resp := &pluginDescribeResponse{}
+	if err := c.conn.Invoke(ctx, "/"+pluginServiceName+"/Describe", &pluginDescribeRequest{}, resp); err != nil {
+		return
+	}
+	zap.L().Info(T("已連線到場景外掛"),
+		zap.String("plugin", c.address),
+		zap.String("name", resp.Name),
+		zap.String("description", resp.Description),
+	)
+}
+
+// pluginRegisters 取得所有已定義暫存器的工程值
+func pluginRegisters(registers *RegisterMap) []pluginRegister {
+	defs := registers.Definitions()
+	result := make([]pluginRegister, 0, len(defs))
+	for _, meta := range defs {
+		value, err := registers.GetScaledValue(meta.Address)
+		if err != nil {
+			continue
+		}
+		result = append(result, pluginRegister{Address: uint32(meta.Address), Name: meta.Name, Value: value})
+	}
+	return result
+}
+
+// applyPluginWrites 寫入外掛回傳的暫存器值 (位址為 0 時依名稱)
+func applyPluginWrites(registers *RegisterMap, writes []pluginRegister) {
+	var names map[string]uint16
+	for _, w := range writes {
+		address := uint16(w.Address)
+		if address == 0 {
+			if names == nil {
+				names = make(map[string]uint16)
+				for _, meta := range registers.Definitions() {
+					names[meta.Name] = meta.Address
+				}
+			}
+			var ok bool
+			if address, ok = names[w.Name]; !ok {
+				continue
+			}
+		}
+		registers.SetScaledValue(address, w.Value)
+	}
+}
+
+// ValidatePluginAddress 檢查外掛位址 (host:port、dns:///host:port 或 unix:///path/to.sock)
+func ValidatePluginAddress(address string) error {
+	switch {
+	case strings.HasPrefix(address, "unix:"):
+		if strings.TrimLeft(strings.TrimPrefix(address, "unix:"), "/") == "" {
+			return fmt.Errorf(T("缺少 socket 路徑: %s"), address)
+		}
+	default:
+		_, port, err := net.SplitHostPort(strings.TrimPrefix(address, "dns:///"))
+		if err != nil {
+			return err
+		}
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return fmt.Errorf(T("無效的連接埠: %s"), port)
+		}
+	}
+
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// --- 協定訊息 (protobuf wire format，欄位編號對應 plugin/scenario.proto) ---
+
+// pluginMessage 外掛協定訊息
+type pluginMessage interface {
+	marshal() []byte
+	unmarshal(data []byte) error
+}
+
+// pluginCodec 以 protobuf wire format 編碼外掛協定訊息 (不依賴 protoc 產生的程式碼)
+type pluginCodec struct{}
+
+func (pluginCodec) Marshal(v any) ([]byte, error) {
+	msg, ok := v.(pluginMessage)
+	if !ok {
+		return nil, fmt.Errorf(T("不支援的外掛訊息類型: %T"), v)
+	}
+	return msg.marshal(), nil
+}
+
+func (pluginCodec) Unmarshal(data []byte, v any) error {
+	msg, ok := v.(pluginMessage)
+	if !ok {
+		return fmt.Errorf(T("不支援的外掛訊息類型: %T"), v)
+	}
+	return msg.unmarshal(data)
+}
+
+func (pluginCodec) Name() string {
+	return "proto"
+}
+
+type pluginDescribeRequest struct{}
+
+func (*pluginDescribeRequest) marshal() []byte { return nil }
+
+func (*pluginDescribeRequest) unmarshal([]byte) error { return nil }
+
+type pluginDescribeResponse struct {
+	Name        string
+	Description string
+}
+
+func (m *pluginDescribeResponse) marshal() []byte {
+	var b []byte
+	b = appendPluginString(b, 1, m.Name)
+	b = appendPluginString(b, 2, m.Description)
+	return b
+}
+
+func (m *pluginDescribeResponse) unmarshal(data []byte) error {
+	return consumePluginFields(data, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			m.Name = string(v)
+		case num == 2 && typ == protowire.BytesType:
+			m.Description = string(v)
+		}
+	})
+}
+
+// pluginRegister 對應 Register
+type pluginRegister struct {
+	Address uint32
+	Name    string
+	Value   float64
+}
+
+func (m *pluginRegister) marshal() []byte {
+	var b []byte
+	if m.Address != 0 {
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(m.Address))
+	}
+	b = appendPluginString(b, 2, m.Name)
+	if m.Value != 0 {
+		b = protowire.AppendTag(b, 3, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(m.Value))
+	}
+	return b
+}
+
+func (m *pluginRegister) unmarshal(data []byte) error {
+	return consumePluginFields(data, func(num protowire.Number, typ protowire.Type, v []byte, n uint64) {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			m.Address = uint32(n)
+		case num == 2 && typ == protowire.BytesType:
+			m.Name = string(v)
+		case num == 3 && typ == protowire.Fixed64Type:
+			m.Value = math.Float64frombits(n)
+		}
+	})
+}
+
+// pluginUpdateRequest 對應 UpdateRequest (reset 為 true 時編碼為 ResetRequest，兩者欄位 1-3 相同)
+type pluginUpdateRequest struct {
+	InstanceID     uint64
+	Registers      []pluginRegister
+	Options        map[string]string
+	ElapsedSeconds float64
+	reset          bool
+}
+
+func (m *pluginUpdateRequest) marshal() []byte {
+	var b []byte
+	if m.InstanceID != 0 {
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, m.InstanceID)
+	}
+	for i := range m.Registers {
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendBytes(b, m.Registers[i].marshal())
+	}
+
+	// map 欄位依鍵排序輸出，讓編碼結果穩定
+	keys := make([]string, 0, len(m.Options))
+	for k := range m.Options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var entry []byte
+		entry = appendPluginString(entry, 1, k)
+		entry = appendPluginString(entry, 2, m.Options[k])
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+
+	if !m.reset && m.ElapsedSeconds != 0 {
+		b = protowire.AppendTag(b, 4, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(m.ElapsedSeconds))
+	}
+	return b
+}
+
+func (m *pluginUpdateRequest) unmarshal(data []byte) error {
+	var err error
+	consumeErr := consumePluginFields(data, func(num protowire.Number, typ protowire.Type, v []byte, n uint64) {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			m.InstanceID = n
+		case num == 2 && typ == protowire.BytesType:
+			var reg pluginRegister
+			if e := reg.unmarshal(v); e != nil {
+				err = e
+			}
+			m.Registers = append(m.Registers, reg)
+		case num == 3 && typ == protowire.BytesType:
+			var key, value string
+			if e := consumePluginFields(v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) {
+				if typ != protowire.BytesType {
+					return
+				}
+				if num == 1 {
+					key = string(v)
+				} else if num == 2 {
+					value = string(v)
+				}
+			}); e != nil {
+				err = e
+			}
+			if m.Options == nil {
+				m.Options = make(map[string]string)
+			}
+			m.Options[key] = value
+		case num == 4 && typ == protowire.Fixed64Type:
+			m.ElapsedSeconds = math.Float64frombits(n)
+		}
+	})
+	if consumeErr != nil {
+		return consumeErr
+	}
+	return err
+}
+
+// pluginUpdateResponse 對應 UpdateResponse
+type pluginUpdateResponse struct {
+	Writes []pluginRegister
+}
+
+func (m *pluginUpdateResponse) marshal() []byte {
+	var b []byte
+	for i := range m.Writes {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, m.Writes[i].marshal())
+	}
+	return b
+}
+
+func (m *pluginUpdateResponse) unmarshal(data []byte) error {
+	var err error
+	consumeErr := consumePluginFields(data, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) {
+		if num == 1 && typ == protowire.BytesType {
+			var reg pluginRegister
+			if e := reg.unmarshal(v); e != nil {
+				err = e
+			}
+			m.Writes = append(m.Writes, reg)
+		}
+	})
+	if consumeErr != nil {
+		return consumeErr
+	}
+	return err
+}
+
+func appendPluginString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+// consumePluginFields 逐一解析欄位；bytes 欄位以 v 傳回內容，varint/fixed 欄位以 n 傳回數值，未知欄位略過
+func consumePluginFields(data []byte, field func(num protowire.Number, typ protowire.Type, v []byte, n uint64)) error {
+	for len(data) > 0 {
+		num, typ, tagLen := protowire.ConsumeTag(data)
+		if tagLen < 0 {
+			return protowire.ParseError(tagLen)
+		}
+		data = data[tagLen:]
+
+		var valueLen int
+		switch typ {
+		case protowire.VarintType:
+			var n uint64
+			n, valueLen = protowire.ConsumeVarint(data)
+			if valueLen >= 0 {
+				field(num, typ, nil, n)
+			}
+		case protowire.Fixed64Type:
+			var n uint64
+			n, valueLen = protowire.ConsumeFixed64(data)
+			if valueLen >= 0 {
+				field(num, typ, nil, n)
+			}
+		case protowire.Fixed32Type:
+			var n uint32
+			n, valueLen = protowire.ConsumeFixed32(data)
+			if valueLen >= 0 {
+				field(num, typ, nil, uint64(n))
+			}
+		case protowire.BytesType:
+			var v []byte
+			v, valueLen = protowire.ConsumeBytes(data)
+			if valueLen >= 0 {
+				field(num, typ, v, 0)
+			}
+		default:
+			valueLen = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if valueLen < 0 {
+			return protowire.ParseError(valueLen)
+		}
+		data = data[valueLen:]
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Pod 身分的環境變數 (由 generate k8s 產生的 StatefulSet 以 Downward API 注入)
+const (
+	EnvPodName      = "POD_NAME"
+	EnvPodNamespace = "POD_NAMESPACE"
+	EnvPodIP        = "POD_IP"
+	EnvPodInterface = "MODBUSSIM_POD_INTERFACE" // 自此介面取得 Slave IP (例如 Multus 附加的 net1；空字串 = POD_IP)
+)
+
+// PodIdentity 以 Kubernetes StatefulSet 運行時的 Pod 身分 (每個 Pod 為一個 Slave，序號決定 Unit ID)
+type PodIdentity struct {
+	Name      string // Pod 名稱 (<StatefulSet>-<序號>)
+	Namespace string
+	IP        net.IP // Slave 監聽的 IP
+	Ordinal   int    // StatefulSet 序號
+}
+
+// PodIdentityFromEnv 由環境變數取得 Pod 身分 (未注入 POD_NAME 時沿用 HOSTNAME，StatefulSet 的主機名稱即 Pod 名稱)
+func PodIdentityFromEnv(getenv func(string) string) (PodIdentity, error) {
+	id := PodIdentity{
+		Name:      getenv(EnvPodName),
+		Namespace: getenv(EnvPodNamespace),
+	}
+	if id.Name == "" {
+		id.Name = getenv("HOSTNAME")
+	}
+	if id.Name == "" {
+		return id, fmt.Errorf(T("未設定環境變數 %s"), EnvPodName)
+	}
+
+	ordinal, err := podOrdinal(id.Name)
+	if err != nil {
+		return id, err
+	}
+	id.Ordinal = ordinal
+
+	if name := getenv(EnvPodInterface); name != "" {
+		id.IP, err = interfaceIPv4(name)
+		if err != nil {
+			return id, err
+		}
+		return id, nil
+	}
+
+	ip := getenv(EnvPodIP)
+	if ip == "" {
+		return id, fmt.Errorf(T("未設定環境變數 %s"), EnvPodIP)
+	}
+	if id.IP = net.ParseIP(ip); id.IP == nil {
+		return id, fmt.Errorf(T("無效的 IP 位址: %s"), ip)
+	}
+	return id, nil
+}
+
+// podOrdinal 由 Pod 名稱的結尾取得 StatefulSet 序號
+func podOrdinal(name string) (int, error) {
+	i := strings.LastIndexByte(name, '-')
+	ordinal, err := strconv.Atoi(name[i+1:])
+	if i < 0 || err != nil || ordinal < 0 {
+		return 0, fmt.Errorf(T("Pod 名稱 %s 不含 StatefulSet 序號"), name)
+	}
+	return ordinal, nil
+}
+
+// interfaceIPv4 網路介面的第一個 IPv4 位址
+func interfaceIPv4(name string) (net.IP, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, fmt.Errorf(T("找不到網路介面 %s: %w"), name, err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf(T("列出 IP 失敗: %w"), err)
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+			return ipNet.IP.To4(), nil
+		}
+	}
+	return nil, fmt.Errorf(T("網路介面 %s 沒有 IPv4 位址"), name)
+}
+
+// seedKey 此 Pod 的 Slave 的亂數序列識別 (Pod 重新排程後 IP 改變，序列仍不變)
+func (p *PodIdentity) seedKey() string {
+	return p.Namespace + "/" + p.Name
+}
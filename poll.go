@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 輪詢分析預設值
+const (
+	DefaultPollMinPolls     = 20
+	DefaultPollHotSpotRatio = 10
+	DefaultPollMaxBlocks    = 10000
+)
+
+// pollKey 輪詢區塊 (同一 Master 對同一 Slave 以相同功能碼讀取相同範圍)
+type pollKey struct {
+	master   string
+	slave    string
+	function uint8
+	address  uint16
+	quantity uint16
+}
+
+// pollBlock 輪詢區塊的觀察結果
+type pollBlock struct {
+	polls       uint64
+	changes     uint64   // 回應內容與上次不同的次數
+	unitChanges []uint32 // 各暫存器/線圈的變化次數
+	first       time.Time
+	last        time.Time
+	lastData    []byte
+}
+
+// PollTracker 觀察各 Master 的讀取請求，找出讀取頻率遠高於數值變化頻率的暫存器 (輪詢熱點)
+type PollTracker struct {
+	minPolls  uint64
+	ratio     float64
+	maxBlocks int
+
+	mu      sync.Mutex
+	blocks  map[pollKey]*pollBlock
+	since   time.Time
+	dropped atomic.Uint64 // 超過區塊上限而未追蹤的請求數
+
+	redundant atomic.Uint64 // 回應與上次相同的輪詢數
+}
+
+// NewPollTracker 依配置建立輪詢分析器
+func NewPollTracker(cfg PollingConfig) *PollTracker {
+	t := &PollTracker{
+		minPolls:  uint64(cfg.MinPolls),
+		ratio:     cfg.HotSpotRatio,
+		maxBlocks: cfg.MaxBlocks,
+		blocks:    make(map[pollKey]*pollBlock),
+		since:     time.Now(),
+	}
+	if t.minPolls == 0 {
+		t.minPolls = DefaultPollMinPolls
+	}
+	if t.ratio == 0 {
+		t.ratio = DefaultPollHotSpotRatio
+	}
+	if t.maxBlocks == 0 {
+		t.maxBlocks = DefaultPollMaxBlocks
+	}
+	return t
+}
+
+// Record 記錄一次成功的讀取 (data 為回應中 byte count 之後的資料)
+func (t *PollTracker) Record(master, slave string, function uint8, address, quantity uint16, data []byte, at time.Time) {
+	key := pollKey{master: master, slave: slave, function: function, address: address, quantity: quantity}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	block, ok := t.blocks[key]
+	if !ok {
+		if len(t.blocks) >= t.maxBlocks {
+			t.dropped.Add(1)
+			return
+		}
+		block = &pollBlock{unitChanges: make([]uint32, quantity), first: at}
+		t.blocks[key] = block
+	}
+
+	block.polls++
+	block.last = at
+	if block.lastData != nil {
+		if bytes.Equal(block.lastData, data) {
+			t.redundant.Add(1)
+		} else {
+			block.changes++
+			for i := range block.unitChanges {
+				if pollUnit(function, block.lastData, i) != pollUnit(function, data, i) {
+					block.unitChanges[i]++
+				}
+			}
+		}
+	}
+	block.lastData = append(block.lastData[:0], data...)
+}
+
+// pollUnit 取得第 i 個暫存器 (16 位元) 或線圈 (位元) 的值
+func pollUnit(function uint8, data []byte, i int) uint16 {
+	switch function {
+	case FuncCodeReadCoils, FuncCodeReadDiscreteInputs:
+		if i/8 >= len(data) {
+			return 0
+		}
+		return uint16(data[i/8]>>(i%8)) & 1
+	default:
+		if 2*i+1 >= len(data) {
+			return 0
+		}
+		return uint16(data[2*i])<<8 | uint16(data[2*i+1])
+	}
+}
+
+// Redundant 回應與上次相同的輪詢數
+func (t *PollTracker) Redundant() uint64 {
+	return t.redundant.Load()
+}
+
+// Reset 清除所有觀察結果並重新起算
+func (t *PollTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.blocks = make(map[pollKey]*pollBlock)
+	t.since = time.Now()
+	t.dropped.Store(0)
+	t.redundant.Store(0)
+}
+
+// PollHotSpot 輪詢熱點 (讀取次數遠多於數值變化次數的區塊)
+type PollHotSpot struct {
+	Slave             string   `json:"slave"`
+	Function          uint8    `json:"function"`
+	Address           uint16   `json:"address"`
+	Quantity          uint16   `json:"quantity"`
+	Polls             uint64   `json:"polls"`
+	Changes           uint64   `json:"changes"`
+	Efficiency        float64  `json:"efficiency"`                   // 變化次數 / 可比較的輪詢次數
+	PollInterval      string   `json:"poll_interval"`                // 平均輪詢間隔
+	ChangeInterval    string   `json:"change_interval,omitempty"`    // 平均變化間隔 (觀察期間未變化時為空)
+	SuggestedInterval string   `json:"suggested_interval,omitempty"` // 建議的輪詢間隔 (即平均變化間隔)
+	StaticRegisters   []uint16 `json:"static_registers,omitempty"`   // 觀察期間從未變化的位址
+}
+
+// MasterPollReport 單一 Master 的輪詢效率
+type MasterPollReport struct {
+	Master         string        `json:"master"`
+	Blocks         int           `json:"blocks"`
+	Polls          uint64        `json:"polls"`
+	RedundantPolls uint64        `json:"redundant_polls"`
+	Efficiency     float64       `json:"efficiency"`
+	HotSpots       []PollHotSpot `json:"hot_spots"` // 依多餘輪詢數排序
+}
+
+// PollReport 輪詢效率報告
+type PollReport struct {
+	Since   time.Time          `json:"since"`
+	Until   time.Time          `json:"until"`
+	Dropped uint64             `json:"dropped,omitempty"` // 超過區塊上限而未追蹤的請求數
+	Masters []MasterPollReport `json:"masters"`
+}
+
+// Report 產生輪詢效率報告 (master 非空時僅含該 Master)
+func (t *PollTracker) Report(master string) PollReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report := PollReport{Since: t.since, Until: time.Now(), Dropped: t.dropped.Load(), Masters: []MasterPollReport{}}
+
+	masters := make(map[string]*MasterPollReport)
+	for key, block := range t.blocks {
+		if master != "" && key.master != master {
+			continue
+		}
+		m, ok := masters[key.master]
+		if !ok {
+			m = &MasterPollReport{Master: key.master, HotSpots: []PollHotSpot{}}
+			masters[key.master] = m
+		}
+		m.Blocks++
+		m.Polls += block.polls
+		if block.polls > 0 {
+			m.RedundantPolls += block.polls - 1 - block.changes
+		}
+
+		if block.polls < t.minPolls || float64(block.polls) < t.ratio*float64(max(block.changes, 1)) {
+			continue
+		}
+		m.HotSpots = append(m.HotSpots, block.hotSpot(key))
+	}
+
+	for _, m := range masters {
+		if compared := m.Polls - uint64(m.Blocks); compared > 0 {
+			m.Efficiency = 1 - float64(m.RedundantPolls)/float64(compared)
+		}
+		sort.Slice(m.HotSpots, func(i, j int) bool {
+			a, b := m.HotSpots[i], m.HotSpots[j]
+			if wa, wb := a.Polls-a.Changes, b.Polls-b.Changes; wa != wb {
+				return wa > wb
+			}
+			if a.Slave != b.Slave {
+				return a.Slave < b.Slave
+			}
+			return a.Address < b.Address
+		})
+		report.Masters = append(report.Masters, *m)
+	}
+	sort.Slice(report.Masters, func(i, j int) bool { return report.Masters[i].Master < report.Masters[j].Master })
+	return report
+}
+
+// hotSpot 轉換為熱點報告
+func (b *pollBlock) hotSpot(key pollKey) PollHotSpot {
+	spot := PollHotSpot{
+		Slave:    key.slave,
+		Function: key.function,
+		Address:  key.address,
+		Quantity: key.quantity,
+		Polls:    b.polls,
+		Changes:  b.changes,
+	}
+	window := b.last.Sub(b.first)
+	if b.polls > 1 {
+		spot.Efficiency = float64(b.changes) / float64(b.polls-1)
+		spot.PollInterval = (window / time.Duration(b.polls-1)).Round(time.Millisecond).String()
+	}
+	if b.changes > 0 {
+		interval := (window / time.Duration(b.changes)).Round(time.Millisecond).String()
+		spot.ChangeInterval = interval
+		spot.SuggestedInterval = interval
+	}
+	for i, changes := range b.unitChanges {
+		if changes == 0 {
+			spot.StaticRegisters = append(spot.StaticRegisters, key.address+uint16(i))
+		}
+	}
+	return spot
+}
+
+// observePoll 記錄成功的讀取請求供輪詢分析 (未啟用時略過)
+func (s *Slave) observePoll(packet, response []byte, remote net.Addr) {
+	if s.polls == nil || len(packet) < ModbusTCPHeaderLength+5 || len(response) < ModbusTCPHeaderLength+2 {
+		return
+	}
+
+	function := packet[ModbusTCPHeaderLength]
+	switch function {
+	case FuncCodeReadCoils, FuncCodeReadDiscreteInputs, FuncCodeReadHoldingRegisters, FuncCodeReadInputRegisters:
+	default:
+		return
+	}
+	pdu := packet[ModbusTCPHeaderLength+1:]
+	address := uint16(pdu[0])<<8 | uint16(pdu[1])
+	quantity := uint16(pdu[2])<<8 | uint16(pdu[3])
+
+	master := remote.String()
+	if host, _, err := net.SplitHostPort(master); err == nil {
+		master = host
+	}
+	s.polls.Record(master, s.ID, function, address, quantity, response[ModbusTCPHeaderLength+2:], time.Now())
+}
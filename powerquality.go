@@ -0,0 +1,195 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// 電能品質暫存器位址
+const (
+	AddrVoltageTHD uint16 = 40030 // 電壓總諧波失真 (%)
+	AddrCurrentTHD uint16 = 40031 // 電流總諧波失真 (%)
+	AddrHarmonic3  uint16 = 40032 // 第 3 次諧波電壓 (相對基波 %)
+	AddrHarmonic5  uint16 = 40033 // 第 5 次諧波電壓 (相對基波 %)
+	AddrHarmonic7  uint16 = 40034 // 第 7 次諧波電壓 (相對基波 %)
+	AddrFlickerPst uint16 = 40035 // 短時閃爍嚴重度 Pst
+	AddrFlickerPlt uint16 = 40036 // 長時閃爍嚴重度 Plt
+)
+
+// ProfilePowerQuality 電能品質分析儀設定檔名稱
+const ProfilePowerQuality = "power_quality"
+
+// 諧波失真場景的預設參數
+const (
+	DefaultHarmonicTHD      = 8.0
+	DefaultHarmonicOrder    = 5
+	DefaultHarmonicInterval = time.Minute
+	DefaultHarmonicDuration = 20 * time.Second
+	DefaultFlickerPst       = 1.2
+)
+
+// 電能品質的正常值
+var (
+	harmonicOrders    = [3]int{3, 5, 7}
+	baselineHarmonics = [3]float64{1.5, 2.0, 1.0} // H3/H5/H7 (%)
+)
+
+const (
+	baselineFlickerPst    = 0.35
+	currentDistortionGain = 3.0  // 非線性負載的電流 THD 約為電壓 THD 的倍數
+	flickerPltSmoothing   = 0.02 // Plt 每次更新向 Pst 靠近的比例 (Plt 為 2 小時的長時指標)
+)
+
+func init() {
+	RegisterDeviceProfile(&DeviceProfile{
+		Name:        ProfilePowerQuality,
+		Description: "電能品質分析儀 (三相暫存器 + 電壓/電流 THD、H3/H5/H7 諧波、閃爍 Pst/Plt)",
+		Registers: append(threePhaseRegisters(),
+			RegisterDefinition{Address: AddrVoltageTHD, Name: "VoltageTHD", DataType: "uint16", Scale: 100, DefaultValue: harmonicTHD(baselineHarmonics), Unit: "%"},
+			RegisterDefinition{Address: AddrCurrentTHD, Name: "CurrentTHD", DataType: "uint16", Scale: 100, DefaultValue: harmonicTHD(baselineHarmonics) * currentDistortionGain, Unit: "%"},
+			RegisterDefinition{Address: AddrHarmonic3, Name: "Harmonic3", DataType: "uint16", Scale: 100, DefaultValue: baselineHarmonics[0], Unit: "%"},
+			RegisterDefinition{Address: AddrHarmonic5, Name: "Harmonic5", DataType: "uint16", Scale: 100, DefaultValue: baselineHarmonics[1], Unit: "%"},
+			RegisterDefinition{Address: AddrHarmonic7, Name: "Harmonic7", DataType: "uint16", Scale: 100, DefaultValue: baselineHarmonics[2], Unit: "%"},
+			RegisterDefinition{Address: AddrFlickerPst, Name: "FlickerPst", DataType: "uint16", Scale: 100, DefaultValue: baselineFlickerPst, Unit: ""},
+			RegisterDefinition{Address: AddrFlickerPlt, Name: "FlickerPlt", DataType: "uint16", Scale: 100, DefaultValue: baselineFlickerPst, Unit: ""},
+		),
+	})
+}
+
+// harmonicTHD 依各次諧波 (相對基波 %) 計算總諧波失真 (%)
+func harmonicTHD(harmonics [3]float64) float64 {
+	sum := 0.0
+	for _, h := range harmonics {
+		sum += h * h
+	}
+	return math.Sqrt(sum)
+}
+
+// updatePowerQuality 更新電能品質暫存器 (僅在設定檔定義時生效)：各次諧波與 Pst 以 harmonics、pst 為中心小幅波動，
+// THD 由各次諧波計算，Plt 緩慢跟隨 Pst (立方平均)
+func updatePowerQuality(registers *RegisterMap, harmonics [3]float64, pst float64) {
+	if _, ok := registers.GetDefinition(AddrVoltageTHD); !ok {
+		return
+	}
+
+	random := registerRandom(registers)
+	addrs := [3]uint16{AddrHarmonic3, AddrHarmonic5, AddrHarmonic7}
+	var measured [3]float64
+	for i, h := range harmonics {
+		measured[i] = h * (1 + (random.Float64()*2-1)*0.1)
+		registers.SetScaledValue(addrs[i], measured[i])
+	}
+	thd := harmonicTHD(measured)
+	registers.SetScaledValue(AddrVoltageTHD, thd)
+	registers.SetScaledValue(AddrCurrentTHD, thd*currentDistortionGain*(1+(random.Float64()*2-1)*0.05))
+
+	pst *= 1 + (random.Float64()*2-1)*0.1
+	plt, _ := registers.GetScaledValue(AddrFlickerPlt)
+	plt = math.Cbrt(plt*plt*plt*(1-flickerPltSmoothing) + pst*pst*pst*flickerPltSmoothing)
+	registers.SetScaledValue(AddrFlickerPst, pst)
+	registers.SetScaledValue(AddrFlickerPlt, plt)
+}
+
+// resetPowerQuality 將電能品質暫存器還原為正常值 (僅在設定檔定義時生效)
+func resetPowerQuality(registers *RegisterMap) {
+	if _, ok := registers.GetDefinition(AddrVoltageTHD); !ok {
+		return
+	}
+	thd := harmonicTHD(baselineHarmonics)
+	registers.SetScaledValue(AddrVoltageTHD, thd)
+	registers.SetScaledValue(AddrCurrentTHD, thd*currentDistortionGain)
+	registers.SetScaledValue(AddrHarmonic3, baselineHarmonics[0])
+	registers.SetScaledValue(AddrHarmonic5, baselineHarmonics[1])
+	registers.SetScaledValue(AddrHarmonic7, baselineHarmonics[2])
+	registers.SetScaledValue(AddrFlickerPst, baselineFlickerPst)
+	registers.SetScaledValue(AddrFlickerPlt, baselineFlickerPst)
+}
+
+// --- Harmonic Distortion Scenario ---
+
+// HarmonicDistortionScenario 諧波失真場景 - 每 harmonic_interval 發生一次持續 duration 的諧波事件
+// (例如變頻器或整流負載投入)：harmonic_order 次諧波增大至電壓 THD 達 harmonic_thd，閃爍 Pst 升至 flicker_pst；
+// 事件之間照常波動。需 power_quality 設定檔，其他設定檔等同 normal
+type HarmonicDistortionScenario struct {
+	normalScenario NormalScenario
+	mu             sync.Mutex
+	starts         map[*RegisterMap]time.Time
+}
+
+func (s *HarmonicDistortionScenario) Type() ScenarioType {
+	return ScenarioHarmonicDistortion
+}
+
+func (s *HarmonicDistortionScenario) Update(registers *RegisterMap, params ScenarioParams) {
+	s.normalScenario.Update(registers, ScenarioParams{
+		VoltageVariance:   0.005,
+		FrequencyVariance: 0.0005,
+	})
+	if !s.EventActive(registers, params) {
+		return
+	}
+
+	thd := params.HarmonicTHD
+	if thd == 0 {
+		thd = DefaultHarmonicTHD
+	}
+	pst := params.FlickerPst
+	if pst == 0 {
+		pst = DefaultFlickerPst
+	}
+	updatePowerQuality(registers, eventHarmonics(params.HarmonicOrder, thd), pst)
+}
+
+func (s *HarmonicDistortionScenario) Reset(registers *RegisterMap) {
+	s.mu.Lock()
+	delete(s.starts, registers)
+	s.mu.Unlock()
+	s.normalScenario.Reset(registers)
+}
+
+// EventActive 映射表目前是否處於諧波事件中 (第一次更新時即開始第一個事件)
+func (s *HarmonicDistortionScenario) EventActive(registers *RegisterMap, params ScenarioParams) bool {
+	now := scenarioNow()
+	s.mu.Lock()
+	if s.starts == nil {
+		s.starts = make(map[*RegisterMap]time.Time)
+	}
+	start, ok := s.starts[registers]
+	if !ok {
+		start = now
+		s.starts[registers] = start
+	}
+	s.mu.Unlock()
+
+	interval := durationOr(params.HarmonicInterval, DefaultHarmonicInterval)
+	duration := durationOr(params.Duration, DefaultHarmonicDuration)
+	return now.Sub(start)%interval < duration
+}
+
+// eventHarmonics 諧波事件的各次諧波：order 次諧波增大至 THD 達 thd，其餘維持正常值
+func eventHarmonics(order int, thd float64) [3]float64 {
+	if order == 0 {
+		order = DefaultHarmonicOrder
+	}
+	harmonics := baselineHarmonics
+	for i, o := range harmonicOrders {
+		if o != order {
+			continue
+		}
+		total := harmonicTHD(harmonics)
+		rest := total*total - harmonics[i]*harmonics[i]
+		harmonics[i] = math.Max(math.Sqrt(math.Max(thd*thd-rest, 0)), harmonics[i])
+	}
+	return harmonics
+}
+
+// isHarmonicOrder 是否為電能品質暫存器提供的諧波次數
+func isHarmonicOrder(order int) bool {
+	for _, o := range harmonicOrders {
+		if o == order {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// privilegeCapabilities 降級後可保留的 capability 與其 Linux 編號
+var privilegeCapabilities = map[string]uint{
+	"net_bind_service": 10, // 綁定 1024 以下的埠 (離線、備援或重試後重新監聽 502 埠)
+	"net_admin":        12, // 配置/移除虛擬 IP (除役的 remove_ip)
+	"net_raw":          13,
+}
+
+// DefaultPrivilegeCapability 預設保留的 capability
+const DefaultPrivilegeCapability = "net_bind_service"
+
+// PrivilegeCapabilityNames 可保留的 capability 名稱
+func PrivilegeCapabilityNames() []string {
+	names := make([]string, 0, len(privilegeCapabilities))
+	for name := range privilegeCapabilities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseCapabilities 將 capability 名稱轉為 Linux 編號
+func parseCapabilities(names []string) ([]uint, error) {
+	caps := make([]uint, 0, len(names))
+	for _, name := range names {
+		c, ok := privilegeCapabilities[name]
+		if !ok {
+			return nil, fmt.Errorf(T("不支援的 capability: %s (可用: %s)"), name, strings.Join(PrivilegeCapabilityNames(), ", "))
+		}
+		caps = append(caps, c)
+	}
+	return caps, nil
+}
+
+// capabilityMasks capset 使用的位元遮罩 (每 32 個 capability 一組)
+func capabilityMasks(caps []uint) [2]uint32 {
+	var masks [2]uint32
+	for _, c := range caps {
+		masks[c/32] |= 1 << (c % 32)
+	}
+	return masks
+}
+
+// privilegeTarget 降級的目標身分
+type privilegeTarget struct {
+	UID, GID int
+	Caps     []uint
+}
+
+// resolvePrivileges 依設定查詢目標使用者、群組與 capability
+func resolvePrivileges(cfg PrivilegeConfig, lookup privilegeLookup) (privilegeTarget, error) {
+	uid, gid, err := lookup.userIDs(cfg.User)
+	if err != nil {
+		return privilegeTarget{}, err
+	}
+	if cfg.Group != "" {
+		if gid, err = lookup.groupID(cfg.Group); err != nil {
+			return privilegeTarget{}, err
+		}
+	}
+	caps, err := parseCapabilities(cfg.Capabilities)
+	if err != nil {
+		return privilegeTarget{}, err
+	}
+	return privilegeTarget{UID: uid, GID: gid, Caps: caps}, nil
+}
+
+// privilegeOps 降級權限使用的系統呼叫 (測試時替換為記錄呼叫順序的實作)
+type privilegeOps interface {
+	KeepCaps(keep bool) error
+	Setgroups(gids []int) error
+	Setgid(gid int) error
+	Setuid(uid int) error
+	Capset(masks [2]uint32) error
+}
+
+// applyPrivilegeDrop 依序降級：保留 permitted capability (PR_SET_KEEPCAPS)、附加群組、GID，
+// 最後才切換 UID (之後已無權限變更群組)，再只留下指定的 capability 並關閉 KEEPCAPS
+func applyPrivilegeDrop(ops privilegeOps, target privilegeTarget) error {
+	if len(target.Caps) > 0 {
+		if err := ops.KeepCaps(true); err != nil {
+			return err
+		}
+	}
+	if err := ops.Setgroups([]int{target.GID}); err != nil {
+		return fmt.Errorf("setgroups: %w", err)
+	}
+	if err := ops.Setgid(target.GID); err != nil {
+		return fmt.Errorf("setgid: %w", err)
+	}
+	if err := ops.Setuid(target.UID); err != nil {
+		return fmt.Errorf("setuid: %w", err)
+	}
+	if len(target.Caps) == 0 {
+		return nil
+	}
+
+	// setuid 後 permitted 保留但 effective 已清空：只留下指定的 capability
+	if err := ops.Capset(capabilityMasks(target.Caps)); err != nil {
+		return fmt.Errorf("capset: %w", err)
+	}
+	return ops.KeepCaps(false)
+}
+
+// DropPrivileges 切換為 cfg.User 指定的一般使用者並只保留 cfg.Capabilities (未指定使用者時不動作)
+//
+// 需在綁定所有 listener 與配置虛擬 IP 之後呼叫；降級後無法恢復 root 權限。
+func DropPrivileges(cfg PrivilegeConfig, logger *zap.Logger) error {
+	if cfg.User == "" {
+		return nil
+	}
+
+	target, err := resolvePrivileges(cfg, systemLookup)
+	if err != nil {
+		return err
+	}
+
+	if euid := os.Geteuid(); euid != 0 {
+		if euid == target.UID {
+			return nil // 已是目標使用者
+		}
+		return fmt.Errorf(T("降級權限需以 root 啟動 (目前 euid=%d)"), euid)
+	}
+
+	if err := dropPrivileges(target); err != nil {
+		return fmt.Errorf(T("降級權限失敗: %w"), err)
+	}
+
+	logger.Info(T("已降級權限"),
+		zap.String("user", cfg.User),
+		zap.Int("uid", target.UID),
+		zap.Int("gid", target.GID),
+		zap.Strings("capabilities", cfg.Capabilities),
+	)
+	return nil
+}
+
+// privilegeLookup 使用者與群組的查詢 (測試時替換)
+type privilegeLookup struct {
+	lookupUser    func(name string) (*user.User, error)
+	lookupUserID  func(uid string) (*user.User, error)
+	lookupGroup   func(name string) (*user.Group, error)
+	lookupGroupID func(gid string) (*user.Group, error)
+}
+
+// systemLookup 以系統的使用者資料庫查詢
+var systemLookup = privilegeLookup{
+	lookupUser:    user.Lookup,
+	lookupUserID:  user.LookupId,
+	lookupGroup:   user.LookupGroup,
+	lookupGroupID: user.LookupGroupId,
+}
+
+// userIDs 依名稱或數字 ID 取得使用者的 UID 與主要群組 (查無資料的數字 ID 直接使用，主要群組同 UID)
+func (l privilegeLookup) userIDs(name string) (uid, gid int, err error) {
+	u, err := l.lookupUser(name)
+	if err != nil {
+		if u, err = l.lookupUserID(name); err != nil {
+			if id, convErr := strconv.Atoi(name); convErr == nil && id > 0 {
+				return id, id, nil
+			}
+			return 0, 0, fmt.Errorf(T("找不到使用者: %s"), name)
+		}
+	}
+	uid, _ = strconv.Atoi(u.Uid)
+	gid, _ = strconv.Atoi(u.Gid)
+	if uid == 0 {
+		return 0, 0, errors.New(T("降級的目標使用者不可為 root"))
+	}
+	return uid, gid, nil
+}
+
+// groupID 依名稱或數字 ID 取得群組 GID
+func (l privilegeLookup) groupID(name string) (int, error) {
+	g, err := l.lookupGroup(name)
+	if err != nil {
+		if g, err = l.lookupGroupID(name); err != nil {
+			if id, convErr := strconv.Atoi(name); convErr == nil && id > 0 {
+				return id, nil
+			}
+			return 0, fmt.Errorf(T("找不到群組: %s"), name)
+		}
+	}
+	gid, _ := strconv.Atoi(g.Gid)
+	return gid, nil
+}
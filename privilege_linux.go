@@ -0,0 +1,63 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// dropPrivileges 切換 UID/GID 並只保留指定的 capability (作用於所有執行緒)
+//
+// 保留 capability 需要 PR_SET_KEEPCAPS 與 capset 套用到所有執行緒，
+// Go 僅在未啟用 cgo 時支援 (syscall.AllThreadsSyscall)。
+func dropPrivileges(target privilegeTarget) error {
+	return applyPrivilegeDrop(linuxPrivilegeOps{}, target)
+}
+
+// linuxPrivilegeOps 以 Linux 系統呼叫實作 privilegeOps
+type linuxPrivilegeOps struct{}
+
+func (linuxPrivilegeOps) KeepCaps(keep bool) error {
+	var arg uintptr
+	if keep {
+		arg = 1
+	}
+	if _, _, errno := syscall.AllThreadsSyscall(syscall.SYS_PRCTL, unix.PR_SET_KEEPCAPS, arg, 0); errno != 0 {
+		if errno == syscall.ENOTSUP {
+			return errors.New(T("保留 capability 需以 CGO_ENABLED=0 建置"))
+		}
+		return fmt.Errorf("prctl(PR_SET_KEEPCAPS): %w", errno)
+	}
+	return nil
+}
+
+func (linuxPrivilegeOps) Setgroups(gids []int) error {
+	return syscall.Setgroups(gids)
+}
+
+func (linuxPrivilegeOps) Setgid(gid int) error {
+	return syscall.Setgid(gid)
+}
+
+func (linuxPrivilegeOps) Setuid(uid int) error {
+	return syscall.Setuid(uid)
+}
+
+func (linuxPrivilegeOps) Capset(masks [2]uint32) error {
+	header := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	var data [2]unix.CapUserData
+	for i, mask := range masks {
+		data[i].Effective = mask
+		data[i].Permitted = mask
+	}
+	if _, _, errno := syscall.AllThreadsSyscall(syscall.SYS_CAPSET,
+		uintptr(unsafe.Pointer(&header)), uintptr(unsafe.Pointer(&data[0])), 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
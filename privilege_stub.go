@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// dropPrivileges 非 Linux 平台不支援權限降級
+func dropPrivileges(target privilegeTarget) error {
+	return errors.New(T("權限降級僅在 Linux 上支援"))
+}
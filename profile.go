@@ -0,0 +1,311 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 三相電表暫存器位址
+const (
+	AddrVoltageA         uint16 = 40009
+	AddrVoltageB         uint16 = 40010
+	AddrVoltageC         uint16 = 40011
+	AddrCurrentA         uint16 = 40012
+	AddrCurrentB         uint16 = 40013
+	AddrCurrentC         uint16 = 40014
+	AddrVoltageUnbalance uint16 = 40015
+)
+
+// 內建設備設定檔名稱
+const (
+	ProfileSinglePhase = "single_phase"
+	ProfileThreePhase  = "three_phase"
+)
+
+// DeviceProfile 設備設定檔 (暫存器佈局與預設值)
+type DeviceProfile struct {
+	Name        string
+	Description string
+	Registers   []RegisterDefinition
+	NewModel    func() DeviceModel // 選用：每個 Slave 建立一個設備模型
+	Addressing  AddressMode        // 暫存器定義的位址慣例 (預設為相容模式)
+
+	// RefreshInterval 量測值的內部更新週期 (選用)：設定後 Slave 依此週期而非 scenario.update_interval 更新暫存器，
+	// 輪詢比更新週期快的 Master 會讀到重複的相同值，如同真實電表；0 表示依 scenario.update_interval
+	RefreshInterval time.Duration
+
+	// HoldingRegisters 保持暫存器數量 (選用，0 表示 registerMapSize)：佈局超出 PDU 位址 9999 的設定檔 (如 SunSpec) 使用
+	HoldingRegisters int
+
+	// Coils 建立暫存器映射表時預設為 ON 的線圈位址 (選用)：快照或持久化還原時以還原的值為準
+	Coils []uint16
+
+	// Functions 設備支援的功能碼 (選用，nil 表示所有已實作的功能碼)：其他功能碼回應 Illegal Function (0x01)，
+	// 模擬只實作 FC03/06 等少數功能碼的設備；可由配置的 slaves.profile_functions 覆寫
+	Functions []uint8
+}
+
+// DeviceModel 設備行為模型 (於每次場景更新後呼叫，模擬設備自身的狀態變化)
+type DeviceModel interface {
+	Update(registers *RegisterMap, now time.Time)
+}
+
+// ScenarioTarget 暫存器佈局與場景寫入的電表位址 (40001-40007) 不相容的設備模型實作此介面：
+// 場景改寫模型回傳的內部電表暫存器，模型於 Update 時再換算為對外的暫存器
+type ScenarioTarget interface {
+	ScenarioRegisters(registers *RegisterMap) *RegisterMap
+}
+
+// scenarioRegisters 場景更新的暫存器映射表 (模型未實作 ScenarioTarget 時即為 registers)
+func scenarioRegisters(registers *RegisterMap, model DeviceModel) *RegisterMap {
+	if target, ok := model.(ScenarioTarget); ok {
+		return target.ScenarioRegisters(registers)
+	}
+	return registers
+}
+
+// StateRestorer 保有暫存器以外狀態的設備模型實作此介面 (快照還原後自暫存器重新載入)
+type StateRestorer interface {
+	RestoreState(registers *RegisterMap)
+}
+
+// 設備設定檔註冊表
+var (
+	deviceProfiles   = make(map[string]*DeviceProfile)
+	deviceProfilesMu sync.RWMutex
+)
+
+func init() {
+	RegisterDeviceProfile(&DeviceProfile{
+		Name:        ProfileSinglePhase,
+		Description: "單相電表 (電壓、電流、頻率、電能、功率因數、有功功率)",
+		Registers:   singlePhaseRegisters(),
+	})
+	RegisterDeviceProfile(&DeviceProfile{
+		Name:        ProfileThreePhase,
+		Description: "三相電表 (單相暫存器 + Va/Vb/Vc、Ia/Ib/Ic、電壓不平衡率)",
+		Registers:   threePhaseRegisters(),
+	})
+}
+
+// singlePhaseRegisters 單相電表暫存器定義 (與 DefaultRegisterMap 一致)
+func singlePhaseRegisters() []RegisterDefinition {
+	return []RegisterDefinition{
+		{Address: 40001, Name: "LineVoltage", DataType: "uint16", Scale: 10, DefaultValue: 220.0, Unit: "V"},
+		{Address: 40002, Name: "LineCurrent", DataType: "uint16", Scale: 100, DefaultValue: 15.50, Unit: "A"},
+		{Address: 40003, Name: "Frequency", DataType: "uint16", Scale: 100, DefaultValue: 60.00, Unit: "Hz"},
+		{Address: 40004, Name: "TotalEnergy", DataType: "uint32", Scale: 1, DefaultValue: 0, Unit: "kWh"},
+		{Address: 40006, Name: "PowerFactor", DataType: "uint16", Scale: 1000, DefaultValue: 0.95, Unit: ""},
+		{Address: 40007, Name: "ActivePower", DataType: "uint32", Scale: 10, DefaultValue: 3300, Unit: "W", Expression: "40001 * 40002 * 40006"},
+	}
+}
+
+// threePhaseRegisters 三相電表暫存器定義 (單相暫存器 + 各相電壓/電流與電壓不平衡率)
+func threePhaseRegisters() []RegisterDefinition {
+	return append(singlePhaseRegisters(),
+		RegisterDefinition{Address: AddrVoltageA, Name: "VoltageA", DataType: "uint16", Scale: 10, DefaultValue: 220.0, Unit: "V"},
+		RegisterDefinition{Address: AddrVoltageB, Name: "VoltageB", DataType: "uint16", Scale: 10, DefaultValue: 220.0, Unit: "V"},
+		RegisterDefinition{Address: AddrVoltageC, Name: "VoltageC", DataType: "uint16", Scale: 10, DefaultValue: 220.0, Unit: "V"},
+		RegisterDefinition{Address: AddrCurrentA, Name: "CurrentA", DataType: "uint16", Scale: 100, DefaultValue: 15.50, Unit: "A"},
+		RegisterDefinition{Address: AddrCurrentB, Name: "CurrentB", DataType: "uint16", Scale: 100, DefaultValue: 15.50, Unit: "A"},
+		RegisterDefinition{Address: AddrCurrentC, Name: "CurrentC", DataType: "uint16", Scale: 100, DefaultValue: 15.50, Unit: "A"},
+		RegisterDefinition{Address: AddrVoltageUnbalance, Name: "VoltageUnbalance", DataType: "uint16", Scale: 100, DefaultValue: 0, Unit: "%"},
+	)
+}
+
+// RegisterDeviceProfile 註冊設備設定檔
+func RegisterDeviceProfile(profile *DeviceProfile) {
+	deviceProfilesMu.Lock()
+	defer deviceProfilesMu.Unlock()
+	deviceProfiles[profile.Name] = profile
+}
+
+// GetDeviceProfile 取得設備設定檔
+func GetDeviceProfile(name string) (*DeviceProfile, bool) {
+	deviceProfilesMu.RLock()
+	defer deviceProfilesMu.RUnlock()
+	profile, ok := deviceProfiles[name]
+	return profile, ok
+}
+
+// ListDeviceProfiles 列出所有設備設定檔 (依名稱排序)
+func ListDeviceProfiles() []*DeviceProfile {
+	deviceProfilesMu.RLock()
+	defer deviceProfilesMu.RUnlock()
+
+	profiles := make([]*DeviceProfile, 0, len(deviceProfiles))
+	for _, p := range deviceProfiles {
+		profiles = append(profiles, p)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles
+}
+
+// HasRegister 設定檔是否定義了指定名稱的暫存器
+func (p *DeviceProfile) HasRegister(name string) bool {
+	for _, def := range p.Registers {
+		if def.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate 驗證設定檔的暫存器定義
+func (p *DeviceProfile) Validate() error {
+	err := validateRegisterDefinitions(p.Registers, p.Addressing, p.holdingRegisters())
+	if err == nil {
+		err = validateExpressions(p.Registers)
+	}
+	if err != nil {
+		return fmt.Errorf(T("設備設定檔 %s: %w"), p.Name, err)
+	}
+	return nil
+}
+
+// holdingRegisters 設定檔的保持暫存器數量
+func (p *DeviceProfile) holdingRegisters() int {
+	if p.HoldingRegisters > 0 {
+		return p.HoldingRegisters
+	}
+	return registerMapSize
+}
+
+// accumulatorUnits 累計量單位 (電能計數器只允許遞增，不應開放寫入)
+var accumulatorUnits = map[string]bool{
+	"Wh":    true,
+	"kWh":   true,
+	"MWh":   true,
+	"varh":  true,
+	"kvarh": true,
+}
+
+// registerMapSize 依暫存器定義建立的映射表各類暫存器的數量 (保持暫存器位址 0-9999 或 40001-50000)
+const registerMapSize = 10000
+
+// ValidateRegisterDefinitions 檢查暫存器定義衝突 (位址超出範圍、位址重疊、重複名稱、scale=0、可寫入的累計量、無效的運算式)；
+// 來自暫存器對應檔的定義於錯誤前加上檔名；位址依 mode 的慣例解讀
+func ValidateRegisterDefinitions(defs []RegisterDefinition, mode AddressMode) error {
+	if err := validateRegisterDefinitions(defs, mode, registerMapSize); err != nil {
+		return err
+	}
+	return validateExpressions(defs)
+}
+
+// validateRegisterDefinitions 檢查各定義本身與相鄰定義的衝突 (保持暫存器共 size 個)
+func validateRegisterDefinitions(defs []RegisterDefinition, mode AddressMode, size int) error {
+	sorted := make([]RegisterDefinition, len(defs))
+	copy(sorted, defs)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Address < sorted[j].Address })
+
+	names := make(map[string]uint16, len(sorted))
+	var prev *RegisterDefinition
+	var prevEnd int
+
+	for i := range sorted {
+		def := &sorted[i]
+
+		dataType, err := ParseDataType(def.DataType)
+		if err != nil {
+			return def.located(fmt.Errorf(T("暫存器 %s (%d): %w"), def.Name, def.Address, err))
+		}
+		if idx := mode.index(RegisterTypeHoldingRegister, def.Address); idx < 0 || idx+dataType.RegisterCount() > size {
+			return def.located(fmt.Errorf(T("暫存器 %s (%d): 位址超出範圍 (保持暫存器 %s)"),
+				def.Name, def.Address, mode.rangeText(RegisterTypeHoldingRegister, size)))
+		}
+		if def.Scale == 0 && dataType.Scaled() {
+			return def.located(fmt.Errorf(T("暫存器 %s (%d): scale 不可為 0"), def.Name, def.Address))
+		}
+		if dataType.IsString() {
+			if err := checkStringValue(def.Name, def.Address, dataType, def.DefaultText); err != nil {
+				return def.located(err)
+			}
+			if def.Expression != "" {
+				return def.located(fmt.Errorf(T("暫存器 %s (%d): 字串類型不可設定運算式"), def.Name, def.Address))
+			}
+		}
+		if def.Writable && accumulatorUnits[def.Unit] {
+			return def.located(fmt.Errorf(T("暫存器 %s (%d): 累計量 (%s) 不可設為可寫入"), def.Name, def.Address, def.Unit))
+		}
+
+		if addr, ok := names[def.Name]; ok && def.Name != "" {
+			return def.located(fmt.Errorf(T("暫存器名稱重複: %s (%d 與 %d)"), def.Name, addr, def.Address))
+		}
+		names[def.Name] = def.Address
+
+		if prev != nil && int(def.Address) < prevEnd {
+			return def.located(fmt.Errorf(T("暫存器 %s (%d, %s 佔用 %d-%d) 與 %s (%d) 位址重疊"),
+				prev.Name, prev.Address, prev.DataType, prev.Address, prevEnd-1, def.Name, def.Address))
+		}
+		prev = def
+		prevEnd = int(def.Address) + dataType.RegisterCount()
+	}
+
+	return nil
+}
+
+// located 在錯誤前加上定義所在的暫存器對應檔 (配置檔內的定義維持原樣)
+func (d *RegisterDefinition) located(err error) error {
+	if d.source == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %w", d.source, err)
+}
+
+// NewRegisterMap 依設定檔建立暫存器映射表並寫入預設值
+func (p *DeviceProfile) NewRegisterMap() (*RegisterMap, error) {
+	rm, err := newRegisterMapFromDefinitions(p.Registers, p.Addressing, p.holdingRegisters())
+	if err != nil {
+		return nil, err
+	}
+	for _, address := range p.Coils {
+		if err := rm.WriteCoil(address, true); err != nil {
+			return nil, err
+		}
+	}
+	return rm, nil
+}
+
+// NewRegisterMapFromDefinitions 依暫存器定義建立暫存器映射表 (位址依 mode 的慣例解讀)
+func NewRegisterMapFromDefinitions(defs []RegisterDefinition, mode AddressMode) (*RegisterMap, error) {
+	return newRegisterMapFromDefinitions(defs, mode, registerMapSize)
+}
+
+// newRegisterMapFromDefinitions 依暫存器定義建立保持暫存器共 holding 個的映射表
+func newRegisterMapFromDefinitions(defs []RegisterDefinition, mode AddressMode, holding int) (*RegisterMap, error) {
+	rm := NewRegisterMap(registerMapSize, registerMapSize, registerMapSize, holding)
+	rm.addressing = mode
+
+	for _, def := range defs {
+		dataType, err := ParseDataType(def.DataType)
+		if err != nil {
+			return nil, fmt.Errorf(T("暫存器 %s (%d): %w"), def.Name, def.Address, err)
+		}
+		if err := rm.DefineRegister(def.Address, def.Name, dataType, def.Scale, def.Unit, def.Writable); err != nil {
+			return nil, err
+		}
+		if dataType.IsString() {
+			if err := rm.SetString(def.Address, def.DefaultText); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := rm.SetScaledValue(def.Address, def.DefaultValue); err != nil {
+			return nil, fmt.Errorf(T("暫存器 %s (%d): %w"), def.Name, def.Address, err)
+		}
+	}
+
+	// 衍生暫存器需在所有參照的暫存器定義完成後才能設定
+	for _, def := range defs {
+		if def.Expression == "" {
+			continue
+		}
+		if err := rm.SetExpression(def.Address, def.Expression); err != nil {
+			return nil, fmt.Errorf(T("暫存器 %s (%d): %w"), def.Name, def.Address, err)
+		}
+	}
+
+	return rm, nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// parseClock 解析 HH:MM，回傳自午夜起的分鐘數
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// contains 時段是否包含指定時間 (結束時間早於開始時間時視為跨午夜)
+func (w ProtectedWindow) contains(now time.Time) bool {
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return false
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// hasTag Slave 是否屬於指定標籤
+func (c *Config) hasTag(ip net.IP, tag string) bool {
+	targets, ok := c.Slaves.Tags[tag]
+	return ok && len(targets) > 0 && MatchTargets(ip, targets)
+}
+
+// protectionReason 回傳 Slave 在指定時間受保護的原因，未受保護時回傳空字串
+func (e *Engine) protectionReason(ip net.IP, now time.Time) string {
+	for _, tag := range e.config.Protection.Tags {
+		if e.config.hasTag(ip, tag) {
+			return "tag:" + tag
+		}
+	}
+
+	for _, w := range e.config.Protection.Windows {
+		if !w.contains(now) {
+			continue
+		}
+		if len(w.Tags) == 0 {
+			return "window:" + w.Name
+		}
+		for _, tag := range w.Tags {
+			if e.config.hasTag(ip, tag) {
+				return "window:" + w.Name
+			}
+		}
+	}
+
+	return ""
+}
+
+// protectionEnabled 是否設定了任何保護規則
+func (e *Engine) protectionEnabled() bool {
+	return len(e.config.Protection.Windows) > 0 || len(e.config.Protection.Tags) > 0
+}
+
+// suppressScenario 抑制對受保護 Slave 的故障注入，保護結束後恢復
+func (e *Engine) suppressScenario(slave *Slave, scenario ScenarioType, reason string) {
+	e.protMu.Lock()
+	if e.suppressed == nil {
+		e.suppressed = make(map[string]ScenarioType)
+	}
+	e.suppressed[slave.ID] = scenario
+	e.protMu.Unlock()
+
+	e.suppressedCount.Add(1)
+	e.logger.Warn(T("已抑制故障注入"),
+		zap.String("audit", "fault_suppressed"),
+		zap.String("slave", slave.ID),
+		zap.String("scenario", scenario.String()),
+		zap.String("reason", reason),
+	)
+}
+
+// clearSuppressed 移除待恢復的場景
+func (e *Engine) clearSuppressed(slave *Slave) {
+	e.protMu.Lock()
+	delete(e.suppressed, slave.ID)
+	e.protMu.Unlock()
+}
+
+// runProtectionEnforcer 定期檢查保護狀態：進入保護時恢復正常場景，離開保護時恢復原場景
+func (e *Engine) runProtectionEnforcer(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			e.enforceProtection(now)
+		}
+	}
+}
+
+// enforceProtection 依指定時間套用保護規則
+func (e *Engine) enforceProtection(now time.Time) {
+	for _, slave := range e.ListSlaves() {
+		reason := e.protectionReason(slave.IP, now)
+
+		if reason != "" {
+			if current := slave.GetScenario(); current != ScenarioNormal {
+				e.suppressScenario(slave, current, reason)
+				slave.ApplyScenario(ScenarioNormal)
+			}
+			continue
+		}
+
+		e.protMu.Lock()
+		scenario, ok := e.suppressed[slave.ID]
+		delete(e.suppressed, slave.ID)
+		e.protMu.Unlock()
+
+		if ok {
+			slave.ApplyScenario(scenario)
+			e.logger.Info(T("保護結束，恢復場景"),
+				zap.String("audit", "fault_resumed"),
+				zap.String("slave", slave.ID),
+				zap.String("scenario", scenario.String()),
+			)
+		}
+	}
+}
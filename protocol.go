@@ -1,5 +1,12 @@
 package main
 
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
 // Modbus 協議常數
 const (
 	// Modbus 功能碼
@@ -11,6 +18,7 @@ const (
 	FuncCodeWriteSingleRegister    = 0x06
 	FuncCodeWriteMultipleCoils     = 0x0F
 	FuncCodeWriteMultipleRegisters = 0x10
+	FuncCodeEncapsulatedInterface  = 0x2B // MEI (僅支援 Read Device Identification)
 
 	// Modbus 異常碼
 	ExceptionCodeIllegalFunction         = 0x01
@@ -26,6 +34,7 @@ const (
 	// Modbus TCP 常數
 	ModbusTCPHeaderLength = 7  // MBAP Header 長度
 	ModbusTCPMaxADULength = 260
+	ModbusTCPMinADULength = 12 // MBAP Header + 功能碼 + 最短請求資料
 	ModbusTCPDefaultPort  = 502
 
 	// 暫存器限制
@@ -69,8 +78,45 @@ const (
 	DataTypeUint32
 	DataTypeInt32
 	DataTypeFloat32
+	DataTypeUint64
+	DataTypeInt64
+	DataTypeFloat64
+
+	// dataTypeString 之後的值為 ASCII 字串，與此值的差為字串的位元組長度 (見 DataTypeString)
+	dataTypeString DataType = 1 << 16
 )
 
+// MaxStringLength 字串類型的最大位元組長度
+const MaxStringLength = 128
+
+// DataTypeString 長度為 length 位元組的 ASCII 字串 (每個暫存器 2 個字元，高位元組在前；不足補 0)
+func DataTypeString(length int) DataType {
+	return dataTypeString + DataType(length)
+}
+
+// IsString 是否為字串類型
+func (dt DataType) IsString() bool {
+	return dt > dataTypeString
+}
+
+// StringLength 字串類型的位元組長度 (非字串類型為 0)
+func (dt DataType) StringLength() int {
+	if !dt.IsString() {
+		return 0
+	}
+	return int(dt - dataTypeString)
+}
+
+// Scaled 是否依 scale 縮放 (浮點數與字串不縮放)
+func (dt DataType) Scaled() bool {
+	switch {
+	case dt == DataTypeFloat32, dt == DataTypeFloat64, dt.IsString():
+		return false
+	default:
+		return true
+	}
+}
+
 func (dt DataType) String() string {
 	switch dt {
 	case DataTypeUint16:
@@ -83,9 +129,68 @@ func (dt DataType) String() string {
 		return "int32"
 	case DataTypeFloat32:
 		return "float32"
-	default:
-		return "unknown"
+	case DataTypeUint64:
+		return "uint64"
+	case DataTypeInt64:
+		return "int64"
+	case DataTypeFloat64:
+		return "float64"
+	}
+	if dt.IsString() {
+		return fmt.Sprintf("string(%d)", dt.StringLength())
 	}
+	return "unknown"
+}
+
+// ParseDataType 解析資料類型字串
+func ParseDataType(s string) (DataType, error) {
+	switch s {
+	case "uint16", "":
+		return DataTypeUint16, nil
+	case "int16":
+		return DataTypeInt16, nil
+	case "uint32":
+		return DataTypeUint32, nil
+	case "int32":
+		return DataTypeInt32, nil
+	case "float32":
+		return DataTypeFloat32, nil
+	case "uint64":
+		return DataTypeUint64, nil
+	case "int64":
+		return DataTypeInt64, nil
+	case "float64":
+		return DataTypeFloat64, nil
+	}
+
+	// string(長度)
+	if inner, ok := strings.CutPrefix(s, "string("); ok && strings.HasSuffix(inner, ")") {
+		length, err := strconv.Atoi(strings.TrimSuffix(inner, ")"))
+		if err != nil || length < 1 || length > MaxStringLength {
+			return DataTypeUint16, fmt.Errorf(T("字串長度必須介於 1-%d: %s"), MaxStringLength, s)
+		}
+		return DataTypeString(length), nil
+	}
+	return DataTypeUint16, fmt.Errorf(T("未知的資料類型: %s"), s)
+}
+
+// RawRange 整數類型的原始值範圍 (浮點數與字串 ok 為 false)
+func (dt DataType) RawRange() (min, max float64, ok bool) {
+	switch dt {
+	case DataTypeUint16:
+		return 0, math.MaxUint16, true
+	case DataTypeInt16:
+		return math.MinInt16, math.MaxInt16, true
+	case DataTypeUint32:
+		return 0, math.MaxUint32, true
+	case DataTypeInt32:
+		return math.MinInt32, math.MaxInt32, true
+	case DataTypeUint64:
+		return 0, math.MaxUint64, true
+	case DataTypeInt64:
+		return math.MinInt64, math.MaxInt64, true
+	}
+	return 0, 0, false
 }
 
 // RegisterCount 返回該資料類型佔用的暫存器數量
@@ -93,7 +198,14 @@ func (dt DataType) RegisterCount() int {
 	switch dt {
 	case DataTypeUint32, DataTypeInt32, DataTypeFloat32:
 		return 2
-	default:
-		return 1
+	case DataTypeUint64, DataTypeInt64, DataTypeFloat64:
+		return 4
 	}
+	if dt.IsString() {
+		return (dt.StringLength() + 1) / 2
+	}
+	return 1
 }
+
+// maxRegisterCount 單一定義最多佔用的暫存器數量 (最長的字串)
+const maxRegisterCount = (MaxStringLength + 1) / 2
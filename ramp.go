@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// rampInterval 每秒 rate 個的間隔 (rate <= 0 表示不限制，回傳 0)
+func rampInterval(rate float64) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / rate)
+}
+
+// pace 等待 interval (0 時立即返回)；ctx 結束時回傳 false
+func pace(ctx context.Context, interval time.Duration) bool {
+	if interval <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// RampPending 逐步上線中尚未啟動的 Slave 數
+func (e *Engine) RampPending() int {
+	return int(e.rampPending.Load())
+}
+
+// runRampUp 依 slaves.ramp_up_rate 逐一啟動 Slave (呼叫端需先設定 e.rampPending)，
+// 避免上千個 listener 與 ARP 通告同時出現；每完成約 10% 記錄一次進度
+func (e *Engine) runRampUp(ctx context.Context, ips []net.IP) {
+	defer e.rampPending.Store(0)
+
+	interval := rampInterval(e.config.Slaves.RampUpRate)
+	step := max(len(ips)/10, 1)
+	started, failed := 0, 0
+	var pending []*pendingBind
+
+	for i, ip := range ips {
+		if i > 0 && !pace(ctx, interval) {
+			return
+		}
+
+		slave, err := e.startSlave(ctx, ip, i)
+		if err != nil {
+			failed++
+			var conflict *BindConflictError
+			if errors.As(err, &conflict) {
+				e.recordBindConflict(ip, conflict)
+				if e.config.Server.BindRetryInterval > 0 {
+					pending = append(pending, &pendingBind{ip: ip, index: i})
+				}
+			} else {
+				e.logger.Warn(T("Slave 啟動失敗"), zap.String("ip", ip.String()), zap.Error(err))
+			}
+		} else {
+			if !e.addRetriedSlave(ctx, slave) {
+				return
+			}
+			e.applyCurrentScenario(slave)
+			started++
+		}
+
+		// 最後一個由 defer 歸零，待綁定重試的數量已先設定，兩者不會同時為 0 而誤判為就緒
+		if remaining := len(ips) - i - 1; remaining > 0 {
+			e.rampPending.Store(int64(remaining))
+		}
+		if done := i + 1; done%step == 0 && done < len(ips) {
+			e.logger.Info(T("Slave 逐步上線中"),
+				zap.Int("started", started),
+				zap.Int("failed", failed),
+				zap.Int("total", len(ips)),
+			)
+		}
+	}
+
+	e.logger.Info(T("Slave 逐步上線完成"),
+		zap.Int("started", started),
+		zap.Int("failed", failed),
+		zap.Duration("elapsed", time.Since(e.stats.StartTime)),
+	)
+
+	if len(pending) > 0 {
+		e.bindPending.Store(int64(len(pending)))
+		go e.runBindRetry(ctx, pending)
+	}
+}
+
+// applyCurrentScenario 對逐步上線的 Slave 套用啟動後才切換的場景 (遵守 targets 與故障注入保護)
+func (e *Engine) applyCurrentScenario(slave *Slave) {
+	scenario := e.GetScenario()
+	if scenario == ScenarioNormal {
+		return
+	}
+	if !MatchTargets(slave.IP, e.config.Scenario.Scenarios[scenario.String()].Targets) {
+		return
+	}
+	if reason := e.protectionReason(slave.IP, time.Now()); reason != "" {
+		e.suppressScenario(slave, scenario, reason)
+		return
+	}
+	slave.ApplyScenario(scenario)
+}
+
+// rampDown 依 slaves.ramp_down_rate 逐一停止 Slave；ctx 結束 (優雅關閉逾時) 時回傳尚未停止的 Slave
+func (e *Engine) rampDown(ctx context.Context, slaves []*Slave) []*Slave {
+	interval := rampInterval(e.config.Slaves.RampDownRate)
+	for i, slave := range slaves {
+		if i > 0 && !pace(ctx, interval) {
+			e.logger.Warn(T("逐步下線逾時，其餘 Slave 立即停止"), zap.Int("remaining", len(slaves)-i))
+			return slaves[i:]
+		}
+		if err := slave.Stop(ctx); err != nil {
+			e.logger.Warn(T("停止 Slave 失敗"),
+				zap.String("id", slave.ID),
+				zap.Error(err),
+			)
+		}
+	}
+	return nil
+}
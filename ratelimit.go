@@ -0,0 +1,63 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// requestLimiter 每個 Slave 的請求速率限制 (token bucket)
+type requestLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // 每秒補充的 token 數
+	burst  float64 // token 上限
+	tokens float64
+	last   time.Time
+}
+
+// newRequestLimiter 建立速率限制；burst 為 0 時為每秒請求數 (至少 1)
+func newRequestLimiter(rate float64, burst int) *requestLimiter {
+	b := float64(burst)
+	if burst <= 0 {
+		b = math.Max(1, math.Ceil(rate))
+	}
+	return &requestLimiter{rate: rate, burst: b, tokens: b}
+}
+
+// allow 取用一個 token；不足時回傳 false
+func (l *requestLimiter) allow(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.last.IsZero() {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// WithRequestRateLimit 限制每秒請求數，超過時回應 Slave Device Busy (0x06)；burst 為允許的瞬間請求數
+func WithRequestRateLimit(rate float64, burst int) SlaveOption {
+	return func(s *Slave) {
+		if rate > 0 {
+			s.limiter = newRequestLimiter(rate, burst)
+		}
+	}
+}
+
+// applyRateLimit 超過請求速率限制時回應 Busy
+func (h *RequestHandler) applyRateLimit(frame *tcpFrame) (response []byte, limited bool) {
+	limiter := h.slave.limiter
+	if limiter == nil || limiter.allow(time.Now()) {
+		return nil, false
+	}
+	h.slave.stats.RateLimited.Add(1)
+	h.logger.Debug(T("超過請求速率限制，回應 Busy"), zap.Uint8("function", frame.Function))
+	return frame.exception(ExceptionCodeSlaveDeviceBusy), true
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// pairState 主備配對的執行期狀態
+type pairState struct {
+	pair          RedundantPair
+	primaryActive bool
+	lastFailover  time.Time
+	failovers     uint64
+	suppressed    bool // 自動切換因保護規則被抑制 (每段保護僅記錄一次)
+}
+
+// active 目前作用中的 IP
+func (p *pairState) active() string {
+	if p.primaryActive {
+		return p.pair.Primary
+	}
+	return p.pair.Standby
+}
+
+// standby 目前備援中的 IP
+func (p *pairState) standby() string {
+	if p.primaryActive {
+		return p.pair.Standby
+	}
+	return p.pair.Primary
+}
+
+// PairStatus 主備配對狀態
+type PairStatus struct {
+	Name         string    `json:"name"`
+	Active       string    `json:"active"`
+	Standby      string    `json:"standby"`
+	Failovers    uint64    `json:"failovers"`
+	LastFailover time.Time `json:"last_failover,omitempty"`
+}
+
+// checkPairs 確認主備配對的兩端都是本實例要啟動的 Slave，避免配對只設定其中一端
+func (e *Engine) checkPairs(ips []net.IP) error {
+	serving := make(map[string]bool, len(ips))
+	for _, ip := range ips {
+		serving[ip.String()] = true
+	}
+	for _, pair := range e.config.Redundancy.Pairs {
+		for _, member := range []string{pair.Primary, pair.Standby} {
+			if !serving[net.ParseIP(member).String()] {
+				return fmt.Errorf(T("配對 %s 的 %s 不是本實例的 Slave (須位於 IP 範圍內、存在於本機且在 slaves.count 之內)"), pair.Name, member)
+			}
+		}
+	}
+	return nil
+}
+
+// initPairs 依配置建立主備配對，並將備援端切換為 standby (逐步上線或等待綁定重試的一端於加入引擎時設定)
+func (e *Engine) initPairs() {
+	e.pairsMu.Lock()
+	defer e.pairsMu.Unlock()
+
+	e.pairs = make(map[string]*pairState)
+	for _, pair := range e.config.Redundancy.Pairs {
+		state := &pairState{pair: pair, primaryActive: true}
+		e.pairs[pair.Name] = state
+
+		if err := e.setPairRoles(state); err != nil {
+			e.logger.Warn(T("設定主備配對失敗"), zap.String("pair", pair.Name), zap.Error(err))
+		}
+	}
+
+	if len(e.pairs) > 0 {
+		e.logger.Info(T("主備配對已建立"),
+			zap.Int("pairs", len(e.pairs)),
+			zap.String("standby_mode", e.config.Redundancy.StandbyMode),
+		)
+	}
+}
+
+// setPairRoles 依配對狀態設定兩端角色 (先降級再升級，模擬切換空窗)；
+// 尚未啟動的一端 (逐步上線或等待綁定重試) 由 applyPairRole 於加入引擎時設定 (呼叫端需持有 e.pairsMu)
+func (e *Engine) setPairRoles(state *pairState) error {
+	mode := e.config.Redundancy.StandbyMode
+
+	if slave, ok := e.GetSlave(net.ParseIP(state.standby())); ok {
+		if err := slave.SetStandby(true, mode); err != nil {
+			return err
+		}
+	}
+	if slave, ok := e.GetSlave(net.ParseIP(state.active())); ok {
+		if err := slave.SetStandby(false, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyPairRole 依所屬配對目前的狀態設定晚於 initPairs 加入引擎的 Slave 的角色
+func (e *Engine) applyPairRole(slave *Slave) {
+	e.pairsMu.Lock()
+	defer e.pairsMu.Unlock()
+
+	for name, state := range e.pairs {
+		var standby bool
+		switch {
+		case net.ParseIP(state.active()).Equal(slave.IP):
+		case net.ParseIP(state.standby()).Equal(slave.IP):
+			standby = true
+		default:
+			continue
+		}
+		if err := slave.SetStandby(standby, e.config.Redundancy.StandbyMode); err != nil {
+			e.logger.Warn(T("設定主備配對失敗"), zap.String("pair", name), zap.Error(err))
+		}
+		return
+	}
+}
+
+// Failover 切換指定配對的主備角色
+func (e *Engine) Failover(name string) (PairStatus, error) {
+	e.pairsMu.Lock()
+	defer e.pairsMu.Unlock()
+
+	state, ok := e.pairs[name]
+	if !ok {
+		return PairStatus{}, fmt.Errorf(T("找不到主備配對: %s"), name)
+	}
+
+	state.primaryActive = !state.primaryActive
+	if err := e.setPairRoles(state); err != nil {
+		state.primaryActive = !state.primaryActive
+		return PairStatus{}, fmt.Errorf(T("切換配對 %s 失敗: %w"), name, err)
+	}
+	state.failovers++
+	state.lastFailover = time.Now()
+	state.suppressed = false
+
+	e.logger.Info(T("主備切換"),
+		zap.String("pair", name),
+		zap.String("active", state.active()),
+		zap.String("standby", state.standby()),
+	)
+
+	return state.status(), nil
+}
+
+// ListPairs 列出所有主備配對狀態
+func (e *Engine) ListPairs() []PairStatus {
+	e.pairsMu.Lock()
+	defer e.pairsMu.Unlock()
+
+	result := make([]PairStatus, 0, len(e.pairs))
+	for _, state := range e.pairs {
+		result = append(result, state.status())
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// totalFailovers 所有配對的切換次數總和
+func (e *Engine) totalFailovers() uint64 {
+	e.pairsMu.Lock()
+	defer e.pairsMu.Unlock()
+
+	var total uint64
+	for _, state := range e.pairs {
+		total += state.failovers
+	}
+	return total
+}
+
+func (p *pairState) status() PairStatus {
+	return PairStatus{
+		Name:         p.pair.Name,
+		Active:       p.active(),
+		Standby:      p.standby(),
+		Failovers:    p.failovers,
+		LastFailover: p.lastFailover,
+	}
+}
+
+// runFailoverScheduler 依 failover_interval 定期自動切換
+func (e *Engine) runFailoverScheduler(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			var due []string
+			e.pairsMu.Lock()
+			for name, state := range e.pairs {
+				interval := state.pair.FailoverInterval
+				if interval <= 0 {
+					continue
+				}
+				last := state.lastFailover
+				if last.IsZero() {
+					last = e.stats.StartTime
+				}
+				if now.Sub(last) < interval {
+					continue
+				}
+				// 任一端受保護時不自動切換
+				reason := e.protectionReason(net.ParseIP(state.pair.Primary), now)
+				if reason == "" {
+					reason = e.protectionReason(net.ParseIP(state.pair.Standby), now)
+				}
+				if reason != "" {
+					if !state.suppressed {
+						state.suppressed = true
+						e.suppressedCount.Add(1)
+						e.logger.Warn(T("已抑制自動主備切換"),
+							zap.String("audit", "fault_suppressed"),
+							zap.String("pair", name),
+							zap.String("reason", reason),
+						)
+					}
+					continue
+				}
+				due = append(due, name)
+			}
+			e.pairsMu.Unlock()
+
+			for _, name := range due {
+				if _, err := e.Failover(name); err != nil {
+					e.logger.Warn(T("自動主備切換失敗"), zap.String("pair", name), zap.Error(err))
+				}
+			}
+		}
+	}
+}
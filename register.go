@@ -2,8 +2,12 @@ package main
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"math"
+	"sort"
+	"strings"
 	"sync"
 )
 
@@ -11,14 +15,25 @@ import (
 type RegisterMap struct {
 	mu sync.RWMutex
 
-	// 暫存器資料
-	coils            []bool   // 0x - Coils
-	discreteInputs   []bool   // 1x - Discrete Inputs
-	inputRegisters   []uint16 // 3x - Input Registers
-	holdingRegisters []uint16 // 4x - Holding Registers
+	// 暫存器資料 (分頁配置，僅定義或寫入過的頁面佔用記憶體)
+	coils            pagedStore[bool]   // 0x - Coils
+	discreteInputs   pagedStore[bool]   // 1x - Discrete Inputs
+	inputRegisters   pagedStore[uint16] // 3x - Input Registers
+	holdingRegisters pagedStore[uint16] // 4x - Holding Registers
 
 	// 暫存器元資料
 	definitions map[uint16]*RegisterMeta
+	derived     []*derivedRegister // 依相依順序排列的衍生暫存器
+	sharedMeta  bool               // 元資料與 Clone 的來源或複本共用 (修改前先複製)
+
+	// 額定量測值 (場景波動的中心值，零值表示預設)
+	nominal MeterNominal
+
+	// 定義與各方法使用的位址慣例
+	addressing AddressMode
+
+	// 場景更新的亂數來源 (nil 表示場景共用的來源)
+	random *lockedRand
 }
 
 // RegisterMeta 暫存器元資料
@@ -31,15 +46,16 @@ type RegisterMeta struct {
 	Writable    bool
 	MinValue    float64
 	MaxValue    float64
+	Expression  string // 衍生值運算式 (空值表示一般暫存器)
 }
 
 // NewRegisterMap 建立新的暫存器映射表
 func NewRegisterMap(coilSize, discreteSize, inputSize, holdingSize int) *RegisterMap {
 	return &RegisterMap{
-		coils:            make([]bool, coilSize),
-		discreteInputs:   make([]bool, discreteSize),
-		inputRegisters:   make([]uint16, inputSize),
-		holdingRegisters: make([]uint16, holdingSize),
+		coils:            newPagedStore[bool](coilSize),
+		discreteInputs:   newPagedStore[bool](discreteSize),
+		inputRegisters:   newPagedStore[uint16](inputSize),
+		holdingRegisters: newPagedStore[uint16](holdingSize),
 		definitions:      make(map[uint16]*RegisterMeta),
 	}
 }
@@ -55,6 +71,7 @@ func DefaultRegisterMap() *RegisterMap {
 	rm.DefineRegister(40004, "TotalEnergy", DataTypeUint32, 1, "kWh", false)
 	rm.DefineRegister(40006, "PowerFactor", DataTypeUint16, 1000, "", false)
 	rm.DefineRegister(40007, "ActivePower", DataTypeUint32, 10, "W", false)
+	rm.SetExpression(40007, "40001 * 40002 * 40006")
 
 	// 設定預設值
 	rm.SetScaledValue(40001, 220.0)   // 220V
@@ -67,11 +84,118 @@ func DefaultRegisterMap() *RegisterMap {
 	return rm
 }
 
-// DefineRegister 定義暫存器
-func (rm *RegisterMap) DefineRegister(address uint16, name string, dataType DataType, scale float64, unit string, writable bool) {
+// Clone 建立 copy-on-write 複本：暫存器頁面與元資料與原本的映射表共用，
+// 任一方寫入某頁時才複製該頁、修改定義時才複製元資料 (同一設定檔的大量 Slave 共用一份基準)
+func (rm *RegisterMap) Clone() *RegisterMap {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.sharedMeta = true
+	return &RegisterMap{
+		coils:            rm.coils.Share(),
+		discreteInputs:   rm.discreteInputs.Share(),
+		inputRegisters:   rm.inputRegisters.Share(),
+		holdingRegisters: rm.holdingRegisters.Share(),
+		definitions:      rm.definitions,
+		derived:          rm.derived,
+		sharedMeta:       true,
+		nominal:          rm.nominal,
+		addressing:       rm.addressing,
+	}
+}
+
+// Addressing 位址慣例
+func (rm *RegisterMap) Addressing() AddressMode {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	return rm.addressing
+}
+
+// SetAddressing 設定位址慣例 (需在定義暫存器之前設定，已定義的位址不會換算)
+func (rm *RegisterMap) SetAddressing(mode AddressMode) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.addressing = mode
+}
+
+// PDUAddress PDU 位址 (Modbus 請求中 0 起算的位址) 在此映射表位址慣例下的位址
+func (rm *RegisterMap) PDUAddress(table RegisterType, pdu uint16) (uint16, bool) {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	return rm.addressing.address(table, int(pdu))
+}
+
+// Nominal 額定量測值 (未設定時為預設的 220V / 15.5A / 60Hz)
+func (rm *RegisterMap) Nominal() MeterNominal {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	if rm.nominal == (MeterNominal{}) {
+		return defaultNominal()
+	}
+	return rm.nominal
+}
+
+// Random 場景更新的亂數來源 (未設定時為 nil)
+func (rm *RegisterMap) Random() *lockedRand {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	return rm.random
+}
+
+// SetRandom 設定場景更新的亂數來源 (讓各 Slave 的量測值波動可重現)
+func (rm *RegisterMap) SetRandom(random *lockedRand) {
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 
+	rm.random = random
+}
+
+// SetNominal 設定額定量測值
+func (rm *RegisterMap) SetNominal(nominal MeterNominal) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.nominal = nominal
+}
+
+// ownMetaLocked 修改元資料前複製共用的定義與衍生暫存器 (呼叫端需持有寫鎖)
+func (rm *RegisterMap) ownMetaLocked() {
+	if !rm.sharedMeta {
+		return
+	}
+
+	definitions := make(map[uint16]*RegisterMeta, len(rm.definitions))
+	for address, meta := range rm.definitions {
+		copied := *meta
+		definitions[address] = &copied
+	}
+	rm.definitions = definitions
+	rm.derived = append([]*derivedRegister(nil), rm.derived...)
+	rm.sharedMeta = false
+}
+
+// DefineRegister 定義暫存器 (相同位址重新定義時取代)；位址超出映射表、scale 為 0 (浮點數與字串除外)
+// 或與其他已定義的暫存器重疊時拒絕
+func (rm *RegisterMap) DefineRegister(address uint16, name string, dataType DataType, scale float64, unit string, writable bool) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	count := dataType.RegisterCount()
+	if idx := rm.holdingIndex(address); idx < 0 || idx+count > rm.holdingRegisters.Len() {
+		return fmt.Errorf(T("暫存器 %s (%d): 位址超出範圍 (保持暫存器 %s)"),
+			name, address, rm.addressing.rangeText(RegisterTypeHoldingRegister, rm.holdingRegisters.Len()))
+	}
+	if scale == 0 && dataType.Scaled() {
+		return fmt.Errorf(T("暫存器 %s (%d): scale 不可為 0"), name, address)
+	}
+	if other := rm.overlappingLocked(address, count); other != nil {
+		return fmt.Errorf(T("暫存器 %s (%d) 與 %s (%d, 佔用 %d 個暫存器) 位址重疊"),
+			name, address, other.Name, other.Address, other.DataType.RegisterCount())
+	}
+
+	rm.ownMetaLocked()
 	rm.definitions[address] = &RegisterMeta{
 		Address:  address,
 		Name:     name,
@@ -80,6 +204,27 @@ func (rm *RegisterMap) DefineRegister(address uint16, name string, dataType Data
 		Unit:     unit,
 		Writable: writable,
 	}
+
+	// 已定義的暫存器所在頁面預先配置
+	idx := rm.holdingIndex(address)
+	rm.holdingRegisters.Reserve(idx, idx+count)
+	return nil
+}
+
+// overlappingLocked 與 [address, address+count) 重疊的其他已定義暫存器 (不含同位址的定義)
+func (rm *RegisterMap) overlappingLocked(address uint16, count int) *RegisterMeta {
+	// 前面位址的多暫存器類型延伸到 address
+	for i := 1; i < maxRegisterCount && i <= int(address); i++ {
+		if meta, ok := rm.definitions[address-uint16(i)]; ok && meta.DataType.RegisterCount() > i {
+			return meta
+		}
+	}
+	for i := 1; i < count; i++ {
+		if meta, ok := rm.definitions[address+uint16(i)]; ok {
+			return meta
+		}
+	}
+	return nil
 }
 
 // GetDefinition 取得暫存器定義
@@ -91,6 +236,19 @@ func (rm *RegisterMap) GetDefinition(address uint16) (*RegisterMeta, bool) {
 	return meta, ok
 }
 
+// Definitions 取得所有暫存器定義 (依位址排序)
+func (rm *RegisterMap) Definitions() []RegisterMeta {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	result := make([]RegisterMeta, 0, len(rm.definitions))
+	for _, meta := range rm.definitions {
+		result = append(result, *meta)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Address < result[j].Address })
+	return result
+}
+
 // --- Coils (0x) ---
 
 // ReadCoil 讀取單一線圈
@@ -98,10 +256,11 @@ func (rm *RegisterMap) ReadCoil(address uint16) (bool, error) {
 	rm.mu.RLock()
 	defer rm.mu.RUnlock()
 
-	if int(address) >= len(rm.coils) {
-		return false, fmt.Errorf("線圈位址超出範圍: %d", address)
+	idx := rm.index(RegisterTypeCoil, address)
+	if idx < 0 || idx >= rm.coils.Len() {
+		return false, fmt.Errorf(T("線圈位址超出範圍: %d"), address)
 	}
-	return rm.coils[address], nil
+	return rm.coils.At(idx), nil
 }
 
 // ReadCoils 讀取多個線圈
@@ -109,13 +268,14 @@ func (rm *RegisterMap) ReadCoils(address uint16, quantity uint16) ([]bool, error
 	rm.mu.RLock()
 	defer rm.mu.RUnlock()
 
-	end := int(address) + int(quantity)
-	if end > len(rm.coils) {
-		return nil, fmt.Errorf("線圈位址超出範圍: %d-%d", address, end-1)
+	idx := rm.index(RegisterTypeCoil, address)
+	end := idx + int(quantity)
+	if idx < 0 || end > rm.coils.Len() {
+		return nil, fmt.Errorf(T("線圈位址超出範圍: %d-%d"), address, int(address)+int(quantity)-1)
 	}
 
 	result := make([]bool, quantity)
-	copy(result, rm.coils[address:end])
+	rm.coils.Read(idx, result)
 	return result, nil
 }
 
@@ -124,10 +284,11 @@ func (rm *RegisterMap) WriteCoil(address uint16, value bool) error {
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 
-	if int(address) >= len(rm.coils) {
-		return fmt.Errorf("線圈位址超出範圍: %d", address)
+	idx := rm.index(RegisterTypeCoil, address)
+	if idx < 0 || idx >= rm.coils.Len() {
+		return fmt.Errorf(T("線圈位址超出範圍: %d"), address)
 	}
-	rm.coils[address] = value
+	rm.coils.Set(idx, value)
 	return nil
 }
 
@@ -136,12 +297,13 @@ func (rm *RegisterMap) WriteCoils(address uint16, values []bool) error {
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 
-	end := int(address) + len(values)
-	if end > len(rm.coils) {
-		return fmt.Errorf("線圈位址超出範圍: %d-%d", address, end-1)
+	idx := rm.index(RegisterTypeCoil, address)
+	end := idx + len(values)
+	if idx < 0 || end > rm.coils.Len() {
+		return fmt.Errorf(T("線圈位址超出範圍: %d-%d"), address, int(address)+len(values)-1)
 	}
 
-	copy(rm.coils[address:end], values)
+	rm.coils.Write(idx, values)
 	return nil
 }
 
@@ -152,10 +314,11 @@ func (rm *RegisterMap) ReadDiscreteInput(address uint16) (bool, error) {
 	rm.mu.RLock()
 	defer rm.mu.RUnlock()
 
-	if int(address) >= len(rm.discreteInputs) {
-		return false, fmt.Errorf("離散輸入位址超出範圍: %d", address)
+	idx := rm.index(RegisterTypeDiscreteInput, address)
+	if idx < 0 || idx >= rm.discreteInputs.Len() {
+		return false, fmt.Errorf(T("離散輸入位址超出範圍: %d"), address)
 	}
-	return rm.discreteInputs[address], nil
+	return rm.discreteInputs.At(idx), nil
 }
 
 // ReadDiscreteInputs 讀取多個離散輸入
@@ -163,13 +326,14 @@ func (rm *RegisterMap) ReadDiscreteInputs(address uint16, quantity uint16) ([]bo
 	rm.mu.RLock()
 	defer rm.mu.RUnlock()
 
-	end := int(address) + int(quantity)
-	if end > len(rm.discreteInputs) {
-		return nil, fmt.Errorf("離散輸入位址超出範圍: %d-%d", address, end-1)
+	idx := rm.index(RegisterTypeDiscreteInput, address)
+	end := idx + int(quantity)
+	if idx < 0 || end > rm.discreteInputs.Len() {
+		return nil, fmt.Errorf(T("離散輸入位址超出範圍: %d-%d"), address, int(address)+int(quantity)-1)
 	}
 
 	result := make([]bool, quantity)
-	copy(result, rm.discreteInputs[address:end])
+	rm.discreteInputs.Read(idx, result)
 	return result, nil
 }
 
@@ -178,13 +342,98 @@ func (rm *RegisterMap) SetDiscreteInput(address uint16, value bool) error {
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 
-	if int(address) >= len(rm.discreteInputs) {
-		return fmt.Errorf("離散輸入位址超出範圍: %d", address)
+	idx := rm.index(RegisterTypeDiscreteInput, address)
+	if idx < 0 || idx >= rm.discreteInputs.Len() {
+		return fmt.Errorf(T("離散輸入位址超出範圍: %d"), address)
+	}
+	rm.discreteInputs.Set(idx, value)
+	return nil
+}
+
+// --- 位元表批次操作 ---
+
+// ReadCoilBitmap 以位元表讀取 count 個線圈 (LSB 先，與 Modbus 線圈回應相同；count <= 0 時讀到結尾)
+func (rm *RegisterMap) ReadCoilBitmap(address uint16, count int) ([]byte, error) {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	start, end := bitSpan(rm.coils.Len(), rm.index(RegisterTypeCoil, address), count)
+	if start < 0 || start >= end || end > rm.coils.Len() {
+		return nil, fmt.Errorf(T("線圈位址超出範圍: %d-%d"), start, end-1)
+	}
+	return CoilsToByte(rm.coils.Slice(start, end)), nil
+}
+
+// WriteCoilBitmap 以位元表寫入 count 個線圈 (count <= 0 時為位元表的全部位元)
+func (rm *RegisterMap) WriteCoilBitmap(address uint16, bitmap []byte, count int) error {
+	count, err := bitmapCount(bitmap, count)
+	if err != nil {
+		return err
+	}
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	start, end := bitSpan(rm.coils.Len(), rm.index(RegisterTypeCoil, address), count)
+	if start < 0 || end > rm.coils.Len() {
+		return fmt.Errorf(T("線圈位址超出範圍: %d-%d"), start, end-1)
+	}
+	rm.coils.Write(start, ByteToCoils(bitmap, count))
+	return nil
+}
+
+// ReadDiscreteInputBitmap 以位元表讀取 count 個離散輸入 (count <= 0 時讀到結尾)
+func (rm *RegisterMap) ReadDiscreteInputBitmap(address uint16, count int) ([]byte, error) {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	start, end := bitSpan(rm.discreteInputs.Len(), rm.index(RegisterTypeDiscreteInput, address), count)
+	if start < 0 || start >= end || end > rm.discreteInputs.Len() {
+		return nil, fmt.Errorf(T("離散輸入位址超出範圍: %d-%d"), start, end-1)
+	}
+	return CoilsToByte(rm.discreteInputs.Slice(start, end)), nil
+}
+
+// SetDiscreteInputBitmap 以位元表設定 count 個離散輸入 (count <= 0 時為位元表的全部位元)
+func (rm *RegisterMap) SetDiscreteInputBitmap(address uint16, bitmap []byte, count int) error {
+	count, err := bitmapCount(bitmap, count)
+	if err != nil {
+		return err
+	}
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	start, end := bitSpan(rm.discreteInputs.Len(), rm.index(RegisterTypeDiscreteInput, address), count)
+	if start < 0 || end > rm.discreteInputs.Len() {
+		return fmt.Errorf(T("離散輸入位址超出範圍: %d-%d"), start, end-1)
 	}
-	rm.discreteInputs[address] = value
+	rm.discreteInputs.Write(start, ByteToCoils(bitmap, count))
 	return nil
 }
 
+// bitSpan 計算自索引 start 起 count 個的範圍 (count <= 0 時到結尾)
+func bitSpan(size int, start int, count int) (int, int) {
+	if count <= 0 {
+		return start, size
+	}
+	return start, start + count
+}
+
+// bitmapCount 決定要寫入的位元數並確認位元表長度足夠
+func bitmapCount(bitmap []byte, count int) (int, error) {
+	if count <= 0 {
+		count = len(bitmap) * 8
+	}
+	if count == 0 {
+		return 0, errors.New(T("位元表為空"))
+	}
+	if count > len(bitmap)*8 {
+		return 0, fmt.Errorf(T("位元表長度不足: %d 位元組無法容納 %d 個位元"), len(bitmap), count)
+	}
+	return count, nil
+}
+
 // --- Input Registers (3x) ---
 
 // ReadInputRegister 讀取單一輸入暫存器
@@ -192,10 +441,11 @@ func (rm *RegisterMap) ReadInputRegister(address uint16) (uint16, error) {
 	rm.mu.RLock()
 	defer rm.mu.RUnlock()
 
-	if int(address) >= len(rm.inputRegisters) {
-		return 0, fmt.Errorf("輸入暫存器位址超出範圍: %d", address)
+	idx := rm.index(RegisterTypeInputRegister, address)
+	if idx < 0 || idx >= rm.inputRegisters.Len() {
+		return 0, fmt.Errorf(T("輸入暫存器位址超出範圍: %d"), address)
 	}
-	return rm.inputRegisters[address], nil
+	return rm.inputRegisters.At(idx), nil
 }
 
 // ReadInputRegisters 讀取多個輸入暫存器
@@ -203,13 +453,14 @@ func (rm *RegisterMap) ReadInputRegisters(address uint16, quantity uint16) ([]ui
 	rm.mu.RLock()
 	defer rm.mu.RUnlock()
 
-	end := int(address) + int(quantity)
-	if end > len(rm.inputRegisters) {
-		return nil, fmt.Errorf("輸入暫存器位址超出範圍: %d-%d", address, end-1)
+	idx := rm.index(RegisterTypeInputRegister, address)
+	end := idx + int(quantity)
+	if idx < 0 || end > rm.inputRegisters.Len() {
+		return nil, fmt.Errorf(T("輸入暫存器位址超出範圍: %d-%d"), address, int(address)+int(quantity)-1)
 	}
 
 	result := make([]uint16, quantity)
-	copy(result, rm.inputRegisters[address:end])
+	rm.inputRegisters.Read(idx, result)
 	return result, nil
 }
 
@@ -218,10 +469,11 @@ func (rm *RegisterMap) SetInputRegister(address uint16, value uint16) error {
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 
-	if int(address) >= len(rm.inputRegisters) {
-		return fmt.Errorf("輸入暫存器位址超出範圍: %d", address)
+	idx := rm.index(RegisterTypeInputRegister, address)
+	if idx < 0 || idx >= rm.inputRegisters.Len() {
+		return fmt.Errorf(T("輸入暫存器位址超出範圍: %d"), address)
 	}
-	rm.inputRegisters[address] = value
+	rm.inputRegisters.Set(idx, value)
 	return nil
 }
 
@@ -233,10 +485,10 @@ func (rm *RegisterMap) ReadHoldingRegister(address uint16) (uint16, error) {
 	defer rm.mu.RUnlock()
 
 	idx := rm.holdingIndex(address)
-	if idx < 0 || idx >= len(rm.holdingRegisters) {
-		return 0, fmt.Errorf("保持暫存器位址超出範圍: %d", address)
+	if idx < 0 || idx >= rm.holdingRegisters.Len() {
+		return 0, fmt.Errorf(T("保持暫存器位址超出範圍: %d"), address)
 	}
-	return rm.holdingRegisters[idx], nil
+	return rm.holdingRegisters.At(idx), nil
 }
 
 // ReadHoldingRegisters 讀取多個保持暫存器
@@ -246,12 +498,12 @@ func (rm *RegisterMap) ReadHoldingRegisters(address uint16, quantity uint16) ([]
 
 	startIdx := rm.holdingIndex(address)
 	endIdx := startIdx + int(quantity)
-	if startIdx < 0 || endIdx > len(rm.holdingRegisters) {
-		return nil, fmt.Errorf("保持暫存器位址超出範圍: %d-%d", address, address+quantity-1)
+	if startIdx < 0 || endIdx > rm.holdingRegisters.Len() {
+		return nil, fmt.Errorf(T("保持暫存器位址超出範圍: %d-%d"), address, address+quantity-1)
 	}
 
 	result := make([]uint16, quantity)
-	copy(result, rm.holdingRegisters[startIdx:endIdx])
+	rm.holdingRegisters.Read(startIdx, result)
 	return result, nil
 }
 
@@ -261,10 +513,10 @@ func (rm *RegisterMap) WriteHoldingRegister(address uint16, value uint16) error
 	defer rm.mu.Unlock()
 
 	idx := rm.holdingIndex(address)
-	if idx < 0 || idx >= len(rm.holdingRegisters) {
-		return fmt.Errorf("保持暫存器位址超出範圍: %d", address)
+	if idx < 0 || idx >= rm.holdingRegisters.Len() {
+		return fmt.Errorf(T("保持暫存器位址超出範圍: %d"), address)
 	}
-	rm.holdingRegisters[idx] = value
+	rm.holdingRegisters.Set(idx, value)
 	return nil
 }
 
@@ -275,17 +527,27 @@ func (rm *RegisterMap) WriteHoldingRegisters(address uint16, values []uint16) er
 
 	startIdx := rm.holdingIndex(address)
 	endIdx := startIdx + len(values)
-	if startIdx < 0 || endIdx > len(rm.holdingRegisters) {
-		return fmt.Errorf("保持暫存器位址超出範圍: %d-%d", address, address+uint16(len(values))-1)
+	if startIdx < 0 || endIdx > rm.holdingRegisters.Len() {
+		return fmt.Errorf(T("保持暫存器位址超出範圍: %d-%d"), address, address+uint16(len(values))-1)
 	}
 
-	copy(rm.holdingRegisters[startIdx:endIdx], values)
+	rm.holdingRegisters.Write(startIdx, values)
 	return nil
 }
 
+// index 依位址慣例將位址轉換為表格索引 (PDU 位址)；不是該表格的位址時回傳 -1
+func (rm *RegisterMap) index(table RegisterType, address uint16) int {
+	return rm.addressing.index(table, address)
+}
+
 // holdingIndex 將 Modbus 位址轉換為陣列索引
 // 40001 -> 0, 40002 -> 1, etc.
 func (rm *RegisterMap) holdingIndex(address uint16) int {
+	return rm.index(RegisterTypeHoldingRegister, address)
+}
+
+// holdingRegisterIndex 保持暫存器位址 (0 起算或 40001 起算) 對應的索引
+func holdingRegisterIndex(address uint16) int {
 	if address >= 40001 {
 		return int(address - 40001)
 	}
@@ -303,55 +565,75 @@ func (rm *RegisterMap) SetScaledValue(address uint16, value float64) error {
 	if !ok {
 		// 沒有定義，直接寫入 uint16
 		idx := rm.holdingIndex(address)
-		if idx < 0 || idx >= len(rm.holdingRegisters) {
-			return fmt.Errorf("保持暫存器位址超出範圍: %d", address)
+		if idx < 0 || idx >= rm.holdingRegisters.Len() {
+			return fmt.Errorf(T("保持暫存器位址超出範圍: %d"), address)
 		}
-		rm.holdingRegisters[idx] = uint16(value)
+		rm.holdingRegisters.Set(idx, uint16(value))
 		return nil
 	}
 
 	scaledValue := value * meta.Scale
 	idx := rm.holdingIndex(address)
 	if idx < 0 {
-		return fmt.Errorf("無效位址: %d", address)
+		return fmt.Errorf(T("無效位址: %d"), address)
 	}
 
 	switch meta.DataType {
 	case DataTypeUint16:
-		if idx >= len(rm.holdingRegisters) {
-			return fmt.Errorf("保持暫存器位址超出範圍: %d", address)
+		if idx >= rm.holdingRegisters.Len() {
+			return fmt.Errorf(T("保持暫存器位址超出範圍: %d"), address)
 		}
-		rm.holdingRegisters[idx] = uint16(scaledValue)
+		rm.holdingRegisters.Set(idx, uint16(scaledValue))
 
 	case DataTypeInt16:
-		if idx >= len(rm.holdingRegisters) {
-			return fmt.Errorf("保持暫存器位址超出範圍: %d", address)
+		if idx >= rm.holdingRegisters.Len() {
+			return fmt.Errorf(T("保持暫存器位址超出範圍: %d"), address)
 		}
-		rm.holdingRegisters[idx] = uint16(int16(scaledValue))
+		rm.holdingRegisters.Set(idx, uint16(int16(scaledValue)))
 
 	case DataTypeUint32:
-		if idx+1 >= len(rm.holdingRegisters) {
-			return fmt.Errorf("保持暫存器位址超出範圍: %d", address)
+		if idx+1 >= rm.holdingRegisters.Len() {
+			return fmt.Errorf(T("保持暫存器位址超出範圍: %d"), address)
 		}
 		u32 := uint32(scaledValue)
-		rm.holdingRegisters[idx] = uint16(u32 >> 16)   // High word
-		rm.holdingRegisters[idx+1] = uint16(u32)       // Low word
+		rm.holdingRegisters.Set(idx, uint16(u32 >> 16))   // High word
+		rm.holdingRegisters.Set(idx+1, uint16(u32))       // Low word
 
 	case DataTypeInt32:
-		if idx+1 >= len(rm.holdingRegisters) {
-			return fmt.Errorf("保持暫存器位址超出範圍: %d", address)
+		if idx+1 >= rm.holdingRegisters.Len() {
+			return fmt.Errorf(T("保持暫存器位址超出範圍: %d"), address)
 		}
 		i32 := int32(scaledValue)
-		rm.holdingRegisters[idx] = uint16(i32 >> 16)   // High word
-		rm.holdingRegisters[idx+1] = uint16(i32)       // Low word
+		rm.holdingRegisters.Set(idx, uint16(i32 >> 16))   // High word
+		rm.holdingRegisters.Set(idx+1, uint16(i32))       // Low word
 
 	case DataTypeFloat32:
-		if idx+1 >= len(rm.holdingRegisters) {
-			return fmt.Errorf("保持暫存器位址超出範圍: %d", address)
+		if idx+1 >= rm.holdingRegisters.Len() {
+			return fmt.Errorf(T("保持暫存器位址超出範圍: %d"), address)
 		}
 		bits := math.Float32bits(float32(value)) // 注意：Float32 不縮放
-		rm.holdingRegisters[idx] = uint16(bits >> 16)   // High word
-		rm.holdingRegisters[idx+1] = uint16(bits)       // Low word
+		rm.holdingRegisters.Set(idx, uint16(bits >> 16))   // High word
+		rm.holdingRegisters.Set(idx+1, uint16(bits))       // Low word
+
+	case DataTypeUint64, DataTypeInt64, DataTypeFloat64:
+		if idx+3 >= rm.holdingRegisters.Len() {
+			return fmt.Errorf(T("保持暫存器位址超出範圍: %d"), address)
+		}
+		var u64 uint64
+		switch meta.DataType {
+		case DataTypeUint64:
+			u64 = uint64(scaledValue)
+		case DataTypeInt64:
+			u64 = uint64(int64(scaledValue))
+		default:
+			u64 = math.Float64bits(value) // Float64 不縮放
+		}
+		for i := 0; i < 4; i++ {
+			rm.holdingRegisters.Set(idx+i, uint16(u64 >> (48 - 16*i))) // 高位字組在前
+		}
+
+	default:
+		return fmt.Errorf(T("暫存器 %s (%d) 為 %s，不是數值"), meta.Name, address, meta.DataType)
 	}
 
 	return nil
@@ -366,67 +648,138 @@ func (rm *RegisterMap) GetScaledValue(address uint16) (float64, error) {
 	if !ok {
 		// 沒有定義，直接讀取 uint16
 		idx := rm.holdingIndex(address)
-		if idx < 0 || idx >= len(rm.holdingRegisters) {
-			return 0, fmt.Errorf("保持暫存器位址超出範圍: %d", address)
+		if idx < 0 || idx >= rm.holdingRegisters.Len() {
+			return 0, fmt.Errorf(T("保持暫存器位址超出範圍: %d"), address)
 		}
-		return float64(rm.holdingRegisters[idx]), nil
+		return float64(rm.holdingRegisters.At(idx)), nil
 	}
 
 	idx := rm.holdingIndex(address)
 	if idx < 0 {
-		return 0, fmt.Errorf("無效位址: %d", address)
+		return 0, fmt.Errorf(T("無效位址: %d"), address)
 	}
 
 	var rawValue float64
 
 	switch meta.DataType {
 	case DataTypeUint16:
-		if idx >= len(rm.holdingRegisters) {
-			return 0, fmt.Errorf("保持暫存器位址超出範圍: %d", address)
+		if idx >= rm.holdingRegisters.Len() {
+			return 0, fmt.Errorf(T("保持暫存器位址超出範圍: %d"), address)
 		}
-		rawValue = float64(rm.holdingRegisters[idx])
+		rawValue = float64(rm.holdingRegisters.At(idx))
 
 	case DataTypeInt16:
-		if idx >= len(rm.holdingRegisters) {
-			return 0, fmt.Errorf("保持暫存器位址超出範圍: %d", address)
+		if idx >= rm.holdingRegisters.Len() {
+			return 0, fmt.Errorf(T("保持暫存器位址超出範圍: %d"), address)
 		}
-		rawValue = float64(int16(rm.holdingRegisters[idx]))
+		rawValue = float64(int16(rm.holdingRegisters.At(idx)))
 
 	case DataTypeUint32:
-		if idx+1 >= len(rm.holdingRegisters) {
-			return 0, fmt.Errorf("保持暫存器位址超出範圍: %d", address)
+		if idx+1 >= rm.holdingRegisters.Len() {
+			return 0, fmt.Errorf(T("保持暫存器位址超出範圍: %d"), address)
 		}
-		u32 := uint32(rm.holdingRegisters[idx])<<16 | uint32(rm.holdingRegisters[idx+1])
+		u32 := uint32(rm.holdingRegisters.At(idx))<<16 | uint32(rm.holdingRegisters.At(idx+1))
 		rawValue = float64(u32)
 
 	case DataTypeInt32:
-		if idx+1 >= len(rm.holdingRegisters) {
-			return 0, fmt.Errorf("保持暫存器位址超出範圍: %d", address)
+		if idx+1 >= rm.holdingRegisters.Len() {
+			return 0, fmt.Errorf(T("保持暫存器位址超出範圍: %d"), address)
 		}
-		i32 := int32(uint32(rm.holdingRegisters[idx])<<16 | uint32(rm.holdingRegisters[idx+1]))
+		i32 := int32(uint32(rm.holdingRegisters.At(idx))<<16 | uint32(rm.holdingRegisters.At(idx+1)))
 		rawValue = float64(i32)
 
 	case DataTypeFloat32:
-		if idx+1 >= len(rm.holdingRegisters) {
-			return 0, fmt.Errorf("保持暫存器位址超出範圍: %d", address)
+		if idx+1 >= rm.holdingRegisters.Len() {
+			return 0, fmt.Errorf(T("保持暫存器位址超出範圍: %d"), address)
 		}
-		bits := uint32(rm.holdingRegisters[idx])<<16 | uint32(rm.holdingRegisters[idx+1])
+		bits := uint32(rm.holdingRegisters.At(idx))<<16 | uint32(rm.holdingRegisters.At(idx+1))
 		return float64(math.Float32frombits(bits)), nil // Float32 不縮放
+
+	case DataTypeUint64, DataTypeInt64, DataTypeFloat64:
+		if idx+3 >= rm.holdingRegisters.Len() {
+			return 0, fmt.Errorf(T("保持暫存器位址超出範圍: %d"), address)
+		}
+		var u64 uint64
+		for i := 0; i < 4; i++ {
+			u64 = u64<<16 | uint64(rm.holdingRegisters.At(idx+i))
+		}
+		switch meta.DataType {
+		case DataTypeUint64:
+			rawValue = float64(u64)
+		case DataTypeInt64:
+			rawValue = float64(int64(u64))
+		default:
+			return math.Float64frombits(u64), nil // Float64 不縮放
+		}
+
+	default:
+		return 0, fmt.Errorf(T("暫存器 %s (%d) 為 %s，不是數值"), meta.Name, address, meta.DataType)
 	}
 
 	return rawValue / meta.Scale, nil
 }
 
+// SetString 設定字串暫存器的內容 (每個暫存器 2 個 ASCII 字元，高位元組在前；不足補 0，超過定義長度時拒絕)
+func (rm *RegisterMap) SetString(address uint16, value string) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	meta, ok := rm.definitions[address]
+	if !ok || !meta.DataType.IsString() {
+		return fmt.Errorf(T("暫存器 %d 不是字串類型"), address)
+	}
+	if err := checkStringValue(meta.Name, address, meta.DataType, value); err != nil {
+		return err
+	}
+
+	buf := make([]byte, meta.DataType.RegisterCount()*2)
+	copy(buf, value)
+	idx := rm.holdingIndex(address)
+	for i := 0; i < len(buf); i += 2 {
+		rm.holdingRegisters.Set(idx+i/2, binary.BigEndian.Uint16(buf[i:]))
+	}
+	return nil
+}
+
+// checkStringValue 檢查字串是否符合字串類型的長度且僅含 ASCII 字元
+func checkStringValue(name string, address uint16, dataType DataType, value string) error {
+	if length := dataType.StringLength(); len(value) > length {
+		return fmt.Errorf(T("暫存器 %s (%d): 字串長度 %d 超過 %d"), name, address, len(value), length)
+	}
+	for i := 0; i < len(value); i++ {
+		if value[i] >= 0x80 {
+			return fmt.Errorf(T("暫存器 %s (%d): 字串只能包含 ASCII 字元"), name, address)
+		}
+	}
+	return nil
+}
+
+// GetString 取得字串暫存器的內容 (去除結尾補的 0)
+func (rm *RegisterMap) GetString(address uint16) (string, error) {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	meta, ok := rm.definitions[address]
+	if !ok || !meta.DataType.IsString() {
+		return "", fmt.Errorf(T("暫存器 %d 不是字串類型"), address)
+	}
+
+	buf := make([]byte, meta.DataType.RegisterCount()*2)
+	idx := rm.holdingIndex(address)
+	for i := 0; i < len(buf); i += 2 {
+		binary.BigEndian.PutUint16(buf[i:], rm.holdingRegisters.At(idx+i/2))
+	}
+	return strings.TrimRight(string(buf[:meta.DataType.StringLength()]), "\x00"), nil
+}
+
 // --- 批量操作 ---
 
-// GetRawHoldingRegisters 直接取得保持暫存器陣列 (供 mbserver 使用)
+// GetRawHoldingRegisters 直接取得保持暫存器陣列
 func (rm *RegisterMap) GetRawHoldingRegisters() []uint16 {
 	rm.mu.RLock()
 	defer rm.mu.RUnlock()
 
-	result := make([]uint16, len(rm.holdingRegisters))
-	copy(result, rm.holdingRegisters)
-	return result
+	return rm.holdingRegisters.Slice(0, rm.holdingRegisters.Len())
 }
 
 // GetRawInputRegisters 直接取得輸入暫存器陣列
@@ -434,9 +787,7 @@ func (rm *RegisterMap) GetRawInputRegisters() []uint16 {
 	rm.mu.RLock()
 	defer rm.mu.RUnlock()
 
-	result := make([]uint16, len(rm.inputRegisters))
-	copy(result, rm.inputRegisters)
-	return result
+	return rm.inputRegisters.Slice(0, rm.inputRegisters.Len())
 }
 
 // GetRawCoils 直接取得線圈陣列
@@ -444,9 +795,7 @@ func (rm *RegisterMap) GetRawCoils() []bool {
 	rm.mu.RLock()
 	defer rm.mu.RUnlock()
 
-	result := make([]bool, len(rm.coils))
-	copy(result, rm.coils)
-	return result
+	return rm.coils.Slice(0, rm.coils.Len())
 }
 
 // GetRawDiscreteInputs 直接取得離散輸入陣列
@@ -454,9 +803,48 @@ func (rm *RegisterMap) GetRawDiscreteInputs() []bool {
 	rm.mu.RLock()
 	defer rm.mu.RUnlock()
 
-	result := make([]bool, len(rm.discreteInputs))
-	copy(result, rm.discreteInputs)
-	return result
+	return rm.discreteInputs.Slice(0, rm.discreteInputs.Len())
+}
+
+// SyncRawTo 將上次同步後寫入過的暫存器頁面複製到呼叫端持有的儲存區
+// (首次同步時完整複製，已配置的頁面沿用原本的記憶體)；每個映射表只應同步到同一組儲存區
+func (rm *RegisterMap) SyncRawTo(holding, input *pagedStore[uint16], coils, discretes *pagedStore[bool]) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.holdingRegisters.SyncTo(holding)
+	rm.inputRegisters.SyncTo(input)
+	rm.coils.SyncTo(coils)
+	rm.discreteInputs.SyncTo(discretes)
+}
+
+// AllocatedPages 已配置的暫存器頁面數 (四種暫存器合計)
+func (rm *RegisterMap) AllocatedPages() int {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	return rm.holdingRegisters.Pages() + rm.inputRegisters.Pages() + rm.coils.Pages() + rm.discreteInputs.Pages()
+}
+
+// SharedPages 與其他映射表共用 (尚未寫入而複製) 的頁面數
+func (rm *RegisterMap) SharedPages() int {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	return rm.holdingRegisters.SharedPages() + rm.inputRegisters.SharedPages() + rm.coils.SharedPages() + rm.discreteInputs.SharedPages()
+}
+
+// Checksum 計算所有暫存器內容 (Holding、Input、Coils、Discrete Inputs) 的 FNV-1a 64 雜湊
+func (rm *RegisterMap) Checksum() uint64 {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	h := fnv.New64a()
+	h.Write(RegistersToBytes(rm.holdingRegisters.Slice(0, rm.holdingRegisters.Len())))
+	h.Write(RegistersToBytes(rm.inputRegisters.Slice(0, rm.inputRegisters.Len())))
+	h.Write(CoilsToByte(rm.coils.Slice(0, rm.coils.Len())))
+	h.Write(CoilsToByte(rm.discreteInputs.Slice(0, rm.discreteInputs.Len())))
+	return h.Sum64()
 }
 
 // ToBytes 將暫存器值轉換為位元組陣列 (Big Endian)
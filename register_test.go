@@ -1,6 +1,9 @@
 package main
 
 import (
+	"bytes"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -52,6 +55,253 @@ func TestRegisterMap_Uint32Register(t *testing.T) {
 	assert.InDelta(t, 123456.0, energy, 1.0, "能量應為 123456 kWh")
 }
 
+func TestAddressMode(t *testing.T) {
+	tests := []struct {
+		mode    AddressMode
+		table   RegisterType
+		address uint16
+		index   int
+	}{
+		{AddressModeAuto, RegisterTypeHoldingRegister, 40001, 0},
+		{AddressModeAuto, RegisterTypeHoldingRegister, 5, 5},
+		{AddressModeAuto, RegisterTypeCoil, 40001, 40001},
+		{AddressModeProtocol, RegisterTypeHoldingRegister, 40001, 40001},
+		{AddressModePLC, RegisterTypeInputRegister, 1, 0},
+		{AddressModePLC, RegisterTypeCoil, 0, -1},
+		{AddressModeModicon, RegisterTypeCoil, 1, 0},
+		{AddressModeModicon, RegisterTypeDiscreteInput, 10001, 0},
+		{AddressModeModicon, RegisterTypeInputRegister, 30010, 9},
+		{AddressModeModicon, RegisterTypeHoldingRegister, 40001, 0},
+		{AddressModeModicon, RegisterTypeInputRegister, 40001, -1},
+		{AddressModeModicon, RegisterTypeHoldingRegister, 1, -1},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.index, tt.mode.index(tt.table, tt.address), "%s %s %d", tt.mode, tt.table, tt.address)
+		if tt.index >= 0 && !(tt.mode == AddressModeAuto && tt.address < 40001 && tt.table == RegisterTypeHoldingRegister) {
+			address, ok := tt.mode.address(tt.table, tt.index)
+			assert.True(t, ok)
+			assert.Equal(t, tt.address, address, "%s %s 索引 %d", tt.mode, tt.table, tt.index)
+		}
+	}
+
+	_, err := ParseAddressMode("one_based")
+	assert.Error(t, err)
+	mode, err := ParseAddressMode("auto")
+	require.NoError(t, err)
+	assert.Equal(t, AddressModeAuto, mode)
+}
+
+func TestRegisterMap_PLCAddressing(t *testing.T) {
+	rm, err := NewRegisterMapFromDefinitions([]RegisterDefinition{
+		{Address: 1, Name: "Voltage", DataType: "uint16", Scale: 10, DefaultValue: 230},
+		{Address: 2, Name: "Energy", DataType: "uint32", Scale: 1, DefaultValue: 70000},
+	}, AddressModePLC)
+	require.NoError(t, err)
+
+	// 位址 1 即 PDU 位址 0，四種表格一致
+	words, err := rm.ReadHoldingRegisters(1, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{2300, 1, 4464}, words)
+	_, err = rm.ReadHoldingRegisters(0, 1)
+	assert.Error(t, err)
+	require.NoError(t, rm.WriteCoil(1, true))
+	coils, err := rm.ReadCoils(1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true}, coils)
+	assert.True(t, rm.GetRawCoils()[0])
+
+	address, ok := rm.PDUAddress(RegisterTypeHoldingRegister, 0)
+	require.True(t, ok)
+	assert.Equal(t, uint16(1), address)
+	assert.Error(t, rm.DefineRegister(40001, "Legacy", DataTypeUint16, 1, "", false), "PLC 慣例沒有 4xxxx 換算")
+
+	// 複本沿用位址慣例
+	assert.Equal(t, AddressModePLC, rm.Clone().Addressing())
+}
+
+func TestRegisterMap_64BitRegisters(t *testing.T) {
+	rm := NewRegisterMap(100, 100, 100, 100)
+	require.NoError(t, rm.DefineRegister(40001, "ImportEnergy", DataTypeUint64, 1000, "Wh", false))
+	require.NoError(t, rm.DefineRegister(40005, "NetEnergy", DataTypeInt64, 1, "Wh", false))
+	require.NoError(t, rm.DefineRegister(40009, "Frequency", DataTypeFloat64, 0, "Hz", false))
+	assert.Error(t, rm.DefineRegister(40004, "Overlap", DataTypeUint16, 1, "", false), "uint64 佔用 40001-40004")
+
+	// 高位字組在前的 4 個暫存器
+	require.NoError(t, rm.SetScaledValue(40001, 0x0001_0002_0003_0004/1000.0))
+	words, err := rm.ReadHoldingRegisters(40001, 4)
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{0x0001, 0x0002, 0x0003, 0x0004}, words)
+	value, err := rm.GetScaledValue(40001)
+	require.NoError(t, err)
+	assert.InDelta(t, 0x0001_0002_0003_0004/1000.0, value, 0.001)
+
+	require.NoError(t, rm.SetScaledValue(40005, -5))
+	value, err = rm.GetScaledValue(40005)
+	require.NoError(t, err)
+	assert.Equal(t, -5.0, value)
+
+	require.NoError(t, rm.SetScaledValue(40009, 59.987654321))
+	value, err = rm.GetScaledValue(40009)
+	require.NoError(t, err)
+	assert.Equal(t, 59.987654321, value)
+}
+
+func TestRegisterMap_StringRegister(t *testing.T) {
+	dataType, err := ParseDataType("string(5)")
+	require.NoError(t, err)
+	assert.Equal(t, 3, dataType.RegisterCount())
+	assert.Equal(t, "string(5)", dataType.String())
+	_, err = ParseDataType("string(0)")
+	assert.Error(t, err)
+
+	rm := NewRegisterMap(100, 100, 100, 100)
+	require.NoError(t, rm.DefineRegister(40001, "Model", dataType, 0, "", false))
+	assert.Error(t, rm.DefineRegister(40003, "Overlap", DataTypeUint16, 1, "", false))
+
+	require.NoError(t, rm.SetString(40001, "PM21"))
+	words, err := rm.ReadHoldingRegisters(40001, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{0x504D, 0x3231, 0x0000}, words)
+	text, err := rm.GetString(40001)
+	require.NoError(t, err)
+	assert.Equal(t, "PM21", text)
+
+	assert.Error(t, rm.SetString(40001, "PM2100"), "超過定義長度")
+	assert.Error(t, rm.SetString(40001, "電表"), "非 ASCII")
+	_, err = rm.GetScaledValue(40001)
+	assert.Error(t, err, "字串沒有數值")
+}
+
+func TestDataType_RawRange(t *testing.T) {
+	min, max, ok := DataTypeInt16.RawRange()
+	assert.True(t, ok)
+	assert.Equal(t, float64(-32768), min)
+	assert.Equal(t, float64(32767), max)
+
+	_, max, ok = DataTypeUint32.RawRange()
+	assert.True(t, ok)
+	assert.Equal(t, float64(4294967295), max)
+
+	_, _, ok = DataTypeFloat32.RawRange()
+	assert.False(t, ok, "浮點數不限制範圍")
+	_, _, ok = DataTypeString(8).RawRange()
+	assert.False(t, ok)
+}
+
+func TestRegisterMap_Definitions(t *testing.T) {
+	rm := DefaultRegisterMap()
+
+	defs := rm.Definitions()
+	require.NotEmpty(t, defs)
+
+	// 依位址排序
+	for i := 1; i < len(defs); i++ {
+		assert.Less(t, defs[i-1].Address, defs[i].Address)
+	}
+	assert.Equal(t, "LineVoltage", defs[0].Name)
+	assert.Equal(t, "V", defs[0].Unit)
+}
+
+func TestRegisterMap_DefineRegisterValidation(t *testing.T) {
+	rm := DefaultRegisterMap()
+
+	// 40004 的 uint32 佔用 40004-40005
+	assert.Error(t, rm.DefineRegister(40005, "Overlap", DataTypeUint16, 1, "", false))
+	assert.Error(t, rm.DefineRegister(40003, "Overlap", DataTypeUint32, 1, "", false), "延伸到下一個定義")
+	assert.Error(t, rm.DefineRegister(50000, "Tail", DataTypeUint32, 1, "", false), "超出映射表")
+	assert.Error(t, rm.DefineRegister(20000, "Gap", DataTypeUint16, 1, "", false))
+	assert.Error(t, rm.DefineRegister(40020, "Zero", DataTypeUint16, 0, "", false))
+	assert.NoError(t, rm.DefineRegister(40020, "Ratio", DataTypeFloat32, 0, "", false), "float32 不使用 scale")
+
+	// 相同位址重新定義時取代
+	require.NoError(t, rm.DefineRegister(40001, "PhaseVoltage", DataTypeUint16, 100, "V", false))
+	meta, ok := rm.GetDefinition(40001)
+	require.True(t, ok)
+	assert.Equal(t, "PhaseVoltage", meta.Name)
+	assert.NoError(t, rm.DefineRegister(50000, "Last", DataTypeUint16, 1, "", false))
+}
+
+func TestRegisterMap_Checksum(t *testing.T) {
+	a := DefaultRegisterMap()
+	b := DefaultRegisterMap()
+	assert.Equal(t, a.Checksum(), b.Checksum(), "相同內容應有相同雜湊")
+
+	require.NoError(t, b.WriteCoil(0, true))
+	assert.NotEqual(t, a.Checksum(), b.Checksum(), "線圈變動應改變雜湊")
+
+	require.NoError(t, a.WriteCoil(0, true))
+	require.NoError(t, a.WriteHoldingRegister(40100, 1))
+	assert.NotEqual(t, a.Checksum(), b.Checksum(), "保持暫存器變動應改變雜湊")
+}
+
+func TestParseExpression(t *testing.T) {
+	values := map[string]float64{"40001": 220, "40002": 15.5, "LineVoltage": 230}
+	lookup := func(ref string) (float64, error) { return values[ref], nil }
+
+	tests := []struct {
+		source string
+		want   float64
+		refs   []string
+	}{
+		{"40007 = 40001 * 40002", 3410, []string{"40001", "40002"}},
+		{"(40001 + 10) / 2", 115, []string{"40001"}},
+		{"-LineVoltage + 2 * 3", -224, []string{"LineVoltage"}},
+		{"40001.0 / 1000", 40.001, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			expr, err := ParseExpression(tt.source)
+			require.NoError(t, err)
+			assert.Equal(t, tt.refs, expr.Refs())
+
+			got, err := expr.Eval(lookup)
+			require.NoError(t, err)
+			assert.InDelta(t, tt.want, got, 1e-9)
+		})
+	}
+
+	for _, bad := range []string{"", "40001 *", "(40001", "40001 $ 2", "LineVoltage = 40001"} {
+		_, err := ParseExpression(bad)
+		assert.Error(t, err, bad)
+	}
+
+	expr, err := ParseExpression("40001 / (40002 - 15.5)")
+	require.NoError(t, err)
+	_, err = expr.Eval(lookup)
+	assert.Error(t, err, "除以零應回傳錯誤")
+}
+
+func TestRegisterMap_EvaluateExpressions(t *testing.T) {
+	rm, err := NewRegisterMapFromDefinitions([]RegisterDefinition{
+		{Address: 40001, Name: "LineVoltage", DataType: "uint16", Scale: 10, DefaultValue: 220.0, Unit: "V"},
+		{Address: 40002, Name: "LineCurrent", DataType: "uint16", Scale: 100, DefaultValue: 10.0, Unit: "A"},
+		{Address: 40006, Name: "PowerFactor", DataType: "uint16", Scale: 1000, DefaultValue: 0.9},
+		// 參照另一個衍生暫存器，需依相依順序計算
+		{Address: 40010, Name: "ActivePowerKW", DataType: "uint16", Scale: 100, Unit: "kW", Expression: "ActivePower / 1000"},
+		{Address: 40007, Name: "ActivePower", DataType: "uint32", Scale: 10, Unit: "W", Expression: "40007 = 40001 * 40002 * 40006"},
+	}, AddressModeAuto)
+	require.NoError(t, err)
+
+	require.NoError(t, rm.EvaluateExpressions())
+	power, _ := rm.GetScaledValue(40007)
+	assert.InDelta(t, 1980.0, power, 0.1)
+	kw, _ := rm.GetScaledValue(40010)
+	assert.InDelta(t, 1.98, kw, 0.01)
+
+	// 輸入變動後衍生值跟著更新
+	require.NoError(t, rm.SetScaledValue(40002, 20.0))
+	require.NoError(t, rm.EvaluateExpressions())
+	power, _ = rm.GetScaledValue(40007)
+	assert.InDelta(t, 3960.0, power, 0.1)
+
+	meta, ok := rm.GetDefinition(40007)
+	require.True(t, ok)
+	assert.Equal(t, "40007 = 40001 * 40002 * 40006", meta.Expression)
+
+	assert.Error(t, rm.SetExpression(40001, "40010 + 1"), "循環參照應被拒絕")
+	assert.Error(t, rm.SetExpression(40002, "40003 = 40001"), "左側位址不符應被拒絕")
+}
+
 func TestRegisterMap_HoldingRegisters(t *testing.T) {
 	rm := NewRegisterMap(100, 100, 100, 100)
 
@@ -111,6 +361,41 @@ func TestRegisterMap_DiscreteInputs(t *testing.T) {
 	assert.True(t, val)
 }
 
+func TestRegisterMap_Bitmap(t *testing.T) {
+	rm := NewRegisterMap(1000, 1000, 100, 100)
+
+	// 線圈 0-999 交錯設定 (0x55 = 位元 0、2、4、6)
+	bitmap := bytes.Repeat([]byte{0x55}, 125)
+	require.NoError(t, rm.WriteCoilBitmap(0, bitmap, 1000))
+	val, err := rm.ReadCoil(998)
+	require.NoError(t, err)
+	assert.True(t, val)
+	val, err = rm.ReadCoil(999)
+	require.NoError(t, err)
+	assert.False(t, val)
+
+	result, err := rm.ReadCoilBitmap(0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, bitmap, result)
+
+	// 非對齊位址與部分位元組：線圈 3-5 設為 1，7 維持 0
+	require.NoError(t, rm.WriteCoilBitmap(3, []byte{0xff}, 3))
+	result, err = rm.ReadCoilBitmap(2, 6)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x1f}, result)
+
+	require.NoError(t, rm.SetDiscreteInputBitmap(10, []byte{0x01, 0x80}, 0))
+	result, err = rm.ReadDiscreteInputBitmap(10, 16)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x01, 0x80}, result)
+
+	assert.Error(t, rm.WriteCoilBitmap(0, []byte{0xff}, 9), "位元表長度不足")
+	assert.Error(t, rm.WriteCoilBitmap(995, []byte{0xff}, 8), "超出範圍")
+	assert.Error(t, rm.WriteCoilBitmap(0, nil, 0), "空位元表")
+	_, err = rm.ReadDiscreteInputBitmap(1000, 0)
+	assert.Error(t, err)
+}
+
 func TestRegisterMap_InputRegisters(t *testing.T) {
 	rm := NewRegisterMap(100, 100, 100, 100)
 
@@ -135,6 +420,77 @@ func TestRegisterMap_OutOfBounds(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestRegisterMap_PagedAllocation(t *testing.T) {
+	rm := DefaultRegisterMap()
+
+	// 僅配置已定義暫存器 (40001-40008) 所在的頁面
+	assert.Equal(t, 1, rm.AllocatedPages())
+
+	// 未配置的頁面讀為零值，寫入零值不配置
+	values, err := rm.ReadInputRegisters(5000, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{0, 0, 0}, values)
+	require.NoError(t, rm.WriteCoils(9000, []bool{false, false}))
+	assert.Equal(t, 1, rm.AllocatedPages())
+	checksum := rm.Checksum()
+
+	// 跨頁寫入配置兩個新頁面
+	require.NoError(t, rm.WriteHoldingRegisters(2046, []uint16{1, 2, 3, 4}))
+	assert.Equal(t, 3, rm.AllocatedPages())
+	values, err = rm.ReadHoldingRegisters(2045, 6)
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{0, 1, 2, 3, 4, 0}, values)
+	assert.NotEqual(t, checksum, rm.Checksum())
+
+	// 最後一頁依長度截短，範圍檢查不變
+	require.NoError(t, rm.WriteCoil(9999, true))
+	coil, err := rm.ReadCoil(9999)
+	require.NoError(t, err)
+	assert.True(t, coil)
+	assert.Error(t, rm.WriteCoil(10000, true))
+
+	// 複製時沿用頁面配置
+	var holding, input pagedStore[uint16]
+	var coils, discretes pagedStore[bool]
+	rm.SyncRawTo(&holding, &input, &coils, &discretes)
+	assert.Equal(t, 3, holding.Pages())
+	assert.Equal(t, 0, input.Pages())
+	assert.Equal(t, 1, coils.Pages())
+	assert.Equal(t, uint16(4), holding.At(2049))
+	assert.Equal(t, rm.GetRawHoldingRegisters(), holding.Slice(0, holding.Len()))
+}
+
+func TestRegisterMap_Clone(t *testing.T) {
+	base := DefaultRegisterMap()
+	clone := base.Clone()
+
+	// 複本與基準共用頁面
+	assert.Equal(t, 1, clone.SharedPages())
+	assert.Equal(t, base.Checksum(), clone.Checksum())
+
+	// 寫入時只複製該頁，基準不受影響
+	require.NoError(t, clone.SetScaledValue(40001, 230.0))
+	assert.Equal(t, 0, clone.SharedPages())
+	voltage, err := base.GetScaledValue(40001)
+	require.NoError(t, err)
+	assert.InDelta(t, 220.0, voltage, 0.01)
+
+	// 新的寫入頁面不影響共用狀態
+	other := base.Clone()
+	require.NoError(t, other.WriteCoil(5000, true))
+	assert.Equal(t, 1, other.SharedPages())
+	assert.Equal(t, 2, other.AllocatedPages())
+
+	// 修改定義時複製元資料
+	other.DefineRegister(40010, "Extra", DataTypeUint16, 1, "", true)
+	require.NoError(t, other.SetExpression(40010, "40001 + 1"))
+	_, ok := base.GetDefinition(40010)
+	assert.False(t, ok)
+	meta, ok := other.GetDefinition(40010)
+	require.True(t, ok)
+	assert.Equal(t, "40001 + 1", meta.Expression)
+}
+
 func TestRegisterMap_Concurrent(t *testing.T) {
 	rm := DefaultRegisterMap()
 	done := make(chan bool)
@@ -156,6 +512,132 @@ func TestRegisterMap_Concurrent(t *testing.T) {
 	}
 }
 
+func TestRegisterMap_Snapshot(t *testing.T) {
+	rm := DefaultRegisterMap()
+	require.NoError(t, rm.SetScaledValue(40004, 123456))
+	require.NoError(t, rm.WriteCoil(10, true))
+	require.NoError(t, rm.SetInputRegister(2000, 7))
+	snap := rm.Snapshot()
+
+	// 僅列出非零值；相鄰的暫存器合併為同一區段
+	assert.Equal(t, []SnapshotBlock{{Start: 10, Values: []uint16{1}}}, snap.Coils)
+	assert.Equal(t, []SnapshotBlock{{Start: 2000, Values: []uint16{7}}}, snap.InputRegisters)
+	assert.Empty(t, snap.DiscreteInputs)
+
+	restored := DefaultRegisterMap()
+	require.NoError(t, restored.WriteCoil(11, true))
+	require.NoError(t, restored.RestoreSnapshot(snap))
+	assert.Equal(t, rm.Checksum(), restored.Checksum(), "快照未列出的位址應歸零")
+	energy, _ := restored.GetScaledValue(40004)
+	assert.Equal(t, 123456.0, energy)
+
+	// 區段超出範圍時不做任何修改
+	err := restored.RestoreSnapshot(RegisterSnapshot{
+		HoldingRegisters: []SnapshotBlock{{Start: 0, Values: []uint16{1}}},
+		Coils:            []SnapshotBlock{{Start: 9999, Values: []uint16{1, 1}}},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, rm.Checksum(), restored.Checksum())
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "engine.json")
+	require.NoError(t, writeFileAtomic(path, []byte("first")))
+	require.NoError(t, writeFileAtomic(path, []byte("second")))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(data))
+	_, err = os.Stat(path + ".tmp")
+	assert.True(t, os.IsNotExist(err), "更名後不留下暫存檔")
+
+	// 目錄不存在時回報錯誤
+	assert.Error(t, writeFileAtomic(filepath.Join(dir, "missing", "engine.json"), []byte("x")))
+}
+
+func TestFileStateStore_Load(t *testing.T) {
+	store, err := newFileStateStore(t.TempDir())
+	require.NoError(t, err)
+	snap, err := store.Load()
+	require.NoError(t, err)
+	assert.Nil(t, snap, "尚無資料")
+
+	// 尚未寫入 engine.json (第一次 checkpoint 途中終止) 時仍讀取 Slave 的 checkpoint
+	require.NoError(t, store.SaveSlave(SlaveSnapshot{ID: "127.0.0.1:502", IP: "127.0.0.1"}))
+	snap, err = store.Load()
+	require.NoError(t, err)
+	require.NotNil(t, snap)
+	assert.Empty(t, snap.Scenario, "維持目前的場景")
+	require.Len(t, snap.Slaves, 1)
+	assert.Equal(t, "127.0.0.1", snap.Slaves[0].IP)
+
+	require.NoError(t, store.SaveScenario("jitter"))
+	snap, err = store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "jitter", snap.Scenario)
+	assert.Len(t, snap.Slaves, 1)
+}
+
+func TestLogStateStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "modbussim.log")
+	store, err := newLogStateStore(path)
+	require.NoError(t, err)
+	snap, err := store.Load()
+	require.NoError(t, err)
+	assert.Nil(t, snap, "尚無資料")
+
+	require.NoError(t, store.SaveScenario("normal"))
+	require.NoError(t, store.SaveSlave(SlaveSnapshot{ID: "127.0.0.2:502", IP: "127.0.0.2"}))
+	require.NoError(t, store.SaveSlave(SlaveSnapshot{ID: "127.0.0.1:502", IP: "127.0.0.1"}))
+	require.NoError(t, store.SaveSlave(SlaveSnapshot{ID: "127.0.0.1:5020", IP: "127.0.0.1"}))
+	require.NoError(t, store.SaveScenario("jitter"))
+	require.NoError(t, store.Close())
+
+	// 模擬當機留下寫到一半的紀錄
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = file.Write([]byte{0, 0, 1, 0, 0xde, 0xad})
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	store, err = newLogStateStore(path)
+	require.NoError(t, err)
+	snap, err = store.Load()
+	require.NoError(t, err)
+	require.NotNil(t, snap)
+	assert.Equal(t, "jitter", snap.Scenario)
+	require.Len(t, snap.Slaves, 2, "以每個 Slave 最後一筆為準")
+	assert.Equal(t, "127.0.0.1:5020", snap.Slaves[0].ID)
+	assert.Equal(t, "127.0.0.2:502", snap.Slaves[1].ID)
+
+	// 截斷後追加的紀錄可再次讀取
+	require.NoError(t, store.SaveSlave(SlaveSnapshot{ID: "127.0.0.3:502", IP: "127.0.0.3"}))
+	require.NoError(t, store.Close())
+	store, err = newLogStateStore(path)
+	require.NoError(t, err)
+	snap, err = store.Load()
+	require.NoError(t, err)
+	assert.Len(t, snap.Slaves, 3)
+
+	// 超過有效紀錄的兩倍時重寫，只保留各鍵最後一筆
+	store.compactSize = 0
+	for i := 0; i < 10; i++ {
+		require.NoError(t, store.SaveScenario("jitter"))
+	}
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, info.Size(), 2*store.liveSize)
+	require.NoError(t, store.Close())
+	store, err = newLogStateStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+	snap, err = store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "jitter", snap.Scenario)
+	assert.Len(t, snap.Slaves, 3)
+}
+
 func TestRegistersToBytes(t *testing.T) {
 	registers := []uint16{0x0102, 0x0304}
 	bytes := RegistersToBytes(registers)
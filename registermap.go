@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/viper"
+)
+
+// RegisterMapFile 外部暫存器對應檔 (JSON/YAML，依副檔名判斷)
+//
+//	include:
+//	  - common/power.yaml
+//	registers:
+//	  - {address: 40001, name: LineVoltage, data_type: uint16, scale: 10, unit: V}
+//
+// include 的檔案依序載入，後載入者與本檔的 registers 中相同位址的定義覆寫先前的定義
+type RegisterMapFile struct {
+	Include   []string             `json:"include,omitempty" mapstructure:"include"` // 路徑相對於本檔所在目錄
+	Registers []RegisterDefinition `json:"registers" mapstructure:"registers"`
+}
+
+// LoadRegisterMap 載入暫存器對應檔並展開 include，回傳依位址排序的定義
+func LoadRegisterMap(path string) ([]RegisterDefinition, error) {
+	return loadRegisterMap(path, nil)
+}
+
+// loadRegisterMap 遞迴載入 (chain 為目前的 include 鏈，用來偵測循環引用)
+func loadRegisterMap(path string, chain []string) ([]RegisterDefinition, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range chain {
+		if p == abs {
+			return nil, fmt.Errorf(T("暫存器對應檔循環引用: %s"), path)
+		}
+	}
+	chain = append(chain, abs)
+
+	v := viper.New()
+	v.SetConfigFile(abs)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf(T("讀取暫存器對應檔 %s 失敗: %w"), path, err)
+	}
+	var file RegisterMapFile
+	if err := v.Unmarshal(&file); err != nil {
+		return nil, fmt.Errorf(T("解析暫存器對應檔 %s 失敗: %w"), path, err)
+	}
+
+	for i := range file.Registers {
+		file.Registers[i].source = path
+	}
+
+	var defs []RegisterDefinition
+	for _, include := range file.Include {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(abs), include)
+		}
+		included, err := loadRegisterMap(include, chain)
+		if err != nil {
+			return nil, err
+		}
+		defs = mergeRegisterDefinitions(defs, included)
+	}
+	return mergeRegisterDefinitions(defs, file.Registers), nil
+}
+
+// mergeRegisterDefinitions 以 overrides 覆寫 base 中相同位址的定義 (其餘附加)，回傳依位址排序的新切片
+func mergeRegisterDefinitions(base, overrides []RegisterDefinition) []RegisterDefinition {
+	byAddress := make(map[uint16]RegisterDefinition, len(base)+len(overrides))
+	for _, def := range base {
+		byAddress[def.Address] = def
+	}
+	for _, def := range overrides {
+		byAddress[def.Address] = def
+	}
+
+	merged := make([]RegisterDefinition, 0, len(byAddress))
+	for _, def := range byAddress {
+		merged = append(merged, def)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Address < merged[j].Address })
+	return merged
+}
+
+// resolveRegistersFile 以 slaves.registers_file 為 default_registers 的基礎 (路徑相對於 dir)；
+// inline 為 true (配置檔本身設定了 default_registers) 時，其中相同位址的定義覆寫檔案內容
+func (s *SlavesConfig) resolveRegistersFile(dir string, inline bool) error {
+	if s.RegistersFile == "" {
+		return nil
+	}
+	path := s.RegistersFile
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	defs, err := LoadRegisterMap(path)
+	if err != nil {
+		return err
+	}
+	if inline {
+		defs = mergeRegisterDefinitions(defs, s.DefaultRegisters)
+	}
+	s.DefaultRegisters = defs
+	return nil
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
-	"math/rand"
+	"encoding/binary"
+	"io"
+	"math"
+	"sort"
 	"sync"
 	"time"
 )
@@ -14,6 +17,23 @@ const (
 	ScenarioVoltageSag
 	ScenarioJitter
 	ScenarioPacketLoss
+	ScenarioPhaseImbalance
+	ScenarioConnectionFlap
+	ScenarioSlowDrain
+	ScenarioDataFreeze
+	ScenarioFragmentedResponse
+	ScenarioExceptionStorm
+	ScenarioCorruptedResponse
+	ScenarioLoadProfile
+	ScenarioScript
+	ScenarioPlugin
+	ScenarioReplay
+	ScenarioLongCommand
+	ScenarioConnectionChurn
+	ScenarioTransactionMismatch
+	ScenarioHarmonicDistortion
+	ScenarioComposite
+	ScenarioChaos
 )
 
 func (s ScenarioType) String() string {
@@ -26,11 +46,54 @@ func (s ScenarioType) String() string {
 		return "jitter"
 	case ScenarioPacketLoss:
 		return "packet_loss"
+	case ScenarioPhaseImbalance:
+		return "phase_imbalance"
+	case ScenarioConnectionFlap:
+		return "connection_flap"
+	case ScenarioSlowDrain:
+		return "slow_drain"
+	case ScenarioDataFreeze:
+		return "data_freeze"
+	case ScenarioFragmentedResponse:
+		return "fragmented_response"
+	case ScenarioExceptionStorm:
+		return "exception_storm"
+	case ScenarioCorruptedResponse:
+		return "corrupted_response"
+	case ScenarioLoadProfile:
+		return "load_profile"
+	case ScenarioScript:
+		return "script"
+	case ScenarioPlugin:
+		return "plugin"
+	case ScenarioReplay:
+		return "replay"
+	case ScenarioLongCommand:
+		return "long_command"
+	case ScenarioConnectionChurn:
+		return "connection_churn"
+	case ScenarioTransactionMismatch:
+		return "transaction_mismatch"
+	case ScenarioHarmonicDistortion:
+		return "harmonic_distortion"
+	case ScenarioComposite:
+		return "composite"
+	case ScenarioChaos:
+		return "chaos"
 	default:
 		return "unknown"
 	}
 }
 
+// ScenarioTypes 所有場景類型 (依定義順序)
+func ScenarioTypes() []ScenarioType {
+	types := make([]ScenarioType, 0, ScenarioChaos+1)
+	for s := ScenarioNormal; s <= ScenarioChaos; s++ {
+		types = append(types, s)
+	}
+	return types
+}
+
 // ParseScenarioType 解析場景類型
 func ParseScenarioType(s string) ScenarioType {
 	switch s {
@@ -42,6 +105,40 @@ func ParseScenarioType(s string) ScenarioType {
 		return ScenarioJitter
 	case "packet_loss":
 		return ScenarioPacketLoss
+	case "phase_imbalance":
+		return ScenarioPhaseImbalance
+	case "connection_flap":
+		return ScenarioConnectionFlap
+	case "slow_drain":
+		return ScenarioSlowDrain
+	case "data_freeze":
+		return ScenarioDataFreeze
+	case "fragmented_response":
+		return ScenarioFragmentedResponse
+	case "exception_storm":
+		return ScenarioExceptionStorm
+	case "corrupted_response":
+		return ScenarioCorruptedResponse
+	case "load_profile":
+		return ScenarioLoadProfile
+	case "script":
+		return ScenarioScript
+	case "plugin":
+		return ScenarioPlugin
+	case "replay":
+		return ScenarioReplay
+	case "long_command":
+		return ScenarioLongCommand
+	case "connection_churn":
+		return ScenarioConnectionChurn
+	case "transaction_mismatch":
+		return ScenarioTransactionMismatch
+	case "harmonic_distortion":
+		return ScenarioHarmonicDistortion
+	case "composite":
+		return ScenarioComposite
+	case "chaos":
+		return ScenarioChaos
 	default:
 		return ScenarioNormal
 	}
@@ -54,6 +151,42 @@ type ScenarioHandler interface {
 	Reset(registers *RegisterMap)
 }
 
+// ResponseShaper 需要控制回應寫出方式的場景實作此介面 (由 TCP 接入層呼叫)
+type ResponseShaper interface {
+	WriteResponse(w io.Writer, response []byte, params ScenarioParams) error
+}
+
+// ExceptionInjector 需要以例外回應取代正常處理的場景實作此介面 (由 Slave 呼叫)
+type ExceptionInjector interface {
+	InjectException(functionCode uint8, params ScenarioParams) (exceptionCode uint8, ok bool)
+}
+
+// RequestInterceptor 需要依請求內容 (功能碼與 PDU 資料) 以例外回應的場景實作此介面 (由 Slave 呼叫)
+// 回應 Acknowledge (0x05) 時請求仍照常執行 (設備已接受命令並在背景處理)，其他例外則不執行
+type RequestInterceptor interface {
+	InterceptRequest(registers *RegisterMap, functionCode uint8, data []byte, params ScenarioParams) (exceptionCode uint8, ok bool)
+}
+
+// RequestJitter 需要延遲回應的場景實作此介面 (由 RequestHandler 對每個請求取樣)
+type RequestJitter interface {
+	JitterModel(params ScenarioParams) DelayModel
+}
+
+// RequestLoss 需要丟棄部分請求 (不回應) 的場景實作此介面 (由 RequestHandler 套用)
+type RequestLoss interface {
+	LossRate(params ScenarioParams) float64
+}
+
+// ScenarioActivator 需要在切換進場景時擷取狀態的場景實作此介面 (由 Slave 呼叫)
+type ScenarioActivator interface {
+	Activate(registers *RegisterMap)
+}
+
+// EnergyAccumulator 累計電能的場景實作此介面 (快照保存不受暫存器縮放捨去的累計值)
+type EnergyAccumulator interface {
+	Energy(registers *RegisterMap) (float64, bool)
+}
+
 // 場景處理器註冊表
 var (
 	scenarioHandlers   = make(map[ScenarioType]ScenarioHandler)
@@ -66,6 +199,23 @@ func init() {
 	RegisterScenarioHandler(&VoltageSagScenario{})
 	RegisterScenarioHandler(&JitterScenario{})
 	RegisterScenarioHandler(&PacketLossScenario{})
+	RegisterScenarioHandler(&PhaseImbalanceScenario{})
+	RegisterScenarioHandler(&ConnectionFlapScenario{})
+	RegisterScenarioHandler(&SlowDrainScenario{})
+	RegisterScenarioHandler(&DataFreezeScenario{})
+	RegisterScenarioHandler(&FragmentedResponseScenario{})
+	RegisterScenarioHandler(&ExceptionStormScenario{})
+	RegisterScenarioHandler(&CorruptedResponseScenario{})
+	RegisterScenarioHandler(&LoadProfileScenario{})
+	RegisterScenarioHandler(&ScriptScenario{})
+	RegisterScenarioHandler(&PluginScenario{})
+	RegisterScenarioHandler(&ReplayScenario{})
+	RegisterScenarioHandler(&LongCommandScenario{})
+	RegisterScenarioHandler(&ConnectionChurnScenario{})
+	RegisterScenarioHandler(&TransactionMismatchScenario{})
+	RegisterScenarioHandler(&HarmonicDistortionScenario{})
+	RegisterScenarioHandler(&CompositeScenario{})
+	RegisterScenarioHandler(&ChaosScenario{})
 }
 
 // RegisterScenarioHandler 註冊場景處理器
@@ -89,19 +239,61 @@ func ListScenarioTypes() []ScenarioType {
 		ScenarioVoltageSag,
 		ScenarioJitter,
 		ScenarioPacketLoss,
+		ScenarioPhaseImbalance,
+		ScenarioConnectionFlap,
+		ScenarioSlowDrain,
+		ScenarioDataFreeze,
+		ScenarioFragmentedResponse,
+		ScenarioExceptionStorm,
+		ScenarioCorruptedResponse,
+		ScenarioLoadProfile,
+		ScenarioScript,
+		ScenarioPlugin,
+		ScenarioReplay,
+		ScenarioLongCommand,
+		ScenarioConnectionChurn,
+		ScenarioTransactionMismatch,
+		ScenarioHarmonicDistortion,
+		ScenarioComposite,
+		ScenarioChaos,
 	}
 }
 
 // --- Normal Scenario ---
 
-// NormalScenario 正常場景 - 小幅波動
+// NormalScenario 正常場景 - 以各電表的額定值為中心小幅波動
 type NormalScenario struct {
-	baseVoltage   float64
-	baseCurrent   float64
-	baseFrequency float64
-	basePower     float64
-	energy        float64
-	lastUpdate    time.Time
+	mu     sync.Mutex
+	states map[*RegisterMap]*normalState
+}
+
+// normalState 各暫存器映射表的累計電能
+type normalState struct {
+	energyState
+}
+
+// energyState 累計電能 (kWh) 與上次累積的時間
+type energyState struct {
+	energy     float64
+	lastUpdate time.Time
+	restored   time.Time // 上次接續快照還原值的時間
+}
+
+// resume 接續快照還原 (RestoreEnergy) 的累計電能，每次還原只套用一次；
+// 新建立的狀態 (fresh) 已自暫存器讀值接續，僅在與還原值相差不到 1 kWh 時沿用還原值，保留縮放捨去的小數而不倒退
+func (s *energyState) resume(registers *RegisterMap, fresh bool) {
+	restore, ok := restoredEnergy(registers)
+	if !ok || !restore.at.After(s.restored) {
+		return
+	}
+	s.restored = restore.at
+	if fresh && math.Abs(restore.energy-s.energy) >= 1 {
+		return
+	}
+	s.energy = restore.energy
+	if s.lastUpdate.Before(restore.at) {
+		s.lastUpdate = restore.at
+	}
 }
 
 func (s *NormalScenario) Type() ScenarioType {
@@ -109,58 +301,92 @@ func (s *NormalScenario) Type() ScenarioType {
 }
 
 func (s *NormalScenario) Update(registers *RegisterMap, params ScenarioParams) {
-	// 初始化基準值
-	if s.baseVoltage == 0 {
-		s.baseVoltage = 220.0
-		s.baseCurrent = 15.5
-		s.baseFrequency = 60.0
-		s.basePower = 3300.0
-		s.lastUpdate = time.Now()
-	}
+	nominal, random := registers.Nominal(), registerRandom(registers)
 
 	// 電壓波動 (±0.5%)
 	voltageVariance := params.VoltageVariance
 	if voltageVariance == 0 {
 		voltageVariance = 0.005
 	}
-	voltage := s.baseVoltage * (1 + (rand.Float64()*2-1)*voltageVariance)
+	voltage := nominal.Voltage * (1 + (random.Float64()*2-1)*voltageVariance)
 
 	// 頻率波動 (±0.05%)
 	freqVariance := params.FrequencyVariance
 	if freqVariance == 0 {
 		freqVariance = 0.0005
 	}
-	frequency := s.baseFrequency * (1 + (rand.Float64()*2-1)*freqVariance)
+	frequency := nominal.Frequency * (1 + (random.Float64()*2-1)*freqVariance)
 
 	// 電流波動 (±2%)
-	current := s.baseCurrent * (1 + (rand.Float64()*2-1)*0.02)
-
-	// 功率計算
-	power := voltage * current * 0.95 // PF = 0.95
-
-	// 累積能量
-	elapsed := time.Since(s.lastUpdate).Hours()
-	s.energy += power * elapsed / 1000 // kWh
-	s.lastUpdate = time.Now()
+	current := nominal.Current * (1 + (random.Float64()*2-1)*0.02)
 
 	// 更新暫存器
 	registers.SetScaledValue(40001, voltage)
 	registers.SetScaledValue(40002, current)
 	registers.SetScaledValue(40003, frequency)
-	registers.SetScaledValue(40004, s.energy)
 	registers.SetScaledValue(40006, 0.95)
-	registers.SetScaledValue(40007, power)
+
+	// 功率計算：先寫入 P = V * I * PF 作為未定義運算式時的值，再由衍生暫存器運算式覆寫
+	registers.SetScaledValue(40007, voltage*current*0.95)
+	registers.EvaluateExpressions()
+	power, _ := registers.GetScaledValue(40007)
+
+	// 累積能量
+	registers.SetScaledValue(40004, s.accumulate(registers, power))
+
+	// 三相設定檔：各相小幅波動
+	updatePhases(registers, voltage, current, -1, 0)
+
+	// 電能品質設定檔：諧波與閃爍小幅波動
+	updatePowerQuality(registers, baselineHarmonics, baselineFlickerPst)
+}
+
+// accumulate 依功率 (W) 累積該映射表的電能並回傳 (kWh)；首次更新時從目前的電能讀值接續
+func (s *NormalScenario) accumulate(registers *RegisterMap, power float64) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := scenarioNow()
+	if s.states == nil {
+		s.states = make(map[*RegisterMap]*normalState)
+	}
+	state, ok := s.states[registers]
+	if !ok {
+		energy, _ := registers.GetScaledValue(40004)
+		state = &normalState{energyState{energy: energy, lastUpdate: now}}
+		s.states[registers] = state
+	}
+	state.resume(registers, !ok)
+	state.energy += power * now.Sub(state.lastUpdate).Hours() / 1000
+	state.lastUpdate = now
+	return state.energy
+}
+
+// Energy 映射表的累計電能 (kWh)；尚未開始累積時回傳 false
+func (s *NormalScenario) Energy(registers *RegisterMap) (float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.states[registers]
+	if !ok {
+		return 0, false
+	}
+	return state.energy, true
 }
 
 func (s *NormalScenario) Reset(registers *RegisterMap) {
-	s.energy = 0
-	s.lastUpdate = time.Now()
-	registers.SetScaledValue(40001, 220.0)
-	registers.SetScaledValue(40002, 15.5)
-	registers.SetScaledValue(40003, 60.0)
+	s.mu.Lock()
+	delete(s.states, registers)
+	s.mu.Unlock()
+
+	nominal := registers.Nominal()
+	registers.SetScaledValue(40001, nominal.Voltage)
+	registers.SetScaledValue(40002, nominal.Current)
+	registers.SetScaledValue(40003, nominal.Frequency)
 	registers.SetScaledValue(40004, 0)
 	registers.SetScaledValue(40006, 0.95)
-	registers.SetScaledValue(40007, 3300.0)
+	registers.SetScaledValue(40007, nominal.Voltage*nominal.Current*0.95)
+	updatePhases(registers, nominal.Voltage, nominal.Current, -1, 0)
+	resetPowerQuality(registers)
 }
 
 // --- Voltage Sag Scenario ---
@@ -180,7 +406,7 @@ func (s *VoltageSagScenario) Type() ScenarioType {
 func (s *VoltageSagScenario) Update(registers *RegisterMap, params ScenarioParams) {
 	// 初始化
 	if s.startTime.IsZero() {
-		s.startTime = time.Now()
+		s.startTime = scenarioNow()
 		s.duration = params.Duration
 		if s.duration == 0 {
 			s.duration = 10 * time.Second
@@ -198,7 +424,7 @@ func (s *VoltageSagScenario) Update(registers *RegisterMap, params ScenarioParam
 	})
 
 	// 在持續時間內套用電壓驟降
-	if time.Since(s.startTime) < s.duration {
+	if scenarioSince(s.startTime) < s.duration {
 		voltage, _ := registers.GetScaledValue(40001)
 		registers.SetScaledValue(40001, voltage*s.sagFactor)
 
@@ -227,15 +453,8 @@ func (s *JitterScenario) Type() ScenarioType {
 }
 
 func (s *JitterScenario) Update(registers *RegisterMap, params ScenarioParams) {
-	// 設定延遲參數 (由 RequestHandler 使用)
-	s.jitterMin = params.JitterMin
-	s.jitterMax = params.JitterMax
-	if s.jitterMin == 0 {
-		s.jitterMin = 100 * time.Millisecond
-	}
-	if s.jitterMax == 0 {
-		s.jitterMax = 500 * time.Millisecond
-	}
+	// 設定延遲參數
+	s.jitterMin, s.jitterMax = s.JitterRange(params)
 
 	// 使用正常場景更新暫存器值
 	s.normalScenario.Update(registers, ScenarioParams{
@@ -248,6 +467,47 @@ func (s *JitterScenario) Reset(registers *RegisterMap) {
 	s.normalScenario.Reset(registers)
 }
 
+// JitterModel 依參數建立回應延遲模型 (由 RequestHandler 套用)
+// 未指定分佈時為 jitter_min-jitter_max 均勻分佈；jitter_mean 預設為範圍中點，jitter_stddev 預設為平均值的 1/4
+func (s *JitterScenario) JitterModel(params ScenarioParams) DelayModel {
+	min, max := s.JitterRange(params)
+	model := DelayModel{
+		Distribution: params.JitterDistribution,
+		Min:          min,
+		Max:          max,
+		Mean:         params.JitterMean,
+		StdDev:       params.JitterStdDev,
+		Alpha:        params.JitterAlpha,
+		Cap:          params.JitterCap,
+		TimeoutRate:  params.JitterTimeoutRate,
+	}
+	if model.Distribution == "" {
+		model.Distribution = DelayUniform
+	}
+	if model.Mean == 0 {
+		model.Mean = (min + max) / 2
+	}
+	if model.StdDev == 0 {
+		model.StdDev = model.Mean / 4
+	}
+	if model.Alpha == 0 {
+		model.Alpha = DefaultParetoAlpha
+	}
+	return model
+}
+
+// JitterRange 依參數計算延遲範圍
+func (s *JitterScenario) JitterRange(params ScenarioParams) (min, max time.Duration) {
+	min, max = params.JitterMin, params.JitterMax
+	if min == 0 {
+		min = 100 * time.Millisecond
+	}
+	if max == 0 {
+		max = 500 * time.Millisecond
+	}
+	return min, max
+}
+
 // GetJitterRange 取得延遲範圍
 func (s *JitterScenario) GetJitterRange() (min, max time.Duration) {
 	return s.jitterMin, s.jitterMax
@@ -266,11 +526,8 @@ func (s *PacketLossScenario) Type() ScenarioType {
 }
 
 func (s *PacketLossScenario) Update(registers *RegisterMap, params ScenarioParams) {
-	// 設定丟失率 (由 RequestHandler 使用)
-	s.lossRate = params.PacketLossRate
-	if s.lossRate == 0 {
-		s.lossRate = 0.05 // 預設 5%
-	}
+	// 設定丟失率
+	s.lossRate = s.LossRate(params)
 
 	// 使用正常場景更新暫存器值
 	s.normalScenario.Update(registers, ScenarioParams{
@@ -283,11 +540,611 @@ func (s *PacketLossScenario) Reset(registers *RegisterMap) {
 	s.normalScenario.Reset(registers)
 }
 
+// LossRate 依參數計算丟失率 (由 RequestHandler 套用)
+func (s *PacketLossScenario) LossRate(params ScenarioParams) float64 {
+	if params.PacketLossRate == 0 {
+		return 0.05 // 預設 5%
+	}
+	return params.PacketLossRate
+}
+
 // GetLossRate 取得丟失率
 func (s *PacketLossScenario) GetLossRate() float64 {
 	return s.lossRate
 }
 
+// --- Phase Imbalance Scenario ---
+
+// PhaseImbalanceScenario 三相不平衡場景 - 單相電壓下降、電流上升
+type PhaseImbalanceScenario struct {
+	normalScenario NormalScenario
+}
+
+func (s *PhaseImbalanceScenario) Type() ScenarioType {
+	return ScenarioPhaseImbalance
+}
+
+func (s *PhaseImbalanceScenario) Update(registers *RegisterMap, params ScenarioParams) {
+	ratio := params.ImbalanceRatio
+	if ratio <= 0 || ratio >= 1 {
+		ratio = 0.10 // 預設偏移 10%
+	}
+
+	// 先用正常場景更新 (含三相平衡值)
+	s.normalScenario.Update(registers, ScenarioParams{
+		VoltageVariance:   0.005,
+		FrequencyVariance: 0.0005,
+	})
+
+	voltage, _ := registers.GetScaledValue(40001)
+	current, _ := registers.GetScaledValue(40002)
+	updatePhases(registers, voltage, current, parsePhase(params.ImbalancePhase), ratio)
+}
+
+func (s *PhaseImbalanceScenario) Reset(registers *RegisterMap) {
+	s.normalScenario.Reset(registers)
+}
+
+// parsePhase 將相別字串 (a/b/c) 轉為索引，預設為 A 相
+func parsePhase(phase string) int {
+	switch phase {
+	case "b", "B":
+		return 1
+	case "c", "C":
+		return 2
+	default:
+		return 0
+	}
+}
+
+// updatePhases 更新三相電壓/電流暫存器 (僅在設定檔定義時生效)
+// skewPhase 為偏移相別索引 (-1 表示平衡)，ratio 為偏移比例
+func updatePhases(registers *RegisterMap, voltage, current float64, skewPhase int, ratio float64) {
+	if _, ok := registers.GetDefinition(AddrVoltageA); !ok {
+		return
+	}
+
+	voltageAddrs := [3]uint16{AddrVoltageA, AddrVoltageB, AddrVoltageC}
+	currentAddrs := [3]uint16{AddrCurrentA, AddrCurrentB, AddrCurrentC}
+
+	random := registerRandom(registers)
+	var voltages [3]float64
+	for i := 0; i < 3; i++ {
+		// 各相之間 ±0.3% 的自然差異
+		v := voltage * (1 + (random.Float64()*2-1)*0.003)
+		c := current * (1 + (random.Float64()*2-1)*0.01)
+		if i == skewPhase {
+			v *= 1 - ratio
+			c *= 1 + ratio
+		}
+		voltages[i] = v
+		registers.SetScaledValue(voltageAddrs[i], v)
+		registers.SetScaledValue(currentAddrs[i], c)
+	}
+
+	if _, ok := registers.GetDefinition(AddrVoltageUnbalance); ok {
+		registers.SetScaledValue(AddrVoltageUnbalance, voltageUnbalance(voltages))
+	}
+}
+
+// voltageUnbalance 計算電壓不平衡率 (NEMA: 最大偏差 / 平均值 × 100%)
+func voltageUnbalance(voltages [3]float64) float64 {
+	avg := (voltages[0] + voltages[1] + voltages[2]) / 3
+	if avg == 0 {
+		return 0
+	}
+	maxDev := 0.0
+	for _, v := range voltages {
+		maxDev = math.Max(maxDev, math.Abs(v-avg))
+	}
+	return maxDev / avg * 100
+}
+
+// --- Connection Flap Scenario ---
+
+// ConnectionFlapScenario 斷線/閃斷場景
+// 暫存器值維持正常波動；listener 的關閉與恢復由 Slave 依 flap_up/flap_down 執行
+type ConnectionFlapScenario struct {
+	normalScenario NormalScenario
+}
+
+func (s *ConnectionFlapScenario) Type() ScenarioType {
+	return ScenarioConnectionFlap
+}
+
+func (s *ConnectionFlapScenario) Update(registers *RegisterMap, params ScenarioParams) {
+	s.normalScenario.Update(registers, ScenarioParams{
+		VoltageVariance:   0.005,
+		FrequencyVariance: 0.0005,
+	})
+}
+
+func (s *ConnectionFlapScenario) Reset(registers *RegisterMap) {
+	s.normalScenario.Reset(registers)
+}
+
+// --- Slow Drain Scenario ---
+
+// SlowDrainScenario 慢速回應場景 - 以固定速率逐位元組寫出回應
+type SlowDrainScenario struct {
+	normalScenario NormalScenario
+}
+
+func (s *SlowDrainScenario) Type() ScenarioType {
+	return ScenarioSlowDrain
+}
+
+func (s *SlowDrainScenario) Update(registers *RegisterMap, params ScenarioParams) {
+	s.normalScenario.Update(registers, ScenarioParams{
+		VoltageVariance:   0.005,
+		FrequencyVariance: 0.0005,
+	})
+}
+
+func (s *SlowDrainScenario) Reset(registers *RegisterMap) {
+	s.normalScenario.Reset(registers)
+}
+
+// WriteResponse 依 drain_rate (bytes/sec) 逐位元組寫出回應
+func (s *SlowDrainScenario) WriteResponse(w io.Writer, response []byte, params ScenarioParams) error {
+	rate := params.DrainRate
+	if rate <= 0 {
+		rate = 10 // 預設 10 bytes/sec
+	}
+	interval := time.Duration(float64(time.Second) / rate)
+
+	for i := range response {
+		if i > 0 {
+			time.Sleep(interval)
+		}
+		if _, err := w.Write(response[i : i+1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// --- Data Freeze Scenario ---
+
+// DataFreezeScenario 資料凍結場景 - 指定暫存器停在凍結當下的值，其餘照常波動
+type DataFreezeScenario struct {
+	normalScenario NormalScenario
+
+	mu        sync.Mutex
+	snapshots map[*RegisterMap]*freezeSnapshot
+}
+
+// freezeSnapshot 凍結當下的暫存器原始值
+type freezeSnapshot struct {
+	frozenAt time.Time
+	words    map[uint16][]uint16
+}
+
+func (s *DataFreezeScenario) Type() ScenarioType {
+	return ScenarioDataFreeze
+}
+
+// Activate 擷取所有已定義暫存器的目前值
+func (s *DataFreezeScenario) Activate(registers *RegisterMap) {
+	snapshot := &freezeSnapshot{
+		frozenAt: scenarioNow(),
+		words:    make(map[uint16][]uint16),
+	}
+	for _, meta := range registers.Definitions() {
+		words, err := registers.ReadHoldingRegisters(meta.Address, uint16(meta.DataType.RegisterCount()))
+		if err == nil {
+			snapshot.words[meta.Address] = words
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.snapshots == nil {
+		s.snapshots = make(map[*RegisterMap]*freezeSnapshot)
+	}
+	s.snapshots[registers] = snapshot
+}
+
+func (s *DataFreezeScenario) Update(registers *RegisterMap, params ScenarioParams) {
+	s.mu.Lock()
+	snapshot, ok := s.snapshots[registers]
+	s.mu.Unlock()
+	if !ok {
+		s.Activate(registers)
+		s.mu.Lock()
+		snapshot = s.snapshots[registers]
+		s.mu.Unlock()
+	}
+
+	s.normalScenario.Update(registers, ScenarioParams{
+		VoltageVariance:   0.005,
+		FrequencyVariance: 0.0005,
+	})
+
+	// 還原凍結的暫存器 (未指定時凍結全部已定義暫存器)
+	frozen := make(map[string]bool, len(params.FreezeRegisters))
+	for _, name := range params.FreezeRegisters {
+		frozen[name] = true
+	}
+	for _, meta := range registers.Definitions() {
+		if len(frozen) > 0 && !frozen[meta.Name] {
+			continue
+		}
+		if words, ok := snapshot.words[meta.Address]; ok {
+			registers.WriteHoldingRegisters(meta.Address, words)
+		}
+	}
+
+	// 資料時間戳停在凍結當下 (uint32 Unix 秒，高位字在前)
+	if params.TimestampRegister > 0 {
+		ts := uint32(snapshot.frozenAt.Unix())
+		registers.WriteHoldingRegisters(params.TimestampRegister, []uint16{uint16(ts >> 16), uint16(ts)})
+	}
+}
+
+func (s *DataFreezeScenario) Reset(registers *RegisterMap) {
+	s.mu.Lock()
+	delete(s.snapshots, registers)
+	s.mu.Unlock()
+
+	s.normalScenario.Reset(registers)
+}
+
+// --- Fragmented Response Scenario ---
+
+// FragmentedResponseScenario 分段回應場景 - 將回應拆成多個 TCP 區段並於區段間延遲
+type FragmentedResponseScenario struct {
+	normalScenario NormalScenario
+}
+
+func (s *FragmentedResponseScenario) Type() ScenarioType {
+	return ScenarioFragmentedResponse
+}
+
+func (s *FragmentedResponseScenario) Update(registers *RegisterMap, params ScenarioParams) {
+	s.normalScenario.Update(registers, ScenarioParams{
+		VoltageVariance:   0.005,
+		FrequencyVariance: 0.0005,
+	})
+}
+
+func (s *FragmentedResponseScenario) Reset(registers *RegisterMap) {
+	s.normalScenario.Reset(registers)
+}
+
+// WriteResponse 依 fragment_size 分段寫出，每段間隔 fragment_delay
+func (s *FragmentedResponseScenario) WriteResponse(w io.Writer, response []byte, params ScenarioParams) error {
+	size := params.FragmentSize
+	if size <= 0 {
+		size = 3 // 預設每段 3 bytes (MBAP Header 也會被切開)
+	}
+	delay := params.FragmentDelay
+	if delay <= 0 {
+		delay = 50 * time.Millisecond
+	}
+
+	for start := 0; start < len(response); start += size {
+		if start > 0 {
+			time.Sleep(delay)
+		}
+		end := start + size
+		if end > len(response) {
+			end = len(response)
+		}
+		if _, err := w.Write(response[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// --- Exception Storm Scenario ---
+
+// ExceptionStormScenario 例外風暴場景 - 依比例與權重回應 Modbus 例外
+type ExceptionStormScenario struct {
+	normalScenario NormalScenario
+}
+
+// ExceptionCodeNames exception_weights 可用的例外名稱
+var ExceptionCodeNames = map[string]uint8{
+	"illegal_data_address": ExceptionCodeIllegalDataAddress,
+	"slave_device_failure": ExceptionCodeSlaveDeviceFailure,
+	"slave_device_busy":    ExceptionCodeSlaveDeviceBusy,
+}
+
+// defaultExceptionWeights 預設例外權重 (忙碌為主，模擬過載設備)
+var defaultExceptionWeights = map[string]float64{
+	"illegal_data_address": 0.25,
+	"slave_device_failure": 0.25,
+	"slave_device_busy":    0.5,
+}
+
+func (s *ExceptionStormScenario) Type() ScenarioType {
+	return ScenarioExceptionStorm
+}
+
+func (s *ExceptionStormScenario) Update(registers *RegisterMap, params ScenarioParams) {
+	s.normalScenario.Update(registers, ScenarioParams{
+		VoltageVariance:   0.005,
+		FrequencyVariance: 0.0005,
+	})
+}
+
+func (s *ExceptionStormScenario) Reset(registers *RegisterMap) {
+	s.normalScenario.Reset(registers)
+}
+
+// InjectException 依 exception_rate 決定是否回應例外，並依 exception_weights 加權挑選例外碼
+func (s *ExceptionStormScenario) InjectException(functionCode uint8, params ScenarioParams) (uint8, bool) {
+	rate := params.ExceptionRate
+	if rate <= 0 {
+		rate = 0.2
+	}
+	if scenarioRandom().Float64() >= rate {
+		return 0, false
+	}
+
+	weights := params.ExceptionWeights
+	if len(weights) == 0 {
+		weights = defaultExceptionWeights
+	}
+
+	// 依名稱排序，確保相同亂數得到相同結果
+	names := make([]string, 0, len(weights))
+	var total float64
+	for name, weight := range weights {
+		if _, ok := ExceptionCodeNames[name]; ok && weight > 0 {
+			names = append(names, name)
+			total += weight
+		}
+	}
+	if total == 0 {
+		return ExceptionCodeSlaveDeviceBusy, true
+	}
+	sort.Strings(names)
+
+	r := scenarioRandom().Float64() * total
+	for _, name := range names {
+		r -= weights[name]
+		if r < 0 {
+			return ExceptionCodeNames[name], true
+		}
+	}
+	return ExceptionCodeNames[names[len(names)-1]], true
+}
+
+// --- Corrupted Response Scenario ---
+
+// 損壞回應模式
+const (
+	CorruptModeByteCount     = "byte_count"     // 錯誤的 Byte Count
+	CorruptModeTransactionID = "transaction_id" // 不符的 Transaction ID
+	CorruptModeTruncate      = "truncate"       // 截斷訊框
+	CorruptModeGarbage       = "garbage"        // PDU 以亂數取代
+)
+
+// CorruptModes 所有損壞回應模式
+var CorruptModes = []string{
+	CorruptModeByteCount,
+	CorruptModeTransactionID,
+	CorruptModeTruncate,
+	CorruptModeGarbage,
+}
+
+// isCorruptMode 是否為有效的損壞回應模式
+func isCorruptMode(mode string) bool {
+	for _, m := range CorruptModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// CorruptedResponseScenario 損壞回應場景 - 依比例寫出格式錯誤的回應
+type CorruptedResponseScenario struct {
+	normalScenario NormalScenario
+}
+
+func (s *CorruptedResponseScenario) Type() ScenarioType {
+	return ScenarioCorruptedResponse
+}
+
+func (s *CorruptedResponseScenario) Update(registers *RegisterMap, params ScenarioParams) {
+	s.normalScenario.Update(registers, ScenarioParams{
+		VoltageVariance:   0.005,
+		FrequencyVariance: 0.0005,
+	})
+}
+
+func (s *CorruptedResponseScenario) Reset(registers *RegisterMap) {
+	s.normalScenario.Reset(registers)
+}
+
+// WriteResponse 依 corrupt_rate 決定是否損壞回應，模式由 corrupt_modes 隨機挑選
+func (s *CorruptedResponseScenario) WriteResponse(w io.Writer, response []byte, params ScenarioParams) error {
+	rate := params.CorruptRate
+	if rate <= 0 {
+		rate = 0.1
+	}
+	if scenarioRandom().Float64() < rate {
+		modes := params.CorruptModes
+		if len(modes) == 0 {
+			modes = CorruptModes
+		}
+		response = corruptResponse(response, modes[scenarioRandom().Intn(len(modes))])
+	}
+
+	_, err := w.Write(response)
+	return err
+}
+
+// corruptResponse 依模式產生損壞的回應 (不修改原始 slice)
+func corruptResponse(response []byte, mode string) []byte {
+	out := append([]byte(nil), response...)
+	if len(out) <= ModbusTCPHeaderLength {
+		return out
+	}
+
+	switch mode {
+	case CorruptModeByteCount:
+		// 讀取回應的 Byte Count 位於 PDU 第 2 個 byte；其他回應改動 MBAP Length
+		if len(out) > ModbusTCPHeaderLength+1 && out[ModbusTCPHeaderLength] <= FuncCodeReadInputRegisters {
+			out[ModbusTCPHeaderLength+1] += 2
+		} else {
+			length := binary.BigEndian.Uint16(out[4:6])
+			binary.BigEndian.PutUint16(out[4:6], length+2)
+		}
+	case CorruptModeTransactionID:
+		tid := binary.BigEndian.Uint16(out[0:2])
+		binary.BigEndian.PutUint16(out[0:2], tid+1+uint16(scenarioRandom().Intn(0xFFFE)))
+	case CorruptModeTruncate:
+		out = out[:1+scenarioRandom().Intn(len(out)-1)]
+	case CorruptModeGarbage:
+		for i := ModbusTCPHeaderLength; i < len(out); i++ {
+			out[i] = byte(scenarioRandom().Intn(256))
+		}
+	}
+	return out
+}
+
+// --- Load Profile Scenario ---
+
+// LoadProfileScenario 日負載曲線場景 - 電流/功率依 24 小時負載曲線變化，電能隨之累積
+type LoadProfileScenario struct {
+	normalScenario NormalScenario
+
+	mu     sync.Mutex
+	states map[*RegisterMap]*loadProfileState
+}
+
+// loadProfileState 各暫存器映射表的模擬時間與電能
+type loadProfileState struct {
+	realStart time.Time
+	simStart  time.Time
+	energyState
+}
+
+func (s *LoadProfileScenario) Type() ScenarioType {
+	return ScenarioLoadProfile
+}
+
+func (s *LoadProfileScenario) Update(registers *RegisterMap, params ScenarioParams) {
+	s.normalScenario.Update(registers, ScenarioParams{
+		VoltageVariance:   0.005,
+		FrequencyVariance: 0.0005,
+	})
+
+	now := scenarioNow()
+	timeScale := params.TimeScale
+	if timeScale <= 0 {
+		timeScale = 1
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.states == nil {
+		s.states = make(map[*RegisterMap]*loadProfileState)
+	}
+	state, ok := s.states[registers]
+	if !ok {
+		// 從目前的電能讀值接續，避免累計值倒退
+		energy, _ := registers.GetScaledValue(40004)
+		state = &loadProfileState{realStart: now, simStart: now, energyState: energyState{energy: energy, lastUpdate: now}}
+		s.states[registers] = state
+	}
+	state.resume(registers, !ok)
+
+	simNow := state.simStart.Add(time.Duration(float64(now.Sub(state.realStart)) * timeScale))
+	factor := loadFactor(params, simNow)
+
+	voltage, _ := registers.GetScaledValue(40001)
+	current := registers.Nominal().Current * factor * (1 + (registerRandom(registers).Float64()*2-1)*0.02)
+	power := voltage * current * 0.95
+
+	// 依模擬時間累積電能 (加速時電能同步加速)
+	state.energy += power * now.Sub(state.lastUpdate).Hours() * timeScale / 1000
+	state.lastUpdate = now
+
+	registers.SetScaledValue(40002, current)
+	registers.SetScaledValue(40004, state.energy)
+	registers.SetScaledValue(40007, power)
+	updatePhases(registers, voltage, current, -1, 0)
+}
+
+// Energy 映射表的累計電能 (kWh)；尚未開始累積時回傳 false
+func (s *LoadProfileScenario) Energy(registers *RegisterMap) (float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.states[registers]
+	if !ok {
+		return 0, false
+	}
+	return state.energy, true
+}
+
+func (s *LoadProfileScenario) Reset(registers *RegisterMap) {
+	s.mu.Lock()
+	delete(s.states, registers)
+	s.mu.Unlock()
+
+	s.normalScenario.Reset(registers)
+}
+
+// loadFactor 計算指定時刻的負載倍率 (相對於額定電流)
+// 設定 load_curve 時以分段線性內插 (跨午夜循環)，否則使用餘弦曲線
+func loadFactor(params ScenarioParams, t time.Time) float64 {
+	hour := float64(t.Hour()) + float64(t.Minute())/60 + float64(t.Second())/3600
+
+	if len(params.LoadCurve) > 0 {
+		return interpolateLoadCurve(params.LoadCurve, hour)
+	}
+
+	low, high, peak := params.LoadMin, params.LoadMax, params.LoadPeakHour
+	if high <= 0 {
+		low, high = 0.3, 1.0
+	}
+	if peak == 0 {
+		peak = 14
+	}
+	return low + (high-low)*(1+math.Cos(2*math.Pi*(hour-peak)/24))/2
+}
+
+// interpolateLoadCurve 在負載曲線點之間線性內插
+func interpolateLoadCurve(curve []LoadPoint, hour float64) float64 {
+	points := make([]LoadPoint, len(curve))
+	copy(points, curve)
+	sort.Slice(points, func(i, j int) bool { return points[i].Hour < points[j].Hour })
+
+	if len(points) == 1 {
+		return points[0].Factor
+	}
+
+	// 找出 hour 前後的點；超出首尾時與另一端跨午夜內插
+	prev, next := points[len(points)-1], points[0]
+	prevHour, nextHour := prev.Hour-24, next.Hour
+	for i, p := range points {
+		if p.Hour > hour {
+			next, nextHour = p, p.Hour
+			if i > 0 {
+				prev, prevHour = points[i-1], points[i-1].Hour
+			}
+			break
+		}
+		if i == len(points)-1 {
+			prev, prevHour = p, p.Hour
+			next, nextHour = points[0], points[0].Hour+24
+		}
+	}
+
+	if nextHour == prevHour {
+		return prev.Factor
+	}
+	ratio := (hour - prevHour) / (nextHour - prevHour)
+	return prev.Factor + (next.Factor-prev.Factor)*ratio
+}
+
 // ScenarioEngine 場景引擎 (管理場景切換和更新)
 type ScenarioEngine struct {
 	mu sync.RWMutex
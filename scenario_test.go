@@ -1,11 +1,23 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestScenarioType_String(t *testing.T) {
@@ -17,6 +29,23 @@ func TestScenarioType_String(t *testing.T) {
 		{ScenarioVoltageSag, "voltage_sag"},
 		{ScenarioJitter, "jitter"},
 		{ScenarioPacketLoss, "packet_loss"},
+		{ScenarioPhaseImbalance, "phase_imbalance"},
+		{ScenarioConnectionFlap, "connection_flap"},
+		{ScenarioSlowDrain, "slow_drain"},
+		{ScenarioDataFreeze, "data_freeze"},
+		{ScenarioFragmentedResponse, "fragmented_response"},
+		{ScenarioExceptionStorm, "exception_storm"},
+		{ScenarioCorruptedResponse, "corrupted_response"},
+		{ScenarioLoadProfile, "load_profile"},
+		{ScenarioScript, "script"},
+		{ScenarioPlugin, "plugin"},
+		{ScenarioReplay, "replay"},
+		{ScenarioLongCommand, "long_command"},
+		{ScenarioConnectionChurn, "connection_churn"},
+		{ScenarioTransactionMismatch, "transaction_mismatch"},
+		{ScenarioHarmonicDistortion, "harmonic_distortion"},
+		{ScenarioComposite, "composite"},
+		{ScenarioChaos, "chaos"},
 	}
 
 	for _, tt := range tests {
@@ -35,6 +64,23 @@ func TestParseScenarioType(t *testing.T) {
 		{"voltage_sag", ScenarioVoltageSag},
 		{"jitter", ScenarioJitter},
 		{"packet_loss", ScenarioPacketLoss},
+		{"phase_imbalance", ScenarioPhaseImbalance},
+		{"connection_flap", ScenarioConnectionFlap},
+		{"slow_drain", ScenarioSlowDrain},
+		{"data_freeze", ScenarioDataFreeze},
+		{"fragmented_response", ScenarioFragmentedResponse},
+		{"exception_storm", ScenarioExceptionStorm},
+		{"corrupted_response", ScenarioCorruptedResponse},
+		{"load_profile", ScenarioLoadProfile},
+		{"script", ScenarioScript},
+		{"plugin", ScenarioPlugin},
+		{"replay", ScenarioReplay},
+		{"long_command", ScenarioLongCommand},
+		{"connection_churn", ScenarioConnectionChurn},
+		{"transaction_mismatch", ScenarioTransactionMismatch},
+		{"harmonic_distortion", ScenarioHarmonicDistortion},
+		{"composite", ScenarioComposite},
+		{"chaos", ScenarioChaos},
 		{"unknown", ScenarioNormal}, // 預設為 normal
 	}
 
@@ -110,6 +156,55 @@ func TestJitterScenario_GetJitterRange(t *testing.T) {
 	assert.Equal(t, 500*time.Millisecond, max)
 }
 
+func TestJitterScenario_JitterModel(t *testing.T) {
+	scenario := &JitterScenario{}
+
+	// 未指定分佈時維持 jitter_min-jitter_max 均勻分佈
+	model := scenario.JitterModel(ScenarioParams{JitterMin: 100 * time.Millisecond, JitterMax: 500 * time.Millisecond})
+	assert.Equal(t, DelayUniform, model.Distribution)
+	assert.Equal(t, 300*time.Millisecond, model.Mean)
+	assert.Equal(t, 75*time.Millisecond, model.StdDev)
+
+	random := newLockedRand(1)
+	sample := func(model DelayModel) (min, max, mean time.Duration) {
+		min = time.Hour
+		var sum time.Duration
+		for i := 0; i < 2000; i++ {
+			d := model.Sample(random)
+			if d < min {
+				min = d
+			}
+			if d > max {
+				max = d
+			}
+			sum += d
+		}
+		return min, max, sum / 2000
+	}
+
+	min, max, _ := sample(model)
+	assert.GreaterOrEqual(t, min, 100*time.Millisecond)
+	assert.Less(t, max, 500*time.Millisecond)
+
+	min, max, _ = sample(scenario.JitterModel(ScenarioParams{JitterDistribution: DelayFixed, JitterMean: 42 * time.Millisecond}))
+	assert.Equal(t, 42*time.Millisecond, min)
+	assert.Equal(t, 42*time.Millisecond, max)
+
+	min, _, mean := sample(scenario.JitterModel(ScenarioParams{JitterDistribution: DelayNormal, JitterMean: 200 * time.Millisecond, JitterStdDev: 150 * time.Millisecond}))
+	assert.Equal(t, time.Duration(0), min, "負值截為 0")
+	assert.InDelta(t, 200*time.Millisecond, mean, float64(20*time.Millisecond))
+
+	min, _, mean = sample(scenario.JitterModel(ScenarioParams{JitterDistribution: DelayLognormal, JitterMean: 200 * time.Millisecond, JitterStdDev: 100 * time.Millisecond}))
+	assert.Greater(t, min, time.Duration(0))
+	assert.InDelta(t, 200*time.Millisecond, mean, float64(20*time.Millisecond))
+
+	// pareto 以 jitter_min 為下限，長尾受 jitter_cap 限制
+	min, max, _ = sample(scenario.JitterModel(ScenarioParams{JitterDistribution: DelayPareto, JitterMin: 50 * time.Millisecond, JitterAlpha: 1.2, JitterCap: 2 * time.Second}))
+	assert.GreaterOrEqual(t, min, 50*time.Millisecond)
+	assert.Greater(t, max, 500*time.Millisecond)
+	assert.LessOrEqual(t, max, 2*time.Second)
+}
+
 func TestPacketLossScenario_GetLossRate(t *testing.T) {
 	rm := DefaultRegisterMap()
 	handler := &PacketLossScenario{}
@@ -123,6 +218,430 @@ func TestPacketLossScenario_GetLossRate(t *testing.T) {
 	assert.Equal(t, 0.05, rate)
 }
 
+func TestPhaseImbalanceScenario_Update(t *testing.T) {
+	profile, ok := GetDeviceProfile(ProfileThreePhase)
+	require.True(t, ok)
+	rm, err := profile.NewRegisterMap()
+	require.NoError(t, err)
+
+	handler := &PhaseImbalanceScenario{}
+	handler.Update(rm, ScenarioParams{ImbalancePhase: "b", ImbalanceRatio: 0.2})
+
+	va, _ := rm.GetScaledValue(AddrVoltageA)
+	vb, _ := rm.GetScaledValue(AddrVoltageB)
+	ib, _ := rm.GetScaledValue(AddrCurrentB)
+	ia, _ := rm.GetScaledValue(AddrCurrentA)
+	assert.Less(t, vb, va*0.85, "B 相電壓應明顯低於 A 相")
+	assert.Greater(t, ib, ia*1.1, "B 相電流應明顯高於 A 相")
+
+	unbalance, _ := rm.GetScaledValue(AddrVoltageUnbalance)
+	assert.Greater(t, unbalance, 5.0, "電壓不平衡率應反映偏移")
+}
+
+func TestPhaseImbalanceScenario_SinglePhaseProfile(t *testing.T) {
+	rm := DefaultRegisterMap()
+	handler := &PhaseImbalanceScenario{}
+	handler.Update(rm, ScenarioParams{})
+
+	// 單相設定檔沒有三相暫存器，不應寫入
+	raw, err := rm.ReadHoldingRegister(AddrVoltageA)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(0), raw)
+}
+
+func TestHarmonicDistortionScenario_Update(t *testing.T) {
+	base := NewVirtualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	restore := setScenarioSources(base, newLockedRand(1))
+	defer restore()
+
+	profile, ok := GetDeviceProfile(ProfilePowerQuality)
+	require.True(t, ok)
+	require.NoError(t, profile.Validate())
+	rm, err := profile.NewRegisterMap()
+	require.NoError(t, err)
+
+	// 正常波動時諧波維持正常值
+	normal := &NormalScenario{}
+	normal.Update(rm, ScenarioParams{})
+	thd, _ := rm.GetScaledValue(AddrVoltageTHD)
+	assert.InDelta(t, harmonicTHD(baselineHarmonics), thd, 0.5)
+
+	// 事件期間第 5 次諧波增大，THD 與 Pst 達設定值
+	handler := &HarmonicDistortionScenario{}
+	params := ScenarioParams{Duration: 20 * time.Second, HarmonicInterval: time.Minute, HarmonicTHD: 8, HarmonicOrder: 5, FlickerPst: 1.2}
+	handler.Update(rm, params)
+	thd, _ = rm.GetScaledValue(AddrVoltageTHD)
+	h3, _ := rm.GetScaledValue(AddrHarmonic3)
+	h5, _ := rm.GetScaledValue(AddrHarmonic5)
+	currentTHD, _ := rm.GetScaledValue(AddrCurrentTHD)
+	pst, _ := rm.GetScaledValue(AddrFlickerPst)
+	assert.InDelta(t, 8, thd, 1)
+	assert.Greater(t, h5, h3*3, "第 5 次諧波應為主")
+	assert.Greater(t, currentTHD, thd*2)
+	assert.InDelta(t, 1.2, pst, 0.15)
+
+	// 事件結束後恢復，下一個間隔再次發生
+	base.Advance(30 * time.Second)
+	handler.Update(rm, params)
+	thd, _ = rm.GetScaledValue(AddrVoltageTHD)
+	assert.Less(t, thd, 4.0)
+	base.Advance(35 * time.Second)
+	assert.True(t, handler.EventActive(rm, params))
+
+	// 單相設定檔沒有電能品質暫存器，等同 normal
+	single := DefaultRegisterMap()
+	handler.Update(single, params)
+	raw, err := single.ReadHoldingRegister(AddrVoltageTHD)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(0), raw)
+}
+
+// chunkRecorder 記錄每次 Write 的內容
+type chunkRecorder struct {
+	chunks [][]byte
+}
+
+func (r *chunkRecorder) Write(p []byte) (int, error) {
+	r.chunks = append(r.chunks, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func TestSlowDrainScenario_WriteResponse(t *testing.T) {
+	handler := &SlowDrainScenario{}
+	response := []byte{0x00, 0x01, 0x00, 0x00, 0x00, 0x05}
+	rec := &chunkRecorder{}
+
+	start := time.Now()
+	err := handler.WriteResponse(rec, response, ScenarioParams{DrainRate: 100})
+	require.NoError(t, err)
+
+	// 逐位元組寫出，6 bytes @100 B/s 至少 50ms
+	assert.Len(t, rec.chunks, len(response))
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestFragmentedResponseScenario_WriteResponse(t *testing.T) {
+	handler := &FragmentedResponseScenario{}
+	response := []byte{0x00, 0x01, 0x00, 0x00, 0x00, 0x05, 0x01, 0x03, 0x02, 0x08, 0x98}
+	rec := &chunkRecorder{}
+
+	err := handler.WriteResponse(rec, response, ScenarioParams{FragmentSize: 4, FragmentDelay: time.Millisecond})
+	require.NoError(t, err)
+
+	// 11 bytes 每段 4 bytes -> 4+4+3
+	require.Len(t, rec.chunks, 3)
+	assert.Len(t, rec.chunks[2], 3)
+	assert.Equal(t, response, append(append(rec.chunks[0], rec.chunks[1]...), rec.chunks[2]...))
+}
+
+func TestExceptionStormScenario_InjectException(t *testing.T) {
+	handler := &ExceptionStormScenario{}
+	params := ScenarioParams{
+		ExceptionRate:    0.5,
+		ExceptionWeights: map[string]float64{"slave_device_busy": 1},
+	}
+
+	injected := 0
+	for i := 0; i < 2000; i++ {
+		code, ok := handler.InjectException(FuncCodeReadHoldingRegisters, params)
+		if ok {
+			injected++
+			assert.Equal(t, uint8(ExceptionCodeSlaveDeviceBusy), code)
+		}
+	}
+	assert.InDelta(t, 1000, injected, 150, "約一半請求應回應例外")
+
+	// exception_rate = 1 時每次都回應例外
+	params.ExceptionRate = 1
+	_, ok := handler.InjectException(FuncCodeReadHoldingRegisters, params)
+	assert.True(t, ok)
+}
+
+func TestCorruptResponse(t *testing.T) {
+	// Read Holding Registers 回應: TID=1, Len=5, Unit=1, FC=3, ByteCount=2, 0x0898
+	response := []byte{0x00, 0x01, 0x00, 0x00, 0x00, 0x05, 0x01, 0x03, 0x02, 0x08, 0x98}
+	original := append([]byte(nil), response...)
+
+	out := corruptResponse(response, CorruptModeByteCount)
+	assert.Equal(t, byte(0x04), out[8], "Byte Count 應被改動")
+
+	out = corruptResponse(response, CorruptModeTransactionID)
+	assert.NotEqual(t, response[0:2], out[0:2], "Transaction ID 應不符")
+
+	out = corruptResponse(response, CorruptModeTruncate)
+	assert.Less(t, len(out), len(response), "訊框應被截斷")
+
+	out = corruptResponse(response, CorruptModeGarbage)
+	assert.Equal(t, response[:ModbusTCPHeaderLength], out[:ModbusTCPHeaderLength], "MBAP Header 應保留")
+
+	assert.Equal(t, original, response, "不應修改原始回應")
+}
+
+func TestLoadFactor(t *testing.T) {
+	at := func(clock string) time.Time {
+		tm, _ := time.Parse("15:04", clock)
+		return tm
+	}
+
+	// 餘弦曲線：尖峰 14 時為 load_max，離峰 2 時為 load_min
+	params := ScenarioParams{LoadMin: 0.3, LoadMax: 1.0, LoadPeakHour: 14}
+	assert.InDelta(t, 1.0, loadFactor(params, at("14:00")), 0.001)
+	assert.InDelta(t, 0.3, loadFactor(params, at("02:00")), 0.001)
+
+	// 分段線性內插，含跨午夜
+	params = ScenarioParams{LoadCurve: []LoadPoint{
+		{Hour: 6, Factor: 0.4},
+		{Hour: 18, Factor: 1.0},
+	}}
+	assert.InDelta(t, 0.7, loadFactor(params, at("12:00")), 0.001)
+	assert.InDelta(t, 0.7, loadFactor(params, at("00:00")), 0.001)
+	assert.InDelta(t, 0.4, loadFactor(params, at("06:00")), 0.001)
+}
+
+func TestBatteryModel_Update(t *testing.T) {
+	profile, ok := GetDeviceProfile(ProfileBattery)
+	require.True(t, ok)
+	registers, err := profile.NewRegisterMap()
+	require.NoError(t, err)
+
+	// 1 kWh 電池以 50 kW 充電
+	require.NoError(t, registers.SetScaledValue(AddrBatteryCapacity, 1))
+	require.NoError(t, registers.SetScaledValue(AddrBatterySetpoint, 50000))
+
+	model := &BatteryModel{}
+	now := time.Now()
+	model.Update(registers, now)
+
+	// 18 秒 = 250 Wh = 25%
+	model.Update(registers, now.Add(18*time.Second))
+	soc, _ := registers.GetScaledValue(AddrBatterySoC)
+	assert.InDelta(t, 75.0, soc, 0.1)
+	power, _ := registers.GetScaledValue(AddrBatteryPower)
+	assert.InDelta(t, 50000, power, 0.1)
+
+	// 充滿後限制在 100%，功率歸零
+	model.Update(registers, now.Add(60*time.Second))
+	model.Update(registers, now.Add(61*time.Second))
+	soc, _ = registers.GetScaledValue(AddrBatterySoC)
+	assert.InDelta(t, 100.0, soc, 0.01)
+	power, _ = registers.GetScaledValue(AddrBatteryPower)
+	assert.InDelta(t, 0, power, 0.1)
+
+	// 超過額定功率的放電設定值被限制
+	require.NoError(t, registers.SetScaledValue(AddrBatterySetpoint, -80000))
+	model.Update(registers, now.Add(62*time.Second))
+	power, _ = registers.GetScaledValue(AddrBatteryPower)
+	assert.InDelta(t, -50000, power, 0.1)
+	cycles, _ := registers.GetScaledValue(AddrBatteryCycles)
+	assert.Greater(t, cycles, 0.0)
+}
+
+func TestHVACModel_Update(t *testing.T) {
+	profile, ok := GetDeviceProfile(ProfileHVAC)
+	require.True(t, ok)
+	registers, err := profile.NewRegisterMap()
+	require.NoError(t, err)
+
+	model := &HVACModel{}
+	now := time.Now()
+	model.Update(registers, now)
+	enabled, _ := registers.ReadCoil(CoilHVACEnable)
+	assert.True(t, enabled)
+
+	// 出水 12 °C、設定值 7 °C：冷氣運轉，一個時間常數後溫差縮小約 63%
+	model.Update(registers, now.Add(hvacRunTimeConstant))
+	running, _ := registers.ReadCoil(CoilHVACCompressor)
+	assert.True(t, running)
+	supply, _ := registers.GetScaledValue(AddrHVACSupplyTemp)
+	assert.InDelta(t, 8.2, supply, 0.1)
+	cop, _ := registers.GetScaledValue(AddrHVACCOP)
+	assert.Greater(t, cop, 1.0)
+	thermal, _ := registers.GetScaledValue(AddrHVACThermalPower)
+	assert.InDelta(t, 30000, thermal, 0.1)
+	power, _ := registers.GetScaledValue(AddrHVACCompressorPower)
+	assert.InDelta(t, thermal/cop, power, 100)
+
+	// 越過設定值後停機，水溫回升
+	model.Update(registers, now.Add(20*time.Minute))
+	model.Update(registers, now.Add(20*time.Minute+time.Second))
+	running, _ = registers.ReadCoil(CoilHVACCompressor)
+	assert.False(t, running)
+	supply, _ = registers.GetScaledValue(AddrHVACSupplyTemp)
+	assert.Less(t, supply, 7.0)
+	model.Update(registers, now.Add(21*time.Minute))
+	warmer, _ := registers.GetScaledValue(AddrHVACSupplyTemp)
+	assert.Greater(t, warmer, supply)
+	power, _ = registers.GetScaledValue(AddrHVACCompressorPower)
+	assert.InDelta(t, 0, power, 0.1)
+
+	// 提高設定值為暖氣 45 °C，水溫朝設定值上升；關閉運轉許可後壓縮機停機
+	require.NoError(t, registers.SetScaledValue(AddrHVACSetpoint, 45))
+	model.Update(registers, now.Add(22*time.Minute))
+	running, _ = registers.ReadCoil(CoilHVACCompressor)
+	assert.True(t, running)
+	model.Update(registers, now.Add(30*time.Minute))
+	supply, _ = registers.GetScaledValue(AddrHVACSupplyTemp)
+	assert.Greater(t, supply, 40.0)
+	ret, _ := registers.GetScaledValue(AddrHVACReturnTemp)
+	assert.Less(t, ret, supply)
+
+	require.NoError(t, registers.WriteCoil(CoilHVACEnable, false))
+	model.Update(registers, now.Add(31*time.Minute))
+	running, _ = registers.ReadCoil(CoilHVACCompressor)
+	assert.False(t, running)
+}
+
+func TestHVACModel_RestoreState(t *testing.T) {
+	base := NewVirtualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	restore := setScenarioSources(base, newLockedRand(1))
+	defer restore()
+
+	profile, ok := GetDeviceProfile(ProfileHVAC)
+	require.True(t, ok)
+	newSlave := func() *Slave {
+		rm, err := profile.NewRegisterMap()
+		require.NoError(t, err)
+		config := DefaultConfig()
+		return NewSlave(net.ParseIP("127.0.0.1"), config.Server.Port, config,
+			WithLogger(zap.NewNop()), WithRegisters(rm), WithModel(profile.NewModel()))
+	}
+
+	// 關閉運轉許可後保存快照
+	source := newSlave()
+	require.NoError(t, source.registers.WriteCoil(CoilHVACEnable, false))
+	snap := source.Snapshot()
+
+	// 還原至新建的 Slave (預設啟用)：運轉許可維持關閉，壓縮機不啟動
+	slave := newSlave()
+	enabled, _ := slave.registers.ReadCoil(CoilHVACEnable)
+	require.True(t, enabled)
+	require.NoError(t, slave.RestoreSnapshot(snap))
+	for i := 0; i < 3; i++ {
+		slave.updateByScenario()
+		base.Advance(time.Minute)
+	}
+	enabled, _ = slave.registers.ReadCoil(CoilHVACEnable)
+	assert.False(t, enabled)
+	running, _ := slave.registers.ReadCoil(CoilHVACCompressor)
+	assert.False(t, running)
+}
+
+func TestGensetModel_Update(t *testing.T) {
+	profile, ok := GetDeviceProfile(ProfileGenset)
+	require.True(t, ok)
+	registers, err := profile.NewRegisterMap()
+	require.NoError(t, err)
+
+	model := &GensetModel{}
+	now := time.Now()
+	model.Update(registers, now)
+
+	// 寫入起動線圈：起動 5 秒後運轉，再 10 秒升至額定輸出
+	require.NoError(t, registers.WriteCoil(CoilGensetStart, true))
+	model.Update(registers, now.Add(time.Second))
+	state, _ := registers.GetScaledValue(AddrGensetState)
+	assert.Equal(t, float64(GensetCranking), state)
+	speed, _ := registers.GetScaledValue(AddrGensetSpeed)
+	assert.Equal(t, gensetCrankSpeed, speed)
+
+	model.Update(registers, now.Add(11*time.Second))
+	state, _ = registers.GetScaledValue(AddrGensetState)
+	assert.Equal(t, float64(GensetRunning), state)
+	power, _ := registers.GetScaledValue(AddrGensetPower)
+	assert.InDelta(t, 50000, power, 0.1)
+	running, _ := registers.ReadCoil(CoilGensetRunning)
+	assert.True(t, running)
+
+	// 額定輸出運轉 1 小時：運轉時數與油耗 (滿油箱可運轉 8 小時)
+	model.Update(registers, now.Add(16*time.Second))
+	state, _ = registers.GetScaledValue(AddrGensetState)
+	assert.Equal(t, float64(GensetRated), state)
+	model.Update(registers, now.Add(16*time.Second+time.Hour))
+	hours, _ := registers.GetScaledValue(AddrGensetRunHours)
+	assert.InDelta(t, 1.0, hours, 0.05)
+	fuel, _ := registers.GetScaledValue(AddrGensetFuelLevel)
+	assert.InDelta(t, 87.5, fuel, 0.2)
+
+	// 起動線圈 OFF 後停機
+	require.NoError(t, registers.WriteCoil(CoilGensetStart, false))
+	model.Update(registers, now.Add(2*time.Hour))
+	state, _ = registers.GetScaledValue(AddrGensetState)
+	assert.Equal(t, float64(GensetStopped), state)
+	speed, _ = registers.GetScaledValue(AddrGensetSpeed)
+	assert.Equal(t, 0.0, speed)
+	running, _ = registers.ReadCoil(CoilGensetRunning)
+	assert.False(t, running)
+
+	// 燃油耗盡時無法起動；經管理 API 加油後可再起動
+	require.NoError(t, registers.SetScaledValue(AddrGensetFuelLevel, 0))
+	require.NoError(t, registers.WriteCoil(CoilGensetStart, true))
+	model.Update(registers, now.Add(2*time.Hour+time.Second))
+	state, _ = registers.GetScaledValue(AddrGensetState)
+	assert.Equal(t, float64(GensetStopped), state)
+	require.NoError(t, registers.SetScaledValue(AddrGensetFuelLevel, 50))
+	model.Update(registers, now.Add(2*time.Hour+2*time.Second))
+	state, _ = registers.GetScaledValue(AddrGensetState)
+	assert.Equal(t, float64(GensetCranking), state)
+}
+
+func TestSunSpecProfiles(t *testing.T) {
+	for name, models := range map[string][]uint16{
+		ProfileSunSpecInverter: {SunSpecModelCommon, SunSpecModelInverter},
+		ProfileSunSpecMeter:    {SunSpecModelCommon, SunSpecModelMeter},
+	} {
+		t.Run(name, func(t *testing.T) {
+			profile, ok := GetDeviceProfile(name)
+			require.True(t, ok)
+			require.NoError(t, profile.Validate())
+			registers, err := profile.NewRegisterMap()
+			require.NoError(t, err)
+			model := profile.NewModel()
+
+			// SunS 標記後依 ID/L 走訪模型鏈直到 0xFFFF
+			marker, err := registers.ReadHoldingRegisters(SunSpecBaseAddress, 2)
+			require.NoError(t, err)
+			assert.Equal(t, []uint16{0x5375, 0x6E53}, marker)
+			var chain []uint16
+			for address := SunSpecBaseAddress + 2; ; {
+				header, err := registers.ReadHoldingRegisters(address, 2)
+				require.NoError(t, err)
+				if header[0] == 0xFFFF {
+					assert.Equal(t, uint16(0), header[1])
+					break
+				}
+				chain = append(chain, header[0])
+				address += 2 + header[1]
+			}
+			assert.Equal(t, models, chain)
+			header, _ := registers.ReadHoldingRegisters(SunSpecBaseAddress+2, 2)
+			assert.Equal(t, uint16(66), header[1])
+
+			// 場景改寫內部電表，不影響 SunS 標記與 Common 模型
+			scenario := &NormalScenario{}
+			scenario.Update(scenarioRegisters(registers, model), ScenarioParams{})
+			model.Update(registers, time.Now())
+			marker, _ = registers.ReadHoldingRegisters(SunSpecBaseAddress, 2)
+			assert.Equal(t, []uint16{0x5375, 0x6E53}, marker)
+			mn, _ := registers.GetString(SunSpecBaseAddress + 4)
+			assert.Equal(t, "ModbusSim", mn)
+
+			// 數值點位依比例因子換算
+			defs := make(map[string]uint16)
+			for _, def := range profile.Registers {
+				defs[def.Name] = def.Address
+			}
+			raw, _ := registers.ReadHoldingRegister(defs["PhVphA"])
+			sf, _ := registers.ReadHoldingRegister(defs["V_SF"])
+			assert.Equal(t, int16(-1), int16(sf))
+			assert.InDelta(t, DefaultNominalVoltage, float64(raw)/10, DefaultNominalVoltage*0.01)
+			power, _ := registers.GetScaledValue(defs["W"])
+			assert.InDelta(t, 3*DefaultNominalVoltage*DefaultNominalCurrent*0.95, power, 300)
+			hz, _ := registers.GetScaledValue(defs["Hz"])
+			assert.InDelta(t, DefaultNominalFrequency, hz, 0.1)
+		})
+	}
+}
+
 func TestScenarioEngine(t *testing.T) {
 	engine := NewScenarioEngine(1 * time.Second)
 
@@ -179,3 +698,1327 @@ func BenchmarkNormalScenario_Update(b *testing.B) {
 		handler.Update(rm, params)
 	}
 }
+
+// newTickSlaves 建立 n 個共用 single_phase 基準的 Slave (不啟動)，並完成首次同步
+func newTickSlaves(tb testing.TB, n int) []*Slave {
+	tb.Helper()
+	profile, ok := GetDeviceProfile("single_phase")
+	require.True(tb, ok)
+	baseline, err := profile.NewRegisterMap()
+	require.NoError(tb, err)
+
+	config := DefaultConfig()
+	slaves := make([]*Slave, n)
+	for i := range slaves {
+		slaves[i] = NewSlave(nil, config.Server.Port, config, WithRegisters(baseline.Clone()))
+		slaves[i].updateByScenario()
+	}
+	return slaves
+}
+
+func TestSlave_SyncRegistersPerTick(t *testing.T) {
+	slaves := newTickSlaves(t, 1000)
+
+	// 每個週期只同步寫入過的頁面，沿用既有的記憶體，不配置
+	allocs := testing.AllocsPerRun(10, func() {
+		for _, s := range slaves {
+			s.registers.SetScaledValue(40001, 230)
+			s.mu.Lock()
+			s.syncRegistersToServer()
+			s.mu.Unlock()
+		}
+	})
+	assert.Zero(t, allocs)
+
+	// 同步後對外提供的暫存器與映射表一致
+	s := slaves[0]
+	assert.Equal(t, s.registers.GetRawHoldingRegisters(), s.image.holding.Slice(0, s.image.holding.Len()))
+	assert.Equal(t, 1, s.image.holding.Pages())
+}
+
+func TestSlave_Blink(t *testing.T) {
+	config := DefaultConfig()
+	slave := NewSlave(net.ParseIP("127.0.0.1"), config.Server.Port, config, WithLogger(zap.NewNop()))
+	_, err := slave.Blink(BlinkOptions{})
+	assert.Error(t, err, "未啟動的 Slave 不可閃爍")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	slave.scenarioCtx = ctx
+	rm := slave.registers
+	holding := func(address uint16) uint16 {
+		value, err := rm.ReadHoldingRegister(address)
+		require.NoError(t, err)
+		return value
+	}
+	coil := func(address uint16) bool {
+		value, err := rm.ReadCoil(address)
+		require.NoError(t, err)
+		return value
+	}
+
+	// 預設：DefaultBlinkRegister 依樣式輪替，不切換任何線圈
+	require.NoError(t, rm.WriteHoldingRegister(DefaultBlinkRegister, 0x0102))
+	status, err := slave.Blink(BlinkOptions{Period: time.Hour})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultBlinkRegister, status.Register)
+	assert.Equal(t, -1, status.Coil)
+	assert.Equal(t, DefaultBlinkPattern[0], holding(DefaultBlinkRegister))
+	assert.False(t, coil(0))
+	slave.StopBlink()
+	assert.Equal(t, uint16(0x0102), holding(DefaultBlinkRegister))
+
+	// 可指定暫存器 0 與線圈；重新開始閃爍時仍還原為最初的值
+	register, coilAddress := uint16(0), uint16(3)
+	require.NoError(t, rm.WriteHoldingRegister(register, 0x1234))
+	opts := BlinkOptions{Period: time.Hour, Register: &register, Coil: &coilAddress}
+	status, err = slave.Blink(opts)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(0), status.Register)
+	assert.Equal(t, 3, status.Coil)
+	assert.Equal(t, DefaultBlinkPattern[0], holding(register))
+	assert.True(t, coil(coilAddress))
+	_, err = slave.Blink(opts)
+	require.NoError(t, err)
+	slave.StopBlink()
+	assert.Equal(t, uint16(0x1234), holding(register))
+	assert.False(t, coil(coilAddress))
+
+	// 逾時後自動還原
+	_, err = slave.Blink(BlinkOptions{Duration: 50 * time.Millisecond, Period: 10 * time.Millisecond, Register: &register, Coil: &coilAddress})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		slave.mu.Lock()
+		defer slave.mu.Unlock()
+		return slave.blink == nil
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, uint16(0x1234), holding(register))
+	assert.False(t, coil(coilAddress))
+}
+
+func TestSlave_UnitRouting(t *testing.T) {
+	single, ok := GetDeviceProfile(ProfileSinglePhase)
+	require.True(t, ok)
+	three, ok := GetDeviceProfile(ProfileThreePhase)
+	require.True(t, ok)
+	primary, err := single.NewRegisterMap()
+	require.NoError(t, err)
+	meter, err := three.NewRegisterMap()
+	require.NoError(t, err)
+	require.NoError(t, primary.WriteHoldingRegister(9, 111))
+	require.NoError(t, meter.WriteHoldingRegister(9, 222))
+
+	logger := zap.NewNop()
+	s := NewSlave(nil, 502, DefaultConfig(), WithLogger(logger), WithRegisters(primary), WithUnitID(1), WithUnit(2, meter, nil))
+	s.mu.Lock()
+	s.syncRegistersToServer()
+	s.mu.Unlock()
+
+	read := func(unitID uint8) ([]byte, bool) {
+		packet := []byte{0, 1, 0, 0, 0, 6, unitID, FuncCodeReadHoldingRegisters, 0, 9, 0, 1}
+		response, _, dropped := s.handler.Handle(newTCPFrame(packet))
+		return response, dropped
+	}
+
+	// 各 Unit ID 讀到各自的暫存器
+	response, dropped := read(1)
+	require.False(t, dropped)
+	assert.Equal(t, uint16(111), binary.BigEndian.Uint16(response[9:11]))
+	response, dropped = read(2)
+	require.False(t, dropped)
+	assert.Equal(t, uint8(2), response[6])
+	assert.Equal(t, uint16(222), binary.BigEndian.Uint16(response[9:11]))
+
+	// 未配置的 Unit ID 不回應
+	_, dropped = read(3)
+	assert.True(t, dropped)
+
+	// 場景更新同時更新邏輯設備
+	before := meter.GetRawHoldingRegisters()
+	s.updateByScenario()
+	assert.NotEqual(t, before, meter.GetRawHoldingRegisters())
+	s.mu.RLock()
+	assert.Equal(t, meter.GetRawHoldingRegisters(), s.units[2].image.holding.Slice(0, s.units[2].image.holding.Len()))
+	s.mu.RUnlock()
+
+	// 未配置邏輯設備時回應任何 Unit ID
+	single2 := NewSlave(nil, 502, DefaultConfig(), WithLogger(logger))
+	_, _, dropped = single2.handler.Handle(newTCPFrame([]byte{0, 1, 0, 0, 0, 6, 9, FuncCodeReadHoldingRegisters, 0, 0, 0, 1}))
+	assert.False(t, dropped)
+}
+
+func TestSlave_UnknownUnitGatewayException(t *testing.T) {
+	config := DefaultConfig()
+	config.Slaves.UnknownUnit = UnknownUnitGatewayException
+	config.Slaves.DownstreamTimeout = 50 * time.Millisecond
+	s := NewSlave(nil, 502, config, WithLogger(zap.NewNop()), WithUnitID(1), WithUnit(2, DefaultRegisterMap(), nil))
+	s.mu.Lock()
+	s.syncRegistersToServer()
+	s.mu.Unlock()
+
+	// 未配置的 Unit ID 等待下游逾時後回應 Gateway Target Device Failed to Respond
+	start := time.Now()
+	response, hasError, dropped := s.handler.Handle(newTCPFrame([]byte{0, 7, 0, 0, 0, 6, 5, FuncCodeReadHoldingRegisters, 0, 0, 0, 1}))
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	assert.False(t, dropped)
+	assert.True(t, hasError)
+	assert.Equal(t, []byte{0, 7, 0, 0, 0, 3, 5, 0x83, ExceptionCodeGatewayTargetNoResponse}, response)
+
+	// 已配置的 Unit ID 照常回應
+	_, hasError, dropped = s.handler.Handle(newTCPFrame([]byte{0, 8, 0, 0, 0, 6, 2, FuncCodeReadHoldingRegisters, 0, 0, 0, 1}))
+	assert.False(t, dropped)
+	assert.False(t, hasError)
+}
+
+func TestScenarioTimeline(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	timeline := NewScenarioTimeline(ScenarioNormal, start)
+
+	usage := func(now time.Time, scenario ScenarioType) ScenarioUsage {
+		for _, u := range timeline.Usage(now) {
+			if u.Scenario == scenario {
+				return u
+			}
+		}
+		t.Fatalf("缺少場景 %s", scenario)
+		return ScenarioUsage{}
+	}
+
+	assert.True(t, timeline.Enter(ScenarioVoltageSag, start.Add(10*time.Second)))
+	assert.False(t, timeline.Enter(ScenarioVoltageSag, start.Add(15*time.Second)), "重複套用相同場景不算切換")
+
+	sag := usage(start.Add(40*time.Second), ScenarioVoltageSag)
+	assert.True(t, sag.Active)
+	assert.Equal(t, uint64(1), sag.Transitions)
+	assert.Equal(t, 30*time.Second, sag.Time, "目前場景的停留時間計至查詢當下")
+
+	assert.True(t, timeline.Enter(ScenarioNormal, start.Add(50*time.Second)))
+	normal := usage(start.Add(60*time.Second), ScenarioNormal)
+	assert.True(t, normal.Active)
+	assert.Equal(t, uint64(1), normal.Transitions, "初始場景不算切換")
+	assert.Equal(t, 20*time.Second, normal.Time)
+	assert.Equal(t, 40*time.Second, usage(start.Add(60*time.Second), ScenarioVoltageSag).Time)
+	assert.Len(t, timeline.Usage(start), len(ScenarioTypes()))
+}
+
+func TestSlave_RequestRateLimit(t *testing.T) {
+	s := NewSlave(nil, 502, DefaultConfig(), WithLogger(zap.NewNop()), WithUnitID(1), WithRequestRateLimit(10, 2))
+	s.mu.Lock()
+	s.syncRegistersToServer()
+	s.mu.Unlock()
+	read := func() []byte {
+		response, _, dropped := s.handler.Handle(newTCPFrame([]byte{0, 1, 0, 0, 0, 6, 1, FuncCodeReadHoldingRegisters, 0, 0, 0, 1}))
+		require.False(t, dropped)
+		return response
+	}
+
+	// burst 內照常回應，超過後回應 Slave Device Busy
+	assert.Equal(t, byte(FuncCodeReadHoldingRegisters), read()[7])
+	assert.Equal(t, byte(FuncCodeReadHoldingRegisters), read()[7])
+	assert.Equal(t, []byte{0, 1, 0, 0, 0, 3, 1, 0x83, ExceptionCodeSlaveDeviceBusy}, read())
+	assert.Equal(t, uint64(1), s.stats.RateLimited.Load())
+
+	// 每秒補充 10 個 token
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, byte(FuncCodeReadHoldingRegisters), read()[7])
+}
+
+func BenchmarkSlave_UpdateByScenario(b *testing.B) {
+	slaves := newTickSlaves(b, 1000)
+
+	// 每次迭代為 1000 個 Slave 的一個更新週期
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, s := range slaves {
+			s.updateByScenario()
+		}
+	}
+}
+
+func TestRandomizeNominal(t *testing.T) {
+	random := newLockedRand(1)
+	cfg := NominalRandomization{Enabled: true}
+
+	voltages := make(map[float64]bool)
+	energies := make(map[float64]bool)
+	var currentSum float64
+	const n = 500
+	for i := 0; i < n; i++ {
+		rm := DefaultRegisterMap()
+		nominal := randomizeNominal(rm, cfg, random)
+		assert.InDelta(t, 220.0, nominal.Voltage, 2.0)
+		assert.GreaterOrEqual(t, nominal.Current, 0.0)
+		assert.Equal(t, nominal, rm.Nominal())
+
+		voltage, _ := rm.GetScaledValue(40001)
+		energy, _ := rm.GetScaledValue(40004)
+		assert.LessOrEqual(t, energy, 10000.0)
+		voltages[voltage] = true
+		energies[energy] = true
+		currentSum += nominal.Current
+	}
+	assert.Greater(t, len(voltages), 20, "電壓應分散")
+	assert.Greater(t, len(energies), n*9/10, "初始電能應幾乎各不相同")
+	assert.InDelta(t, 15.5, currentSum/n, 0.5)
+}
+
+func TestNormalScenario_PerMapNominal(t *testing.T) {
+	handler := &NormalScenario{}
+	params := ScenarioParams{VoltageVariance: 0.005}
+
+	// 以各映射表的額定值為中心波動，電能各自累計
+	a, b := DefaultRegisterMap(), DefaultRegisterMap()
+	a.SetNominal(MeterNominal{Voltage: 230, Current: 10, Frequency: 50})
+	require.NoError(t, b.SetScaledValue(40004, 5000))
+
+	handler.Update(a, params)
+	handler.Update(b, params)
+
+	voltage, _ := a.GetScaledValue(40001)
+	assert.InDelta(t, 230.0, voltage, 230*0.005+0.1)
+	frequency, _ := a.GetScaledValue(40003)
+	assert.InDelta(t, 50.0, frequency, 0.1)
+	voltage, _ = b.GetScaledValue(40001)
+	assert.InDelta(t, 220.0, voltage, 220*0.005+0.1)
+
+	energyA, _ := a.GetScaledValue(40004)
+	energyB, _ := b.GetScaledValue(40004)
+	assert.Less(t, energyA, 1.0)
+	assert.GreaterOrEqual(t, energyB, 5000.0)
+
+	// Reset 回到額定值
+	handler.Reset(a)
+	voltage, _ = a.GetScaledValue(40001)
+	assert.InDelta(t, 230.0, voltage, 0.1)
+}
+
+func TestNormalScenario_RestoreEnergy(t *testing.T) {
+	handler := &NormalScenario{}
+	h := NewScenarioHarness(handler)
+	defer h.Close()
+	h.Run(time.Hour, time.Minute)
+
+	// 還原後自還原值 (含暫存器捨去的小數) 接續累積，而非場景保存的舊值
+	RestoreEnergy(h.Registers(), 1234.75)
+	h.Step(time.Minute)
+	energy, ok := handler.Energy(h.Registers())
+	require.True(t, ok)
+	assert.Greater(t, energy, 1234.75)
+	assert.Less(t, energy, 1235.0)
+	reading, _ := h.Registers().GetScaledValue(40004)
+	assert.Equal(t, 1234.0, reading)
+
+	// 同一次還原只套用一次
+	h.Step(time.Hour)
+	next, _ := handler.Energy(h.Registers())
+	assert.Greater(t, next, energy+1)
+}
+
+func TestSlaveSeed_Reproducible(t *testing.T) {
+	config := DefaultConfig()
+	newSeeded := func(seed int64) *Slave {
+		s := NewSlave(net.ParseIP("10.0.0.1"), config.Server.Port, config, WithSeed(seed), WithLogger(zap.NewNop()))
+		randomizeNominal(s.registers, NominalRandomization{Enabled: true}, newLockedRand(slaveSeed(seed, s.ID, randomStreamNominal)))
+		return s
+	}
+	trace := func(s *Slave) (values [][]uint16, drops []bool) {
+		for i := 0; i < 5; i++ {
+			s.updateByScenario()
+			raw, err := s.registers.ReadHoldingRegisters(40001, 3)
+			require.NoError(t, err)
+			values = append(values, raw)
+			s.handler.SetPacketLoss(0.5)
+			drops = append(drops, s.handler.shouldDropPacket())
+		}
+		return values, drops
+	}
+
+	// 相同種子與 Slave ID：額定值、量測值波動與封包丟失的序列相同
+	valuesA, dropsA := trace(newSeeded(42))
+	valuesB, dropsB := trace(newSeeded(42))
+	assert.Equal(t, valuesA, valuesB)
+	assert.Equal(t, dropsA, dropsB)
+	assert.Contains(t, dropsA, true)
+	assert.Contains(t, dropsA, false)
+
+	// 不同種子產生不同的序列
+	valuesC, _ := trace(newSeeded(43))
+	assert.NotEqual(t, valuesA, valuesC)
+
+	// 同一種子下各 Slave 的序列互不相同
+	assert.NotEqual(t, slaveSeed(42, "10.0.0.1:502", randomStreamScenario), slaveSeed(42, "10.0.0.2:502", randomStreamScenario))
+	assert.NotEqual(t, slaveSeed(42, "10.0.0.1:502", randomStreamScenario), slaveSeed(42, "10.0.0.1:502", randomStreamRequests))
+}
+
+// countingModel 記錄更新次數的設備模型
+type countingModel struct {
+	updates atomic.Int64
+}
+
+func (m *countingModel) Update(*RegisterMap, time.Time) {
+	m.updates.Add(1)
+}
+
+func TestScenarioUpdater(t *testing.T) {
+	updater := newScenarioUpdater(1, 100, zap.NewNop())
+	defer updater.Close()
+
+	// 250 個 Slave 分成三批，在 100ms 的週期內錯開更新
+	config := DefaultConfig()
+	models := make([]*countingModel, 250)
+	slaves := make([]*Slave, len(models))
+	for i := range slaves {
+		models[i] = &countingModel{}
+		slaves[i] = NewSlave(nil, config.Server.Port, config, WithModel(models[i]))
+		updater.add(slaves[i], 100*time.Millisecond)
+	}
+
+	require.Eventually(t, func() bool {
+		for _, m := range models {
+			if m.updates.Load() < 2 {
+				return false
+			}
+		}
+		return true
+	}, 2*time.Second, 20*time.Millisecond)
+
+	// 移除後不再更新 (已送出的批次最多再更新一次)
+	updater.remove(slaves[0])
+	removed := models[0].updates.Load()
+	kept := models[1].updates.Load()
+	time.Sleep(300 * time.Millisecond)
+	assert.LessOrEqual(t, models[0].updates.Load(), removed+1)
+	assert.Greater(t, models[1].updates.Load(), kept)
+}
+
+func TestDataFreezeScenario_Update(t *testing.T) {
+	registers := DefaultRegisterMap()
+	require.NoError(t, registers.SetScaledValue(40001, 231.0))
+	require.NoError(t, registers.SetScaledValue(40007, 4200))
+
+	scenario := &DataFreezeScenario{}
+	scenario.Activate(registers)
+
+	params := ScenarioParams{
+		FreezeRegisters:   []string{"LineVoltage", "ActivePower"},
+		TimestampRegister: 40020,
+	}
+	for i := 0; i < 20; i++ {
+		scenario.Update(registers, params)
+	}
+
+	voltage, err := registers.GetScaledValue(40001)
+	require.NoError(t, err)
+	assert.InDelta(t, 231.0, voltage, 0.01, "凍結的電壓不應變動")
+
+	power, err := registers.GetScaledValue(40007)
+	require.NoError(t, err)
+	assert.InDelta(t, 4200, power, 0.1, "凍結的功率不應變動")
+
+	words, err := registers.ReadHoldingRegisters(40020, 2)
+	require.NoError(t, err)
+	ts := int64(uint32(words[0])<<16 | uint32(words[1]))
+	assert.InDelta(t, time.Now().Unix(), ts, 5, "時間戳應停在凍結時刻")
+}
+
+func TestScriptScenario_Update(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "device.lua")
+	require.NoError(t, os.WriteFile(path, []byte(`
+local ticks = 0
+function Update(regs, t, dt)
+  ticks = ticks + 1
+  regs.set("LineVoltage", 200 + ticks)
+  regs.set(40002, 10)
+  regs.set_coil(0, ticks % 2 == 1)
+end
+function Reset(regs)
+  regs.set("LineVoltage", 123)
+end
+`), 0o644))
+	require.NoError(t, ValidateScript(path))
+
+	registers := DefaultRegisterMap()
+	scenario := &ScriptScenario{}
+	params := ScenarioParams{Script: path}
+
+	scenario.Update(registers, params)
+	scenario.Update(registers, params)
+	voltage, _ := registers.GetScaledValue(40001)
+	assert.InDelta(t, 202.0, voltage, 0.01, "腳本應在每次更新時執行")
+	coil, _ := registers.ReadCoil(0)
+	assert.False(t, coil)
+
+	// 衍生暫存器依腳本寫入的值重新計算 (202V × 10A × 0.95)
+	power, _ := registers.GetScaledValue(40007)
+	assert.InDelta(t, 1919.0, power, 0.1)
+
+	// 重新進入場景時重新載入腳本 (狀態歸零)
+	scenario.Activate(registers)
+	scenario.Update(registers, params)
+	voltage, _ = registers.GetScaledValue(40001)
+	assert.InDelta(t, 201.0, voltage, 0.01)
+
+	scenario.Reset(registers)
+	voltage, _ = registers.GetScaledValue(40001)
+	assert.InDelta(t, 123.0, voltage, 0.01, "應呼叫腳本的 Reset")
+
+	// 執行錯誤後停止呼叫腳本
+	require.NoError(t, os.WriteFile(path, []byte(`function Update(regs) regs.set("NoSuchRegister", 1) end`), 0o644))
+	scenario.Update(registers, params)
+	require.NoError(t, registers.SetScaledValue(40001, 220))
+	scenario.Update(registers, params)
+	voltage, _ = registers.GetScaledValue(40001)
+	assert.InDelta(t, 220.0, voltage, 0.01)
+
+	assert.Error(t, ValidateScript(filepath.Join(t.TempDir(), "missing.lua")))
+	assert.NoError(t, ValidateScript("scripts/solar_inverter.lua"), "範例腳本應可載入")
+}
+
+// testPlugin 測試用外掛：將 LineVoltage 設為 200 + 呼叫次數，Reset 時寫入 options 的 voltage
+type testPlugin struct {
+	updates int
+	options map[string]string
+}
+
+func servePluginForTest(t *testing.T, plugin *testPlugin) string {
+	t.Helper()
+
+	handler := func(method string) grpc.MethodDesc {
+		return grpc.MethodDesc{
+			MethodName: method,
+			Handler: func(_ any, _ context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				if method == "Describe" {
+					return &pluginDescribeResponse{Name: "test"}, dec(&pluginDescribeRequest{})
+				}
+				req := &pluginUpdateRequest{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				plugin.options = req.Options
+				if method == "Reset" {
+					return &pluginUpdateResponse{Writes: []pluginRegister{{Address: 40001, Value: 123}}}, nil
+				}
+				plugin.updates++
+				return &pluginUpdateResponse{Writes: []pluginRegister{
+					{Name: "LineVoltage", Value: float64(200 + plugin.updates)},
+					{Address: 40002, Value: 10},
+				}}, nil
+			},
+		}
+	}
+
+	server := grpc.NewServer(grpc.ForceServerCodec(pluginCodec{}))
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: pluginServiceName,
+		HandlerType: (*any)(nil),
+		Methods:     []grpc.MethodDesc{handler("Describe"), handler("Update"), handler("Reset")},
+	}, plugin)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+func TestPluginScenario_Update(t *testing.T) {
+	plugin := &testPlugin{}
+	address := servePluginForTest(t, plugin)
+	require.NoError(t, ValidatePluginAddress(address))
+
+	registers := DefaultRegisterMap()
+	scenario := &PluginScenario{}
+	params := ScenarioParams{Plugin: address, PluginOptions: map[string]string{"model": "x1"}}
+
+	scenario.Update(registers, params)
+	scenario.Update(registers, params)
+	voltage, _ := registers.GetScaledValue(40001)
+	assert.InDelta(t, 202.0, voltage, 0.01, "應套用外掛回傳的寫入")
+	assert.Equal(t, map[string]string{"model": "x1"}, plugin.options)
+
+	// 衍生暫存器依外掛寫入的值重新計算 (202V × 10A × 0.95)
+	power, _ := registers.GetScaledValue(40007)
+	assert.InDelta(t, 1919.0, power, 0.1)
+
+	scenario.Reset(registers)
+	voltage, _ = registers.GetScaledValue(40001)
+	assert.InDelta(t, 123.0, voltage, 0.01, "應呼叫外掛的 Reset")
+
+	// 外掛無法連線時不變更暫存器
+	down := ScenarioParams{Plugin: "127.0.0.1:1"}
+	other := DefaultRegisterMap()
+	before, _ := other.GetScaledValue(40001)
+	scenario.Update(other, down)
+	after, _ := other.GetScaledValue(40001)
+	assert.Equal(t, before, after)
+
+	for _, invalid := range []string{"localhost", "host:port", "unix://"} {
+		assert.Error(t, ValidatePluginAddress(invalid), invalid)
+	}
+	assert.NoError(t, ValidatePluginAddress("unix:///tmp/plugin.sock"))
+}
+
+func TestCluster_JoinAndReport(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Network.IPRanges = []IPRange{{Start: "10.1.0.1", End: "10.1.0.5"}}
+	cfg.Slaves.UnitIDStart = 10
+
+	coordinator, err := NewCoordinator(cfg, zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, coordinator.Start("127.0.0.1:0"))
+	t.Cleanup(coordinator.Stop)
+
+	newAgent := func(id string, count int) (*ClusterAgent, *Config) {
+		agentCfg := DefaultConfig()
+		agentCfg.Cluster.Coordinator = coordinator.Addr().String()
+		agentCfg.Cluster.AgentID = id
+		agentCfg.Slaves.Count = count
+		agent, err := NewClusterAgent(agentCfg, zap.NewNop())
+		require.NoError(t, err)
+		t.Cleanup(func() { agent.Close() })
+		return agent, agentCfg
+	}
+	ctx := context.Background()
+
+	// 依加入順序分段分配，Unit ID 接續前一個 agent
+	a, aCfg := newAgent("a", 3)
+	require.NoError(t, a.Join(ctx))
+	assert.Equal(t, []IPRange{{Start: "10.1.0.1", End: "10.1.0.3"}}, aCfg.Network.IPRanges)
+	assert.Equal(t, uint8(10), aCfg.Slaves.UnitIDStart)
+
+	b, bCfg := newAgent("b", 3)
+	require.NoError(t, b.Join(ctx))
+	assert.Equal(t, []IPRange{{Start: "10.1.0.4", End: "10.1.0.5"}}, bCfg.Network.IPRanges)
+	assert.Equal(t, 2, bCfg.Slaves.Count, "最後一段只剩 2 個 IP")
+	assert.Equal(t, uint8(13), bCfg.Slaves.UnitIDStart)
+
+	c, _ := newAgent("c", 3)
+	err = c.Join(ctx)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(errors.Unwrap(err)), "IP 已分配完畢")
+
+	// 重新加入取回相同的範圍
+	require.NoError(t, a.Join(ctx))
+	assert.Equal(t, []IPRange{{Start: "10.1.0.1", End: "10.1.0.3"}}, aCfg.Network.IPRanges)
+
+	// 回報指標並跟隨叢集場景
+	require.NoError(t, coordinator.SetScenario("voltage_sag"))
+	assert.Error(t, coordinator.SetScenario("blackout"))
+	engine := NewEngine(aCfg, zap.NewNop())
+	a.report(ctx, engine)
+	assert.Equal(t, ScenarioVoltageSag, engine.GetScenario())
+
+	clusterStatus := coordinator.Status()
+	assert.Equal(t, 5, clusterStatus.Assigned)
+	require.Len(t, clusterStatus.Agents, 2)
+	assert.True(t, clusterStatus.Agents[0].Up)
+	assert.Equal(t, "normal", clusterStatus.Agents[0].Scenario, "回報的是套用前的場景")
+
+	// 重新啟動的 coordinator 沿用 agent 先前的範圍
+	restarted, err := NewCoordinator(cfg, zap.NewNop())
+	require.NoError(t, err)
+	assignment, err := restarted.join(ctx, &clusterJoinRequest{AgentID: "b", Capacity: 3, Offset: 3})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(3), assignment.Offset)
+	assignment, err = restarted.join(ctx, &clusterJoinRequest{AgentID: "a", Capacity: 3, Offset: 0})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0), assignment.Offset)
+	assert.True(t, restarted.handleReport(&clusterReport{AgentID: "x"}).Rejoin, "未知的 agent 需重新加入")
+}
+
+func TestScenarioHarness(t *testing.T) {
+	// 電壓驟降依虛擬時間持續 10 秒後恢復
+	h := NewScenarioHarness(&VoltageSagScenario{}, WithHarnessParams(ScenarioParams{Duration: 10 * time.Second}))
+	h.Run(30*time.Second, time.Second)
+	voltage := h.Series("LineVoltage")
+	assert.Equal(t, 31, voltage.Len(), "初始值 + 每秒一點")
+	assert.Less(t, voltage.Between(time.Second, 10*time.Second).Max(), 180.0)
+	assert.Greater(t, voltage.Between(11*time.Second, 31*time.Second).Min(), 210.0)
+	h.Close()
+
+	// 正常場景 1 小時累積的電能 (功率約 3.2 kW)
+	h = NewScenarioHarness(&NormalScenario{})
+	h.Run(time.Hour, time.Minute)
+	energy := h.SeriesAt(40004)
+	assert.True(t, energy.Monotonic(), "電能只增不減")
+	assert.InDelta(t, 3.2, energy.Last(), 1.0)
+	assert.Equal(t, time.Hour, h.Elapsed())
+	first := h.Series("LineVoltage").Values()
+	h.Close()
+
+	// 相同種子的軌跡完全相同
+	h = NewScenarioHarness(&NormalScenario{})
+	h.Run(time.Hour, time.Minute)
+	assert.Equal(t, first, h.Series("LineVoltage").Values())
+	assert.Zero(t, h.Series("NoSuchRegister").Len())
+	h.Close()
+
+	// 日負載曲線依虛擬時鐘的時段變化 (預設 14:00 尖峰)
+	h = NewScenarioHarness(&LoadProfileScenario{})
+	h.Run(24*time.Hour, 10*time.Minute)
+	current := h.Series("LineCurrent")
+	assert.Greater(t, current.At(14*time.Hour), current.At(2*time.Hour))
+	h.Close()
+}
+
+// testCapture 產生 Ethernet/IPv4/TCP 的 pcap 擷取檔
+type testCapture struct {
+	buf  []byte
+	seq  map[bool]uint32
+	base time.Time
+}
+
+func newTestCapture() *testCapture {
+	c := &testCapture{seq: map[bool]uint32{true: 1000, false: 5000}, base: time.Unix(1700000000, 0)}
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header, 0xa1b2c3d4)
+	binary.LittleEndian.PutUint16(header[4:], 2)
+	binary.LittleEndian.PutUint16(header[6:], 4)
+	binary.LittleEndian.PutUint32(header[16:], 65535)
+	binary.LittleEndian.PutUint32(header[20:], 1)
+	c.buf = append(c.buf, header...)
+	return c
+}
+
+// frame 寫入一個 Modbus TCP 訊框 (toServer 為 Master -> Slave)
+func (c *testCapture) frame(offset time.Duration, toServer bool, tid uint16, unit uint8, pdu []byte) {
+	mbap := make([]byte, 7)
+	binary.BigEndian.PutUint16(mbap, tid)
+	binary.BigEndian.PutUint16(mbap[4:], uint16(len(pdu)+1))
+	mbap[6] = unit
+	payload := append(mbap, pdu...)
+
+	tcp := make([]byte, 20)
+	src, dst := uint16(40000), uint16(502)
+	if !toServer {
+		src, dst = dst, src
+	}
+	binary.BigEndian.PutUint16(tcp, src)
+	binary.BigEndian.PutUint16(tcp[2:], dst)
+	binary.BigEndian.PutUint32(tcp[4:], c.seq[toServer])
+	c.seq[toServer] += uint32(len(payload))
+	tcp[12] = 5 << 4
+	tcp[13] = 0x18
+
+	ip := make([]byte, 20)
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:], uint16(20+len(tcp)+len(payload)))
+	ip[9] = 6
+	client, server := []byte{10, 0, 0, 1}, []byte{10, 0, 0, 2}
+	if !toServer {
+		client, server = server, client
+	}
+	copy(ip[12:], client)
+	copy(ip[16:], server)
+
+	eth := make([]byte, 14)
+	binary.BigEndian.PutUint16(eth[12:], 0x0800)
+
+	packet := append(append(append(eth, ip...), tcp...), payload...)
+	at := c.base.Add(offset)
+	record := make([]byte, 16)
+	binary.LittleEndian.PutUint32(record, uint32(at.Unix()))
+	binary.LittleEndian.PutUint32(record[4:], uint32(at.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(record[8:], uint32(len(packet)))
+	binary.LittleEndian.PutUint32(record[12:], uint32(len(packet)))
+	c.buf = append(append(c.buf, record...), packet...)
+}
+
+func TestReplayScenario_Update(t *testing.T) {
+	c := newTestCapture()
+	// 讀取 FC03 40001-40002，兩次相同的輪詢只保留第一次
+	c.frame(0, true, 1, 1, []byte{0x03, 0, 0, 0, 2})
+	c.frame(10*time.Millisecond, false, 1, 1, []byte{0x03, 4, 0x08, 0x98, 0x05, 0xdc})
+	c.frame(time.Second, true, 2, 1, []byte{0x03, 0, 0, 0, 2})
+	c.frame(time.Second+10*time.Millisecond, false, 2, 1, []byte{0x03, 4, 0x08, 0x98, 0x05, 0xdc})
+	// 5 秒後電壓降為 180.0 V
+	c.frame(5*time.Second, true, 3, 1, []byte{0x03, 0, 0, 0, 2})
+	c.frame(5*time.Second+10*time.Millisecond, false, 3, 1, []byte{0x03, 4, 0x07, 0x08, 0x05, 0xdc})
+	// 例外回應與寫入線圈
+	c.frame(6*time.Second, true, 4, 1, []byte{0x04, 0, 0, 0, 1})
+	c.frame(6*time.Second+10*time.Millisecond, false, 4, 1, []byte{0x84, 0x02})
+	c.frame(7*time.Second, true, 5, 2, []byte{0x05, 0, 3, 0xff, 0x00})
+	c.frame(7*time.Second+10*time.Millisecond, false, 5, 2, []byte{0x05, 0, 3, 0xff, 0x00})
+
+	timeline, err := ReadCapture(bytes.NewReader(c.buf), 0)
+	require.NoError(t, err)
+	assert.Equal(t, CaptureStats{Packets: 10, Requests: 5, Responses: 5, Exceptions: 1}, timeline.Stats)
+	require.Len(t, timeline.Frames, 3)
+	assert.Equal(t, CaptureFrame{Offset: 10 * time.Millisecond, Unit: 1, Table: CaptureHoldingRegisters, Values: []uint16{2200, 1500}}, timeline.Frames[0])
+	assert.Equal(t, []uint16{1800, 1500}, timeline.Frames[1].Values)
+	assert.Equal(t, CaptureFrame{Offset: 7*time.Second + 10*time.Millisecond, Unit: 2, Table: CaptureCoils, Address: 3, Values: []uint16{1}}, timeline.Frames[2])
+	assert.Equal(t, []uint8{1, 2}, timeline.Units())
+
+	_, err = ReadCapture(bytes.NewReader([]byte("not a capture")), 0)
+	assert.Error(t, err)
+
+	// 時間軸存成 JSON 後重播，僅套用 Unit 1
+	path := filepath.Join(t.TempDir(), "timeline.json")
+	require.NoError(t, timeline.Save(path))
+	require.NoError(t, ValidateCapture(path, 0))
+
+	h := NewScenarioHarness(&ReplayScenario{}, WithHarnessParams(ScenarioParams{Capture: path, CaptureUnit: 1}))
+	defer h.Close()
+	h.Run(10*time.Second, time.Second)
+
+	voltage := h.Series("LineVoltage")
+	assert.Equal(t, 220.0, voltage.At(4*time.Second))
+	assert.Equal(t, 180.0, voltage.At(6*time.Second))
+	assert.Equal(t, 180.0, voltage.Last(), "播完後維持最後狀態")
+	coil, err := h.Registers().ReadCoil(3)
+	require.NoError(t, err)
+	assert.False(t, coil, "Unit 2 不重播")
+
+	// 重新進入場景時從頭開始，time_scale 加速重播
+	h.SetParams(ScenarioParams{Capture: path, TimeScale: 10})
+	h.Activate()
+	h.Step(time.Second)
+	assert.Equal(t, 180.0, h.Series("LineVoltage").Last())
+	coil, err = h.Registers().ReadCoil(3)
+	require.NoError(t, err)
+	assert.True(t, coil)
+}
+
+func TestTransactionMismatchScenario_SequenceResponse(t *testing.T) {
+	scenario := &TransactionMismatchScenario{}
+	response := []byte{0x00, 0x07, 0x00, 0x00, 0x00, 0x05, 0x01, 0x03, 0x02, 0x08, 0x98}
+	sequence := func(mode string, hasPrevious bool) ([]byte, time.Duration) {
+		params := ScenarioParams{MismatchRate: 1, MismatchModes: []string{mode}}
+		return scenario.SequenceResponse(response, 6, hasPrevious, params)
+	}
+
+	out, hold := sequence(MismatchModeWrong, true)
+	assert.Zero(t, hold)
+	assert.NotEqual(t, response[0:2], out[0:2])
+	assert.Equal(t, response[2:], out[2:])
+	assert.Equal(t, []byte{0x00, 0x07}, response[0:2], "不修改原始回應")
+
+	out, _ = sequence(MismatchModePrevious, true)
+	assert.Equal(t, []byte{0x00, 0x06}, out[0:2])
+	// 第一個請求沒有上一個 Transaction ID
+	out, _ = sequence(MismatchModePrevious, false)
+	assert.NotEqual(t, response[0:2], out[0:2])
+
+	out, hold = sequence(MismatchModeReorder, true)
+	assert.Equal(t, DefaultReorderWait, hold)
+	assert.Equal(t, response, out)
+}
+
+func TestLongCommandScenario(t *testing.T) {
+	params := ScenarioParams{CommandRegister: 40100, StatusRegister: 40101, CommandDuration: 5 * time.Second}
+	scenario := &LongCommandScenario{}
+	h := NewScenarioHarness(scenario, WithHarnessParams(params))
+	defer h.Close()
+	regs := h.Registers()
+
+	status := func() uint16 {
+		value, err := regs.ReadHoldingRegister(40101)
+		require.NoError(t, err)
+		return value
+	}
+	// FC06 寫入 PDU 位址 99 (= 40100)
+	writeCommand := []byte{0x00, 0x63, 0x00, 0x01}
+
+	// 讀取與其他位址的寫入不攔截
+	_, ok := scenario.InterceptRequest(regs, FuncCodeReadHoldingRegisters, []byte{0x00, 0x63, 0x00, 0x02}, params)
+	assert.False(t, ok)
+	_, ok = scenario.InterceptRequest(regs, FuncCodeWriteSingleRegister, []byte{0x00, 0x00, 0x00, 0x01}, params)
+	assert.False(t, ok)
+	assert.Equal(t, LongCommandIdle, status())
+
+	code, ok := scenario.InterceptRequest(regs, FuncCodeWriteSingleRegister, writeCommand, params)
+	assert.True(t, ok)
+	assert.Equal(t, uint8(ExceptionCodeAcknowledge), code)
+	assert.Equal(t, LongCommandInProgress, status())
+
+	// 執行中再次下達命令 (FC16 寫入 40099-40100) 回應忙碌
+	h.Step(2 * time.Second)
+	code, ok = scenario.InterceptRequest(regs, FuncCodeWriteMultipleRegisters, []byte{0x00, 0x62, 0x00, 0x02, 0x04, 0, 0, 0, 1}, params)
+	assert.True(t, ok)
+	assert.Equal(t, uint8(ExceptionCodeSlaveDeviceBusy), code)
+	assert.Equal(t, LongCommandInProgress, status())
+
+	h.Step(3 * time.Second)
+	assert.Equal(t, LongCommandComplete, status())
+
+	// 完成後可再次下達命令
+	code, _ = scenario.InterceptRequest(regs, FuncCodeWriteSingleRegister, writeCommand, params)
+	assert.Equal(t, uint8(ExceptionCodeAcknowledge), code)
+	assert.Equal(t, LongCommandInProgress, status())
+}
+
+func TestEngine_TuneScenario(t *testing.T) {
+	config := DefaultConfig()
+	config.Scenario.Scenarios["packet_loss"] = ScenarioParams{PacketLossRate: 0.01, CorruptModes: []string{"truncate"}}
+	engine := NewEngine(config, zap.NewNop())
+	slave := NewSlave(net.ParseIP("127.0.0.1"), config.Server.Port, config, WithLogger(zap.NewNop()), WithScenarioTuning(engine.tuning))
+	engine.slaves[slave.ID] = slave
+	slave.ApplyScenario(ScenarioPacketLoss)
+	assert.Equal(t, 0.01, slave.handler.packetLossRate)
+
+	// 只覆寫指定的鍵，並立即套用到運行該場景的 Slave
+	params, retuned, err := engine.TuneScenario(ScenarioPacketLoss, []byte(`{"packet_loss_rate": 0.2, "jitter_max": "50ms", "corrupt_modes": ["garbage"]}`))
+	require.NoError(t, err)
+	assert.Equal(t, 1, retuned)
+	assert.Equal(t, 0.2, params.PacketLossRate)
+	assert.Equal(t, 50*time.Millisecond, params.JitterMax)
+	assert.Equal(t, []string{"garbage"}, params.CorruptModes, "清單整個取代")
+	assert.Equal(t, 0.2, slave.handler.packetLossRate)
+	assert.Equal(t, params, engine.ScenarioParams(ScenarioPacketLoss))
+	assert.Equal(t, 0.01, config.Scenario.Scenarios["packet_loss"].PacketLossRate, "不修改配置檔的參數")
+
+	// 其他場景不受影響
+	_, retuned, err = engine.TuneScenario(ScenarioJitter, []byte(`{"jitter_max": "1s"}`))
+	require.NoError(t, err)
+	assert.Equal(t, 0, retuned)
+	assert.Equal(t, 0.2, slave.handler.packetLossRate)
+
+	// 無法即時調整或無效的參數不生效
+	_, _, err = engine.TuneScenario(ScenarioPacketLoss, []byte(`{"targets": ["10.0.0.1"]}`))
+	assert.Error(t, err)
+	_, _, err = engine.TuneScenario(ScenarioPacketLoss, []byte(`{"packet_loss_rate": 2}`))
+	assert.Error(t, err)
+	_, _, err = engine.TuneScenario(ScenarioPacketLoss, []byte(`{}`))
+	assert.Error(t, err)
+	assert.Equal(t, 0.2, slave.handler.packetLossRate)
+
+	// 還原為配置檔的參數
+	reset, retuned := engine.ResetScenarioTuning(ScenarioPacketLoss)
+	assert.True(t, reset)
+	assert.Equal(t, 1, retuned)
+	assert.Equal(t, 0.01, slave.handler.packetLossRate)
+	reset, _ = engine.ResetScenarioTuning(ScenarioPacketLoss)
+	assert.False(t, reset)
+}
+
+func TestParseTuneArgs(t *testing.T) {
+	patch, err := parseTuneArgs([]string{"packet_loss_rate=0.05", "jitter_max=500ms", "corrupt_modes=truncate,garbage", "exception_weights.slave_device_busy=3"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"packet_loss_rate":  0.05,
+		"jitter_max":        "500ms",
+		"corrupt_modes":     []string{"truncate", "garbage"},
+		"exception_weights": map[string]any{"slave_device_busy": 3.0},
+	}, patch)
+
+	_, err = parseTuneArgs([]string{"packet_loss_rate"})
+	assert.Error(t, err)
+}
+
+func TestCompositeScenario(t *testing.T) {
+	sag := &VoltageSagScenario{}
+	composite := NewCompositeScenario(
+		ScenarioComponent{Handler: sag, Params: ScenarioParams{VoltageVariance: 0.5, Duration: time.Minute}},
+		ScenarioComponent{Handler: &JitterScenario{}, Params: ScenarioParams{JitterMin: 10 * time.Millisecond, JitterMax: 20 * time.Millisecond}},
+		ScenarioComponent{Handler: &PacketLossScenario{}, Params: ScenarioParams{PacketLossRate: 0.2}},
+		ScenarioComponent{Handler: &CorruptedResponseScenario{}, Params: ScenarioParams{CorruptRate: 1, CorruptModes: []string{CorruptModeTruncate}}},
+		ScenarioComponent{Handler: &FragmentedResponseScenario{}, Params: ScenarioParams{FragmentSize: 2}},
+	)
+
+	// 暫存器由 voltage_sag 決定，只影響通訊的場景不覆寫
+	h := NewScenarioHarness(composite)
+	h.Step(time.Second)
+	nominal := h.Registers().Nominal().Voltage
+	assert.InDelta(t, nominal*0.5, h.Series("LineVoltage").Last(), nominal*0.01, "電壓應降至 50%")
+
+	// 延遲與丟包：丟包率依各場景合併
+	model := composite.delayModel()
+	require.NotNil(t, model)
+	assert.Equal(t, 20*time.Millisecond, model.Max)
+	assert.InDelta(t, 0.2, composite.LossRate(ScenarioParams{}), 1e-9)
+	assert.InDelta(t, 0.36, NewCompositeScenario(
+		ScenarioComponent{Handler: &PacketLossScenario{}, Params: ScenarioParams{PacketLossRate: 0.2}},
+		ScenarioComponent{Handler: &PacketLossScenario{}, Params: ScenarioParams{PacketLossRate: 0.2}},
+	).LossRate(ScenarioParams{}), 1e-9)
+
+	// 回應依序串接：先截斷再分段寫出
+	response := []byte{0x00, 0x01, 0x00, 0x00, 0x00, 0x05, 0x01, 0x03, 0x02, 0x08, 0x98}
+	rec := &chunkRecorder{}
+	require.NoError(t, composite.WriteResponse(rec, response, ScenarioParams{}))
+	written := bytes.Join(rec.chunks, nil)
+	assert.Less(t, len(written), len(response), "應先被截斷")
+	assert.Len(t, rec.chunks, (len(written)+1)/2, "再以 2 bytes 分段")
+
+	// 第一個注入例外者生效
+	storm := NewCompositeScenario(
+		ScenarioComponent{Handler: &ExceptionStormScenario{}, Params: ScenarioParams{ExceptionRate: 1, ExceptionWeights: map[string]float64{"slave_device_busy": 1}}},
+		ScenarioComponent{Handler: &ExceptionStormScenario{}, Params: ScenarioParams{ExceptionRate: 1, ExceptionWeights: map[string]float64{"slave_device_failure": 1}}},
+	)
+	code, ok := storm.InjectException(FuncCodeReadHoldingRegisters, ScenarioParams{})
+	assert.True(t, ok)
+	assert.Equal(t, uint8(ExceptionCodeSlaveDeviceBusy), code)
+	_, ok = composite.InjectException(FuncCodeReadHoldingRegisters, ScenarioParams{})
+	assert.False(t, ok)
+
+	// Reset 重設所有場景：電壓回到額定值，voltage_sag 重新計時
+	h.Reset()
+	assert.InDelta(t, nominal, h.Series("LineVoltage").Last(), 0.1)
+	assert.True(t, sag.startTime.IsZero())
+	h.Close()
+
+	// 只有通訊場景時暫存器照常波動
+	h = NewScenarioHarness(NewCompositeScenario(ScenarioComponent{Handler: &JitterScenario{}}))
+	defer h.Close()
+	h.Step(time.Second)
+	assert.InDelta(t, nominal, h.Series("LineVoltage").Last(), nominal*0.005+0.1)
+	assert.Nil(t, NewCompositeScenario(ScenarioComponent{Handler: &PacketLossScenario{}}).delayModel())
+}
+
+func TestSlave_CompositeScenario(t *testing.T) {
+	config := DefaultConfig()
+	config.Scenario.Scenarios["composite"] = ScenarioParams{Components: []string{"jitter", "packet_loss", "connection_flap"}}
+	config.Scenario.Scenarios["packet_loss"] = ScenarioParams{PacketLossRate: 0.1}
+	engine := NewEngine(config, zap.NewNop())
+	slave := NewSlave(net.ParseIP("127.0.0.1"), config.Server.Port, config, WithLogger(zap.NewNop()), WithScenarioTuning(engine.tuning))
+	engine.slaves[slave.ID] = slave
+
+	slave.ApplyScenario(ScenarioComposite)
+	assert.NotNil(t, slave.handler.jitter)
+	assert.InDelta(t, 0.1, slave.handler.packetLossRate, 1e-9)
+
+	_, handler, params := slave.currentScenario()
+	flap, ok := scenarioComponent(ScenarioConnectionFlap, handler, params)
+	assert.True(t, ok, "組合中的 connection_flap 應生效")
+	assert.Equal(t, config.Scenario.Scenarios["connection_flap"], flap)
+	_, ok = scenarioComponent(ScenarioConnectionChurn, handler, params)
+	assert.False(t, ok)
+
+	// 調整組合中的場景時一併更新
+	_, retuned, err := engine.TuneScenario(ScenarioPacketLoss, []byte(`{"packet_loss_rate": 0.3}`))
+	require.NoError(t, err)
+	assert.Equal(t, 1, retuned)
+	assert.InDelta(t, 0.3, slave.handler.packetLossRate, 1e-9)
+}
+
+func TestEngine_Chaos(t *testing.T) {
+	config := DefaultConfig()
+	config.Scenario.Scenarios["chaos"] = ScenarioParams{
+		ChaosScenarios:   []string{"jitter"},
+		ChaosIntervalMin: time.Minute,
+		ChaosIntervalMax: time.Minute,
+		ChaosDurationMin: 90 * time.Second,
+		ChaosDurationMax: 90 * time.Second,
+		ChaosFractionMin: 0.5,
+		ChaosFractionMax: 0.5,
+		ChaosMaxActive:   1,
+	}
+	engine := NewEngine(config, zap.NewNop())
+	for i := 1; i <= 4; i++ {
+		slave := NewSlave(net.IPv4(127, 0, 0, byte(i)), config.Server.Port, config, WithLogger(zap.NewNop()))
+		engine.slaves[slave.ID] = slave
+	}
+	countScenario := func(scenario ScenarioType) int {
+		n := 0
+		for _, slave := range engine.ListSlaves() {
+			if slave.GetScenario() == scenario {
+				n++
+			}
+		}
+		return n
+	}
+
+	// 整體場景不是 chaos 時不注入
+	now := time.Now()
+	engine.chaosTick(now)
+	assert.False(t, engine.ChaosStatus().Running)
+
+	require.NoError(t, engine.ApplyScenario(ScenarioChaos))
+	engine.chaosTick(now)
+	status := engine.ChaosStatus()
+	assert.True(t, status.Running)
+	assert.Equal(t, now.Add(time.Minute), status.NextAt)
+	assert.Equal(t, 4, countScenario(ScenarioChaos), "到達排定時間前不注入")
+
+	// 注入：一半的 Slave 套用擾動場景
+	engine.chaosTick(now.Add(time.Minute))
+	status = engine.ChaosStatus()
+	require.Len(t, status.Disturbances, 1)
+	assert.Equal(t, "jitter", status.Disturbances[0].Scenario)
+	assert.Len(t, status.Disturbances[0].Slaves, 2)
+	assert.Equal(t, uint64(1), status.Injected)
+	assert.Equal(t, 2, countScenario(ScenarioJitter))
+	stats := engine.Stats()
+	assert.Equal(t, uint64(1), stats.ChaosDisturbances)
+	assert.Equal(t, 2, stats.ChaosSlaves)
+
+	// 達擾動上限時不再注入
+	engine.chaosTick(now.Add(2 * time.Minute))
+	assert.Equal(t, uint64(1), engine.ChaosStatus().Injected)
+
+	// 擾動到期後恢復為 chaos 場景
+	engine.chaosTick(now.Add(150 * time.Second))
+	assert.Empty(t, engine.ChaosStatus().Disturbances)
+	assert.Equal(t, 4, countScenario(ScenarioChaos))
+
+	engine.chaosTick(now.Add(3 * time.Minute))
+	status = engine.ChaosStatus()
+	assert.Len(t, status.Disturbances, 1)
+	assert.Equal(t, uint64(2), status.Injected)
+
+	// 切換整體場景時結束混沌模式，受擾動的 Slave 套用新場景
+	require.NoError(t, engine.ApplyScenario(ScenarioNormal))
+	status = engine.ChaosStatus()
+	assert.False(t, status.Running)
+	assert.Empty(t, status.Disturbances)
+	assert.Equal(t, uint64(2), status.Injected)
+	assert.Equal(t, 4, countScenario(ScenarioNormal))
+}
+
+func TestSimulationClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	base := NewVirtualClock(start)
+	clock := NewSimulationClock(base, 60)
+	assert.Equal(t, start, clock.Now())
+
+	// 實際 1 分鐘模擬 1 小時
+	base.Advance(time.Minute)
+	assert.Equal(t, start.Add(time.Hour), clock.Now())
+
+	// 調整倍率時模擬時間連續，之後以新倍率前進
+	clock.SetSpeed(1)
+	assert.Equal(t, start.Add(time.Hour), clock.Now())
+	base.Advance(time.Minute)
+	assert.Equal(t, start.Add(time.Hour+time.Minute), clock.Now())
+	assert.Equal(t, 1.0, clock.Speed())
+
+	assert.Equal(t, 1.0, NewSimulationClock(base, 0).Speed())
+}
+
+func TestSimulationClock_Scenarios(t *testing.T) {
+	base := NewVirtualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	restore := setScenarioSources(NewSimulationClock(base, 60), newLockedRand(1))
+	defer restore()
+
+	config := DefaultConfig()
+	config.Clock.Register = 40120
+	slave := NewSlave(net.ParseIP("127.0.0.1"), config.Server.Port, config, WithLogger(zap.NewNop()))
+
+	// 電能依模擬時間累積：實際 1 分鐘累積 1 小時的電能
+	slave.updateByScenario()
+	before, _ := slave.registers.GetScaledValue(40004)
+	power, _ := slave.registers.GetScaledValue(40007)
+	base.Advance(time.Minute)
+	slave.updateByScenario()
+	after, _ := slave.registers.GetScaledValue(40004)
+	assert.InDelta(t, power/1000, after-before, power/1000*0.1)
+
+	// 模擬時間寫入 clock.register (uint32 Unix 秒，高位字在前)
+	words, err := slave.registers.ReadHoldingRegisters(40120, 2)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC).Unix(), int64(words[0])<<16|int64(words[1]))
+}
+
+func TestSimulationClock_DeviceModel(t *testing.T) {
+	base := NewVirtualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	restore := setScenarioSources(NewSimulationClock(base, 60), newLockedRand(1))
+	defer restore()
+
+	profile, ok := GetDeviceProfile(ProfileBattery)
+	require.True(t, ok)
+	rm, err := profile.NewRegisterMap()
+	require.NoError(t, err)
+	config := DefaultConfig()
+	slave := NewSlave(net.ParseIP("127.0.0.1"), config.Server.Port, config,
+		WithLogger(zap.NewNop()), WithRegisters(rm), WithModel(profile.NewModel()))
+
+	// 100 kWh 電池以 50 kW 充電：實際 30 秒為模擬 30 分鐘 = 25%
+	require.NoError(t, rm.SetScaledValue(AddrBatterySetpoint, 50000))
+	slave.updateByScenario()
+	base.Advance(30 * time.Second)
+	slave.updateByScenario()
+	soc, _ := rm.GetScaledValue(AddrBatterySoC)
+	assert.InDelta(t, 75.0, soc, 0.1)
+}
+
+func TestEngine_SetClockSpeed(t *testing.T) {
+	engine := NewEngine(DefaultConfig(), zap.NewNop())
+	assert.Equal(t, 1.0, engine.ClockStatus().Speed)
+
+	status, err := engine.SetClockSpeed(60)
+	require.NoError(t, err)
+	assert.Equal(t, 60.0, status.Speed)
+	_, err = engine.SetClockSpeed(0)
+	assert.Error(t, err)
+	assert.Equal(t, 60.0, engine.ClockStatus().Speed)
+}
+
+func TestDeviceClock(t *testing.T) {
+	base := NewVirtualClock(time.Date(2024, 2, 28, 23, 59, 0, 0, time.UTC))
+	restore := setScenarioSources(base, newLockedRand(1))
+	defer restore()
+
+	clock := newDeviceClock(DeviceClockConfig{DriftPPM: 100, Skew: -30 * time.Second}, newLockedRand(1))
+	assert.Equal(t, uint16(DefaultDeviceClockRegister), clock.register)
+	assert.Equal(t, base.Now().Add(-30*time.Second), clock.Now())
+
+	// 100 ppm：參考時間經過 10000 秒時設備多走 1 秒
+	base.Advance(10000 * time.Second)
+	assert.Equal(t, base.Now().Add(-29*time.Second), clock.Now())
+
+	// 校時後依相同的走時誤差前進
+	clock.Set(base.Now())
+	base.Advance(10000 * time.Second)
+	assert.Equal(t, base.Now().Add(time.Second), clock.Now())
+
+	// 暫存器格式
+	at := time.Date(2024, 2, 29, 12, 34, 56, 0, time.UTC)
+	words := clock.words(at)
+	assert.Equal(t, []uint16{2024, 2, 29, 12, 34, 56}, words)
+	parsed, ok := clock.parse(words)
+	assert.True(t, ok)
+	assert.Equal(t, at, parsed)
+	_, ok = clock.parse([]uint16{2023, 2, 29, 0, 0, 0})
+	assert.False(t, ok, "非閏年沒有 2 月 29 日")
+	_, ok = clock.parse([]uint16{2024, 13, 1, 0, 0, 0})
+	assert.False(t, ok)
+
+	unix := newDeviceClock(DeviceClockConfig{Format: DeviceClockFormatUnix}, newLockedRand(1))
+	parsed, ok = unix.parse(unix.words(at))
+	assert.True(t, ok)
+	assert.True(t, at.Equal(parsed))
+}
+
+func TestSlave_DeviceClock(t *testing.T) {
+	config := DefaultConfig()
+	config.Slaves.DeviceClock = DeviceClockConfig{Enabled: true, DriftSpread: 50, SkewSpread: time.Minute, DeviceIDObject: 0x80}
+	slave := NewSlave(net.ParseIP("127.0.0.1"), config.Server.Port, config, WithLogger(zap.NewNop()), WithSeed(42))
+	other := NewSlave(net.ParseIP("127.0.0.2"), config.Server.Port, config, WithLogger(zap.NewNop()), WithSeed(42))
+
+	// 各 Slave 的走時誤差與偏差不同，但相同種子可重現
+	status, ok := slave.DeviceClockStatus()
+	require.True(t, ok)
+	otherStatus, _ := other.DeviceClockStatus()
+	assert.NotEqual(t, status.DriftPPM, otherStatus.DriftPPM)
+	assert.InDelta(t, 0, status.DriftPPM, 50)
+	assert.LessOrEqual(t, status.Offset.Abs(), time.Minute+time.Second)
+	again := NewSlave(net.ParseIP("127.0.0.1"), config.Server.Port, config, WithLogger(zap.NewNop()), WithSeed(42))
+	againStatus, _ := again.DeviceClockStatus()
+	assert.Equal(t, status.DriftPPM, againStatus.DriftPPM)
+
+	// Master 只寫入秒也會校時；無效的日期時間拒絕
+	words, err := slave.registers.ReadHoldingRegisters(DefaultDeviceClockRegister, 6)
+	require.NoError(t, err)
+	synced, ok, err := slave.deviceClockWrite(slave.registers, DefaultDeviceClockRegister-40001+5, []uint16{0})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(int(words[0]), time.Month(words[1]), int(words[2]), int(words[3]), int(words[4]), 0, 0, time.UTC), synced)
+	_, _, err = slave.deviceClockWrite(slave.registers, DefaultDeviceClockRegister-40001+1, []uint16{13})
+	assert.Error(t, err)
+	_, ok, err = slave.deviceClockWrite(slave.registers, 0, []uint16{1})
+	assert.NoError(t, err)
+	assert.False(t, ok, "與時間暫存器無關的寫入")
+
+	// FC 43：單一讀取設備時間物件
+	response, err := slave.readDeviceIdentification([]byte{0x0E, 0x04, 0x80})
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x0E, 0x04, 0x83, 0x00, 0x00, 0x01, 0x80}, response[:7])
+	_, err = time.Parse(time.RFC3339, string(response[8:8+response[7]]))
+	assert.NoError(t, err)
+
+	// 串流讀取基本物件不含設備時間；擴充物件包含
+	response, err = slave.readDeviceIdentification([]byte{0x0E, 0x01, 0x00})
+	require.NoError(t, err)
+	assert.Equal(t, byte(3), response[5])
+	response, err = slave.readDeviceIdentification([]byte{0x0E, 0x03, 0x00})
+	require.NoError(t, err)
+	assert.Equal(t, byte(4), response[5])
+	_, err = slave.readDeviceIdentification([]byte{0x0E, 0x04, 0x81})
+	assert.Error(t, err)
+}
+
+func TestDemandMeter(t *testing.T) {
+	t0 := time.Date(2024, 1, 31, 23, 0, 0, 0, time.UTC)
+	m := newDemandMeter(DemandConfig{})
+	m.accumulate(3600, t0)
+
+	// 3.6 kW 持續 15 分鐘：需量 3.6 kW，第一個區間 0.9 kWh
+	m.accumulate(3600, t0.Add(15*time.Minute))
+	assert.InDelta(t, 3.6, m.demand, 1e-9)
+	assert.InDelta(t, 0.9, m.lastKWh, 1e-9)
+	assert.InDelta(t, 0, m.intervalKWh, 1e-9)
+
+	// 功率加倍 5 分鐘：時窗內 10 分鐘 3.6 kW + 5 分鐘 7.2 kW
+	m.accumulate(7200, t0.Add(20*time.Minute))
+	assert.InDelta(t, 4.8, m.demand, 1e-9)
+	assert.InDelta(t, 0.6, m.intervalKWh, 1e-9)
+	assert.InDelta(t, 4.8, m.peak, 1e-9)
+	assert.Equal(t, t0.Add(20*time.Minute), m.peakAt)
+
+	// 跨月時最大需量重設
+	m.accumulate(1200, t0.Add(65*time.Minute))
+	assert.InDelta(t, 1.2, m.demand, 1e-9)
+	assert.InDelta(t, 1.2, m.peak, 1e-9)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), m.peakAt)
+}
+
+func TestSlave_Demand(t *testing.T) {
+	base := NewVirtualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	restore := setScenarioSources(base, newLockedRand(1))
+	defer restore()
+
+	config := DefaultConfig()
+	config.Slaves.Demand = DemandConfig{Enabled: true, Interval: time.Hour}
+	slave := NewSlave(net.ParseIP("127.0.0.1"), config.Server.Port, config, WithLogger(zap.NewNop()))
+	meta, ok := slave.registers.GetDefinition(DefaultDemandRegister)
+	require.True(t, ok)
+	assert.Equal(t, "Demand", meta.Name)
+
+	slave.updateByScenario()
+	base.Advance(15 * time.Minute)
+	slave.updateByScenario()
+
+	// 需量依模擬時鐘計算，約等於有功功率
+	power, _ := slave.registers.GetScaledValue(40007)
+	demand, _ := slave.registers.GetScaledValue(DefaultDemandRegister)
+	peak, _ := slave.registers.GetScaledValue(DefaultDemandRegister + 2)
+	peakAt, _ := slave.registers.GetScaledValue(DefaultDemandRegister + 4)
+	energy, _ := slave.registers.GetScaledValue(DefaultDemandRegister + 6)
+	assert.InDelta(t, power/1000, demand, power/1000*0.1)
+	assert.Equal(t, demand, peak)
+	assert.Equal(t, float64(base.Now().Unix()), peakAt)
+	assert.InDelta(t, demand/4, energy, 0.01)
+
+	// 與設定檔重疊的位址停用需量計算
+	for _, register := range []uint16{40001, 40004} {
+		config.Slaves.Demand.Register = register
+		overlapping := NewSlave(net.ParseIP("127.0.0.2"), config.Server.Port, config, WithLogger(zap.NewNop()))
+		assert.Nil(t, overlapping.demand)
+	}
+}
+
+func TestTariffMeter(t *testing.T) {
+	schedule := append(append([]TariffPeriod(nil), DefaultTariffSchedule...), TariffPeriod{Start: "00:00", Tariff: 4, Days: []string{"sat", "sun"}})
+	m := newTariffMeter(TariffConfig{Schedule: schedule})
+
+	// 週五 06:00-08:00：07:00 由 T1 切換為 T2
+	t0 := time.Date(2024, 1, 5, 6, 0, 0, 0, time.UTC)
+	m.accumulate(1000, t0)
+	m.accumulate(1000, t0.Add(2*time.Hour))
+	assert.InDeltaSlice(t, []float64{1, 1, 0, 0}, m.energy[:], 1e-9)
+
+	// 跨日：週五 T2 至 17:00、T3 至 22:00、T1 至 24:00，週六 00:00 起為 T4 (同時開始時以後列者為準)
+	m.accumulate(1000, time.Date(2024, 1, 6, 1, 0, 0, 0, time.UTC))
+	assert.InDeltaSlice(t, []float64{3, 10, 5, 1}, m.energy[:], 1e-9)
+	assert.Equal(t, 4, m.tariffAt(time.Date(2024, 1, 6, 1, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 3, m.tariffAt(time.Date(2024, 1, 8, 18, 0, 0, 0, time.UTC)))
+
+	// Slave 依模擬時鐘累積並寫入暫存器
+	base := NewVirtualClock(time.Date(2024, 1, 8, 16, 30, 0, 0, time.UTC))
+	restore := setScenarioSources(base, newLockedRand(1))
+	defer restore()
+
+	config := DefaultConfig()
+	config.Slaves.Tariff = TariffConfig{Enabled: true}
+	slave := NewSlave(net.ParseIP("127.0.0.1"), config.Server.Port, config, WithLogger(zap.NewNop()))
+	slave.updateByScenario()
+	base.Advance(time.Hour)
+	slave.updateByScenario()
+
+	tariff, _ := slave.registers.GetScaledValue(DefaultTariffRegister)
+	t2, _ := slave.registers.GetScaledValue(DefaultTariffRegister + 3)
+	t3, _ := slave.registers.GetScaledValue(DefaultTariffRegister + 5)
+	power, _ := slave.registers.GetScaledValue(40007)
+	assert.Equal(t, 3.0, tariff)
+	assert.InDelta(t, power/2000, t2, 0.01)
+	assert.InDelta(t, t2, t3, 0.01)
+}
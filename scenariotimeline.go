@@ -0,0 +1,67 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// ScenarioTimeline 整體場景的切換次數與停留時間 (供 Grafana 標註故障注入期間)
+type ScenarioTimeline struct {
+	mu          sync.Mutex
+	current     ScenarioType
+	since       time.Time
+	transitions map[ScenarioType]uint64
+	elapsed     map[ScenarioType]time.Duration // 已結束區段的累計停留時間
+}
+
+// ScenarioUsage 單一場景的統計
+type ScenarioUsage struct {
+	Scenario    ScenarioType
+	Active      bool
+	Transitions uint64        // 切換進入此場景的次數
+	Time        time.Duration // 累計停留時間 (目前場景計至查詢當下)
+}
+
+// NewScenarioTimeline 建立場景時間軸，自 now 起停留在 initial
+func NewScenarioTimeline(initial ScenarioType, now time.Time) *ScenarioTimeline {
+	return &ScenarioTimeline{
+		current:     initial,
+		since:       now,
+		transitions: make(map[ScenarioType]uint64),
+		elapsed:     make(map[ScenarioType]time.Duration),
+	}
+}
+
+// Enter 切換到 scenario；與目前場景相同時不視為切換，回傳 false
+func (t *ScenarioTimeline) Enter(scenario ScenarioType, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if scenario == t.current {
+		return false
+	}
+	t.elapsed[t.current] += now.Sub(t.since)
+	t.current, t.since = scenario, now
+	t.transitions[scenario]++
+	return true
+}
+
+// Usage 所有場景類型的統計 (依定義順序)
+func (t *ScenarioTimeline) Usage(now time.Time) []ScenarioUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	types := ScenarioTypes()
+	usage := make([]ScenarioUsage, len(types))
+	for i, scenario := range types {
+		usage[i] = ScenarioUsage{
+			Scenario:    scenario,
+			Transitions: t.transitions[scenario],
+			Time:        t.elapsed[scenario],
+		}
+		if scenario == t.current {
+			usage[i].Active = true
+			usage[i].Time += now.Sub(t.since)
+		}
+	}
+	return usage
+}
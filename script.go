@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	"go.uber.org/zap"
+)
+
+// scriptCallTimeout 單次呼叫腳本的時間上限 (避免無窮迴圈卡住場景更新)
+const scriptCallTimeout = time.Second
+
+// ScriptScenario 腳本場景 - 每次更新呼叫 Lua 腳本的 Update(regs, t, dt)，不需重新編譯即可自訂設備行為
+//
+// 腳本可定義：
+//
+//	function Update(regs, t, dt) ... end  -- t: 載入後經過秒數，dt: 距上次更新秒數
+//	function Reset(regs) ... end          -- 選用，未定義時還原為正常場景的預設值
+//
+// regs 提供 get/set (工程值，參數為位址或暫存器名稱)、read/write (原始 16 位元值) 與 coil/set_coil。
+// 未指定 script 時行為與正常場景相同。
+type ScriptScenario struct {
+	normalScenario NormalScenario
+
+	mu     sync.Mutex
+	states map[*RegisterMap]*scriptState
+}
+
+// scriptState 單一暫存器映射表的腳本執行環境 (LState 不可並發使用)
+type scriptState struct {
+	mu       sync.Mutex
+	path     string
+	L        *lua.LState
+	regs     *lua.LTable
+	loadedAt time.Time
+	last     time.Time
+	failed   bool // 載入或執行失敗後停止呼叫，直到重新進入場景
+}
+
+func (s *ScriptScenario) Type() ScenarioType {
+	return ScenarioScript
+}
+
+// Activate 進入場景時丟棄舊的執行環境，下次更新重新載入腳本 (可在不重啟的情況下套用修改)
+func (s *ScriptScenario) Activate(registers *RegisterMap) {
+	s.mu.Lock()
+	state := s.states[registers]
+	delete(s.states, registers)
+	s.mu.Unlock()
+
+	if state != nil {
+		state.close()
+	}
+}
+
+func (s *ScriptScenario) Update(registers *RegisterMap, params ScenarioParams) {
+	if params.Script == "" {
+		s.normalScenario.Update(registers, params)
+		return
+	}
+
+	state := s.state(registers, params.Script)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.failed {
+		return
+	}
+
+	now := scenarioNow()
+	t := now.Sub(state.loadedAt).Seconds()
+	dt := now.Sub(state.last).Seconds()
+	state.last = now
+
+	if err := state.call("Update", lua.LNumber(t), lua.LNumber(dt)); err != nil {
+		state.fail(T("腳本執行失敗"), err)
+		return
+	}
+
+	// 腳本寫入的輸入值變動後，衍生暫存器跟著重新計算
+	registers.EvaluateExpressions()
+}
+
+func (s *ScriptScenario) Reset(registers *RegisterMap) {
+	s.mu.Lock()
+	state := s.states[registers]
+	delete(s.states, registers)
+	s.mu.Unlock()
+
+	if state == nil {
+		s.normalScenario.Reset(registers)
+		return
+	}
+	defer state.close()
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.failed || state.L.GetGlobal("Reset") == lua.LNil {
+		s.normalScenario.Reset(registers)
+		return
+	}
+	if err := state.call("Reset"); err != nil {
+		state.fail(T("腳本執行失敗"), err)
+	}
+}
+
+// state 取得 (必要時載入) 暫存器映射表對應的腳本執行環境；腳本路徑變更時重新載入
+func (s *ScriptScenario) state(registers *RegisterMap, path string) *scriptState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.states == nil {
+		s.states = make(map[*RegisterMap]*scriptState)
+	}
+	if state, ok := s.states[registers]; ok && state.path == path {
+		return state
+	} else if ok {
+		state.close()
+	}
+
+	state := newScriptState(path, registers)
+	s.states[registers] = state
+	return state
+}
+
+// newScriptState 建立沙箱化的 Lua 環境並執行腳本 (僅開放 base、table、string、math)
+func newScriptState(path string, registers *RegisterMap) *scriptState {
+	now := scenarioNow()
+	state := &scriptState{path: path, loadedAt: now, last: now}
+	state.L = newScriptLState()
+	state.regs = newScriptRegisters(state.L, registers)
+
+	fn, err := state.L.LoadFile(path)
+	if err == nil {
+		state.L.Push(fn)
+		err = state.protectedCall(0)
+	}
+	if err != nil {
+		state.fail(T("載入腳本失敗"), err)
+	}
+	return state
+}
+
+// newScriptLState 建立不含 os、io 等函式庫的 Lua 環境
+func newScriptLState() *lua.LState {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	// base 函式庫中可讀取檔案的函式一併移除
+	for _, name := range []string{"dofile", "loadfile"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+	return L
+}
+
+// call 呼叫腳本中的全域函式 (未定義時略過)，第一個參數固定為 regs
+func (st *scriptState) call(name string, args ...lua.LValue) error {
+	fn := st.L.GetGlobal(name)
+	if fn == lua.LNil {
+		return nil
+	}
+	st.L.Push(fn)
+	st.L.Push(st.regs)
+	for _, arg := range args {
+		st.L.Push(arg)
+	}
+	return st.protectedCall(1 + len(args))
+}
+
+// protectedCall 以逾時限制執行堆疊上的函式
+func (st *scriptState) protectedCall(nargs int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), scriptCallTimeout)
+	defer cancel()
+	st.L.SetContext(ctx)
+	defer st.L.RemoveContext()
+	return st.L.PCall(nargs, 0, nil)
+}
+
+// fail 記錄錯誤並停止呼叫腳本
+func (st *scriptState) fail(msg string, err error) {
+	st.failed = true
+	zap.L().Warn(msg, zap.String("script", st.path), zap.Error(err))
+}
+
+func (st *scriptState) close() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.L.Close()
+}
+
+// newScriptRegisters 建立腳本存取暫存器用的 regs 表
+func newScriptRegisters(L *lua.LState, registers *RegisterMap) *lua.LTable {
+	// 參數可為位址 (數字) 或暫存器名稱 (字串)
+	address := func(L *lua.LState) uint16 {
+		switch v := L.Get(1).(type) {
+		case lua.LNumber:
+			return uint16(v)
+		case lua.LString:
+			for _, meta := range registers.Definitions() {
+				if meta.Name == string(v) {
+					return meta.Address
+				}
+			}
+			L.ArgError(1, fmt.Sprintf(T("未定義的暫存器: %s"), string(v)))
+		default:
+			L.ArgError(1, T("需要暫存器位址或名稱"))
+		}
+		return 0
+	}
+	check := func(L *lua.LState, err error) {
+		if err != nil {
+			L.RaiseError("%s", err.Error())
+		}
+	}
+
+	return L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"get": func(L *lua.LState) int {
+			value, err := registers.GetScaledValue(address(L))
+			check(L, err)
+			L.Push(lua.LNumber(value))
+			return 1
+		},
+		"set": func(L *lua.LState) int {
+			check(L, registers.SetScaledValue(address(L), float64(L.CheckNumber(2))))
+			return 0
+		},
+		"read": func(L *lua.LState) int {
+			value, err := registers.ReadHoldingRegister(address(L))
+			check(L, err)
+			L.Push(lua.LNumber(value))
+			return 1
+		},
+		"write": func(L *lua.LState) int {
+			check(L, registers.WriteHoldingRegister(address(L), uint16(L.CheckInt(2))))
+			return 0
+		},
+		"coil": func(L *lua.LState) int {
+			value, err := registers.ReadCoil(uint16(L.CheckInt(1)))
+			check(L, err)
+			L.Push(lua.LBool(value))
+			return 1
+		},
+		"set_coil": func(L *lua.LState) int {
+			check(L, registers.WriteCoil(uint16(L.CheckInt(1)), L.ToBool(2)))
+			return 0
+		},
+	})
+}
+
+// ValidateScript 檢查腳本檔案可讀且語法正確 (不執行)
+func ValidateScript(path string) error {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	defer L.Close()
+
+	_, err := L.LoadFile(path)
+	return err
+}
@@ -0,0 +1,30 @@
+-- 太陽能逆變器範例：發電功率依日照曲線 (06:00-18:00 正弦) 變化，電流由功率反推
+-- 使用方式：scenario.scenarios.script.script = "scripts/solar_inverter.lua"
+
+local rated_power = 5000 -- W
+local day_length = 86400 -- 模擬一天的秒數 (縮短可加速日夜循環)
+local energy = 0
+
+function Update(regs, t, dt)
+  local hour = (t % day_length) / day_length * 24
+  local sun = 0
+  if hour > 6 and hour < 18 then
+    sun = math.sin((hour - 6) / 12 * math.pi)
+  end
+
+  local voltage = 230 + (math.random() - 0.5) * 2
+  local power = rated_power * sun * (0.97 + math.random() * 0.03)
+
+  energy = energy + power * dt / 3600 / 1000
+  regs.set("LineVoltage", voltage)
+  regs.set("LineCurrent", power / voltage)
+  regs.set("Frequency", 60 + (math.random() - 0.5) * 0.02)
+  regs.set("PowerFactor", 1.0)
+  regs.set("TotalEnergy", energy)
+end
+
+function Reset(regs)
+  energy = 0
+  regs.set("LineCurrent", 0)
+  regs.set("TotalEnergy", 0)
+end
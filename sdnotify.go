@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// readyPollInterval 等待所有 Slave 完成綁定時的檢查間隔
+const readyPollInterval = 200 * time.Millisecond
+
+// Ready 引擎運行中且所有 Slave 都已完成綁定 (逐步上線與綁定衝突重試皆已結束)
+func (e *Engine) Ready() bool {
+	return e.State() == EngineStateRunning && e.RampPending() == 0 && e.BindPending() == 0
+}
+
+// waitReady 等待引擎就緒，期間以 STATUS= 向 systemd 回報尚未綁定的 Slave 數 (ctx 取消時回傳 false)
+func waitReady(ctx context.Context, engine *Engine) bool {
+	ticker := time.NewTicker(readyPollInterval)
+	defer ticker.Stop()
+
+	last := -1
+	for !engine.Ready() {
+		if pending := engine.RampPending() + engine.BindPending(); pending != last {
+			sdNotify("STATUS=" + fmt.Sprintf(T("等待 %d 個 Slave 完成綁定"), pending))
+			last = pending
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+	return true
+}
+
+// notifySystemdReady 所有 Slave 完成綁定後通知 systemd (Type=notify)；不是由 systemd 啟動時不做任何事
+func notifySystemdReady(ctx context.Context, engine *Engine) {
+	if os.Getenv("NOTIFY_SOCKET") == "" || !waitReady(ctx, engine) {
+		return
+	}
+	status := fmt.Sprintf(T("%d 個 Slave 運行中"), engine.Stats().ActiveSlaves)
+	if _, err := sdNotify("READY=1\nSTATUS=" + status); err != nil {
+		logger.Warn(T("通知 systemd 失敗"), zap.Error(err))
+	}
+}
+
+// sdNotify 將狀態送到 NOTIFY_SOCKET (sd_notify 協定)；未設定時回傳 false
+// (以 @ 開頭的抽象命名空間 socket 由 net 套件處理)
+func sdNotify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"os"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -54,6 +56,102 @@ type Engine struct {
 
 	// 場景
 	currentScenario ScenarioType
+	scenarios       *ScenarioTimeline
+
+	// 執行期間調整的場景參數 (tuneMu 序列化調整，避免兩個調整互相覆蓋)
+	tuneMu sync.Mutex
+	tuning *scenarioTuning
+
+	// 主備配對
+	pairsMu sync.Mutex
+	pairs   map[string]*pairState
+
+	// 故障域
+	domainsMu sync.Mutex
+	domains   map[string]*domainState
+
+	// 故障注入保護 (受保護期間被抑制、待恢復的場景)
+	protMu          sync.Mutex
+	suppressed      map[string]ScenarioType
+	suppressedCount atomic.Uint64
+
+	// 監聽位址衝突 (累計次數與等待重試的 Slave 數)
+	bindConflicts atomic.Uint64
+	bindPending   atomic.Int64
+
+	// 逐步上線尚未啟動的 Slave 數 (slaves.ramp_up_rate > 0 時)
+	rampPending atomic.Int64
+
+	// 收到快照時尚未啟動的 Slave (依 IP，啟動時還原；由 mu 保護)
+	pendingRestores map[string]SlaveSnapshot
+
+	// 配置漂移 (設定檔基準、個別重新設定的基準與上次檢查有漂移的 Slave)
+	driftMu         sync.Mutex
+	profileBaseline *registerBaseline
+	baselines       map[string]*registerBaseline
+	drifted         map[string]bool
+
+	// 各設備設定檔共用的暫存器基準 (register_sharing 為 shared 時)
+	profileRegsMu sync.Mutex
+	profileRegs   map[*DeviceProfile]*RegisterMap
+
+	// 請求延遲直方圖與追蹤器 (追蹤未啟用時 tracer 為 nil)
+	latency *LatencyHistogram
+	tracer  *Tracer
+
+	// 輪詢分析 (未啟用時為 nil)
+	polls *PollTracker
+
+	// 請求稽核日誌 (未啟用時為 nil)
+	audit *AuditLog
+
+	// 狀態持久化儲存 (未啟用時為 nil)
+	store StateStore
+
+	// Sparkplug B 發布器 (未啟用時為 nil)
+	sparkplug *SparkplugPublisher
+
+	// OPC UA 鏡像伺服器 (未啟用時為 nil)
+	opcua *OPCUAServer
+
+	// BACnet Who-Is 廣播接收 (未啟用時為 nil)
+	bacnet *bacnetDiscovery
+
+	// shared 模式的共用 listener (per_slave 模式為 nil)
+	listeners *listenerPool
+
+	// 集中式場景更新器 (scenario.workers > 0 時)
+	updater *scenarioUpdater
+
+	// 本次運行的亂數種子 (seed 未設定時於啟動時產生)
+	seed atomic.Int64
+
+	// Pod 身分 (slaves.identity 為 pod 時)
+	pod *PodIdentity
+
+	// 自我監控設備 (未啟用時為 nil)
+	diagnostics *Slave
+
+	// 模擬時鐘 (場景的時間來源)
+	clock          *SimulationClock
+	clockStartedAt time.Time
+
+	// 混沌模式 (整體場景為 chaos 時隨機注入的擾動)
+	chaosMu sync.Mutex
+	chaos   chaosState
+
+	// 開機風暴 (進行中或最近一次)
+	bootMu    sync.Mutex
+	bootStorm *bootStormState
+
+	// 除役排程 (各 Slave 的除役時間與已除役事件)
+	decomMu        sync.Mutex
+	decomPlans     map[string]decommissionPlan
+	decommissioned []DecommissionEvent
+
+	// 背景工作
+	bgCtx  context.Context
+	cancel context.CancelFunc
 
 	// 日誌
 	logger *zap.Logger
@@ -61,42 +159,151 @@ type Engine struct {
 
 // EngineStats 引擎統計資訊
 type EngineStats struct {
-	StartTime      time.Time
-	SlaveCount     int
-	ActiveSlaves   int
-	TotalRequests  uint64
-	TotalErrors    uint64
-	BytesReceived  uint64
-	BytesSent      uint64
+	StartTime            time.Time
+	ConfiguredSlaves     int // 應運行的 Slave 數 (含逐步上線與綁定重試中的 Slave)
+	SlaveCount           int
+	ActiveSlaves         int
+	TotalRequests        uint64
+	TotalErrors          uint64
+	BytesReceived        uint64
+	BytesSent            uint64
+	OfflineSlaves        int
+	StandbySlaves        int
+	TotalFlaps           uint64
+	TotalFailovers       uint64
+	SuppressedInjections uint64
+	DomainOutages        uint64
+	DomainsDown          int
+	BindConflicts        uint64
+	BindPending          int
+	RampPending          int
+	DriftedSlaves        int
+	RedundantPolls       uint64
+	DecommissionedSlaves int
+	ActiveConnections    int
+	RejectedConnections  uint64
+	ForcedDisconnects    uint64
+	TimedOutConnections  uint64
+	RateLimitedRequests  uint64
+	UnroutedConnections  uint64
+	ChaosDisturbances    uint64
+	ChaosSlaves          int
+	Seed                 int64
 }
 
 // NewEngine 建立新的引擎
 func NewEngine(config *Config, logger *zap.Logger) *Engine {
-	return &Engine{
+	e := &Engine{
 		config:          config,
 		slaves:          make(map[string]*Slave),
 		currentScenario: ScenarioNormal,
+		scenarios:       NewScenarioTimeline(ScenarioNormal, time.Now()),
+		tuning:          newScenarioTuning(),
+		clock:           NewSimulationClock(systemClock{}, config.Clock.Speed),
+		latency:         NewLatencyHistogram(DefaultLatencyBuckets),
 		logger:          logger,
 	}
+	if config.Polling.Enabled {
+		e.polls = NewPollTracker(config.Polling)
+	}
+	return e
 }
 
 // Start 啟動引擎
 func (e *Engine) Start(ctx context.Context) error {
 	if !e.state.CompareAndSwap(int32(EngineStateStopped), int32(EngineStateStarting)) {
-		return fmt.Errorf("引擎已經在運行中")
+		return errors.New(T("引擎已經在運行中"))
 	}
 
 	e.stats.StartTime = time.Now()
-	e.logger.Info("正在啟動引擎",
+
+	// 所有亂數來源皆由此種子導出，記錄於日誌與指標以便重現
+	seed := e.config.Seed
+	if seed == 0 {
+		seed = e.stats.StartTime.UnixNano()
+	}
+	e.seed.Store(seed)
+	seedScenarioRandom(seed)
+
+	// 場景改用模擬時鐘 (clock.speed 不為 1 時加速)
+	setScenarioClock(e.clock)
+	e.mu.Lock()
+	e.clockStartedAt = e.clock.Now()
+	e.mu.Unlock()
+	if speed := e.clock.Speed(); speed != 1 {
+		e.logger.Info(T("模擬時鐘已加速"), zap.Float64("speed", speed))
+	}
+
+	if e.config.Slaves.Identity == SlaveIdentityPod {
+		pod, err := PodIdentityFromEnv(os.Getenv)
+		if err != nil {
+			e.state.Store(int32(EngineStateStopped))
+			return fmt.Errorf(T("取得 Pod 身分失敗: %w"), err)
+		}
+		e.pod = &pod
+		e.logger.Info(T("以 Pod 身分運行"),
+			zap.String("pod", pod.Name),
+			zap.String("namespace", pod.Namespace),
+			zap.String("ip", pod.IP.String()),
+			zap.Int("ordinal", pod.Ordinal),
+		)
+	}
+
+	e.logger.Info(T("正在啟動引擎"),
 		zap.Int("slave_count", e.config.Slaves.Count),
 		zap.Int("port", e.config.Server.Port),
+		zap.Int64("seed", seed),
 	)
 
 	// 取得要綁定的 IP 列表
 	ips, err := e.getBindIPs()
 	if err != nil {
 		e.state.Store(int32(EngineStateStopped))
-		return fmt.Errorf("取得綁定 IP 失敗: %w", err)
+		return fmt.Errorf(T("取得綁定 IP 失敗: %w"), err)
+	}
+	if err := e.checkPairs(ips[:min(len(ips), e.config.Slaves.Count)]); err != nil {
+		e.state.Store(int32(EngineStateStopped))
+		return err
+	}
+
+	if e.config.Audit.Enabled {
+		audit, err := NewAuditLog(e.config.Audit, e.logger)
+		if err != nil {
+			e.state.Store(int32(EngineStateStopped))
+			return err
+		}
+		e.audit = audit
+	}
+
+	// 上次運行留下的 checkpoint，於 Slave 啟動後還原
+	var checkpoint *Snapshot
+	if e.config.Persistence.Enabled {
+		checkpoint, err = e.openStateStore()
+		if err != nil {
+			e.closeAudit()
+			e.state.Store(int32(EngineStateStopped))
+			return fmt.Errorf(T("開啟狀態儲存失敗: %w"), err)
+		}
+	}
+
+	if e.config.Tracing.Enabled {
+		e.tracer = NewTracer(e.config.Tracing, e.logger)
+		e.tracer.Start()
+	}
+
+	if e.config.Server.Listener == ListenerShared {
+		e.mu.Lock()
+		e.listeners = newListenerPool(e.config.Server.OriginalDst, e.logger)
+		e.mu.Unlock()
+		if interfaces := e.config.Network.Interfaces(); len(interfaces) > 1 {
+			e.logger.Warn(T("shared listener 不套用 IP 範圍的網路介面綁定"), zap.Strings("interfaces", interfaces))
+		}
+	}
+
+	if e.config.Scenario.Workers > 0 {
+		e.mu.Lock()
+		e.updater = newScenarioUpdater(e.config.Scenario.Workers, e.config.Scenario.BatchSize, e.logger)
+		e.mu.Unlock()
 	}
 
 	// 建立並啟動 Slaves
@@ -104,6 +311,18 @@ func (e *Engine) Start(ctx context.Context) error {
 	errChan := make(chan error, len(ips))
 	semaphore := make(chan struct{}, 100) // 限制並發啟動數量
 
+	var pendingMu sync.Mutex
+	var pending []*pendingBind
+
+	e.stats.ConfiguredSlaves = min(len(ips), e.config.Slaves.Count)
+
+	// 設定 ramp_up_rate 時改由背景依速率啟動
+	var ramp []net.IP
+	if e.config.Slaves.RampUpRate > 0 {
+		ramp = ips[:min(len(ips), e.config.Slaves.Count)]
+		ips = nil
+	}
+
 	for i, ip := range ips {
 		if i >= e.config.Slaves.Count {
 			break
@@ -116,17 +335,19 @@ func (e *Engine) Start(ctx context.Context) error {
 			semaphore <- struct{}{}
 			defer func() { <-semaphore }()
 
-			unitID := uint8((int(e.config.Slaves.UnitIDStart) + idx - 1) % 255 + 1)
-			slave := NewSlave(
-				ip,
-				e.config.Server.Port,
-				e.config,
-				WithUnitID(unitID),
-				WithLogger(e.logger.With(zap.String("slave_id", fmt.Sprintf("%s:%d", ip.String(), e.config.Server.Port)))),
-			)
-
-			if err := slave.Start(ctx); err != nil {
-				errChan <- fmt.Errorf("啟動 Slave %s 失敗: %w", ip.String(), err)
+			slave, err := e.startSlave(ctx, ip, idx)
+			if err != nil {
+				// 位址被占用時個別記錄，並視配置排入重試
+				var conflict *BindConflictError
+				if errors.As(err, &conflict) {
+					e.recordBindConflict(ip, conflict)
+					if e.config.Server.BindRetryInterval > 0 {
+						pendingMu.Lock()
+						pending = append(pending, &pendingBind{ip: ip, index: idx})
+						pendingMu.Unlock()
+					}
+				}
+				errChan <- err
 				return
 			}
 
@@ -147,22 +368,102 @@ func (e *Engine) Start(ctx context.Context) error {
 	}
 
 	if len(errors) > 0 {
-		e.logger.Warn("部分 Slaves 啟動失敗",
+		e.logger.Warn(T("部分 Slaves 啟動失敗"),
 			zap.Int("failed", len(errors)),
 			zap.Int("success", len(e.slaves)),
+			zap.Uint64("bind_conflicts", e.bindConflicts.Load()),
+			zap.Int("retrying", len(pending)),
 		)
-		// 如果所有 Slaves 都失敗，返回錯誤
-		if len(e.slaves) == 0 {
+		// 如果所有 Slaves 都失敗且沒有待重試的，返回錯誤
+		if len(e.slaves) == 0 && len(pending) == 0 {
+			e.stopTracer()
+			e.closeAudit()
+			e.closeStateStore()
+			e.closeListeners()
 			e.state.Store(int32(EngineStateStopped))
-			return fmt.Errorf("所有 Slaves 啟動失敗: %v", errors[0])
+			return fmt.Errorf(T("所有 Slaves 啟動失敗: %v"), errors[0])
 		}
 	}
 
 	e.stats.SlaveCount = len(e.slaves)
 	e.stats.ActiveSlaves = len(e.slaves)
+
+	// 背景工作 (隨引擎停止而結束)
+	bgCtx, cancel := context.WithCancel(ctx)
+	e.bgCtx, e.cancel = bgCtx, cancel
+
+	// 在排程重試與逐步上線前還原，尚未啟動的 Slave 於啟動時還原
+	if checkpoint != nil {
+		if _, err := e.RestoreSnapshot(checkpoint); err != nil {
+			e.logger.Warn(T("還原 checkpoint 失敗"), zap.Error(err))
+		}
+	}
+	if e.store != nil {
+		go e.runCheckpoints(bgCtx, e.store)
+	}
+	if e.config.MQTT.Enabled {
+		// broker 無法連線時於背景重試，不影響模擬
+		e.sparkplug = NewSparkplugPublisher(e.config.MQTT, e, e.logger)
+		go e.sparkplug.Run(bgCtx)
+	}
+
+	e.initPairs()
+	for _, pair := range e.config.Redundancy.Pairs {
+		if pair.FailoverInterval > 0 {
+			go e.runFailoverScheduler(bgCtx)
+			break
+		}
+	}
+	if e.protectionEnabled() {
+		go e.runProtectionEnforcer(bgCtx)
+	}
+	if len(pending) > 0 {
+		e.bindPending.Store(int64(len(pending)))
+		go e.runBindRetry(bgCtx, pending)
+	}
+	if len(ramp) > 0 {
+		e.rampPending.Store(int64(len(ramp)))
+		e.logger.Info(T("Slave 將逐步上線"),
+			zap.Int("total", len(ramp)),
+			zap.Float64("rate", e.config.Slaves.RampUpRate),
+		)
+		go e.runRampUp(bgCtx, ramp)
+	}
+	go e.runChaos(bgCtx)
+	e.initDomains()
+	if len(e.config.FailureDomains) > 0 {
+		go e.runDomainScheduler(bgCtx)
+	}
+	e.initDrift()
+	if e.config.Drift.Interval > 0 {
+		go e.runDriftChecker(bgCtx)
+	}
+	if len(e.config.Decommission.Rules) > 0 {
+		e.initDecommission()
+		go e.runDecommissionScheduler(bgCtx)
+	}
+	if e.config.Diagnostics.Enabled {
+		// 自我監控設備為輔助功能，啟動失敗不影響模擬
+		if err := e.startDiagnostics(bgCtx); err != nil {
+			e.logger.Error(T("自我監控設備未啟動"), zap.Error(err))
+		}
+	}
+	if e.config.OPCUA.Enabled {
+		// OPC UA 伺服器為輔助功能，啟動失敗不影響模擬
+		if err := e.startOPCUA(); err != nil {
+			e.logger.Error(T("OPC UA 伺服器未啟動"), zap.Error(err))
+		}
+	}
+	if e.config.BACnet.Enabled && len(e.config.BACnet.Broadcast) > 0 {
+		// 各 Slave 仍回應直接送達的 Who-Is，廣播接收失敗不影響模擬
+		if err := e.startBACnetDiscovery(); err != nil {
+			e.logger.Error(T("BACnet Who-Is 廣播接收未啟動"), zap.Error(err))
+		}
+	}
+
 	e.state.Store(int32(EngineStateRunning))
 
-	e.logger.Info("引擎啟動完成",
+	e.logger.Info(T("引擎啟動完成"),
 		zap.Int("active_slaves", e.stats.ActiveSlaves),
 		zap.Duration("startup_time", time.Since(e.stats.StartTime)),
 	)
@@ -170,13 +471,176 @@ func (e *Engine) Start(ctx context.Context) error {
 	return nil
 }
 
+// startSlave 建立並啟動指定 IP 的 Slave (idx 決定 Unit ID)
+func (e *Engine) startSlave(ctx context.Context, ip net.IP, idx int) (*Slave, error) {
+	if e.pod != nil {
+		// 每個 Pod 依 StatefulSet 序號接續前一個 Pod 的 Unit ID
+		idx = e.pod.Ordinal
+	}
+	unitID := uint8((int(e.config.Slaves.UnitIDStart)+idx-1)%255 + 1)
+	profileName := e.config.Slaves.Profile
+	units := e.config.Slaves.Units
+	if len(units) > 0 {
+		// 第一個邏輯設備為主設備
+		unitID = units[0].UnitID
+		if units[0].Profile != "" {
+			profileName = units[0].Profile
+		}
+	}
+	opts := []SlaveOption{
+		WithUnitID(unitID),
+		WithLogger(e.logger.With(zap.String("slave_id", fmt.Sprintf("%s:%d", ip.String(), e.config.Server.Port)))),
+		WithLatencyHistogram(e.latency),
+		WithScenarioTuning(e.tuning),
+		WithSeed(e.seed.Load()),
+	}
+	if e.pod != nil {
+		opts = append(opts, WithSeedKey(e.pod.seedKey()))
+	}
+	if e.tracer != nil {
+		opts = append(opts, WithTracer(e.tracer))
+	}
+	if e.polls != nil {
+		opts = append(opts, WithPollTracker(e.polls))
+	}
+	if e.audit != nil {
+		opts = append(opts, WithAuditLog(e.audit))
+	}
+	e.mu.RLock()
+	pool, updater := e.listeners, e.updater
+	e.mu.RUnlock()
+	if rule, ok := e.config.aclRule(ip); ok {
+		opts = append(opts, WithClientACL(rule.Allow, rule.Action))
+	}
+	if len(e.config.Server.ExtraPorts) > 0 {
+		opts = append(opts, WithExtraPorts(e.config.Server.ExtraPorts...))
+	}
+	if transport := e.config.Network.TransportFor(ip); transport == TransportUDP {
+		opts = append(opts, WithTransport(transport))
+	} else if pool != nil {
+		opts = append(opts, WithListenerPool(pool))
+	}
+	if updater != nil {
+		opts = append(opts, WithScenarioUpdater(updater))
+	}
+	if iface, explicit := e.config.Network.InterfaceFor(ip); explicit {
+		opts = append(opts, WithInterface(iface))
+	}
+	if e.config.IEC104.serves(profileName) {
+		opts = append(opts, WithIEC104(e.config.IEC104.Port))
+	}
+	if bacnet := &e.config.BACnet; bacnet.serves(e.config, ip) {
+		opts = append(opts, WithBACnet(bacnet.Port, bacnet.deviceInstance(ip), bacnet.Registers))
+	}
+	for _, rule := range e.config.writeHookRules(ip) {
+		opts = append(opts, WithWriteHook(rule.hook()))
+	}
+	if limit := e.config.Slaves.RequestRateLimit; limit > 0 {
+		opts = append(opts, WithRequestRateLimit(limit, e.config.Slaves.RequestBurst))
+	}
+	for _, rule := range e.config.functionOverrideRules(ip, profileName) {
+		opts = append(opts, WithFunctionOverride(rule.override(), rule.Functions...))
+	}
+	refresh := e.config.Slaves.RefreshInterval
+	if profile, ok := GetDeviceProfile(profileName); ok {
+		if refresh == 0 {
+			refresh = profile.RefreshInterval
+		}
+		rm, err := e.newSlaveRegisters(profile)
+		if err != nil {
+			return nil, fmt.Errorf(T("建立 Slave %s 暫存器失敗: %w"), ip.String(), err)
+		}
+		opts = append(opts, WithRegisters(rm), WithSupportedFunctions(e.config.profileFunctions(profile)...))
+		if profile.NewModel != nil {
+			opts = append(opts, WithModel(profile.NewModel()))
+		}
+	}
+	if refresh > 0 {
+		opts = append(opts, WithRefreshInterval(refresh))
+	}
+	for _, unit := range units[min(1, len(units)):] {
+		opt, err := e.unitOption(unit)
+		if err != nil {
+			return nil, fmt.Errorf(T("建立 Slave %s 暫存器失敗: %w"), ip.String(), err)
+		}
+		opts = append(opts, opt)
+	}
+	slave := NewSlave(ip, e.config.Server.Port, e.config, opts...)
+	if e.config.Slaves.Randomize.Enabled {
+		random := newLockedRand(slaveSeed(e.seed.Load(), slave.seedKey, randomStreamNominal))
+		randomizeNominal(scenarioRegisters(slave.Registers(), slave.model), e.config.Slaves.Randomize, random)
+		for _, u := range slave.units {
+			random := newLockedRand(slaveSeed(e.seed.Load(), u.seedID(slave.seedKey), randomStreamNominal))
+			randomizeNominal(scenarioRegisters(u.registers, u.model), e.config.Slaves.Randomize, random)
+		}
+	}
+
+	if err := slave.Start(ctx); err != nil {
+		return nil, fmt.Errorf(T("啟動 Slave %s 失敗: %w"), ip.String(), err)
+	}
+	e.restorePending(slave)
+	return slave, nil
+}
+
+// unitOption 建立閘道後方的邏輯設備 (設定檔未指定時沿用 slaves.profile)
+func (e *Engine) unitOption(unit UnitConfig) (SlaveOption, error) {
+	profileName := unit.Profile
+	if profileName == "" {
+		profileName = e.config.Slaves.Profile
+	}
+	profile, ok := GetDeviceProfile(profileName)
+	if !ok {
+		return WithUnit(unit.UnitID, DefaultRegisterMap(), nil), nil
+	}
+	rm, err := e.newSlaveRegisters(profile)
+	if err != nil {
+		return nil, err
+	}
+	var model DeviceModel
+	if profile.NewModel != nil {
+		model = profile.NewModel()
+	}
+	return WithUnit(unit.UnitID, rm, model, e.config.profileFunctions(profile)...), nil
+}
+
+// newSlaveRegisters 建立 Slave 的暫存器：shared 模式為設定檔共用基準的 copy-on-write 複本，
+// independent 模式各自依設定檔建立
+func (e *Engine) newSlaveRegisters(profile *DeviceProfile) (*RegisterMap, error) {
+	if e.config.Slaves.RegisterSharing == RegisterSharingIndependent {
+		return profile.NewRegisterMap()
+	}
+
+	e.profileRegsMu.Lock()
+	defer e.profileRegsMu.Unlock()
+
+	baseline, ok := e.profileRegs[profile]
+	if !ok {
+		var err error
+		if baseline, err = profile.NewRegisterMap(); err != nil {
+			return nil, err
+		}
+		if e.profileRegs == nil {
+			e.profileRegs = make(map[*DeviceProfile]*RegisterMap)
+		}
+		e.profileRegs[profile] = baseline
+	}
+	return baseline.Clone(), nil
+}
+
 // Stop 停止引擎
 func (e *Engine) Stop(ctx context.Context) error {
 	if !e.state.CompareAndSwap(int32(EngineStateRunning), int32(EngineStateStopping)) {
 		return nil
 	}
 
-	e.logger.Info("正在停止引擎", zap.Int("slave_count", len(e.slaves)))
+	e.logger.Info(T("正在停止引擎"), zap.Int("slave_count", len(e.slaves)))
+
+	if e.cancel != nil {
+		e.cancel()
+	}
+	e.closeSparkplug(ctx)
+	e.stopOPCUA()
+	e.stopBACnetDiscovery()
 
 	var wg sync.WaitGroup
 	semaphore := make(chan struct{}, 100)
@@ -188,6 +652,15 @@ func (e *Engine) Stop(ctx context.Context) error {
 	}
 	e.mu.RUnlock()
 
+	// 停止前保存最後一次 checkpoint
+	if e.store != nil {
+		e.checkpoint(context.Background(), e.store, slaves, 0)
+	}
+
+	if e.config.Slaves.RampDownRate > 0 {
+		slaves = e.rampDown(ctx, slaves)
+	}
+
 	for _, slave := range slaves {
 		wg.Add(1)
 		go func(s *Slave) {
@@ -197,7 +670,7 @@ func (e *Engine) Stop(ctx context.Context) error {
 			defer func() { <-semaphore }()
 
 			if err := s.Stop(ctx); err != nil {
-				e.logger.Warn("停止 Slave 失敗",
+				e.logger.Warn(T("停止 Slave 失敗"),
 					zap.String("id", s.ID),
 					zap.Error(err),
 				)
@@ -215,19 +688,80 @@ func (e *Engine) Stop(ctx context.Context) error {
 	select {
 	case <-done:
 	case <-ctx.Done():
-		e.logger.Warn("停止引擎超時")
+		e.logger.Warn(T("停止引擎超時"))
 	}
 
 	e.mu.Lock()
 	e.slaves = make(map[string]*Slave)
 	e.mu.Unlock()
 
+	e.stopDiagnostics(ctx)
+	e.stopTracer()
+	e.closeAudit()
+	e.closeStateStore()
+	e.closeListeners()
+	e.closeUpdater()
+
 	e.state.Store(int32(EngineStateStopped))
-	e.logger.Info("引擎已停止")
+	e.logger.Info(T("引擎已停止"))
 
 	return nil
 }
 
+// stopTracer 停止追蹤器並送出剩餘的 span
+func (e *Engine) stopTracer() {
+	if e.tracer != nil {
+		e.tracer.Stop()
+		e.tracer = nil
+	}
+}
+
+// closeUpdater 停止集中式場景更新器
+func (e *Engine) closeUpdater() {
+	e.mu.Lock()
+	updater := e.updater
+	e.updater = nil
+	e.mu.Unlock()
+	if updater != nil {
+		updater.Close()
+	}
+}
+
+// closeListeners 關閉 shared 模式的共用 listener (保留以便停止後仍可查詢統計)
+func (e *Engine) closeListeners() {
+	e.mu.RLock()
+	pool := e.listeners
+	e.mu.RUnlock()
+	if pool != nil {
+		pool.Close()
+	}
+}
+
+// closeAudit 寫出並關閉稽核日誌
+func (e *Engine) closeAudit() {
+	if e.audit != nil {
+		if err := e.audit.Close(); err != nil {
+			e.logger.Warn(T("關閉稽核日誌失敗"), zap.Error(err))
+		}
+		e.audit = nil
+	}
+}
+
+// Latency 請求延遲直方圖
+func (e *Engine) Latency() *LatencyHistogram {
+	return e.latency
+}
+
+// ScenarioTimeline 整體場景的切換次數與停留時間
+func (e *Engine) ScenarioTimeline() *ScenarioTimeline {
+	return e.scenarios
+}
+
+// Polls 輪詢分析器 (未啟用時為 nil)
+func (e *Engine) Polls() *PollTracker {
+	return e.polls
+}
+
 // GetSlave 取得指定 IP 的 Slave
 func (e *Engine) GetSlave(ip net.IP) (*Slave, bool) {
 	e.mu.RLock()
@@ -266,10 +800,18 @@ func (e *Engine) State() EngineState {
 
 // Stats 取得統計資訊
 func (e *Engine) Stats() EngineStats {
+	// 先於 e.mu 之外取得 (Failover / DomainOutage 持有各自的鎖時會查詢 Slaves)
+	failovers := e.totalFailovers()
+	domainOutages, domainsDown := e.domainStats()
+	driftedSlaves := e.DriftedSlaves()
+	decommissioned := e.DecommissionedSlaves()
+	chaosDisturbances, chaosSlaves := e.chaosStats()
+
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 
 	stats := e.stats
+	stats.Seed = e.seed.Load()
 
 	// 彙整所有 Slaves 的統計
 	for _, slave := range e.slaves {
@@ -278,6 +820,35 @@ func (e *Engine) Stats() EngineStats {
 		stats.TotalErrors += slaveStats.ErrorCount.Load()
 		stats.BytesReceived += slaveStats.BytesReceived.Load()
 		stats.BytesSent += slaveStats.BytesSent.Load()
+		stats.TotalFlaps += slaveStats.FlapCount.Load()
+		stats.RejectedConnections += slaveStats.RejectedConns.Load()
+		stats.ForcedDisconnects += slaveStats.ForcedDisconnects.Load()
+		stats.RateLimitedRequests += slaveStats.RateLimited.Load()
+		stats.TimedOutConnections += slaveStats.TimedOutConns.Load()
+		stats.ActiveConnections += slave.ConnCount()
+		if slave.State() == SlaveStateOffline {
+			stats.OfflineSlaves++
+		}
+		if slave.IsStandby() {
+			stats.StandbySlaves++
+		}
+	}
+	stats.ActiveSlaves -= stats.OfflineSlaves + stats.StandbySlaves
+	stats.TotalFailovers = failovers
+	stats.SuppressedInjections = e.suppressedCount.Load()
+	stats.DomainOutages = domainOutages
+	stats.DomainsDown = domainsDown
+	stats.BindConflicts = e.bindConflicts.Load()
+	stats.BindPending = e.BindPending()
+	stats.RampPending = e.RampPending()
+	stats.DriftedSlaves = driftedSlaves
+	stats.DecommissionedSlaves = decommissioned
+	stats.ChaosDisturbances, stats.ChaosSlaves = chaosDisturbances, chaosSlaves
+	if e.listeners != nil {
+		stats.UnroutedConnections = e.listeners.Unrouted()
+	}
+	if e.polls != nil {
+		stats.RedundantPolls = e.polls.Redundant()
 	}
 
 	return stats
@@ -287,14 +858,40 @@ func (e *Engine) Stats() EngineStats {
 func (e *Engine) ApplyScenario(scenario ScenarioType) error {
 	e.mu.Lock()
 	e.currentScenario = scenario
+	e.scenarios.Enter(scenario, time.Now())
 	e.mu.Unlock()
 
-	e.logger.Info("套用場景", zap.String("scenario", scenario.String()))
+	// 離開混沌模式時先結束進行中的擾動
+	if scenario != ScenarioChaos {
+		e.stopChaos()
+	}
+
+	// 若場景設定了 targets，僅套用至符合的 Slaves
+	targets := e.config.Scenario.Scenarios[scenario.String()].Targets
 
+	applied := 0
+	now := time.Now()
 	for _, slave := range e.ListSlaves() {
+		if !MatchTargets(slave.IP, targets) {
+			continue
+		}
+		// 受保護的 Slave 不注入故障，保護結束後由 enforcer 恢復
+		if scenario != ScenarioNormal {
+			if reason := e.protectionReason(slave.IP, now); reason != "" {
+				e.suppressScenario(slave, scenario, reason)
+				continue
+			}
+		}
+		e.clearSuppressed(slave)
 		slave.ApplyScenario(scenario)
+		applied++
 	}
 
+	e.logger.Info(T("套用場景"),
+		zap.String("scenario", scenario.String()),
+		zap.Int("slaves", applied),
+	)
+
 	return nil
 }
 
@@ -307,6 +904,11 @@ func (e *Engine) GetScenario() ScenarioType {
 
 // getBindIPs 取得要綁定的 IP 列表
 func (e *Engine) getBindIPs() ([]net.IP, error) {
+	// 以 Pod 身分運行時於 Pod 的 IP 上監聽 (映像內配置檔的 IP 範圍不適用於 Pod)
+	if e.pod != nil {
+		return []net.IP{e.pod.IP}, nil
+	}
+
 	// 如果有配置 IP 範圍，先展開再驗證
 	if len(e.config.Network.IPRanges) > 0 {
 		configuredIPs, err := e.config.ExpandIPRanges()
@@ -314,15 +916,36 @@ func (e *Engine) getBindIPs() ([]net.IP, error) {
 			return nil, err
 		}
 
+		// 以 SO_ORIGINAL_DST 分派時，目的 IP 由 iptables 轉向而來，不需存在於本機
+		if e.config.Server.Listener == ListenerShared && e.config.Server.OriginalDst {
+			return configuredIPs, nil
+		}
+
 		// 取得本機可用 IP
 		localSet := getLocalIPSet()
 
-		// 過濾出本機實際存在的 IP
+		// 過濾出本機實際存在的 IP；範圍指定了網路介面時，IP 須位於該介面上
 		var available []net.IP
+		ifaceSets := make(map[string]map[string]bool)
 		for _, ip := range configuredIPs {
-			if localSet[ip.String()] {
-				available = append(available, ip)
+			if !localSet[ip.String()] {
+				continue
+			}
+			if iface, explicit := e.config.Network.InterfaceFor(ip); explicit {
+				set, ok := ifaceSets[iface]
+				if !ok {
+					set = getInterfaceIPSet(iface)
+					ifaceSets[iface] = set
+				}
+				if !set[ip.String()] {
+					e.logger.Warn(T("IP 不在指定的網路介面上，略過"),
+						zap.String("ip", ip.String()),
+						zap.String("interface", iface),
+					)
+					continue
+				}
 			}
+			available = append(available, ip)
 		}
 
 		if len(available) > 0 {
@@ -330,7 +953,7 @@ func (e *Engine) getBindIPs() ([]net.IP, error) {
 		}
 
 		// 配置的 IP 都不在本機上，回退到 0.0.0.0
-		e.logger.Warn("配置的 IP 範圍不存在於本機，回退為 0.0.0.0",
+		e.logger.Warn(T("配置的 IP 範圍不存在於本機，回退為 0.0.0.0"),
 			zap.Int("configured", len(configuredIPs)),
 		)
 	}
@@ -402,3 +1025,25 @@ func getLocalIPSet() map[string]bool {
 
 	return set
 }
+
+// getInterfaceIPSet 取得指定網路介面上的 IPv4 集合 (介面不存在時為空)
+func getInterfaceIPSet(name string) map[string]bool {
+	set := make(map[string]bool)
+
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return set
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return set
+	}
+
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+			set[ipNet.IP.String()] = true
+		}
+	}
+
+	return set
+}
@@ -4,11 +4,11 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/tbrandon/mbserver"
 	"go.uber.org/zap"
 )
 
@@ -20,6 +20,8 @@ const (
 	SlaveStateStarting
 	SlaveStateRunning
 	SlaveStateStopping
+	SlaveStateOffline
+	SlaveStateStandby
 )
 
 func (s SlaveState) String() string {
@@ -32,6 +34,10 @@ func (s SlaveState) String() string {
 		return "running"
 	case SlaveStateStopping:
 		return "stopping"
+	case SlaveStateOffline:
+		return "offline"
+	case SlaveStateStandby:
+		return "standby"
 	default:
 		return "unknown"
 	}
@@ -53,12 +59,99 @@ type Slave struct {
 	// 暫存器
 	registers *RegisterMap
 
-	// Modbus Server
-	server *mbserver.Server
+	// 設備模型 (選用)
+	model DeviceModel
+
+	// 同一端點以其他 Unit ID 定址的邏輯設備 (nil 表示主設備回應任何 Unit ID)
+	units map[uint8]*unitDevice
+
+	// 對外提供的暫存器 (由 s.mu 保護) 與請求處理器
+	image   registerImage
+	handler *RequestHandler
+
+	// TCP 接入層
+	listenMu sync.Mutex
+	listener *slaveListener
+
+	// 所有埠號上的 Modbus TCP 連線數
+	conns atomic.Int32
+
+	// 單一 Master queue 模式下正在服務的連線 (容量 1)
+	master chan struct{}
+
+	// Modbus 以外的南向協定 (IEC 104、BACnet/IP 等) 與開啟中的接入層 (由 listenMu 保護)
+	protocols         []func(s *Slave) protocolListener
+	protocolListeners []protocolListener
+
+	// 限定收送封包的網路介面 (空字串表示僅依 IP 綁定)
+	iface string
+
+	// Modbus 傳輸方式 (空字串為 tcp；udp 時不使用共用 listener)
+	transport string
+
+	// 用戶端允許清單 (nil 表示不限制)
+	acl *clientACL
+
+	// 主設備支援的功能碼與各功能碼的行為覆寫 (建立後唯讀)
+	functions functionSet
+	overrides map[uint8]FunctionOverride
+
+	// 請求速率限制 (nil 表示不限制)
+	limiter *requestLimiter
+
+	// shared 模式的共用 listener (nil 表示自行監聽)
+	pool *listenerPool
+
+	// 集中式場景更新器 (nil 表示自行以 ticker 更新)
+	updater *scenarioUpdater
+
+	// 執行期間調整的場景參數 (nil 表示只使用配置檔)
+	tuning *scenarioTuning
+
+	// 亂數種子 (0 表示使用場景共用的亂數來源) 與導出序列的識別 (預設為 ID)
+	seed    int64
+	seedKey string
+
+	// 設備時鐘、需量計算與多費率電能 (未啟用時為 nil)
+	clock  *deviceClock
+	demand *demandMeter
+	tariff *tariffMeter
+
+	// 斷線模擬
+	flapChangedAt time.Time
+	churnAt       time.Time // connection_churn 場景上次中斷連線的時間
+
+	// 備援配對 (silent 模式下的備援端)
+	silentStandby atomic.Bool
+
+	// 所屬故障域停擺中 (期間不因斷線閃斷恢復上線)
+	domainOutage atomic.Bool
+
+	// 開機風暴斷電中 (期間不恢復上線)
+	powerOff atomic.Bool
+
+	// 識別閃爍 (由 s.mu 保護)
+	blink *blinkState
+
+	// 寫入回呼 (由 s.mu 保護)
+	writeHooks []WriteHook
 
 	// 統計
 	stats SlaveStats
 
+	// 請求延遲與追蹤 (選用，由引擎共用)
+	latency *LatencyHistogram
+	tracer  *Tracer
+
+	// 輪詢分析 (選用，由引擎共用)
+	polls *PollTracker
+
+	// 請求稽核日誌 (選用，由引擎共用)
+	audit *AuditLog
+
+	// 量測值內部更新週期 (0 表示依 scenario.update_interval)
+	refreshInterval time.Duration
+
 	// 場景
 	scenario     ScenarioType
 	scenarioCtx  context.Context
@@ -79,6 +172,11 @@ type SlaveStats struct {
 	LastRequestTime atomic.Int64
 	BytesReceived   atomic.Uint64
 	BytesSent       atomic.Uint64
+	FlapCount       atomic.Uint64
+	ForcedDisconnects atomic.Uint64
+	RateLimited     atomic.Uint64
+	RejectedConns   atomic.Uint64
+	TimedOutConns   atomic.Uint64
 }
 
 // SlaveOption Slave 配置選項
@@ -98,6 +196,101 @@ func WithRegisters(rm *RegisterMap) SlaveOption {
 	}
 }
 
+// WithModel 設定設備模型
+func WithModel(model DeviceModel) SlaveOption {
+	return func(s *Slave) {
+		s.model = model
+	}
+}
+
+// WithLatencyHistogram 設定請求延遲直方圖
+func WithLatencyHistogram(h *LatencyHistogram) SlaveOption {
+	return func(s *Slave) {
+		s.latency = h
+	}
+}
+
+// WithTracer 設定追蹤器
+func WithTracer(t *Tracer) SlaveOption {
+	return func(s *Slave) {
+		s.tracer = t
+	}
+}
+
+// WithPollTracker 設定輪詢分析器
+func WithPollTracker(t *PollTracker) SlaveOption {
+	return func(s *Slave) {
+		s.polls = t
+	}
+}
+
+// WithAuditLog 設定請求稽核日誌
+func WithAuditLog(a *AuditLog) SlaveOption {
+	return func(s *Slave) {
+		s.audit = a
+	}
+}
+
+// WithRefreshInterval 設定量測值內部更新週期
+func WithRefreshInterval(d time.Duration) SlaveOption {
+	return func(s *Slave) {
+		s.refreshInterval = d
+	}
+}
+
+// WithInterface 設定限定收送封包的網路介面
+func WithInterface(name string) SlaveOption {
+	return func(s *Slave) {
+		s.iface = name
+	}
+}
+
+// WithExtraPorts 除主要埠號外同時在其他埠號上提供 Modbus (共用同一份暫存器與狀態)
+func WithExtraPorts(ports ...int) SlaveOption {
+	return func(s *Slave) {
+		for _, port := range ports {
+			s.protocols = append(s.protocols, func(s *Slave) protocolListener {
+				return newSlaveListener(s, port)
+			})
+		}
+	}
+}
+
+// WithTransport 設定 Modbus 傳輸方式 (tcp 或 udp)
+func WithTransport(transport string) SlaveOption {
+	return func(s *Slave) {
+		s.transport = transport
+	}
+}
+
+// WithListenerPool 改由共用 listener 接受連線 (shared 模式)
+func WithListenerPool(p *listenerPool) SlaveOption {
+	return func(s *Slave) {
+		s.pool = p
+	}
+}
+
+// WithScenarioUpdater 改由集中式場景更新器分批更新
+func WithScenarioUpdater(u *scenarioUpdater) SlaveOption {
+	return func(s *Slave) {
+		s.updater = u
+	}
+}
+
+// WithSeed 以全域種子導出此 Slave 獨立的亂數序列 (延遲抖動、封包丟失與量測值波動)
+func WithSeed(seed int64) SlaveOption {
+	return func(s *Slave) {
+		s.seed = seed
+	}
+}
+
+// WithSeedKey 以 key 取代 ID 導出亂數序列 (IP 會變動的環境，例如 Kubernetes Pod，仍維持相同的序列)
+func WithSeedKey(key string) SlaveOption {
+	return func(s *Slave) {
+		s.seedKey = key
+	}
+}
+
 // WithLogger 設定日誌
 func WithLogger(logger *zap.Logger) SlaveOption {
 	return func(s *Slave) {
@@ -115,6 +308,7 @@ func NewSlave(ip net.IP, port int, config *Config, opts ...SlaveOption) *Slave {
 		registers: DefaultRegisterMap(),
 		config:    config,
 		scenario:  ScenarioNormal,
+		master:    make(chan struct{}, 1),
 	}
 
 	for _, opt := range opts {
@@ -124,6 +318,20 @@ func NewSlave(ip net.IP, port int, config *Config, opts ...SlaveOption) *Slave {
 	if s.logger == nil {
 		s.logger, _ = zap.NewProduction()
 	}
+	if s.seedKey == "" {
+		s.seedKey = s.ID
+	}
+	s.handler = NewRequestHandler(s, s.logger)
+	if s.seed != 0 {
+		s.handler.random = newLockedRand(slaveSeed(s.seed, s.seedKey, randomStreamRequests))
+		s.registers.SetRandom(newLockedRand(slaveSeed(s.seed, s.seedKey, randomStreamScenario)))
+		for _, u := range s.units {
+			u.registers.SetRandom(newLockedRand(slaveSeed(s.seed, u.seedID(s.seedKey), randomStreamScenario)))
+		}
+	}
+	s.initDeviceClock()
+	s.initDemand()
+	s.initTariff()
 
 	return s
 }
@@ -131,31 +339,49 @@ func NewSlave(ip net.IP, port int, config *Config, opts ...SlaveOption) *Slave {
 // Start 啟動 Slave
 func (s *Slave) Start(ctx context.Context) error {
 	if !s.state.CompareAndSwap(int32(SlaveStateStopped), int32(SlaveStateStarting)) {
-		return fmt.Errorf("slave %s 已經在運行中", s.ID)
+		return fmt.Errorf(T("slave %s 已經在運行中"), s.ID)
 	}
 
-	// 建立 mbserver
-	s.server = mbserver.NewServer()
-
-	// 設定暫存器資料
+	// 設定對外提供的暫存器
+	s.mu.Lock()
 	s.syncRegistersToServer()
+	s.mu.Unlock()
 
-	// 啟動伺服器 (ListenTCP 同步建立 listener，內部以 goroutine accept)
+	// 啟動 TCP 接入層 (同步建立 listener，內部以 goroutine accept)
 	s.stats.StartTime = time.Now()
-	addr := fmt.Sprintf("%s:%d", s.IP.String(), s.Port)
-
-	if err := s.server.ListenTCP(addr); err != nil {
+	addr := s.listenAddr()
+
+	s.listenMu.Lock()
+	s.listener = newSlaveListener(s, s.Port)
+	err := s.listener.Listen()
+	if err == nil {
+		var failed string
+		if failed, err = s.listenProtocolsLocked(); err != nil {
+			s.listener.Close()
+			s.listener = nil
+			addr = failed
+		}
+	}
+	s.listenMu.Unlock()
+	if err != nil {
 		s.state.Store(int32(SlaveStateStopped))
-		return fmt.Errorf("監聽 %s 失敗: %w", addr, err)
+		if isAddrInUse(err) {
+			return newBindConflictError(addr, err)
+		}
+		return fmt.Errorf(T("監聽 %s 失敗: %w"), addr, err)
 	}
 
 	// 啟動場景更新
 	s.scenarioCtx, s.scenarioStop = context.WithCancel(ctx)
-	go s.runScenarioUpdater()
+	if s.updater != nil {
+		s.updater.add(s, s.updateInterval())
+	} else {
+		go s.runScenarioUpdater()
+	}
 
 	s.state.Store(int32(SlaveStateRunning))
 
-	s.logger.Info("Slave 已啟動",
+	s.logger.Info(T("Slave 已啟動"),
 		zap.String("id", s.ID),
 		zap.String("addr", addr),
 		zap.Uint8("unitID", s.UnitID),
@@ -166,7 +392,9 @@ func (s *Slave) Start(ctx context.Context) error {
 
 // Stop 停止 Slave
 func (s *Slave) Stop(ctx context.Context) error {
-	if !s.state.CompareAndSwap(int32(SlaveStateRunning), int32(SlaveStateStopping)) {
+	if !s.state.CompareAndSwap(int32(SlaveStateRunning), int32(SlaveStateStopping)) &&
+		!s.state.CompareAndSwap(int32(SlaveStateOffline), int32(SlaveStateStopping)) &&
+		!s.state.CompareAndSwap(int32(SlaveStateStandby), int32(SlaveStateStopping)) {
 		return nil // 已經停止
 	}
 
@@ -174,15 +402,22 @@ func (s *Slave) Stop(ctx context.Context) error {
 	if s.scenarioStop != nil {
 		s.scenarioStop()
 	}
+	if s.updater != nil {
+		s.updater.remove(s)
+	}
 
-	// 關閉伺服器
-	if s.server != nil {
-		s.server.Close()
+	// 關閉 listener 與既有連線
+	s.listenMu.Lock()
+	if s.listener != nil {
+		s.listener.Close()
+		s.listener = nil
 	}
+	s.closeProtocolsLocked()
+	s.listenMu.Unlock()
 
 	s.state.Store(int32(SlaveStateStopped))
 
-	s.logger.Info("Slave 已停止",
+	s.logger.Info(T("Slave 已停止"),
 		zap.String("id", s.ID),
 		zap.Duration("uptime", time.Since(s.stats.StartTime)),
 		zap.Uint64("requests", s.stats.RequestCount.Load()),
@@ -201,6 +436,11 @@ func (s *Slave) GetStats() *SlaveStats {
 	return &s.stats
 }
 
+// ConnCount 目前的 Modbus TCP 連線數 (所有埠號合計，未監聽時為 0)
+func (s *Slave) ConnCount() int {
+	return int(s.conns.Load())
+}
+
 // Registers 取得暫存器映射
 func (s *Slave) Registers() *RegisterMap {
 	return s.registers
@@ -211,6 +451,17 @@ func (s *Slave) ApplyScenario(scenario ScenarioType) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.scenario = scenario
+	s.flapChangedAt = time.Time{}
+	s.churnAt = time.Time{}
+
+	handler := s.scenarioHandler(scenario)
+	s.handler.applyScenario(handler, s.scenarioParams(scenario))
+	if activator, ok := handler.(ScenarioActivator); ok {
+		activator.Activate(s.registers)
+		for _, u := range s.units {
+			activator.Activate(u.registers)
+		}
+	}
 }
 
 // GetScenario 取得當前場景
@@ -220,45 +471,245 @@ func (s *Slave) GetScenario() ScenarioType {
 	return s.scenario
 }
 
-// syncRegistersToServer 同步暫存器到 mbserver
-func (s *Slave) syncRegistersToServer() {
-	if s.server == nil {
-		return
+// listenAddr 監聽位址
+func (s *Slave) listenAddr() string {
+	ip := "0.0.0.0"
+	if s.IP != nil {
+		ip = s.IP.String()
 	}
+	return fmt.Sprintf("%s:%d", ip, s.Port)
+}
 
-	// mbserver 使用 byte slice 作為內部存儲
-	// Holding Registers: 位址 0-65535
-	holdingRegs := s.registers.GetRawHoldingRegisters()
-	s.server.HoldingRegisters = make([]uint16, len(holdingRegs))
-	copy(s.server.HoldingRegisters, holdingRegs)
+// GoOffline 模擬設備離線：關閉 listener 並中斷所有既有連線
+func (s *Slave) GoOffline() {
+	if s.suspend(SlaveStateOffline) {
+		s.stats.FlapCount.Add(1)
+		s.logger.Info(T("Slave 已離線 (模擬斷線)"), zap.String("id", s.ID))
+	}
+}
 
-	// Input Registers
-	inputRegs := s.registers.GetRawInputRegisters()
-	s.server.InputRegisters = make([]uint16, len(inputRegs))
-	copy(s.server.InputRegisters, inputRegs)
+// GoOnline 結束離線模擬，重新開始監聽
+func (s *Slave) GoOnline() error {
+	resumed, err := s.resume(SlaveStateOffline)
+	if resumed {
+		s.logger.Info(T("Slave 已恢復上線"), zap.String("id", s.ID))
+	}
+	return err
+}
+
+// heldOffline 是否因故障域停擺或開機風暴斷電而需維持離線 (由停擺/斷電結束時恢復上線)
+func (s *Slave) heldOffline() bool {
+	return s.domainOutage.Load() || s.powerOff.Load()
+}
 
-	// Coils
-	coils := s.registers.GetRawCoils()
-	s.server.Coils = make([]byte, (len(coils)+7)/8)
-	for i, coil := range coils {
-		if coil {
-			s.server.Coils[i/8] |= 1 << (i % 8)
+// SetStandby 設定備援角色
+// refuse 模式下備援端關閉 listener；silent 模式下接受連線但不回應
+func (s *Slave) SetStandby(standby bool, mode string) error {
+	if mode == StandbyModeSilent {
+		s.silentStandby.Store(standby)
+		return nil
+	}
+
+	if standby {
+		s.suspend(SlaveStateStandby)
+		return nil
+	}
+	_, err := s.resume(SlaveStateStandby)
+	return err
+}
+
+// IsStandby 是否為備援 (非作用中) 角色
+func (s *Slave) IsStandby() bool {
+	return s.State() == SlaveStateStandby || s.silentStandby.Load()
+}
+
+// suspend 由 Running 轉為指定狀態並關閉 listener
+func (s *Slave) suspend(to SlaveState) bool {
+	s.listenMu.Lock()
+	defer s.listenMu.Unlock()
+
+	if !s.state.CompareAndSwap(int32(SlaveStateRunning), int32(to)) {
+		return false
+	}
+
+	if s.listener != nil {
+		s.listener.Close()
+		s.listener = nil
+	}
+	s.closeProtocolsLocked()
+	return true
+}
+
+// resume 由指定狀態恢復為 Running 並重新監聽
+func (s *Slave) resume(from SlaveState) (bool, error) {
+	s.listenMu.Lock()
+	defer s.listenMu.Unlock()
+
+	if s.State() != from {
+		return false, nil
+	}
+
+	listener := newSlaveListener(s, s.Port)
+	if err := listener.Listen(); err != nil {
+		return false, fmt.Errorf(T("重新監聽 %s 失敗: %w"), s.listenAddr(), err)
+	}
+	if addr, err := s.listenProtocolsLocked(); err != nil {
+		listener.Close()
+		return false, fmt.Errorf(T("重新監聽 %s 失敗: %w"), addr, err)
+	}
+	if !s.state.CompareAndSwap(int32(from), int32(SlaveStateRunning)) {
+		// 期間已被停止
+		listener.Close()
+		s.closeProtocolsLocked()
+		return false, nil
+	}
+	s.listener = listener
+	return true, nil
+}
+
+// protocolListener Modbus 以外的南向協定接入層 (與 Modbus listener 一同開啟與關閉，離線模擬時同樣中斷)
+type protocolListener interface {
+	Listen() error
+	Close()
+	Addr() string
+}
+
+// protocolAddr 在 Slave 的 IP 上以指定埠監聽的位址
+func (s *Slave) protocolAddr(port int) string {
+	host, _, _ := net.SplitHostPort(s.listenAddr())
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// listenProtocolsLocked 開始監聽 Modbus 以外的協定 (任一失敗時關閉已開啟者並返回其位址；呼叫端需持有 s.listenMu)
+func (s *Slave) listenProtocolsLocked() (string, error) {
+	for _, newListener := range s.protocols {
+		listener := newListener(s)
+		if err := listener.Listen(); err != nil {
+			s.closeProtocolsLocked()
+			return listener.Addr(), err
 		}
+		s.protocolListeners = append(s.protocolListeners, listener)
+	}
+	return "", nil
+}
+
+// closeProtocolsLocked 關閉 Modbus 以外的協定接入層與既有連線 (呼叫端需持有 s.listenMu)
+func (s *Slave) closeProtocolsLocked() {
+	for _, listener := range s.protocolListeners {
+		listener.Close()
 	}
+	s.protocolListeners = nil
+}
 
-	// Discrete Inputs
-	discretes := s.registers.GetRawDiscreteInputs()
-	s.server.DiscreteInputs = make([]byte, (len(discretes)+7)/8)
-	for i, d := range discretes {
-		if d {
-			s.server.DiscreteInputs[i/8] |= 1 << (i % 8)
+// handleFrame 執行 Modbus 請求並返回回應位元組
+func (s *Slave) handleFrame(frame *tcpFrame) (response []byte, hasError bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	target, ok := s.target(frame.UnitID)
+	if !ok {
+		return frame.exception(ExceptionCodeGatewayTargetNoResponse), true
+	}
+	data, err := s.handler.HandleRequest(target, frame.Function, frame.Data)
+	if err != nil {
+		return frame.exception(exceptionCode(err)), true
+	}
+
+	// 回應超過 ADU 上限時以 Illegal Data Value 拒絕 (模擬緩衝區較小的設備)
+	response = frame.response(data)
+	if len(response) > s.maxADUSize() {
+		return frame.exception(ExceptionCodeIllegalDataValue), true
+	}
+	return response, false
+}
+
+// processFrame 處理請求；當前場景實作 ExceptionInjector 或 RequestInterceptor 時可能直接回應例外
+func (s *Slave) processFrame(frame *tcpFrame) (response []byte, hasError bool) {
+	_, handler, params := s.currentScenario()
+	if interceptor, ok := handler.(RequestInterceptor); ok {
+		if code, intercept := interceptor.InterceptRequest(s.unitRegisters(frame.UnitID), frame.Function, frame.Data, params); intercept {
+			// Acknowledge 表示設備已接受命令，寫入照常生效；場景變更的狀態暫存器立即同步，下次輪詢即可讀到
+			if code == ExceptionCodeAcknowledge {
+				s.handleFrame(frame)
+				s.mu.Lock()
+				s.syncRegistersToServer()
+				s.mu.Unlock()
+			}
+			return frame.exception(code), true
+		}
+	}
+	if injector, ok := handler.(ExceptionInjector); ok {
+		if code, inject := injector.InjectException(frame.Function, params); inject {
+			return frame.exception(code), true
 		}
 	}
+	return s.handleFrame(frame)
+}
+
+// maxADUSize ADU 上限 (未設定時為協定上限 260)
+func (s *Slave) maxADUSize() int {
+	if s.config == nil || s.config.Server.MaxADUSize == 0 {
+		return ModbusTCPMaxADULength
+	}
+	return s.config.Server.MaxADUSize
+}
+
+// downstreamTimeout 未配置的 Unit ID 以 0x0B 回應前的等待時間 (unknown_unit 非 gateway_exception 時 ok 為 false，不回應)
+func (s *Slave) downstreamTimeout() (timeout time.Duration, ok bool) {
+	if s.config == nil || s.config.Slaves.UnknownUnit != UnknownUnitGatewayException {
+		return 0, false
+	}
+	if s.config.Slaves.DownstreamTimeout == 0 {
+		return DefaultDownstreamTimeout, true
+	}
+	return s.config.Slaves.DownstreamTimeout, true
+}
+
+// maxConnections 同時連線數上限 (0 表示不限)
+func (s *Slave) maxConnections() int {
+	if s.config == nil {
+		return 0
+	}
+	if s.config.Server.SingleMaster == SingleMasterRefuse {
+		return 1
+	}
+	return s.config.Server.MaxConnections
+}
+
+// singleMaster 單一 Master 模式 (空字串表示停用)
+func (s *Slave) singleMaster() string {
+	if s.config == nil {
+		return ""
+	}
+	return s.config.Server.SingleMaster
+}
+
+// maxInFlight 每條連線同時處理的請求數 (1 表示依序處理)
+func (s *Slave) maxInFlight() int {
+	if s.config == nil || s.config.Server.MaxInFlight < 1 {
+		return 1
+	}
+	return s.config.Server.MaxInFlight
+}
+
+// connTimeouts 連線的讀取、寫入與閒置逾時 (0 表示不限)
+func (s *Slave) connTimeouts() (read, write, idle time.Duration) {
+	if s.config == nil {
+		return 0, 0, 0
+	}
+	return s.config.Server.ReadTimeout, s.config.Server.WriteTimeout, s.config.Server.IdleTimeout
+}
+
+// syncRegistersToServer 同步暫存器到對外提供的暫存器 (呼叫端需持有 s.mu)
+func (s *Slave) syncRegistersToServer() {
+	// 僅複製上次同步後寫入過的頁面，並沿用上次配置的記憶體，避免每個更新週期複製整個映射表
+	s.registers.SyncRawTo(&s.image.holding, &s.image.input, &s.image.coils, &s.image.discretes)
+	s.syncUnitsToServer()
 }
 
-// runScenarioUpdater 運行場景更新器
+// runScenarioUpdater 運行場景更新器 (量測值僅在每個週期更新一次，期間的輪詢讀到相同的值)
 func (s *Slave) runScenarioUpdater() {
-	ticker := time.NewTicker(s.config.Scenario.UpdateInterval)
+	ticker := time.NewTicker(s.updateInterval())
 	defer ticker.Stop()
 
 	for {
@@ -271,31 +722,117 @@ func (s *Slave) runScenarioUpdater() {
 	}
 }
 
-// updateByScenario 根據場景更新暫存器值
-func (s *Slave) updateByScenario() {
+// updateInterval 場景更新週期 (refresh_interval 優先於 scenario.update_interval)
+func (s *Slave) updateInterval() time.Duration {
+	if s.refreshInterval > 0 {
+		return s.refreshInterval
+	}
+	return s.config.Scenario.UpdateInterval
+}
+
+// currentScenario 取得當前場景、處理器與參數
+func (s *Slave) currentScenario() (ScenarioType, ScenarioHandler, ScenarioParams) {
 	s.mu.RLock()
 	scenario := s.scenario
 	s.mu.RUnlock()
 
-	handler := GetScenarioHandler(scenario)
-	if handler == nil {
-		return
-	}
+	return scenario, s.scenarioHandler(scenario), s.scenarioParams(scenario)
+}
 
+// scenarioParams 場景的參數 (執行期間調整過的優先；未配置時為零值)
+func (s *Slave) scenarioParams(scenario ScenarioType) ScenarioParams {
+	if params, ok := s.tuning.get(scenario); ok {
+		return params
+	}
 	params, ok := s.config.Scenario.Scenarios[scenario.String()]
 	if !ok {
 		params = ScenarioParams{}
 	}
+	return params
+}
 
-	// 更新暫存器值
-	handler.Update(s.registers, params)
+// updateByScenario 根據場景更新暫存器值
+func (s *Slave) updateByScenario() {
+	_, handler, params := s.currentScenario()
+	if handler == nil {
+		return
+	}
+
+	// 斷線模擬：離開 connection_flap 場景時恢復上線
+	if flap, ok := scenarioComponent(ScenarioConnectionFlap, handler, params); ok {
+		s.updateFlap(flap)
+	} else if s.State() == SlaveStateOffline && !s.heldOffline() {
+		if err := s.GoOnline(); err != nil {
+			s.logger.Warn(T("恢復上線失敗"), zap.String("id", s.ID), zap.Error(err))
+		}
+	}
 
-	// 同步到 mbserver
+	// 連線擾動：依 churn_rate 隨機中斷既有連線
+	if churn, ok := scenarioComponent(ScenarioConnectionChurn, handler, params); ok {
+		s.updateChurn(churn)
+	}
+
+	// 更新暫存器值與請求處理的延遲抖動、封包丟失 (配置重新載入後生效)
+	handler.Update(scenarioRegisters(s.registers, s.model), params)
+	s.handler.applyScenario(handler, params)
+	s.updateClockRegister()
+	s.updateDeviceClock()
+	s.updateDemand()
+	s.updateTariff()
+	now := scenarioNow()
+	if s.model != nil {
+		s.model.Update(s.registers, now)
+	}
+	s.updateUnits(handler, params, now)
+
+	// 同步到對外提供的暫存器 (識別閃爍優先於場景寫入的值)
 	s.mu.Lock()
+	s.applyBlinkLocked()
 	s.syncRegistersToServer()
 	s.mu.Unlock()
 }
 
+// updateFlap 依 flap_up/flap_down 交替切換上線與離線
+func (s *Slave) updateFlap(params ScenarioParams) {
+	up, down := params.FlapUp, params.FlapDown
+	if up == 0 {
+		up = 15 * time.Second
+	}
+	if down == 0 {
+		down = 5 * time.Second
+	}
+
+	now := scenarioNow()
+	s.mu.Lock()
+	elapsed := now.Sub(s.flapChangedAt)
+	if s.flapChangedAt.IsZero() {
+		elapsed = up // 剛套用場景時立即離線
+	}
+	s.mu.Unlock()
+
+	switch s.State() {
+	case SlaveStateRunning:
+		if elapsed < up {
+			return
+		}
+		s.GoOffline()
+	case SlaveStateOffline:
+		if elapsed < down || s.heldOffline() {
+			return
+		}
+		if err := s.GoOnline(); err != nil {
+			s.logger.Warn(T("恢復上線失敗"), zap.String("id", s.ID), zap.Error(err))
+			return
+		}
+	default:
+		return
+	}
+
+	s.mu.Lock()
+	s.flapChangedAt = now
+	s.mu.Unlock()
+}
+
 // recordRequest 記錄請求
 func (s *Slave) recordRequest(bytesIn, bytesOut int, hasError bool) {
 	s.stats.RequestCount.Add(1)
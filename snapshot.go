@@ -0,0 +1,398 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SnapshotVersion 快照檔格式版本
+const SnapshotVersion = 1
+
+// Snapshot 模擬器狀態快照 (各 Slave 的暫存器內容、累計電能與目前的場景)
+// 長時間的浸泡測試於重新啟動模擬器後還原，電能累計值不歸零
+type Snapshot struct {
+	Version  int             `json:"version"`
+	Time     time.Time       `json:"time"`
+	Scenario string          `json:"scenario"` // 空值表示還原時維持目前的場景
+	Slaves   []SlaveSnapshot `json:"slaves"`
+}
+
+// SlaveSnapshot 單一 Slave 的狀態 (還原時依 IP 對應)
+type SlaveSnapshot struct {
+	ID      string           `json:"id"`
+	IP      string           `json:"ip"`
+	Devices []DeviceSnapshot `json:"devices"` // 主設備在前，其後依 Unit ID 排列閘道後方的邏輯設備
+}
+
+// DeviceSnapshot 主設備或邏輯設備的狀態
+type DeviceSnapshot struct {
+	UnitID    uint8            `json:"unit_id"`
+	Energy    *float64         `json:"energy_kwh,omitempty"` // 累計電能 (含暫存器縮放捨去的小數)
+	Registers RegisterSnapshot `json:"registers"`
+}
+
+// RegisterSnapshot 暫存器映射表的內容 (以 PDU 位址索引，僅列出非零值的連續區段)
+type RegisterSnapshot struct {
+	Coils            []SnapshotBlock `json:"coils,omitempty"`
+	DiscreteInputs   []SnapshotBlock `json:"discrete_inputs,omitempty"`
+	InputRegisters   []SnapshotBlock `json:"input_registers,omitempty"`
+	HoldingRegisters []SnapshotBlock `json:"holding_registers,omitempty"`
+}
+
+// SnapshotBlock 連續的非零值 (線圈與離散輸入為 0/1)
+type SnapshotBlock struct {
+	Start  int      `json:"start"`
+	Values []uint16 `json:"values"`
+}
+
+// RestoreResult 快照還原結果
+type RestoreResult struct {
+	Scenario string `json:"scenario"`
+	Restored int    `json:"restored"`
+	Pending  int    `json:"pending"` // 尚未啟動 (逐步上線或等待綁定重試)，啟動時還原
+	Failed   int    `json:"failed"`
+}
+
+// SaveSnapshot 將快照寫入檔案 (先寫入暫存檔再更名，中途失敗不會留下不完整的快照)
+func SaveSnapshot(path string, snap *Snapshot) error {
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return fmt.Errorf(T("序列化快照失敗: %w"), err)
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf(T("寫入快照檔失敗: %w"), err)
+	}
+	return nil
+}
+
+// writeFileAtomic 寫入暫存檔並 fsync 後更名，再 fsync 所在目錄
+// (寫入途中終止或主機當機都不會留下不完整的檔案，更名完成即已寫入磁碟)
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// LoadSnapshot 讀取快照檔
+func LoadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(T("讀取快照檔失敗: %w"), err)
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf(T("解析快照檔 %s 失敗: %w"), path, err)
+	}
+	if snap.Version != SnapshotVersion {
+		return nil, fmt.Errorf(T("不支援的快照版本: %d"), snap.Version)
+	}
+	return &snap, nil
+}
+
+// --- Engine ---
+
+// Snapshot 擷取所有 Slave 的狀態 (依 ID 排序)
+func (e *Engine) Snapshot() *Snapshot {
+	slaves := e.ListSlaves()
+	sort.Slice(slaves, func(i, j int) bool { return slaves[i].ID < slaves[j].ID })
+
+	snap := &Snapshot{
+		Version:  SnapshotVersion,
+		Time:     time.Now(),
+		Scenario: e.GetScenario().String(),
+		Slaves:   make([]SlaveSnapshot, 0, len(slaves)),
+	}
+	for _, slave := range slaves {
+		snap.Slaves = append(snap.Slaves, slave.Snapshot())
+	}
+	return snap
+}
+
+// RestoreSnapshot 套用快照的場景並還原各 Slave；尚未啟動的 Slave 於啟動時還原 (取代先前等待中的快照)
+func (e *Engine) RestoreSnapshot(snap *Snapshot) (RestoreResult, error) {
+	if snap.Version != SnapshotVersion {
+		return RestoreResult{}, fmt.Errorf(T("不支援的快照版本: %d"), snap.Version)
+	}
+
+	// 先切換場景 (場景啟用時可能擷取暫存器)，再還原暫存器；未記錄場景時維持目前的場景
+	scenario := e.GetScenario()
+	if snap.Scenario != "" {
+		scenario = ParseScenarioType(snap.Scenario)
+	}
+	if scenario != e.GetScenario() {
+		if err := e.ApplyScenario(scenario); err != nil {
+			return RestoreResult{}, err
+		}
+	}
+
+	result := RestoreResult{Scenario: scenario.String()}
+	pending := make(map[string]SlaveSnapshot)
+	for _, s := range snap.Slaves {
+		ip := net.ParseIP(s.IP)
+		if ip == nil {
+			e.logger.Warn(T("快照中的 Slave IP 無效"), zap.String("ip", s.IP))
+			result.Failed++
+			continue
+		}
+		slave, ok := e.GetSlave(ip)
+		if !ok {
+			pending[ip.String()] = s
+			result.Pending++
+			continue
+		}
+		if err := slave.RestoreSnapshot(s); err != nil {
+			e.logger.Warn(T("還原 Slave 快照失敗"), zap.String("id", slave.ID), zap.Error(err))
+			result.Failed++
+			continue
+		}
+		result.Restored++
+	}
+
+	e.mu.Lock()
+	e.pendingRestores = pending
+	e.mu.Unlock()
+
+	e.logger.Info(T("已還原快照"),
+		zap.Time("time", snap.Time),
+		zap.String("scenario", result.Scenario),
+		zap.Int("restored", result.Restored),
+		zap.Int("pending", result.Pending),
+		zap.Int("failed", result.Failed),
+	)
+	return result, nil
+}
+
+// restorePending 還原 Slave 啟動前收到的快照
+func (e *Engine) restorePending(slave *Slave) {
+	e.mu.Lock()
+	snap, ok := e.pendingRestores[slave.IP.String()]
+	delete(e.pendingRestores, slave.IP.String())
+	e.mu.Unlock()
+	if !ok {
+		return
+	}
+	if err := slave.RestoreSnapshot(snap); err != nil {
+		e.logger.Warn(T("還原 Slave 快照失敗"), zap.String("id", slave.ID), zap.Error(err))
+	}
+}
+
+// --- Slave ---
+
+// Snapshot 擷取 Slave 的暫存器內容與累計電能
+func (s *Slave) Snapshot() SlaveSnapshot {
+	_, handler, _ := s.currentScenario()
+
+	snap := SlaveSnapshot{
+		ID:      s.ID,
+		IP:      s.IP.String(),
+		Devices: []DeviceSnapshot{deviceSnapshot(s.UnitID, s.registers, s.model, handler)},
+	}
+	for _, id := range s.unitIDs() {
+		snap.Devices = append(snap.Devices, deviceSnapshot(id, s.units[id].registers, s.units[id].model, handler))
+	}
+	return snap
+}
+
+// RestoreSnapshot 還原暫存器內容與累計電能 (第一個設備為主設備，其餘依 Unit ID 對應)
+func (s *Slave) RestoreSnapshot(snap SlaveSnapshot) error {
+	type target struct {
+		registers *RegisterMap
+		model     DeviceModel
+	}
+	targets := make([]target, len(snap.Devices))
+	for i, d := range snap.Devices {
+		if i == 0 {
+			targets[i] = target{s.registers, s.model}
+			continue
+		}
+		u, ok := s.units[d.UnitID]
+		if !ok {
+			return fmt.Errorf(T("快照中的 Unit ID 未配置: %d"), d.UnitID)
+		}
+		targets[i] = target{u.registers, u.model}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, d := range snap.Devices {
+		t := targets[i]
+		if err := t.registers.RestoreSnapshot(d.Registers); err != nil {
+			return fmt.Errorf("Unit ID %d: %w", d.UnitID, err)
+		}
+		if d.Energy != nil {
+			RestoreEnergy(scenarioRegisters(t.registers, t.model), *d.Energy)
+		}
+		if restorer, ok := t.model.(StateRestorer); ok {
+			restorer.RestoreState(t.registers)
+		}
+	}
+	s.syncRegistersToServer()
+	return nil
+}
+
+// unitIDs 閘道後方邏輯設備的 Unit ID (由小到大)
+func (s *Slave) unitIDs() []uint8 {
+	ids := make([]uint8, 0, len(s.units))
+	for id := range s.units {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// deviceSnapshot 擷取設備的暫存器與累計電能 (場景未累計時取電能暫存器的讀值)
+func deviceSnapshot(unitID uint8, registers *RegisterMap, model DeviceModel, handler ScenarioHandler) DeviceSnapshot {
+	d := DeviceSnapshot{UnitID: unitID, Registers: registers.Snapshot()}
+	registers = scenarioRegisters(registers, model)
+	if accumulator, ok := handler.(EnergyAccumulator); ok {
+		if energy, ok := accumulator.Energy(registers); ok {
+			d.Energy = &energy
+			return d
+		}
+	}
+	if energy, err := registers.GetScaledValue(40004); err == nil {
+		d.Energy = &energy
+	}
+	return d
+}
+
+// --- 累計電能 ---
+
+// energyRestores 快照還原的累計電能 (*RegisterMap -> energyRestore)，各場景下次累積時接續
+var energyRestores sync.Map
+
+// energyRestore 還原的累計電能與還原時間
+type energyRestore struct {
+	energy float64
+	at     time.Time
+}
+
+// RestoreEnergy 還原映射表的累計電能 (kWh)，目前與之後切換的場景自此值接續累積
+func RestoreEnergy(registers *RegisterMap, energy float64) {
+	energyRestores.Store(registers, energyRestore{energy: energy, at: scenarioNow()})
+}
+
+// restoredEnergy 映射表最近一次還原的累計電能
+func restoredEnergy(registers *RegisterMap) (energyRestore, bool) {
+	v, ok := energyRestores.Load(registers)
+	if !ok {
+		return energyRestore{}, false
+	}
+	return v.(energyRestore), true
+}
+
+// --- RegisterMap ---
+
+// Snapshot 擷取四種暫存器的內容
+func (rm *RegisterMap) Snapshot() RegisterSnapshot {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	return RegisterSnapshot{
+		Coils:            snapshotBlocks(&rm.coils, bitWord),
+		DiscreteInputs:   snapshotBlocks(&rm.discreteInputs, bitWord),
+		InputRegisters:   snapshotBlocks(&rm.inputRegisters, sameWord),
+		HoldingRegisters: snapshotBlocks(&rm.holdingRegisters, sameWord),
+	}
+}
+
+// RestoreSnapshot 以快照取代四種暫存器的內容 (快照未列出的位址歸零；任一區段超出範圍時不做任何修改)
+func (rm *RegisterMap) RestoreSnapshot(snap RegisterSnapshot) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	coils, err := blockValues(rm.coils.Len(), snap.Coils, wordBit)
+	if err != nil {
+		return err
+	}
+	discretes, err := blockValues(rm.discreteInputs.Len(), snap.DiscreteInputs, wordBit)
+	if err != nil {
+		return err
+	}
+	inputs, err := blockValues(rm.inputRegisters.Len(), snap.InputRegisters, sameWord)
+	if err != nil {
+		return err
+	}
+	holdings, err := blockValues(rm.holdingRegisters.Len(), snap.HoldingRegisters, sameWord)
+	if err != nil {
+		return err
+	}
+
+	rm.coils.Write(0, coils)
+	rm.discreteInputs.Write(0, discretes)
+	rm.inputRegisters.Write(0, inputs)
+	rm.holdingRegisters.Write(0, holdings)
+	return nil
+}
+
+// snapshotBlocks 已配置頁面中的非零值，相鄰者合併為同一區段
+func snapshotBlocks[V comparable](p *pagedStore[V], word func(V) uint16) []SnapshotBlock {
+	var blocks []SnapshotBlock
+	var zero V
+	for n, page := range p.pages {
+		for i, v := range page {
+			if v == zero {
+				continue
+			}
+			idx := n*registerPageSize + i
+			if last := len(blocks) - 1; last >= 0 && blocks[last].Start+len(blocks[last].Values) == idx {
+				blocks[last].Values = append(blocks[last].Values, word(v))
+				continue
+			}
+			blocks = append(blocks, SnapshotBlock{Start: idx, Values: []uint16{word(v)}})
+		}
+	}
+	return blocks
+}
+
+// blockValues 將區段展開為長度 size 的完整內容
+func blockValues[V comparable](size int, blocks []SnapshotBlock, value func(uint16) V) ([]V, error) {
+	values := make([]V, size)
+	for _, b := range blocks {
+		if b.Start < 0 || b.Start+len(b.Values) > size {
+			return nil, fmt.Errorf(T("快照區段超出範圍: %d-%d (共 %d 個)"), b.Start, b.Start+len(b.Values)-1, size)
+		}
+		for i, v := range b.Values {
+			values[b.Start+i] = value(v)
+		}
+	}
+	return values, nil
+}
+
+func bitWord(v bool) uint16 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+func wordBit(v uint16) bool    { return v != 0 }
+func sameWord(v uint16) uint16 { return v }
@@ -0,0 +1,507 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// MQTT (Sparkplug B) 發布預設值
+const (
+	SparkplugNamespace   = "spBv1.0"
+	DefaultMQTTGroupID   = "modbussim"
+	DefaultMQTTInterval  = 5 * time.Second
+	DefaultMQTTKeepAlive = 30 * time.Second
+)
+
+// Sparkplug B 資料類型 (僅列出使用到的)
+const (
+	sparkplugUInt64  = 8
+	sparkplugDouble  = 10
+	sparkplugBoolean = 11
+	sparkplugString  = 12
+)
+
+// Sparkplug B 節點控制與出生序號的指標名稱
+const (
+	sparkplugBdSeq   = "bdSeq"
+	sparkplugRebirth = "Node Control/Rebirth"
+)
+
+// sparkplugMetric Sparkplug B 的一個指標 (Value 為 uint64、float64、bool 或 string)
+type sparkplugMetric struct {
+	Name     string // DDATA 以 alias 識別，名稱留空
+	Alias    uint64
+	Datatype uint32
+	Value    any
+	Unit     string // DBIRTH 的 engUnit 屬性
+}
+
+// sparkplugPayload Sparkplug B 訊息內容 (protobuf wire format，欄位編號對應 Eclipse Tahu 的 sparkplug_b.proto)
+type sparkplugPayload struct {
+	Timestamp uint64 // Unix 毫秒
+	Seq       *uint64
+	Metrics   []sparkplugMetric
+}
+
+func (p *sparkplugPayload) marshal() []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.VarintType)
+	b = protowire.AppendVarint(b, p.Timestamp)
+	for _, m := range p.Metrics {
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendBytes(b, m.marshal())
+	}
+	if p.Seq != nil {
+		b = protowire.AppendTag(b, 3, protowire.VarintType)
+		b = protowire.AppendVarint(b, *p.Seq)
+	}
+	return b
+}
+
+func (m *sparkplugMetric) marshal() []byte {
+	var b []byte
+	b = appendPluginString(b, 1, m.Name)
+	b = protowire.AppendTag(b, 2, protowire.VarintType)
+	b = protowire.AppendVarint(b, m.Alias)
+	b = protowire.AppendTag(b, 4, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(m.Datatype))
+	if m.Unit != "" {
+		// PropertySet{keys: ["engUnit"], values: [PropertyValue{type: String, string_value: unit}]}
+		var value []byte
+		value = protowire.AppendTag(value, 1, protowire.VarintType)
+		value = protowire.AppendVarint(value, sparkplugString)
+		value = appendPluginString(value, 8, m.Unit)
+		var props []byte
+		props = appendPluginString(props, 1, "engUnit")
+		props = protowire.AppendTag(props, 2, protowire.BytesType)
+		props = protowire.AppendBytes(props, value)
+		b = protowire.AppendTag(b, 9, protowire.BytesType)
+		b = protowire.AppendBytes(b, props)
+	}
+	switch v := m.Value.(type) {
+	case uint64:
+		b = protowire.AppendTag(b, 11, protowire.VarintType)
+		b = protowire.AppendVarint(b, v)
+	case float64:
+		b = protowire.AppendTag(b, 13, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(v))
+	case bool:
+		b = protowire.AppendTag(b, 14, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeBool(v))
+	case string:
+		b = protowire.AppendTag(b, 15, protowire.BytesType)
+		b = protowire.AppendString(b, v)
+	default:
+		b = protowire.AppendTag(b, 7, protowire.VarintType) // is_null
+		b = protowire.AppendVarint(b, 1)
+	}
+	return b
+}
+
+// unmarshalSparkplugPayload 解析 Sparkplug B 訊息 (用於 NCMD；engUnit 等屬性不解析)
+func unmarshalSparkplugPayload(data []byte) (*sparkplugPayload, error) {
+	p := &sparkplugPayload{}
+	var err error
+	consumeErr := consumePluginFields(data, func(num protowire.Number, typ protowire.Type, v []byte, n uint64) {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			p.Timestamp = n
+		case num == 2 && typ == protowire.BytesType:
+			var m sparkplugMetric
+			if e := m.unmarshal(v); e != nil {
+				err = e
+			}
+			p.Metrics = append(p.Metrics, m)
+		case num == 3 && typ == protowire.VarintType:
+			seq := n
+			p.Seq = &seq
+		}
+	})
+	if consumeErr != nil {
+		return nil, consumeErr
+	}
+	return p, err
+}
+
+func (m *sparkplugMetric) unmarshal(data []byte) error {
+	return consumePluginFields(data, func(num protowire.Number, typ protowire.Type, v []byte, n uint64) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			m.Name = string(v)
+		case num == 2 && typ == protowire.VarintType:
+			m.Alias = n
+		case num == 4 && typ == protowire.VarintType:
+			m.Datatype = uint32(n)
+		case num == 10 && typ == protowire.VarintType, num == 11 && typ == protowire.VarintType:
+			m.Value = n
+		case num == 12 && typ == protowire.Fixed32Type:
+			m.Value = float64(math.Float32frombits(uint32(n)))
+		case num == 13 && typ == protowire.Fixed64Type:
+			m.Value = math.Float64frombits(n)
+		case num == 14 && typ == protowire.VarintType:
+			m.Value = protowire.DecodeBool(n)
+		case num == 15 && typ == protowire.BytesType:
+			m.Value = string(v)
+		}
+	})
+}
+
+// --- 發布 ---
+
+// SparkplugPublisher 將各 Slave 的已定義暫存器以 Sparkplug B 發布至 MQTT broker
+// 模擬器為 edge node，每個 Slave 為一個 device (device ID 為 Slave IP)；
+// 連線後發布 NBIRTH 與各 Slave 的 DBIRTH，之後每隔 interval 以 DDATA 發布變化的值，Slave 停止或離線時發布 DDEATH
+type SparkplugPublisher struct {
+	config MQTTConfig
+	engine *Engine
+	logger *zap.Logger
+
+	wanted  map[string]bool             // 指定的暫存器名稱 (空表示全部)
+	bdSeq   uint64                      // 下一次連線的出生序號
+	seq     uint64                      // 訊息序號 (0-255，NBIRTH 為 0)
+	devices map[string]*sparkplugDevice // 已發布 DBIRTH 的 Slave (依 device ID)
+	rebirth chan struct{}               // 收到 NCMD Node Control/Rebirth
+	done    chan struct{}               // Run 結束時關閉
+}
+
+// sparkplugDevice 已發布 DBIRTH 的 Slave 與上次發布的值 (依 alias)
+type sparkplugDevice struct {
+	slave *Slave
+	last  map[uint64]any
+}
+
+// NewSparkplugPublisher 建立 Sparkplug B 發布器 (呼叫 Run 開始連線)
+func NewSparkplugPublisher(config MQTTConfig, engine *Engine, logger *zap.Logger) *SparkplugPublisher {
+	if config.EdgeNodeID == "" {
+		config.EdgeNodeID, _ = os.Hostname()
+	}
+	if config.ClientID == "" {
+		config.ClientID = "modbussim-" + config.EdgeNodeID
+	}
+	wanted := make(map[string]bool, len(config.Registers))
+	for _, name := range config.Registers {
+		wanted[name] = true
+	}
+	return &SparkplugPublisher{
+		config:  config,
+		engine:  engine,
+		logger:  logger.With(zap.String("component", "sparkplug")),
+		wanted:  wanted,
+		rebirth: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+}
+
+// topic Sparkplug B 主題 (device 為空時為 edge node 的訊息)
+func (p *SparkplugPublisher) topic(messageType, device string) string {
+	topic := SparkplugNamespace + "/" + p.config.GroupID + "/" + messageType + "/" + p.config.EdgeNodeID
+	if device != "" {
+		topic += "/" + device
+	}
+	return topic
+}
+
+// Run 連線並持續發布，斷線時以指數退避重新連線 (重新連線後重新發布出生訊息)；ctx 結束時發布死亡訊息後返回
+func (p *SparkplugPublisher) Run(ctx context.Context) {
+	defer close(p.done)
+
+	backoff := time.Second
+	for {
+		connected, err := p.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			backoff = time.Second
+		}
+		p.logger.Warn(T("MQTT 連線中斷，稍後重新連線"), zap.String("broker", p.config.Broker),
+			zap.Duration("retry_in", backoff), zap.Error(err))
+		if !pace(ctx, backoff) {
+			return
+		}
+		backoff = min(backoff*2, 30*time.Second)
+	}
+}
+
+// Wait 等待 Run 發布死亡訊息並結束 (ctx 結束時放棄等待)
+func (p *SparkplugPublisher) Wait(ctx context.Context) {
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+	}
+}
+
+// session 一次連線期間的發布 (connected 表示曾成功連線)
+func (p *SparkplugPublisher) session(ctx context.Context) (connected bool, err error) {
+	bdSeq := p.bdSeq
+	p.bdSeq = (p.bdSeq + 1) % 256
+	death := &sparkplugPayload{
+		Timestamp: uint64(time.Now().UnixMilli()),
+		Metrics:   []sparkplugMetric{{Name: sparkplugBdSeq, Datatype: sparkplugUInt64, Value: bdSeq}},
+	}
+
+	client, err := dialMQTT(ctx, p.config.Broker, mqttConnectOptions{
+		ClientID:    p.config.ClientID,
+		Username:    p.config.Username,
+		Password:    p.config.Password,
+		KeepAlive:   p.config.KeepAlive,
+		WillTopic:   p.topic("NDEATH", ""),
+		WillPayload: death.marshal(),
+		WillQoS:     1,
+	}, p.handleCommand)
+	if err != nil {
+		return false, err
+	}
+	p.logger.Info(T("已連線至 MQTT broker"), zap.String("broker", p.config.Broker),
+		zap.String("group_id", p.config.GroupID), zap.String("edge_node_id", p.config.EdgeNodeID))
+
+	p.devices = make(map[string]*sparkplugDevice)
+	if err := client.Subscribe(p.topic("NCMD", "")); err != nil {
+		return true, err
+	}
+	if err := p.publishBirths(client, bdSeq); err != nil {
+		return true, err
+	}
+
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			p.close(client, death)
+			return true, nil
+		case <-client.Done():
+			return true, client.Err()
+		case <-p.rebirth:
+			p.logger.Info(T("收到 Sparkplug 重新出生命令"))
+			p.devices = make(map[string]*sparkplugDevice)
+			err = p.publishBirths(client, bdSeq)
+		case <-ticker.C:
+			err = p.update(client)
+		}
+		if err != nil {
+			client.Close()
+			return true, err
+		}
+	}
+}
+
+// handleCommand 處理 NCMD (僅支援 Node Control/Rebirth)
+func (p *SparkplugPublisher) handleCommand(topic string, data []byte) {
+	payload, err := unmarshalSparkplugPayload(data)
+	if err != nil {
+		p.logger.Warn(T("解析 Sparkplug 命令失敗"), zap.String("topic", topic), zap.Error(err))
+		return
+	}
+	for _, m := range payload.Metrics {
+		if m.Name == sparkplugRebirth && m.Value == true {
+			select {
+			case p.rebirth <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// publishBirths 發布 NBIRTH 與目前各 Slave 的 DBIRTH
+func (p *SparkplugPublisher) publishBirths(client *mqttClient, bdSeq uint64) error {
+	p.seq = 0
+	birth := &sparkplugPayload{
+		Timestamp: uint64(time.Now().UnixMilli()),
+		Metrics: []sparkplugMetric{
+			{Name: sparkplugBdSeq, Datatype: sparkplugUInt64, Value: bdSeq},
+			{Name: sparkplugRebirth, Alias: 1, Datatype: sparkplugBoolean, Value: false},
+		},
+	}
+	if err := p.publish(client, "NBIRTH", "", birth); err != nil {
+		return err
+	}
+	return p.update(client)
+}
+
+// update 新出現的 Slave 發布 DBIRTH、既有的發布變化的值、停止或離線的發布 DDEATH
+func (p *SparkplugPublisher) update(client *mqttClient) error {
+	seen := make(map[string]bool)
+	for _, slave := range p.slaves() {
+		device := slave.IP.String()
+		seen[device] = true
+		metrics := p.metrics(slave)
+
+		d, ok := p.devices[device]
+		if !ok || d.slave != slave {
+			d = &sparkplugDevice{slave: slave, last: make(map[uint64]any, len(metrics))}
+			for _, m := range metrics {
+				d.last[m.Alias] = m.Value
+			}
+			if err := p.publish(client, "DBIRTH", device, p.payload(metrics)); err != nil {
+				return err
+			}
+			p.devices[device] = d
+			continue
+		}
+
+		var changed []sparkplugMetric
+		for _, m := range metrics {
+			if last, ok := d.last[m.Alias]; ok && last == m.Value {
+				continue
+			}
+			d.last[m.Alias] = m.Value
+			changed = append(changed, sparkplugMetric{Alias: m.Alias, Datatype: m.Datatype, Value: m.Value})
+		}
+		if len(changed) > 0 {
+			if err := p.publish(client, "DDATA", device, p.payload(changed)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for device := range p.devices {
+		if seen[device] {
+			continue
+		}
+		if err := p.publish(client, "DDEATH", device, p.payload(nil)); err != nil {
+			return err
+		}
+		delete(p.devices, device)
+	}
+	return nil
+}
+
+// close 發布全部 DDEATH 與 NDEATH 後斷線 (正常斷線時 broker 不發布遺囑訊息)
+func (p *SparkplugPublisher) close(client *mqttClient, death *sparkplugPayload) {
+	for device := range p.devices {
+		p.publish(client, "DDEATH", device, p.payload(nil))
+	}
+	death.Timestamp = uint64(time.Now().UnixMilli())
+	client.Publish(p.topic("NDEATH", ""), death.marshal(), false)
+	client.Close()
+}
+
+// slaves 發布的 Slave (運行中且符合 targets/tags，依 ID 排序)
+func (p *SparkplugPublisher) slaves() []*Slave {
+	var result []*Slave
+	for _, slave := range p.engine.ListSlaves() {
+		if slave.State() != SlaveStateRunning || !p.config.matches(p.engine.config, slave.IP) {
+			continue
+		}
+		result = append(result, slave)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// metrics Slave 已定義暫存器的目前值 (alias 為暫存器位址；字串類型為 String，其餘為縮放後的 Double)
+func (p *SparkplugPublisher) metrics(slave *Slave) []sparkplugMetric {
+	registers := slave.Registers()
+	var metrics []sparkplugMetric
+	for _, meta := range registers.Definitions() {
+		if len(p.wanted) > 0 && !p.wanted[meta.Name] {
+			continue
+		}
+		m := sparkplugMetric{Name: meta.Name, Alias: uint64(meta.Address), Unit: meta.Unit}
+		if meta.DataType.IsString() {
+			value, err := registers.GetString(meta.Address)
+			if err != nil {
+				continue
+			}
+			m.Datatype, m.Value = sparkplugString, value
+		} else {
+			value, err := registers.GetScaledValue(meta.Address)
+			if err != nil {
+				continue
+			}
+			m.Datatype, m.Value = sparkplugDouble, value
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
+
+// payload 以目前時間建立訊息
+func (p *SparkplugPublisher) payload(metrics []sparkplugMetric) *sparkplugPayload {
+	return &sparkplugPayload{Timestamp: uint64(time.Now().UnixMilli()), Metrics: metrics}
+}
+
+// publish 附上訊息序號後發布
+func (p *SparkplugPublisher) publish(client *mqttClient, messageType, device string, payload *sparkplugPayload) error {
+	seq := p.seq
+	payload.Seq = &seq
+	p.seq = (p.seq + 1) % 256
+	return client.Publish(p.topic(messageType, device), payload.marshal(), false)
+}
+
+// --- 配置 ---
+
+// Validate 驗證 MQTT 發布設定
+func (m *MQTTConfig) Validate(tags map[string][]string) error {
+	if m.Interval < 0 || m.KeepAlive < 0 {
+		return fmt.Errorf(T("MQTT 的 interval 與 keep_alive 不可為負: interval=%v keep_alive=%v"), m.Interval, m.KeepAlive)
+	}
+	if !m.Enabled {
+		return nil
+	}
+	if _, _, err := parseMQTTBroker(m.Broker); err != nil {
+		return err
+	}
+	if m.Interval == 0 {
+		return errors.New(T("啟用 MQTT 發布時必須指定 interval"))
+	}
+	if m.GroupID == "" {
+		return errors.New(T("啟用 MQTT 發布時必須指定 group_id"))
+	}
+	// Sparkplug B 的 ID 不可包含主題的分隔與萬用字元
+	for _, id := range []string{m.GroupID, m.EdgeNodeID} {
+		if strings.ContainsAny(id, "/+#") {
+			return fmt.Errorf(T("Sparkplug ID 不可包含 /、+ 或 #: %s"), id)
+		}
+	}
+	for _, target := range m.Targets {
+		if net.ParseIP(target) == nil {
+			if _, _, err := net.ParseCIDR(target); err != nil {
+				return fmt.Errorf(T("MQTT 發布的目標無效: %s"), target)
+			}
+		}
+	}
+	for _, tag := range m.Tags {
+		if _, ok := tags[tag]; !ok {
+			return fmt.Errorf(T("MQTT 發布使用未定義的 Slave 標籤: %s"), tag)
+		}
+	}
+	return nil
+}
+
+// matches Slave 是否發布 (targets 與 tags 皆空表示全部 Slave)
+func (m *MQTTConfig) matches(c *Config, ip net.IP) bool {
+	if len(m.Targets) == 0 && len(m.Tags) == 0 {
+		return true
+	}
+	if len(m.Targets) > 0 && MatchTargets(ip, m.Targets) {
+		return true
+	}
+	for _, tag := range m.Tags {
+		if c.hasTag(ip, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// --- Engine ---
+
+// closeSparkplug 等待 Sparkplug 發布器發布死亡訊息 (背景工作已取消)
+func (e *Engine) closeSparkplug(ctx context.Context) {
+	if e.sparkplug != nil {
+		e.sparkplug.Wait(ctx)
+		e.sparkplug = nil
+	}
+}
@@ -0,0 +1,468 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// SunSpec 設備設定檔名稱
+const (
+	ProfileSunSpecInverter = "sunspec_inverter"
+	ProfileSunSpecMeter    = "sunspec_meter"
+)
+
+// SunSpec 模型 ID
+const (
+	SunSpecModelCommon   uint16 = 1
+	SunSpecModelInverter uint16 = 103 // 三相逆變器 (整數 + 比例因子)
+	SunSpecModelMeter    uint16 = 203 // 三相 Y 接電表 (整數 + 比例因子)
+	sunspecModelEnd      uint16 = 0xFFFF
+)
+
+// SunSpecBaseAddress SunS 標記的 PDU 位址 (EMS 依序探測 40000、0、50000，40000 為規範建議的基底位址)
+const SunSpecBaseAddress uint16 = 40000
+
+// sunspecHoldingRegisters SunSpec 設定檔的保持暫存器數量 (涵蓋完整的 PDU 位址空間)
+const sunspecHoldingRegisters = 65536
+
+// SunSpec 未實作點位的值
+const (
+	sunspecNotImplementedInt16  = -32768 // 0x8000
+	sunspecNotImplementedUint16 = 65535  // 0xFFFF (enum16)
+)
+
+// Common 模型的識別資訊 (SN 等可透過管理 API 的字串寫入修改)
+const (
+	sunspecManufacturer = "ModbusSim"
+	sunspecVersion      = "1.0"
+	sunspecSerialNumber = "SIM-00000001"
+)
+
+// 逆變器模型的假設值
+const (
+	sunspecDCVoltage  = 600.0 // 直流側電壓 (V)
+	sunspecEfficiency = 0.97  // 轉換效率
+	sunspecAmbientC   = 30.0  // 滿載以外的機櫃溫度基準 (°C)
+)
+
+// SunSpec 運轉狀態 (St，enum16)
+const (
+	sunspecStateSleeping = 2
+	sunspecStateMPPT     = 4
+)
+
+func init() {
+	inverter := sunspecInverterLayout()
+	RegisterDeviceProfile(&DeviceProfile{
+		Name:             ProfileSunSpecInverter,
+		Description:      "SunSpec 逆變器 (SunS 標記位於 40000，Common 模型 1 + 模型 103 三相逆變器)",
+		Registers:        inverter.defs,
+		NewModel:         func() DeviceModel { return newSunSpecModel(SunSpecModelInverter, inverter.points) },
+		Addressing:       AddressModeProtocol,
+		HoldingRegisters: sunspecHoldingRegisters,
+	})
+
+	meter := sunspecMeterLayout()
+	RegisterDeviceProfile(&DeviceProfile{
+		Name:             ProfileSunSpecMeter,
+		Description:      "SunSpec 電表 (SunS 標記位於 40000，Common 模型 1 + 模型 203 三相 Y 接電表)",
+		Registers:        meter.defs,
+		NewModel:         func() DeviceModel { return newSunSpecModel(SunSpecModelMeter, meter.points) },
+		Addressing:       AddressModeProtocol,
+		HoldingRegisters: sunspecHoldingRegisters,
+	})
+}
+
+// --- 暫存器佈局 ---
+
+// sunspecLayout 自 SunS 標記起依序配置模型鏈的暫存器定義 (PDU 位址)
+type sunspecLayout struct {
+	defs   []RegisterDefinition
+	points map[string]uint16 // 點位名稱 -> 位址
+	next   uint16
+	length int // 目前模型的長度 (L) 定義的索引
+}
+
+// newSunSpecLayout 建立佈局並寫入 SunS 標記與 Common 模型
+func newSunSpecLayout(model string) *sunspecLayout {
+	l := &sunspecLayout{points: make(map[string]uint16), next: SunSpecBaseAddress}
+	l.text("SunS", 4, "SunS")
+
+	l.begin("Common", SunSpecModelCommon)
+	l.text("Mn", 32, sunspecManufacturer)
+	l.text("Md", 32, model)
+	l.text("Opt", 16, "")
+	l.text("Vr", 16, sunspecVersion)
+	l.text("SN", 32, sunspecSerialNumber)
+	l.value("DA", "uint16", 0, "", 1)
+	l.value("Pad", "uint16", 0, "", 0x8000)
+	return l
+}
+
+// begin 開始一個模型 (ID 與長度 L 兩個標頭暫存器，L 於下一個模型開始或 finish 時回填)
+func (l *sunspecLayout) begin(name string, id uint16) {
+	l.closeModel()
+	l.value(name+"_ID", "uint16", 0, "", float64(id))
+	l.length = len(l.defs)
+	l.value(name+"_L", "uint16", 0, "", 0)
+}
+
+// closeModel 回填目前模型的長度 (標頭之後的暫存器數)
+func (l *sunspecLayout) closeModel() {
+	if l.length == 0 {
+		return
+	}
+	header := l.defs[l.length].Address
+	l.defs[l.length].DefaultValue = float64(l.next - header - 1)
+	l.length = 0
+}
+
+// finish 加上結束標記 (ID 0xFFFF、L 0) 並回傳佈局
+func (l *sunspecLayout) finish() *sunspecLayout {
+	l.closeModel()
+	l.value("End_ID", "uint16", 0, "", float64(sunspecModelEnd))
+	l.value("End_L", "uint16", 0, "", 0)
+	return l
+}
+
+// value 數值點位：工程值 = 原始值 × 10^sf (即 scale = 10^-sf)
+func (l *sunspecLayout) value(name, dataType string, sf int, unit string, defaultValue float64) {
+	l.add(RegisterDefinition{Name: name, DataType: dataType, Scale: math.Pow10(-sf), DefaultValue: defaultValue, Unit: unit}, 1)
+	if dataType == "uint32" {
+		l.next++
+	}
+}
+
+// scaleFactor 比例因子點位 (sunssf，固定值)
+func (l *sunspecLayout) scaleFactor(name string, sf int) {
+	l.value(name, "int16", 0, "", float64(sf))
+}
+
+// notImplemented 未實作的 int16 點位 (值為 0x8000)
+func (l *sunspecLayout) notImplemented(names ...string) {
+	for _, name := range names {
+		l.value(name, "int16", 0, "", sunspecNotImplementedInt16)
+	}
+}
+
+// text 字串點位 (chars 個 ASCII 字元，不足補 0)
+func (l *sunspecLayout) text(name string, chars int, value string) {
+	l.add(RegisterDefinition{Name: name, DataType: DataTypeString(chars).String(), DefaultText: value}, chars/2)
+}
+
+func (l *sunspecLayout) add(def RegisterDefinition, count int) {
+	def.Address = l.next
+	l.defs = append(l.defs, def)
+	l.points[def.Name] = def.Address
+	l.next += uint16(count)
+}
+
+// phases 依序配置總量與 A/B/C 三相 (名稱為 name、namephA、namephB、namephC)
+func (l *sunspecLayout) phases(name, dataType string, sf int, unit string) {
+	l.value(name, dataType, sf, unit, 0)
+	for _, phase := range []string{"phA", "phB", "phC"} {
+		l.value(name+phase, dataType, sf, unit, 0)
+	}
+}
+
+// accumulators 依序配置總量與 A/B/C 三相的累計量 (acc32，名稱為 name、namePhA、namePhB、namePhC；0 表示未累計)
+func (l *sunspecLayout) accumulators(name string, unit string) {
+	l.value(name, "uint32", 0, unit, 0)
+	for _, phase := range []string{"PhA", "PhB", "PhC"} {
+		l.value(name+phase, "uint32", 0, unit, 0)
+	}
+}
+
+// sunspecInverterLayout 模型 103 (三相逆變器) 的佈局
+func sunspecInverterLayout() *sunspecLayout {
+	l := newSunSpecLayout("Inverter 103")
+	l.begin("Inverter", SunSpecModelInverter)
+	l.value("A", "uint16", -2, "A", 0)
+	l.value("AphA", "uint16", -2, "A", 0)
+	l.value("AphB", "uint16", -2, "A", 0)
+	l.value("AphC", "uint16", -2, "A", 0)
+	l.scaleFactor("A_SF", -2)
+	l.value("PPVphAB", "uint16", -1, "V", 0)
+	l.value("PPVphBC", "uint16", -1, "V", 0)
+	l.value("PPVphCA", "uint16", -1, "V", 0)
+	l.value("PhVphA", "uint16", -1, "V", DefaultNominalVoltage)
+	l.value("PhVphB", "uint16", -1, "V", DefaultNominalVoltage)
+	l.value("PhVphC", "uint16", -1, "V", DefaultNominalVoltage)
+	l.scaleFactor("V_SF", -1)
+	l.value("W", "int16", 0, "W", 0)
+	l.scaleFactor("W_SF", 0)
+	l.value("Hz", "uint16", -2, "Hz", DefaultNominalFrequency)
+	l.scaleFactor("Hz_SF", -2)
+	l.value("VA", "int16", 0, "VA", 0)
+	l.scaleFactor("VA_SF", 0)
+	l.value("VAr", "int16", 0, "var", 0)
+	l.scaleFactor("VAr_SF", 0)
+	l.value("PF", "int16", -1, "%", 0)
+	l.scaleFactor("PF_SF", -1)
+	l.value("WH", "uint32", 0, "Wh", 0)
+	l.scaleFactor("WH_SF", 0)
+	l.value("DCA", "uint16", -2, "A", 0)
+	l.scaleFactor("DCA_SF", -2)
+	l.value("DCV", "uint16", -1, "V", 0)
+	l.scaleFactor("DCV_SF", -1)
+	l.value("DCW", "int16", 0, "W", 0)
+	l.scaleFactor("DCW_SF", 0)
+	l.value("TmpCab", "int16", -1, "°C", sunspecAmbientC)
+	l.notImplemented("TmpSnk", "TmpTrns", "TmpOt")
+	l.scaleFactor("Tmp_SF", -1)
+	l.value("St", "uint16", 0, "", sunspecStateSleeping)
+	l.value("StVnd", "uint16", 0, "", sunspecNotImplementedUint16)
+	for _, name := range []string{"Evt1", "Evt2", "EvtVnd1", "EvtVnd2", "EvtVnd3", "EvtVnd4"} {
+		l.value(name, "uint32", 0, "", 0)
+	}
+	return l.finish()
+}
+
+// sunspecMeterLayout 模型 203 (三相 Y 接電表) 的佈局 (視在與無效電能未實作)
+func sunspecMeterLayout() *sunspecLayout {
+	l := newSunSpecLayout("Meter 203")
+	l.begin("Meter", SunSpecModelMeter)
+	l.phases("A", "int16", -2, "A")
+	l.scaleFactor("A_SF", -2)
+	l.phases("PhV", "int16", -1, "V")
+	l.value("PPV", "int16", -1, "V", 0)
+	l.value("PPVphAB", "int16", -1, "V", 0)
+	l.value("PPVphBC", "int16", -1, "V", 0)
+	l.value("PPVphCA", "int16", -1, "V", 0)
+	l.scaleFactor("V_SF", -1)
+	l.value("Hz", "int16", -2, "Hz", DefaultNominalFrequency)
+	l.scaleFactor("Hz_SF", -2)
+	l.phases("W", "int16", 0, "W")
+	l.scaleFactor("W_SF", 0)
+	l.phases("VA", "int16", 0, "VA")
+	l.scaleFactor("VA_SF", 0)
+	l.phases("VAR", "int16", 0, "var")
+	l.scaleFactor("VAR_SF", 0)
+	l.phases("PF", "int16", -1, "%")
+	l.scaleFactor("PF_SF", -1)
+	l.accumulators("TotWhExp", "Wh")
+	l.accumulators("TotWhImp", "Wh")
+	l.scaleFactor("TotWh_SF", 0)
+	l.accumulators("TotVAhExp", "VAh")
+	l.accumulators("TotVAhImp", "VAh")
+	l.notImplemented("TotVAh_SF")
+	for _, name := range []string{"TotVArhImpQ1", "TotVArhImpQ2", "TotVArhExpQ3", "TotVArhExpQ4"} {
+		l.accumulators(name, "varh")
+	}
+	l.notImplemented("TotVArh_SF")
+	l.value("Evt", "uint32", 0, "", 0)
+	return l.finish()
+}
+
+// --- 設備模型 ---
+
+// sunspecMeterDefinitions 場景改寫的內部三相電表暫存器：
+// 電流為每相電流、有功功率為三相合計，電能以 Wh 解析度保存
+func sunspecMeterDefinitions() []RegisterDefinition {
+	defs := singlePhaseRegisters()
+	for i := range defs {
+		switch defs[i].Address {
+		case 40004:
+			defs[i].Scale = 1000
+		case 40007:
+			defs[i].Expression = "3 * 40001 * 40002 * 40006"
+		}
+	}
+	phaseVoltages := [3]uint16{AddrVoltageA, AddrVoltageB, AddrVoltageC}
+	phaseCurrents := [3]uint16{AddrCurrentA, AddrCurrentB, AddrCurrentC}
+	for i, phase := range []string{"A", "B", "C"} {
+		defs = append(defs,
+			RegisterDefinition{Address: phaseVoltages[i], Name: "Voltage" + phase, DataType: "uint16", Scale: 10, DefaultValue: DefaultNominalVoltage, Unit: "V"},
+			RegisterDefinition{Address: phaseCurrents[i], Name: "Current" + phase, DataType: "uint16", Scale: 100, DefaultValue: DefaultNominalCurrent, Unit: "A"},
+		)
+	}
+	return defs
+}
+
+// sunspecMeterTemplate 內部電表暫存器的基準 (各模型取 copy-on-write 複本)
+var sunspecMeterTemplate = sync.OnceValue(func() *RegisterMap {
+	rm, err := NewRegisterMapFromDefinitions(sunspecMeterDefinitions(), AddressModeAuto)
+	if err != nil {
+		panic(err) // 內建定義必定有效
+	}
+	return rm
+})
+
+// SunSpecModel SunSpec 設備模型：場景改寫內部的三相電表暫存器 (40001-40007 與各相電壓電流)，
+// 每次更新後換算為模型 103 或 203 的點位，電壓驟降、三相不平衡等場景因此同樣反映在 SunSpec 讀值
+type SunSpecModel struct {
+	model  uint16
+	points map[string]uint16
+	meter  *RegisterMap
+	random sync.Once
+}
+
+func newSunSpecModel(model uint16, points map[string]uint16) *SunSpecModel {
+	return &SunSpecModel{model: model, points: points, meter: sunspecMeterTemplate().Clone()}
+}
+
+// ScenarioRegisters 場景改寫的內部電表暫存器 (沿用對外暫存器的亂數來源，讓設定 seed 時的量測值可重現)
+func (m *SunSpecModel) ScenarioRegisters(registers *RegisterMap) *RegisterMap {
+	m.random.Do(func() {
+		m.meter.SetRandom(registers.Random())
+	})
+	return m.meter
+}
+
+// sunspecPhases 內部電表的各相量測值
+type sunspecPhases struct {
+	voltage, current, power [3]float64
+	frequency, pf, energy   float64 // energy 為 Wh
+	total                   float64 // 三相有功功率合計 (W)
+}
+
+// read 讀取內部電表；三相有功功率依各相視在功率的比例分配，合計與電能累積使用的功率一致
+func (m *SunSpecModel) read() sunspecPhases {
+	var p sunspecPhases
+	voltageAddrs := [3]uint16{AddrVoltageA, AddrVoltageB, AddrVoltageC}
+	currentAddrs := [3]uint16{AddrCurrentA, AddrCurrentB, AddrCurrentC}
+	apparent := 0.0
+	for i := range 3 {
+		p.voltage[i], _ = m.meter.GetScaledValue(voltageAddrs[i])
+		p.current[i], _ = m.meter.GetScaledValue(currentAddrs[i])
+		apparent += p.voltage[i] * p.current[i]
+	}
+	p.frequency, _ = m.meter.GetScaledValue(40003)
+	energy, _ := m.meter.GetScaledValue(40004)
+	p.energy = energy * 1000
+	p.pf, _ = m.meter.GetScaledValue(40006)
+	p.total, _ = m.meter.GetScaledValue(40007)
+	for i := range 3 {
+		if apparent > 0 {
+			p.power[i] = p.total * p.voltage[i] * p.current[i] / apparent
+		}
+	}
+	return p
+}
+
+// Update 將內部電表的量測值換算為 SunSpec 點位
+func (m *SunSpecModel) Update(registers *RegisterMap, now time.Time) {
+	p := m.read()
+	if m.model == SunSpecModelInverter {
+		m.updateInverter(registers, p)
+	} else {
+		m.updateMeter(registers, p)
+	}
+}
+
+// updateInverter 模型 103：交流側取自內部電表，直流側依固定效率與直流電壓推算
+func (m *SunSpecModel) updateInverter(registers *RegisterMap, p sunspecPhases) {
+	m.set(registers, "A", p.current[0]+p.current[1]+p.current[2])
+	m.setPhases(registers, "Aph", p.current)
+	m.setPhases(registers, "PhVph", p.voltage)
+	m.setLineVoltages(registers, p.voltage)
+
+	va := p.total / math.Max(p.pf, 0.01)
+	m.set(registers, "W", p.total)
+	m.set(registers, "Hz", p.frequency)
+	m.set(registers, "VA", va)
+	m.set(registers, "VAr", math.Sqrt(math.Max(0, va*va-p.total*p.total)))
+	m.set(registers, "PF", p.pf*100)
+	m.set(registers, "WH", p.energy)
+
+	dcPower := p.total / sunspecEfficiency
+	m.set(registers, "DCW", dcPower)
+	m.set(registers, "DCV", sunspecDCVoltage)
+	m.set(registers, "DCA", dcPower/sunspecDCVoltage)
+
+	// 機櫃溫度隨負載率上升 (額定功率取自額定值)
+	nominal := m.meter.Nominal()
+	rated := 3 * nominal.Voltage * nominal.Current
+	load := 0.0
+	if rated > 0 {
+		load = math.Min(1.5, p.total/rated)
+	}
+	m.set(registers, "TmpCab", sunspecAmbientC+15*load)
+
+	state := sunspecStateSleeping
+	if p.total > 0 {
+		state = sunspecStateMPPT
+	}
+	m.set(registers, "St", float64(state))
+}
+
+// updateMeter 模型 203：各相與合計的電流、電壓、功率與功率因數，輸入電能為內部電表的累計電能
+func (m *SunSpecModel) updateMeter(registers *RegisterMap, p sunspecPhases) {
+	var apparent, reactive, pfs [3]float64
+	pf := math.Max(p.pf, 0.01)
+	for i := range 3 {
+		apparent[i] = p.power[i] / pf
+		reactive[i] = math.Sqrt(math.Max(0, apparent[i]*apparent[i]-p.power[i]*p.power[i]))
+		pfs[i] = p.pf * 100
+	}
+
+	m.set(registers, "A", p.current[0]+p.current[1]+p.current[2])
+	m.setPhases(registers, "Aph", p.current)
+	m.set(registers, "PhV", (p.voltage[0]+p.voltage[1]+p.voltage[2])/3)
+	m.setPhases(registers, "PhVph", p.voltage)
+	m.set(registers, "PPV", m.setLineVoltages(registers, p.voltage))
+	m.set(registers, "Hz", p.frequency)
+	m.set(registers, "W", p.total)
+	m.setPhases(registers, "Wph", p.power)
+	m.set(registers, "VA", apparent[0]+apparent[1]+apparent[2])
+	m.setPhases(registers, "VAph", apparent)
+	m.set(registers, "VAR", reactive[0]+reactive[1]+reactive[2])
+	m.setPhases(registers, "VARph", reactive)
+	m.set(registers, "PF", p.pf*100)
+	m.setPhases(registers, "PFph", pfs)
+	m.set(registers, "TotWhImp", p.energy)
+}
+
+// setLineVoltages 由相電壓 (相差 120°) 計算線電壓並寫入，回傳三者的平均
+func (m *SunSpecModel) setLineVoltages(registers *RegisterMap, phases [3]float64) float64 {
+	sum := 0.0
+	for i, name := range []string{"PPVphAB", "PPVphBC", "PPVphCA"} {
+		a, b := phases[i], phases[(i+1)%3]
+		v := math.Sqrt(a*a + b*b + a*b)
+		m.set(registers, name, v)
+		sum += v
+	}
+	return sum / 3
+}
+
+// setPhases 寫入 prefix + A/B/C 三相的點位
+func (m *SunSpecModel) setPhases(registers *RegisterMap, prefix string, values [3]float64) {
+	for i, phase := range []string{"A", "B", "C"} {
+		m.set(registers, prefix+phase, values[i])
+	}
+}
+
+func (m *SunSpecModel) set(registers *RegisterMap, name string, value float64) {
+	registers.SetScaledValue(m.points[name], value)
+}
+
+func (m *SunSpecModel) get(registers *RegisterMap, name string) float64 {
+	value, _ := registers.GetScaledValue(m.points[name])
+	return value
+}
+
+// RestoreState 快照還原後，自 SunSpec 點位重新載入內部電表的電壓、電流、頻率、功率因數與電能
+// (累計電能由 RestoreEnergy 接續，此處僅讓還原後的第一次讀值一致)
+func (m *SunSpecModel) RestoreState(registers *RegisterMap) {
+	var voltage, current float64
+	voltageAddrs := [3]uint16{AddrVoltageA, AddrVoltageB, AddrVoltageC}
+	currentAddrs := [3]uint16{AddrCurrentA, AddrCurrentB, AddrCurrentC}
+	for i, phase := range []string{"A", "B", "C"} {
+		v, c := m.get(registers, "PhVph"+phase), m.get(registers, "Aph"+phase)
+		m.meter.SetScaledValue(voltageAddrs[i], v)
+		m.meter.SetScaledValue(currentAddrs[i], c)
+		voltage += v / 3
+		current += c / 3
+	}
+	energy := m.get(registers, "WH")
+	if m.model == SunSpecModelMeter {
+		energy = m.get(registers, "TotWhImp")
+	}
+	m.meter.SetScaledValue(40001, voltage)
+	m.meter.SetScaledValue(40002, current)
+	m.meter.SetScaledValue(40003, m.get(registers, "Hz"))
+	m.meter.SetScaledValue(40004, energy/1000)
+	m.meter.SetScaledValue(40006, m.get(registers, "PF")/100)
+	m.meter.EvaluateExpressions()
+}
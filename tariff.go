@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 多費率電能預設值
+const (
+	DefaultTariffRegister = 40230
+	tariffCount           = 4 // T1-T4
+)
+
+// DefaultTariffSchedule 未設定時段時的分時電價 (離峰 T1、半尖峰 T2、尖峰 T3)
+var DefaultTariffSchedule = []TariffPeriod{
+	{Start: "00:00", Tariff: 1},
+	{Start: "07:00", Tariff: 2},
+	{Start: "17:00", Tariff: 3},
+	{Start: "22:00", Tariff: 1},
+}
+
+// tariffWeekdays 時段的星期名稱
+var tariffWeekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// tariffPeriod 解析後的時段
+type tariffPeriod struct {
+	start  time.Duration // 自當日 00:00 起的時間
+	tariff int
+	days   map[time.Weekday]bool // nil 表示每天
+}
+
+func (p tariffPeriod) applies(day time.Weekday) bool {
+	return p.days == nil || p.days[day]
+}
+
+// parseTariffSchedule 解析分時電價時段 (開始時間 HH:MM、費率 1-4、星期 mon-sun)
+func parseTariffSchedule(schedule []TariffPeriod) ([]tariffPeriod, error) {
+	periods := make([]tariffPeriod, 0, len(schedule))
+	for _, p := range schedule {
+		start, err := time.Parse("15:04", p.Start)
+		if err != nil {
+			return nil, fmt.Errorf(T("無效的費率時段開始時間: %s (格式為 HH:MM)"), p.Start)
+		}
+		if p.Tariff < 1 || p.Tariff > tariffCount {
+			return nil, fmt.Errorf(T("費率時段 %s 的費率必須介於 1-4: %d"), p.Start, p.Tariff)
+		}
+		period := tariffPeriod{
+			start:  time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute,
+			tariff: p.Tariff,
+		}
+		for _, name := range p.Days {
+			day, ok := tariffWeekdays[strings.ToLower(name)]
+			if !ok {
+				return nil, fmt.Errorf(T("費率時段 %s 的星期無效: %s (可用: mon, tue, wed, thu, fri, sat, sun)"), p.Start, name)
+			}
+			if period.days == nil {
+				period.days = make(map[time.Weekday]bool)
+			}
+			period.days[day] = true
+		}
+		periods = append(periods, period)
+	}
+	return periods, nil
+}
+
+// tariffMeter 依分時電價時段將有功電能累積至 T1-T4 (依模擬時鐘切換)
+type tariffMeter struct {
+	register uint16
+	periods  []tariffPeriod
+	location *time.Location
+
+	mu     sync.Mutex
+	last   time.Time
+	energy [tariffCount]float64 // 各費率的電能 (kWh)
+}
+
+// newTariffMeter 依設定建立多費率電能 (時段需已通過驗證)
+func newTariffMeter(cfg TariffConfig) *tariffMeter {
+	schedule := cfg.Schedule
+	if len(schedule) == 0 {
+		schedule = DefaultTariffSchedule
+	}
+	periods, _ := parseTariffSchedule(schedule)
+	m := &tariffMeter{
+		register: cfg.Register,
+		periods:  periods,
+		location: time.UTC,
+	}
+	if m.register == 0 {
+		m.register = DefaultTariffRegister
+	}
+	if cfg.TimeZone != "" {
+		if location, err := time.LoadLocation(cfg.TimeZone); err == nil {
+			m.location = location
+		}
+	}
+	return m
+}
+
+// tariffAt t 時生效的費率：當天開始時間不晚於 t 的最後一個時段 (同時開始時以後列者為準)，沒有時為 T1
+func (m *tariffMeter) tariffAt(t time.Time) int {
+	t = t.In(m.location)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, m.location)
+	elapsed := t.Sub(midnight)
+
+	tariff, latest := 1, time.Duration(-1)
+	for _, p := range m.periods {
+		if p.applies(t.Weekday()) && p.start <= elapsed && p.start >= latest {
+			tariff, latest = p.tariff, p.start
+		}
+	}
+	return tariff
+}
+
+// nextSwitch t 之後第一個可能切換費率的時間 (當天之後的時段開始或隔天 00:00)
+func (m *tariffMeter) nextSwitch(t time.Time) time.Time {
+	t = t.In(m.location)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, m.location)
+	next := midnight.AddDate(0, 0, 1)
+	for _, p := range m.periods {
+		if at := midnight.Add(p.start); p.applies(t.Weekday()) && at.After(t) && at.Before(next) {
+			next = at
+		}
+	}
+	return next
+}
+
+// accumulate 以功率 power (W) 累積至 now，跨越時段時依時間比例分配至各費率
+func (m *tariffMeter) accumulate(power float64, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.last.IsZero() {
+		m.last = now
+		return
+	}
+	for m.last.Before(now) {
+		next := m.nextSwitch(m.last)
+		if now.Before(next) {
+			next = now
+		}
+		m.energy[m.tariffAt(m.last)-1] += power * next.Sub(m.last).Hours() / 1000
+		m.last = next
+	}
+}
+
+// write 將目前的費率與各費率電能寫入暫存器
+func (m *tariffMeter) write(registers *RegisterMap, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	registers.SetScaledValue(m.register, float64(m.tariffAt(now)))
+	for i, energy := range m.energy {
+		registers.SetScaledValue(m.register+1+uint16(i)*2, energy)
+	}
+}
+
+// tariffRegisters 多費率電能暫存器的定義
+func tariffRegisters(register uint16) []RegisterDefinition {
+	defs := []RegisterDefinition{
+		{Address: register, Name: "ActiveTariff", DataType: "uint16", Scale: 1, Unit: ""},
+	}
+	for i := 0; i < tariffCount; i++ {
+		defs = append(defs, RegisterDefinition{
+			Address:  register + 1 + uint16(i)*2,
+			Name:     fmt.Sprintf("EnergyT%d", i+1),
+			DataType: "uint32",
+			Scale:    100,
+			Unit:     "kWh",
+		})
+	}
+	return defs
+}
+
+// initTariff 依 slaves.tariff 定義多費率電能暫存器；暫存器與設定檔的暫存器重疊時停用並記錄警告
+func (s *Slave) initTariff() {
+	if s.config == nil || !s.config.Slaves.Tariff.Enabled {
+		return
+	}
+	meter := newTariffMeter(s.config.Slaves.Tariff)
+	if err := s.defineComputedRegisters(tariffRegisters(meter.register)); err != nil {
+		s.logger.Warn(T("多費率電能暫存器定義失敗，停用多費率電能"), zap.String("id", s.ID), zap.Error(err))
+		return
+	}
+	s.tariff = meter
+}
+
+// updateTariff 以目前的有功功率累積目前費率的電能，並寫入多費率電能暫存器
+func (s *Slave) updateTariff() {
+	if s.tariff == nil {
+		return
+	}
+	now := scenarioNow()
+	power, _ := scenarioRegisters(s.registers, s.model).GetScaledValue(40007)
+	s.tariff.accumulate(power, now)
+	s.tariff.write(s.registers, now)
+}
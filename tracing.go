@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 追蹤匯出預設值
+const (
+	DefaultTracingEndpoint      = "http://localhost:4318/v1/traces"
+	DefaultTracingServiceName   = "modbussim"
+	DefaultTracingSampleRate    = 0.1
+	DefaultTracingFlushInterval = 5 * time.Second
+
+	tracingQueueSize = 4096
+	tracingBatchSize = 512
+)
+
+// modbusFunctionNames 功能碼名稱 (用於 span 名稱)
+var modbusFunctionNames = map[uint8]string{
+	FuncCodeReadCoils:              "ReadCoils",
+	FuncCodeReadDiscreteInputs:     "ReadDiscreteInputs",
+	FuncCodeReadHoldingRegisters:   "ReadHoldingRegisters",
+	FuncCodeReadInputRegisters:     "ReadInputRegisters",
+	FuncCodeWriteSingleCoil:        "WriteSingleCoil",
+	FuncCodeWriteSingleRegister:    "WriteSingleRegister",
+	FuncCodeWriteMultipleCoils:     "WriteMultipleCoils",
+	FuncCodeWriteMultipleRegisters: "WriteMultipleRegisters",
+	FuncCodeEncapsulatedInterface:  "ReadDeviceIdentification",
+}
+
+// Span 單一 Modbus 交易的 span (欄位對應 OTLP/JSON 格式)
+type Span struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []SpanAttribute `json:"attributes,omitempty"`
+	Status            SpanStatus      `json:"status"`
+}
+
+// SpanAttribute span 屬性
+type SpanAttribute struct {
+	Key   string        `json:"key"`
+	Value SpanAttrValue `json:"value"`
+}
+
+// SpanAttrValue span 屬性值 (OTLP AnyValue，整數以字串表示)
+type SpanAttrValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+// SpanStatus span 狀態 (0 未設定、2 錯誤)
+type SpanStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// span 類型與狀態碼 (OTLP)
+const (
+	spanKindServer  = 2
+	spanStatusError = 2
+)
+
+func stringAttr(key, value string) SpanAttribute {
+	return SpanAttribute{Key: key, Value: SpanAttrValue{StringValue: &value}}
+}
+
+func intAttr(key string, value int64) SpanAttribute {
+	v := strconv.FormatInt(value, 10)
+	return SpanAttribute{Key: key, Value: SpanAttrValue{IntValue: &v}}
+}
+
+func boolAttr(key string, value bool) SpanAttribute {
+	return SpanAttribute{Key: key, Value: SpanAttrValue{BoolValue: &value}}
+}
+
+// Tracer 以 OTLP/HTTP (JSON) 批次匯出 Modbus 交易 span
+type Tracer struct {
+	config   TracingConfig
+	endpoint string
+	client   *http.Client
+	logger   *zap.Logger
+
+	spans   chan Span
+	dropped atomic.Uint64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewTracer 建立追蹤器
+func NewTracer(config TracingConfig, logger *zap.Logger) *Tracer {
+	return &Tracer{
+		config:   config,
+		endpoint: config.ResolveEndpoint(),
+		client:   &http.Client{Timeout: 10 * time.Second},
+		logger:   logger,
+		spans:    make(chan Span, tracingQueueSize),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// ResolveEndpoint 取得 OTLP traces 端點 (未設定時依 OTEL_EXPORTER_OTLP_* 環境變數)
+func (c TracingConfig) ResolveEndpoint() string {
+	if c.Endpoint != "" {
+		return c.Endpoint
+	}
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); endpoint != "" {
+		return endpoint
+	}
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		return strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+	}
+	return DefaultTracingEndpoint
+}
+
+// Start 啟動背景匯出
+func (t *Tracer) Start() {
+	t.logger.Info(T("已啟用追蹤"),
+		zap.String("endpoint", t.endpoint),
+		zap.Float64("sample_rate", t.config.SampleRate),
+	)
+	go t.run()
+}
+
+// Stop 停止背景匯出並送出剩餘的 span
+func (t *Tracer) Stop() {
+	close(t.stop)
+	<-t.done
+}
+
+// Sampled 依取樣率決定是否追蹤此次交易
+func (t *Tracer) Sampled() bool {
+	return t.config.SampleRate > 0 && rand.Float64() < t.config.SampleRate
+}
+
+// Record 排入待匯出的 span，佇列已滿時丟棄
+func (t *Tracer) Record(span Span) {
+	select {
+	case t.spans <- span:
+	default:
+		t.dropped.Add(1)
+	}
+}
+
+// run 依批次大小或間隔匯出 span
+func (t *Tracer) run() {
+	defer close(t.done)
+
+	interval := t.config.FlushInterval
+	if interval <= 0 {
+		interval = DefaultTracingFlushInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	batch := make([]Span, 0, tracingBatchSize)
+	flush := func() {
+		if dropped := t.dropped.Swap(0); dropped > 0 {
+			t.logger.Warn(T("追蹤佇列已滿，已丟棄 span"), zap.Uint64("dropped", dropped))
+		}
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.export(batch); err != nil {
+			t.logger.Warn(T("匯出 span 失敗"), zap.Int("spans", len(batch)), zap.Error(err))
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case span := <-t.spans:
+			batch = append(batch, span)
+			if len(batch) >= tracingBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-t.stop:
+			for {
+				select {
+				case span := <-t.spans:
+					batch = append(batch, span)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// export 以 OTLP/HTTP JSON 送出一批 span
+func (t *Tracer) export(spans []Span) error {
+	serviceName := t.config.ServiceName
+	if serviceName == "" {
+		serviceName = DefaultTracingServiceName
+	}
+
+	payload := map[string]any{
+		"resourceSpans": []map[string]any{{
+			"resource": map[string]any{
+				"attributes": []SpanAttribute{
+					stringAttr("service.name", serviceName),
+					stringAttr("service.version", Version),
+				},
+			},
+			"scopeSpans": []map[string]any{{
+				"scope": map[string]string{"name": "modbus-simulator", "version": Version},
+				"spans": spans,
+			}},
+		}},
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := t.client.Post(t.endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// newTraceID 產生 16 bytes 的 trace ID (hex)
+func newTraceID() string {
+	var id [16]byte
+	binary.BigEndian.PutUint64(id[:8], rand.Uint64())
+	binary.BigEndian.PutUint64(id[8:], rand.Uint64()|1)
+	return fmt.Sprintf("%x", id)
+}
+
+// newSpanID 產生 8 bytes 的 span ID (hex)
+func newSpanID() string {
+	return fmt.Sprintf("%016x", rand.Uint64()|1)
+}
+
+// newRequestSpan 依請求與回應 ADU 建立 span
+func (s *Slave) newRequestSpan(packet, response []byte, remote net.Addr, start, end time.Time) Span {
+	function := packet[ModbusTCPHeaderLength]
+	name, ok := modbusFunctionNames[function]
+	if !ok {
+		name = fmt.Sprintf("Function%d", function)
+	}
+
+	scenario, _, _ := s.currentScenario()
+	span := Span{
+		TraceID:           newTraceID(),
+		SpanID:            newSpanID(),
+		Name:              "modbus/" + name,
+		Kind:              spanKindServer,
+		StartTimeUnixNano: strconv.FormatInt(start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(end.UnixNano(), 10),
+		Attributes: []SpanAttribute{
+			stringAttr("rpc.system", "modbus"),
+			intAttr("modbus.function_code", int64(function)),
+			intAttr("modbus.transaction_id", int64(binary.BigEndian.Uint16(packet[0:2]))),
+			intAttr("modbus.unit_id", int64(packet[ModbusTCPHeaderLength-1])),
+			stringAttr("modbussim.slave_id", s.ID),
+			intAttr("server.port", int64(s.Port)),
+			stringAttr("modbussim.scenario", scenario.String()),
+			intAttr("modbussim.response_bytes", int64(len(response))),
+		},
+	}
+	if s.IP != nil {
+		span.Attributes = append(span.Attributes, stringAttr("server.address", s.IP.String()))
+	}
+
+	// 來源位址與埠分開記錄，方便與 EMS collector 端 span 的 server.address/server.port 對照
+	if host, port, err := net.SplitHostPort(remote.String()); err == nil {
+		span.Attributes = append(span.Attributes, stringAttr("client.address", host))
+		if n, err := strconv.Atoi(port); err == nil {
+			span.Attributes = append(span.Attributes, intAttr("client.port", int64(n)))
+		}
+	} else {
+		span.Attributes = append(span.Attributes, stringAttr("client.address", remote.String()))
+	}
+
+	if address, quantity, ok := requestRange(packet); ok {
+		span.Attributes = append(span.Attributes,
+			intAttr("modbus.address", int64(address)),
+			intAttr("modbus.quantity", int64(quantity)),
+		)
+	}
+
+	// 回應功能碼最高位元為 1 表示 Modbus 例外
+	if len(response) > ModbusTCPHeaderLength+1 && response[ModbusTCPHeaderLength]&0x80 != 0 {
+		code := response[ModbusTCPHeaderLength+1]
+		span.Attributes = append(span.Attributes,
+			boolAttr("modbus.exception", true),
+			intAttr("modbus.exception_code", int64(code)),
+		)
+		span.Status = SpanStatus{Code: spanStatusError, Message: (&ModbusError{Code: code}).Error()}
+	}
+	return span
+}
+
+// requestRange 取出請求 PDU 的起始位址與數量 (單筆寫入的數量為 1，其他功能碼回傳 false)
+func requestRange(packet []byte) (address, quantity uint16, ok bool) {
+	data := packet[ModbusTCPHeaderLength+1:]
+	if len(data) < 4 {
+		return 0, 0, false
+	}
+
+	address = binary.BigEndian.Uint16(data[0:2])
+	switch packet[ModbusTCPHeaderLength] {
+	case FuncCodeReadCoils, FuncCodeReadDiscreteInputs, FuncCodeReadHoldingRegisters, FuncCodeReadInputRegisters,
+		FuncCodeWriteMultipleCoils, FuncCodeWriteMultipleRegisters:
+		return address, binary.BigEndian.Uint16(data[2:4]), true
+	case FuncCodeWriteSingleCoil, FuncCodeWriteSingleRegister:
+		return address, 1, true
+	}
+	return 0, 0, false
+}
+
+// observeRequest 記錄請求延遲；啟用追蹤且被取樣時產生 span，並以 trace ID 作為直方圖的 exemplar
+func (s *Slave) observeRequest(packet, response []byte, remote net.Addr, start time.Time) {
+	end := time.Now()
+
+	var traceID string
+	if s.tracer != nil && s.tracer.Sampled() {
+		span := s.newRequestSpan(packet, response, remote, start, end)
+		s.tracer.Record(span)
+		traceID = span.TraceID
+	}
+
+	if s.latency != nil {
+		s.latency.Observe(end.Sub(start).Seconds(), traceID, end)
+	}
+}
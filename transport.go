@@ -0,0 +1,504 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultIdleTimeout 預設的連線閒置逾時 (EMS 斷線後遺留的 socket 在此時間後回收)
+const DefaultIdleTimeout = 5 * time.Minute
+
+// slaveListener Slave 自有的 TCP 接入層
+// 追蹤並可主動關閉既有連線；
+// shared 模式下不自行監聽，由共用 listener 將連線分派進來；
+// udp 傳輸方式下改為接收 datagram (沒有連線)
+type slaveListener struct {
+	slave      *Slave
+	port       int
+	addr       string
+	listener   net.Listener
+	packetConn net.PacketConn
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+	done   chan struct{} // Close 時關閉，結束排隊等待的連線
+	wg     sync.WaitGroup
+}
+
+// newSlaveListener 建立 Slave 在指定埠號上的 TCP 接入層
+func newSlaveListener(slave *Slave, port int) *slaveListener {
+	return &slaveListener{
+		slave: slave,
+		port:  port,
+		addr:  slave.protocolAddr(port),
+		conns: make(map[net.Conn]struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// Addr 監聽位址
+func (l *slaveListener) Addr() string {
+	return l.addr
+}
+
+// Listen 開始監聽並在背景接受連線 (shared 模式改為登記到共用 listener)
+func (l *slaveListener) Listen() error {
+	if l.slave.pool != nil {
+		return l.slave.pool.attach(l)
+	}
+
+	var lc net.ListenConfig
+	if l.slave.iface != "" {
+		lc.Control = bindToDevice(l.slave.iface)
+	}
+	if l.slave.transport == TransportUDP {
+		conn, err := lc.ListenPacket(context.Background(), "udp", l.addr)
+		if err != nil {
+			return err
+		}
+		l.packetConn = conn
+
+		l.wg.Add(1)
+		go l.serveDatagrams()
+		return nil
+	}
+	listener, err := lc.Listen(context.Background(), "tcp", l.addr)
+	if err != nil {
+		return err
+	}
+	l.listener = listener
+
+	l.wg.Add(1)
+	go l.acceptLoop()
+	return nil
+}
+
+// Close 關閉 listener 與所有既有連線，並等待處理 goroutine 結束
+func (l *slaveListener) Close() {
+	if l.slave.pool != nil {
+		l.slave.pool.detach(l)
+	} else if l.listener != nil {
+		l.listener.Close()
+	} else if l.packetConn != nil {
+		l.packetConn.Close()
+	}
+
+	l.mu.Lock()
+	l.closed = true
+	for conn := range l.conns {
+		conn.Close()
+	}
+	l.mu.Unlock()
+	close(l.done)
+
+	l.wg.Wait()
+}
+
+// acceptLoop 接受連線迴圈
+func (l *slaveListener) acceptLoop() {
+	defer l.wg.Done()
+
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				l.slave.logger.Warn(T("接受連線失敗"), zap.String("addr", l.addr), zap.Error(err))
+			}
+			return
+		}
+		l.accept(conn)
+	}
+}
+
+// accept 開始處理新連線；已達連線數上限時接受後立即關閉 (與實體設備的行為相同)，不影響既有連線
+func (l *slaveListener) accept(conn net.Conn) {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		conn.Close()
+		return
+	}
+	permitted := l.slave.acl.permits(conn.RemoteAddr())
+	if !permitted && !l.slave.acl.exception {
+		l.mu.Unlock()
+		l.slave.denyConn(conn)
+		return
+	}
+	// 連線數上限以 Slave 在所有埠號上的連線總數計算
+	if n, max := l.slave.conns.Add(1), l.slave.maxConnections(); max > 0 && int(n) > max {
+		l.slave.conns.Add(-1)
+		l.mu.Unlock()
+		if tcpConn, ok := conn.(*net.TCPConn); ok && l.slave.singleMaster() == SingleMasterRefuse {
+			// 如同只有一個 socket 的設備以 RST 拒絕
+			tcpConn.SetLinger(0)
+		}
+		conn.Close()
+		l.slave.stats.RejectedConns.Add(1)
+		l.slave.logger.Debug(T("連線數已達上限，拒絕連線"),
+			zap.String("remote", conn.RemoteAddr().String()),
+			zap.Int("max_connections", max),
+		)
+		return
+	}
+	l.conns[conn] = struct{}{}
+	l.wg.Add(1)
+	l.mu.Unlock()
+
+	go l.serveConn(conn, permitted)
+}
+
+// serveConn 處理單一連線上的 Modbus TCP 請求 (不在允許清單的用戶端每個請求皆回應 0x0A)
+func (l *slaveListener) serveConn(conn net.Conn, permitted bool) {
+	defer l.wg.Done()
+	defer func() {
+		l.mu.Lock()
+		delete(l.conns, conn)
+		l.mu.Unlock()
+		l.slave.conns.Add(-1)
+		conn.Close()
+	}()
+
+	// 單一 Master queue 模式：前一個連線關閉前不讀取此連線的請求
+	if l.slave.singleMaster() == SingleMasterQueue {
+		select {
+		case l.slave.master <- struct{}{}:
+			defer func() { <-l.slave.master }()
+		case <-l.done:
+			return
+		}
+	}
+
+	readTimeout, writeTimeout, idleTimeout := l.slave.connTimeouts()
+	responses := &connResponses{out: &deadlineWriter{conn: conn, timeout: writeTimeout}}
+
+	// max_in_flight 大於 1 時，同一連線上的 pipelined 請求並行處理，回應依完成順序寫出
+	var inFlight chan struct{}
+	var pending sync.WaitGroup
+	if n := l.slave.maxInFlight(); n > 1 {
+		inFlight = make(chan struct{}, n)
+	}
+	defer pending.Wait()
+
+	for {
+		// 等待下一個請求期間套用閒置逾時 (有延後的回應時改為其等待時間)，收到第一個 byte 後改為讀取逾時
+		wait := idleTimeout
+		if held, ok := responses.heldWait(); ok {
+			wait = held
+		}
+		conn.SetReadDeadline(deadline(wait))
+		in := &frameReader{conn: conn, timeout: readTimeout}
+
+		packet, err := readMBAPFrame(in, l.slave.maxADUSize())
+		if err != nil {
+			// 延後的回應等不到下一個請求：直接寫出後繼續等待
+			if errors.Is(err, os.ErrDeadlineExceeded) && !in.started {
+				if _, ok := responses.heldWait(); ok {
+					if err := responses.flush(l); err != nil {
+						return
+					}
+					continue
+				}
+			}
+			switch {
+			case errors.Is(err, os.ErrDeadlineExceeded):
+				l.slave.stats.TimedOutConns.Add(1)
+				msg := T("連線閒置逾時，關閉連線")
+				if in.started {
+					msg = T("讀取請求逾時，關閉連線")
+				}
+				l.slave.logger.Debug(msg, zap.String("remote", conn.RemoteAddr().String()))
+			case !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed):
+				l.slave.logger.Debug(T("讀取請求失敗"),
+					zap.String("remote", conn.RemoteAddr().String()),
+					zap.Error(err),
+				)
+			}
+			return
+		}
+
+		// silent 備援端：讀取請求但不回應
+		if l.slave.silentStandby.Load() {
+			continue
+		}
+
+		if inFlight == nil {
+			if !l.serveRequest(conn, responses, packet, permitted) {
+				return
+			}
+			continue
+		}
+
+		// 處理中的請求達上限時暫停讀取，直到其中一個完成
+		inFlight <- struct{}{}
+		pending.Add(1)
+		go func() {
+			defer func() {
+				<-inFlight
+				pending.Done()
+			}()
+			if !l.serveRequest(conn, responses, packet, permitted) {
+				conn.Close()
+			}
+		}()
+	}
+}
+
+// serveRequest 處理一個請求並寫出回應；寫出失敗時回傳 false (呼叫端應關閉連線)
+func (l *slaveListener) serveRequest(conn net.Conn, responses *connResponses, packet []byte, permitted bool) bool {
+	start := time.Now()
+	response, hasError, dropped := l.handle(packet, permitted)
+	if dropped {
+		return true
+	}
+	if err := responses.write(l, packet, response); err != nil {
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			l.slave.stats.TimedOutConns.Add(1)
+			l.slave.logger.Debug(T("寫出回應逾時，關閉連線"), zap.String("remote", conn.RemoteAddr().String()))
+		}
+		return false
+	}
+	l.slave.recordRequest(len(packet), len(response), hasError)
+	l.slave.observeRequest(packet, response, conn.RemoteAddr(), start)
+	l.slave.auditRequest(packet, response, conn.RemoteAddr(), start)
+	if !hasError {
+		l.slave.observePoll(packet, response, conn.RemoteAddr())
+	}
+	return true
+}
+
+// connResponses 連線的回應寫出 (並行處理的請求共用，確保回應不會交錯寫出)
+type connResponses struct {
+	mu  sync.Mutex
+	out *deadlineWriter
+	seq responseSequence
+}
+
+// write 依當前場景的 ResponseSequencer 寫出回應；先寫出本次回應，再寫出先前延後的回應
+// (兩個 pipelined 請求的回應順序因此對調)
+func (c *connResponses) write(l *slaveListener, request, response []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	written, hold := c.seq.next(l.slave, request, response)
+	if hold {
+		return nil
+	}
+	for _, r := range [][]byte{written, c.seq.flush()} {
+		if r == nil {
+			continue
+		}
+		if err := l.writeResponse(c.out, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// heldWait 有延後的回應時回傳其等待下一個請求的時間
+func (c *connResponses) heldWait() (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.seq.wait, c.seq.held != nil
+}
+
+// flush 寫出延後的回應 (已由其他請求的回應帶出時不做任何事)
+func (c *connResponses) flush(l *slaveListener) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if held := c.seq.flush(); held != nil {
+		return l.writeResponse(c.out, held)
+	}
+	return nil
+}
+
+// serveDatagrams 處理 Modbus UDP 請求 (如同實體 RTU 一次處理一個請求)；格式錯誤的 datagram 直接丟棄
+func (l *slaveListener) serveDatagrams() {
+	defer l.wg.Done()
+
+	maxADU := l.slave.maxADUSize()
+	buf := make([]byte, maxADU+1)
+	for {
+		n, from, err := l.packetConn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				l.slave.logger.Warn(T("接收 datagram 失敗"), zap.String("addr", l.addr), zap.Error(err))
+			}
+			return
+		}
+
+		packet, err := readMBAPFrame(bytes.NewReader(buf[:n]), maxADU)
+		if err == nil && len(packet) != n {
+			err = fmt.Errorf(T("datagram 長度與 MBAP 長度不符: %d"), n)
+		}
+		if err != nil {
+			l.slave.logger.Debug(T("讀取請求失敗"), zap.String("remote", from.String()), zap.Error(err))
+			continue
+		}
+
+		// silent 備援端：讀取請求但不回應
+		if l.slave.silentStandby.Load() {
+			continue
+		}
+		permitted := l.slave.acl.permits(from)
+		if !permitted && !l.slave.acl.exception {
+			continue
+		}
+
+		start := time.Now()
+		response, hasError, dropped := l.handle(packet, permitted)
+		if dropped {
+			continue
+		}
+		if err := l.writeDatagram(response, from); err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				l.slave.logger.Debug(T("寫出回應失敗"), zap.String("remote", from.String()), zap.Error(err))
+			}
+			continue
+		}
+		l.slave.recordRequest(len(packet), len(response), hasError)
+		l.slave.observeRequest(packet, response, from, start)
+		l.slave.auditRequest(packet, response, from, start)
+		if !hasError {
+			l.slave.observePoll(packet, response, from)
+		}
+	}
+}
+
+// handle 處理一個請求；不在允許清單的用戶端不執行請求，直接回應 Gateway Path Unavailable
+func (l *slaveListener) handle(packet []byte, permitted bool) (response []byte, hasError, dropped bool) {
+	frame := newTCPFrame(packet)
+	if !permitted {
+		return frame.exception(ExceptionCodeGatewayPathUnavailable), true, false
+	}
+	return l.slave.handler.Handle(frame)
+}
+
+// writeDatagram 以單一 datagram 寫出回應；ResponseShaper 場景的分段寫出先收集完整 (保留其延遲) 再送出
+func (l *slaveListener) writeDatagram(response []byte, to net.Addr) error {
+	var buf bytes.Buffer
+	if err := l.writeResponse(&buf, response); err != nil {
+		return err
+	}
+	_, writeTimeout, _ := l.slave.connTimeouts()
+	l.packetConn.SetWriteDeadline(deadline(writeTimeout))
+	_, err := l.packetConn.WriteTo(buf.Bytes(), to)
+	return err
+}
+
+// writeResponse 寫出回應；當前場景實作 ResponseShaper 時交由場景控制寫出方式
+func (l *slaveListener) writeResponse(w io.Writer, response []byte) error {
+	_, handler, params := l.slave.currentScenario()
+	if shaper, ok := handler.(ResponseShaper); ok {
+		return shaper.WriteResponse(w, response, params)
+	}
+
+	_, err := w.Write(response)
+	return err
+}
+
+// deadline 由現在起算 timeout 後的期限 (0 表示不限)
+func deadline(timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(timeout)
+}
+
+// frameReader 讀取一個訊框；讀到第一個 byte 時將讀取期限改為 timeout 後
+type frameReader struct {
+	conn    net.Conn
+	timeout time.Duration
+	started bool
+}
+
+func (r *frameReader) Read(p []byte) (int, error) {
+	n, err := r.conn.Read(p)
+	if n > 0 && !r.started {
+		r.started = true
+		r.conn.SetReadDeadline(deadline(r.timeout))
+	}
+	return n, err
+}
+
+// deadlineWriter 每次寫出前重設寫入期限，分段寫出的場景 (慢速排出、分段回應) 不會因累計時間逾時
+type deadlineWriter struct {
+	conn    net.Conn
+	timeout time.Duration
+}
+
+func (w *deadlineWriter) Write(p []byte) (int, error) {
+	w.conn.SetWriteDeadline(deadline(w.timeout))
+	return w.conn.Write(p)
+}
+
+// tcpFrame Modbus TCP 請求 (MBAP Header + PDU)
+type tcpFrame struct {
+	TransactionID uint16
+	UnitID        uint8
+	Function      uint8
+	Data          []byte // 功能碼之後的 PDU 資料
+}
+
+// newTCPFrame 解析 readMBAPFrame 讀到的 ADU
+func newTCPFrame(packet []byte) *tcpFrame {
+	return &tcpFrame{
+		TransactionID: binary.BigEndian.Uint16(packet[0:2]),
+		UnitID:        packet[6],
+		Function:      packet[7],
+		Data:          packet[ModbusTCPHeaderLength+1:],
+	}
+}
+
+// response 以請求的 Transaction ID 與 Unit ID 組成回應 ADU
+func (f *tcpFrame) response(data []byte) []byte {
+	adu := make([]byte, ModbusTCPHeaderLength+1+len(data))
+	binary.BigEndian.PutUint16(adu[0:2], f.TransactionID)
+	binary.BigEndian.PutUint16(adu[4:6], uint16(2+len(data)))
+	adu[6] = f.UnitID
+	adu[7] = f.Function
+	copy(adu[ModbusTCPHeaderLength+1:], data)
+	return adu
+}
+
+// exception 例外回應 ADU (功能碼最高位元設為 1)
+func (f *tcpFrame) exception(code uint8) []byte {
+	adu := f.response([]byte{code})
+	adu[ModbusTCPHeaderLength] |= 0x80
+	return adu
+}
+
+// readMBAPFrame 讀取一個完整的 Modbus TCP ADU (MBAP Header + PDU)，超過 maxADU 時回傳錯誤
+func readMBAPFrame(r io.Reader, maxADU int) ([]byte, error) {
+	header := make([]byte, ModbusTCPHeaderLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	protocolID := binary.BigEndian.Uint16(header[2:4])
+	length := int(binary.BigEndian.Uint16(header[4:6]))
+	if protocolID != 0 {
+		return nil, fmt.Errorf(T("無效的協定識別碼: %d"), protocolID)
+	}
+	// Length 包含 Unit ID，PDU 至少需要功能碼
+	if length < 2 || ModbusTCPHeaderLength-1+length > maxADU {
+		return nil, fmt.Errorf(T("無效的 MBAP 長度: %d"), length)
+	}
+
+	packet := make([]byte, ModbusTCPHeaderLength-1+length)
+	copy(packet, header)
+	if _, err := io.ReadFull(r, packet[ModbusTCPHeaderLength:]); err != nil {
+		return nil, err
+	}
+	return packet, nil
+}
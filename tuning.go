@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// tunableScenarioParams 可在執行期間調整的場景參數 (以 JSON/配置檔的鍵名表示)；
+// 其餘參數 (targets、腳本、外掛、擷取檔等) 於套用場景時才生效，需重新套用場景
+var tunableScenarioParams = map[string]bool{
+	"voltage_variance":    true,
+	"frequency_variance":  true,
+	"jitter_min":          true,
+	"jitter_max":          true,
+	"jitter_distribution": true,
+	"jitter_mean":         true,
+	"jitter_stddev":       true,
+	"jitter_alpha":        true,
+	"jitter_cap":          true,
+	"jitter_timeout_rate": true,
+	"packet_loss_rate":    true,
+	"imbalance_ratio":     true,
+	"flap_up":             true,
+	"flap_down":           true,
+	"drain_rate":          true,
+	"fragment_size":       true,
+	"fragment_delay":      true,
+	"exception_rate":      true,
+	"exception_weights":   true,
+	"corrupt_rate":        true,
+	"corrupt_modes":       true,
+	"load_min":            true,
+	"load_max":            true,
+	"load_peak_hour":      true,
+	"time_scale":          true,
+	"churn_rate":          true,
+	"churn_modes":         true,
+	"mismatch_rate":       true,
+	"mismatch_modes":      true,
+	"reorder_wait":        true,
+	"harmonic_thd":        true,
+	"harmonic_order":      true,
+	"harmonic_interval":   true,
+	"flicker_pst":         true,
+	"chaos_scenarios":     true,
+	"chaos_interval_min":  true,
+	"chaos_interval_max":  true,
+	"chaos_duration_min":  true,
+	"chaos_duration_max":  true,
+	"chaos_fraction_min":  true,
+	"chaos_fraction_max":  true,
+	"chaos_max_active":    true,
+}
+
+// scenarioTuning 執行期間調整過的場景參數 (Slave 讀取場景參數時優先於配置檔)
+type scenarioTuning struct {
+	mu     sync.RWMutex
+	params map[ScenarioType]ScenarioParams
+}
+
+func newScenarioTuning() *scenarioTuning {
+	return &scenarioTuning{params: make(map[ScenarioType]ScenarioParams)}
+}
+
+// get 取得調整後的參數 (未調整過或 t 為 nil 時回傳 false)
+func (t *scenarioTuning) get(scenario ScenarioType) (ScenarioParams, bool) {
+	if t == nil {
+		return ScenarioParams{}, false
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	params, ok := t.params[scenario]
+	return params, ok
+}
+
+func (t *scenarioTuning) set(scenario ScenarioType, params ScenarioParams) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.params[scenario] = params
+}
+
+// reset 捨棄調整，回到配置檔的參數；未調整過時回傳 false
+func (t *scenarioTuning) reset(scenario ScenarioType) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.params[scenario]; !ok {
+		return false
+	}
+	delete(t.params, scenario)
+	return true
+}
+
+// WithScenarioTuning 套用執行期間調整的場景參數 (由引擎共用)
+func WithScenarioTuning(t *scenarioTuning) SlaveOption {
+	return func(s *Slave) {
+		s.tuning = t
+	}
+}
+
+// retuneScenario 目前的場景為 scenario (或包含 scenario 的組合場景) 時，以最新參數更新請求處理的延遲與丟包設定
+// (不重設暫存器與場景狀態)
+func (s *Slave) retuneScenario(scenario ScenarioType) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	handler, params := s.scenarioHandler(s.scenario), s.scenarioParams(s.scenario)
+	if _, ok := scenarioComponent(scenario, handler, params); !ok {
+		return false
+	}
+	s.handler.applyScenario(handler, params)
+	return true
+}
+
+// ScenarioParams 場景目前生效的參數 (執行期間調整過的優先於配置檔)
+func (e *Engine) ScenarioParams(scenario ScenarioType) ScenarioParams {
+	if params, ok := e.tuning.get(scenario); ok {
+		return params
+	}
+	return e.config.Scenario.Scenarios[scenario.String()]
+}
+
+// TuneScenario 以 JSON 調整場景的參數 (只覆寫 patch 中的鍵，時間可用 "50ms" 等字串)，
+// 並立即套用到運行該場景的 Slave，不需重新套用場景；回傳調整後的參數與更新的 Slave 數
+func (e *Engine) TuneScenario(scenario ScenarioType, patch []byte) (ScenarioParams, int, error) {
+	v := viper.New()
+	v.SetConfigType("json")
+	if err := v.ReadConfig(bytes.NewReader(patch)); err != nil {
+		return ScenarioParams{}, 0, fmt.Errorf(T("解析場景參數失敗: %w"), err)
+	}
+	keys := make(map[string]bool)
+	for _, key := range v.AllKeys() {
+		key, _, _ = strings.Cut(key, ".")
+		if !tunableScenarioParams[key] {
+			return ScenarioParams{}, 0, fmt.Errorf(T("場景參數 %s 無法即時調整，需重新套用場景"), key)
+		}
+		keys[key] = true
+	}
+	if len(keys) == 0 {
+		return ScenarioParams{}, 0, errors.New(T("未指定要調整的場景參數"))
+	}
+
+	e.tuneMu.Lock()
+	defer e.tuneMu.Unlock()
+
+	params := e.ScenarioParams(scenario)
+	// 清單與對應表整個取代，不與原本的值合併
+	for key := range keys {
+		switch key {
+		case "exception_weights":
+			params.ExceptionWeights = nil
+		case "corrupt_modes":
+			params.CorruptModes = nil
+		case "churn_modes":
+			params.ChurnModes = nil
+		case "mismatch_modes":
+			params.MismatchModes = nil
+		case "chaos_scenarios":
+			params.ChaosScenarios = nil
+		}
+	}
+	if err := v.Unmarshal(&params); err != nil {
+		return ScenarioParams{}, 0, fmt.Errorf(T("解析場景參數失敗: %w"), err)
+	}
+	profile, _ := GetDeviceProfile(e.config.Slaves.Profile)
+	if profile == nil {
+		profile, _ = GetDeviceProfile(ProfileSinglePhase)
+	}
+	if err := params.Validate(scenario.String(), profile); err != nil {
+		return ScenarioParams{}, 0, err
+	}
+
+	e.tuning.set(scenario, params)
+	retuned := e.retuneSlaves(scenario)
+
+	names := make([]string, 0, len(keys))
+	for key := range keys {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	e.logger.Info(T("已調整場景參數"),
+		zap.String("scenario", scenario.String()),
+		zap.Strings("params", names),
+		zap.Int("slaves", retuned),
+	)
+	return params, retuned, nil
+}
+
+// ResetScenarioTuning 捨棄場景參數的調整，回到配置檔的參數；回傳是否調整過與更新的 Slave 數
+func (e *Engine) ResetScenarioTuning(scenario ScenarioType) (bool, int) {
+	e.tuneMu.Lock()
+	defer e.tuneMu.Unlock()
+
+	if !e.tuning.reset(scenario) {
+		return false, 0
+	}
+	retuned := e.retuneSlaves(scenario)
+	e.logger.Info(T("已還原場景參數"),
+		zap.String("scenario", scenario.String()),
+		zap.Int("slaves", retuned),
+	)
+	return true, retuned
+}
+
+// retuneSlaves 以最新參數更新運行 scenario 的所有 Slave
+func (e *Engine) retuneSlaves(scenario ScenarioType) int {
+	retuned := 0
+	for _, slave := range e.ListSlaves() {
+		if slave.retuneScenario(scenario) {
+			retuned++
+		}
+	}
+	return retuned
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// unitDevice 閘道後方的邏輯設備 (同一個 TCP 端點以 Unit ID 區分，模擬串接多台 RTU 電表的 Modbus 閘道)
+type unitDevice struct {
+	unitID    uint8
+	registers *RegisterMap
+	model     DeviceModel
+	functions functionSet
+
+	// 對外提供的暫存器 (由所屬 Slave 的 mu 保護)
+	image registerImage
+}
+
+// requestTarget 請求作用的暫存器：主設備或閘道後方的邏輯設備
+type requestTarget struct {
+	registers *RegisterMap
+	image     *registerImage
+}
+
+// WithUnit 在同一個端點加入以 unitID 定址的邏輯設備 (各自的暫存器、設備模型與支援的功能碼，省略 functions 表示全部支援)
+func WithUnit(unitID uint8, rm *RegisterMap, model DeviceModel, functions ...uint8) SlaveOption {
+	return func(s *Slave) {
+		if s.units == nil {
+			s.units = make(map[uint8]*unitDevice)
+		}
+		s.units[unitID] = &unitDevice{
+			unitID:    unitID,
+			registers: rm,
+			model:     model,
+			functions: newFunctionSet(functions),
+		}
+	}
+}
+
+// seedID 導出此邏輯設備亂數序列的識別 (與主設備的序列互相獨立)
+func (u *unitDevice) seedID(slaveID string) string {
+	return fmt.Sprintf("%s/%d", slaveID, u.unitID)
+}
+
+// target 依 Unit ID 取得請求作用的暫存器 (呼叫端需持有 s.mu)；
+// 未配置其他邏輯設備時主設備回應任何 Unit ID，否則僅回應已配置的 Unit ID
+func (s *Slave) target(unitID uint8) (requestTarget, bool) {
+	if len(s.units) == 0 || unitID == s.UnitID {
+		return requestTarget{registers: s.registers, image: &s.image}, true
+	}
+	if u, ok := s.units[unitID]; ok {
+		return requestTarget{registers: u.registers, image: &u.image}, true
+	}
+	return requestTarget{}, false
+}
+
+// unitRegisters Unit ID 對應的暫存器 (未配置的 Unit ID 為主設備的暫存器)
+func (s *Slave) unitRegisters(unitID uint8) *RegisterMap {
+	if u, ok := s.units[unitID]; ok {
+		return u.registers
+	}
+	return s.registers
+}
+
+// supportsFunction Unit ID 對應的設備是否支援該功能碼 (未配置的 Unit ID 依主設備)
+func (s *Slave) supportsFunction(unitID, function uint8) bool {
+	if u, ok := s.units[unitID]; ok && unitID != s.UnitID {
+		return u.functions.supports(function)
+	}
+	return s.functions.supports(function)
+}
+
+// hasUnit 是否回應該 Unit ID (邏輯設備於建立時配置，之後不變動，不需加鎖)
+func (s *Slave) hasUnit(unitID uint8) bool {
+	if len(s.units) == 0 || unitID == s.UnitID {
+		return true
+	}
+	_, ok := s.units[unitID]
+	return ok
+}
+
+// updateUnits 依場景更新各邏輯設備的暫存器 (與主設備相同的場景與參數)
+func (s *Slave) updateUnits(handler ScenarioHandler, params ScenarioParams, now time.Time) {
+	for _, u := range s.units {
+		handler.Update(scenarioRegisters(u.registers, u.model), params)
+		if u.model != nil {
+			u.model.Update(u.registers, now)
+		}
+	}
+}
+
+// syncUnitsToServer 同步各邏輯設備的暫存器到對外提供的暫存器 (呼叫端需持有 s.mu)
+func (s *Slave) syncUnitsToServer() {
+	for _, u := range s.units {
+		u.registers.SyncRawTo(&u.image.holding, &u.image.input, &u.image.coils, &u.image.discretes)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultUpdaterBatchSize 集中式場景更新每批的 Slave 數
+const DefaultUpdaterBatchSize = 100
+
+// scenarioUpdater 集中式場景更新器 (scenario.workers > 0 時啟用)
+//
+// 大量 Slave 不再各自擁有 ticker：同一更新週期的 Slave 分成數批，批次在週期內錯開觸發，
+// 每批再分片交由固定數量的 worker 執行，避免數千個計時器同時觸發造成的 CPU 尖峰。
+type scenarioUpdater struct {
+	workers   int
+	batchSize int
+	work      chan []*Slave
+
+	mu       sync.Mutex
+	groups   map[time.Duration]*updateGroup
+	closed   bool
+	groupWg  sync.WaitGroup
+	workerWg sync.WaitGroup
+
+	logger *zap.Logger
+}
+
+// updateGroup 相同更新週期的 Slave (依加入順序輪流更新)
+type updateGroup struct {
+	updater  *scenarioUpdater
+	interval time.Duration
+	stop     chan struct{}
+
+	mu     sync.Mutex
+	slaves []*Slave
+	index  map[*Slave]int
+	cursor int // 下一批的起點
+}
+
+// newScenarioUpdater 建立集中式場景更新器並啟動 worker
+func newScenarioUpdater(workers, batchSize int, logger *zap.Logger) *scenarioUpdater {
+	if batchSize <= 0 {
+		batchSize = DefaultUpdaterBatchSize
+	}
+	u := &scenarioUpdater{
+		workers:   workers,
+		batchSize: batchSize,
+		work:      make(chan []*Slave, workers),
+		groups:    make(map[time.Duration]*updateGroup),
+		logger:    logger,
+	}
+	for i := 0; i < workers; i++ {
+		u.workerWg.Add(1)
+		go u.worker()
+	}
+	return u
+}
+
+// add 登記 Slave 依 interval 更新 (必要時建立該週期的群組)
+func (u *scenarioUpdater) add(s *Slave, interval time.Duration) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.closed {
+		return
+	}
+	g, ok := u.groups[interval]
+	if !ok {
+		g = &updateGroup{
+			updater:  u,
+			interval: interval,
+			stop:     make(chan struct{}),
+			index:    make(map[*Slave]int),
+		}
+		u.groups[interval] = g
+		u.logger.Debug(T("建立場景更新群組"), zap.Duration("interval", interval), zap.Int("batch_size", u.batchSize))
+		u.groupWg.Add(1)
+		go g.run()
+	}
+	g.add(s)
+}
+
+// remove 取消 Slave 的更新 (已送出的批次仍可能再更新一次)
+func (u *scenarioUpdater) remove(s *Slave) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for _, g := range u.groups {
+		g.remove(s)
+	}
+}
+
+// Close 停止所有群組與 worker
+func (u *scenarioUpdater) Close() {
+	u.mu.Lock()
+	if u.closed {
+		u.mu.Unlock()
+		return
+	}
+	u.closed = true
+	for _, g := range u.groups {
+		close(g.stop)
+	}
+	u.groups = nil
+	u.mu.Unlock()
+
+	// 群組不再送出批次後才關閉工作佇列
+	u.groupWg.Wait()
+	close(u.work)
+	u.workerWg.Wait()
+}
+
+// dispatch 將一批 Slave 分片交給 worker
+func (u *scenarioUpdater) dispatch(batch []*Slave) {
+	shard := (len(batch) + u.workers - 1) / u.workers
+	for start := 0; start < len(batch); start += shard {
+		u.work <- batch[start:min(start+shard, len(batch))]
+	}
+}
+
+// worker 執行分片中各 Slave 的場景更新
+func (u *scenarioUpdater) worker() {
+	defer u.workerWg.Done()
+
+	for shard := range u.work {
+		for _, s := range shard {
+			s.updateByScenario()
+		}
+	}
+}
+
+// add 加入 Slave
+func (g *updateGroup) add(s *Slave) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.index[s]; ok {
+		return
+	}
+	g.index[s] = len(g.slaves)
+	g.slaves = append(g.slaves, s)
+}
+
+// remove 移除 Slave (以最後一個 Slave 填補其位置)
+func (g *updateGroup) remove(s *Slave) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	i, ok := g.index[s]
+	if !ok {
+		return
+	}
+	last := len(g.slaves) - 1
+	g.slaves[i] = g.slaves[last]
+	g.index[g.slaves[i]] = i
+	g.slaves[last] = nil
+	g.slaves = g.slaves[:last]
+	delete(g.index, s)
+}
+
+// run 依錯開的相位逐批觸發更新：週期切成 ceil(Slave 數 / batch_size) 段，每段更新一批
+func (g *updateGroup) run() {
+	defer g.updater.groupWg.Done()
+
+	timer := time.NewTimer(g.interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-g.stop:
+			return
+		case <-timer.C:
+		}
+
+		batch, step := g.next()
+		if len(batch) > 0 {
+			g.updater.dispatch(batch)
+		}
+		timer.Reset(step)
+	}
+}
+
+// next 取出下一批 Slave 的複本與到下一批的間隔
+func (g *updateGroup) next() ([]*Slave, time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	n := len(g.slaves)
+	if n == 0 {
+		return nil, g.interval
+	}
+	size := g.updater.batchSize
+	batches := (n + size - 1) / size
+	if g.cursor >= n {
+		g.cursor = 0
+	}
+	end := min(g.cursor+size, n)
+	batch := append([]*Slave(nil), g.slaves[g.cursor:end]...)
+	g.cursor = end
+	return batch, g.interval / time.Duration(batches)
+}
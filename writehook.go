@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// WriteEvent Master 寫入的一個線圈或保持暫存器
+type WriteEvent struct {
+	Table     RegisterType
+	Address   uint16       // 映射表位址慣例下的位址
+	Value     uint16       // 線圈為 0/1
+	Registers *RegisterMap // 被寫入的設備 (主設備或閘道後方的邏輯設備)
+}
+
+// WriteHookFunc 寫入觸發的回呼
+// 呼叫時持有 Slave.mu，只能讀寫 event.Registers，不可呼叫會鎖定 Slave 的方法；返回後同步到對外提供的暫存器
+type WriteHookFunc func(event WriteEvent) error
+
+// WriteHook Master 寫入指定位址後觸發的回呼 (例如寫入跳脫線圈後，延遲一段時間改變斷路器狀態的離散輸入)
+type WriteHook struct {
+	Name    string
+	Table   RegisterType  // RegisterTypeCoil 或 RegisterTypeHoldingRegister
+	Address uint16        // 映射表位址慣例下的位址
+	Value   *uint16       // 僅在寫入此值時觸發 (nil 表示任何值)
+	Delay   time.Duration // 寫入後延遲多久執行 (0 表示在回應前執行，Master 的下一次輪詢即可讀到)
+	Action  WriteHookFunc
+}
+
+// WithWriteHook 加入寫入回呼
+func WithWriteHook(hook WriteHook) SlaveOption {
+	return func(s *Slave) {
+		s.writeHooks = append(s.writeHooks, hook)
+	}
+}
+
+// OnWrite 執行期間加入寫入回呼
+func (s *Slave) OnWrite(hook WriteHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writeHooks = append(s.writeHooks, hook)
+}
+
+// fireWriteHooks 對成功寫入的每個位址觸發符合的回呼 (pdu 為請求的起始位址；呼叫端需持有 s.mu)
+func (s *Slave) fireWriteHooks(registers *RegisterMap, table RegisterType, pdu uint16, values []uint16) {
+	if len(s.writeHooks) == 0 {
+		return
+	}
+	for i, value := range values {
+		address, ok := registers.PDUAddress(table, pdu+uint16(i))
+		if !ok {
+			continue
+		}
+		event := WriteEvent{Table: table, Address: address, Value: value, Registers: registers}
+		for _, hook := range s.writeHooks {
+			if hook.Table != table || hook.Address != address || (hook.Value != nil && *hook.Value != value) {
+				continue
+			}
+			if hook.Delay <= 0 {
+				s.runWriteHook(hook, event)
+				continue
+			}
+			go s.delayWriteHook(s.scenarioCtx, hook, event)
+		}
+	}
+}
+
+// delayWriteHook 延遲後執行回呼；期間 Slave 停止則放棄
+func (s *Slave) delayWriteHook(ctx context.Context, hook WriteHook, event WriteEvent) {
+	timer := time.NewTimer(hook.Delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return
+	case <-timer.C:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ctx.Err() != nil {
+		return
+	}
+	s.runWriteHook(hook, event)
+}
+
+// runWriteHook 執行回呼並同步到對外提供的暫存器 (呼叫端需持有 s.mu)
+func (s *Slave) runWriteHook(hook WriteHook, event WriteEvent) {
+	if err := hook.Action(event); err != nil {
+		s.logger.Warn(T("寫入回呼執行失敗"),
+			zap.String("hook", hook.Name),
+			zap.Uint16("address", event.Address),
+			zap.Error(err),
+		)
+	} else {
+		s.logger.Debug(T("觸發寫入回呼"),
+			zap.String("hook", hook.Name),
+			zap.Uint16("address", event.Address),
+			zap.Uint16("value", event.Value),
+		)
+	}
+	s.syncRegistersToServer()
+}
+
+// coilValues 將線圈值轉為 0/1
+func coilValues(values []bool) []uint16 {
+	words := make([]uint16, len(values))
+	for i, v := range values {
+		if v {
+			words[i] = 1
+		}
+	}
+	return words
+}
+
+// --- 配置 ---
+
+// parseTableName 解析資料表名稱 (與擷取時間軸相同: coils, discrete_inputs, input_registers, holding_registers)
+func parseTableName(name string) (RegisterType, bool) {
+	switch name {
+	case CaptureCoils:
+		return RegisterTypeCoil, true
+	case CaptureDiscreteInputs:
+		return RegisterTypeDiscreteInput, true
+	case CaptureInputRegisters:
+		return RegisterTypeInputRegister, true
+	case CaptureHoldingRegisters:
+		return RegisterTypeHoldingRegister, true
+	default:
+		return 0, false
+	}
+}
+
+// writeTable 寫入任一資料表的一個位址 (線圈與離散輸入以非 0 為 ON)
+func writeTable(registers *RegisterMap, table RegisterType, address, value uint16) error {
+	switch table {
+	case RegisterTypeCoil:
+		return registers.WriteCoil(address, value != 0)
+	case RegisterTypeDiscreteInput:
+		return registers.SetDiscreteInput(address, value != 0)
+	case RegisterTypeInputRegister:
+		return registers.SetInputRegister(address, value)
+	default:
+		return registers.WriteHoldingRegister(address, value)
+	}
+}
+
+// Validate 驗證寫入回呼規則
+func (r WriteHookRule) Validate(tags map[string][]string) error {
+	if r.Name == "" {
+		return errors.New(T("寫入回呼必須指定名稱"))
+	}
+	if table, ok := parseTableName(r.Table); !ok || (table != RegisterTypeCoil && table != RegisterTypeHoldingRegister) {
+		return fmt.Errorf(T("寫入回呼 %s 的 table 必須為 coils 或 holding_registers: %s"), r.Name, r.Table)
+	}
+	if r.Delay < 0 {
+		return fmt.Errorf(T("寫入回呼 %s 的 delay 不可為負"), r.Name)
+	}
+	if len(r.Actions) == 0 {
+		return fmt.Errorf(T("寫入回呼 %s 必須指定至少一個動作"), r.Name)
+	}
+	for _, action := range r.Actions {
+		if _, ok := parseTableName(action.Table); !ok {
+			return fmt.Errorf(T("寫入回呼 %s 的動作使用不支援的資料表: %s"), r.Name, action.Table)
+		}
+	}
+	for _, target := range r.Targets {
+		if net.ParseIP(target) == nil {
+			if _, _, err := net.ParseCIDR(target); err != nil {
+				return fmt.Errorf(T("寫入回呼 %s 的目標無效: %s"), r.Name, target)
+			}
+		}
+	}
+	for _, tag := range r.Tags {
+		if _, ok := tags[tag]; !ok {
+			return fmt.Errorf(T("寫入回呼 %s 使用未定義的 Slave 標籤: %s"), r.Name, tag)
+		}
+	}
+	return nil
+}
+
+// hook 轉為 Slave 的寫入回呼 (規則需已通過驗證)
+func (r WriteHookRule) hook() WriteHook {
+	table, _ := parseTableName(r.Table)
+	actions := r.Actions
+	return WriteHook{
+		Name:    r.Name,
+		Table:   table,
+		Address: r.Address,
+		Value:   r.Value,
+		Delay:   r.Delay,
+		Action: func(event WriteEvent) error {
+			var errs []error
+			for _, action := range actions {
+				value := event.Value
+				if action.Value != nil {
+					value = *action.Value
+				}
+				target, _ := parseTableName(action.Table)
+				if err := writeTable(event.Registers, target, action.Address, value); err != nil {
+					errs = append(errs, fmt.Errorf("%s %d: %w", action.Table, action.Address, err))
+				}
+			}
+			return errors.Join(errs...)
+		},
+	}
+}
+
+// writeHookRules 套用於 Slave 的寫入回呼規則 (targets 與 tags 皆空表示全部 Slave)
+func (c *Config) writeHookRules(ip net.IP) []WriteHookRule {
+	var rules []WriteHookRule
+	for _, rule := range c.WriteHooks {
+		if len(rule.Targets) == 0 && len(rule.Tags) == 0 {
+			rules = append(rules, rule)
+			continue
+		}
+		if len(rule.Targets) > 0 && MatchTargets(ip, rule.Targets) {
+			rules = append(rules, rule)
+			continue
+		}
+		for _, tag := range rule.Tags {
+			if c.hasTag(ip, tag) {
+				rules = append(rules, rule)
+				break
+			}
+		}
+	}
+	return rules
+}